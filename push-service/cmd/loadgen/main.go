@@ -0,0 +1,293 @@
+// Command loadgen measures how much load the service takes. It registers
+// synthetic devices through the API, then sends notifications to them at a
+// fixed rate, either through POST /v1/push/send or by publishing gateway
+// messages straight onto push.queue, and reports the p50, p95 and p99
+// latency of enqueueing them and of delivering them end to end.
+//
+// Delivery latency runs from the send to the attempt that settled the
+// notification, as GET /v1/notifications/{id} reports it, so the clocks of
+// loadgen and the workers must agree. Point it at an instance running with
+// FCM_MODE=sandbox, or every token will be rejected by FCM.
+//
+//	go run ./cmd/loadgen -users 1000 -rate 200 -duration 1m
+//	go run ./cmd/loadgen -target queue -config config.yaml -rate 500
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Targets a load run sends through
+const (
+	targetAPI   = "api"
+	targetQueue = "queue"
+)
+
+var platforms = []string{"android", "ios", "web"}
+
+// options are the command line flags
+type options struct {
+	apiURL         string
+	headers        headerFlags
+	target         string
+	configFile     string
+	prefix         string
+	users          int
+	devicesPerUser int
+	register       bool
+	rate           float64
+	duration       time.Duration
+	concurrency    int
+	priority       string
+	sample         float64
+	pollInterval   time.Duration
+	wait           time.Duration
+}
+
+// headerFlags collects repeated -header "Name: value" flags
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerFlags) Set(value string) error {
+	if name, _, ok := strings.Cut(value, ":"); !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header %q is not Name: value", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.apiURL, "api", "http://localhost:8080", "base URL of the push service API")
+	flag.Var(&opts.headers, "header", "header added to every API request, e.g. the internal access header; repeatable")
+	flag.StringVar(&opts.target, "target", targetAPI, "send through the api, or publish gateway messages onto the queue")
+	flag.StringVar(&opts.configFile, "config", os.Getenv("CONFIG_FILE"), "YAML or JSON config file whose queue broker -target queue publishes to")
+	flag.StringVar(&opts.prefix, "prefix", "loadgen", "prefix of the synthetic user IDs and device tokens")
+	flag.IntVar(&opts.users, "users", 100, "synthetic users to send to")
+	flag.IntVar(&opts.devicesPerUser, "devices-per-user", 1, "synthetic devices each user has")
+	flag.BoolVar(&opts.register, "register", true, "register the synthetic devices before sending; they are kept for later runs")
+	flag.Float64Var(&opts.rate, "rate", 10, "notifications sent per second")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "how long to send for")
+	flag.IntVar(&opts.concurrency, "concurrency", 32, "sends and registrations in flight at most")
+	flag.StringVar(&opts.priority, "priority", "", "priority of the notifications sent through the api")
+	flag.Float64Var(&opts.sample, "sample", 1, "share of the notifications whose delivery is tracked, 0 to skip delivery latency")
+	flag.DurationVar(&opts.pollInterval, "poll-interval", 250*time.Millisecond, "how often tracked notifications are checked for delivery")
+	flag.DurationVar(&opts.wait, "wait", 30*time.Second, "how long to wait for deliveries after the last send")
+	flag.Parse()
+
+	if err := opts.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, &opts); err != nil {
+		log.Fatalf("loadgen: %v", err)
+	}
+}
+
+func (o *options) validate() error {
+	switch {
+	case o.target != targetAPI && o.target != targetQueue:
+		return fmt.Errorf("unknown target %q; use %s or %s", o.target, targetAPI, targetQueue)
+	case o.users < 1 || o.devicesPerUser < 1:
+		return fmt.Errorf("-users and -devices-per-user must be at least 1")
+	case o.rate <= 0:
+		return fmt.Errorf("-rate must be positive")
+	case o.concurrency < 1:
+		return fmt.Errorf("-concurrency must be at least 1")
+	case o.sample < 0 || o.sample > 1:
+		return fmt.Errorf("-sample must be between 0 and 1")
+	case o.pollInterval <= 0:
+		return fmt.Errorf("-poll-interval must be positive")
+	}
+	return nil
+}
+
+func run(ctx context.Context, opts *options) error {
+	api := newAPIClient(opts)
+
+	if opts.register {
+		start := time.Now()
+		if err := registerDevices(ctx, api, opts); err != nil {
+			return err
+		}
+		log.Printf("Registered %d devices of %d users in %s", opts.users*opts.devicesPerUser, opts.users, time.Since(start).Round(time.Millisecond))
+	}
+
+	var send sender = api
+	if opts.target == targetQueue {
+		publisher, err := newQueuePublisher(opts.configFile)
+		if err != nil {
+			return err
+		}
+		defer publisher.Close()
+		send = publisher
+	}
+
+	tracker := newTracker(api, opts.pollInterval)
+	trackCtx, stopTracking := context.WithCancel(context.Background())
+	tracking := make(chan struct{})
+	go func() {
+		tracker.run(trackCtx)
+		close(tracking)
+	}()
+
+	log.Printf("Sending %.1f notifications per second through the %s for %s", opts.rate, opts.target, opts.duration)
+	result := generate(ctx, send, tracker, opts)
+
+	if tracker.pending() > 0 {
+		log.Printf("Waiting up to %s for %d deliveries", opts.wait, tracker.pending())
+		tracker.waitSettled(ctx, opts.wait)
+	}
+	stopTracking()
+	<-tracking
+
+	result.print(os.Stdout, tracker)
+	return nil
+}
+
+// registerDevices registers every synthetic device, opts.concurrency at a
+// time
+func registerDevices(ctx context.Context, api *apiClient, opts *options) error {
+	devices := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed int
+	var firstErr error
+
+	for range opts.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range devices {
+				user := i / opts.devicesPerUser
+				err := api.registerDevice(ctx, userID(opts.prefix, user), deviceToken(opts.prefix, user, i%opts.devicesPerUser), platforms[i%len(platforms)])
+				if err != nil {
+					mu.Lock()
+					if failed == 0 {
+						firstErr = err
+					}
+					failed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	total := opts.users * opts.devicesPerUser
+feed:
+	for i := range total {
+		select {
+		case devices <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(devices)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to register %d of %d devices: %w", failed, total, firstErr)
+	}
+	return nil
+}
+
+// generate sends at opts.rate for opts.duration. A tick finding all
+// opts.concurrency senders busy is skipped and counted, as the service or
+// loadgen can't keep up with the rate.
+func generate(ctx context.Context, send sender, tracker *tracker, opts *options) *runResult {
+	result := &runResult{target: opts.target, rate: opts.rate}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range opts.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				user := userID(opts.prefix, n%opts.users)
+				start := time.Now()
+				id, err := send.send(ctx, user, n, opts.priority)
+				result.recordEnqueue(time.Since(start), err)
+				if err == nil && sampled(n, opts.sample) {
+					tracker.track(id, start)
+				}
+			}
+		}()
+	}
+
+	interval := time.Duration(float64(time.Second) / opts.rate)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.After(opts.duration)
+
+	start := time.Now()
+	n := 0
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			select {
+			case jobs <- n:
+				n++
+			default:
+				result.skipped.Add(1)
+			}
+		case <-deadline:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	result.elapsed = time.Since(start)
+	return result
+}
+
+// sampled tells if the nth send is tracked, spreading a share of sample
+// evenly over the sends
+func sampled(n int, sample float64) bool {
+	return int(float64(n+1)*sample) > int(float64(n)*sample)
+}
+
+func userID(prefix string, user int) string {
+	return fmt.Sprintf("%s-user-%d", prefix, user)
+}
+
+func deviceToken(prefix string, user, device int) string {
+	return fmt.Sprintf("%s-token-%d-%d", prefix, user, device)
+}
+
+// sender enqueues a notification to a user and returns its ID
+type sender interface {
+	send(ctx context.Context, userID string, n int, priority string) (string, error)
+}
+
+// statusError is an unexpected API response
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.status, http.StatusText(e.status), e.body)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"push-service/internal/models"
+)
+
+// latencies collects durations to report percentiles of
+type latencies struct {
+	mu     sync.Mutex
+	values []time.Duration
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.values = append(l.values, d)
+}
+
+// summary formats the count and the p50, p95, p99 and max
+func (l *latencies) summary() string {
+	l.mu.Lock()
+	values := slices.Clone(l.values)
+	l.mu.Unlock()
+	if len(values) == 0 {
+		return "n=0"
+	}
+
+	slices.Sort(values)
+	percentile := func(p float64) time.Duration {
+		return values[int(p*float64(len(values)-1))]
+	}
+	return fmt.Sprintf("n=%d p50=%s p95=%s p99=%s max=%s", len(values),
+		round(percentile(0.50)), round(percentile(0.95)), round(percentile(0.99)), round(values[len(values)-1]))
+}
+
+func round(d time.Duration) time.Duration {
+	if d < 10*time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// runResult is what the sends of a run came to
+type runResult struct {
+	target  string
+	rate    float64
+	elapsed time.Duration
+	enqueue latencies
+	failed  atomic.Int64
+	skipped atomic.Int64
+
+	mu       sync.Mutex
+	firstErr error
+}
+
+func (r *runResult) recordEnqueue(d time.Duration, err error) {
+	if err == nil {
+		r.enqueue.add(d)
+		return
+	}
+	r.failed.Add(1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.firstErr == nil {
+		r.firstErr = err
+	}
+}
+
+func (r *runResult) print(w io.Writer, tracker *tracker) {
+	r.enqueue.mu.Lock()
+	sent := len(r.enqueue.values)
+	r.enqueue.mu.Unlock()
+
+	fmt.Fprintf(w, "\nTarget:     %s\n", r.target)
+	fmt.Fprintf(w, "Rate:       %.1f/s asked, %.1f/s enqueued over %s\n", r.rate, float64(sent)/r.elapsed.Seconds(), r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Enqueued:   %d, %d failed, %d skipped with every sender busy\n", sent, r.failed.Load(), r.skipped.Load())
+	if r.firstErr != nil {
+		fmt.Fprintf(w, "First error: %v\n", r.firstErr)
+	}
+	fmt.Fprintf(w, "Enqueue:    %s\n", r.enqueue.summary())
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if tracker.tracked == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Tracked:    %d, %d unsettled\n", tracker.tracked, len(tracker.waiting))
+	for _, status := range slices.Sorted(maps.Keys(tracker.statuses)) {
+		fmt.Fprintf(w, "  %-20s %d\n", status, tracker.statuses[status])
+	}
+	fmt.Fprintf(w, "Delivery:   %s\n", tracker.delivery.summary())
+}
+
+// settled are the notification statuses no further attempt follows
+var settled = map[string]bool{
+	models.NotificationStatusDelivered:    true,
+	models.NotificationStatusPartial:      true,
+	models.NotificationStatusFailed:       true,
+	models.NotificationStatusDeadLettered: true,
+}
+
+// tracker polls the API for the notifications it tracks until each is
+// settled, and records how long delivering them took
+type tracker struct {
+	api      *apiClient
+	interval time.Duration
+	delivery latencies
+
+	mu       sync.Mutex
+	waiting  map[string]time.Time // sent at, by notification ID
+	tracked  int
+	statuses map[string]int // of the settled notifications
+}
+
+func newTracker(api *apiClient, interval time.Duration) *tracker {
+	return &tracker{
+		api:      api,
+		interval: interval,
+		waiting:  make(map[string]time.Time),
+		statuses: make(map[string]int),
+	}
+}
+
+// track starts tracking a notification sent at sentAt
+func (t *tracker) track(id string, sentAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.waiting[id] = sentAt
+	t.tracked++
+}
+
+func (t *tracker) pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.waiting)
+}
+
+// waitSettled waits until no tracked notification is waiting, for at most
+// timeout
+func (t *tracker) waitSettled(ctx context.Context, timeout time.Duration) {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for t.pending() > 0 {
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run polls the waiting notifications every interval until ctx is cancelled
+func (t *tracker) run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.poll(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll checks each waiting notification once, a few at a time
+func (t *tracker) poll(ctx context.Context) {
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.waiting))
+	for id := range t.waiting {
+		ids = append(ids, id)
+	}
+	t.mu.Unlock()
+
+	ch := make(chan string)
+	var wg sync.WaitGroup
+	for range min(len(ids), 16) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ch {
+				t.check(ctx, id)
+			}
+		}()
+	}
+	for _, id := range ids {
+		ch <- id
+	}
+	close(ch)
+	wg.Wait()
+}
+
+// check settles a notification if its last attempt did. Its delivery took
+// until that attempt was made.
+func (t *tracker) check(ctx context.Context, id string) {
+	detail, err := t.api.notification(ctx, id)
+	if err != nil || detail == nil || !settled[detail.Status] {
+		return
+	}
+	settledAt := detail.Attempts[len(detail.Attempts)-1].AttemptedAt
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sentAt, ok := t.waiting[id]
+	if !ok {
+		return
+	}
+	delete(t.waiting, id)
+	t.statuses[detail.Status]++
+	t.delivery.add(max(settledAt.Sub(sentAt), 0))
+}
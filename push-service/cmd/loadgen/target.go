@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"push-service/internal/app"
+	"push-service/internal/config"
+	"push-service/internal/gateway"
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+// apiClient calls the push service API
+type apiClient struct {
+	baseURL string
+	headers http.Header
+	client  *http.Client
+}
+
+func newAPIClient(opts *options) *apiClient {
+	headers := make(http.Header)
+	for _, header := range opts.headers {
+		name, value, _ := strings.Cut(header, ":")
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = opts.concurrency * 2 // senders and the tracker's polls
+	return &apiClient{
+		baseURL: strings.TrimRight(opts.apiURL, "/"),
+		headers: headers,
+		client:  &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}
+}
+
+// do sends a request with body encoded as JSON, if it isn't nil, and decodes
+// a response with the wanted status into out. Other statuses are returned as
+// a *statusError.
+func (a *apiClient) do(ctx context.Context, method, path string, body, out any, want int) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	for name, values := range a.headers {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{status: resp.StatusCode, body: strings.TrimSpace(string(text))}
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (a *apiClient) registerDevice(ctx context.Context, userID, token, platform string) error {
+	return a.do(ctx, http.MethodPost, "/v1/devices", models.CreateDeviceRequest{
+		UserID:   userID,
+		Token:    token,
+		Platform: platform,
+	}, nil, http.StatusCreated)
+}
+
+// send sends a notification through POST /v1/push/send
+func (a *apiClient) send(ctx context.Context, userID string, n int, priority string) (string, error) {
+	var resp struct {
+		NotificationID string `json:"notification_id"`
+		DropReason     string `json:"drop_reason"`
+	}
+	err := a.do(ctx, http.MethodPost, "/v1/push/send", models.SendPushRequest{
+		UserID:   userID,
+		Title:    "Load test",
+		Body:     fmt.Sprintf("Notification %d", n),
+		Data:     map[string]any{"loadgen": "true"},
+		Priority: priority,
+	}, &resp, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	if resp.NotificationID == "" {
+		return "", fmt.Errorf("notification dropped: %s", resp.DropReason)
+	}
+	return resp.NotificationID, nil
+}
+
+// notification gets a notification's delivery attempts; it returns nil
+// while the notification has none
+func (a *apiClient) notification(ctx context.Context, id string) (*models.NotificationDetail, error) {
+	var detail models.NotificationDetail
+	err := a.do(ctx, http.MethodGet, "/v1/notifications/"+url.PathEscape(id), nil, &detail, http.StatusOK)
+	if statusErr, ok := err.(*statusError); ok && statusErr.status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// queuePublisher publishes gateway messages onto push.queue, past the API,
+// on the broker the config file sets up
+type queuePublisher struct {
+	broker queue.Broker
+	db     *database.DB
+}
+
+func newQueuePublisher(configFile string) (*queuePublisher, error) {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.Queue.Backend == config.QueueBackendMemory {
+		return nil, fmt.Errorf("the memory queue backend can't be reached from another process; use -target api")
+	}
+
+	p := &queuePublisher{}
+	if cfg.Queue.Backend == config.QueueBackendPostgres {
+		if p.db, err = database.NewPostgresDB(&cfg.Database); err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+	}
+	if p.broker, err = app.NewBroker(cfg, p.db); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to connect to queue broker %s: %w", cfg.Queue.Backend, err)
+	}
+	if err := p.broker.Declare(context.Background(), queue.GatewayQueueSpec(&cfg.Queue.Gateway)); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to declare %s: %w", queue.GatewayPushQueueName, err)
+	}
+	return p, nil
+}
+
+// send publishes a gateway message with a new notification ID
+func (p *queuePublisher) send(ctx context.Context, userID string, n int, _ string) (string, error) {
+	message := models.GatewayPushMessage{
+		SchemaVersion:  gateway.LatestSchemaVersion,
+		NotificationID: uuid.New().String(),
+		UserID:         userID,
+		Template: &models.GatewayTemplate{
+			Subject: "Load test",
+			Body:    fmt.Sprintf("Notification %d", n),
+		},
+		Data: map[string]any{"loadgen": "true"},
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	if err := p.broker.Publish(ctx, queue.GatewayPushQueueName, body); err != nil {
+		return "", err
+	}
+	return message.NotificationID, nil
+}
+
+func (p *queuePublisher) Close() {
+	if p.broker != nil {
+		p.broker.Close()
+	}
+	if p.db != nil {
+		p.db.Close()
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// apiClient calls the push service API with the API key in the internal
+// access header
+type apiClient struct {
+	baseURL   string
+	keyHeader string
+	key       string
+	client    *http.Client
+}
+
+func newAPIClient(opts *options) *apiClient {
+	return &apiClient{
+		baseURL:   strings.TrimRight(opts.apiURL, "/"),
+		keyHeader: opts.keyHeader,
+		key:       opts.key,
+		client:    &http.Client{Timeout: opts.timeout},
+	}
+}
+
+// statusError is a response with a status the call didn't expect
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.status, http.StatusText(e.status), e.message)
+}
+
+// do sends a request with body encoded as JSON, if it isn't nil, and decodes
+// a response with the wanted status into out. Other statuses are returned as
+// a *statusError with the API's error and details.
+func (a *apiClient) do(ctx context.Context, method, path string, body, out any, want int) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if a.key != "" {
+		req.Header.Set(a.keyHeader, a.key)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		return readStatusError(resp)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func readStatusError(resp *http.Response) error {
+	text, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var apiErr struct {
+		Error   string `json:"error"`
+		Details any    `json:"details"`
+	}
+	message := strings.TrimSpace(string(text))
+	if json.Unmarshal(text, &apiErr) == nil && apiErr.Error != "" {
+		message = apiErr.Error
+		if apiErr.Details != nil {
+			message += fmt.Sprintf(" (%v)", apiErr.Details)
+		}
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		message += "; pass the API key with -api-key or PUSHCTL_API_KEY"
+	}
+	return &statusError{status: resp.StatusCode, message: message}
+}
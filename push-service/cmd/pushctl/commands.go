@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"push-service/internal/models"
+)
+
+// cli is what the commands run with
+type cli struct {
+	api  *apiClient
+	json bool
+	out  io.Writer
+}
+
+// print writes v as indented JSON with -json, or as text otherwise
+func (c *cli) print(v any, text func(w io.Writer)) error {
+	if c.json {
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	text(w)
+	return w.Flush()
+}
+
+// dataFlags collects repeated -data key=value flags
+type dataFlags map[string]any
+
+func (d dataFlags) String() string {
+	pairs := make([]string, 0, len(d))
+	for key, value := range d {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (d dataFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("data %q is not key=value", value)
+	}
+	d[key] = val
+	return nil
+}
+
+func runDevices(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing subcommand: register, list or unregister")
+	}
+	switch args[0] {
+	case "register":
+		return registerDevice(ctx, c, args[1:])
+	case "list":
+		return listDevices(ctx, c, args[1:])
+	case "unregister":
+		return unregisterDevice(ctx, c, args[1:])
+	}
+	return fmt.Errorf("unknown subcommand %q: use register, list or unregister", args[0])
+}
+
+func registerDevice(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("devices register", "-user <id> -token <token> -platform <ios|android|web>")
+	userID := fs.String("user", "", "user the device belongs to")
+	token := fs.String("token", "", "the device's FCM token")
+	platform := fs.String("platform", "", "ios, android or web")
+	deviceID := fs.String("device-id", "", "the device's stable ID, replacing its registration under an older token")
+	fs.Parse(args)
+	if *userID == "" || *token == "" || *platform == "" {
+		fs.Usage()
+		return errors.New("-user, -token and -platform are required")
+	}
+
+	req := models.CreateDeviceRequest{UserID: *userID, Token: *token, Platform: *platform}
+	if *deviceID != "" {
+		req.DeviceID = deviceID
+	}
+	var resp struct {
+		Device models.DeviceResponse `json:"device"`
+	}
+	if err := c.api.do(ctx, http.MethodPost, "/v1/devices", req, &resp, http.StatusCreated); err != nil {
+		return err
+	}
+	return c.print(resp, func(w io.Writer) {
+		device := resp.Device
+		state := "registered"
+		switch {
+		case device.Replaced:
+			state = "replaced its older registration"
+		case device.Resurrected:
+			state = "restored"
+		}
+		fmt.Fprintf(w, "Device %s of %s %s (%s)\n", device.ID, device.UserID, state, device.Platform)
+	})
+}
+
+func listDevices(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("devices list", "-user <id>")
+	userID := fs.String("user", "", "user whose devices to list")
+	fs.Parse(args)
+	if *userID == "" {
+		fs.Usage()
+		return errors.New("-user is required")
+	}
+
+	var resp struct {
+		UserID  string                  `json:"user_id"`
+		Devices []models.DeviceResponse `json:"devices"`
+		Count   int                     `json:"count"`
+	}
+	if err := c.api.do(ctx, http.MethodGet, "/v1/devices?user_id="+url.QueryEscape(*userID), nil, &resp, http.StatusOK); err != nil {
+		return err
+	}
+	return c.print(resp, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tPLATFORM\tACTIVE\tPERMISSION\tLAST SEEN\tTOKEN")
+		for _, device := range resp.Devices {
+			lastSeen := "-"
+			if device.LastSeenAt != nil {
+				lastSeen = device.LastSeenAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\n", device.ID, device.Platform, device.IsActive, deref(device.PermissionStatus), lastSeen, device.Token)
+		}
+	})
+}
+
+func unregisterDevice(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("devices unregister", "<token>")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("the device token is required")
+	}
+
+	if err := c.api.do(ctx, http.MethodDelete, "/v1/devices/"+url.PathEscape(fs.Arg(0)), nil, nil, http.StatusOK); err != nil {
+		return err
+	}
+	return c.print(map[string]string{"token": fs.Arg(0)}, func(w io.Writer) {
+		fmt.Fprintln(w, "Device unregistered")
+	})
+}
+
+func runSend(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("send", "-user <id> [-title <title>] [-body <body>] [-data key=value]...")
+	userID := fs.String("user", "", "user to send to")
+	title := fs.String("title", "Test notification", "notification title")
+	body := fs.String("body", "Sent with pushctl", "notification body")
+	priority := fs.String("priority", "", "critical, high, normal or low")
+	data := dataFlags{}
+	fs.Var(data, "data", "data key=value sent with the notification; repeatable")
+	wait := fs.Duration("wait", 0, "wait this long at most for the notification to be settled, and show its attempts")
+	fs.Parse(args)
+	if *userID == "" {
+		fs.Usage()
+		return errors.New("-user is required")
+	}
+
+	req := models.SendPushRequest{UserID: *userID, Title: *title, Body: *body, Priority: *priority}
+	if len(data) > 0 {
+		req.Data = data
+	}
+	var resp struct {
+		NotificationID string `json:"notification_id,omitempty"`
+		DropReason     string `json:"drop_reason,omitempty"`
+	}
+	if err := c.api.do(ctx, http.MethodPost, "/v1/push/send", req, &resp, http.StatusOK); err != nil {
+		return err
+	}
+	if resp.NotificationID == "" || *wait <= 0 {
+		return c.print(resp, func(w io.Writer) {
+			if resp.NotificationID == "" {
+				fmt.Fprintf(w, "Notification dropped: %s\n", resp.DropReason)
+				return
+			}
+			fmt.Fprintf(w, "Notification %s enqueued\n", resp.NotificationID)
+		})
+	}
+
+	detail, err := waitSettled(ctx, c.api, resp.NotificationID, *wait)
+	if err != nil {
+		return err
+	}
+	if detail == nil {
+		return fmt.Errorf("notification %s has no delivery attempt after %s", resp.NotificationID, *wait)
+	}
+	return printDetail(c, detail)
+}
+
+// settled are the notification statuses no further attempt follows
+var settled = map[string]bool{
+	models.NotificationStatusDelivered:    true,
+	models.NotificationStatusPartial:      true,
+	models.NotificationStatusFailed:       true,
+	models.NotificationStatusDeadLettered: true,
+}
+
+// waitSettled polls a notification until it is settled or timeout has
+// passed, and returns its last detail; nil if it has no attempt yet
+func waitSettled(ctx context.Context, api *apiClient, id string, timeout time.Duration) (*models.NotificationDetail, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	var detail *models.NotificationDetail
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return detail, nil
+		}
+		next, err := getNotification(ctx, api, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return detail, nil
+			}
+			return nil, err
+		}
+		if next != nil {
+			detail = next
+			if settled[detail.Status] {
+				return detail, nil
+			}
+		}
+	}
+}
+
+// getNotification gets a notification's delivery attempts; it returns nil
+// while the notification has none
+func getNotification(ctx context.Context, api *apiClient, id string) (*models.NotificationDetail, error) {
+	var detail models.NotificationDetail
+	err := api.do(ctx, http.MethodGet, "/v1/notifications/"+url.PathEscape(id), nil, &detail, http.StatusOK)
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+func runStatus(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("status", "<notification-id>")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("the notification ID is required")
+	}
+
+	detail, err := getNotification(ctx, c.api, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if detail == nil {
+		return fmt.Errorf("notification %s has no delivery attempt; it is still queued, or doesn't exist", fs.Arg(0))
+	}
+	return printDetail(c, detail)
+}
+
+func printDetail(c *cli, detail *models.NotificationDetail) error {
+	return c.print(detail, func(w io.Writer) {
+		fmt.Fprintf(w, "Notification %s of %s: %s\n\n", detail.ID, detail.UserID, detail.Status)
+		fmt.Fprintln(w, "ATTEMPTED AT\tRETRY\tFROM\tDELIVERED\tFAILED\tNEXT\tERROR")
+		for _, attempt := range detail.Attempts {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\t%s\n",
+				attempt.AttemptedAt.Local().Format(time.DateTime), attempt.RetryCount, attempt.Queue,
+				attempt.SuccessCount, attempt.FailureCount, deref(attempt.NextQueue), deref(attempt.Error))
+		}
+
+		var failed []models.AttemptTokenResult
+		for _, result := range detail.Attempts[len(detail.Attempts)-1].Results {
+			if !result.Success {
+				failed = append(failed, result)
+			}
+		}
+		if len(failed) == 0 {
+			return
+		}
+		fmt.Fprintln(w, "\nFAILED TOKEN\tCODE\tERROR")
+		for _, result := range failed {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", result.Token, deref(result.ErrorCode), deref(result.Error))
+		}
+	})
+}
+
+func runStats(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("stats", "")
+	fs.Parse(args)
+
+	var resp struct {
+		Queues map[string]int64 `json:"queues"`
+	}
+	if err := c.api.do(ctx, http.MethodGet, "/v1/queue/stats", nil, &resp, http.StatusOK); err != nil {
+		return err
+	}
+	return c.print(resp, func(w io.Writer) {
+		fmt.Fprintln(w, "QUEUE\tMESSAGES")
+		for _, name := range slices.Sorted(maps.Keys(resp.Queues)) {
+			fmt.Fprintf(w, "%s\t%d\n", name, resp.Queues[name])
+		}
+	})
+}
+
+func runDLQ(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing subcommand: peek or replay")
+	}
+	switch args[0] {
+	case "peek":
+		return peekDLQ(ctx, c, args[1:])
+	case "replay":
+		return replayDLQ(ctx, c, args[1:])
+	}
+	return fmt.Errorf("unknown subcommand %q: use peek or replay", args[0])
+}
+
+func peekDLQ(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("dlq peek", "[-limit <n>]")
+	limit := fs.Int("limit", 10, "messages to show at most, from the head of the queue")
+	fs.Parse(args)
+
+	var resp models.DeadLetters
+	if err := c.api.do(ctx, http.MethodGet, "/v1/admin/dead-letters?limit="+strconv.Itoa(*limit), nil, &resp, http.StatusOK); err != nil {
+		return err
+	}
+	return c.print(resp, func(w io.Writer) {
+		if resp.Count == 0 {
+			fmt.Fprintln(w, "The dead letter queue is empty")
+			return
+		}
+		fmt.Fprintln(w, "DEAD-LETTERED AT\tNOTIFICATION\tUSER\tPRIORITY\tRETRIES\tTOKENS\tTITLE\tERROR")
+		for _, deadLetter := range resp.DeadLetters {
+			deadLetteredAt := "-"
+			if deadLetter.DeadLetteredAt != nil {
+				deadLetteredAt = deadLetter.DeadLetteredAt.Local().Format(time.DateTime)
+			}
+			errText := deadLetter.LastError
+			if deadLetter.Error != "" {
+				errText = "undecodable: " + deadLetter.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+				deadLetteredAt, orDash(deadLetter.NotificationID), orDash(deadLetter.UserID), orDash(deadLetter.Priority),
+				deadLetter.RetryCount, len(deadLetter.DeviceTokens), deadLetter.Title, errText)
+		}
+	})
+}
+
+func replayDLQ(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("dlq replay", "-limit <n>")
+	limit := fs.Int("limit", 0, "messages to replay at most, from the head of the queue")
+	fs.Parse(args)
+	if *limit < 1 {
+		fs.Usage()
+		return errors.New("-limit is required")
+	}
+
+	var resp models.DeadLetterReplay
+	if err := c.api.do(ctx, http.MethodPost, "/v1/admin/dead-letters/replay", map[string]int{"limit": *limit}, &resp, http.StatusOK); err != nil {
+		return err
+	}
+	return c.print(resp, func(w io.Writer) {
+		fmt.Fprintf(w, "Replayed %d dead letters onto the push queues", resp.Replayed)
+		if resp.Skipped > 0 {
+			fmt.Fprintf(w, "; %d that can't be decoded were left in the queue", resp.Skipped)
+		}
+		fmt.Fprintln(w)
+	})
+}
+
+func runTail(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("tail", "[-n <count>] [-user <id>] [-failures]")
+	last := fs.Int("n", 10, "recent attempts to print first")
+	userID := fs.String("user", "", "only attempts for this user")
+	failures := fs.Bool("failures", false, "only attempts with failed tokens")
+	interval := fs.Duration("interval", time.Second, "how often to check for new attempts")
+	fs.Parse(args)
+
+	// With -n 0, the first call only finds the newest attempt to start after
+	limit := max(*last, 1)
+	var after int64
+	first := true
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		path := fmt.Sprintf("/v1/admin/delivery-events?limit=%d", min(limit, 500))
+		if after > 0 {
+			path += "&after=" + strconv.FormatInt(after, 10)
+		}
+		var resp models.DeliveryEvents
+		if err := c.api.do(ctx, http.MethodGet, path, nil, &resp, http.StatusOK); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if !first || *last > 0 {
+			for _, event := range resp.Events {
+				if (*userID == "" || event.UserID == *userID) && (!*failures || event.FailureCount > 0) {
+					printEvent(c, event)
+				}
+			}
+		}
+		after, first, limit = resp.After, false, 500
+
+		// Keep reading while a backlog of attempts is waiting
+		if resp.Count == 500 {
+			continue
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func printEvent(c *cli, event models.DeliveryEvent) {
+	if c.json {
+		encoded, _ := json.Marshal(event)
+		fmt.Fprintln(c.out, string(encoded))
+		return
+	}
+	line := fmt.Sprintf("%s  %-19s  %s  user=%s  from=%s  delivered=%d failed=%d",
+		event.AttemptedAt.Local().Format(time.DateTime), event.Status, event.NotificationID, event.UserID,
+		event.Queue, event.SuccessCount, event.FailureCount)
+	if event.NextQueue != nil {
+		line += "  next=" + *event.NextQueue
+	}
+	if event.Error != nil {
+		line += "  error=" + strconv.Quote(*event.Error)
+	}
+	fmt.Fprintln(c.out, line)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return *s
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+// Command pushctl is the on-call tool for a running push service. It
+// registers devices, sends test pushes, shows a notification's delivery
+// attempts and the queue lengths, peeks at and replays the dead letter
+// queue, and tails delivery attempts as the workers make them.
+//
+// It calls the API, authenticating with an API key sent in the internal
+// access header: the ACCESS_INTERNAL_HEADER_VALUE the instance checks, in
+// its ACCESS_INTERNAL_HEADER.
+//
+//	export PUSHCTL_API=https://push.internal PUSHCTL_API_KEY=...
+//	pushctl devices register -user user123 -token fcm-token -platform android
+//	pushctl send -user user123 -title Test -body "Hello from on-call"
+//	pushctl status 0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de
+//	pushctl dlq peek -limit 5
+//	pushctl dlq replay -limit 100
+//	pushctl tail -user user123
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// options are the global flags, given before the command
+type options struct {
+	apiURL    string
+	keyHeader string
+	key       string
+	timeout   time.Duration
+	json      bool
+}
+
+// command is a pushctl command. Commands with subcommands dispatch on their
+// first argument themselves.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, cli *cli, args []string) error
+}
+
+var commands = []command{
+	{"devices", "register, list or unregister a user's devices", runDevices},
+	{"send", "send a test push to a user", runSend},
+	{"status", "show a notification's status and delivery attempts", runStatus},
+	{"stats", "show the length of every push queue", runStats},
+	{"dlq", "peek at or replay the dead letter queue", runDLQ},
+	{"tail", "print delivery attempts as they are made", runTail},
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.apiURL, "api", envOr("PUSHCTL_API", "http://localhost:8080"), "base URL of the push service API (PUSHCTL_API)")
+	flag.StringVar(&opts.key, "api-key", os.Getenv("PUSHCTL_API_KEY"), "API key, the instance's ACCESS_INTERNAL_HEADER_VALUE (PUSHCTL_API_KEY)")
+	flag.StringVar(&opts.keyHeader, "api-key-header", envOr("PUSHCTL_API_KEY_HEADER", "X-Internal-Request"), "header the API key is sent in, the instance's ACCESS_INTERNAL_HEADER (PUSHCTL_API_KEY_HEADER)")
+	flag.DurationVar(&opts.timeout, "timeout", time.Minute, "how long each API call may take")
+	flag.BoolVar(&opts.json, "json", false, "print the API's responses as JSON")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	name, args := flag.Arg(0), flag.Args()[1:]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		err := cmd.run(ctx, &cli{api: newAPIClient(&opts), json: opts.json, out: os.Stdout}, args)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "pushctl %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "pushctl: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: pushctl [flags] <command> [command flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(out, "\nRun pushctl <command> -h for the command's flags.\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newFlagSet returns the flag set of a command, whose usage names it
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet("pushctl "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: pushctl %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
@@ -0,0 +1,151 @@
+// Command scheduler runs the time-based background jobs: campaign pacing,
+// alert rule evaluation, deferred deliveries and digests, device cleanup,
+// the retention purge, the outbox relay and the dead letter archiver. Run
+// one next to API and worker replicas started with
+// QUEUE_WORKER_RUN_JOBS=false, so they don't each run the same tickers.
+//
+// It serves /health, /ready, /metrics, GET /v1/admin/consumers and POST
+// /v1/admin/config/reload on SCHEDULER_PORT.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"push-service/internal/app"
+	"push-service/internal/config"
+	"push-service/internal/handlers"
+	"push-service/internal/reload"
+	"push-service/internal/worker"
+	"push-service/pkg/database"
+	"push-service/pkg/logger"
+	"push-service/pkg/metrics"
+	"push-service/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON config file; environment variables override its settings")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize logger
+	if err := logger.InitGlobal(cfg.Log.Level, cfg.Log.Format, cfg.Log.Redact); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.L().Sync()
+	if !cfg.Log.Redact {
+		logger.L().Warn("Log redaction is off: push tokens, user IDs and notification content are logged in full")
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), &cfg.Tracing)
+	if err != nil {
+		logger.L().Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
+	// Respect container CPU/memory limits before sizing any pools
+	worker.ConfigureRuntime(&cfg.Queue.Worker)
+
+	gin.SetMode(cfg.Server.Mode)
+
+	// Apply database migrations
+	if cfg.Database.AutoMigrate {
+		if err := app.WaitForDatabase(cfg); err != nil {
+			logger.L().Fatal("Database not available", zap.Error(err))
+		}
+		if err := database.Migrate(&cfg.Database); err != nil {
+			logger.L().Fatal("Failed to migrate database", zap.Error(err))
+		}
+	}
+
+	// Connect to the database, the queue broker, FCM and the other backends
+	deps, err := app.Open(cfg)
+	if err != nil {
+		logger.L().Fatal("Failed to initialize dependencies", zap.Error(err))
+	}
+	defer deps.Close()
+
+	svc, err := app.NewServices(deps, cfg)
+	if err != nil {
+		logger.L().Fatal("Failed to initialize services", zap.Error(err))
+	}
+	jobs := svc.Jobs
+
+	// Reload the tunable settings on SIGHUP or from the admin API
+	reloader := reload.New(*configFile, cfg, nil, deps.Projects)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go reloader.Watch(watchCtx)
+
+	srv := &http.Server{
+		Addr:    ":" + cfg.Scheduler.Port,
+		Handler: setupRouter(deps, reloader, cfg),
+	}
+	go func() {
+		logger.L().Info("Starting scheduler HTTP server", zap.String("port", cfg.Scheduler.Port))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.L().Fatal("Failed to start scheduler HTTP server", zap.Error(err))
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs.Start(ctx)
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.L().Info("Shutting down scheduler...")
+	cancel()
+	jobs.Wait()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.L().Error("Scheduler HTTP server forced to shutdown", zap.Error(err))
+	}
+
+	logger.L().Info("Scheduler exited properly")
+}
+
+// setupRouter serves the health checks, metrics and configuration reloads
+func setupRouter(deps *app.Deps, reloader *reload.Reloader, cfg *config.Config) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	if err := router.SetTrustedProxies(cfg.Access.TrustedProxies); err != nil {
+		logger.L().Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+	internalOnly, err := handlers.InternalOnly(&cfg.Access)
+	if err != nil {
+		logger.L().Fatal("Invalid internal access settings", zap.Error(err))
+	}
+
+	adminHandler := handlers.NewAdminHandler(nil, deps.FCMClient, reloader)
+
+	router.GET("/health", handlers.HealthCheck)
+	router.GET("/ready", handlers.ReadinessCheck(deps.DB, deps.FCMClient))
+	router.GET("/metrics", metrics.Handler())
+
+	admin := router.Group("/v1/admin", internalOnly)
+	admin.GET("/consumers", adminHandler.ListConsumers)
+	admin.POST("/config/reload", adminHandler.ReloadConfig)
+
+	return router
+}
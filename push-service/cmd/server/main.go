@@ -23,23 +23,27 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	_ "push-service/docs/swagger"
+	"push-service/internal/app"
 	"push-service/internal/config"
 	"push-service/internal/handlers"
-	"push-service/internal/platform/fcm"
-	"push-service/internal/queue"
-	"push-service/internal/repository"
-	"push-service/internal/service"
+	"push-service/internal/openapi"
+	"push-service/internal/reload"
+	"push-service/internal/worker"
 	"push-service/pkg/database"
 	"push-service/pkg/logger"
-	"push-service/pkg/rabbitmq"
+	"push-service/pkg/metrics"
+	"push-service/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -48,43 +52,83 @@ import (
 )
 
 func main() {
+	migrateOnly := flag.Bool("migrate", false, "apply the database migrations and exit")
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON config file; environment variables override its settings")
+	flag.Parse()
+
+	// Subcommands, e.g. config validate or openapi
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args(), *configFile))
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configFile)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
 	// Initialize logger
-	if err := logger.InitGlobal(cfg.Log.Level, cfg.Log.Format); err != nil {
+	if err := logger.InitGlobal(cfg.Log.Level, cfg.Log.Format, cfg.Log.Redact); err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer logger.L().Sync()
+	if !cfg.Log.Redact {
+		logger.L().Warn("Log redaction is off: push tokens, user IDs and notification content are logged in full")
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), &cfg.Tracing)
+	if err != nil {
+		logger.L().Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
+	// Respect container CPU/memory limits before sizing any pools
+	worker.ConfigureRuntime(&cfg.Queue.Worker)
 
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
-	// Initialize database
-	db, err := database.NewPostgresDB(&cfg.Database)
-	if err != nil {
-		logger.L().Fatal("Failed to connect to database", zap.Error(err))
+	// Apply database migrations
+	if *migrateOnly || cfg.Database.AutoMigrate {
+		if err := app.WaitForDatabase(cfg); err != nil {
+			logger.L().Fatal("Database not available", zap.Error(err))
+		}
+		if err := database.Migrate(&cfg.Database); err != nil {
+			logger.L().Fatal("Failed to migrate database", zap.Error(err))
+		}
+		if *migrateOnly {
+			return
+		}
 	}
-	defer db.Close()
 
-	// Initialize RabbitMQ
-	rabbitmqClient, err := rabbitmq.NewRabbitMQClient(&cfg.RabbitMQ)
+	// Connect to the database, the queue broker, FCM and the other backends
+	deps, err := app.Open(cfg)
 	if err != nil {
-		logger.L().Fatal("Failed to connect to RabbitMQ", zap.Error(err))
+		logger.L().Fatal("Failed to initialize dependencies", zap.Error(err))
 	}
-	defer rabbitmqClient.Close()
+	defer deps.Close()
 
-	// Initialize FCM client
-	fcmClient, err := fcm.NewFCMClient(&cfg.FCM)
+	// The repositories and services, shared by the API and the worker
+	svc, err := app.NewServices(deps, cfg)
 	if err != nil {
-		logger.L().Fatal("Failed to initialize FCM client", zap.Error(err))
+		logger.L().Fatal("Failed to initialize services", zap.Error(err))
 	}
 
+	// Initialize queue worker, unless it runs on its own (cmd/worker)
+	var pushWorker *worker.Worker
+	if cfg.Server.RunWorker {
+		pushWorker = app.NewPushWorker(deps, svc, cfg)
+	}
+
+	// Reload the tunable settings on SIGHUP or from the admin API
+	reloader := reload.New(*configFile, cfg, pushWorker, deps.Projects)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go reloader.Watch(watchCtx)
+
 	// Create Gin router
-	router := setupRouter(db, rabbitmqClient, fcmClient, cfg)
+	router := setupRouter(deps, svc, pushWorker, reloader, cfg)
 
 	// Create server
 	srv := &http.Server{
@@ -101,7 +145,9 @@ func main() {
 	}()
 
 	// Start queue worker
-	go startPushWorker(rabbitmqClient, fcmClient, db, cfg)
+	if pushWorker != nil {
+		startPushWorker(pushWorker)
+	}
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -110,131 +156,229 @@ func main() {
 
 	logger.L().Info("Shutting down server...")
 
+	// Drain the worker while the HTTP server shuts down
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		if pushWorker != nil {
+			stopPushWorker(pushWorker, &cfg.Queue.Worker)
+		}
+	}()
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.L().Fatal("Server forced to shutdown", zap.Error(err))
+		logger.L().Error("Server forced to shutdown", zap.Error(err))
 	}
+	<-drained
 
 	logger.L().Info("Server exited properly")
 }
 
-func setupRouter(db *database.DB, rabbitmqClient *rabbitmq.RabbitMQClient, fcmClient fcm.FCMClient, cfg *config.Config) *gin.Engine {
-	router := gin.New()
+// runCommand runs a subcommand and returns the exit code:
+//
+//	config validate [--config file]   load and validate the configuration
+func runCommand(args []string, configFile string) int {
+	if len(args) == 1 && args[0] == "openapi" {
+		doc, err := openapi.Document()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to build OpenAPI document: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(doc))
+		return 0
+	}
+	if len(args) < 2 || args[0] != "config" || args[1] != "validate" {
+		fmt.Fprintf(os.Stderr, "unknown command %q; usage: server [--config file] config validate, or server openapi\n", strings.Join(args, " "))
+		return 2
+	}
 
-	// Middleware
-	router.Use(gin.Recovery())
-	router.Use(loggerMiddleware())
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	flags.StringVar(&configFile, "config", configFile, "YAML or JSON config file; environment variables override its settings")
+	if err := flags.Parse(args[2:]); err != nil {
+		return 2
+	}
 
-	// Initialize repositories and services
-	deviceRepo := repository.NewDeviceRepository(db.Pool)
-	pushQueue, err := queue.NewPushQueue(rabbitmqClient, &cfg.Queue)
+	file, err := config.Validate(configFile)
+	if file == "" {
+		file = "environment only, no config file"
+	}
 	if err != nil {
-		logger.L().Fatal("Failed to initialize push queue", zap.Error(err))
+		fmt.Fprintf(os.Stderr, "Invalid configuration (%s): %v\n", file, err)
+		return 1
+	}
+	fmt.Printf("Configuration is valid (%s)\n", file)
+	return 0
+}
+
+func setupRouter(deps *app.Deps, svc *app.Services, pushWorker *worker.Worker, reloader *reload.Reloader, cfg *config.Config) *gin.Engine {
+	db, fcmClient, hub := deps.DB, deps.FCMClient, deps.Hub
+
+	router := gin.New()
+	if err := handlers.RegisterValidators(); err != nil {
+		logger.L().Fatal("Failed to register request validators", zap.Error(err))
 	}
 
-	deviceService := service.NewDeviceService(deviceRepo, fcmClient, cfg)
-	pushService := service.NewPushService(deviceRepo, fcmClient, pushQueue, cfg)
+	// Client IPs come from X-Forwarded-For only behind the trusted proxies
+	if err := router.SetTrustedProxies(cfg.Access.TrustedProxies); err != nil {
+		logger.L().Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+	internalOnly, err := handlers.InternalOnly(&cfg.Access)
+	if err != nil {
+		logger.L().Fatal("Invalid internal access settings", zap.Error(err))
+	}
 
-	deviceHandler := handlers.NewDeviceHandler(deviceService)
-	pushHandler := handlers.NewPushHandler(pushService)
+	// Middleware
+	router.Use(gin.Recovery())
+	router.Use(loggerMiddleware(cfg.Log.Redact))
+	router.Use(tracing.Middleware())
+
+	deviceHandler := handlers.NewDeviceHandler(svc.Devices)
+	sdkHandler := handlers.NewSDKHandler(svc.Devices)
+	muteHandler := handlers.NewMuteHandler(svc.Mutes)
+	pushHandler := handlers.NewPushHandler(svc.Push)
+	campaignHandler := handlers.NewCampaignHandler(svc.Campaigns)
+	adminHandler := handlers.NewAdminHandler(pushWorker, fcmClient, reloader)
+	alertHandler := handlers.NewAlertHandler(svc.Alerts)
+	tenantHandler := handlers.NewTenantHandler(svc.Tenants)
+	analyticsHandler := handlers.NewAnalyticsHandler(svc.Analytics)
+	webhookHandler := handlers.NewWebhookHandler(svc.Webhooks)
+	inboxHandler := handlers.NewInboxHandler(svc.Inbox)
+	realtimeHandler := handlers.NewRealtimeHandler(hub, &cfg.Realtime)
+	deviceImportHandler := handlers.NewDeviceImportHandler(svc.DeviceImports)
+	userDataHandler := handlers.NewUserDataHandler(svc.UserData)
+	deadLetterHandler := handlers.NewDeadLetterHandler(svc.DeadLetters, svc.PushQueue)
+	chaosHandler := handlers.NewChaosHandler(deps.Faults, deps.Broker)
 
 	// Health check
 	router.GET("/health", handlers.HealthCheck)
-	router.GET("/ready", handlers.ReadinessCheck(db))
+	router.GET("/health/details", handlers.HealthDetails(pushWorker))
+	router.GET("/ready", handlers.ReadinessCheck(db, fcmClient))
+
+	// Prometheus metrics
+	router.GET("/metrics", metrics.Handler())
 
-	// Swagger documentation
+	// Swagger documentation, and the OpenAPI 3.0 document for client generators
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/openapi.json", handlers.OpenAPI)
 
 	// API v1 routes
 	v1 := router.Group("/v1")
 	{
 		v1.POST("/devices", deviceHandler.RegisterDevice)
 		v1.DELETE("/devices/:token", deviceHandler.UnregisterDevice)
+		v1.POST("/devices/:token/heartbeat", deviceHandler.Heartbeat)
 		v1.GET("/devices", deviceHandler.GetUserDevices)
-		v1.POST("/push/send", pushHandler.SendPush)
-		v1.POST("/push/send-bulk", pushHandler.SendBulkPush)
-		v1.GET("/queue/stats", pushHandler.GetQueueStats)
-		v1.POST("/push/test-direct", pushHandler.TestDirectSend)
+		v1.POST("/mutes", muteHandler.MuteUser)
+		v1.GET("/mutes", muteHandler.GetUserMutes)
+		v1.DELETE("/mutes/:id", muteHandler.UnmuteUser)
+		v1.POST("/push/send", internalOnly, pushHandler.SendPush)
+		v1.POST("/push/send-bulk", internalOnly, pushHandler.SendBulkPush)
+		v1.POST("/push/send-bundle", internalOnly, pushHandler.SendBundle)
+		v1.POST("/push/raw", internalOnly, pushHandler.SendRaw)
+		v1.GET("/push/dry-runs/:id", pushHandler.GetDryRun)
+		v1.GET("/notifications/:id", pushHandler.GetNotification)
+		v1.GET("/notifications/:id/tokens", internalOnly, pushHandler.GetNotificationTokens)
+		v1.POST("/campaigns", internalOnly, campaignHandler.CreateCampaign)
+		v1.GET("/campaigns/:id", campaignHandler.GetCampaign)
+		v1.POST("/campaigns/:id/cancel", internalOnly, campaignHandler.CancelCampaign)
+		v1.POST("/campaigns/:id/continue", internalOnly, campaignHandler.ContinueCampaign)
+		v1.GET("/queue/stats", internalOnly, pushHandler.GetQueueStats)
+		v1.GET("/analytics/summary", analyticsHandler.GetSummary)
+		v1.GET("/analytics/campaigns/:id/variants", analyticsHandler.GetCampaignVariants)
+		v1.POST("/webhooks", internalOnly, webhookHandler.CreateWebhook)
+		v1.GET("/webhooks", internalOnly, webhookHandler.ListWebhooks)
+		v1.DELETE("/webhooks/:id", internalOnly, webhookHandler.DeleteWebhook)
+		v1.GET("/inbox", inboxHandler.GetInbox)
+		v1.POST("/inbox/read-all", inboxHandler.MarkAllRead)
+		v1.POST("/inbox/:id/read", inboxHandler.MarkRead)
+		v1.GET("/ws", realtimeHandler.Connect)
+		v1.GET("/stream", realtimeHandler.Stream)
+		v1.POST("/push/test-direct", internalOnly, pushHandler.TestDirectSend)
+		v1.DELETE("/users/:id/data", internalOnly, userDataHandler.DeleteUserData)
+
+		sdk := v1.Group("/sdk")
+		sdk.POST("/tokens", sdkHandler.RegisterToken)
+		sdk.POST("/tokens/refresh", sdkHandler.RefreshToken)
+		sdk.PUT("/tokens/:token/permission", sdkHandler.UpdatePermission)
+		sdk.GET("/tokens/:token/config", sdkHandler.GetDeviceConfig)
+		sdk.POST("/opens", analyticsHandler.RecordOpen)
+
+		admin := v1.Group("/admin", internalOnly)
+		if pushWorker != nil {
+			admin.GET("/worker", adminHandler.GetWorkerSettings)
+			admin.PUT("/worker", adminHandler.UpdateWorkerSettings)
+			admin.GET("/worker/autoscale", adminHandler.GetWorkerAutoscale)
+		}
+		admin.GET("/consumers", adminHandler.ListConsumers)
+		admin.GET("/fcm/status", adminHandler.GetProviderStatus)
+		admin.POST("/fcm/reload", adminHandler.ReloadProviderCredentials)
+		admin.GET("/fcm/sandbox/sends", adminHandler.ListSandboxSends)
+		admin.DELETE("/fcm/sandbox/sends", adminHandler.ResetSandboxSends)
+		admin.POST("/config/reload", adminHandler.ReloadConfig)
+		admin.GET("/chaos", chaosHandler.GetChaos)
+		admin.PUT("/chaos", chaosHandler.UpdateChaos)
+		admin.POST("/chaos/kill-channels", chaosHandler.KillChannels)
+		admin.GET("/alert-rules", alertHandler.ListAlertRules)
+		admin.POST("/alert-rules", alertHandler.CreateAlertRule)
+		admin.DELETE("/alert-rules/:name", alertHandler.DeleteAlertRule)
+		admin.GET("/webhook-deliveries", webhookHandler.ListDeliveries)
+		admin.GET("/tenants/:id", tenantHandler.GetTenant)
+		admin.PUT("/tenants/:id", tenantHandler.UpdateTenant)
+		admin.GET("/devices", deviceHandler.ListDevices)
+		admin.GET("/devices/export", deviceHandler.ExportDevices)
+		admin.POST("/devices/imports", deviceImportHandler.ImportDevices)
+		admin.GET("/devices/imports", deviceImportHandler.ListDeviceImports)
+		admin.GET("/devices/imports/:id", deviceImportHandler.GetDeviceImport)
+		admin.GET("/delivery-events", pushHandler.ListDeliveryEvents)
+		admin.GET("/dead-letters", deadLetterHandler.ListDeadLetters)
+		admin.POST("/dead-letters/replay", deadLetterHandler.ReplayDeadLetters)
+		admin.GET("/dead-letters/archive", deadLetterHandler.ListArchivedDeadLetters)
 	}
 
 	return router
 }
 
-func startPushWorker(rabbitmqClient *rabbitmq.RabbitMQClient, fcmClient fcm.FCMClient, db *database.DB, cfg *config.Config) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Initialize repositories and services for worker
-	deviceRepo := repository.NewDeviceRepository(db.Pool)
-	pushQueue, err := queue.NewPushQueue(rabbitmqClient, &cfg.Queue)
-	if err != nil {
-		logger.L().Fatal("Failed to initialize push queue in worker", zap.Error(err))
-	}
-	pushService := service.NewPushService(deviceRepo, fcmClient, pushQueue, cfg)
-
-	logger.L().Info("Starting push worker...",
-		zap.Int("prefetch_count", cfg.Queue.Worker.PrefetchCount),
-	)
+func startPushWorker(pushWorker *worker.Worker) {
+	logger.L().Info("Starting push worker...")
 
-	// Start consuming messages from internal queue
-	msgs, err := pushQueue.ConsumePush(ctx)
-	if err != nil {
-		logger.L().Fatal("Failed to start consuming messages from internal queue", zap.Error(err))
+	if err := pushWorker.Start(context.Background()); err != nil {
+		logger.L().Fatal("Failed to start push worker", zap.Error(err))
 	}
+}
 
-	// Process internal queue messages in a goroutine
-	go func() {
-		for delivery := range msgs {
-			// Process each message
-			if err := pushService.ProcessPushFromQueue(ctx, delivery); err != nil {
-				logger.L().Error("Failed to process push message from queue",
-					zap.Error(err),
-					zap.Uint64("delivery_tag", delivery.DeliveryTag),
-				)
-			}
-		}
-	}()
+// stopPushWorker stops consuming and waits for the messages being processed,
+// up to QUEUE_WORKER_DRAIN_TIMEOUT
+func stopPushWorker(pushWorker *worker.Worker, cfg *config.WorkerConfig) {
+	logger.L().Info("Push worker shutting down...")
 
-	// Start consuming messages from API Gateway queue
-	gatewayMsgs, err := pushQueue.ConsumeFromGateway(ctx)
-	if err != nil {
-		logger.L().Fatal("Failed to start consuming messages from gateway queue", zap.Error(err))
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancel()
+	if err := pushWorker.Stop(ctx); err != nil {
+		logger.L().Warn("Push worker cancelled messages still being processed", zap.Error(err))
 	}
-
-	// Process gateway messages in a goroutine
-	go func() {
-		for delivery := range gatewayMsgs {
-			// Process each gateway message
-			if err := pushService.ProcessGatewayMessage(ctx, delivery); err != nil {
-				logger.L().Error("Failed to process gateway message",
-					zap.Error(err),
-					zap.Uint64("delivery_tag", delivery.DeliveryTag),
-				)
-			}
-		}
-	}()
-
-	logger.L().Info("Push workers started (internal and gateway queues)")
-
-	// Wait for context cancellation (graceful shutdown)
-	<-ctx.Done()
-	logger.L().Info("Push worker shutting down...")
 }
 
-func loggerMiddleware() gin.HandlerFunc {
+func loggerMiddleware(redact bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 
 		// Process request
 		c.Next()
 
+		// Paths can hold tokens, e.g. /v1/devices/{token}, so the matched
+		// route is logged instead; it is empty if no route matched
+		path := c.Request.URL.Path
+		if redact {
+			path = c.FullPath()
+		}
+
 		// Log after request completion
 		logger.L().Info("HTTP request",
 			zap.String("method", c.Request.Method),
-			zap.String("path", c.Request.URL.Path),
+			zap.String("path", path),
 			zap.Int("status", c.Writer.Status()),
 			zap.Duration("duration", time.Since(start)),
 			zap.String("client_ip", c.ClientIP()),
@@ -0,0 +1,177 @@
+// Command worker consumes the push queues and runs the background jobs,
+// without the HTTP API, so consumers can be scaled apart from the API. Run
+// the API with SERVER_RUN_WORKER=false next to it.
+//
+// It serves /health, /health/details, /ready, /metrics and the worker's
+// admin API on QUEUE_WORKER_PORT.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"push-service/internal/app"
+	"push-service/internal/config"
+	"push-service/internal/handlers"
+	"push-service/internal/reload"
+	"push-service/internal/worker"
+	"push-service/pkg/database"
+	"push-service/pkg/logger"
+	"push-service/pkg/metrics"
+	"push-service/pkg/realtime"
+	"push-service/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON config file; environment variables override its settings")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize logger
+	if err := logger.InitGlobal(cfg.Log.Level, cfg.Log.Format, cfg.Log.Redact); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.L().Sync()
+	if !cfg.Log.Redact {
+		logger.L().Warn("Log redaction is off: push tokens, user IDs and notification content are logged in full")
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), &cfg.Tracing)
+	if err != nil {
+		logger.L().Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
+	// Respect container CPU/memory limits before sizing any pools
+	worker.ConfigureRuntime(&cfg.Queue.Worker)
+
+	gin.SetMode(cfg.Server.Mode)
+
+	// Apply database migrations
+	if cfg.Database.AutoMigrate {
+		if err := app.WaitForDatabase(cfg); err != nil {
+			logger.L().Fatal("Database not available", zap.Error(err))
+		}
+		if err := database.Migrate(&cfg.Database); err != nil {
+			logger.L().Fatal("Failed to migrate database", zap.Error(err))
+		}
+	}
+
+	if cfg.Realtime.Enabled && cfg.Realtime.Bus != realtime.BusRedis {
+		logger.L().Warn("Realtime events published by a separate worker only reach the API's connections over the redis bus",
+			zap.String("bus", cfg.Realtime.Bus),
+		)
+	}
+
+	// Connect to the database, the queue broker, FCM and the other backends
+	deps, err := app.Open(cfg)
+	if err != nil {
+		logger.L().Fatal("Failed to initialize dependencies", zap.Error(err))
+	}
+	defer deps.Close()
+
+	svc, err := app.NewServices(deps, cfg)
+	if err != nil {
+		logger.L().Fatal("Failed to initialize services", zap.Error(err))
+	}
+	pushWorker := app.NewPushWorker(deps, svc, cfg)
+
+	// Reload the tunable settings on SIGHUP or from the admin API
+	reloader := reload.New(*configFile, cfg, pushWorker, deps.Projects)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go reloader.Watch(watchCtx)
+
+	srv := &http.Server{
+		Addr:    ":" + cfg.Queue.Worker.Port,
+		Handler: setupRouter(deps, pushWorker, reloader, cfg),
+	}
+	go func() {
+		logger.L().Info("Starting worker HTTP server", zap.String("port", cfg.Queue.Worker.Port))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.L().Fatal("Failed to start worker HTTP server", zap.Error(err))
+		}
+	}()
+
+	// Start consuming
+	logger.L().Info("Starting push worker...")
+	if err := pushWorker.Start(context.Background()); err != nil {
+		logger.L().Fatal("Failed to start push worker", zap.Error(err))
+	}
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.L().Info("Shutting down worker...")
+
+	// Stop consuming and let the messages being processed finish; health
+	// and metrics stay up meanwhile
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.Queue.Worker.DrainTimeout)
+	defer cancelDrain()
+	if err := pushWorker.Stop(drainCtx); err != nil {
+		logger.L().Warn("Push worker cancelled messages still being processed", zap.Error(err))
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.L().Error("Worker HTTP server forced to shutdown", zap.Error(err))
+	}
+
+	logger.L().Info("Worker exited properly")
+}
+
+// setupRouter serves the health checks, metrics and the admin endpoints
+// that act on this worker
+func setupRouter(deps *app.Deps, pushWorker *worker.Worker, reloader *reload.Reloader, cfg *config.Config) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	if err := router.SetTrustedProxies(cfg.Access.TrustedProxies); err != nil {
+		logger.L().Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+	internalOnly, err := handlers.InternalOnly(&cfg.Access)
+	if err != nil {
+		logger.L().Fatal("Invalid internal access settings", zap.Error(err))
+	}
+
+	adminHandler := handlers.NewAdminHandler(pushWorker, deps.FCMClient, reloader)
+	chaosHandler := handlers.NewChaosHandler(deps.Faults, deps.Broker)
+
+	router.GET("/health", handlers.HealthCheck)
+	router.GET("/health/details", handlers.HealthDetails(pushWorker))
+	router.GET("/ready", handlers.ReadinessCheck(deps.DB, deps.FCMClient))
+	router.GET("/metrics", metrics.Handler())
+
+	admin := router.Group("/v1/admin", internalOnly)
+	admin.GET("/worker", adminHandler.GetWorkerSettings)
+	admin.PUT("/worker", adminHandler.UpdateWorkerSettings)
+	admin.GET("/worker/autoscale", adminHandler.GetWorkerAutoscale)
+	admin.GET("/consumers", adminHandler.ListConsumers)
+	admin.GET("/fcm/status", adminHandler.GetProviderStatus)
+	admin.POST("/fcm/reload", adminHandler.ReloadProviderCredentials)
+	admin.GET("/fcm/sandbox/sends", adminHandler.ListSandboxSends)
+	admin.DELETE("/fcm/sandbox/sends", adminHandler.ResetSandboxSends)
+	admin.POST("/config/reload", adminHandler.ReloadConfig)
+	admin.GET("/chaos", chaosHandler.GetChaos)
+	admin.PUT("/chaos", chaosHandler.UpdateChaos)
+	admin.POST("/chaos/kill-channels", chaosHandler.KillChannels)
+
+	return router
+}
@@ -4,11 +4,11 @@ package swagger
 import "github.com/swaggo/swag"
 
 const docTemplate = `{
-    "schemes": {{ marshal .Schemes }},
+    "schemes": [[ marshal .Schemes ]],
     "swagger": "2.0",
     "info": {
-        "description": "{{escape .Description}}",
-        "title": "{{.Title}}",
+        "description": "[[escape .Description]]",
+        "title": "[[.Title]]",
         "contact": {
             "name": "API Support",
             "email": "support@example.com"
@@ -17,10 +17,10 @@ const docTemplate = `{
             "name": "MIT",
             "url": "https://opensource.org/licenses/MIT"
         },
-        "version": "{{.Version}}"
+        "version": "[[.Version]]"
     },
-    "host": "{{.Host}}",
-    "basePath": "{{.BasePath}}",
+    "host": "[[.Host]]",
+    "basePath": "[[.BasePath]]",
     "paths": {
         "/health": {
             "get": {
@@ -45,9 +45,35 @@ const docTemplate = `{
                 }
             }
         },
+        "/health/details": {
+            "get": {
+                "description": "Returns the queue worker's progress: when it last finished a message, the messages in flight and waiting, its consumers and broker reconnects. Responds 503 while the worker is stalled, i.e. messages are in flight or waiting but none finished within QUEUE_WORKER_STALL_TIMEOUT, or stopped.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Detailed health check endpoint",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.HealthDetailsResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.HealthDetailsResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/ready": {
             "get": {
-                "description": "Returns the readiness status of the service including database connectivity",
+                "description": "Returns the readiness status of the service including database (and read replica) connectivity, the database schema version and FCM credential status",
                 "consumes": [
                     "application/json"
                 ],
@@ -74,37 +100,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/devices": {
+        "/v1/admin/alert-rules": {
             "get": {
-                "description": "Get all registered devices for a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List the built-in alert rules that are on and the rules from the config file and the admin API, with the outcome of their last evaluation",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "devices"
-                ],
-                "summary": "Get user devices",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "user_id",
-                        "in": "query",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "List alert rules",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.GetUserDevicesResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AlertRule"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "User ID is required",
+                    "403": {
+                        "description": "Not an internal client",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -113,7 +130,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Failed to get user devices",
+                        "description": "Failed to list alert rules",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -124,7 +141,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Register a device token for push notifications",
+                "description": "Add a rule that calls a webhook when a delivery metric over a window crosses a threshold, e.g. failure_rate \u003e 0.2 for platform=ios over 10m, or dead_letters \u003e 1000 over 1h. The webhook is called again when the rule resolves.",
                 "consumes": [
                     "application/json"
                 ],
@@ -132,17 +149,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "devices"
+                    "admin"
                 ],
-                "summary": "Register a new device",
+                "summary": "Create an alert rule",
                 "parameters": [
                     {
-                        "description": "Device registration request",
+                        "description": "Alert rule",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateDeviceRequest"
+                            "$ref": "#/definitions/models.CreateAlertRuleRequest"
                         }
                     }
                 ],
@@ -150,11 +167,29 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handlers.RegisterDeviceResponse"
+                            "$ref": "#/definitions/models.AlertRule"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid alert rule",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Alert rule already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -163,7 +198,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Failed to register device",
+                        "description": "Failed to create alert rule",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -174,31 +209,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/devices/{token}": {
+        "/v1/admin/alert-rules/{name}": {
             "delete": {
-                "description": "Unregister a device token (soft delete)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Delete an alert rule created through the admin API. Rules from the config file can only be removed there.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "devices"
+                    "admin"
                 ],
-                "summary": "Unregister a device",
+                "summary": "Delete an alert rule",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Device token",
-                        "name": "token",
+                        "description": "Rule name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Device unregistered successfully",
+                        "description": "Alert rule deleted successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -206,8 +238,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Device token is required",
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Alert rule not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -216,7 +257,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Failed to unregister device",
+                        "description": "Failed to delete alert rule",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -227,9 +268,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/push/send": {
-            "post": {
-                "description": "Send a push notification to a user's devices via RabbitMQ queue",
+        "/v1/admin/chaos": {
+            "get": {
+                "description": "With CHAOS_ENABLED, get this instance's fault injection rates and how many faults of each kind it injected",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get fault injection settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChaosResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Fault injection is off",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "With CHAOS_ENABLED, change this instance's fault injection rates: the shares of FCM requests delayed by fcm_delay or failed with INTERNAL, of queue acks dropped, and of acks that close the broker channels instead. Set a rate to 0 to stop that fault. Changes last until the restart.",
                 "consumes": [
                     "application/json"
                 ],
@@ -237,23 +314,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "push"
+                    "admin"
                 ],
-                "summary": "Send push notification",
+                "summary": "Update fault injection settings",
                 "parameters": [
                     {
-                        "description": "Push notification request",
+                        "description": "Fault injection settings",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.SendPushRequest"
+                            "$ref": "#/definitions/handlers.UpdateChaosRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Push notification enqueued successfully",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChaosResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or settings",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -261,17 +350,49 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Invalid request body",
+                    "404": {
+                        "description": "Fault injection is off",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/v1/admin/chaos/kill-channels": {
+            "post": {
+                "description": "With CHAOS_ENABLED, close this instance's RabbitMQ publisher and consumer channels now, as a channel error would. They are reopened, and the consumers' unacked messages are redelivered.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Close the broker channels",
+                "responses": {
+                    "204": {
+                        "description": "Channels closed"
                     },
-                    "500": {
-                        "description": "Failed to send push notification",
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Fault injection is off",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "The queue backend has no channels",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -282,40 +403,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/push/send-bulk": {
+        "/v1/admin/config/reload": {
             "post": {
-                "description": "Send push notifications to multiple users via RabbitMQ queue",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Re-read the configuration file and environment and apply the log level, worker prefetch count and concurrency, retry limits and FCM send rate caps without restarting. Other changed settings apply after a restart. SIGHUP does the same.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "push"
-                ],
-                "summary": "Send bulk push notifications",
-                "parameters": [
-                    {
-                        "description": "Bulk push notification request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.BulkPushRequest"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Reload configuration",
                 "responses": {
                     "200": {
-                        "description": "Bulk push notifications enqueued successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/reload.Result"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid configuration; nothing was applied",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -323,8 +429,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Failed to send bulk push notifications",
+                    "403": {
+                        "description": "Not an internal client",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -335,33 +441,62 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/push/test-direct": {
-            "post": {
-                "description": "Send a test push notification directly via FCM (bypasses queue, for testing only)",
-                "consumes": [
+        "/v1/admin/consumers": {
+            "get": {
+                "description": "List this instance's active queue consumers with their broker consumer tags and how many of their messages are unacknowledged, to find the replica holding unacked messages",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List queue consumers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ConsumersResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/dead-letters": {
+            "get": {
+                "description": "Get the messages at the head of the dead letter queue, oldest first, without removing them: each message's notification, retry count and masked device tokens, and why messages that can't be decoded couldn't. At most the worker's prefetch count are returned. The messages are put back afterwards, at the end of the queue on Kafka.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "push"
+                    "admin"
                 ],
-                "summary": "Test direct FCM send",
+                "summary": "Peek at the dead letter queue",
                 "parameters": [
                     {
-                        "description": "Direct send request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object"
-                        }
+                        "type": "integer",
+                        "description": "Maximum messages to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "FCM test message sent successfully",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeadLetters"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -369,8 +504,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Invalid request body",
+                    "403": {
+                        "description": "Not an internal client",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -379,7 +514,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "FCM send failed",
+                        "description": "Failed to peek at dead letters",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -390,29 +525,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/queue/stats": {
+        "/v1/admin/dead-letters/archive": {
             "get": {
-                "description": "Get statistics for all push notification queues (main, retry, dead letter)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get where a notification's dead letters were archived: the gzipped NDJSON object holding each and its line in the object. Dead letters moved out of the dead letter queue but not yet written to the bucket are listed without an object.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "queue"
+                    "admin"
+                ],
+                "summary": "Find a notification's archived dead letters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Notification ID",
+                        "name": "notification_id",
+                        "in": "query",
+                        "required": true
+                    }
                 ],
-                "summary": "Get queue statistics",
                 "responses": {
                     "200": {
-                        "description": "Queue statistics",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ArchivedDeadLetters"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing notification_id",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Failed to get queue statistics",
+                        "description": "Failed to list archived dead letters",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -422,22 +580,5353 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.GetUserDevicesResponse": {
-            "description": "User devices response",
-            "type": "object",
+        },
+        "/v1/admin/dead-letters/replay": {
+            "post": {
+                "description": "Move up to limit messages from the head of the dead letter queue back to the push queues of their priorities, with their retries reset, e.g. once an FCM incident is over. Messages that can't be decoded are left in the queue and counted as skipped.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replay dead letters",
+                "parameters": [
+                    {
+                        "description": "How many dead letters to replay",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReplayDeadLettersRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeadLetterReplay"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to replay dead letters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/delivery-events": {
+            "get": {
+                "description": "Get the delivery attempts of every notification recorded after the attempt with ID after, oldest first, each with the status it left its notification in. Without after, get the last ones. Pass the response's after to get the attempts recorded since, e.g. to follow deliveries as they happen. Tokens are masked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List recent delivery attempts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Only attempts recorded after the attempt with this ID",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum attempts to return (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeliveryEvents"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid after or limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list delivery attempts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/devices": {
+            "get": {
+                "description": "List device records, active or not, optionally filtered, a page at a time in device ID order, to investigate registrations. Pass the returned next_cursor as cursor to get the next page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List devices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only this user's devices",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only devices of users whose ID starts with this",
+                        "name": "user_id_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only devices on this platform (ios, android or web)",
+                        "name": "platform",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only active (true) or deactivated (false) devices",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only devices last seen at or after this time (RFC 3339)",
+                        "name": "seen_since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only devices registered before this time (RFC 3339)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum devices to return (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DevicePage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, limit or cursor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list devices",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/devices/export": {
+            "get": {
+                "description": "Stream every device record, active or not, optionally filtered, as NDJSON (one device per line) or CSV, in device ID order. If the download breaks off, pass the id of the last device received as cursor to resume after it. The CSV has a header and can be imported with POST /v1/admin/devices/imports.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export devices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ndjson (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only this user's devices",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only devices of users whose ID starts with this",
+                        "name": "user_id_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only devices on this platform (ios, android or web)",
+                        "name": "platform",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only active (true) or deactivated (false) devices",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only devices last seen at or after this time (RFC 3339)",
+                        "name": "seen_since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only devices registered before this time (RFC 3339)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last device already received",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "NDJSON or CSV device records",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid export parameters or cursor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to export devices",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/devices/imports": {
+            "get": {
+                "description": "List the most recent device imports, newest first, with their progress",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List device imports",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum imports to return (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListDeviceImportsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list device imports",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Stream an NDJSON file (one {\"user_id\",\"token\",\"platform\"} object per line) or a CSV file with a user_id,token,platform header as the request body. Rows are validated and upserted in batches: registered tokens are moved to the row's user and platform and reactivated. Invalid rows are skipped and counted. The request returns when the import finishes; follow its progress meanwhile with GET /v1/admin/devices/imports.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import devices in bulk",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ndjson or csv; defaults from the Content-Type (application/x-ndjson or text/csv)",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceImport"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown import format or invalid import file",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to import devices",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/devices/imports/{id}": {
+            "get": {
+                "description": "Get a device import's status, row counts and the first rejected rows",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a device import",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Import ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceImport"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Device import not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get device import",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/fcm/reload": {
+            "post": {
+                "description": "Re-read the FCM service account and verify it with a validate-only probe; delivery resumes if it succeeds",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reload FCM credentials",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/fcm.ProviderStatus"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Credentials still rejected",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/fcm/sandbox/sends": {
+            "get": {
+                "description": "With FCM_MODE=sandbox, list the most recent sends this instance made to the simulated FCM of the primary project, newest first, with the simulated errors",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List FCM sandbox sends",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum sends to return (default 50, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SandboxSendsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "FCM sandbox mode is off",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "With FCM_MODE=sandbox, forget the sends this instance recorded",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Clear FCM sandbox sends",
+                "responses": {
+                    "204": {
+                        "description": "Sends cleared"
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "FCM sandbox mode is off",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/fcm/status": {
+            "get": {
+                "description": "Report whether FCM is currently accepting the service credentials",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get FCM provider status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/fcm.ProviderStatus"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/tenants/{id}": {
+            "get": {
+                "description": "Get whether a tenant is in soft launch, its daily send cap, test users and sends so far today. Tenants that were never updated are reported with the defaults.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get tenant soft launch settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tenant"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get tenant",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Set a tenant's soft launch daily send cap and test users, or lift soft launch with soft_launch=false. While in soft launch, a tenant's sends go to its test users' devices only, bulk sends are dry runs and campaigns are rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update tenant soft launch settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tenant settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateTenantRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tenant"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update tenant",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/webhook-deliveries": {
+            "get": {
+                "description": "Get this instance's most recent webhook delivery attempts, newest first, and the webhooks whose circuit is open",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List recent webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only deliveries to this webhook",
+                        "name": "webhook_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum deliveries to return (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListWebhookDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/worker": {
+            "get": {
+                "description": "Get the queue worker's current prefetch count, pool size and active handlers",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get worker settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/worker.Settings"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Change the queue worker's prefetch count and/or pool size at runtime without redeploying",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update worker settings",
+                "parameters": [
+                    {
+                        "description": "Worker settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateWorkerSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/worker.Settings"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, settings that aren't positive or concurrency above resource limit",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update worker settings",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/worker/autoscale": {
+            "get": {
+                "description": "Get the queue backlog and how busy the worker's pool was at the last autoscale reading, and the pool size the controller wants, e.g. for KEDA's metrics-api scaler",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get worker load",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/worker.AutoscaleStatus"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "No reading yet",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/analytics/campaigns/{id}/variants": {
+            "get": {
+                "description": "Get the sent, delivered, failed and opened counts of each variant of a campaign, with its open rate (opened over delivered). Opens are counted when the client SDK reports the campaign_id and variant from the notification's data.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Compare a campaign's A/B variants",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CampaignVariantReport"
+                        }
+                    },
+                    "404": {
+                        "description": "Campaign not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get analytics",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/analytics/summary": {
+            "get": {
+                "description": "Get the sent, delivered, failed and opened counts per UTC day and platform from the worker's rollups, with their totals overall and per platform. Sent counts every token attempted, retries included; opened counts opens reported by the client SDK. Without dates the summary covers the last 7 days; a range covers at most 366 days.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Get delivery analytics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day, inclusive (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day, inclusive (YYYY-MM-DD); defaults to today",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AnalyticsSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid date range",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get analytics",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/campaigns": {
+            "post": {
+                "description": "Send a notification to a large audience. Users are enqueued gradually, paced against each FCM project's daily quota: the spread policy carries the overflow into the following days, the failover policy sends it through the failover projects first. analytics_label segments the campaign's deliveries in Firebase reporting. With variants, each user gets one variant's title and body, split by the variants' weights. With a canary, a random share of the audience is sent to first; the campaign then soaks until the soak has passed or it is continued.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "Launch a campaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID; tenants in soft launch can't launch campaigns",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Campaign request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateCampaignRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Campaign"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create campaign",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/campaigns/{id}": {
+            "get": {
+                "description": "Get a campaign's progress and, while it is running, its projected completion time given the remaining FCM quota",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "Get campaign status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Campaign"
+                        }
+                    },
+                    "404": {
+                        "description": "Campaign not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get campaign",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/campaigns/{id}/cancel": {
+            "post": {
+                "description": "Stop enqueueing a running or soaking campaign. Users already enqueued are still delivered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "Cancel a campaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Campaign cancelled successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Campaign not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to cancel campaign",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/campaigns/{id}/continue": {
+            "post": {
+                "description": "Send a soaking campaign to the rest of its audience now, instead of waiting for its soak to pass. Campaigns with a canary but no soak wait for this call.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "Continue a campaign after its canary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Campaign continued successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Campaign not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Campaign is not soaking",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to continue campaign",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/devices": {
+            "get": {
+                "description": "Get all registered devices for a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "devices"
+                ],
+                "summary": "Get user devices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetUserDevicesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "User ID is required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get user devices",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Register a device token for push notifications",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "devices"
+                ],
+                "summary": "Register a new device",
+                "parameters": [
+                    {
+                        "description": "Device registration request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateDeviceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RegisterDeviceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to register device",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/devices/{token}": {
+            "delete": {
+                "description": "Unregister a device token (soft delete)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "devices"
+                ],
+                "summary": "Unregister a device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Device unregistered successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Device token is required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to unregister device",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/devices/{token}/heartbeat": {
+            "post": {
+                "description": "Record that the app is still running on the device, so it counts as recently active. Registration and token refresh count as well. Devices that stop checking in are zombie registrations.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "devices"
+                ],
+                "summary": "Record a device heartbeat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.HeartbeatResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Device not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to record heartbeat",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/inbox": {
+            "get": {
+                "description": "Get the notifications the user was sent, newest first, with their read state and the user's unread count. Pass the returned next_cursor as cursor to get the next page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inbox"
+                ],
+                "summary": "Get a user's inbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum items to return (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only unread items",
+                        "name": "unread_only",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.InboxPage"
+                        }
+                    },
+                    "400": {
+                        "description": "User ID is required, or invalid limit or cursor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get inbox",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/inbox/read-all": {
+            "post": {
+                "description": "Mark all of the user's unread inbox items read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inbox"
+                ],
+                "summary": "Mark a user's inbox read",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "User ID is required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to mark inbox read",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/inbox/{id}/read": {
+            "post": {
+                "description": "Mark one of the user's inbox items read. Marking an item that is already read keeps its read time.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inbox"
+                ],
+                "summary": "Mark an inbox item read",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Inbox item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "User ID is required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Inbox item not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to mark inbox item read",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/mutes": {
+            "get": {
+                "description": "Get a user's active sender and category mutes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mutes"
+                ],
+                "summary": "Get user mutes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetUserMutesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "User ID is required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get user mutes",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Stop delivering a user's notifications from one sender or category, without opting them out of push",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mutes"
+                ],
+                "summary": "Mute a sender or category",
+                "parameters": [
+                    {
+                        "description": "Mute request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateMuteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserMute"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to mute",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/mutes/{id}": {
+            "delete": {
+                "description": "Resume delivery for a previously muted sender or category",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mutes"
+                ],
+                "summary": "Remove a mute",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mute ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Mute removed successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "User ID is required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Mute not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to remove mute",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/notifications/{id}": {
+            "get": {
+                "description": "Get every delivery attempt of a notification, oldest first: when it was made, the queue the message came from (e.g. a retry tier), its retry number, each token's result and where the message went next. The status is derived from the last attempt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Get notification delivery attempts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Notification ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Notification not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get notification",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/notifications/{id}/tokens": {
+            "get": {
+                "description": "Get the latest delivery result of a notification for each of its device tokens: whether it was delivered, FCM's message ID, or the error kind, FCM error code and error of its last failure, and how many attempts were made. Pass a device's token to see only its result. Tokens are masked in the response.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Get notification results per device token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Notification ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only this device token's result",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationTokenResults"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Notification not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get notification token results",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/push/dry-runs/{id}": {
+            "get": {
+                "description": "Get the FCM validation result for every device token of a dry run. The status is pending until the worker has processed all of the dry run's messages.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Get dry run results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dry run ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DryRun"
+                        }
+                    },
+                    "404": {
+                        "description": "Dry run not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get dry run",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/push/raw": {
+            "post": {
+                "description": "Send a complete FCM v1 message (the \"message\" object of FCM's messages:send, without credentials) to the token, topic or condition it names. The message is sent as is, but still goes through the queue, retries and delivery attempts; look it up under the returned notification_id. Meant for teams migrating off direct FCM usage. Mutes don't apply, and tenants in soft launch can't send raw messages.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Send a raw FCM message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Raw FCM message",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RawPushRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Raw message enqueued successfully with its notification_id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SendPushResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or FCM message",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send raw message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/push/send": {
+            "post": {
+                "description": "Send a push notification to a user's devices via RabbitMQ queue. With dry_run, the notification goes through the queue and the worker but is only validated by FCM; the per-token results are available from /v1/push/dry-runs/{id} under the returned dry_run_id. max_retries and retry_backoff override the retry policy for this notification, e.g. to retry an OTP aggressively or send a marketing push to the dead letter queue on its first failure; max_retries is capped by the server. priority (critical, high, normal or low) selects the queue the notification waits in; higher priorities are processed first. Notifications with the same collapse_key replace each other on the device instead of stacking. A notification with a ttl (e.g. 10m) is dropped by the worker and by FCM once the ttl has passed, instead of being delivered late. Up to three actions add buttons to the notification, and click_action sets the Android intent action opened on tap. ios sets the APNs badge, sound, category and thread_id. platform_overrides holds FCM v1 android, apns and webpush objects that are merged verbatim over the configs built from the other fields. analytics_label segments the deliveries in Firebase reporting. With deliver_at_local_time (HH:MM), the notification is held back and delivered at that time in each device's timezone, or the server's default timezone for devices that haven't reported one. When digests are enabled, low priority notifications are buffered and delivered in the user's next digest, and the returned notification_id is the buffered item's.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Send push notification",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID; notifications of tenants in soft launch go to their test users' devices",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Push notification request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SendPushRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Push notification enqueued successfully with its notification_id (a dry_run_id for dry runs), or dropped with a drop_reason because the user muted its sender or category",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SendPushResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, retry policy, ttl or platform overrides",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Notification and data larger than FCM accepts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Soft launch daily send cap reached",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send push notification",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/push/send-bulk": {
+            "post": {
+                "description": "Send push notifications to multiple users via RabbitMQ queue. Users listed under users may carry their own vars, which replace the {{name}} placeholders in the title and body when each user's notification is processed. With dry_run, the notifications are only validated by FCM; see /v1/push/dry-runs/{id}. Bulk sends of tenants in soft launch are always dry runs. priority selects the queue the notifications wait in, and analytics_label segments their deliveries in Firebase reporting.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Send bulk push notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Bulk push notification request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BulkPushRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Bulk push notifications enqueued successfully (with a dry_run_id for dry runs)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SendBulkPushResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Notification and data larger than FCM accepts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send bulk push notifications",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/push/send-bundle": {
+            "post": {
+                "description": "Send related notifications (e.g. a badge update, a data sync and an alert) to a user's devices as one unit. The bundle is enqueued all-or-nothing and its items share a bundle_id. Items without a title and body are silent data messages and are delivered to each device before the visible ones; a device whose data message fails does not get the alert until the bundle is retried. priority selects the queue the bundle waits in.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Send a notification bundle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID; bundles of tenants in soft launch go to their test users' devices",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Push bundle request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SendBundleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Push bundle enqueued successfully, or dropped with a drop_reason because the user muted its sender or category",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SendBundleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Soft launch daily send cap reached",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send push bundle",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/push/test-direct": {
+            "post": {
+                "description": "Send a test push notification directly via FCM (bypasses queue, for testing only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Test direct FCM send",
+                "parameters": [
+                    {
+                        "description": "Direct send request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "FCM test message sent successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "FCM send failed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/queue/stats": {
+            "get": {
+                "description": "Get statistics for all push notification queues (main, retry, dead letter)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "Get queue statistics",
+                "responses": {
+                    "200": {
+                        "description": "Queue statistics",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get queue statistics",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sdk/opens": {
+            "post": {
+                "description": "Count a notification opened on the device in today's delivery analytics. If platform is omitted it is detected from the X-Platform header or the User-Agent. For campaign variants, pass the campaign_id and variant from the notification's data to count the open towards the variant.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sdk"
+                ],
+                "summary": "Report a notification open from the client SDK",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device platform (ios, android or web)",
+                        "name": "X-Platform",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Notification open",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RecordOpenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Open recorded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to record open",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sdk/tokens": {
+            "post": {
+                "description": "Register a device token. If platform is omitted it is detected from the X-Platform header or the User-Agent. An initial permission status may be reported along with the token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sdk"
+                ],
+                "summary": "Register a token from the client SDK",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device platform (ios, android or web)",
+                        "name": "X-Platform",
+                        "in": "header"
+                    },
+                    {
+                        "description": "SDK token registration request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SDKRegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RegisterDeviceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or platform could not be detected",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to register device",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sdk/tokens/refresh": {
+            "post": {
+                "description": "Replace a device's token after FCM rotated it. The device keeps its ID, user and permission status.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sdk"
+                ],
+                "summary": "Refresh a rotated token",
+                "parameters": [
+                    {
+                        "description": "Token refresh request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RegisterDeviceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Device not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to refresh token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sdk/tokens/{token}/config": {
+            "get": {
+                "description": "Get the configuration the client SDK applies on this device",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sdk"
+                ],
+                "summary": "Get device config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Device not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get device config",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sdk/tokens/{token}/permission": {
+            "put": {
+                "description": "Store the device's OS notification permission status (granted, denied or provisional)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sdk"
+                ],
+                "summary": "Report notification permission",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Permission status",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdatePermissionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Permission status updated successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Device not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update permission status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/stream": {
+            "get": {
+                "description": "Stream the user's notifications as they are processed, as Server-Sent Events of type notification whose data is the same JSON event the WebSocket gets. For web dashboards that can't keep an FCM service worker. A stream counts as a connection for suppress_push_if_connected. Comments are sent as keepalives.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "realtime"
+                ],
+                "summary": "Receive notifications as Server-Sent Events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "$ref": "#/definitions/realtime.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "User ID is required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Origin not allowed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Realtime delivery is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/{id}/data": {
+            "delete": {
+                "description": "Hard-delete everything stored about a user, e.g. for a GDPR erasure request: their devices, notification history and delivery attempts, inbox and digest items, mutes, dry run results and scheduled pushes. The user is also removed from campaigns and tenants' test users. Messages already queued for the user are dropped instead of delivered. Deleting the data of a user with none succeeds with zero counts.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete a user's data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserDataDeletion"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete user data",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks": {
+            "get": {
+                "description": "Get the tenant's webhooks, without their secrets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List delivery status webhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListWebhooksResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list webhooks",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Have the tenant's notification events (queued, sent, failed, dead_lettered) POSTed to a URL. Requests are signed with the returned secret, which is not shown again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register a delivery status webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Webhook request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to register webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}": {
+            "delete": {
+                "description": "Stop sending the tenant's notification events to a webhook",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a delivery status webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an internal client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/ws": {
+            "get": {
+                "description": "Upgrade to a WebSocket that receives the user's notifications as they are processed, as JSON events of type notification whose data is the notification. Sends with suppress_push_if_connected skip the push while the user has a connection on any replica. The server pings the connection; messages from the client are ignored.",
+                "tags": [
+                    "realtime"
+                ],
+                "summary": "Receive notifications over a WebSocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching protocols; events follow on the socket",
+                        "schema": {
+                            "$ref": "#/definitions/realtime.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "User ID is required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Realtime delivery is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "fcm.ProviderStatus": {
+            "type": "object",
+            "properties": {
+                "failure_rate": {
+                    "type": "number",
+                    "example": 0.02
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "recent_sends": {
+                    "description": "RecentSends is the number of messages sent in the last minute, and\nFailureRate the share of them FCM failed on its side",
+                    "type": "integer",
+                    "example": 1200
+                },
+                "since": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                },
+                "throttled_until": {
+                    "description": "ThrottledUntil is set while sends are paused after a quota error",
+                    "type": "string"
+                }
+            }
+        },
+        "fcm.SandboxSend": {
+            "description": "A send recorded by the FCM sandbox",
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "World"
+                },
+                "condition": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "error_code": {
+                    "description": "ErrorCode is the simulated FCM error, e.g. UNREGISTERED",
+                    "type": "string",
+                    "example": "UNREGISTERED"
+                },
+                "message_id": {
+                    "description": "MessageID is what FCM would return; empty for failed sends",
+                    "type": "string",
+                    "example": "projects/sandbox/messages/0:1700000000000000%abc"
+                },
+                "project_id": {
+                    "type": "string",
+                    "example": "sandbox"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Hello"
+                },
+                "token": {
+                    "description": "Token is masked",
+                    "type": "string",
+                    "example": "dGVzdFRva2...VuMTIzNDU2"
+                },
+                "topic": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ChaosResponse": {
+            "description": "Fault injection settings and injected faults of one instance",
+            "type": "object",
+            "properties": {
+                "ack_drop_rate": {
+                    "type": "number",
+                    "example": 0.01
+                },
+                "channel_kill_rate": {
+                    "type": "number",
+                    "example": 0.001
+                },
+                "fcm_delay": {
+                    "type": "string",
+                    "example": "2s"
+                },
+                "fcm_delay_rate": {
+                    "type": "number",
+                    "example": 0.1
+                },
+                "fcm_failure_rate": {
+                    "type": "number",
+                    "example": 0.05
+                },
+                "injected": {
+                    "description": "Injected counts the faults injected since the start, by kind",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int64"
+                    }
+                }
+            }
+        },
+        "handlers.ConsumersResponse": {
+            "description": "Queue consumers of one instance",
+            "type": "object",
+            "properties": {
+                "consumers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/queue.ConsumerInfo"
+                    }
+                },
+                "host": {
+                    "type": "string",
+                    "example": "push-worker-7d9f"
+                }
+            }
+        },
+        "handlers.FieldError": {
+            "description": "A request field that failed validation, with the value it had and the constraint it broke",
+            "type": "object",
+            "properties": {
+                "constraint": {
+                    "description": "Constraint is the validation that failed, e.g. required, max, oneof,\nor type when the value has the wrong JSON type",
+                    "type": "string",
+                    "example": "required"
+                },
+                "field": {
+                    "description": "Field is the field's path in the request body, e.g. notification.title\nor device_tokens[2]",
+                    "type": "string",
+                    "example": "notification.title"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "notification.title is required"
+                },
+                "param": {
+                    "description": "Param is the constraint's parameter, e.g. 100 for max=100",
+                    "type": "string",
+                    "example": ""
+                },
+                "value": {
+                    "description": "Value is the rejected value; it is left out for missing fields",
+                    "type": "string",
+                    "example": ""
+                }
+            }
+        },
+        "handlers.GetUserDevicesResponse": {
+            "description": "User devices response",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeviceResponse"
+                    }
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "handlers.GetUserMutesResponse": {
+            "description": "User mutes response",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "mutes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UserMute"
+                    }
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "handlers.HealthDetailsResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "healthy"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00Z"
+                },
+                "worker": {
+                    "description": "Worker is omitted when this process runs no queue worker",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/worker.Health"
+                        }
+                    ]
+                }
+            }
+        },
+        "handlers.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "database": {
+                    "type": "string",
+                    "example": "healthy"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "ok"
+                },
+                "replica": {
+                    "type": "string",
+                    "example": "healthy"
+                },
+                "schema": {
+                    "description": "Schema is current, behind (migrations this build needs are missing),\ndirty (a migration failed partway) or unknown",
+                    "type": "string",
+                    "example": "current"
+                },
+                "schema_version": {
+                    "type": "integer",
+                    "example": 26
+                },
+                "status": {
+                    "type": "string",
+                    "example": "healthy"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00Z"
+                }
+            }
+        },
+        "handlers.HeartbeatResponse": {
+            "description": "Device heartbeat response",
+            "type": "object",
+            "properties": {
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Heartbeat recorded"
+                }
+            }
+        },
+        "handlers.ListDeviceImportsResponse": {
+            "description": "Recent device imports, newest first",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "imports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeviceImport"
+                    }
+                }
+            }
+        },
+        "handlers.ListWebhookDeliveriesResponse": {
+            "description": "Webhook deliveries response",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhook.Delivery"
+                    }
+                },
+                "open_circuits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhook.Circuit"
+                    }
+                }
+            }
+        },
+        "handlers.ListWebhooksResponse": {
+            "description": "List webhooks response",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Webhook"
+                    }
+                }
+            }
+        },
+        "handlers.RegisterDeviceResponse": {
+            "description": "Device registration response",
+            "type": "object",
+            "properties": {
+                "device": {
+                    "$ref": "#/definitions/models.DeviceResponse"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Device registered successfully"
+                }
+            }
+        },
+        "handlers.ReplayDeadLettersRequest": {
+            "description": "Dead letter replay request",
+            "type": "object",
+            "required": [
+                "limit"
+            ],
+            "properties": {
+                "limit": {
+                    "description": "Limit is how many messages to replay at most",
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 1,
+                    "example": 10
+                }
+            }
+        },
+        "handlers.SandboxSendsResponse": {
+            "description": "Sends recorded by the FCM sandbox",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "sends": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fcm.SandboxSend"
+                    }
+                },
+                "total": {
+                    "description": "Total counts every send since the start or the last reset, including\nthose no longer kept",
+                    "type": "integer",
+                    "example": 1520
+                }
+            }
+        },
+        "handlers.SendBulkPushResponse": {
+            "description": "Bulk send accepted: every user's notification enqueued, or a dry run with its dry_run_id",
+            "type": "object",
+            "properties": {
+                "dry_run_id": {
+                    "type": "string",
+                    "example": "5d0f3f4e-2c71-4c8b-9a51-6b1f0c7e2a90"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Bulk push notifications sent successfully"
+                },
+                "user_count": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "handlers.SendBundleResponse": {
+            "description": "Bundle accepted: enqueued with its bundle_id, or dropped with a drop_reason",
+            "type": "object",
+            "properties": {
+                "bundle_id": {
+                    "type": "string",
+                    "example": "3c2b7d1e-9f4a-4e55-8b0c-1d2e3f4a5b6c"
+                },
+                "drop_reason": {
+                    "type": "string",
+                    "example": "muted_category"
+                },
+                "item_count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Push bundle sent successfully"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "handlers.SendPushResponse": {
+            "description": "Send accepted: enqueued with its notification_id, enqueued as a dry run with its dry_run_id, or dropped with a drop_reason",
+            "type": "object",
+            "properties": {
+                "drop_reason": {
+                    "type": "string",
+                    "example": "muted_sender"
+                },
+                "dry_run_id": {
+                    "type": "string",
+                    "example": "5d0f3f4e-2c71-4c8b-9a51-6b1f0c7e2a90"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Push notification sent successfully"
+                },
+                "notification_id": {
+                    "type": "string",
+                    "example": "0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "handlers.UpdateChaosRequest": {
+            "description": "Fault injection settings update (omitted fields are unchanged)",
+            "type": "object",
+            "properties": {
+                "ack_drop_rate": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0.01
+                },
+                "channel_kill_rate": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0.001
+                },
+                "fcm_delay": {
+                    "type": "string",
+                    "example": "2s"
+                },
+                "fcm_delay_rate": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0.1
+                },
+                "fcm_failure_rate": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0.05
+                }
+            }
+        },
+        "handlers.UpdateWorkerSettingsRequest": {
+            "description": "Worker settings update request (omitted fields are unchanged)",
+            "type": "object",
+            "properties": {
+                "concurrency": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 8
+                },
+                "prefetch_count": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 20
+                }
+            }
+        },
+        "handlers.ValidationErrorResponse": {
+            "description": "Invalid request body, with each field that failed validation",
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "string",
+                    "example": "notification.title is required"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Invalid request body"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.FieldError"
+                    }
+                }
+            }
+        },
+        "models.AlertRule": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "filter": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "metric": {
+                    "type": "string",
+                    "example": "failure_rate"
+                },
+                "min_events": {
+                    "description": "MinEvents keeps rate rules (failure_rate, retry_rate and\nfcm_error_rate) quiet until the window has this many deliveries",
+                    "type": "integer",
+                    "example": 100
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ios_failure_rate"
+                },
+                "operator": {
+                    "type": "string",
+                    "example": "\u003e"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "api"
+                },
+                "state": {
+                    "$ref": "#/definitions/models.AlertState"
+                },
+                "threshold": {
+                    "type": "number",
+                    "example": 0.2
+                },
+                "webhook_url": {
+                    "type": "string"
+                },
+                "window": {
+                    "type": "string",
+                    "example": "10m"
+                }
+            }
+        },
+        "models.AlertState": {
+            "type": "object",
+            "properties": {
+                "evaluated_at": {
+                    "type": "string"
+                },
+                "firing": {
+                    "type": "boolean"
+                },
+                "since": {
+                    "description": "when the rule started firing",
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number",
+                    "example": 0.27
+                }
+            }
+        },
+        "models.AnalyticsSummary": {
+            "type": "object",
+            "properties": {
+                "by_platform": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.DeliveryCounts"
+                    }
+                },
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeliveryRollup"
+                    }
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2026-10-09"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2026-10-15"
+                },
+                "totals": {
+                    "$ref": "#/definitions/models.DeliveryCounts"
+                }
+            }
+        },
+        "models.ArchivedDeadLetter": {
+            "description": "A dead letter moved to the archive: the object holding it and its line in the object, once written",
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "type": "string"
+                },
+                "dead_lettered_at": {
+                    "type": "string"
+                },
+                "line": {
+                    "description": "1-based",
+                    "type": "integer",
+                    "example": 42
+                },
+                "notification_id": {
+                    "type": "string",
+                    "example": "0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"
+                },
+                "object_url": {
+                    "type": "string",
+                    "example": "s3://push-archive/dead-letters/2026/10/16/20261016T140000Z-5f0c9a.ndjson.gz"
+                },
+                "staged_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ArchivedDeadLetters": {
+            "description": "Where a notification's dead letters were archived, oldest first",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "dead_letters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ArchivedDeadLetter"
+                    }
+                },
+                "notification_id": {
+                    "type": "string",
+                    "example": "0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"
+                }
+            }
+        },
+        "models.AttemptTokenResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "error_code": {
+                    "description": "ErrorCode is FCM's error code",
+                    "type": "string",
+                    "example": "UNAVAILABLE"
+                },
+                "error_kind": {
+                    "type": "string",
+                    "example": "unavailable"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "description": "Token is masked; DeviceToken is the token itself, which is stored\nwith the token's delivery result but not with the attempt",
+                    "type": "string"
+                }
+            }
+        },
+        "models.BulkPushRequest": {
+            "type": "object",
+            "required": [
+                "body",
+                "title"
+            ],
+            "properties": {
+                "analytics_label": {
+                    "description": "Segments the notifications' deliveries in Firebase reporting",
+                    "type": "string",
+                    "example": "weekly_digest"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "bypass_frequency_cap": {
+                    "type": "boolean"
+                },
+                "bypass_quiet_hours": {
+                    "description": "Override the category's policy, as for single sends",
+                    "type": "boolean"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "dry_run": {
+                    "description": "Validate with FCM without delivering",
+                    "type": "boolean"
+                },
+                "priority": {
+                    "description": "Which priority queue the notifications go through",
+                    "type": "string",
+                    "enum": [
+                        "critical",
+                        "high",
+                        "normal",
+                        "low"
+                    ],
+                    "example": "low"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "user_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "users": {
+                    "description": "Users with per-user template variables",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BulkRecipient"
+                    }
+                }
+            }
+        },
+        "models.BulkRecipient": {
+            "type": "object",
+            "required": [
+                "user_id"
+            ],
+            "properties": {
+                "user_id": {
+                    "type": "string",
+                    "example": "u1"
+                },
+                "vars": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.BundleItem": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "image": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Campaign": {
+            "type": "object",
+            "properties": {
+                "analytics_label": {
+                    "description": "AnalyticsLabel segments the campaign's deliveries in Firebase reporting",
+                    "type": "string"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "canary_completed_at": {
+                    "type": "string"
+                },
+                "canary_percent": {
+                    "description": "Canary rollout: the first CanaryUsers of the shuffled audience are\nsent to, then the campaign soaks until ContinueAt, which is set\nCanarySoakSeconds after the canary or by an explicit continue",
+                    "type": "integer"
+                },
+                "canary_soak_seconds": {
+                    "type": "integer"
+                },
+                "canary_users": {
+                    "type": "integer"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "continue_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "enqueued_tokens": {
+                    "type": "integer"
+                },
+                "enqueued_users": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "processed_users": {
+                    "type": "integer"
+                },
+                "projected_completion_at": {
+                    "type": "string"
+                },
+                "quota_policy": {
+                    "type": "string"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "total_users": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "Variants, if set, replace Title and Body: each user gets one, picked\nby a hash of the campaign and user ID",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CampaignVariant"
+                    }
+                }
+            }
+        },
+        "models.CampaignVariant": {
+            "type": "object",
+            "required": [
+                "body",
+                "name",
+                "title",
+                "weight"
+            ],
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "short_copy"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1,
+                    "example": 50
+                }
+            }
+        },
+        "models.CampaignVariantReport": {
+            "type": "object",
+            "properties": {
+                "campaign_id": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VariantRollup"
+                    }
+                }
+            }
+        },
+        "models.CanaryRequest": {
+            "type": "object",
+            "required": [
+                "percent"
+            ],
+            "properties": {
+                "percent": {
+                    "type": "integer",
+                    "maximum": 50,
+                    "minimum": 1,
+                    "example": 5
+                },
+                "soak": {
+                    "type": "string",
+                    "example": "30m"
+                }
+            }
+        },
+        "models.CreateAlertRuleRequest": {
+            "type": "object",
+            "required": [
+                "metric",
+                "name",
+                "operator",
+                "window"
+            ],
+            "properties": {
+                "filter": {
+                    "description": "platform, project and/or category",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "metric": {
+                    "type": "string",
+                    "enum": [
+                        "failure_rate",
+                        "failures",
+                        "dead_letters",
+                        "dead_letter_depth",
+                        "retry_rate",
+                        "fcm_error_rate"
+                    ],
+                    "example": "failure_rate"
+                },
+                "min_events": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 100
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "ios_failure_rate"
+                },
+                "operator": {
+                    "type": "string",
+                    "example": "\u003e"
+                },
+                "threshold": {
+                    "type": "number",
+                    "example": 0.2
+                },
+                "webhook_url": {
+                    "description": "Defaults to the configured webhook",
+                    "type": "string"
+                },
+                "window": {
+                    "type": "string",
+                    "example": "10m"
+                }
+            }
+        },
+        "models.CreateCampaignRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "user_ids"
+            ],
+            "properties": {
+                "analytics_label": {
+                    "description": "Segments the campaign's deliveries in Firebase reporting",
+                    "type": "string",
+                    "example": "spring_sale_2026"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "canary": {
+                    "description": "Send to a small share of the audience first",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CanaryRequest"
+                        }
+                    ]
+                },
+                "category": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "image": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "spring_sale"
+                },
+                "quota_policy": {
+                    "description": "Defaults to the configured policy",
+                    "type": "string",
+                    "enum": [
+                        "spread",
+                        "failover"
+                    ],
+                    "example": "spread"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "user_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "variants": {
+                    "description": "A/B test variants, whose weights add up to 100, instead of one title\nand body",
+                    "type": "array",
+                    "maxItems": 10,
+                    "minItems": 2,
+                    "items": {
+                        "$ref": "#/definitions/models.CampaignVariant"
+                    }
+                }
+            }
+        },
+        "models.CreateDeviceRequest": {
+            "type": "object",
+            "required": [
+                "platform",
+                "token",
+                "user_id"
+            ],
+            "properties": {
+                "app_version": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "4.2.0"
+                },
+                "device_id": {
+                    "description": "DeviceID is a stable ID of the device, e.g. from the keychain. A new\ntoken registered with it replaces the device's old registration.",
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1
+                },
+                "device_model": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "iPhone15,2"
+                },
+                "locale": {
+                    "type": "string",
+                    "maxLength": 35,
+                    "example": "en-GB"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "os_version": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "17.4.1"
+                },
+                "permission_status": {
+                    "type": "string",
+                    "enum": [
+                        "granted",
+                        "denied",
+                        "provisional"
+                    ]
+                },
+                "platform": {
+                    "type": "string",
+                    "enum": [
+                        "ios",
+                        "android",
+                        "web"
+                    ]
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateMuteRequest": {
+            "type": "object",
+            "required": [
+                "scope",
+                "user_id",
+                "value"
+            ],
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string",
+                    "enum": [
+                        "sender",
+                        "category"
+                    ],
+                    "example": "sender"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                },
+                "value": {
+                    "type": "string",
+                    "example": "brand_acme"
+                }
+            }
+        },
+        "models.CreateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "notification.sent",
+                        "notification.failed"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://api.example.com/push-events"
+                }
+            }
+        },
+        "models.DeadLetter": {
+            "description": "A dead-lettered push message, with its device tokens masked",
+            "type": "object",
+            "properties": {
+                "campaign_id": {
+                    "type": "string"
+                },
+                "dead_lettered_at": {
+                    "type": "string"
+                },
+                "device_tokens": {
+                    "description": "masked",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "dGVzdF90b...a2VuXzEyMw"
+                    ]
+                },
+                "error": {
+                    "description": "Error is why the message couldn't be decoded; it can't be replayed",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "notification_id": {
+                    "type": "string",
+                    "example": "0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"
+                },
+                "priority": {
+                    "type": "string",
+                    "example": "high"
+                },
+                "retry_count": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "title": {
+                    "type": "string",
+                    "example": "New message"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "models.DeadLetterReplay": {
+            "description": "Dead letters replayed onto the push queues",
+            "type": "object",
+            "properties": {
+                "replayed": {
+                    "description": "Replayed messages went back to their push queue with no retries counted",
+                    "type": "integer",
+                    "example": 10
+                },
+                "skipped": {
+                    "description": "Skipped messages couldn't be decoded and were left in the queue",
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "models.DeadLetters": {
+            "description": "Messages at the head of the dead letter queue, oldest first",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "dead_letters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeadLetter"
+                    }
+                }
+            }
+        },
+        "models.DeliveryAttempt": {
+            "type": "object",
+            "properties": {
+                "attempted_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failure_count": {
+                    "type": "integer"
+                },
+                "next_queue": {
+                    "description": "NextQueue is where the message went after the attempt; empty once the\nnotification was settled",
+                    "type": "string",
+                    "example": "push_retries_2m"
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "queue": {
+                    "description": "Queue is the queue the message came from, e.g. a retry tier",
+                    "type": "string",
+                    "example": "push_retries_30s"
+                },
+                "redelivered": {
+                    "type": "boolean"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AttemptTokenResult"
+                    }
+                },
+                "retry_count": {
+                    "type": "integer"
+                },
+                "success_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DeliveryCounts": {
+            "type": "object",
+            "properties": {
+                "delivered": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "opened": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DeliveryEvent": {
+            "description": "A delivery attempt of any notification, with its ID to continue the feed after it",
+            "type": "object",
+            "properties": {
+                "attempted_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failure_count": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1042
+                },
+                "next_queue": {
+                    "description": "NextQueue is where the message went after the attempt; empty once the\nnotification was settled",
+                    "type": "string",
+                    "example": "push_retries_2m"
+                },
+                "notification_id": {
+                    "type": "string",
+                    "example": "0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "queue": {
+                    "description": "Queue is the queue the message came from, e.g. a retry tier",
+                    "type": "string",
+                    "example": "push_retries_30s"
+                },
+                "redelivered": {
+                    "type": "boolean"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AttemptTokenResult"
+                    }
+                },
+                "retry_count": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "Status is the one the attempt left its notification in",
+                    "type": "string",
+                    "example": "delivered"
+                },
+                "success_count": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "models.DeliveryEvents": {
+            "description": "Delivery attempts recorded after a given one, oldest first",
+            "type": "object",
+            "properties": {
+                "after": {
+                    "description": "After is the ID to pass to get the attempts recorded since",
+                    "type": "integer",
+                    "example": 1042
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeliveryEvent"
+                    }
+                }
+            }
+        },
+        "models.DeliveryRollup": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "type": "string",
+                    "example": "2026-10-15"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "opened": {
+                    "type": "integer"
+                },
+                "platform": {
+                    "type": "string",
+                    "example": "android"
+                },
+                "sent": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Device": {
+            "type": "object",
+            "properties": {
+                "app_version": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "4.2.0"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deactivated_at": {
+                    "type": "string"
+                },
+                "device_id": {
+                    "description": "StableDeviceID identifies the physical device across app reinstalls\nand token changes; nil unless the client sends one",
+                    "type": "string"
+                },
+                "device_model": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "iPhone15,2"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "last_seen_at": {
+                    "description": "LastSeenAt is when the app last registered, refreshed the token or\nsent a heartbeat from the device",
+                    "type": "string"
+                },
+                "locale": {
+                    "type": "string",
+                    "maxLength": 35,
+                    "example": "en-GB"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "os_version": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "17.4.1"
+                },
+                "permission_status": {
+                    "description": "PermissionStatus is the OS notification permission reported by the\nclient SDK; nil until reported.",
+                    "type": "string"
+                },
+                "permission_updated_at": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone is the device's IANA timezone, e.g. Europe/Berlin; nil until\nreported. Sends at a local time use it.",
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeviceConfig": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "string"
+                },
+                "permission_recheck_interval_seconds": {
+                    "type": "integer",
+                    "example": 86400
+                },
+                "permission_status": {
+                    "type": "string",
+                    "example": "granted"
+                },
+                "platform": {
+                    "type": "string",
+                    "example": "ios"
+                },
+                "push_enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "token_refresh_interval_seconds": {
+                    "type": "integer",
+                    "example": 86400
+                }
+            }
+        },
+        "models.DeviceImport": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "why a failed import stopped",
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeviceImportError"
+                    }
+                },
+                "format": {
+                    "type": "string",
+                    "example": "csv"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "inserted": {
+                    "type": "integer",
+                    "example": 240000
+                },
+                "invalid": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "rows_read": {
+                    "type": "integer",
+                    "example": 250000
+                },
+                "status": {
+                    "type": "string",
+                    "example": "running"
+                },
+                "updated": {
+                    "type": "integer",
+                    "example": 9990
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeviceImportError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "unknown platform \"blackberry\""
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "models.DevicePage": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Device"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeviceResponse": {
+            "type": "object",
+            "properties": {
+                "app_version": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "4.2.0"
+                },
+                "device_id": {
+                    "description": "The client's stable device ID",
+                    "type": "string"
+                },
+                "device_model": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "iPhone15,2"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "locale": {
+                    "type": "string",
+                    "maxLength": 35,
+                    "example": "en-GB"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "os_version": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "17.4.1"
+                },
+                "permission_status": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "replaced": {
+                    "description": "The device's registration under an older token was replaced",
+                    "type": "boolean"
+                },
+                "resurrected": {
+                    "description": "A deactivated registration was restored",
+                    "type": "boolean"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DryRun": {
+            "type": "object",
+            "properties": {
+                "completed_messages": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message_count": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DryRunResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "completed"
+                }
+            }
+        },
+        "models.DryRunResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "error_kind": {
+                    "type": "string",
+                    "example": "unregistered"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.IOSOptions": {
+            "type": "object",
+            "properties": {
+                "badge": {
+                    "description": "0 clears the badge",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 3
+                },
+                "category": {
+                    "type": "string",
+                    "example": "NEW_MESSAGE"
+                },
+                "sound": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "thread_id": {
+                    "description": "Groups notifications",
+                    "type": "string",
+                    "example": "chat-42"
+                }
+            }
+        },
+        "models.InboxItem": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "notification_id": {
+                    "type": "string"
+                },
+                "read_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.InboxPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.InboxItem"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "unread_count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "models.NotificationAction": {
+            "type": "object",
+            "required": [
+                "id",
+                "title"
+            ],
+            "properties": {
+                "icon": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "approve"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Approve"
+                }
+            }
+        },
+        "models.NotificationDetail": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeliveryAttempt"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "delivered"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.NotificationTokenResults": {
+            "description": "A notification's latest delivery result for each of its device tokens",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "notification_id": {
+                    "type": "string",
+                    "example": "0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TokenDeliveryResult"
+                    }
+                }
+            }
+        },
+        "models.PlatformOverrides": {
+            "type": "object",
+            "properties": {
+                "android": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "apns": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "webpush": {
+                    "type": "object",
+                    "additionalProperties": {}
+                }
+            }
+        },
+        "models.RawPushRequest": {
+            "type": "object",
+            "required": [
+                "message"
+            ],
+            "properties": {
+                "message": {
+                    "type": "object"
+                },
+                "priority": {
+                    "description": "Which priority queue the message goes through",
+                    "type": "string",
+                    "enum": [
+                        "critical",
+                        "high",
+                        "normal",
+                        "low"
+                    ],
+                    "example": "high"
+                },
+                "user_id": {
+                    "description": "Recorded with the delivery attempts",
+                    "type": "string"
+                }
+            }
+        },
+        "models.RecordOpenRequest": {
+            "type": "object",
+            "required": [
+                "notification_id"
+            ],
+            "properties": {
+                "campaign_id": {
+                    "description": "The campaign_id and variant from the notification's data, if it was a\nvariant of an A/B tested campaign",
+                    "type": "string"
+                },
+                "notification_id": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string",
+                    "enum": [
+                        "ios",
+                        "android",
+                        "web"
+                    ],
+                    "example": "android"
+                },
+                "variant": {
+                    "type": "string",
+                    "example": "short_copy"
+                }
+            }
+        },
+        "models.RefreshTokenRequest": {
+            "type": "object",
+            "required": [
+                "new_token",
+                "old_token"
+            ],
+            "properties": {
+                "new_token": {
+                    "type": "string",
+                    "example": "new_fcm_token"
+                },
+                "old_token": {
+                    "type": "string",
+                    "example": "old_fcm_token"
+                }
+            }
+        },
+        "models.SDKRegisterRequest": {
+            "type": "object",
+            "required": [
+                "token",
+                "user_id"
+            ],
+            "properties": {
+                "app_version": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "4.2.0"
+                },
+                "device_id": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1,
+                    "example": "8F14E45F-CEEA-467A-9575-2F5C3C8F6A1B"
+                },
+                "device_model": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "iPhone15,2"
+                },
+                "locale": {
+                    "type": "string",
+                    "maxLength": 35,
+                    "example": "en-GB"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "os_version": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "17.4.1"
+                },
+                "permission_status": {
+                    "type": "string",
+                    "enum": [
+                        "granted",
+                        "denied",
+                        "provisional"
+                    ],
+                    "example": "granted"
+                },
+                "platform": {
+                    "type": "string",
+                    "enum": [
+                        "ios",
+                        "android",
+                        "web"
+                    ],
+                    "example": "android"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Berlin"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "fcm_token_here"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "models.SendBundleRequest": {
+            "type": "object",
+            "required": [
+                "items",
+                "user_id"
+            ],
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/models.BundleItem"
+                    }
+                },
+                "platforms": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "priority": {
+                    "description": "Which priority queue the bundle goes through",
+                    "type": "string",
+                    "enum": [
+                        "critical",
+                        "high",
+                        "normal",
+                        "low"
+                    ],
+                    "example": "high"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SendPushRequest": {
+            "type": "object",
+            "required": [
+                "body",
+                "title",
+                "user_id"
+            ],
+            "properties": {
+                "actions": {
+                    "type": "array",
+                    "maxItems": 3,
+                    "items": {
+                        "$ref": "#/definitions/models.NotificationAction"
+                    }
+                },
+                "analytics_label": {
+                    "description": "Segments the notification's deliveries in Firebase reporting",
+                    "type": "string",
+                    "example": "otp"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "bypass_frequency_cap": {
+                    "type": "boolean"
+                },
+                "bypass_quiet_hours": {
+                    "description": "Override the category's policy: whether the notification is delivered\nduring quiet hours, and whether it skips the frequency cap",
+                    "type": "boolean"
+                },
+                "category": {
+                    "description": "Category users can mute",
+                    "type": "string"
+                },
+                "click_action": {
+                    "description": "What tapping the notification or one of its buttons does. ClickAction\nis the Android intent action opened on tap; link stays the deep link.",
+                    "type": "string",
+                    "example": "OPEN_ORDER"
+                },
+                "collapse_key": {
+                    "description": "Notifications with the same collapse key replace each other on the\ndevice instead of stacking, e.g. live score updates",
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "match-1234"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "deliver_at_local_time": {
+                    "description": "Delivers the notification at the next HH:MM in each device's own\ntimezone instead of right away. Ignored for dry runs.",
+                    "type": "string",
+                    "example": "09:00"
+                },
+                "dry_run": {
+                    "description": "Validate with FCM without delivering",
+                    "type": "boolean"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "ios": {
+                    "description": "Badge, sound and grouping on iOS",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.IOSOptions"
+                        }
+                    ]
+                },
+                "link": {
+                    "type": "string"
+                },
+                "max_retries": {
+                    "description": "Retry policy overrides, bounded by the server's caps",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 8
+                },
+                "platform_overrides": {
+                    "description": "Merged verbatim into the FCM platform configs, e.g. to set an APNs\nheader that has no field here",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.PlatformOverrides"
+                        }
+                    ]
+                },
+                "platforms": {
+                    "description": "Filter by specific platforms",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "priority": {
+                    "description": "Which priority queue the notification goes through",
+                    "type": "string",
+                    "enum": [
+                        "critical",
+                        "high",
+                        "normal",
+                        "low"
+                    ],
+                    "example": "high"
+                },
+                "retry_backoff": {
+                    "description": "Every retry waits in the shortest retry tier at least this long",
+                    "type": "string",
+                    "example": "30s"
+                },
+                "sender": {
+                    "description": "Sender ID users can mute",
+                    "type": "string"
+                },
+                "suppress_push_if_connected": {
+                    "description": "Skips the push for users with an open realtime connection, who get\nthe notification over it instead",
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "How long the notification is worth delivering, e.g. \"10m\". It is\ndropped by the worker and by FCM once that has passed.",
+                    "type": "string",
+                    "example": "10m"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Tenant": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_send_cap": {
+                    "description": "DailySendCap overrides the configured cap of sends per UTC day",
+                    "type": "integer",
+                    "example": 1000
+                },
+                "id": {
+                    "type": "string",
+                    "example": "acme"
+                },
+                "sent_today": {
+                    "type": "integer"
+                },
+                "soft_launch": {
+                    "type": "boolean"
+                },
+                "soft_launch_lifted_at": {
+                    "type": "string"
+                },
+                "test_user_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TokenDeliveryResult": {
+            "description": "The latest outcome of delivering a notification to one device token",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "error": {
+                    "type": "string"
+                },
+                "error_code": {
+                    "type": "string",
+                    "example": "UNREGISTERED"
+                },
+                "error_kind": {
+                    "type": "string",
+                    "example": "unregistered"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "description": "masked",
+                    "type": "string",
+                    "example": "dGVzdF90b...a2VuXzEyMw"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UpdatePermissionRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
             "properties": {
-                "count": {
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "granted",
+                        "denied",
+                        "provisional"
+                    ],
+                    "example": "provisional"
+                }
+            }
+        },
+        "models.UpdateTenantRequest": {
+            "type": "object",
+            "properties": {
+                "daily_send_cap": {
                     "type": "integer",
-                    "example": 2
+                    "minimum": 1,
+                    "example": 1000
                 },
-                "devices": {
+                "soft_launch": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "test_user_ids": {
                     "type": "array",
+                    "maxItems": 100,
                     "items": {
-                        "$ref": "#/definitions/models.DeviceResponse"
+                        "type": "string"
                     }
+                }
+            }
+        },
+        "models.UserDataDeletion": {
+            "description": "Rows deleted per kind of data when a user's data was erased",
+            "type": "object",
+            "properties": {
+                "campaigns": {
+                    "description": "campaigns the user was removed from",
+                    "type": "integer",
+                    "example": 1
+                },
+                "dead_letters": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "delivery_attempts": {
+                    "type": "integer",
+                    "example": 41
+                },
+                "devices": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "digest_items": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "dry_run_results": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "erased_at": {
+                    "type": "string"
+                },
+                "frequency_counts": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "inbox_items": {
+                    "type": "integer",
+                    "example": 40
+                },
+                "mutes": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "notifications": {
+                    "type": "integer",
+                    "example": 40
+                },
+                "outbox_messages": {
+                    "type": "integer",
+                    "example": 40
+                },
+                "scheduled_pushes": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "tenants": {
+                    "description": "tenants the user was a test user of",
+                    "type": "integer",
+                    "example": 0
+                },
+                "token_results": {
+                    "type": "integer",
+                    "example": 80
                 },
                 "user_id": {
                     "type": "string",
@@ -445,139 +5934,342 @@ const docTemplate = `{
                 }
             }
         },
-        "handlers.HealthResponse": {
+        "models.UserMute": {
             "type": "object",
             "properties": {
-                "database": {
-                    "type": "string",
-                    "example": "healthy"
+                "created_at": {
+                    "type": "string"
                 },
-                "status": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.VariantRollup": {
+            "type": "object",
+            "properties": {
+                "delivered": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "open_rate": {
+                    "type": "number",
+                    "example": 0.042
+                },
+                "opened": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "variant": {
                     "type": "string",
-                    "example": "healthy"
+                    "example": "short_copy"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
                 },
-                "timestamp": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "notification.sent",
+                        "notification.failed"
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Only returned when the webhook is created",
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "url": {
                     "type": "string",
-                    "example": "2025-01-01T00:00:00Z"
+                    "example": "https://api.example.com/push-events"
                 }
             }
         },
-        "handlers.RegisterDeviceResponse": {
-            "description": "Device registration response",
+        "queue.ConsumerInfo": {
+            "description": "A queue consumer of this process",
             "type": "object",
             "properties": {
-                "device": {
-                    "$ref": "#/definitions/models.DeviceResponse"
+                "delivered": {
+                    "description": "Delivered counts the messages handed to the consumer",
+                    "type": "integer",
+                    "example": 1520
                 },
-                "message": {
+                "host": {
                     "type": "string",
-                    "example": "Device registered successfully"
+                    "example": "push-worker-7d9f"
+                },
+                "last_delivered_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:05:00Z"
+                },
+                "prefetch": {
+                    "description": "Prefetch is the prefetch count the consumer was started with",
+                    "type": "integer",
+                    "example": 10
+                },
+                "purpose": {
+                    "type": "string",
+                    "example": "push-high"
+                },
+                "queue": {
+                    "type": "string",
+                    "example": "push.queue.high"
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "tag": {
+                    "description": "Tag identifies the consumer to the broker: host, purpose and a\nsequence number, e.g. push-worker-7d9f:push-high:3",
+                    "type": "string",
+                    "example": "push-worker-7d9f:push-high:3"
+                },
+                "unacked": {
+                    "description": "Unacked counts those not yet acked or nacked",
+                    "type": "integer",
+                    "example": 4
                 }
             }
         },
-        "models.BulkPushRequest": {
+        "realtime.Event": {
             "type": "object",
-            "required": [
-                "body",
-                "title",
-                "user_ids"
-            ],
             "properties": {
-                "body": {
+                "at": {
                     "type": "string"
                 },
                 "data": {
-                    "type": "object",
-                    "additionalProperties": {}
+                    "type": "object"
                 },
-                "title": {
+                "id": {
                     "type": "string"
                 },
-                "user_ids": {
+                "type": {
+                    "type": "string",
+                    "example": "notification"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "reload.Result": {
+            "description": "Settings changed by a configuration reload",
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "description": "Applied lists the settings that changed and now apply",
                     "type": "array",
                     "items": {
                         "type": "string"
-                    }
+                    },
+                    "example": [
+                        "log.level",
+                        "queue.worker.prefetch_count"
+                    ]
+                },
+                "restart_required": {
+                    "description": "RestartRequired is set when other settings changed too; they apply\nafter a restart",
+                    "type": "boolean",
+                    "example": false
                 }
             }
         },
-        "models.CreateDeviceRequest": {
+        "webhook.Circuit": {
             "type": "object",
-            "required": [
-                "platform",
-                "token",
-                "user_id"
-            ],
             "properties": {
-                "platform": {
-                    "type": "string",
-                    "enum": [
-                        "ios",
-                        "android",
-                        "web"
-                    ]
+                "failures": {
+                    "type": "integer",
+                    "example": 5
                 },
-                "token": {
+                "open_until": {
                     "type": "string"
                 },
-                "user_id": {
+                "webhook_id": {
                     "type": "string"
                 }
             }
         },
-        "models.DeviceResponse": {
+        "webhook.Delivery": {
             "type": "object",
             "properties": {
-                "id": {
+                "at": {
                     "type": "string"
                 },
-                "is_active": {
-                    "type": "boolean"
+                "attempt": {
+                    "type": "integer",
+                    "example": 1
                 },
-                "platform": {
+                "duration_ms": {
+                    "type": "integer",
+                    "example": 84
+                },
+                "error": {
                     "type": "string"
                 },
-                "token": {
+                "event_id": {
                     "type": "string"
                 },
-                "user_id": {
+                "event_type": {
+                    "type": "string",
+                    "example": "notification.sent"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "description": "delivered, retrying, failed or circuit_open",
+                    "type": "string",
+                    "example": "delivered"
+                },
+                "status_code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "url": {
+                    "type": "string"
+                },
+                "webhook_id": {
                     "type": "string"
                 }
             }
         },
-        "models.SendPushRequest": {
+        "worker.AutoscaleStatus": {
+            "description": "Queue backlog and worker load, for autoscalers such as KEDA",
             "type": "object",
-            "required": [
-                "body",
-                "title",
-                "user_id"
-            ],
             "properties": {
-                "body": {
-                    "type": "string"
+                "active": {
+                    "type": "integer",
+                    "example": 8
                 },
-                "data": {
-                    "type": "object",
-                    "additionalProperties": {}
+                "backlog": {
+                    "description": "Backlog is the number of messages waiting in the push queues of every\npriority and the gateway queue, not counting those delivered to a\nworker",
+                    "type": "integer",
+                    "example": 1200
                 },
-                "image": {
-                    "type": "string"
+                "concurrency": {
+                    "type": "integer",
+                    "example": 10
                 },
-                "link": {
-                    "type": "string"
+                "controller": {
+                    "description": "Controller is whether the worker resizes its pool on its own",
+                    "type": "boolean",
+                    "example": false
                 },
-                "platforms": {
-                    "description": "Filter by specific platforms",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
+                "desired_concurrency": {
+                    "description": "DesiredConcurrency is what the controller sizes the pool to: the\nhandlers that were busy plus one per target backlog of messages\nwaiting, within bounds",
+                    "type": "integer",
+                    "example": 32
+                },
+                "queues": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int64"
                     }
                 },
-                "title": {
-                    "type": "string"
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
                 },
-                "user_id": {
-                    "type": "string"
+                "utilization": {
+                    "description": "Utilization is the average share of the pool busy over the last\ninterval",
+                    "type": "number",
+                    "example": 0.85
+                }
+            }
+        },
+        "worker.Health": {
+            "description": "Queue worker status",
+            "type": "object",
+            "properties": {
+                "concurrency": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "consumers": {
+                    "description": "Consumers counts the queue consumers of this process",
+                    "type": "integer",
+                    "example": 5
+                },
+                "in_flight": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "last_processed_at": {
+                    "description": "LastProcessedAt is when a handler last finished a message",
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00Z"
+                },
+                "reconnects": {
+                    "description": "Reconnects counts how often the broker connections were\nre-established, where the broker tells",
+                    "type": "integer",
+                    "example": 0
+                },
+                "status": {
+                    "description": "Status is ok, paused (while FCM rejects our credentials or throttles\nus), stalled (messages are in flight or waiting, but none finished\nwithin the stall timeout) or stopped",
+                    "type": "string",
+                    "example": "ok"
+                },
+                "waiting": {
+                    "description": "Waiting counts the messages in the push and gateway queues",
+                    "type": "integer",
+                    "example": 120
+                }
+            }
+        },
+        "worker.Settings": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "concurrency": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "max_concurrency": {
+                    "type": "integer",
+                    "example": 32
+                },
+                "prefetch_count": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "speed_factor": {
+                    "description": "SpeedFactor is below 1 while the worker is slowed down because FCM\nfails many sends: prefetch and concurrency are that share of what\nthey were set to",
+                    "type": "number",
+                    "example": 1
                 }
             }
         }
@@ -594,8 +6286,8 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "A microservice for sending push notifications via Firebase Cloud Messaging (FCM) with RabbitMQ queue support\nFeatures:\n- Device registration and management\n- Queue-based push notification processing\n- Token validation\n- Rich notifications (title, body, image, link)\n- Retry mechanism with dead letter queue\n- Queue statistics",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
-	LeftDelim:        "{{",
-	RightDelim:       "}}",
+	LeftDelim:        "[[",
+	RightDelim:       "]]",
 }
 
 func init() {
@@ -0,0 +1,322 @@
+// Package app wires together what the service's binaries share: the
+// connections to Postgres, the queue broker, FCM and the other backends, and
+// the services and the queue worker built on them.
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"push-service/internal/chaos"
+	"push-service/internal/config"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/platform/resolver"
+	"push-service/internal/queue"
+	"push-service/internal/repository"
+	"push-service/internal/service"
+	"push-service/internal/worker"
+	"push-service/pkg/database"
+	"push-service/pkg/eventsink"
+	"push-service/pkg/lock"
+	"push-service/pkg/logger"
+	"push-service/pkg/objectstore"
+	"push-service/pkg/rabbitmq"
+	"push-service/pkg/realtime"
+	"push-service/pkg/redis"
+	"push-service/pkg/webhook"
+)
+
+// Deps are the connections and clients the API and the worker are built on
+type Deps struct {
+	DB           *database.DB
+	Broker       queue.Broker
+	FCMClient    fcm.FCMClient
+	Projects     *fcm.Projects
+	UserResolver resolver.UserResolver
+	Locker       lock.Locker
+	Dispatcher   *webhook.Dispatcher
+	Hub          *realtime.Hub // nil unless realtime delivery is enabled
+	EventSink    *eventsink.Sink
+	Faults       *chaos.Faults // nil unless fault injection is enabled
+
+	closers []func()
+}
+
+// Open connects to everything cfg configures, waiting up to
+// STARTUP_WAIT_TIMEOUT for Postgres and the queue broker to come up. If one
+// fails, those already opened are closed.
+func Open(cfg *config.Config) (*Deps, error) {
+	d := &Deps{}
+	if err := d.open(cfg); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Deps) open(cfg *config.Config) error {
+	var err error
+
+	d.Faults = chaos.New(&cfg.Chaos)
+
+	err = retry(&cfg.Startup, "database", func() (err error) {
+		d.DB, err = database.NewPostgresDB(&cfg.Database)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	d.onClose(d.DB.Close)
+
+	err = retry(&cfg.Startup, "queue broker", func() (err error) {
+		d.Broker, err = NewBroker(cfg, d.DB)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to queue broker %s: %w", cfg.Queue.Backend, err)
+	}
+	d.onClose(func() { d.Broker.Close() })
+	d.Broker = queue.WithFaults(d.Broker, d.Faults)
+
+	d.FCMClient, err = fcm.NewFCMClient(&cfg.FCM, d.Faults)
+	if err != nil {
+		return fmt.Errorf("failed to initialize FCM client: %w", err)
+	}
+	d.Projects, err = fcm.NewProjects(d.FCMClient, &cfg.FCM, d.Faults)
+	if err != nil {
+		return fmt.Errorf("failed to initialize FCM failover projects: %w", err)
+	}
+
+	// The external user resolver, if configured
+	d.UserResolver, err = resolver.New(&cfg.Queue.Gateway.UserResolver)
+	if err != nil {
+		return fmt.Errorf("failed to initialize user resolver: %w", err)
+	}
+	if d.UserResolver != nil {
+		d.onClose(func() { d.UserResolver.Close() })
+	}
+
+	// The distributed locks of background jobs
+	locker, closeLocker, err := NewLocker(cfg, d.DB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s locks: %w", cfg.Lock.Backend, err)
+	}
+	d.Locker = locker
+	d.onClose(closeLocker)
+
+	d.Dispatcher = webhook.NewDispatcher(&cfg.Webhooks)
+	d.onClose(d.Dispatcher.Close)
+
+	hub, closeHub, err := NewRealtimeHub(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize realtime delivery over %s: %w", cfg.Realtime.Bus, err)
+	}
+	d.Hub = hub
+	d.onClose(closeHub)
+
+	d.EventSink, err = eventsink.New(&cfg.EventSink)
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s event sink: %w", cfg.EventSink.Backend, err)
+	}
+	d.onClose(d.EventSink.Close)
+
+	return nil
+}
+
+func (d *Deps) onClose(close func()) {
+	d.closers = append(d.closers, close)
+}
+
+// Close closes what Open opened, in reverse order
+func (d *Deps) Close() {
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		d.closers[i]()
+	}
+	d.closers = nil
+}
+
+// NewBroker connects to the message broker selected by QUEUE_BACKEND
+func NewBroker(cfg *config.Config, db *database.DB) (queue.Broker, error) {
+	switch cfg.Queue.Backend {
+	case config.QueueBackendPostgres:
+		return queue.NewPostgresBroker(db.Pool, &cfg.Queue.Postgres), nil
+	case config.QueueBackendKafka:
+		return queue.NewKafkaBroker(&cfg.Kafka)
+	case config.QueueBackendNATS:
+		return queue.NewNATSBroker(&cfg.NATS, cfg.Queue.Retry.MaxRetries)
+	case config.QueueBackendMemory:
+		logger.L().Warn("Using the in-memory queue backend; queued messages are lost on restart")
+		return queue.NewMemoryBroker(), nil
+	default:
+		rabbitmqClient, err := rabbitmq.NewRabbitMQClient(&cfg.RabbitMQ)
+		if err != nil {
+			return nil, err
+		}
+		broker := queue.NewRabbitMQBroker(rabbitmqClient)
+		broker.BatchAcks(cfg.Queue.Worker.AckBatchSize, cfg.Queue.Worker.AckInterval)
+		return broker, nil
+	}
+}
+
+// NewLocker creates the locker selected by LOCK_BACKEND. The returned
+// function closes the Redis connection, if one was opened.
+func NewLocker(cfg *config.Config, db *database.DB) (lock.Locker, func(), error) {
+	if cfg.Lock.Backend != lock.BackendRedis {
+		locker, err := lock.New(&cfg.Lock, db.Pool, nil)
+		return locker, func() {}, err
+	}
+
+	redisClient, err := redis.NewRedisClient(&cfg.Redis)
+	if err != nil {
+		return nil, nil, err
+	}
+	locker, err := lock.New(&cfg.Lock, db.Pool, redisClient.Client)
+	if err != nil {
+		redisClient.Close()
+		return nil, nil, err
+	}
+	return locker, func() { redisClient.Close() }, nil
+}
+
+// NewRealtimeHub creates the realtime hub if REALTIME_ENABLED is set, or
+// returns nil. The returned function closes the hub and its Redis
+// connection, if one was opened.
+func NewRealtimeHub(cfg *config.Config) (*realtime.Hub, func(), error) {
+	if !cfg.Realtime.Enabled {
+		return nil, func() {}, nil
+	}
+	if cfg.Realtime.Bus != realtime.BusRedis {
+		hub, err := realtime.New(&cfg.Realtime, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return hub, hub.Close, nil
+	}
+
+	redisClient, err := redis.NewRedisClient(&cfg.Redis)
+	if err != nil {
+		return nil, nil, err
+	}
+	hub, err := realtime.New(&cfg.Realtime, redisClient.Client)
+	if err != nil {
+		redisClient.Close()
+		return nil, nil, err
+	}
+	return hub, func() {
+		hub.Close()
+		redisClient.Close()
+	}, nil
+}
+
+// Services are what the API, the worker and the background jobs are built on
+type Services struct {
+	PushQueue     *queue.PushQueue
+	Push          service.PushService
+	Alerts        service.AlertService
+	Devices       service.DeviceService
+	DeviceImports service.DeviceImportService
+	Mutes         service.MuteService
+	Campaigns     service.CampaignService
+	Tenants       service.TenantService
+	Analytics     service.AnalyticsService
+	Webhooks      service.WebhookService
+	Inbox         service.InboxService
+	UserData      service.UserDataService
+	DeadLetters   service.DeadLetterArchiver
+	Jobs          *worker.Jobs
+}
+
+// NewServices builds the repositories on deps and the services on them
+func NewServices(deps *Deps, cfg *config.Config) (*Services, error) {
+	db := deps.DB
+
+	deviceRepo := repository.NewDeviceRepository(db.Pool, db.ReadPool())
+	muteRepo := repository.NewMuteRepository(db.Pool)
+	quotaRepo := repository.NewQuotaRepository(db.Pool)
+	dryRunRepo := repository.NewDryRunRepository(db.Pool)
+	campaignRepo := repository.NewCampaignRepository(db.Pool)
+	alertRepo := repository.NewAlertRepository(db.Pool)
+	tenantRepo := repository.NewTenantRepository(db.Pool)
+	attemptRepo := repository.NewDeliveryAttemptRepository(db.Pool)
+	analyticsRepo := repository.NewAnalyticsRepository(db.Pool, db.ReadPool())
+	webhookRepo := repository.NewWebhookRepository(db.Pool)
+	scheduledRepo := repository.NewScheduledPushRepository(db.Pool)
+	digestRepo := repository.NewDigestRepository(db.Pool)
+	inboxRepo := repository.NewInboxRepository(db.Pool)
+	deviceImportRepo := repository.NewDeviceImportRepository(db.Pool)
+	userDataRepo := repository.NewUserDataRepository(db.Pool)
+	outboxRepo := repository.NewOutboxRepository(db.Pool)
+	frequencyRepo := repository.NewFrequencyRepository(db.Pool)
+	retentionRepo := repository.NewRetentionRepository(db.Pool)
+	archiveRepo := repository.NewDeadLetterArchiveRepository(db.Pool)
+	pushQueue, err := queue.NewPushQueue(deps.Broker, &cfg.Queue, &cfg.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize push queue: %w", err)
+	}
+	var archiveStore objectstore.Store
+	if cfg.Archive.Enabled {
+		archiveStore, err = objectstore.New(context.Background(), &cfg.Archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize dead letter archive store: %w", err)
+		}
+	}
+	alertService := service.NewAlertService(alertRepo, pushQueue, deps.Locker, cfg)
+	webhookService := service.NewWebhookService(webhookRepo, deps.Dispatcher, cfg)
+	inboxService := service.NewInboxService(inboxRepo, cfg)
+	userDataService := service.NewUserDataService(userDataRepo, cfg)
+	pushService := service.NewPushService(service.PushDeps{
+		DeviceRepo:    deviceRepo,
+		MuteRepo:      muteRepo,
+		QuotaRepo:     quotaRepo,
+		DryRunRepo:    dryRunRepo,
+		TenantRepo:    tenantRepo,
+		AttemptRepo:   attemptRepo,
+		AnalyticsRepo: analyticsRepo,
+		ScheduledRepo: scheduledRepo,
+		DigestRepo:    digestRepo,
+		OutboxRepo:    outboxRepo,
+		FrequencyRepo: frequencyRepo,
+		Projects:      deps.Projects,
+		UserResolver:  deps.UserResolver,
+		Alerts:        alertService,
+		Webhooks:      webhookService,
+		Inbox:         inboxService,
+		UserData:      userDataService,
+		Realtime:      deps.Hub,
+		Events:        deps.EventSink,
+		PushQueue:     pushQueue,
+	}, cfg)
+	campaignService := service.NewCampaignService(campaignRepo, quotaRepo, tenantRepo, pushService, pushQueue, deps.Projects, cfg)
+	schedulerService := service.NewSchedulerService(scheduledRepo, digestRepo, pushService, pushQueue, cfg)
+	deviceCleanupService := service.NewDeviceCleanupService(deviceRepo, deps.Locker, cfg)
+	retentionService := service.NewRetentionService(retentionRepo, deps.Locker, cfg)
+	outboxRelay := service.NewOutboxRelay(outboxRepo, pushQueue, deps.Locker, cfg)
+	deadLetterArchiver := service.NewDeadLetterArchiver(archiveRepo, pushQueue, archiveStore, deps.Locker, cfg)
+
+	return &Services{
+		PushQueue:     pushQueue,
+		Push:          pushService,
+		Alerts:        alertService,
+		Devices:       service.NewDeviceService(deviceRepo, deps.FCMClient, pushQueue, webhookService, cfg),
+		DeviceImports: service.NewDeviceImportService(deviceRepo, deviceImportRepo, cfg),
+		Mutes:         service.NewMuteService(muteRepo),
+		Campaigns:     campaignService,
+		Tenants:       service.NewTenantService(tenantRepo, cfg),
+		Analytics:     service.NewAnalyticsService(analyticsRepo, campaignRepo, deps.EventSink),
+		Webhooks:      webhookService,
+		Inbox:         inboxService,
+		UserData:      userDataService,
+		DeadLetters:   deadLetterArchiver,
+		Jobs:          worker.NewJobs(campaignService, alertService, schedulerService, deviceCleanupService, retentionService, outboxRelay, deadLetterArchiver),
+	}, nil
+}
+
+// NewPushWorker builds the queue worker on svc. It runs the background jobs
+// too unless QUEUE_WORKER_RUN_JOBS is off.
+func NewPushWorker(deps *Deps, svc *Services, cfg *config.Config) *worker.Worker {
+	jobs := svc.Jobs
+	if !cfg.Queue.Worker.RunJobs {
+		jobs = nil
+	}
+	return worker.New(svc.Push, svc.Alerts, jobs, svc.PushQueue, deps.FCMClient, &cfg.Queue)
+}
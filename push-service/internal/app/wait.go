@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// WaitForDatabase blocks until Postgres accepts connections, retrying up to
+// STARTUP_WAIT_TIMEOUT, so migrations don't fail while it is starting
+func WaitForDatabase(cfg *config.Config) error {
+	return retry(&cfg.Startup, "database", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		conn, err := pgx.Connect(ctx, cfg.Database.GetDatabaseURL())
+		if err != nil {
+			return err
+		}
+		defer conn.Close(context.Background())
+		return conn.Ping(ctx)
+	})
+}
+
+// retry calls connect until it succeeds or the startup wait timeout has
+// passed, backing off between attempts. It returns the last error.
+func retry(cfg *config.StartupConfig, name string, connect func() error) error {
+	backoff := cfg.InitialBackoff
+	if backoff <= 0 {
+		backoff = time.Second // default
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 10 * time.Second // default
+	}
+	deadline := time.Now().Add(cfg.WaitTimeout)
+
+	for attempt := 1; ; attempt++ {
+		err := connect()
+		if err == nil {
+			if attempt > 1 {
+				logger.L().Info("Connected after waiting", zap.String("dependency", name), zap.Int("attempts", attempt))
+			}
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if attempt > 1 {
+				return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+
+		wait := min(backoff, remaining)
+		logger.L().Warn("Dependency not available yet, retrying",
+			zap.String("dependency", name),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", wait),
+			zap.Error(err),
+		)
+		time.Sleep(wait)
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
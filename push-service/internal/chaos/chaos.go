@@ -0,0 +1,190 @@
+// Package chaos injects faults when CHAOS_ENABLED is set: it delays and
+// fails FCM requests, drops queue acks and closes the broker's channels, at
+// rates that can be changed at runtime. It exists to check that retries,
+// dead-lettering and reconnection behave as designed, and must never be
+// enabled in production.
+package chaos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Kinds of faults, as counted by Injected and in
+// push_service_chaos_faults_total
+const (
+	FaultFCMDelay    = "fcm_delay"
+	FaultFCMFailure  = "fcm_failure"
+	FaultAckDrop     = "ack_drop"
+	FaultChannelKill = "channel_kill"
+)
+
+// Faults decides which faults to inject. A nil *Faults injects none.
+type Faults struct {
+	mu       sync.RWMutex
+	cfg      config.ChaosConfig
+	injected map[string]int64
+}
+
+// New returns the faults cfg configures, or nil unless fault injection is
+// enabled
+func New(cfg *config.ChaosConfig) *Faults {
+	if !cfg.Enabled {
+		return nil
+	}
+	zap.L().Warn("Fault injection is enabled: FCM sends, queue acks and broker channels fail on purpose",
+		zap.Float64("fcm_delay_rate", cfg.FCMDelayRate),
+		zap.Float64("fcm_failure_rate", cfg.FCMFailureRate),
+		zap.Float64("ack_drop_rate", cfg.AckDropRate),
+		zap.Float64("channel_kill_rate", cfg.ChannelKillRate),
+	)
+	return &Faults{cfg: *cfg, injected: make(map[string]int64)}
+}
+
+// Settings returns the current rates and delay
+func (f *Faults) Settings() config.ChaosConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cfg
+}
+
+// Update replaces the rates and delay, if they are valid
+func (f *Faults) Update(cfg config.ChaosConfig) error {
+	cfg.Enabled = true
+	if err := config.ValidateChaos(&cfg); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.cfg = cfg
+	f.mu.Unlock()
+
+	zap.L().Warn("Fault injection settings changed",
+		zap.Duration("fcm_delay", cfg.FCMDelay),
+		zap.Float64("fcm_delay_rate", cfg.FCMDelayRate),
+		zap.Float64("fcm_failure_rate", cfg.FCMFailureRate),
+		zap.Float64("ack_drop_rate", cfg.AckDropRate),
+		zap.Float64("channel_kill_rate", cfg.ChannelKillRate),
+	)
+	return nil
+}
+
+// Injected counts the faults injected since the start, by kind
+func (f *Faults) Injected() map[string]int64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	injected := make(map[string]int64, len(f.injected))
+	for kind, n := range f.injected {
+		injected[kind] = n
+	}
+	return injected
+}
+
+// Record counts a fault injected elsewhere, e.g. channels closed from the
+// admin API
+func (f *Faults) Record(kind string) {
+	f.mu.Lock()
+	f.injected[kind]++
+	f.mu.Unlock()
+	metrics.ChaosFaults.WithLabelValues(kind).Inc()
+}
+
+// AckFault picks the fault to inject into an ack: "", FaultAckDrop or, if
+// the broker's channels can be closed, FaultChannelKill
+func (f *Faults) AckFault(canKill bool) string {
+	if f == nil {
+		return ""
+	}
+	cfg := f.Settings()
+	r := rand.Float64()
+	switch {
+	case r < cfg.AckDropRate:
+		f.Record(FaultAckDrop)
+		return FaultAckDrop
+	case canKill && r < cfg.AckDropRate+cfg.ChannelKillRate:
+		f.Record(FaultChannelKill)
+		return FaultChannelKill
+	}
+	return ""
+}
+
+// Transport wraps next so FCM requests are delayed and failed at the
+// configured rates. It returns next itself if f is nil.
+func (f *Faults) Transport(next http.RoundTripper) http.RoundTripper {
+	if f == nil {
+		return next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{faults: f, next: next}
+}
+
+type transport struct {
+	faults *Faults
+	next   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cfg := t.faults.Settings()
+
+	if cfg.FCMDelay > 0 && rand.Float64() < cfg.FCMDelayRate {
+		t.faults.Record(FaultFCMDelay)
+		select {
+		case <-time.After(cfg.FCMDelay):
+		case <-req.Context().Done():
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, req.Context().Err()
+		}
+	}
+
+	if rand.Float64() < cfg.FCMFailureRate {
+		t.faults.Record(FaultFCMFailure)
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return internalError(req), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// internalError is FCM's answer to a request it failed on its side, which
+// the SDK doesn't retry itself
+func internalError(req *http.Request) *http.Response {
+	status := http.StatusInternalServerError
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"code":    status,
+			"message": "Internal error encountered (injected fault).",
+			"status":  "INTERNAL",
+			"details": []map[string]string{{
+				"@type":     "type.googleapis.com/google.firebase.fcm.v1.FcmError",
+				"errorCode": "INTERNAL",
+			}},
+		},
+	})
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -2,39 +2,148 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
-	FCM      FCMConfig      `mapstructure:"fcm"`
-	Log      LogConfig      `mapstructure:"log"`
-	Queue    QueueConfig    `mapstructure:"queue"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Startup    StartupConfig    `mapstructure:"startup"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	RabbitMQ   RabbitMQConfig   `mapstructure:"rabbitmq"`
+	Kafka      KafkaConfig      `mapstructure:"kafka"`
+	NATS       NATSConfig       `mapstructure:"nats"`
+	FCM        FCMConfig        `mapstructure:"fcm"`
+	Log        LogConfig        `mapstructure:"log"`
+	Queue      QueueConfig      `mapstructure:"queue"`
+	Campaign   CampaignConfig   `mapstructure:"campaign"`
+	SDK        SDKConfig        `mapstructure:"sdk"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Lock       LockConfig       `mapstructure:"lock"`
+	Alerting   AlertingConfig   `mapstructure:"alerting"`
+	SoftLaunch SoftLaunchConfig `mapstructure:"soft_launch"`
+	Webhooks   WebhooksConfig   `mapstructure:"webhooks"`
+	Scheduler  SchedulerConfig  `mapstructure:"scheduler"`
+	Digest     DigestConfig     `mapstructure:"digest"`
+	QuietHours QuietHoursConfig `mapstructure:"quiet_hours"`
+	Categories CategoriesConfig `mapstructure:"categories"`
+	Inbox      InboxConfig      `mapstructure:"inbox"`
+	Realtime   RealtimeConfig   `mapstructure:"realtime"`
+	Devices    DevicesConfig    `mapstructure:"devices"`
+	Privacy    PrivacyConfig    `mapstructure:"privacy"`
+	Retention  RetentionConfig  `mapstructure:"retention"`
+	Outbox     OutboxConfig     `mapstructure:"outbox"`
+	EventSink  EventSinkConfig  `mapstructure:"event_sink"`
+	Archive    ArchiveConfig    `mapstructure:"archive"`
+	Access     AccessConfig     `mapstructure:"access"`
+	Chaos      ChaosConfig      `mapstructure:"chaos"`
 }
 
 type ServerConfig struct {
 	Port            string        `mapstructure:"port"`
 	Mode            string        `mapstructure:"mode"`
 	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// RunWorker runs the queue worker in the API process; turn it off when
+	// the worker is deployed on its own (cmd/worker)
+	RunWorker bool `mapstructure:"run_worker"`
+}
+
+// StartupConfig sets how long the binaries wait for Postgres and the queue
+// broker at startup, e.g. while docker-compose or a Kubernetes rollout brings
+// them up. Connections are retried with a backoff that starts at
+// InitialBackoff and doubles up to MaxBackoff, until WaitTimeout has passed;
+// with a WaitTimeout of 0 the first failure is fatal.
+type StartupConfig struct {
+	WaitTimeout    time.Duration `mapstructure:"wait_timeout"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+}
+
+// AccessConfig restricts the internal-only endpoints (the admin API, sends,
+// campaigns and user data erasure) to internal clients: those whose IP is in
+// one of InternalCIDRs, or whose request carries InternalHeader set to
+// InternalHeaderValue, which the ingress adds to requests it let through.
+// With neither set, anyone may call them. The client IP is taken from
+// X-Forwarded-For only for requests from TrustedProxies.
+type AccessConfig struct {
+	InternalCIDRs       []string `mapstructure:"internal_cidrs"`
+	InternalHeader      string   `mapstructure:"internal_header"`
+	InternalHeaderValue string   `mapstructure:"internal_header_value"`
+	TrustedProxies      []string `mapstructure:"trusted_proxies"`
+}
+
+// Restricted tells if the internal-only endpoints are restricted
+func (a *AccessConfig) Restricted() bool {
+	return len(a.InternalCIDRs) > 0 || a.InternalHeader != ""
+}
+
+// Prefixes parses InternalCIDRs; a single IP is a prefix of its full length
+func (a *AccessConfig) Prefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(a.InternalCIDRs))
+	for _, cidr := range a.InternalCIDRs {
+		cidr = strings.TrimSpace(cidr)
+		if addr, err := netip.ParseAddr(cidr); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid internal CIDR %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ChaosConfig injects faults, to check that retries, dead-lettering and
+// reconnection behave as designed. The rates are shares of FCM requests
+// delayed by FCMDelay or failed with UNAVAILABLE, of acks silently dropped,
+// and of acks that close the broker channels instead. They can be changed
+// at runtime from the admin API, but only if Enabled was set at startup.
+// Never enable it in production.
+type ChaosConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	FCMDelay        time.Duration `mapstructure:"fcm_delay"`
+	FCMDelayRate    float64       `mapstructure:"fcm_delay_rate"`
+	FCMFailureRate  float64       `mapstructure:"fcm_failure_rate"`
+	AckDropRate     float64       `mapstructure:"ack_drop_rate"`
+	ChannelKillRate float64       `mapstructure:"channel_kill_rate"`
 }
 
 type DatabaseConfig struct {
-	Host            string        `mapstructure:"host"`
-	Port            string        `mapstructure:"port"`
-	User            string        `mapstructure:"user"`
-	Password        string        `mapstructure:"password"`
-	Name            string        `mapstructure:"name"`
-	SSLMode         string        `mapstructure:"ssl_mode"`
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	Name     string `mapstructure:"name"`
+	SSLMode  string `mapstructure:"ssl_mode"`
+	// MaxOpenConns is the most connections a pool opens, MaxIdleConns the
+	// fewest it keeps open, even when idle
 	MaxOpenConns    int           `mapstructure:"max_open_conns"`
 	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	// ConnMaxIdleTime closes connections idle for longer, down to
+	// MaxIdleConns
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+	// HealthCheckPeriod is how often idle connections are checked and
+	// closed past their lifetime or idle time
+	HealthCheckPeriod time.Duration `mapstructure:"health_check_period"`
+	// ReplicaURL is the connection URL of a read replica. Read-only queries
+	// that can lag behind writes go to it; unset, everything uses the
+	// primary.
+	ReplicaURL string `mapstructure:"replica_url"`
+	// AutoMigrate applies the embedded migrations on startup
+	AutoMigrate bool `mapstructure:"auto_migrate"`
 }
 
 type RedisConfig struct {
@@ -45,47 +154,718 @@ type RedisConfig struct {
 }
 
 type FCMConfig struct {
-	CredentialsJSON string `mapstructure:"credentials_json"`
+	// Mode is live, or sandbox to send to a simulated FCM instead, which
+	// needs no credentials; see SandboxConfig
+	Mode              string        `mapstructure:"mode"`
+	Sandbox           SandboxConfig `mapstructure:"sandbox"`
+	CredentialsJSON   string        `mapstructure:"credentials_json"`
+	ProjectID         string        `mapstructure:"project_id"`
+	UseFile           bool          `mapstructure:"use_file"`
+	AuthProbeInterval time.Duration `mapstructure:"auth_probe_interval"`
+
+	// Endpoint replaces FCM's API URL, e.g. with an emulator or the fcmtest
+	// server in tests. Credentials are optional then.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// QuotaBackoff is how long sends pause after a quota error that came
+	// without a Retry-After hint
+	QuotaBackoff time.Duration `mapstructure:"quota_backoff"`
+
+	// DailyQuota is the number of messages the project may send per UTC day;
+	// 0 means unlimited. Campaigns are paced against it.
+	DailyQuota int `mapstructure:"daily_quota"`
+
+	// MaxSendRate caps the messages per second each instance sends through
+	// the project; 0 means unlimited. Sends wait for their turn.
+	MaxSendRate float64 `mapstructure:"max_send_rate"`
+
+	// FailoverProjects take campaign overflow under the "failover" quota
+	// policy. Each is a full project config with its own credentials.
+	FailoverProjects []FCMConfig `mapstructure:"failover_projects"`
+
+	// MaxPayloadSize is the largest notification and data payload sends may
+	// have, in bytes. Larger sends are rejected, or with OversizePayload
+	// "truncate" have data keys dropped until they fit.
+	MaxPayloadSize  int    `mapstructure:"max_payload_size"`
+	OversizePayload string `mapstructure:"oversize_payload"`
+}
+
+// FCM modes
+const (
+	FCMModeLive    = "live"
+	FCMModeSandbox = "sandbox"
+)
+
+// SandboxConfig sets how the simulated FCM of the sandbox mode behaves. It
+// records sends in memory, keeping the last MaxRecorded, and answers each
+// after Latency. The rates are the shares of sends it fails: with
+// UNREGISTERED, which deactivates the device, QUOTA_EXCEEDED, which pauses
+// sends for FCM_QUOTA_BACKOFF, and INTERNAL, which is retried.
+type SandboxConfig struct {
+	UnregisteredRate float64       `mapstructure:"unregistered_rate"`
+	QuotaRate        float64       `mapstructure:"quota_rate"`
+	FailureRate      float64       `mapstructure:"failure_rate"`
+	Latency          time.Duration `mapstructure:"latency"`
+	MaxRecorded      int           `mapstructure:"max_recorded"`
+}
+
+// Oversize payload policies
+const (
+	OversizePayloadReject   = "reject"
+	OversizePayloadTruncate = "truncate"
+)
+
+// Campaign quota policies
+const (
+	QuotaPolicySpread   = "spread"
+	QuotaPolicyFailover = "failover"
+)
+
+type CampaignConfig struct {
+	// QuotaPolicy decides what happens when a campaign needs more sends than
+	// the project's remaining daily quota: "spread" defers the rest to the
+	// next quota day, "failover" sends the overflow through FailoverProjects
+	// before deferring.
+	QuotaPolicy  string        `mapstructure:"quota_policy"`
+	TickInterval time.Duration `mapstructure:"tick_interval"`
+	BatchSize    int           `mapstructure:"batch_size"`
+}
+
+// SDKConfig is handed to the client SDK in the per-device config
+type SDKConfig struct {
+	TokenRefreshInterval      time.Duration `mapstructure:"token_refresh_interval"`
+	PermissionRecheckInterval time.Duration `mapstructure:"permission_recheck_interval"`
+}
+
+// TracingConfig controls OpenTelemetry tracing. A trace is sampled at the
+// rate of its tenant if listed, else of its priority if listed, else at
+// SampleRate; rates are between 0 and 1.
+type TracingConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Endpoint    string `mapstructure:"endpoint"` // OTLP/HTTP collector URL
+	ServiceName string `mapstructure:"service_name"`
+
+	SampleRate          float64            `mapstructure:"sample_rate"`
+	TenantSampleRates   map[string]float64 `mapstructure:"tenant_sample_rates"`
+	PrioritySampleRates map[string]float64 `mapstructure:"priority_sample_rates"`
+}
+
+// LockConfig selects the store behind the distributed locks of background
+// jobs: "postgres" (advisory locks) or "redis"
+type LockConfig struct {
+	Backend   string `mapstructure:"backend"`
+	KeyPrefix string `mapstructure:"key_prefix"`
+}
+
+// AlertingConfig controls the built-in alert rules engine. Rules come from
+// Builtin, Rules and the admin API; one instance at a time evaluates them
+// every EvaluationInterval and POSTs firing and resolved alerts to the
+// rule's webhook, or WebhookURL, and sends them to SlackWebhookURL and Email
+// if set. Delivery counts older than Retention are deleted, so it bounds
+// rule windows.
+type AlertingConfig struct {
+	Enabled            bool               `mapstructure:"enabled"`
+	EvaluationInterval time.Duration      `mapstructure:"evaluation_interval"`
+	FlushInterval      time.Duration      `mapstructure:"flush_interval"` // how often workers store delivery counts
+	Retention          time.Duration      `mapstructure:"retention"`
+	WebhookURL         string             `mapstructure:"webhook_url"`
+	WebhookTimeout     time.Duration      `mapstructure:"webhook_timeout"`
+	SlackWebhookURL    string             `mapstructure:"slack_webhook_url"`
+	Email              AlertEmailConfig   `mapstructure:"email"`
+	Builtin            AlertBuiltinConfig `mapstructure:"builtin"`
+	Rules              []AlertRuleConfig  `mapstructure:"rules"`
+}
+
+// AlertBuiltinConfig sets the thresholds of the built-in rules, which watch
+// the dead letter queue and the delivery outcomes of every platform and
+// project without having to be written out as rules. Each fires when its
+// metric goes over Threshold, and is off while Threshold is 0.
+type AlertBuiltinConfig struct {
+	DeadLetterGrowth AlertThresholdConfig `mapstructure:"dead_letter_growth"`
+	DeadLetterDepth  AlertThresholdConfig `mapstructure:"dead_letter_depth"`
+	FailureRate      AlertThresholdConfig `mapstructure:"failure_rate"`
+	RetryRate        AlertThresholdConfig `mapstructure:"retry_rate"`
+	FCMErrorRate     AlertThresholdConfig `mapstructure:"fcm_error_rate"`
+}
+
+// AlertThresholdConfig is the threshold of a built-in rule. MinEvents only
+// applies to the rate rules.
+type AlertThresholdConfig struct {
+	Threshold float64       `mapstructure:"threshold"`
+	Window    time.Duration `mapstructure:"window"`
+	MinEvents int64         `mapstructure:"min_events"`
+}
+
+// Rules returns the built-in rules whose threshold is set, named after
+// their metric but for dead_letter_growth, a dead_letters rule
+func (c AlertBuiltinConfig) Rules() []AlertRuleConfig {
+	builtins := []struct {
+		name, metric string
+		threshold    AlertThresholdConfig
+	}{
+		{"dead_letter_growth", "dead_letters", c.DeadLetterGrowth},
+		{"dead_letter_depth", "dead_letter_depth", c.DeadLetterDepth},
+		{"failure_rate", "failure_rate", c.FailureRate},
+		{"retry_rate", "retry_rate", c.RetryRate},
+		{"fcm_error_rate", "fcm_error_rate", c.FCMErrorRate},
+	}
+
+	var rules []AlertRuleConfig
+	for _, b := range builtins {
+		if b.threshold.Threshold == 0 {
+			continue
+		}
+		rules = append(rules, AlertRuleConfig{
+			Name:      b.name,
+			Metric:    b.metric,
+			Operator:  ">",
+			Threshold: b.threshold.Threshold,
+			Window:    b.threshold.Window,
+			MinEvents: b.threshold.MinEvents,
+		})
+	}
+	return rules
+}
+
+// AlertEmailConfig sends alerts by email through an SMTP server, From each
+// of To. The server is used with STARTTLS when it offers it, and with
+// Username and Password if set. Email is off without a host or recipients.
+type AlertEmailConfig struct {
+	SMTPHost string   `mapstructure:"smtp_host"`
+	SMTPPort int      `mapstructure:"smtp_port"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// Enabled reports whether alerts are sent by email
+func (c AlertEmailConfig) Enabled() bool {
+	return c.SMTPHost != "" && len(c.To) > 0
+}
+
+// AlertRuleConfig is an alert rule from the config file. Metric is
+// failure_rate, failures, dead_letters, dead_letter_depth, retry_rate or
+// fcm_error_rate; Filter may match deliveries on platform, project and
+// category.
+type AlertRuleConfig struct {
+	Name       string            `mapstructure:"name"`
+	Metric     string            `mapstructure:"metric"`
+	Filter     map[string]string `mapstructure:"filter"`
+	Operator   string            `mapstructure:"operator"`
+	Threshold  float64           `mapstructure:"threshold"`
+	Window     time.Duration     `mapstructure:"window"`
+	MinEvents  int64             `mapstructure:"min_events"`
+	WebhookURL string            `mapstructure:"webhook_url"`
+}
+
+// SoftLaunchConfig puts new tenants in soft launch until an admin lifts it:
+// their sends go to their test users' devices only, at most DailySendCap per
+// UTC day, and broadcasts are dry runs. Requests without a tenant are exempt.
+type SoftLaunchConfig struct {
+	Enabled      bool `mapstructure:"enabled"`
+	DailySendCap int  `mapstructure:"daily_send_cap"` // tenants can override it
+}
+
+// WebhooksConfig configures the webhooks tenants register. Workers cache
+// each tenant's webhooks for CacheTTL, so a new or deleted webhook can take
+// that long to take effect on other instances. A delivery is attempted up
+// to MaxAttempts times, backing off from InitialBackoff to MaxBackoff, and
+// an endpoint that fails FailureThreshold times in a row is skipped for
+// OpenDuration. The last DeliveryLogSize deliveries are kept in memory.
+type WebhooksConfig struct {
+	Timeout          time.Duration `mapstructure:"timeout"`
+	CacheTTL         time.Duration `mapstructure:"cache_ttl"`
+	MaxAttempts      int           `mapstructure:"max_attempts"`
+	InitialBackoff   time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff       time.Duration `mapstructure:"max_backoff"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	OpenDuration     time.Duration `mapstructure:"open_duration"`
+	DeliveryLogSize  int           `mapstructure:"delivery_log_size"`
+}
+
+// SchedulerConfig configures deferred deliveries, e.g. sends at a local
+// time. Every TickInterval, up to BatchSize due deliveries are enqueued.
+// Devices that haven't reported a timezone get DefaultTimezone.
+type SchedulerConfig struct {
+	// Port serves health, readiness and metrics of the scheduler process
+	// (cmd/scheduler)
+	Port            string        `mapstructure:"port"`
+	TickInterval    time.Duration `mapstructure:"tick_interval"`
+	BatchSize       int           `mapstructure:"batch_size"`
+	DefaultTimezone string        `mapstructure:"default_timezone"`
+}
+
+// DigestConfig configures digests. When enabled, low-priority sends are
+// buffered per user and delivered together Interval after the first of
+// them, as one notification made from Title and Body. In those, {{count}}
+// is the number of notifications and {{titles}} their most recent titles.
+type DigestConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	Title    string        `mapstructure:"title"`
+	Body     string        `mapstructure:"body"`
+}
+
+// QuietHoursConfig configures quiet hours. When enabled, notifications that
+// would reach a device between Start and End (HH:MM, e.g. 22:00 and 08:00)
+// in its timezone are held back until End, unless their category or request
+// bypasses quiet hours.
+type QuietHoursConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Start   string `mapstructure:"start"`
+	End     string `mapstructure:"end"`
+}
+
+// CategoriesConfig holds the policies of notification categories by name,
+// e.g. transactional, marketing and system. Notifications of other
+// categories, or without one, get the service's defaults.
+type CategoriesConfig map[string]CategoryConfig
+
+// CategoryConfig is the processing policy of the notifications of a
+// category, for what their request doesn't set: MaxRetries (unset for the
+// queue's), TTL (0 for none), whether they are delivered during quiet hours,
+// and FrequencyCap, how many of them a user gets per FrequencyWindow at most
+// (0 for no cap).
+type CategoryConfig struct {
+	MaxRetries       *int          `mapstructure:"max_retries"`
+	TTL              time.Duration `mapstructure:"ttl"`
+	BypassQuietHours bool          `mapstructure:"bypass_quiet_hours"`
+	FrequencyCap     int           `mapstructure:"frequency_cap"`
+	FrequencyWindow  time.Duration `mapstructure:"frequency_window"`
+}
+
+// InboxConfig configures the inbox. When enabled, workers store every
+// notification they process in its user's inbox.
+type InboxConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// RealtimeConfig configures delivery to open app sessions over WebSockets
+// and Server-Sent Events.
+// Bus is "memory" for a single replica or "redis" to fan events out over
+// Redis pub/sub on Channel; sessions are then tracked under KeyPrefix, and
+// count as connected for PresenceTTL after their replica last refreshed
+// them. Connections are pinged every PingInterval. Browsers may connect
+// from AllowedOrigins, or from anywhere if it is empty.
+type RealtimeConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Bus            string        `mapstructure:"bus"`
+	Channel        string        `mapstructure:"channel"`
+	KeyPrefix      string        `mapstructure:"key_prefix"`
+	PresenceTTL    time.Duration `mapstructure:"presence_ttl"`
+	PingInterval   time.Duration `mapstructure:"ping_interval"`
+	SessionBuffer  int           `mapstructure:"session_buffer"` // events queued per session before they are dropped
+	AllowedOrigins []string      `mapstructure:"allowed_origins"`
+}
+
+// DevicesConfig configures device management
+type DevicesConfig struct {
+	Import  DeviceImportConfig  `mapstructure:"import"`
+	Cleanup DeviceCleanupConfig `mapstructure:"cleanup"`
+}
+
+// DeviceImportConfig configures bulk device imports. Rows are upserted
+// BatchSize at a time, and the first MaxErrors rejected rows are kept on
+// the import.
+type DeviceImportConfig struct {
+	BatchSize int `mapstructure:"batch_size"`
+	MaxErrors int `mapstructure:"max_errors"`
+}
+
+// DeviceCleanupConfig configures the device cleanup job. When enabled, one
+// instance at a time runs it every Interval: devices not seen for
+// StaleAfter are deactivated, and devices deactivated longer than Retention
+// ago are deleted, BatchSize rows at a time.
+type DeviceCleanupConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Interval   time.Duration `mapstructure:"interval"`
+	StaleAfter time.Duration `mapstructure:"stale_after"`
+	Retention  time.Duration `mapstructure:"retention"`
+	BatchSize  int           `mapstructure:"batch_size"`
+}
+
+// PrivacyConfig configures user data deletion. Erased users are remembered
+// for ErasureWindow, which should cover the longest a message can stay
+// queued, retried or dead-lettered, so workers drop messages queued for them
+// before the erasure. Workers reload the erasures every ErasureRefresh.
+type PrivacyConfig struct {
+	ErasureWindow  time.Duration `mapstructure:"erasure_window"`
+	ErasureRefresh time.Duration `mapstructure:"erasure_refresh"`
+}
+
+// RetentionConfig configures how long stored data is kept. When enabled, one
+// instance at a time purges every Interval, BatchSize rows at a time:
+// notifications and inbox items older than Notifications, and delivery
+// attempts, token delivery results and dry runs older than DeliveryLogs.
+// Messages in the dead-letter and malformed queues expire after DeadLetters
+// whether or not the purge is enabled. Outbox messages are deleted Outbox
+// after they were published.
+type RetentionConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Interval      time.Duration `mapstructure:"interval"`
+	Notifications time.Duration `mapstructure:"notifications"`
+	DeliveryLogs  time.Duration `mapstructure:"delivery_logs"`
+	DeadLetters   time.Duration `mapstructure:"dead_letters"`
+	Outbox        time.Duration `mapstructure:"outbox"`
+	BatchSize     int           `mapstructure:"batch_size"`
+}
+
+// OutboxConfig configures the transactional outbox of sends. When enabled, a
+// send's notification record and queue message are stored in one
+// transaction before the message is published. Messages still unpublished
+// RelayDelay after they were stored, e.g. because the broker was down, are
+// published by one instance at a time every RelayInterval, BatchSize at a
+// time.
+type OutboxConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	RelayInterval time.Duration `mapstructure:"relay_interval"`
+	RelayDelay    time.Duration `mapstructure:"relay_delay"`
+	BatchSize     int           `mapstructure:"batch_size"`
+}
+
+// EventSinkConfig configures streaming delivery events (sent, failed and
+// opened) to an analytics warehouse. Backend is "clickhouse" or "bigquery",
+// or empty to stream nothing. Up to BufferSize events wait in memory and are
+// written BatchSize at a time, at least every FlushInterval; a batch that
+// still fails after MaxAttempts writes is dropped. Each write times out
+// after Timeout.
+type EventSinkConfig struct {
+	Backend       string               `mapstructure:"backend"`
+	BufferSize    int                  `mapstructure:"buffer_size"`
+	BatchSize     int                  `mapstructure:"batch_size"`
+	FlushInterval time.Duration        `mapstructure:"flush_interval"`
+	MaxAttempts   int                  `mapstructure:"max_attempts"`
+	Timeout       time.Duration        `mapstructure:"timeout"`
+	ClickHouse    ClickHouseSinkConfig `mapstructure:"clickhouse"`
+	BigQuery      BigQuerySinkConfig   `mapstructure:"bigquery"`
+}
+
+// ClickHouseSinkConfig is where the event sink inserts into ClickHouse: the
+// HTTP interface at URL (e.g. http://clickhouse:8123) and Table, which may
+// be qualified with its database
+type ClickHouseSinkConfig struct {
+	URL      string `mapstructure:"url"`
+	Table    string `mapstructure:"table"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// BigQuerySinkConfig is where the event sink streams into BigQuery. The
+// service account in CredentialsFile is used, or the application default
+// credentials if it is empty.
+type BigQuerySinkConfig struct {
 	ProjectID       string `mapstructure:"project_id"`
-	UseFile         bool   `mapstructure:"use_file"`
+	Dataset         string `mapstructure:"dataset"`
+	Table           string `mapstructure:"table"`
+	CredentialsFile string `mapstructure:"credentials_file"`
+}
+
+// ArchiveConfig configures archiving dead letters to object storage before
+// they expire. When enabled, one instance at a time moves the dead letters
+// older than After out of the dead letter queue every Interval, into gzipped
+// NDJSON objects of up to BatchSize messages under Prefix in Bucket. Backend
+// is "s3" or "gcs".
+type ArchiveConfig struct {
+	Enabled   bool             `mapstructure:"enabled"`
+	Interval  time.Duration    `mapstructure:"interval"`
+	After     time.Duration    `mapstructure:"after"`
+	BatchSize int              `mapstructure:"batch_size"`
+	Backend   string           `mapstructure:"backend"`
+	Bucket    string           `mapstructure:"bucket"`
+	Prefix    string           `mapstructure:"prefix"`
+	S3        S3ArchiveConfig  `mapstructure:"s3"`
+	GCS       GCSArchiveConfig `mapstructure:"gcs"`
+}
+
+// S3ArchiveConfig configures the S3 archive backend. Credentials come from
+// the AWS SDK's default chain (environment, shared config, instance role).
+// Endpoint and UsePathStyle are for S3-compatible stores such as MinIO.
+type S3ArchiveConfig struct {
+	Region       string `mapstructure:"region"`
+	Endpoint     string `mapstructure:"endpoint"`
+	UsePathStyle bool   `mapstructure:"use_path_style"`
 }
 
+// GCSArchiveConfig configures the Google Cloud Storage archive backend. The
+// service account in CredentialsFile is used, or the application default
+// credentials if it is empty.
+type GCSArchiveConfig struct {
+	CredentialsFile string `mapstructure:"credentials_file"`
+}
+
+// LogConfig configures logging. Redact masks push tokens, user IDs,
+// notification content and client IPs in every log entry; turn it off only
+// as a break-glass measure while debugging.
 type LogConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
+	Redact bool   `mapstructure:"redact"`
 }
 
 type RabbitMQConfig struct {
+	Name     string `mapstructure:"name"`
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	VHost    string `mapstructure:"vhost"`
+
+	ReconnectInitialBackoff time.Duration `mapstructure:"reconnect_initial_backoff"`
+	ReconnectMaxBackoff     time.Duration `mapstructure:"reconnect_max_backoff"`
+
+	PublisherConfirms bool          `mapstructure:"publisher_confirms"`
+	ConfirmTimeout    time.Duration `mapstructure:"confirm_timeout"`
+
+	// TLS switches to amqps. The CA file verifies the broker (system roots
+	// are used if empty); the client cert/key enable mutual TLS.
+	TLS            bool   `mapstructure:"tls"`
+	CACertFile     string `mapstructure:"ca_cert_file"`
+	ClientCertFile string `mapstructure:"client_cert_file"`
+	ClientKeyFile  string `mapstructure:"client_key_file"`
+	TLSServerName  string `mapstructure:"tls_server_name"`
+
+	ConnectionName string        `mapstructure:"connection_name"`
+	Heartbeat      time.Duration `mapstructure:"heartbeat"`
+	DialTimeout    time.Duration `mapstructure:"dial_timeout"`
 }
 
+// KafkaConfig is used when the queue backend is "kafka". Every queue is a
+// topic consumed by the consumer group "<GroupID>.<topic>".
+type KafkaConfig struct {
+	Brokers           []string      `mapstructure:"brokers"`
+	GroupID           string        `mapstructure:"group_id"`
+	ClientID          string        `mapstructure:"client_id"`
+	Partitions        int           `mapstructure:"partitions"`
+	ReplicationFactor int           `mapstructure:"replication_factor"`
+	DialTimeout       time.Duration `mapstructure:"dial_timeout"`
+}
+
+// NATSConfig is used when the queue backend is "nats". Every queue is a
+// JetStream work-queue stream on the subject "<SubjectPrefix>.<queue>".
+type NATSConfig struct {
+	URL           string        `mapstructure:"url"`
+	Name          string        `mapstructure:"name"`
+	SubjectPrefix string        `mapstructure:"subject_prefix"`
+	Durable       string        `mapstructure:"durable"`
+	Replicas      int           `mapstructure:"replicas"`
+	AckWait       time.Duration `mapstructure:"ack_wait"`
+}
+
+// Queue backends
+const (
+	QueueBackendRabbitMQ = "rabbitmq"
+	QueueBackendKafka    = "kafka"
+	QueueBackendNATS     = "nats"
+	QueueBackendPostgres = "postgres"
+	QueueBackendMemory   = "memory" // in process, for local development
+)
+
+// Queue message encodings
+const (
+	QueueEncodingJSON     = "json"
+	QueueEncodingProtobuf = "protobuf"
+)
+
 type QueueConfig struct {
-	Worker      WorkerConfig      `mapstructure:"worker"`
-	Retry       RetryConfig       `mapstructure:"retry"`
-	Validation  ValidationConfig  `mapstructure:"validation"`
+	// Backend selects the message broker behind the push queue
+	Backend string `mapstructure:"backend"`
+	// Encoding selects how push messages are published; either is consumed
+	Encoding   string              `mapstructure:"encoding"`
+	Worker     WorkerConfig        `mapstructure:"worker"`
+	Retry      RetryConfig         `mapstructure:"retry"`
+	Validation ValidationConfig    `mapstructure:"validation"`
+	Bulk       BulkConfig          `mapstructure:"bulk"`
+	Gateway    GatewayConfig       `mapstructure:"gateway"`
+	Postgres   PostgresQueueConfig `mapstructure:"postgres"`
+}
+
+// PostgresQueueConfig is used when the queue backend is "postgres". Messages
+// are rows of the queue_messages table.
+type PostgresQueueConfig struct {
+	// PollInterval bounds how long a consumer waits without a notification,
+	// e.g. for a delayed message to become due
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// VisibilityTimeout is how long a claimed message stays locked before
+	// another consumer may claim it again
+	VisibilityTimeout time.Duration `mapstructure:"visibility_timeout"`
 }
 
 type WorkerConfig struct {
+	// Port serves health, readiness, metrics and the worker admin API when
+	// the worker runs on its own (cmd/worker)
+	Port          string        `mapstructure:"port"`
 	PrefetchCount int           `mapstructure:"prefetch_count"`
+	Concurrency   int           `mapstructure:"concurrency"`
 	PollInterval  time.Duration `mapstructure:"poll_interval"`
 	BatchSize     int           `mapstructure:"batch_size"`
+	// AckBatchSize acks up to this many RabbitMQ deliveries at once, and
+	// AckInterval is the longest an ack waits; 0 or 1 acks one by one
+	AckBatchSize int           `mapstructure:"ack_batch_size"`
+	AckInterval  time.Duration `mapstructure:"ack_interval"`
+	// RecoverPanics turns a panic while processing a message into a retry of
+	// that message instead of a crash
+	RecoverPanics bool `mapstructure:"recover_panics"`
+	// RunJobs runs the time-based background jobs in the worker; turn it off
+	// when they run in the scheduler process (cmd/scheduler)
+	RunJobs bool `mapstructure:"run_jobs"`
+	// DrainTimeout is how long shutdown waits for the messages being
+	// processed to finish after consumption stops; keep it below the
+	// orchestrator's grace period
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// StallTimeout is how long messages may be in flight or waiting without
+	// one finishing before /health/details reports the worker stalled
+	StallTimeout time.Duration `mapstructure:"stall_timeout"`
+
+	// Resource-aware caps on Concurrency
+	MaxConcurrencyPerCPU int     `mapstructure:"max_concurrency_per_cpu"`
+	MemoryPerHandlerMB   int     `mapstructure:"memory_per_handler_mb"`
+	MemoryLimitRatio     float64 `mapstructure:"memory_limit_ratio"`
+
+	Backpressure BackpressureConfig `mapstructure:"backpressure"`
+	Autoscale    AutoscaleConfig    `mapstructure:"autoscale"`
 }
 
+// BackpressureConfig slows consumption while FCM fails a large share of
+// sends. Every CheckInterval, if at least MinSends messages were sent in the
+// last minute and FCM failed Threshold of them or more, the worker halves its
+// speed, down to MinFactor of its prefetch and concurrency, and waits up to
+// MaxDelay between deliveries. Otherwise it speeds up by RecoveryStep until
+// it is back to full speed.
+type BackpressureConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Threshold     float64       `mapstructure:"threshold"`
+	MinSends      int64         `mapstructure:"min_sends"`
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+	MinFactor     float64       `mapstructure:"min_factor"`
+	RecoveryStep  float64       `mapstructure:"recovery_step"`
+	MaxDelay      time.Duration `mapstructure:"max_delay"`
+}
+
+// AutoscaleConfig configures the signals for scaling workers. Every
+// Interval, the worker exports the backlog of the push queues and how busy
+// its pool was. When enabled, it also sizes its pool, between MinConcurrency
+// and MaxConcurrency, to the handlers that were busy plus one per
+// TargetBacklog messages waiting.
+type AutoscaleConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Interval       time.Duration `mapstructure:"interval"`
+	MinConcurrency int           `mapstructure:"min_concurrency"`
+	MaxConcurrency int           `mapstructure:"max_concurrency"` // 0 for the resource limit
+	TargetBacklog  int           `mapstructure:"target_backlog"`
+}
+
+// RetryConfig sets how often failed sends are retried. Each Tiers entry is a
+// retry queue with that message TTL; retry n waits in tier n, and retries
+// beyond the last tier reuse it. Sends may override MaxRetries up to
+// MaxRetriesCap.
 type RetryConfig struct {
-	MaxRetries int           `mapstructure:"max_retries"`
-	Backoff    time.Duration `mapstructure:"backoff"`
+	MaxRetries    int             `mapstructure:"max_retries"`
+	MaxRetriesCap int             `mapstructure:"max_retries_cap"`
+	Tiers         []time.Duration `mapstructure:"tiers"`
+}
+
+// BulkConfig configures bulk sends and campaigns. Recipients' devices are
+// looked up LookupSize users per query, and their mutes checked Concurrency
+// users at a time. Messages are published BatchSize at a time.
+type BulkConfig struct {
+	Concurrency int `mapstructure:"concurrency"`
+	BatchSize   int `mapstructure:"batch_size"`
+	LookupSize  int `mapstructure:"lookup_size"`
+}
+
+// Gateway modes: how gateway messages reach FCM
+const (
+	// GatewayModeEnqueue publishes them to the internal push queues first
+	GatewayModeEnqueue = "enqueue"
+	// GatewayModeDirect sends them from the gateway queue's consumer; only
+	// their retries go through the internal queues
+	GatewayModeDirect = "direct"
+)
+
+// GatewayConfig configures the gateway's push.queue. Mode is how its
+// messages are processed. On RabbitMQ the queue is bound to Exchange, of
+// ExchangeType direct or topic, with each of Bindings; RoutingKeys is a
+// shorthand of bindings without handling of their own. Brokers lists
+// additional (e.g. regional) brokers whose gateway push.queue is consumed
+// alongside the primary broker's. BrokerHosts is a shorthand of host[:port]
+// entries that share the primary's credentials.
+// UserResolver resolves the tokens of users unknown to the device registry.
+// TypePriorities and TypeTTLs are the priority and TTL of gateway messages by
+// their type; a message's own priority wins over its type's.
+type GatewayConfig struct {
+	Mode           string                   `mapstructure:"mode"`
+	Exchange       string                   `mapstructure:"exchange"`
+	ExchangeType   string                   `mapstructure:"exchange_type"`
+	Bindings       []GatewayBindingConfig   `mapstructure:"bindings"`
+	RoutingKeys    []string                 `mapstructure:"routing_keys"`
+	Brokers        []RabbitMQConfig         `mapstructure:"brokers"`
+	BrokerHosts    []string                 `mapstructure:"broker_hosts"`
+	UserResolver   UserResolverConfig       `mapstructure:"user_resolver"`
+	TypePriorities map[string]string        `mapstructure:"type_priorities"`
+	TypeTTLs       map[string]time.Duration `mapstructure:"type_ttls"`
+}
+
+// GatewayBindingConfig binds push.queue with a routing key, a pattern like
+// push.marketing.# on a topic exchange, and sets how the messages published
+// with a matching key are handled: the Type of those without one, the
+// Priority of those without their own, and the gateway Mode, if not the
+// gateway's
+type GatewayBindingConfig struct {
+	RoutingKey string `mapstructure:"routing_key"`
+	Type       string `mapstructure:"type"`
+	Priority   string `mapstructure:"priority"`
+	Mode       string `mapstructure:"mode"`
+}
+
+// UserResolverConfig points at an external system that knows users' push
+// tokens. Gateway messages for users with no registered devices are resolved
+// through it before falling back to the message's push_token. URL is an
+// http(s):// endpoint or a grpc:// or grpcs:// address; empty disables it.
+type UserResolverConfig struct {
+	URL       string        `mapstructure:"url"`
+	AuthToken string        `mapstructure:"auth_token"`
+	Timeout   time.Duration `mapstructure:"timeout"`
 }
 
 type ValidationConfig struct {
-	Enabled bool          `mapstructure:"enabled"`
-	Timeout time.Duration `mapstructure:"timeout"`
+	Enabled     bool          `mapstructure:"enabled"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	Concurrency int           `mapstructure:"concurrency"`
 }
 
-func Load() (*Config, error) {
+// Load reads the configuration from file, a YAML or JSON file, or if file
+// is empty from config.yaml in the working directory, ./config or
+// /etc/push-service, if there is one. Environment variables override the
+// file's settings. Keys of the file that match no setting, e.g. misspelled
+// ones, are reported as a warning.
+func Load(file string) (*Config, error) {
+	config, unknown, err := load(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		fmt.Printf("Warning: unknown settings in %s: %s\n", viper.ConfigFileUsed(), strings.Join(unknown, ", "))
+	}
+	return config, nil
+}
+
+// Validate loads the configuration like Load, but fails on unknown keys
+// too. It returns the config file used, if any.
+func Validate(file string) (string, error) {
+	_, unknown, err := load(file)
+	if err != nil {
+		return viper.ConfigFileUsed(), err
+	}
+	if len(unknown) > 0 {
+		return viper.ConfigFileUsed(), fmt.Errorf("unknown settings: %s", strings.Join(unknown, ", "))
+	}
+	return viper.ConfigFileUsed(), nil
+}
+
+// load reads and validates the configuration, and returns the keys of the
+// config file that match no setting
+func load(file string) (*Config, []string, error) {
 	// Load .env file if exists
 	godotenv.Load() // This will load .env file, but doesn't fail if it doesn't exist
 
@@ -96,18 +876,26 @@ func Load() (*Config, error) {
 	}
 	godotenv.Load(fmt.Sprintf(".env.%s", env))
 
-	// Set up Viper
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./config")
-	viper.AddConfigPath("/etc/push-service/")
+	// Set up Viper; the file's type follows its extension
+	if file != "" {
+		viper.SetConfigFile(file)
+	} else {
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath(".")
+		viper.AddConfigPath("./config")
+		viper.AddConfigPath("/etc/push-service/")
+	}
 
 	// Set defaults
 	setDefaults()
 
-	// Read config file (optional - can use env vars only)
+	// Read config file (optional - can use env vars only, unless a file was
+	// given)
 	if err := viper.ReadInConfig(); err != nil {
+		if file != "" {
+			return nil, nil, fmt.Errorf("unable to read config file %s: %w", file, err)
+		}
 		fmt.Printf("Warning: Config file not found, using environment variables: %v\n", err)
 	}
 
@@ -116,51 +904,268 @@ func Load() (*Config, error) {
 
 	// Unmarshal config
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
+	var metadata mapstructure.Metadata
+	if err := viper.Unmarshal(&config, func(decoder *mapstructure.DecoderConfig) {
+		decoder.Metadata = &metadata
+	}); err != nil {
+		return nil, nil, fmt.Errorf("unable to decode config into struct: %w", err)
+	}
+
+	config.Queue.Gateway.resolveBrokers(config.RabbitMQ)
+	config.Queue.Gateway.resolveBindings()
+
+	// Viper can't decode maps from env vars; they use key=value lists
+	if err := parseSampleRates(os.Getenv("TRACING_TENANT_SAMPLE_RATES"), &config.Tracing.TenantSampleRates); err != nil {
+		return nil, nil, fmt.Errorf("invalid TRACING_TENANT_SAMPLE_RATES: %w", err)
+	}
+	if err := parseSampleRates(os.Getenv("TRACING_PRIORITY_SAMPLE_RATES"), &config.Tracing.PrioritySampleRates); err != nil {
+		return nil, nil, fmt.Errorf("invalid TRACING_PRIORITY_SAMPLE_RATES: %w", err)
+	}
+	if err := parseKeyValues(os.Getenv("QUEUE_GATEWAY_TYPE_PRIORITIES"), &config.Queue.Gateway.TypePriorities, func(value string) (string, error) {
+		return value, nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("invalid QUEUE_GATEWAY_TYPE_PRIORITIES: %w", err)
+	}
+	if err := parseKeyValues(os.Getenv("QUEUE_GATEWAY_TYPE_TTLS"), &config.Queue.Gateway.TypeTTLs, time.ParseDuration); err != nil {
+		return nil, nil, fmt.Errorf("invalid QUEUE_GATEWAY_TYPE_TTLS: %w", err)
 	}
 
 	// Validate required fields
 	if err := validateConfig(&config); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return &config, nil
+	sort.Strings(metadata.Unused)
+	return &config, metadata.Unused, nil
 }
 
 func setDefaults() {
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.mode", "debug")
 	viper.SetDefault("server.shutdown_timeout", "30s")
+	viper.SetDefault("server.run_worker", true)
+
+	// Startup defaults
+	viper.SetDefault("startup.wait_timeout", "60s")
+	viper.SetDefault("startup.initial_backoff", "1s")
+	viper.SetDefault("startup.max_backoff", "10s")
 
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", "5432")
 	viper.SetDefault("database.name", "push_service")
 	viper.SetDefault("database.ssl_mode", "disable")
-	viper.SetDefault("database.max_open_conns", 25)
-	viper.SetDefault("database.max_idle_conns", 25)
-	viper.SetDefault("database.conn_max_lifetime", "5m")
+	viper.SetDefault("database.max_open_conns", 50)
+	viper.SetDefault("database.max_idle_conns", 5)
+	viper.SetDefault("database.conn_max_lifetime", "30m")
+	viper.SetDefault("database.conn_max_idle_time", "5m")
+	viper.SetDefault("database.health_check_period", "1m")
+	viper.SetDefault("database.replica_url", "")
+	viper.SetDefault("database.auto_migrate", false)
 
 	viper.SetDefault("redis.host", "localhost")
 	viper.SetDefault("redis.port", "6379")
 	viper.SetDefault("redis.db", 0)
 
+	viper.SetDefault("campaign.quota_policy", QuotaPolicySpread)
+	viper.SetDefault("campaign.tick_interval", "10s")
+	viper.SetDefault("campaign.batch_size", 500)
+
+	viper.SetDefault("sdk.token_refresh_interval", "24h")
+	viper.SetDefault("sdk.permission_recheck_interval", "24h")
+
 	viper.SetDefault("rabbitmq.host", "localhost")
 	viper.SetDefault("rabbitmq.port", "5672")
 	viper.SetDefault("rabbitmq.username", "guest")
 	viper.SetDefault("rabbitmq.password", "guest")
 	viper.SetDefault("rabbitmq.vhost", "/")
+	viper.SetDefault("rabbitmq.reconnect_initial_backoff", "1s")
+	viper.SetDefault("rabbitmq.reconnect_max_backoff", "30s")
+	viper.SetDefault("rabbitmq.publisher_confirms", true)
+	viper.SetDefault("rabbitmq.confirm_timeout", "5s")
+	viper.SetDefault("rabbitmq.tls", false)
+	viper.SetDefault("rabbitmq.connection_name", "push-service")
+	viper.SetDefault("rabbitmq.heartbeat", "10s")
+	viper.SetDefault("rabbitmq.dial_timeout", "30s")
+
+	viper.SetDefault("kafka.brokers", []string{"localhost:9092"})
+	viper.SetDefault("kafka.group_id", "push-service")
+	viper.SetDefault("kafka.client_id", "push-service")
+	viper.SetDefault("kafka.partitions", 6)
+	viper.SetDefault("kafka.replication_factor", 1)
+	viper.SetDefault("kafka.dial_timeout", "10s")
+
+	viper.SetDefault("nats.url", "nats://localhost:4222")
+	viper.SetDefault("nats.name", "push-service")
+	viper.SetDefault("nats.subject_prefix", "push")
+	viper.SetDefault("nats.durable", "push-service")
+	viper.SetDefault("nats.replicas", 1)
+	viper.SetDefault("nats.ack_wait", "30s")
 
+	viper.SetDefault("queue.backend", QueueBackendRabbitMQ)
+	viper.SetDefault("queue.encoding", QueueEncodingJSON)
+	viper.SetDefault("queue.postgres.poll_interval", "1s")
+	viper.SetDefault("queue.postgres.visibility_timeout", "5m")
+	viper.SetDefault("queue.worker.port", "8081")
 	viper.SetDefault("queue.worker.prefetch_count", 10)
+	viper.SetDefault("queue.worker.concurrency", 10)
+	viper.SetDefault("queue.worker.max_concurrency_per_cpu", 8)
+	viper.SetDefault("queue.worker.memory_per_handler_mb", 16)
+	viper.SetDefault("queue.worker.memory_limit_ratio", 0.9)
 	viper.SetDefault("queue.worker.poll_interval", "1s")
 	viper.SetDefault("queue.worker.batch_size", 10)
+	viper.SetDefault("queue.worker.ack_batch_size", 0)
+	viper.SetDefault("queue.worker.ack_interval", "100ms")
+	viper.SetDefault("queue.worker.recover_panics", true)
+	viper.SetDefault("queue.worker.run_jobs", true)
+	viper.SetDefault("queue.worker.drain_timeout", "25s")
+	viper.SetDefault("queue.worker.stall_timeout", "5m")
+	viper.SetDefault("queue.worker.backpressure.enabled", true)
+	viper.SetDefault("queue.worker.backpressure.threshold", 0.5)
+	viper.SetDefault("queue.worker.backpressure.min_sends", 100)
+	viper.SetDefault("queue.worker.backpressure.check_interval", "10s")
+	viper.SetDefault("queue.worker.backpressure.min_factor", 0.1)
+	viper.SetDefault("queue.worker.backpressure.recovery_step", 0.1)
+	viper.SetDefault("queue.worker.backpressure.max_delay", "1s")
+	viper.SetDefault("queue.worker.autoscale.enabled", false)
+	viper.SetDefault("queue.worker.autoscale.interval", "15s")
+	viper.SetDefault("queue.worker.autoscale.min_concurrency", 1)
+	viper.SetDefault("queue.worker.autoscale.max_concurrency", 0)
+	viper.SetDefault("queue.worker.autoscale.target_backlog", 20)
 	viper.SetDefault("queue.retry.max_retries", 5)
-	viper.SetDefault("queue.retry.backoff", "5s")
+	viper.SetDefault("queue.retry.max_retries_cap", 10)
+	viper.SetDefault("queue.gateway.mode", GatewayModeEnqueue)
+	viper.SetDefault("queue.gateway.exchange", "notifications.direct")
+	viper.SetDefault("queue.gateway.exchange_type", "direct")
+	viper.SetDefault("queue.gateway.user_resolver.timeout", "2s")
+	viper.SetDefault("queue.retry.tiers", []string{"30s", "2m", "10m"})
 	viper.SetDefault("queue.validation.enabled", true)
 	viper.SetDefault("queue.validation.timeout", "5s")
+	viper.SetDefault("queue.validation.concurrency", 10)
+	viper.SetDefault("queue.bulk.concurrency", 10)
+	viper.SetDefault("queue.bulk.batch_size", 100)
+	viper.SetDefault("queue.bulk.lookup_size", 1000)
+
+	viper.SetDefault("fcm.mode", FCMModeLive)
+	viper.SetDefault("fcm.sandbox.latency", "20ms")
+	viper.SetDefault("fcm.sandbox.max_recorded", 1000)
+	viper.SetDefault("fcm.auth_probe_interval", "1m")
+	viper.SetDefault("fcm.quota_backoff", "1m")
+	viper.SetDefault("fcm.max_payload_size", 4096)
+	viper.SetDefault("fcm.oversize_payload", OversizePayloadReject)
+
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.service_name", "push-service")
+	viper.SetDefault("tracing.sample_rate", 0.1)
+
+	viper.SetDefault("lock.backend", "postgres")
+	viper.SetDefault("lock.key_prefix", "push-service:")
+
+	viper.SetDefault("alerting.enabled", false)
+	viper.SetDefault("alerting.evaluation_interval", "1m")
+	viper.SetDefault("alerting.flush_interval", "10s")
+	viper.SetDefault("alerting.retention", "24h")
+	viper.SetDefault("alerting.webhook_timeout", "5s")
+	viper.SetDefault("alerting.email.smtp_port", 587)
+	viper.SetDefault("alerting.builtin.dead_letter_growth.window", "1h")
+	viper.SetDefault("alerting.builtin.dead_letter_depth.window", "1m")
+	viper.SetDefault("alerting.builtin.failure_rate.window", "10m")
+	viper.SetDefault("alerting.builtin.failure_rate.min_events", 100)
+	viper.SetDefault("alerting.builtin.retry_rate.window", "10m")
+	viper.SetDefault("alerting.builtin.retry_rate.min_events", 100)
+	viper.SetDefault("alerting.builtin.fcm_error_rate.window", "10m")
+	viper.SetDefault("alerting.builtin.fcm_error_rate.min_events", 100)
+
+	viper.SetDefault("soft_launch.enabled", false)
+	viper.SetDefault("soft_launch.daily_send_cap", 1000)
+
+	viper.SetDefault("webhooks.timeout", "5s")
+	viper.SetDefault("webhooks.cache_ttl", "30s")
+	viper.SetDefault("webhooks.max_attempts", 5)
+	viper.SetDefault("webhooks.initial_backoff", "1s")
+	viper.SetDefault("webhooks.max_backoff", "1m")
+	viper.SetDefault("webhooks.failure_threshold", 5)
+	viper.SetDefault("webhooks.open_duration", "1m")
+	viper.SetDefault("webhooks.delivery_log_size", 1000)
+
+	viper.SetDefault("scheduler.port", "8082")
+	viper.SetDefault("scheduler.tick_interval", "10s")
+	viper.SetDefault("scheduler.batch_size", 500)
+	viper.SetDefault("scheduler.default_timezone", "UTC")
+
+	viper.SetDefault("digest.enabled", false)
+	viper.SetDefault("digest.interval", "1h")
+	viper.SetDefault("digest.title", "You have {{count}} new updates")
+	viper.SetDefault("digest.body", "{{titles}}")
+
+	// Quiet hours defaults
+	viper.SetDefault("quiet_hours.enabled", false)
+	viper.SetDefault("quiet_hours.start", "22:00")
+	viper.SetDefault("quiet_hours.end", "08:00")
+
+	// Category policy defaults
+	viper.SetDefault("categories.transactional.max_retries", 8)
+	viper.SetDefault("categories.transactional.bypass_quiet_hours", true)
+	viper.SetDefault("categories.marketing.max_retries", 2)
+	viper.SetDefault("categories.marketing.ttl", "24h")
+	viper.SetDefault("categories.marketing.frequency_window", "24h")
+	viper.SetDefault("categories.system.bypass_quiet_hours", true)
+
+	viper.SetDefault("inbox.enabled", false)
+
+	viper.SetDefault("realtime.enabled", false)
+	viper.SetDefault("realtime.bus", "memory")
+	viper.SetDefault("realtime.channel", "push:realtime")
+	viper.SetDefault("realtime.key_prefix", "push:presence:")
+	viper.SetDefault("realtime.presence_ttl", "1m")
+	viper.SetDefault("realtime.ping_interval", "30s")
+	viper.SetDefault("realtime.session_buffer", 16)
+
+	viper.SetDefault("devices.import.batch_size", 1000)
+	viper.SetDefault("devices.import.max_errors", 100)
+	viper.SetDefault("devices.cleanup.enabled", false)
+	viper.SetDefault("devices.cleanup.interval", "1h")
+	viper.SetDefault("devices.cleanup.stale_after", "2160h")
+	viper.SetDefault("devices.cleanup.retention", "720h")
+	viper.SetDefault("devices.cleanup.batch_size", 1000)
+
+	viper.SetDefault("privacy.erasure_window", "720h")
+	viper.SetDefault("privacy.erasure_refresh", "30s")
+
+	viper.SetDefault("retention.enabled", true)
+	viper.SetDefault("retention.interval", "1h")
+	viper.SetDefault("retention.notifications", "2160h")
+	viper.SetDefault("retention.delivery_logs", "720h")
+	viper.SetDefault("retention.dead_letters", "168h")
+	viper.SetDefault("retention.outbox", "24h")
+	viper.SetDefault("retention.batch_size", 1000)
+
+	viper.SetDefault("outbox.enabled", true)
+	viper.SetDefault("outbox.relay_interval", "1s")
+	viper.SetDefault("outbox.relay_delay", "10s")
+	viper.SetDefault("outbox.batch_size", 100)
+
+	viper.SetDefault("event_sink.backend", "")
+	viper.SetDefault("event_sink.buffer_size", 10000)
+	viper.SetDefault("event_sink.batch_size", 500)
+	viper.SetDefault("event_sink.flush_interval", "5s")
+	viper.SetDefault("event_sink.max_attempts", 3)
+	viper.SetDefault("event_sink.timeout", "10s")
+	viper.SetDefault("event_sink.clickhouse.table", "push_delivery_events")
+	viper.SetDefault("event_sink.bigquery.table", "push_delivery_events")
+
+	viper.SetDefault("archive.enabled", false)
+	viper.SetDefault("archive.interval", "1h")
+	viper.SetDefault("archive.after", "144h")
+	viper.SetDefault("archive.batch_size", 10000)
+	viper.SetDefault("archive.backend", "s3")
+	viper.SetDefault("archive.prefix", "dead-letters/")
+
+	viper.SetDefault("chaos.enabled", false)
+	viper.SetDefault("chaos.fcm_delay", "2s")
 
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.format", "json")
+	viper.SetDefault("log.redact", true)
 }
 
 func bindEnvVars() {
@@ -168,6 +1173,26 @@ func bindEnvVars() {
 	viper.BindEnv("server.port", "SERVER_PORT")
 	viper.BindEnv("server.mode", "SERVER_MODE")
 	viper.BindEnv("server.shutdown_timeout", "SERVER_SHUTDOWN_TIMEOUT")
+	viper.BindEnv("server.run_worker", "SERVER_RUN_WORKER")
+
+	// Startup
+	viper.BindEnv("startup.wait_timeout", "STARTUP_WAIT_TIMEOUT")
+	viper.BindEnv("startup.initial_backoff", "STARTUP_INITIAL_BACKOFF")
+	viper.BindEnv("startup.max_backoff", "STARTUP_MAX_BACKOFF")
+
+	// Access
+	viper.BindEnv("access.internal_cidrs", "ACCESS_INTERNAL_CIDRS")
+	viper.BindEnv("access.internal_header", "ACCESS_INTERNAL_HEADER")
+	viper.BindEnv("access.internal_header_value", "ACCESS_INTERNAL_HEADER_VALUE")
+	viper.BindEnv("access.trusted_proxies", "ACCESS_TRUSTED_PROXIES")
+
+	// Chaos
+	viper.BindEnv("chaos.enabled", "CHAOS_ENABLED")
+	viper.BindEnv("chaos.fcm_delay", "CHAOS_FCM_DELAY")
+	viper.BindEnv("chaos.fcm_delay_rate", "CHAOS_FCM_DELAY_RATE")
+	viper.BindEnv("chaos.fcm_failure_rate", "CHAOS_FCM_FAILURE_RATE")
+	viper.BindEnv("chaos.ack_drop_rate", "CHAOS_ACK_DROP_RATE")
+	viper.BindEnv("chaos.channel_kill_rate", "CHAOS_CHANNEL_KILL_RATE")
 
 	// Database
 	viper.BindEnv("database.host", "DB_HOST")
@@ -179,6 +1204,10 @@ func bindEnvVars() {
 	viper.BindEnv("database.max_open_conns", "DB_MAX_OPEN_CONNS")
 	viper.BindEnv("database.max_idle_conns", "DB_MAX_IDLE_CONNS")
 	viper.BindEnv("database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME")
+	viper.BindEnv("database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME")
+	viper.BindEnv("database.health_check_period", "DB_HEALTH_CHECK_PERIOD")
+	viper.BindEnv("database.replica_url", "DB_REPLICA_URL")
+	viper.BindEnv("database.auto_migrate", "DB_AUTO_MIGRATE")
 
 	// Redis
 	viper.BindEnv("redis.host", "REDIS_HOST")
@@ -192,24 +1221,353 @@ func bindEnvVars() {
 	viper.BindEnv("rabbitmq.username", "RABBITMQ_USERNAME")
 	viper.BindEnv("rabbitmq.password", "RABBITMQ_PASSWORD")
 	viper.BindEnv("rabbitmq.vhost", "RABBITMQ_VHOST")
+	viper.BindEnv("rabbitmq.reconnect_initial_backoff", "RABBITMQ_RECONNECT_INITIAL_BACKOFF")
+	viper.BindEnv("rabbitmq.reconnect_max_backoff", "RABBITMQ_RECONNECT_MAX_BACKOFF")
+	viper.BindEnv("rabbitmq.publisher_confirms", "RABBITMQ_PUBLISHER_CONFIRMS")
+	viper.BindEnv("rabbitmq.confirm_timeout", "RABBITMQ_CONFIRM_TIMEOUT")
+	viper.BindEnv("rabbitmq.tls", "RABBITMQ_TLS")
+	viper.BindEnv("rabbitmq.ca_cert_file", "RABBITMQ_CA_CERT_FILE")
+	viper.BindEnv("rabbitmq.client_cert_file", "RABBITMQ_CLIENT_CERT_FILE")
+	viper.BindEnv("rabbitmq.client_key_file", "RABBITMQ_CLIENT_KEY_FILE")
+	viper.BindEnv("rabbitmq.tls_server_name", "RABBITMQ_TLS_SERVER_NAME")
+	viper.BindEnv("rabbitmq.connection_name", "RABBITMQ_CONNECTION_NAME")
+	viper.BindEnv("rabbitmq.heartbeat", "RABBITMQ_HEARTBEAT")
+	viper.BindEnv("rabbitmq.dial_timeout", "RABBITMQ_DIAL_TIMEOUT")
+
+	// Kafka
+	viper.BindEnv("kafka.brokers", "KAFKA_BROKERS")
+	viper.BindEnv("kafka.group_id", "KAFKA_GROUP_ID")
+	viper.BindEnv("kafka.client_id", "KAFKA_CLIENT_ID")
+	viper.BindEnv("kafka.partitions", "KAFKA_PARTITIONS")
+	viper.BindEnv("kafka.replication_factor", "KAFKA_REPLICATION_FACTOR")
+	viper.BindEnv("kafka.dial_timeout", "KAFKA_DIAL_TIMEOUT")
+
+	// NATS
+	viper.BindEnv("nats.url", "NATS_URL")
+	viper.BindEnv("nats.name", "NATS_NAME")
+	viper.BindEnv("nats.subject_prefix", "NATS_SUBJECT_PREFIX")
+	viper.BindEnv("nats.durable", "NATS_DURABLE")
+	viper.BindEnv("nats.replicas", "NATS_REPLICAS")
+	viper.BindEnv("nats.ack_wait", "NATS_ACK_WAIT")
 
 	// Queue
+	viper.BindEnv("queue.backend", "QUEUE_BACKEND")
+	viper.BindEnv("queue.encoding", "QUEUE_ENCODING")
+	viper.BindEnv("queue.postgres.poll_interval", "QUEUE_POSTGRES_POLL_INTERVAL")
+	viper.BindEnv("queue.postgres.visibility_timeout", "QUEUE_POSTGRES_VISIBILITY_TIMEOUT")
+	viper.BindEnv("queue.worker.port", "QUEUE_WORKER_PORT")
 	viper.BindEnv("queue.worker.prefetch_count", "QUEUE_WORKER_PREFETCH_COUNT")
+	viper.BindEnv("queue.worker.concurrency", "QUEUE_WORKER_CONCURRENCY")
+	viper.BindEnv("queue.worker.max_concurrency_per_cpu", "QUEUE_WORKER_MAX_CONCURRENCY_PER_CPU")
+	viper.BindEnv("queue.worker.memory_per_handler_mb", "QUEUE_WORKER_MEMORY_PER_HANDLER_MB")
+	viper.BindEnv("queue.worker.memory_limit_ratio", "QUEUE_WORKER_MEMORY_LIMIT_RATIO")
 	viper.BindEnv("queue.worker.poll_interval", "QUEUE_WORKER_POLL_INTERVAL")
 	viper.BindEnv("queue.worker.batch_size", "QUEUE_WORKER_BATCH_SIZE")
+	viper.BindEnv("queue.worker.ack_batch_size", "QUEUE_WORKER_ACK_BATCH_SIZE")
+	viper.BindEnv("queue.worker.ack_interval", "QUEUE_WORKER_ACK_INTERVAL")
+	viper.BindEnv("queue.worker.recover_panics", "QUEUE_WORKER_RECOVER_PANICS")
+	viper.BindEnv("queue.worker.run_jobs", "QUEUE_WORKER_RUN_JOBS")
+	viper.BindEnv("queue.worker.drain_timeout", "QUEUE_WORKER_DRAIN_TIMEOUT")
+	viper.BindEnv("queue.worker.stall_timeout", "QUEUE_WORKER_STALL_TIMEOUT")
+	viper.BindEnv("queue.worker.backpressure.enabled", "QUEUE_WORKER_BACKPRESSURE_ENABLED")
+	viper.BindEnv("queue.worker.backpressure.threshold", "QUEUE_WORKER_BACKPRESSURE_THRESHOLD")
+	viper.BindEnv("queue.worker.backpressure.min_sends", "QUEUE_WORKER_BACKPRESSURE_MIN_SENDS")
+	viper.BindEnv("queue.worker.backpressure.check_interval", "QUEUE_WORKER_BACKPRESSURE_CHECK_INTERVAL")
+	viper.BindEnv("queue.worker.backpressure.min_factor", "QUEUE_WORKER_BACKPRESSURE_MIN_FACTOR")
+	viper.BindEnv("queue.worker.backpressure.recovery_step", "QUEUE_WORKER_BACKPRESSURE_RECOVERY_STEP")
+	viper.BindEnv("queue.worker.backpressure.max_delay", "QUEUE_WORKER_BACKPRESSURE_MAX_DELAY")
+	viper.BindEnv("queue.worker.autoscale.enabled", "QUEUE_WORKER_AUTOSCALE_ENABLED")
+	viper.BindEnv("queue.worker.autoscale.interval", "QUEUE_WORKER_AUTOSCALE_INTERVAL")
+	viper.BindEnv("queue.worker.autoscale.min_concurrency", "QUEUE_WORKER_AUTOSCALE_MIN_CONCURRENCY")
+	viper.BindEnv("queue.worker.autoscale.max_concurrency", "QUEUE_WORKER_AUTOSCALE_MAX_CONCURRENCY")
+	viper.BindEnv("queue.worker.autoscale.target_backlog", "QUEUE_WORKER_AUTOSCALE_TARGET_BACKLOG")
 	viper.BindEnv("queue.retry.max_retries", "QUEUE_RETRY_MAX_RETRIES")
-	viper.BindEnv("queue.retry.backoff", "QUEUE_RETRY_BACKOFF")
+	viper.BindEnv("queue.retry.max_retries_cap", "QUEUE_RETRY_MAX_RETRIES_CAP")
+	viper.BindEnv("queue.retry.tiers", "QUEUE_RETRY_TIERS")
 	viper.BindEnv("queue.validation.enabled", "QUEUE_VALIDATION_ENABLED")
 	viper.BindEnv("queue.validation.timeout", "QUEUE_VALIDATION_TIMEOUT")
+	viper.BindEnv("queue.validation.concurrency", "QUEUE_VALIDATION_CONCURRENCY")
+	viper.BindEnv("queue.bulk.concurrency", "QUEUE_BULK_CONCURRENCY")
+	viper.BindEnv("queue.bulk.batch_size", "QUEUE_BULK_BATCH_SIZE")
+	viper.BindEnv("queue.bulk.lookup_size", "QUEUE_BULK_LOOKUP_SIZE")
+	viper.BindEnv("queue.gateway.mode", "QUEUE_GATEWAY_MODE")
+	viper.BindEnv("queue.gateway.exchange", "QUEUE_GATEWAY_EXCHANGE")
+	viper.BindEnv("queue.gateway.exchange_type", "QUEUE_GATEWAY_EXCHANGE_TYPE")
+	viper.BindEnv("queue.gateway.routing_keys", "QUEUE_GATEWAY_ROUTING_KEYS")
+	viper.BindEnv("queue.gateway.broker_hosts", "QUEUE_GATEWAY_BROKER_HOSTS")
+	viper.BindEnv("queue.gateway.user_resolver.url", "QUEUE_GATEWAY_USER_RESOLVER_URL")
+	viper.BindEnv("queue.gateway.user_resolver.auth_token", "QUEUE_GATEWAY_USER_RESOLVER_AUTH_TOKEN")
+	viper.BindEnv("queue.gateway.user_resolver.timeout", "QUEUE_GATEWAY_USER_RESOLVER_TIMEOUT")
 
 	// FCM
+	viper.BindEnv("fcm.mode", "FCM_MODE")
+	viper.BindEnv("fcm.sandbox.unregistered_rate", "FCM_SANDBOX_UNREGISTERED_RATE")
+	viper.BindEnv("fcm.sandbox.quota_rate", "FCM_SANDBOX_QUOTA_RATE")
+	viper.BindEnv("fcm.sandbox.failure_rate", "FCM_SANDBOX_FAILURE_RATE")
+	viper.BindEnv("fcm.sandbox.latency", "FCM_SANDBOX_LATENCY")
+	viper.BindEnv("fcm.sandbox.max_recorded", "FCM_SANDBOX_MAX_RECORDED")
 	viper.BindEnv("fcm.credentials_json", "FCM_CREDENTIALS_JSON")
 	viper.BindEnv("fcm.project_id", "FCM_PROJECT_ID")
+	viper.BindEnv("fcm.endpoint", "FCM_ENDPOINT")
 	viper.BindEnv("fcm.use_file", "FCM_USE_FILE")
+	viper.BindEnv("fcm.auth_probe_interval", "FCM_AUTH_PROBE_INTERVAL")
+	viper.BindEnv("fcm.quota_backoff", "FCM_QUOTA_BACKOFF")
+	viper.BindEnv("fcm.daily_quota", "FCM_DAILY_QUOTA")
+	viper.BindEnv("fcm.max_send_rate", "FCM_MAX_SEND_RATE")
+	viper.BindEnv("fcm.max_payload_size", "FCM_MAX_PAYLOAD_SIZE")
+	viper.BindEnv("fcm.oversize_payload", "FCM_OVERSIZE_PAYLOAD")
+
+	// Campaigns
+	viper.BindEnv("campaign.quota_policy", "CAMPAIGN_QUOTA_POLICY")
+	viper.BindEnv("campaign.tick_interval", "CAMPAIGN_TICK_INTERVAL")
+	viper.BindEnv("campaign.batch_size", "CAMPAIGN_BATCH_SIZE")
+
+	// Client SDK
+	viper.BindEnv("sdk.token_refresh_interval", "SDK_TOKEN_REFRESH_INTERVAL")
+	viper.BindEnv("sdk.permission_recheck_interval", "SDK_PERMISSION_RECHECK_INTERVAL")
+
+	// Tracing
+	viper.BindEnv("tracing.enabled", "TRACING_ENABLED")
+	viper.BindEnv("tracing.endpoint", "TRACING_ENDPOINT")
+	viper.BindEnv("tracing.service_name", "TRACING_SERVICE_NAME")
+	viper.BindEnv("tracing.sample_rate", "TRACING_SAMPLE_RATE")
+
+	// Lock
+	viper.BindEnv("lock.backend", "LOCK_BACKEND")
+	viper.BindEnv("lock.key_prefix", "LOCK_KEY_PREFIX")
+
+	// Alerting
+	viper.BindEnv("alerting.enabled", "ALERTING_ENABLED")
+	viper.BindEnv("alerting.evaluation_interval", "ALERTING_EVALUATION_INTERVAL")
+	viper.BindEnv("alerting.flush_interval", "ALERTING_FLUSH_INTERVAL")
+	viper.BindEnv("alerting.retention", "ALERTING_RETENTION")
+	viper.BindEnv("alerting.webhook_url", "ALERTING_WEBHOOK_URL")
+	viper.BindEnv("alerting.webhook_timeout", "ALERTING_WEBHOOK_TIMEOUT")
+	viper.BindEnv("alerting.slack_webhook_url", "ALERTING_SLACK_WEBHOOK_URL")
+	viper.BindEnv("alerting.email.smtp_host", "ALERTING_EMAIL_SMTP_HOST")
+	viper.BindEnv("alerting.email.smtp_port", "ALERTING_EMAIL_SMTP_PORT")
+	viper.BindEnv("alerting.email.username", "ALERTING_EMAIL_USERNAME")
+	viper.BindEnv("alerting.email.password", "ALERTING_EMAIL_PASSWORD")
+	viper.BindEnv("alerting.email.from", "ALERTING_EMAIL_FROM")
+	viper.BindEnv("alerting.email.to", "ALERTING_EMAIL_TO")
+	viper.BindEnv("alerting.builtin.dead_letter_growth.threshold", "ALERTING_BUILTIN_DEAD_LETTER_GROWTH_THRESHOLD")
+	viper.BindEnv("alerting.builtin.dead_letter_growth.window", "ALERTING_BUILTIN_DEAD_LETTER_GROWTH_WINDOW")
+	viper.BindEnv("alerting.builtin.dead_letter_depth.threshold", "ALERTING_BUILTIN_DEAD_LETTER_DEPTH_THRESHOLD")
+	viper.BindEnv("alerting.builtin.dead_letter_depth.window", "ALERTING_BUILTIN_DEAD_LETTER_DEPTH_WINDOW")
+	viper.BindEnv("alerting.builtin.failure_rate.threshold", "ALERTING_BUILTIN_FAILURE_RATE_THRESHOLD")
+	viper.BindEnv("alerting.builtin.failure_rate.window", "ALERTING_BUILTIN_FAILURE_RATE_WINDOW")
+	viper.BindEnv("alerting.builtin.failure_rate.min_events", "ALERTING_BUILTIN_FAILURE_RATE_MIN_EVENTS")
+	viper.BindEnv("alerting.builtin.retry_rate.threshold", "ALERTING_BUILTIN_RETRY_RATE_THRESHOLD")
+	viper.BindEnv("alerting.builtin.retry_rate.window", "ALERTING_BUILTIN_RETRY_RATE_WINDOW")
+	viper.BindEnv("alerting.builtin.retry_rate.min_events", "ALERTING_BUILTIN_RETRY_RATE_MIN_EVENTS")
+	viper.BindEnv("alerting.builtin.fcm_error_rate.threshold", "ALERTING_BUILTIN_FCM_ERROR_RATE_THRESHOLD")
+	viper.BindEnv("alerting.builtin.fcm_error_rate.window", "ALERTING_BUILTIN_FCM_ERROR_RATE_WINDOW")
+	viper.BindEnv("alerting.builtin.fcm_error_rate.min_events", "ALERTING_BUILTIN_FCM_ERROR_RATE_MIN_EVENTS")
+
+	// Soft launch
+	viper.BindEnv("soft_launch.enabled", "SOFT_LAUNCH_ENABLED")
+	viper.BindEnv("soft_launch.daily_send_cap", "SOFT_LAUNCH_DAILY_SEND_CAP")
+
+	// Webhooks
+	viper.BindEnv("webhooks.timeout", "WEBHOOKS_TIMEOUT")
+	viper.BindEnv("webhooks.cache_ttl", "WEBHOOKS_CACHE_TTL")
+	viper.BindEnv("webhooks.max_attempts", "WEBHOOKS_MAX_ATTEMPTS")
+	viper.BindEnv("webhooks.initial_backoff", "WEBHOOKS_INITIAL_BACKOFF")
+	viper.BindEnv("webhooks.max_backoff", "WEBHOOKS_MAX_BACKOFF")
+	viper.BindEnv("webhooks.failure_threshold", "WEBHOOKS_FAILURE_THRESHOLD")
+	viper.BindEnv("webhooks.open_duration", "WEBHOOKS_OPEN_DURATION")
+	viper.BindEnv("webhooks.delivery_log_size", "WEBHOOKS_DELIVERY_LOG_SIZE")
+
+	// Scheduler
+	viper.BindEnv("scheduler.port", "SCHEDULER_PORT")
+	viper.BindEnv("scheduler.tick_interval", "SCHEDULER_TICK_INTERVAL")
+	viper.BindEnv("scheduler.batch_size", "SCHEDULER_BATCH_SIZE")
+	viper.BindEnv("scheduler.default_timezone", "SCHEDULER_DEFAULT_TIMEZONE")
+
+	// Digests
+	viper.BindEnv("digest.enabled", "DIGEST_ENABLED")
+	viper.BindEnv("digest.interval", "DIGEST_INTERVAL")
+	viper.BindEnv("digest.title", "DIGEST_TITLE")
+	viper.BindEnv("digest.body", "DIGEST_BODY")
+
+	viper.BindEnv("quiet_hours.enabled", "QUIET_HOURS_ENABLED")
+	viper.BindEnv("quiet_hours.start", "QUIET_HOURS_START")
+	viper.BindEnv("quiet_hours.end", "QUIET_HOURS_END")
+
+	// Inbox
+	viper.BindEnv("inbox.enabled", "INBOX_ENABLED")
+
+	// Realtime
+	viper.BindEnv("realtime.enabled", "REALTIME_ENABLED")
+	viper.BindEnv("realtime.bus", "REALTIME_BUS")
+	viper.BindEnv("realtime.channel", "REALTIME_CHANNEL")
+	viper.BindEnv("realtime.key_prefix", "REALTIME_KEY_PREFIX")
+	viper.BindEnv("realtime.presence_ttl", "REALTIME_PRESENCE_TTL")
+	viper.BindEnv("realtime.ping_interval", "REALTIME_PING_INTERVAL")
+	viper.BindEnv("realtime.session_buffer", "REALTIME_SESSION_BUFFER")
+	viper.BindEnv("realtime.allowed_origins", "REALTIME_ALLOWED_ORIGINS")
+
+	// Devices
+	viper.BindEnv("devices.import.batch_size", "DEVICES_IMPORT_BATCH_SIZE")
+	viper.BindEnv("devices.import.max_errors", "DEVICES_IMPORT_MAX_ERRORS")
+	viper.BindEnv("devices.cleanup.enabled", "DEVICES_CLEANUP_ENABLED")
+	viper.BindEnv("devices.cleanup.interval", "DEVICES_CLEANUP_INTERVAL")
+	viper.BindEnv("devices.cleanup.stale_after", "DEVICES_CLEANUP_STALE_AFTER")
+	viper.BindEnv("devices.cleanup.retention", "DEVICES_CLEANUP_RETENTION")
+	viper.BindEnv("devices.cleanup.batch_size", "DEVICES_CLEANUP_BATCH_SIZE")
+
+	// Privacy
+	viper.BindEnv("privacy.erasure_window", "PRIVACY_ERASURE_WINDOW")
+	viper.BindEnv("privacy.erasure_refresh", "PRIVACY_ERASURE_REFRESH")
+
+	// Retention
+	viper.BindEnv("retention.enabled", "RETENTION_ENABLED")
+	viper.BindEnv("retention.interval", "RETENTION_INTERVAL")
+	viper.BindEnv("retention.notifications", "RETENTION_NOTIFICATIONS")
+	viper.BindEnv("retention.delivery_logs", "RETENTION_DELIVERY_LOGS")
+	viper.BindEnv("retention.dead_letters", "RETENTION_DEAD_LETTERS")
+	viper.BindEnv("retention.outbox", "RETENTION_OUTBOX")
+	viper.BindEnv("retention.batch_size", "RETENTION_BATCH_SIZE")
+
+	// Outbox
+	viper.BindEnv("outbox.enabled", "OUTBOX_ENABLED")
+	viper.BindEnv("outbox.relay_interval", "OUTBOX_RELAY_INTERVAL")
+	viper.BindEnv("outbox.relay_delay", "OUTBOX_RELAY_DELAY")
+	viper.BindEnv("outbox.batch_size", "OUTBOX_BATCH_SIZE")
+
+	// Event sink
+	viper.BindEnv("event_sink.backend", "EVENT_SINK_BACKEND")
+	viper.BindEnv("event_sink.buffer_size", "EVENT_SINK_BUFFER_SIZE")
+	viper.BindEnv("event_sink.batch_size", "EVENT_SINK_BATCH_SIZE")
+	viper.BindEnv("event_sink.flush_interval", "EVENT_SINK_FLUSH_INTERVAL")
+	viper.BindEnv("event_sink.max_attempts", "EVENT_SINK_MAX_ATTEMPTS")
+	viper.BindEnv("event_sink.timeout", "EVENT_SINK_TIMEOUT")
+	viper.BindEnv("event_sink.clickhouse.url", "EVENT_SINK_CLICKHOUSE_URL")
+	viper.BindEnv("event_sink.clickhouse.table", "EVENT_SINK_CLICKHOUSE_TABLE")
+	viper.BindEnv("event_sink.clickhouse.username", "EVENT_SINK_CLICKHOUSE_USERNAME")
+	viper.BindEnv("event_sink.clickhouse.password", "EVENT_SINK_CLICKHOUSE_PASSWORD")
+	viper.BindEnv("event_sink.bigquery.project_id", "EVENT_SINK_BIGQUERY_PROJECT_ID")
+	viper.BindEnv("event_sink.bigquery.dataset", "EVENT_SINK_BIGQUERY_DATASET")
+	viper.BindEnv("event_sink.bigquery.table", "EVENT_SINK_BIGQUERY_TABLE")
+	viper.BindEnv("event_sink.bigquery.credentials_file", "EVENT_SINK_BIGQUERY_CREDENTIALS_FILE")
+
+	// Archive
+	viper.BindEnv("archive.enabled", "ARCHIVE_ENABLED")
+	viper.BindEnv("archive.interval", "ARCHIVE_INTERVAL")
+	viper.BindEnv("archive.after", "ARCHIVE_AFTER")
+	viper.BindEnv("archive.batch_size", "ARCHIVE_BATCH_SIZE")
+	viper.BindEnv("archive.backend", "ARCHIVE_BACKEND")
+	viper.BindEnv("archive.bucket", "ARCHIVE_BUCKET")
+	viper.BindEnv("archive.prefix", "ARCHIVE_PREFIX")
+	viper.BindEnv("archive.s3.region", "ARCHIVE_S3_REGION")
+	viper.BindEnv("archive.s3.endpoint", "ARCHIVE_S3_ENDPOINT")
+	viper.BindEnv("archive.s3.use_path_style", "ARCHIVE_S3_USE_PATH_STYLE")
+	viper.BindEnv("archive.gcs.credentials_file", "ARCHIVE_GCS_CREDENTIALS_FILE")
 
 	// Log
 	viper.BindEnv("log.level", "LOG_LEVEL")
 	viper.BindEnv("log.format", "LOG_FORMAT")
+	viper.BindEnv("log.redact", "LOG_REDACT")
+}
+
+// resolveBindings expands RoutingKeys into Bindings. Without any, push.queue
+// keeps its routing key push.
+func (g *GatewayConfig) resolveBindings() {
+	for _, routingKey := range g.RoutingKeys {
+		if routingKey = strings.TrimSpace(routingKey); routingKey != "" {
+			g.Bindings = append(g.Bindings, GatewayBindingConfig{RoutingKey: routingKey})
+		}
+	}
+	g.RoutingKeys = nil
+
+	if len(g.Bindings) == 0 {
+		g.Bindings = []GatewayBindingConfig{{RoutingKey: "push"}}
+	}
+}
+
+// resolveBrokers expands BrokerHosts into Brokers and fills unset broker
+// settings from the primary RabbitMQ config.
+func (g *GatewayConfig) resolveBrokers(primary RabbitMQConfig) {
+	for _, hostPort := range g.BrokerHosts {
+		hostPort = strings.TrimSpace(hostPort)
+		if hostPort == "" {
+			continue
+		}
+		broker := RabbitMQConfig{Host: hostPort}
+		if host, port, err := net.SplitHostPort(hostPort); err == nil {
+			broker.Host, broker.Port = host, port
+		}
+		g.Brokers = append(g.Brokers, broker)
+	}
+	g.BrokerHosts = nil
+
+	for i := range g.Brokers {
+		broker := &g.Brokers[i]
+		if broker.Name == "" {
+			broker.Name = broker.Host
+		}
+		if broker.Port == "" {
+			broker.Port = primary.Port
+		}
+		if broker.Username == "" {
+			broker.Username = primary.Username
+			broker.Password = primary.Password
+		}
+		if broker.VHost == "" {
+			broker.VHost = primary.VHost
+		}
+		if broker.ReconnectInitialBackoff == 0 {
+			broker.ReconnectInitialBackoff = primary.ReconnectInitialBackoff
+		}
+		if broker.ReconnectMaxBackoff == 0 {
+			broker.ReconnectMaxBackoff = primary.ReconnectMaxBackoff
+		}
+		if !broker.TLS && broker.CACertFile == "" && broker.ClientCertFile == "" {
+			broker.TLS = primary.TLS
+			broker.CACertFile = primary.CACertFile
+			broker.ClientCertFile = primary.ClientCertFile
+			broker.ClientKeyFile = primary.ClientKeyFile
+		}
+		if broker.ConnectionName == "" {
+			broker.ConnectionName = primary.ConnectionName
+		}
+		if broker.Heartbeat == 0 {
+			broker.Heartbeat = primary.Heartbeat
+		}
+		if broker.DialTimeout == 0 {
+			broker.DialTimeout = primary.DialTimeout
+		}
+	}
+}
+
+// parseSampleRates merges a "key=rate,key=rate" list into rates
+func parseSampleRates(value string, rates *map[string]float64) error {
+	return parseKeyValues(value, rates, func(rate string) (float64, error) {
+		return strconv.ParseFloat(rate, 64)
+	})
+}
+
+// parseKeyValues merges a "key=value,key=value" list into values, parsing
+// each value with parse
+func parseKeyValues[V any](list string, values *map[string]V, parse func(string) (V, error)) error {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	if *values == nil {
+		*values = make(map[string]V)
+	}
+	for _, entry := range strings.Split(list, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value, got %q", entry)
+		}
+		parsed, err := parse(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+		(*values)[strings.TrimSpace(key)] = parsed
+	}
+	return nil
 }
 
 // GetDatabaseURL builds the database connection URL
@@ -258,10 +1616,377 @@ func validateConfig(config *Config) error {
 	if config.Database.Password == "" {
 		return fmt.Errorf("database password is required")
 	}
-	if config.FCM.CredentialsJSON == "" {
-		return fmt.Errorf("FCM credentials are required")
+	switch config.FCM.Mode {
+	case "", FCMModeLive:
+		if config.FCM.CredentialsJSON == "" && config.FCM.Endpoint == "" {
+			return fmt.Errorf("FCM credentials are required")
+		}
+	case FCMModeSandbox:
+		if err := validateSandbox(&config.FCM.Sandbox); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown FCM mode %q", config.FCM.Mode)
+	}
+	for i, project := range config.FCM.FailoverProjects {
+		if project.ProjectID == "" || (project.CredentialsJSON == "" && project.Endpoint == "" && config.FCM.Mode != FCMModeSandbox) {
+			return fmt.Errorf("FCM failover project %d needs project_id and credentials_json", i+1)
+		}
+	}
+	if err := ValidateChaos(&config.Chaos); err != nil {
+		return err
+	}
+	if config.Startup.WaitTimeout < 0 {
+		return fmt.Errorf("startup wait timeout must not be negative")
+	}
+	for _, tier := range config.Queue.Retry.Tiers {
+		if tier <= 0 {
+			return fmt.Errorf("retry tiers must be positive durations, got %s", tier)
+		}
+	}
+	switch config.Queue.Backend {
+	case "", QueueBackendRabbitMQ, QueueBackendMemory:
+	case QueueBackendPostgres:
+		if config.Queue.Postgres.VisibilityTimeout <= 0 {
+			return fmt.Errorf("queue postgres visibility timeout must be positive")
+		}
+	case QueueBackendKafka:
+		if len(config.Kafka.Brokers) == 0 {
+			return fmt.Errorf("kafka brokers are required for the kafka queue backend")
+		}
+	case QueueBackendNATS:
+		if config.NATS.URL == "" || config.NATS.SubjectPrefix == "" || config.NATS.Durable == "" {
+			return fmt.Errorf("nats url, subject_prefix and durable are required for the nats queue backend")
+		}
+	default:
+		return fmt.Errorf("unknown queue backend %q", config.Queue.Backend)
+	}
+	if worker := &config.Queue.Worker; worker.AckBatchSize > 1 {
+		if worker.AckInterval <= 0 {
+			return fmt.Errorf("queue worker ack_interval must be positive with ack_batch_size set")
+		}
+		// A batch that can't fill before the broker stops delivering would
+		// only ever be sent by the interval
+		if worker.PrefetchCount > 0 && worker.AckBatchSize > worker.PrefetchCount {
+			return fmt.Errorf("queue worker ack_batch_size (%d) must not exceed prefetch_count (%d)", worker.AckBatchSize, worker.PrefetchCount)
+		}
+	}
+	if autoscale := &config.Queue.Worker.Autoscale; autoscale.Enabled {
+		if autoscale.MinConcurrency < 1 {
+			return fmt.Errorf("autoscale min_concurrency must be positive, got %d", autoscale.MinConcurrency)
+		}
+		if autoscale.MaxConcurrency != 0 && autoscale.MaxConcurrency < autoscale.MinConcurrency {
+			return fmt.Errorf("autoscale max_concurrency (%d) must not be below min_concurrency (%d)", autoscale.MaxConcurrency, autoscale.MinConcurrency)
+		}
+		if autoscale.TargetBacklog < 1 {
+			return fmt.Errorf("autoscale target_backlog must be positive, got %d", autoscale.TargetBacklog)
+		}
+	}
+	if backpressure := &config.Queue.Worker.Backpressure; backpressure.Enabled {
+		if backpressure.Threshold <= 0 || backpressure.Threshold > 1 {
+			return fmt.Errorf("backpressure threshold must be in (0, 1], got %g", backpressure.Threshold)
+		}
+		if backpressure.MinFactor <= 0 || backpressure.MinFactor > 1 {
+			return fmt.Errorf("backpressure min_factor must be in (0, 1], got %g", backpressure.MinFactor)
+		}
+	}
+	switch config.FCM.OversizePayload {
+	case "", OversizePayloadReject, OversizePayloadTruncate:
+	default:
+		return fmt.Errorf("unknown fcm oversize_payload policy %q", config.FCM.OversizePayload)
 	}
+	switch config.Queue.Encoding {
+	case "", QueueEncodingJSON, QueueEncodingProtobuf:
+	default:
+		return fmt.Errorf("unknown queue encoding %q", config.Queue.Encoding)
+	}
+	if err := validateSampleRate("tracing sample_rate", config.Tracing.SampleRate); err != nil {
+		return err
+	}
+	for tenant, rate := range config.Tracing.TenantSampleRates {
+		if err := validateSampleRate("tracing sample rate of tenant "+tenant, rate); err != nil {
+			return err
+		}
+	}
+	for priority, rate := range config.Tracing.PrioritySampleRates {
+		if err := validateSampleRate("tracing sample rate of priority "+priority, rate); err != nil {
+			return err
+		}
+	}
+	if err := validateGateway(&config.Queue.Gateway); err != nil {
+		return err
+	}
+	if err := validateQuietHours(&config.QuietHours); err != nil {
+		return err
+	}
+	if err := validateCategories(config.Categories); err != nil {
+		return err
+	}
+	switch config.Campaign.QuotaPolicy {
+	case "", QuotaPolicySpread, QuotaPolicyFailover:
+	default:
+		return fmt.Errorf("unknown campaign quota policy %q", config.Campaign.QuotaPolicy)
+	}
+	switch config.Lock.Backend {
+	case "", "postgres", "redis":
+	default:
+		return fmt.Errorf("unknown lock backend %q", config.Lock.Backend)
+	}
+	switch config.Realtime.Bus {
+	case "", "memory", "redis":
+	default:
+		return fmt.Errorf("unknown realtime bus %q", config.Realtime.Bus)
+	}
+	switch config.EventSink.Backend {
+	case "":
+	case "clickhouse":
+		if config.EventSink.ClickHouse.URL == "" {
+			return fmt.Errorf("the clickhouse event sink needs a url")
+		}
+	case "bigquery":
+		if config.EventSink.BigQuery.ProjectID == "" || config.EventSink.BigQuery.Dataset == "" {
+			return fmt.Errorf("the bigquery event sink needs a project_id and a dataset")
+		}
+	default:
+		return fmt.Errorf("unknown event sink backend %q", config.EventSink.Backend)
+	}
+	if err := validateArchive(config); err != nil {
+		return err
+	}
+	if _, err := config.Access.Prefixes(); err != nil {
+		return err
+	}
+	if config.Access.InternalHeader != "" && config.Access.InternalHeaderValue == "" {
+		return fmt.Errorf("the access internal header needs a value")
+	}
+	if err := validateAlertRules(&config.Alerting); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(config.Scheduler.DefaultTimezone); err != nil {
+		return fmt.Errorf("invalid scheduler default timezone %q: %w", config.Scheduler.DefaultTimezone, err)
+	}
+
+	return nil
+}
+
+func validateArchive(config *Config) error {
+	archive := &config.Archive
+	if !archive.Enabled {
+		return nil
+	}
+
+	switch archive.Backend {
+	case "s3", "gcs":
+	default:
+		return fmt.Errorf("unknown archive backend %q", archive.Backend)
+	}
+	if archive.Bucket == "" {
+		return fmt.Errorf("the archive needs a bucket")
+	}
+	// Dead letters must be archived before the broker expires them
+	deadLetters := config.Retention.DeadLetters
+	if deadLetters <= 0 {
+		deadLetters = 7 * 24 * time.Hour // default
+	}
+	if archive.After >= deadLetters {
+		return fmt.Errorf("archive after (%s) must be shorter than the dead letter retention (%s)", archive.After, deadLetters)
+	}
+	return nil
+}
+
+func validateGateway(cfg *GatewayConfig) error {
+	if err := validateGatewayMode(cfg.Mode); err != nil {
+		return err
+	}
+	switch cfg.ExchangeType {
+	case "", "direct", "topic":
+	default:
+		return fmt.Errorf("unknown gateway exchange type %q, want direct or topic", cfg.ExchangeType)
+	}
+	for _, binding := range cfg.Bindings {
+		if binding.RoutingKey == "" {
+			return fmt.Errorf("gateway bindings need a routing_key")
+		}
+		if err := validateGatewayMode(binding.Mode); err != nil {
+			return fmt.Errorf("gateway binding %s: %w", binding.RoutingKey, err)
+		}
+		switch binding.Priority {
+		case "", "critical", "high", "normal", "low":
+		default:
+			return fmt.Errorf("gateway binding %s has unknown priority %q", binding.RoutingKey, binding.Priority)
+		}
+	}
+	for messageType, priority := range cfg.TypePriorities {
+		switch priority {
+		case "critical", "high", "normal", "low":
+		default:
+			return fmt.Errorf("gateway type %s has unknown priority %q", messageType, priority)
+		}
+	}
+	for messageType, ttl := range cfg.TypeTTLs {
+		// FCM keeps messages for 28 days at most
+		if ttl <= 0 || ttl > 28*24*time.Hour {
+			return fmt.Errorf("gateway type %s needs a positive ttl of at most 28 days, got %s", messageType, ttl)
+		}
+	}
+	return nil
+}
+
+func validateQuietHours(cfg *QuietHoursConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	start, err := time.Parse("15:04", cfg.Start)
+	if err != nil {
+		return fmt.Errorf("quiet hours start must be HH:MM, got %q", cfg.Start)
+	}
+	end, err := time.Parse("15:04", cfg.End)
+	if err != nil {
+		return fmt.Errorf("quiet hours end must be HH:MM, got %q", cfg.End)
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("quiet hours start and end must differ")
+	}
+	return nil
+}
+
+func validateCategories(categories CategoriesConfig) error {
+	for name, category := range categories {
+		if category.MaxRetries != nil && *category.MaxRetries < 0 {
+			return fmt.Errorf("category %s: max_retries must not be negative", name)
+		}
+		// FCM keeps messages for 28 days at most
+		if category.TTL < 0 || category.TTL > 28*24*time.Hour {
+			return fmt.Errorf("category %s: ttl must be at most 28 days, got %s", name, category.TTL)
+		}
+		if category.FrequencyCap < 0 {
+			return fmt.Errorf("category %s: frequency_cap must not be negative", name)
+		}
+		if category.FrequencyCap > 0 && (category.FrequencyWindow <= 0 || category.FrequencyWindow > 31*24*time.Hour) {
+			return fmt.Errorf("category %s: frequency_window must be positive and at most 31 days, got %s", name, category.FrequencyWindow)
+		}
+	}
+	return nil
+}
 
+func validateGatewayMode(mode string) error {
+	switch mode {
+	case "", GatewayModeEnqueue, GatewayModeDirect:
+		return nil
+	default:
+		return fmt.Errorf("unknown gateway mode %q", mode)
+	}
+}
+
+func validateAlertRules(cfg *AlertingConfig) error {
+	retention := cfg.Retention
+	if retention <= 0 {
+		retention = 24 * time.Hour // default
+	}
+
+	// The built-in rules are checked like the others, and their names are
+	// taken while they are on
+	builtins := cfg.Builtin.Rules()
+	for _, rule := range builtins {
+		if rule.Threshold < 0 {
+			return fmt.Errorf("built-in alert rule %q: threshold must not be negative", rule.Name)
+		}
+	}
+	rules := append(builtins, cfg.Rules...)
+	names := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("alert rules need a name")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate alert rule %q", rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch rule.Metric {
+		case "failure_rate", "failures", "retry_rate", "fcm_error_rate":
+		case "dead_letters", "dead_letter_depth":
+			if len(rule.Filter) > 0 {
+				return fmt.Errorf("alert rule %q: %s rules take no filter", rule.Name, rule.Metric)
+			}
+		default:
+			return fmt.Errorf("alert rule %q: unknown metric %q", rule.Name, rule.Metric)
+		}
+		for label := range rule.Filter {
+			switch label {
+			case "platform", "project", "category":
+			default:
+				return fmt.Errorf("alert rule %q: unknown filter label %q", rule.Name, label)
+			}
+		}
+		switch rule.Operator {
+		case ">", ">=", "<", "<=":
+		default:
+			return fmt.Errorf("alert rule %q: unknown operator %q", rule.Name, rule.Operator)
+		}
+		if rule.Window <= 0 || rule.Window > retention {
+			return fmt.Errorf("alert rule %q: window must be positive and at most the %s retention", rule.Name, retention)
+		}
+		if rule.WebhookURL == "" && cfg.WebhookURL == "" && cfg.SlackWebhookURL == "" && !cfg.Email.Enabled() {
+			return fmt.Errorf("alert rule %q has no webhook_url and no default webhook, Slack or email is configured", rule.Name)
+		}
+	}
+	if cfg.Email.Enabled() {
+		if cfg.Email.From == "" {
+			return fmt.Errorf("alerting email needs a from address")
+		}
+		if cfg.Email.SMTPPort <= 0 {
+			return fmt.Errorf("alerting email smtp_port must be positive, got %d", cfg.Email.SMTPPort)
+		}
+	}
+	return nil
+}
+
+func validateSandbox(cfg *SandboxConfig) error {
+	if err := validateSampleRate("FCM sandbox unregistered_rate", cfg.UnregisteredRate); err != nil {
+		return err
+	}
+	if err := validateSampleRate("FCM sandbox quota_rate", cfg.QuotaRate); err != nil {
+		return err
+	}
+	if err := validateSampleRate("FCM sandbox failure_rate", cfg.FailureRate); err != nil {
+		return err
+	}
+	if total := cfg.UnregisteredRate + cfg.QuotaRate + cfg.FailureRate; total > 1 {
+		return fmt.Errorf("FCM sandbox failure rates must add up to at most 1, got %g", total)
+	}
+	return nil
+}
+
+// ValidateChaos checks the fault injection rates and delay
+func ValidateChaos(cfg *ChaosConfig) error {
+	rates := []struct {
+		name string
+		rate float64
+	}{
+		{"chaos fcm_delay_rate", cfg.FCMDelayRate},
+		{"chaos fcm_failure_rate", cfg.FCMFailureRate},
+		{"chaos ack_drop_rate", cfg.AckDropRate},
+		{"chaos channel_kill_rate", cfg.ChannelKillRate},
+	}
+	for _, r := range rates {
+		if err := validateSampleRate(r.name, r.rate); err != nil {
+			return err
+		}
+	}
+	if total := cfg.AckDropRate + cfg.ChannelKillRate; total > 1 {
+		return fmt.Errorf("chaos ack_drop_rate and channel_kill_rate must add up to at most 1, got %g", total)
+	}
+	if cfg.FCMDelay < 0 {
+		return fmt.Errorf("chaos fcm_delay must not be negative")
+	}
+	return nil
+}
+
+func validateSampleRate(name string, rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("%s must be between 0 and 1, got %g", name, rate)
+	}
 	return nil
 }
 
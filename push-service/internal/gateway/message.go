@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"push-service/internal/models"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// LatestSchemaVersion is the newest gateway message schema
+const LatestSchemaVersion = 1
+
+// decoder turns a message body that matches its version's schema into a
+// GatewayPushMessage
+type decoder func(body []byte) (*models.GatewayPushMessage, error)
+
+// decoders decode each schema version. A new version gets a schema in
+// schemas/ and a decoder here; older versions keep theirs for as long as
+// the gateway may publish them.
+var decoders = map[int]decoder{
+	1: decodeV1,
+}
+
+// Decode validates a gateway message against the schema of its
+// schema_version, 1 if it has none, and decodes it with that version's
+// decoder. It returns a *SchemaError if the body is not JSON, has an
+// unknown version or doesn't match its schema.
+func Decode(body []byte) (*models.GatewayPushMessage, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return nil, &SchemaError{SchemaVersion: 1, Violations: []string{"/: invalid JSON: " + err.Error()}}
+	}
+
+	version := schemaVersion(doc)
+	decode, ok := decoders[version]
+	if !ok {
+		return nil, &SchemaError{SchemaVersion: version, Violations: []string{"/schema_version: unsupported version"}}
+	}
+	if err := validate(version, doc); err != nil {
+		return nil, err
+	}
+
+	message, err := decode(body)
+	if err != nil {
+		return nil, err
+	}
+	message.SchemaVersion = version
+	return message, nil
+}
+
+// schemaVersion returns the message's schema_version, 1 if it has none. A
+// schema_version that isn't an integer is left to the v1 schema to reject.
+func schemaVersion(doc any) int {
+	object, ok := doc.(map[string]any)
+	if !ok {
+		return 1
+	}
+	number, ok := object["schema_version"].(json.Number)
+	if !ok {
+		return 1
+	}
+	version, err := number.Int64()
+	if err != nil || version < 1 {
+		return 1
+	}
+	return int(version)
+}
+
+// decodeV1 decodes version 1, whose fields are GatewayPushMessage's
+func decodeV1(body []byte) (*models.GatewayPushMessage, error) {
+	var message models.GatewayPushMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
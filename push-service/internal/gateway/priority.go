@@ -0,0 +1,25 @@
+package gateway
+
+import (
+	"strings"
+
+	"push-service/internal/models"
+)
+
+// priorities maps the priorities the gateway publishes, and the service's
+// own, to the service's priorities
+var priorities = map[string]string{
+	"urgent":                models.PriorityCritical,
+	models.PriorityCritical: models.PriorityCritical,
+	models.PriorityHigh:     models.PriorityHigh,
+	"medium":                models.PriorityNormal,
+	models.PriorityNormal:   models.PriorityNormal,
+	models.PriorityLow:      models.PriorityLow,
+	"bulk":                  models.PriorityLow,
+}
+
+// Priority returns the service's priority for a gateway message's priority,
+// ignoring case, or "" if it has none or one the service doesn't know
+func Priority(value string) string {
+	return priorities[strings.ToLower(strings.TrimSpace(value))]
+}
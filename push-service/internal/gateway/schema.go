@@ -0,0 +1,92 @@
+// Package gateway is the contract between the API Gateway and the push
+// service: the messages the gateway publishes to push.queue, described by
+// versioned JSON Schemas in schemas/ and decoded into
+// models.GatewayPushMessage by a decoder per version.
+package gateway
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// schemas are the compiled schemas by version
+var schemas = mustCompileSchemas()
+
+// printer renders validation errors
+var printer = message.NewPrinter(language.English)
+
+// SchemaError is why a message doesn't match its gateway message schema
+type SchemaError struct {
+	SchemaVersion int
+	// Violations are where and how the message breaks the schema, e.g.
+	// "/user_id: missing property"
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("gateway message does not match schema v%d: %s", e.SchemaVersion, strings.Join(e.Violations, "; "))
+}
+
+// validate checks a parsed message against the schema of its version
+func validate(version int, doc any) error {
+	schema, ok := schemas[version]
+	if !ok {
+		return &SchemaError{SchemaVersion: version, Violations: []string{"/schema_version: unsupported version"}}
+	}
+
+	err := schema.Validate(doc)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return &SchemaError{SchemaVersion: version, Violations: violations(validationErr)}
+	}
+	return err
+}
+
+func schemaFile(version int) string {
+	return fmt.Sprintf("schemas/push-message.v%d.json", version)
+}
+
+// violations flattens a validation error into its leaf errors
+func violations(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		return []string{"/" + strings.Join(err.InstanceLocation, "/") + ": " + err.ErrorKind.LocalizedString(printer)}
+	}
+
+	var leaves []string
+	for _, cause := range err.Causes {
+		leaves = append(leaves, violations(cause)...)
+	}
+	return leaves
+}
+
+func mustCompileSchemas() map[int]*jsonschema.Schema {
+	compiled := make(map[int]*jsonschema.Schema)
+	for version := 1; ; version++ {
+		file, err := schemaFiles.Open(schemaFile(version))
+		if err != nil {
+			break
+		}
+		doc, err := jsonschema.UnmarshalJSON(file)
+		file.Close()
+		if err != nil {
+			panic(fmt.Sprintf("gateway schema v%d: %v", version, err))
+		}
+
+		url := fmt.Sprintf("urn:push-service:gateway:push-message:v%d", version)
+		compiler := jsonschema.NewCompiler()
+		if err := compiler.AddResource(url, doc); err != nil {
+			panic(fmt.Sprintf("gateway schema v%d: %v", version, err))
+		}
+		compiled[version] = compiler.MustCompile(url)
+	}
+	return compiled
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/netip"
+	"push-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// InternalOnly restricts the routes it guards to internal clients: those
+// whose IP is in one of the configured CIDRs, or whose request carries the
+// internal header with its value. Other clients get 403. Every client is let
+// through if access isn't restricted.
+func InternalOnly(cfg *config.AccessConfig) (gin.HandlerFunc, error) {
+	if !cfg.Restricted() {
+		return func(c *gin.Context) { c.Next() }, nil
+	}
+
+	prefixes, err := cfg.Prefixes()
+	if err != nil {
+		return nil, err
+	}
+	header := cfg.InternalHeader
+	value := []byte(cfg.InternalHeaderValue)
+
+	return func(c *gin.Context) {
+		if header != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader(header)), value) == 1 {
+			c.Next()
+			return
+		}
+		if addr, err := netip.ParseAddr(c.ClientIP()); err == nil {
+			addr = addr.Unmap()
+			for _, prefix := range prefixes {
+				if prefix.Contains(addr) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		zap.L().Warn("Rejected request to internal-only endpoint",
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.FullPath()),
+			zap.String("client_ip", c.ClientIP()),
+		)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden", "details": "this endpoint is only reachable from the internal network"})
+	}, nil
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/queue"
+	"push-service/internal/reload"
+	"push-service/internal/worker"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UpdateWorkerSettingsRequest represents a runtime worker settings change
+// @Description Worker settings update request (omitted fields are unchanged)
+type UpdateWorkerSettingsRequest struct {
+	PrefetchCount *int `json:"prefetch_count,omitempty" binding:"omitempty,min=1" example:"20"`
+	Concurrency   *int `json:"concurrency,omitempty" binding:"omitempty,min=1" example:"8"`
+}
+
+// ConsumersResponse lists the queue consumers of the instance that answered
+// @Description Queue consumers of one instance
+type ConsumersResponse struct {
+	Host      string               `json:"host" example:"push-worker-7d9f"`
+	Consumers []queue.ConsumerInfo `json:"consumers"`
+}
+
+// SandboxSendsResponse lists the sends the FCM sandbox recorded
+// @Description Sends recorded by the FCM sandbox
+type SandboxSendsResponse struct {
+	// Total counts every send since the start or the last reset, including
+	// those no longer kept
+	Total int64             `json:"total" example:"1520"`
+	Count int               `json:"count" example:"50"`
+	Sends []fcm.SandboxSend `json:"sends"`
+}
+
+type AdminHandler struct {
+	worker    *worker.Worker
+	fcmClient fcm.FCMClient
+	reloader  *reload.Reloader
+}
+
+func NewAdminHandler(w *worker.Worker, fcmClient fcm.FCMClient, reloader *reload.Reloader) *AdminHandler {
+	return &AdminHandler{worker: w, fcmClient: fcmClient, reloader: reloader}
+}
+
+// GetWorkerSettings godoc
+// @Summary Get worker settings
+// @Description Get the queue worker's current prefetch count, pool size and active handlers
+// @Tags admin
+// @Produce json
+// @Success 200 {object} worker.Settings
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/worker [get]
+func (h *AdminHandler) GetWorkerSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, h.worker.Settings())
+}
+
+// UpdateWorkerSettings godoc
+// @Summary Update worker settings
+// @Description Change the queue worker's prefetch count and/or pool size at runtime without redeploying
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body UpdateWorkerSettingsRequest true "Worker settings"
+// @Success 200 {object} worker.Settings
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body, settings that aren't positive or concurrency above resource limit"
+// @Failure 500 {object} map[string]string "Failed to update worker settings"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/worker [put]
+func (h *AdminHandler) UpdateWorkerSettings(c *gin.Context) {
+	var req UpdateWorkerSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid worker settings request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	settings, err := h.worker.Update(req.PrefetchCount, req.Concurrency)
+	if errors.Is(err, worker.ErrConcurrencyLimit) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Concurrency above resource limit", "details": err.Error()})
+		return
+	}
+	if errors.Is(err, worker.ErrInvalidSettings) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid worker settings", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		zap.L().Error("Failed to update worker settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update worker settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetWorkerAutoscale godoc
+// @Summary Get worker load
+// @Description Get the queue backlog and how busy the worker's pool was at the last autoscale reading, and the pool size the controller wants, e.g. for KEDA's metrics-api scaler
+// @Tags admin
+// @Produce json
+// @Success 200 {object} worker.AutoscaleStatus
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Failure 503 {object} map[string]string "No reading yet"
+// @Router /v1/admin/worker/autoscale [get]
+func (h *AdminHandler) GetWorkerAutoscale(c *gin.Context) {
+	status, ok := h.worker.Autoscale()
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No load reading yet"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// ListConsumers godoc
+// @Summary List queue consumers
+// @Description List this instance's active queue consumers with their broker consumer tags and how many of their messages are unacknowledged, to find the replica holding unacked messages
+// @Tags admin
+// @Produce json
+// @Success 200 {object} ConsumersResponse
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/consumers [get]
+func (h *AdminHandler) ListConsumers(c *gin.Context) {
+	c.JSON(http.StatusOK, ConsumersResponse{
+		Host:      queue.Hostname(),
+		Consumers: queue.ActiveConsumers(),
+	})
+}
+
+// GetProviderStatus godoc
+// @Summary Get FCM provider status
+// @Description Report whether FCM is currently accepting the service credentials
+// @Tags admin
+// @Produce json
+// @Success 200 {object} fcm.ProviderStatus
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/fcm/status [get]
+func (h *AdminHandler) GetProviderStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.fcmClient.Status())
+}
+
+// ReloadProviderCredentials godoc
+// @Summary Reload FCM credentials
+// @Description Re-read the FCM service account and verify it with a validate-only probe; delivery resumes if it succeeds
+// @Tags admin
+// @Produce json
+// @Success 200 {object} fcm.ProviderStatus
+// @Failure 502 {object} map[string]string "Credentials still rejected"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/fcm/reload [post]
+func (h *AdminHandler) ReloadProviderCredentials(c *gin.Context) {
+	if err := h.fcmClient.Reload(c.Request.Context()); err != nil {
+		zap.L().Error("Failed to reload FCM credentials", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to reload FCM credentials",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.fcmClient.Status())
+}
+
+// ListSandboxSends godoc
+// @Summary List FCM sandbox sends
+// @Description With FCM_MODE=sandbox, list the most recent sends this instance made to the simulated FCM of the primary project, newest first, with the simulated errors
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Maximum sends to return (default 50, max 1000)"
+// @Success 200 {object} SandboxSendsResponse
+// @Failure 400 {object} map[string]string "Invalid limit"
+// @Failure 404 {object} map[string]string "FCM sandbox mode is off"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/fcm/sandbox/sends [get]
+func (h *AdminHandler) ListSandboxSends(c *gin.Context) {
+	sandbox := h.fcmClient.Sandbox()
+	if sandbox == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "FCM sandbox mode is off"})
+		return
+	}
+
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit", "details": "limit must be between 1 and 1000"})
+			return
+		}
+		limit = parsed
+	}
+
+	sends, total := sandbox.Sends(limit)
+	c.JSON(http.StatusOK, SandboxSendsResponse{
+		Total: total,
+		Count: len(sends),
+		Sends: sends,
+	})
+}
+
+// ResetSandboxSends godoc
+// @Summary Clear FCM sandbox sends
+// @Description With FCM_MODE=sandbox, forget the sends this instance recorded
+// @Tags admin
+// @Success 204 "Sends cleared"
+// @Failure 404 {object} map[string]string "FCM sandbox mode is off"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/fcm/sandbox/sends [delete]
+func (h *AdminHandler) ResetSandboxSends(c *gin.Context) {
+	sandbox := h.fcmClient.Sandbox()
+	if sandbox == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "FCM sandbox mode is off"})
+		return
+	}
+
+	sandbox.Reset()
+	c.Status(http.StatusNoContent)
+}
+
+// ReloadConfig godoc
+// @Summary Reload configuration
+// @Description Re-read the configuration file and environment and apply the log level, worker prefetch count and concurrency, retry limits and FCM send rate caps without restarting. Other changed settings apply after a restart. SIGHUP does the same.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} reload.Result
+// @Failure 400 {object} map[string]string "Invalid configuration; nothing was applied"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/config/reload [post]
+func (h *AdminHandler) ReloadConfig(c *gin.Context) {
+	result, err := h.reloader.Reload()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to reload configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
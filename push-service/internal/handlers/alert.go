@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AlertHandler struct {
+	alertService service.AlertService
+}
+
+func NewAlertHandler(alertService service.AlertService) *AlertHandler {
+	return &AlertHandler{alertService: alertService}
+}
+
+// ListAlertRules godoc
+// @Summary List alert rules
+// @Description List the built-in alert rules that are on and the rules from the config file and the admin API, with the outcome of their last evaluation
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.AlertRule
+// @Failure 500 {object} map[string]string "Failed to list alert rules"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/alert-rules [get]
+func (h *AlertHandler) ListAlertRules(c *gin.Context) {
+	rules, err := h.alertService.ListRules(c.Request.Context())
+	if err != nil {
+		zap.L().Error("Failed to list alert rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alert rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateAlertRule godoc
+// @Summary Create an alert rule
+// @Description Add a rule that calls a webhook when a delivery metric over a window crosses a threshold, e.g. failure_rate > 0.2 for platform=ios over 10m, or dead_letters > 1000 over 1h. The webhook is called again when the rule resolves.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body models.CreateAlertRuleRequest true "Alert rule"
+// @Success 201 {object} models.AlertRule
+// @Failure 400 {object} map[string]string "Invalid alert rule"
+// @Failure 409 {object} map[string]string "Alert rule already exists"
+// @Failure 500 {object} map[string]string "Failed to create alert rule"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/alert-rules [post]
+func (h *AlertHandler) CreateAlertRule(c *gin.Context) {
+	var req models.CreateAlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid alert rule request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	rule, err := h.alertService.CreateRule(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAlertRule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule", "details": err.Error()})
+		case errors.Is(err, service.ErrAlertRuleExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "Alert rule already exists"})
+		default:
+			zap.L().Error("Failed to create alert rule", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert rule"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteAlertRule godoc
+// @Summary Delete an alert rule
+// @Description Delete an alert rule created through the admin API. Rules from the config file can only be removed there.
+// @Tags admin
+// @Produce json
+// @Param name path string true "Rule name"
+// @Success 200 {object} map[string]string "Alert rule deleted successfully"
+// @Failure 404 {object} map[string]string "Alert rule not found"
+// @Failure 500 {object} map[string]string "Failed to delete alert rule"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/alert-rules/{name} [delete]
+func (h *AlertHandler) DeleteAlertRule(c *gin.Context) {
+	if err := h.alertService.DeleteRule(c.Request.Context(), c.Param("name")); err != nil {
+		if errors.Is(err, service.ErrAlertRuleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return
+		}
+		zap.L().Error("Failed to delete alert rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert rule deleted successfully"})
+}
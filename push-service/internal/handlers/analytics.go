@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type AnalyticsHandler struct {
+	analyticsService service.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService service.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{analyticsService: analyticsService}
+}
+
+// GetSummary godoc
+// @Summary Get delivery analytics
+// @Description Get the sent, delivered, failed and opened counts per UTC day and platform from the worker's rollups, with their totals overall and per platform. Sent counts every token attempted, retries included; opened counts opens reported by the client SDK. Without dates the summary covers the last 7 days; a range covers at most 366 days.
+// @Tags analytics
+// @Produce json
+// @Param from query string false "First day, inclusive (YYYY-MM-DD)"
+// @Param to query string false "Last day, inclusive (YYYY-MM-DD); defaults to today"
+// @Success 200 {object} models.AnalyticsSummary
+// @Failure 400 {object} map[string]string "Invalid date range"
+// @Failure 500 {object} map[string]string "Failed to get analytics"
+// @Router /v1/analytics/summary [get]
+func (h *AnalyticsHandler) GetSummary(c *gin.Context) {
+	summary, err := h.analyticsService.GetSummary(c.Request.Context(), c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDateRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range", "details": err.Error()})
+			return
+		}
+		zap.L().Error("Failed to get analytics summary", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetCampaignVariants godoc
+// @Summary Compare a campaign's A/B variants
+// @Description Get the sent, delivered, failed and opened counts of each variant of a campaign, with its open rate (opened over delivered). Opens are counted when the client SDK reports the campaign_id and variant from the notification's data.
+// @Tags analytics
+// @Produce json
+// @Param id path string true "Campaign ID"
+// @Success 200 {object} models.CampaignVariantReport
+// @Failure 404 {object} map[string]string "Campaign not found"
+// @Failure 500 {object} map[string]string "Failed to get analytics"
+// @Router /v1/analytics/campaigns/{id}/variants [get]
+func (h *AnalyticsHandler) GetCampaignVariants(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	report, err := h.analyticsService.GetCampaignVariants(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		zap.L().Error("Failed to get campaign variant analytics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RecordOpen godoc
+// @Summary Report a notification open from the client SDK
+// @Description Count a notification opened on the device in today's delivery analytics. If platform is omitted it is detected from the X-Platform header or the User-Agent. For campaign variants, pass the campaign_id and variant from the notification's data to count the open towards the variant.
+// @Tags sdk
+// @Accept json
+// @Produce json
+// @Param X-Platform header string false "Device platform (ios, android or web)"
+// @Param request body models.RecordOpenRequest true "Notification open"
+// @Success 202 {object} map[string]string "Open recorded"
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 500 {object} map[string]string "Failed to record open"
+// @Router /v1/sdk/opens [post]
+func (h *AnalyticsHandler) RecordOpen(c *gin.Context) {
+	var req models.RecordOpenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid notification open", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+	if req.Platform == "" {
+		req.Platform = detectPlatform(c.GetHeader(PlatformHeader), c.GetHeader("User-Agent"))
+	}
+
+	if err := h.analyticsService.RecordOpen(c.Request.Context(), req); err != nil {
+		zap.L().Error("Failed to record notification open", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record open"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Open recorded"})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+	"push-service/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type CampaignHandler struct {
+	campaignService service.CampaignService
+}
+
+func NewCampaignHandler(campaignService service.CampaignService) *CampaignHandler {
+	return &CampaignHandler{campaignService: campaignService}
+}
+
+// CreateCampaign godoc
+// @Summary Launch a campaign
+// @Description Send a notification to a large audience. Users are enqueued gradually, paced against each FCM project's daily quota: the spread policy carries the overflow into the following days, the failover policy sends it through the failover projects first. analytics_label segments the campaign's deliveries in Firebase reporting. With variants, each user gets one variant's title and body, split by the variants' weights. With a canary, a random share of the audience is sent to first; the campaign then soaks until the soak has passed or it is continued.
+// @Tags campaigns
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant ID; tenants in soft launch can't launch campaigns"
+// @Param request body models.CreateCampaignRequest true "Campaign request"
+// @Success 201 {object} models.Campaign
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 403 {object} map[string]string "Tenant is in soft launch"
+// @Failure 500 {object} map[string]string "Failed to create campaign"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/campaigns [post]
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	var req models.CreateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid campaign request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+	req.TenantID = c.GetHeader(tracing.TenantHeader)
+
+	campaign, err := h.campaignService.CreateCampaign(c.Request.Context(), req)
+	if err != nil {
+		if softLaunchRejected(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidVariants) || errors.Is(err, service.ErrInvalidCanary) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+		zap.L().Error("Failed to create campaign", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// GetCampaign godoc
+// @Summary Get campaign status
+// @Description Get a campaign's progress and, while it is running, its projected completion time given the remaining FCM quota
+// @Tags campaigns
+// @Accept json
+// @Produce json
+// @Param id path string true "Campaign ID"
+// @Success 200 {object} models.Campaign
+// @Failure 404 {object} map[string]string "Campaign not found"
+// @Failure 500 {object} map[string]string "Failed to get campaign"
+// @Router /v1/campaigns/{id} [get]
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	campaign, err := h.campaignService.GetCampaign(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		zap.L().Error("Failed to get campaign", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// CancelCampaign godoc
+// @Summary Cancel a campaign
+// @Description Stop enqueueing a running or soaking campaign. Users already enqueued are still delivered.
+// @Tags campaigns
+// @Accept json
+// @Produce json
+// @Param id path string true "Campaign ID"
+// @Success 200 {object} map[string]string "Campaign cancelled successfully"
+// @Failure 404 {object} map[string]string "Campaign not found"
+// @Failure 500 {object} map[string]string "Failed to cancel campaign"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/campaigns/{id}/cancel [post]
+func (h *CampaignHandler) CancelCampaign(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	if err := h.campaignService.CancelCampaign(c.Request.Context(), id); err != nil {
+		if errors.Is(err, service.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		zap.L().Error("Failed to cancel campaign", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign cancelled successfully"})
+}
+
+// ContinueCampaign godoc
+// @Summary Continue a campaign after its canary
+// @Description Send a soaking campaign to the rest of its audience now, instead of waiting for its soak to pass. Campaigns with a canary but no soak wait for this call.
+// @Tags campaigns
+// @Accept json
+// @Produce json
+// @Param id path string true "Campaign ID"
+// @Success 200 {object} map[string]string "Campaign continued successfully"
+// @Failure 404 {object} map[string]string "Campaign not found"
+// @Failure 409 {object} map[string]string "Campaign is not soaking"
+// @Failure 500 {object} map[string]string "Failed to continue campaign"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/campaigns/{id}/continue [post]
+func (h *CampaignHandler) ContinueCampaign(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	if err := h.campaignService.ContinueCampaign(c.Request.Context(), id); err != nil {
+		if errors.Is(err, service.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		if errors.Is(err, service.ErrCampaignNotSoaking) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Campaign is not soaking", "details": "only a campaign waiting after its canary can be continued"})
+			return
+		}
+		zap.L().Error("Failed to continue campaign", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to continue campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign continued successfully"})
+}
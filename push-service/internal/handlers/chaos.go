@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"push-service/internal/chaos"
+	"push-service/internal/config"
+	"push-service/internal/queue"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UpdateChaosRequest changes the fault injection rates at runtime
+// @Description Fault injection settings update (omitted fields are unchanged)
+type UpdateChaosRequest struct {
+	FCMDelay        *string  `json:"fcm_delay,omitempty" example:"2s"`
+	FCMDelayRate    *float64 `json:"fcm_delay_rate,omitempty" binding:"omitempty,min=0,max=1" example:"0.1"`
+	FCMFailureRate  *float64 `json:"fcm_failure_rate,omitempty" binding:"omitempty,min=0,max=1" example:"0.05"`
+	AckDropRate     *float64 `json:"ack_drop_rate,omitempty" binding:"omitempty,min=0,max=1" example:"0.01"`
+	ChannelKillRate *float64 `json:"channel_kill_rate,omitempty" binding:"omitempty,min=0,max=1" example:"0.001"`
+}
+
+// ChaosResponse reports the fault injection settings of the instance that
+// answered and the faults it injected
+// @Description Fault injection settings and injected faults of one instance
+type ChaosResponse struct {
+	FCMDelay        string  `json:"fcm_delay" example:"2s"`
+	FCMDelayRate    float64 `json:"fcm_delay_rate" example:"0.1"`
+	FCMFailureRate  float64 `json:"fcm_failure_rate" example:"0.05"`
+	AckDropRate     float64 `json:"ack_drop_rate" example:"0.01"`
+	ChannelKillRate float64 `json:"channel_kill_rate" example:"0.001"`
+	// Injected counts the faults injected since the start, by kind
+	Injected map[string]int64 `json:"injected"`
+}
+
+type ChaosHandler struct {
+	faults *chaos.Faults
+	broker queue.Broker
+}
+
+func NewChaosHandler(faults *chaos.Faults, broker queue.Broker) *ChaosHandler {
+	return &ChaosHandler{faults: faults, broker: broker}
+}
+
+// enabled answers 404 and returns false if fault injection is off
+func (h *ChaosHandler) enabled(c *gin.Context) bool {
+	if h.faults == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fault injection is off", "details": "start the instance with CHAOS_ENABLED=true"})
+		return false
+	}
+	return true
+}
+
+func (h *ChaosHandler) response() ChaosResponse {
+	cfg := h.faults.Settings()
+	return ChaosResponse{
+		FCMDelay:        cfg.FCMDelay.String(),
+		FCMDelayRate:    cfg.FCMDelayRate,
+		FCMFailureRate:  cfg.FCMFailureRate,
+		AckDropRate:     cfg.AckDropRate,
+		ChannelKillRate: cfg.ChannelKillRate,
+		Injected:        h.faults.Injected(),
+	}
+}
+
+// GetChaos godoc
+// @Summary Get fault injection settings
+// @Description With CHAOS_ENABLED, get this instance's fault injection rates and how many faults of each kind it injected
+// @Tags admin
+// @Produce json
+// @Success 200 {object} ChaosResponse
+// @Failure 404 {object} map[string]string "Fault injection is off"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/chaos [get]
+func (h *ChaosHandler) GetChaos(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.response())
+}
+
+// UpdateChaos godoc
+// @Summary Update fault injection settings
+// @Description With CHAOS_ENABLED, change this instance's fault injection rates: the shares of FCM requests delayed by fcm_delay or failed with INTERNAL, of queue acks dropped, and of acks that close the broker channels instead. Set a rate to 0 to stop that fault. Changes last until the restart.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body UpdateChaosRequest true "Fault injection settings"
+// @Success 200 {object} ChaosResponse
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body or settings"
+// @Failure 404 {object} map[string]string "Fault injection is off"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/chaos [put]
+func (h *ChaosHandler) UpdateChaos(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+
+	var req UpdateChaosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid fault injection request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	cfg := h.faults.Settings()
+	if req.FCMDelay != nil {
+		delay, err := time.ParseDuration(*req.FCMDelay)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fcm_delay", "details": err.Error()})
+			return
+		}
+		cfg.FCMDelay = delay
+	}
+	setRate(&cfg.FCMDelayRate, req.FCMDelayRate)
+	setRate(&cfg.FCMFailureRate, req.FCMFailureRate)
+	setRate(&cfg.AckDropRate, req.AckDropRate)
+	setRate(&cfg.ChannelKillRate, req.ChannelKillRate)
+
+	if err := h.faults.Update(cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fault injection settings", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.response())
+}
+
+func setRate(rate *float64, value *float64) {
+	if value != nil {
+		*rate = *value
+	}
+}
+
+// KillChannels godoc
+// @Summary Close the broker channels
+// @Description With CHAOS_ENABLED, close this instance's RabbitMQ publisher and consumer channels now, as a channel error would. They are reopened, and the consumers' unacked messages are redelivered.
+// @Tags admin
+// @Success 204 "Channels closed"
+// @Failure 404 {object} map[string]string "Fault injection is off"
+// @Failure 409 {object} map[string]string "The queue backend has no channels"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/chaos/kill-channels [post]
+func (h *ChaosHandler) KillChannels(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	if !queue.KillChannels(h.broker) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The queue backend has no channels", "details": "only the " + config.QueueBackendRabbitMQ + " backend has channels to close"})
+		return
+	}
+	h.faults.Record(chaos.FaultChannelKill)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/internal/service"
+	"push-service/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type DeadLetterHandler struct {
+	archiver  service.DeadLetterArchiver
+	pushQueue *queue.PushQueue
+}
+
+func NewDeadLetterHandler(archiver service.DeadLetterArchiver, pushQueue *queue.PushQueue) *DeadLetterHandler {
+	return &DeadLetterHandler{archiver: archiver, pushQueue: pushQueue}
+}
+
+// ReplayDeadLettersRequest replays messages from the dead letter queue
+// @Description Dead letter replay request
+type ReplayDeadLettersRequest struct {
+	// Limit is how many messages to replay at most
+	Limit int `json:"limit" binding:"required,min=1,max=10000" example:"10"`
+}
+
+// ListDeadLetters godoc
+// @Summary Peek at the dead letter queue
+// @Description Get the messages at the head of the dead letter queue, oldest first, without removing them: each message's notification, retry count and masked device tokens, and why messages that can't be decoded couldn't. At most the worker's prefetch count are returned. The messages are put back afterwards, at the end of the queue on Kafka.
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Maximum messages to return (default 10, max 100)"
+// @Success 200 {object} models.DeadLetters
+// @Failure 400 {object} map[string]string "Invalid limit"
+// @Failure 500 {object} map[string]string "Failed to peek at dead letters"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/dead-letters [get]
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	limit := 10
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit", "details": "limit must be between 1 and 100"})
+			return
+		}
+		limit = parsed
+	}
+
+	peeked, err := h.pushQueue.PeekDeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		zap.L().Error("Failed to peek at dead letters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to peek at dead letters", "details": err.Error()})
+		return
+	}
+
+	deadLetters := make([]models.DeadLetter, len(peeked))
+	for i, peek := range peeked {
+		deadLetters[i] = deadLetterResponse(peek)
+	}
+	c.JSON(http.StatusOK, models.DeadLetters{DeadLetters: deadLetters, Count: len(deadLetters)})
+}
+
+func deadLetterResponse(peek queue.DeadLetter) models.DeadLetter {
+	deadLetter := models.DeadLetter{ID: peek.Delivery.ID}
+	if !peek.Delivery.PublishedAt.IsZero() {
+		deadLetteredAt := peek.Delivery.PublishedAt.UTC()
+		deadLetter.DeadLetteredAt = &deadLetteredAt
+	}
+	if peek.Message == nil {
+		deadLetter.Error = peek.Err.Error()
+		return deadLetter
+	}
+
+	message := peek.Message
+	deadLetter.NotificationID = message.Notification.ID
+	deadLetter.UserID = message.Notification.UserID
+	deadLetter.Title = message.Notification.Title
+	deadLetter.Priority = message.Priority
+	deadLetter.CampaignID = message.CampaignID
+	deadLetter.RetryCount = message.RetryCount
+	deadLetter.LastError = message.LastError
+	for _, token := range message.DeviceTokens {
+		deadLetter.DeviceTokens = append(deadLetter.DeviceTokens, logger.MaskToken(token))
+	}
+	return deadLetter
+}
+
+// ReplayDeadLetters godoc
+// @Summary Replay dead letters
+// @Description Move up to limit messages from the head of the dead letter queue back to the push queues of their priorities, with their retries reset, e.g. once an FCM incident is over. Messages that can't be decoded are left in the queue and counted as skipped.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body ReplayDeadLettersRequest true "How many dead letters to replay"
+// @Success 200 {object} models.DeadLetterReplay
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 500 {object} map[string]string "Failed to replay dead letters"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/dead-letters/replay [post]
+func (h *DeadLetterHandler) ReplayDeadLetters(c *gin.Context) {
+	var req ReplayDeadLettersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid dead letter replay request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	replayed, skipped, err := h.pushQueue.ReplayDeadLetters(c.Request.Context(), req.Limit)
+	if err != nil {
+		zap.L().Error("Failed to replay dead letters", zap.Int("replayed", replayed), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "Failed to replay dead letters",
+			"details":  err.Error(),
+			"replayed": replayed,
+		})
+		return
+	}
+
+	zap.L().Info("Dead letters replayed", zap.Int("replayed", replayed), zap.Int("skipped", skipped))
+	c.JSON(http.StatusOK, models.DeadLetterReplay{Replayed: replayed, Skipped: skipped})
+}
+
+// ListArchivedDeadLetters godoc
+// @Summary Find a notification's archived dead letters
+// @Description Get where a notification's dead letters were archived: the gzipped NDJSON object holding each and its line in the object. Dead letters moved out of the dead letter queue but not yet written to the bucket are listed without an object.
+// @Tags admin
+// @Produce json
+// @Param notification_id query string true "Notification ID"
+// @Success 200 {object} models.ArchivedDeadLetters
+// @Failure 400 {object} map[string]string "Missing notification_id"
+// @Failure 500 {object} map[string]string "Failed to list archived dead letters"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/dead-letters/archive [get]
+func (h *DeadLetterHandler) ListArchivedDeadLetters(c *gin.Context) {
+	notificationID := c.Query("notification_id")
+	if notificationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing notification_id", "details": "notification_id is required"})
+		return
+	}
+
+	archived, err := h.archiver.ListArchived(c.Request.Context(), notificationID)
+	if err != nil {
+		zap.L().Error("Failed to list archived dead letters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list archived dead letters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, archived)
+}
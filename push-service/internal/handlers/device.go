@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"push-service/internal/models"
 	"push-service/internal/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -32,6 +34,13 @@ type GetUserDevicesResponse struct {
 	Count   int                     `json:"count" example:"2"`
 }
 
+// HeartbeatResponse represents a recorded device heartbeat
+// @Description Device heartbeat response
+type HeartbeatResponse struct {
+	Message    string    `json:"message" example:"Heartbeat recorded"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
 type DeviceHandler struct {
 	deviceService service.DeviceService
 }
@@ -48,14 +57,14 @@ func NewDeviceHandler(deviceService service.DeviceService) *DeviceHandler {
 // @Produce json
 // @Param request body models.CreateDeviceRequest true "Device registration request"
 // @Success 201 {object} RegisterDeviceResponse
-// @Failure 400 {object} map[string]string "Invalid request body"
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
 // @Failure 500 {object} map[string]string "Failed to register device"
 // @Router /v1/devices [post]
 func (h *DeviceHandler) RegisterDevice(c *gin.Context) {
 	var req models.CreateDeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		zap.L().Warn("Invalid request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		invalidBody(c, err)
 		return
 	}
 
@@ -100,6 +109,31 @@ func (h *DeviceHandler) UnregisterDevice(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Device unregistered successfully"})
 }
 
+// Heartbeat godoc
+// @Summary Record a device heartbeat
+// @Description Record that the app is still running on the device, so it counts as recently active. Registration and token refresh count as well. Devices that stop checking in are zombie registrations.
+// @Tags devices
+// @Produce json
+// @Param token path string true "Device token"
+// @Success 200 {object} HeartbeatResponse
+// @Failure 404 {object} map[string]string "Device not found"
+// @Failure 500 {object} map[string]string "Failed to record heartbeat"
+// @Router /v1/devices/{token}/heartbeat [post]
+func (h *DeviceHandler) Heartbeat(c *gin.Context) {
+	lastSeenAt, err := h.deviceService.Heartbeat(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		zap.L().Error("Failed to record heartbeat", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record heartbeat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, HeartbeatResponse{Message: "Heartbeat recorded", LastSeenAt: lastSeenAt})
+}
+
 // GetUserDevices godoc
 // @Summary Get user devices
 // @Description Get all registered devices for a user
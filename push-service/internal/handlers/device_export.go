@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// deviceExportColumns are the columns of a CSV device export. It can be
+// imported again as is.
+var deviceExportColumns = []string{
+	"id", "device_id", "user_id", "token", "platform", "is_active", "permission_status",
+	"timezone", "app_version", "os_version", "device_model", "locale",
+	"metadata", "last_seen_at", "created_at", "updated_at", "deactivated_at",
+}
+
+// ListDevices godoc
+// @Summary List devices
+// @Description List device records, active or not, optionally filtered, a page at a time in device ID order, to investigate registrations. Pass the returned next_cursor as cursor to get the next page.
+// @Tags admin
+// @Produce json
+// @Param user_id query string false "Only this user's devices"
+// @Param user_id_prefix query string false "Only devices of users whose ID starts with this"
+// @Param platform query string false "Only devices on this platform (ios, android or web)"
+// @Param active query bool false "Only active (true) or deactivated (false) devices"
+// @Param seen_since query string false "Only devices last seen at or after this time (RFC 3339)"
+// @Param created_before query string false "Only devices registered before this time (RFC 3339)"
+// @Param limit query int false "Maximum devices to return (default 50, max 500)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} models.DevicePage
+// @Failure 400 {object} map[string]string "Invalid filter, limit or cursor"
+// @Failure 500 {object} map[string]string "Failed to list devices"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/devices [get]
+func (h *DeviceHandler) ListDevices(c *gin.Context) {
+	filter, ok := deviceFilter(c)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit", "details": "limit must be between 1 and 500"})
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.deviceService.ListDevices(c.Request.Context(), filter, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor", "details": err.Error()})
+			return
+		}
+		zap.L().Error("Failed to list devices", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ExportDevices godoc
+// @Summary Export devices
+// @Description Stream every device record, active or not, optionally filtered, as NDJSON (one device per line) or CSV, in device ID order. If the download breaks off, pass the id of the last device received as cursor to resume after it. The CSV has a header and can be imported with POST /v1/admin/devices/imports.
+// @Tags admin
+// @Produce plain
+// @Param format query string false "ndjson (default) or csv"
+// @Param user_id query string false "Only this user's devices"
+// @Param user_id_prefix query string false "Only devices of users whose ID starts with this"
+// @Param platform query string false "Only devices on this platform (ios, android or web)"
+// @Param active query bool false "Only active (true) or deactivated (false) devices"
+// @Param seen_since query string false "Only devices last seen at or after this time (RFC 3339)"
+// @Param created_before query string false "Only devices registered before this time (RFC 3339)"
+// @Param cursor query string false "ID of the last device already received"
+// @Success 200 {string} string "NDJSON or CSV device records"
+// @Failure 400 {object} map[string]string "Invalid export parameters or cursor"
+// @Failure 500 {object} map[string]string "Failed to export devices"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/devices/export [get]
+func (h *DeviceHandler) ExportDevices(c *gin.Context) {
+	format := c.DefaultQuery("format", models.DeviceImportFormatNDJSON)
+	if format != models.DeviceImportFormatNDJSON && format != models.DeviceImportFormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format", "details": "format must be ndjson or csv"})
+		return
+	}
+
+	filter, ok := deviceFilter(c)
+	if !ok {
+		return
+	}
+
+	// Headers go out with the first page, so a failure before it still gets
+	// an error response
+	var csvWriter *csv.Writer
+	started := false
+	start := func() {
+		started = true
+		filename := fmt.Sprintf("devices-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if format == models.DeviceImportFormatCSV {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Status(http.StatusOK)
+			csvWriter = csv.NewWriter(c.Writer)
+			csvWriter.Write(deviceExportColumns)
+			return
+		}
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+
+	exported := 0
+	err := h.deviceService.ExportDevices(c.Request.Context(), filter, c.Query("cursor"), func(devices []models.Device) error {
+		if !started {
+			start()
+		}
+		for _, device := range devices {
+			if err := writeExportedDevice(c, csvWriter, device); err != nil {
+				return err
+			}
+		}
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		exported += len(devices)
+		return nil
+	})
+	if err != nil {
+		if started {
+			// The client resumes from the last complete record it got
+			zap.L().Error("Device export interrupted", zap.Int("exported", exported), zap.Error(err))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor", "details": err.Error()})
+			return
+		}
+		zap.L().Error("Failed to export devices", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export devices"})
+		return
+	}
+
+	if !started {
+		start()
+		if csvWriter != nil {
+			csvWriter.Flush()
+		}
+	}
+	zap.L().Info("Devices exported", zap.String("format", format), zap.Int("exported", exported))
+}
+
+// deviceFilter reads a device filter from the query, responding with 400 if
+// it is invalid
+func deviceFilter(c *gin.Context) (models.DeviceFilter, bool) {
+	filter := models.DeviceFilter{
+		UserID:       c.Query("user_id"),
+		UserIDPrefix: c.Query("user_id_prefix"),
+		Platform:     c.Query("platform"),
+	}
+	switch filter.Platform {
+	case "", "ios", "android", "web":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid platform", "details": "platform must be ios, android or web"})
+		return filter, false
+	}
+	if value := c.Query("active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid active filter", "details": "active must be true or false"})
+			return filter, false
+		}
+		filter.Active = &active
+	}
+	if value := c.Query("seen_since"); value != "" {
+		seenSince, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid seen_since", "details": "seen_since must be an RFC 3339 time"})
+			return filter, false
+		}
+		filter.SeenSince = &seenSince
+	}
+	if value := c.Query("created_before"); value != "" {
+		createdBefore, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created_before", "details": "created_before must be an RFC 3339 time"})
+			return filter, false
+		}
+		filter.CreatedBefore = &createdBefore
+	}
+
+	return filter, true
+}
+
+func writeExportedDevice(c *gin.Context, csvWriter *csv.Writer, device models.Device) error {
+	if csvWriter == nil {
+		line, err := json.Marshal(device)
+		if err != nil {
+			return err
+		}
+		_, err = c.Writer.Write(append(line, '\n'))
+		return err
+	}
+
+	var metadata []byte
+	if device.Metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(device.Metadata); err != nil {
+			return err
+		}
+	}
+
+	return csvWriter.Write([]string{
+		device.ID,
+		stringValue(device.StableDeviceID),
+		device.UserID,
+		device.Token,
+		device.Platform,
+		strconv.FormatBool(device.IsActive),
+		stringValue(device.PermissionStatus),
+		stringValue(device.Timezone),
+		stringValue(device.AppVersion),
+		stringValue(device.OSVersion),
+		stringValue(device.DeviceModel),
+		stringValue(device.Locale),
+		string(metadata),
+		timeValue(device.LastSeenAt),
+		device.CreatedAt.UTC().Format(time.RFC3339Nano),
+		device.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		timeValue(device.DeactivatedAt),
+	})
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func timeValue(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339Nano)
+}
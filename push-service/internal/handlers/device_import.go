@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListDeviceImportsResponse represents the recent device imports
+// @Description Recent device imports, newest first
+type ListDeviceImportsResponse struct {
+	Imports []models.DeviceImport `json:"imports"`
+	Count   int                   `json:"count" example:"1"`
+}
+
+type DeviceImportHandler struct {
+	importService service.DeviceImportService
+}
+
+func NewDeviceImportHandler(importService service.DeviceImportService) *DeviceImportHandler {
+	return &DeviceImportHandler{importService: importService}
+}
+
+// ImportDevices godoc
+// @Summary Import devices in bulk
+// @Description Stream an NDJSON file (one {"user_id","token","platform"} object per line) or a CSV file with a user_id,token,platform header as the request body. Rows are validated and upserted in batches: registered tokens are moved to the row's user and platform and reactivated. Invalid rows are skipped and counted. The request returns when the import finishes; follow its progress meanwhile with GET /v1/admin/devices/imports.
+// @Tags admin
+// @Accept plain
+// @Produce json
+// @Param format query string false "ndjson or csv; defaults from the Content-Type (application/x-ndjson or text/csv)"
+// @Success 200 {object} models.DeviceImport
+// @Failure 400 {object} map[string]string "Unknown import format or invalid import file"
+// @Failure 500 {object} map[string]string "Failed to import devices"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/devices/imports [post]
+func (h *DeviceImportHandler) ImportDevices(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		format = importFormat(c.GetHeader("Content-Type"))
+	}
+
+	deviceImport, err := h.importService.Import(c.Request.Context(), format, c.Request.Body)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownImportFormat) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown import format", "details": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidImportFile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import file", "details": err.Error(), "import": deviceImport})
+			return
+		}
+		zap.L().Error("Failed to import devices", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import devices", "details": err.Error(), "import": deviceImport})
+		return
+	}
+
+	c.JSON(http.StatusOK, deviceImport)
+}
+
+// importFormat maps an import's Content-Type to its format
+func importFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return models.DeviceImportFormatNDJSON
+	case "text/csv":
+		return models.DeviceImportFormatCSV
+	}
+	return ""
+}
+
+// ListDeviceImports godoc
+// @Summary List device imports
+// @Description List the most recent device imports, newest first, with their progress
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Maximum imports to return (default 20, max 100)"
+// @Success 200 {object} ListDeviceImportsResponse
+// @Failure 400 {object} map[string]string "Invalid limit"
+// @Failure 500 {object} map[string]string "Failed to list device imports"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/devices/imports [get]
+func (h *DeviceImportHandler) ListDeviceImports(c *gin.Context) {
+	limit := 20
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit", "details": "limit must be between 1 and 100"})
+			return
+		}
+		limit = parsed
+	}
+
+	imports, err := h.importService.ListImports(c.Request.Context(), limit)
+	if err != nil {
+		zap.L().Error("Failed to list device imports", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list device imports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListDeviceImportsResponse{Imports: imports, Count: len(imports)})
+}
+
+// GetDeviceImport godoc
+// @Summary Get a device import
+// @Description Get a device import's status, row counts and the first rejected rows
+// @Tags admin
+// @Produce json
+// @Param id path string true "Import ID"
+// @Success 200 {object} models.DeviceImport
+// @Failure 404 {object} map[string]string "Device import not found"
+// @Failure 500 {object} map[string]string "Failed to get device import"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/devices/imports/{id} [get]
+func (h *DeviceImportHandler) GetDeviceImport(c *gin.Context) {
+	deviceImport, err := h.importService.GetImport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrDeviceImportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Device import not found"})
+			return
+		}
+		zap.L().Error("Failed to get device import", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get device import"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deviceImport)
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/worker"
 	"push-service/pkg/database"
 	"time"
 
@@ -13,6 +15,12 @@ type HealthResponse struct {
 	Status    string `json:"status" example:"healthy"`
 	Timestamp string `json:"timestamp" example:"2025-01-01T00:00:00Z"`
 	Database  string `json:"database,omitempty" example:"healthy"`
+	Replica   string `json:"replica,omitempty" example:"healthy"`
+	Provider  string `json:"provider,omitempty" example:"ok"`
+	// Schema is current, behind (migrations this build needs are missing),
+	// dirty (a migration failed partway) or unknown
+	Schema        string `json:"schema,omitempty" example:"current"`
+	SchemaVersion uint   `json:"schema_version,omitempty" example:"26"`
 }
 
 // HealthCheck godoc
@@ -34,16 +42,55 @@ func HealthCheck(c *gin.Context) {
 	})
 }
 
+// HealthDetailsResponse represents the detailed health check response
+type HealthDetailsResponse struct {
+	Status    string `json:"status" example:"healthy"`
+	Timestamp string `json:"timestamp" example:"2025-01-01T00:00:00Z"`
+	// Worker is omitted when this process runs no queue worker
+	Worker *worker.Health `json:"worker,omitempty"`
+}
+
+// HealthDetails godoc
+// @Summary Detailed health check endpoint
+// @Description Returns the queue worker's progress: when it last finished a message, the messages in flight and waiting, its consumers and broker reconnects. Responds 503 while the worker is stalled, i.e. messages are in flight or waiting but none finished within QUEUE_WORKER_STALL_TIMEOUT, or stopped.
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthDetailsResponse
+// @Failure 503 {object} HealthDetailsResponse
+// @Router /health/details [get]
+func HealthDetails(pushWorker *worker.Worker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response := HealthDetailsResponse{
+			Status:    "healthy",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		status := http.StatusOK
+
+		if pushWorker != nil {
+			health := pushWorker.Health(c.Request.Context())
+			response.Worker = &health
+			if health.Status == worker.HealthStalled || health.Status == worker.HealthStopped {
+				response.Status = "unhealthy"
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		c.JSON(status, response)
+	}
+}
+
 // ReadinessCheck godoc
 // @Summary Readiness check endpoint
-// @Description Returns the readiness status of the service including database connectivity
+// @Description Returns the readiness status of the service including database (and read replica) connectivity, the database schema version and FCM credential status
 // @Tags health
 // @Accept json
 // @Produce json
 // @Success 200 {object} HealthResponse
 // @Failure 503 {object} HealthResponse
 // @Router /ready [get]
-func ReadinessCheck(db *database.DB) gin.HandlerFunc {
+func ReadinessCheck(db *database.DB, fcmClient fcm.FCMClient) gin.HandlerFunc {
+	latestMigration := database.LatestMigration()
+
 	return func(c *gin.Context) {
 		var dbStatus string
 
@@ -53,15 +100,44 @@ func ReadinessCheck(db *database.DB) gin.HandlerFunc {
 			dbStatus = "healthy"
 		}
 
+		// Without a replica, its reads go to the primary
+		var replicaStatus string
+		if db.Replica != nil {
+			if err := db.Replica.Ping(c.Request.Context()); err != nil {
+				replicaStatus = "unhealthy"
+			} else {
+				replicaStatus = "healthy"
+			}
+		}
+
+		// A newer schema is fine, e.g. while an older build is rolled back.
+		// So is one not managed by migrations, whose version is unknown.
+		schemaStatus := "current"
+		schemaVersion, dirty, err := database.SchemaVersion(c.Request.Context(), db.Pool)
+		switch {
+		case err != nil:
+			schemaStatus = "unknown"
+		case dirty:
+			schemaStatus = "dirty"
+		case schemaVersion < latestMigration:
+			schemaStatus = "behind"
+		}
+
+		providerStatus := fcmClient.Status().Status
+
 		status := http.StatusOK
-		if dbStatus != "healthy" {
+		if dbStatus != "healthy" || replicaStatus == "unhealthy" || schemaStatus == "behind" || schemaStatus == "dirty" || providerStatus != fcm.ProviderStatusOK {
 			status = http.StatusServiceUnavailable
 		}
 
 		c.JSON(status, HealthResponse{
-			Status:    "ready",
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-			Database:  dbStatus,
+			Status:        "ready",
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+			Database:      dbStatus,
+			Replica:       replicaStatus,
+			Provider:      providerStatus,
+			Schema:        schemaStatus,
+			SchemaVersion: schemaVersion,
 		})
 	}
 }
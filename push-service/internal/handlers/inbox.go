@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"push-service/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type InboxHandler struct {
+	inboxService service.InboxService
+}
+
+func NewInboxHandler(inboxService service.InboxService) *InboxHandler {
+	return &InboxHandler{inboxService: inboxService}
+}
+
+// GetInbox godoc
+// @Summary Get a user's inbox
+// @Description Get the notifications the user was sent, newest first, with their read state and the user's unread count. Pass the returned next_cursor as cursor to get the next page.
+// @Tags inbox
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param limit query int false "Maximum items to return (default 20, max 100)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param unread_only query bool false "Only unread items"
+// @Success 200 {object} models.InboxPage
+// @Failure 400 {object} map[string]string "User ID is required, or invalid limit or cursor"
+// @Failure 500 {object} map[string]string "Failed to get inbox"
+// @Router /v1/inbox [get]
+func (h *InboxHandler) GetInbox(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	limit := 20
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit", "details": "limit must be between 1 and 100"})
+			return
+		}
+		limit = parsed
+	}
+	unreadOnly := c.Query("unread_only") == "true"
+
+	page, err := h.inboxService.ListInbox(c.Request.Context(), userID, c.Query("cursor"), limit, unreadOnly)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor", "details": err.Error()})
+			return
+		}
+		zap.L().Error("Failed to get inbox", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inbox"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// MarkRead godoc
+// @Summary Mark an inbox item read
+// @Description Mark one of the user's inbox items read. Marking an item that is already read keeps its read time.
+// @Tags inbox
+// @Produce json
+// @Param id path string true "Inbox item ID"
+// @Param user_id query string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "User ID is required"
+// @Failure 404 {object} map[string]string "Inbox item not found"
+// @Failure 500 {object} map[string]string "Failed to mark inbox item read"
+// @Router /v1/inbox/{id}/read [post]
+func (h *InboxHandler) MarkRead(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	if err := h.inboxService.MarkRead(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, service.ErrInboxItemNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Inbox item not found"})
+			return
+		}
+		zap.L().Error("Failed to mark inbox item read", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark inbox item read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Inbox item marked read"})
+}
+
+// MarkAllRead godoc
+// @Summary Mark a user's inbox read
+// @Description Mark all of the user's unread inbox items read
+// @Tags inbox
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string "User ID is required"
+// @Failure 500 {object} map[string]string "Failed to mark inbox read"
+// @Router /v1/inbox/read-all [post]
+func (h *InboxHandler) MarkAllRead(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	marked, err := h.inboxService.MarkAllRead(c.Request.Context(), userID)
+	if err != nil {
+		zap.L().Error("Failed to mark inbox read", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark inbox read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Inbox marked read",
+		"marked":  marked,
+	})
+}
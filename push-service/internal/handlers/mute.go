@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetUserMutesResponse represents the user mutes response
+// @Description User mutes response
+type GetUserMutesResponse struct {
+	UserID string            `json:"user_id" example:"user123"`
+	Mutes  []models.UserMute `json:"mutes"`
+	Count  int               `json:"count" example:"1"`
+}
+
+type MuteHandler struct {
+	muteService service.MuteService
+}
+
+func NewMuteHandler(muteService service.MuteService) *MuteHandler {
+	return &MuteHandler{muteService: muteService}
+}
+
+// MuteUser godoc
+// @Summary Mute a sender or category
+// @Description Stop delivering a user's notifications from one sender or category, without opting them out of push
+// @Tags mutes
+// @Accept json
+// @Produce json
+// @Param request body models.CreateMuteRequest true "Mute request"
+// @Success 201 {object} models.UserMute
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 500 {object} map[string]string "Failed to mute"
+// @Router /v1/mutes [post]
+func (h *MuteHandler) MuteUser(c *gin.Context) {
+	var req models.CreateMuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid mute request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	mute, err := h.muteService.Mute(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrMuteExpired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+		zap.L().Error("Failed to mute", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mute"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, mute)
+}
+
+// UnmuteUser godoc
+// @Summary Remove a mute
+// @Description Resume delivery for a previously muted sender or category
+// @Tags mutes
+// @Accept json
+// @Produce json
+// @Param id path string true "Mute ID"
+// @Param user_id query string true "User ID"
+// @Success 200 {object} map[string]string "Mute removed successfully"
+// @Failure 400 {object} map[string]string "User ID is required"
+// @Failure 404 {object} map[string]string "Mute not found"
+// @Failure 500 {object} map[string]string "Failed to remove mute"
+// @Router /v1/mutes/{id} [delete]
+func (h *MuteHandler) UnmuteUser(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	if err := h.muteService.Unmute(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, service.ErrMuteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Mute not found"})
+			return
+		}
+		zap.L().Error("Failed to remove mute", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove mute"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mute removed successfully"})
+}
+
+// GetUserMutes godoc
+// @Summary Get user mutes
+// @Description Get a user's active sender and category mutes
+// @Tags mutes
+// @Accept json
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Success 200 {object} GetUserMutesResponse
+// @Failure 400 {object} map[string]string "User ID is required"
+// @Failure 500 {object} map[string]string "Failed to get user mutes"
+// @Router /v1/mutes [get]
+func (h *MuteHandler) GetUserMutes(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	mutes, err := h.muteService.GetUserMutes(c.Request.Context(), userID)
+	if err != nil {
+		zap.L().Error("Failed to get user mutes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user mutes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id": userID,
+		"mutes":   mutes,
+		"count":   len(mutes),
+	})
+}
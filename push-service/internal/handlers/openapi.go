@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"push-service/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// OpenAPI serves the API's OpenAPI 3.0 document, for client generators
+func OpenAPI(c *gin.Context) {
+	doc, err := openapi.Document()
+	if err != nil {
+		zap.L().Error("Failed to build OpenAPI document", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build OpenAPI document"})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", doc)
+}
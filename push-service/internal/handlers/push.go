@@ -2,14 +2,47 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"push-service/internal/models"
 	"push-service/internal/service"
+	"push-service/pkg/tracing"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// SendPushResponse is the response to a send. The notification is only
+// enqueued; its delivery attempts are under notification_id.
+// @Description Send accepted: enqueued with its notification_id, enqueued as a dry run with its dry_run_id, or dropped with a drop_reason
+type SendPushResponse struct {
+	Message        string `json:"message" example:"Push notification sent successfully"`
+	UserID         string `json:"user_id,omitempty" example:"user123"`
+	NotificationID string `json:"notification_id,omitempty" example:"0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"`
+	DryRunID       string `json:"dry_run_id,omitempty" example:"5d0f3f4e-2c71-4c8b-9a51-6b1f0c7e2a90"`
+	DropReason     string `json:"drop_reason,omitempty" example:"muted_sender"`
+}
+
+// SendBulkPushResponse is the response to a bulk send
+// @Description Bulk send accepted: every user's notification enqueued, or a dry run with its dry_run_id
+type SendBulkPushResponse struct {
+	Message   string `json:"message" example:"Bulk push notifications sent successfully"`
+	UserCount int    `json:"user_count" example:"2"`
+	DryRunID  string `json:"dry_run_id,omitempty" example:"5d0f3f4e-2c71-4c8b-9a51-6b1f0c7e2a90"`
+}
+
+// SendBundleResponse is the response to a bundle send
+// @Description Bundle accepted: enqueued with its bundle_id, or dropped with a drop_reason
+type SendBundleResponse struct {
+	Message    string `json:"message" example:"Push bundle sent successfully"`
+	UserID     string `json:"user_id" example:"user123"`
+	BundleID   string `json:"bundle_id,omitempty" example:"3c2b7d1e-9f4a-4e55-8b0c-1d2e3f4a5b6c"`
+	ItemCount  int    `json:"item_count,omitempty" example:"3"`
+	DropReason string `json:"drop_reason,omitempty" example:"muted_category"`
+}
+
 type PushHandler struct {
 	pushService service.PushService
 }
@@ -20,24 +53,63 @@ func NewPushHandler(pushService service.PushService) *PushHandler {
 
 // SendPush godoc
 // @Summary Send push notification
-// @Description Send a push notification to a user's devices via RabbitMQ queue
+// @Description Send a push notification to a user's devices via RabbitMQ queue. With dry_run, the notification goes through the queue and the worker but is only validated by FCM; the per-token results are available from /v1/push/dry-runs/{id} under the returned dry_run_id. max_retries and retry_backoff override the retry policy for this notification, e.g. to retry an OTP aggressively or send a marketing push to the dead letter queue on its first failure; max_retries is capped by the server. priority (critical, high, normal or low) selects the queue the notification waits in; higher priorities are processed first. Notifications with the same collapse_key replace each other on the device instead of stacking. A notification with a ttl (e.g. 10m) is dropped by the worker and by FCM once the ttl has passed, instead of being delivered late. Up to three actions add buttons to the notification, and click_action sets the Android intent action opened on tap. ios sets the APNs badge, sound, category and thread_id. platform_overrides holds FCM v1 android, apns and webpush objects that are merged verbatim over the configs built from the other fields. analytics_label segments the deliveries in Firebase reporting. With deliver_at_local_time (HH:MM), the notification is held back and delivered at that time in each device's timezone, or the server's default timezone for devices that haven't reported one. When digests are enabled, low priority notifications are buffered and delivered in the user's next digest, and the returned notification_id is the buffered item's.
 // @Tags push
 // @Accept json
 // @Produce json
+// @Param X-Tenant-ID header string false "Tenant ID; notifications of tenants in soft launch go to their test users' devices"
 // @Param request body models.SendPushRequest true "Push notification request"
-// @Success 200 {object} map[string]string "Push notification enqueued successfully"
-// @Failure 400 {object} map[string]string "Invalid request body"
+// @Success 200 {object} SendPushResponse "Push notification enqueued successfully with its notification_id (a dry_run_id for dry runs), or dropped with a drop_reason because the user muted its sender or category"
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body, retry policy, ttl or platform overrides"
+// @Failure 403 {object} map[string]string "Tenant is in soft launch and has no test devices"
+// @Failure 413 {object} map[string]string "Notification and data larger than FCM accepts"
+// @Failure 429 {object} map[string]string "Soft launch daily send cap reached"
 // @Failure 500 {object} map[string]string "Failed to send push notification"
+// @Failure 403 {object} map[string]string "Not an internal client"
 // @Router /v1/push/send [post]
 func (h *PushHandler) SendPush(c *gin.Context) {
 	var req models.SendPushRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		zap.L().Warn("Invalid push request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		invalidBody(c, err)
 		return
 	}
+	req.TenantID = c.GetHeader(tracing.TenantHeader)
 
-	if err := h.pushService.SendPush(c.Request.Context(), req); err != nil {
+	id, err := h.pushService.SendPush(c.Request.Context(), req)
+	if err != nil {
+		var dropped *service.DroppedError
+		if errors.As(err, &dropped) {
+			c.JSON(http.StatusOK, SendPushResponse{
+				Message:    "Push notification dropped",
+				UserID:     req.UserID,
+				DropReason: dropped.Reason,
+			})
+			return
+		}
+		if softLaunchRejected(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidRetryPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retry policy", "details": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTTL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ttl", "details": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPlatformOverrides) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid platform overrides", "details": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidLocalTime) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deliver_at_local_time", "details": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrPayloadTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Payload too large", "details": err.Error()})
+			return
+		}
 		zap.L().Error("Failed to send push", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to send push notification",
@@ -46,43 +118,311 @@ func (h *PushHandler) SendPush(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Push notification sent successfully",
-		"user_id": req.UserID,
+	if req.DryRun {
+		c.JSON(http.StatusOK, SendPushResponse{
+			Message:  "Push notification dry run enqueued",
+			UserID:   req.UserID,
+			DryRunID: id,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SendPushResponse{
+		Message:        "Push notification sent successfully",
+		UserID:         req.UserID,
+		NotificationID: id,
 	})
 }
 
 // SendBulkPush godoc
 // @Summary Send bulk push notifications
-// @Description Send push notifications to multiple users via RabbitMQ queue
+// @Description Send push notifications to multiple users via RabbitMQ queue. Users listed under users may carry their own vars, which replace the {{name}} placeholders in the title and body when each user's notification is processed. With dry_run, the notifications are only validated by FCM; see /v1/push/dry-runs/{id}. Bulk sends of tenants in soft launch are always dry runs. priority selects the queue the notifications wait in, and analytics_label segments their deliveries in Firebase reporting.
 // @Tags push
 // @Accept json
 // @Produce json
+// @Param X-Tenant-ID header string false "Tenant ID"
 // @Param request body models.BulkPushRequest true "Bulk push notification request"
-// @Success 200 {object} map[string]interface{} "Bulk push notifications enqueued successfully"
-// @Failure 400 {object} map[string]string "Invalid request body"
+// @Success 200 {object} SendBulkPushResponse "Bulk push notifications enqueued successfully (with a dry_run_id for dry runs)"
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 413 {object} map[string]string "Notification and data larger than FCM accepts"
 // @Failure 500 {object} map[string]string "Failed to send bulk push notifications"
+// @Failure 403 {object} map[string]string "Not an internal client"
 // @Router /v1/push/send-bulk [post]
 func (h *PushHandler) SendBulkPush(c *gin.Context) {
 	var req models.BulkPushRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		zap.L().Warn("Invalid bulk push request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		invalidBody(c, err)
 		return
 	}
+	req.TenantID = c.GetHeader(tracing.TenantHeader)
 
-	if err := h.pushService.SendBulkPush(c.Request.Context(), req); err != nil {
+	dryRunID, err := h.pushService.SendBulkPush(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrPayloadTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Payload too large", "details": err.Error()})
+			return
+		}
 		zap.L().Error("Failed to send bulk push", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send bulk push notifications"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":    "Bulk push notifications sent successfully",
-		"user_count": len(req.UserIDs),
+	if dryRunID != "" {
+		c.JSON(http.StatusOK, SendBulkPushResponse{
+			Message:   "Bulk push dry run enqueued",
+			UserCount: len(req.UserIDs) + len(req.Users),
+			DryRunID:  dryRunID,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SendBulkPushResponse{
+		Message:   "Bulk push notifications sent successfully",
+		UserCount: len(req.UserIDs) + len(req.Users),
 	})
 }
 
+// SendBundle godoc
+// @Summary Send a notification bundle
+// @Description Send related notifications (e.g. a badge update, a data sync and an alert) to a user's devices as one unit. The bundle is enqueued all-or-nothing and its items share a bundle_id. Items without a title and body are silent data messages and are delivered to each device before the visible ones; a device whose data message fails does not get the alert until the bundle is retried. priority selects the queue the bundle waits in.
+// @Tags push
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant ID; bundles of tenants in soft launch go to their test users' devices"
+// @Param request body models.SendBundleRequest true "Push bundle request"
+// @Success 200 {object} SendBundleResponse "Push bundle enqueued successfully, or dropped with a drop_reason because the user muted its sender or category"
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 403 {object} map[string]string "Tenant is in soft launch and has no test devices"
+// @Failure 429 {object} map[string]string "Soft launch daily send cap reached"
+// @Failure 500 {object} map[string]string "Failed to send push bundle"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/push/send-bundle [post]
+func (h *PushHandler) SendBundle(c *gin.Context) {
+	var req models.SendBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid push bundle request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+	req.TenantID = c.GetHeader(tracing.TenantHeader)
+
+	bundleID, err := h.pushService.SendBundle(c.Request.Context(), req)
+	if err != nil {
+		var dropped *service.DroppedError
+		if errors.As(err, &dropped) {
+			c.JSON(http.StatusOK, SendBundleResponse{
+				Message:    "Push bundle dropped",
+				UserID:     req.UserID,
+				DropReason: dropped.Reason,
+			})
+			return
+		}
+		if softLaunchRejected(c, err) {
+			return
+		}
+		zap.L().Error("Failed to send push bundle", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to send push bundle",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SendBundleResponse{
+		Message:   "Push bundle sent successfully",
+		UserID:    req.UserID,
+		BundleID:  bundleID,
+		ItemCount: len(req.Items),
+	})
+}
+
+// SendRaw godoc
+// @Summary Send a raw FCM message
+// @Description Send a complete FCM v1 message (the "message" object of FCM's messages:send, without credentials) to the token, topic or condition it names. The message is sent as is, but still goes through the queue, retries and delivery attempts; look it up under the returned notification_id. Meant for teams migrating off direct FCM usage. Mutes don't apply, and tenants in soft launch can't send raw messages.
+// @Tags push
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant ID"
+// @Param request body models.RawPushRequest true "Raw FCM message"
+// @Success 200 {object} SendPushResponse "Raw message enqueued successfully with its notification_id"
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body or FCM message"
+// @Failure 403 {object} map[string]string "Tenant is in soft launch"
+// @Failure 500 {object} map[string]string "Failed to send raw message"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/push/raw [post]
+func (h *PushHandler) SendRaw(c *gin.Context) {
+	var req models.RawPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid raw push request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+	req.TenantID = c.GetHeader(tracing.TenantHeader)
+
+	id, err := h.pushService.SendRaw(c.Request.Context(), req)
+	if err != nil {
+		if softLaunchRejected(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidRawMessage) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid FCM message", "details": err.Error()})
+			return
+		}
+		zap.L().Error("Failed to send raw message", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to send raw message",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SendPushResponse{
+		Message:        "Raw message sent successfully",
+		NotificationID: id,
+	})
+}
+
+// softLaunchRejected responds to a send a tenant's soft launch doesn't allow,
+// and reports whether err was one
+func softLaunchRejected(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrSoftLaunchCap):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Soft launch daily send cap reached", "details": err.Error()})
+	case errors.Is(err, service.ErrSoftLaunchNoTestUsers), errors.Is(err, service.ErrSoftLaunchCampaign), errors.Is(err, service.ErrSoftLaunchRaw):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while the tenant is in soft launch", "details": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
+// GetDryRun godoc
+// @Summary Get dry run results
+// @Description Get the FCM validation result for every device token of a dry run. The status is pending until the worker has processed all of the dry run's messages.
+// @Tags push
+// @Accept json
+// @Produce json
+// @Param id path string true "Dry run ID"
+// @Success 200 {object} models.DryRun
+// @Failure 404 {object} map[string]string "Dry run not found"
+// @Failure 500 {object} map[string]string "Failed to get dry run"
+// @Router /v1/push/dry-runs/{id} [get]
+func (h *PushHandler) GetDryRun(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dry run not found"})
+		return
+	}
+
+	dryRun, err := h.pushService.GetDryRun(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrDryRunNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dry run not found"})
+			return
+		}
+		zap.L().Error("Failed to get dry run", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get dry run"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dryRun)
+}
+
+// GetNotification godoc
+// @Summary Get notification delivery attempts
+// @Description Get every delivery attempt of a notification, oldest first: when it was made, the queue the message came from (e.g. a retry tier), its retry number, each token's result and where the message went next. The status is derived from the last attempt.
+// @Tags push
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} models.NotificationDetail
+// @Failure 404 {object} map[string]string "Notification not found"
+// @Failure 500 {object} map[string]string "Failed to get notification"
+// @Router /v1/notifications/{id} [get]
+func (h *PushHandler) GetNotification(c *gin.Context) {
+	detail, err := h.pushService.GetNotification(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		zap.L().Error("Failed to get notification", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// GetNotificationTokens godoc
+// @Summary Get notification results per device token
+// @Description Get the latest delivery result of a notification for each of its device tokens: whether it was delivered, FCM's message ID, or the error kind, FCM error code and error of its last failure, and how many attempts were made. Pass a device's token to see only its result. Tokens are masked in the response.
+// @Tags push
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Param token query string false "Only this device token's result"
+// @Success 200 {object} models.NotificationTokenResults
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Failure 404 {object} map[string]string "Notification not found"
+// @Failure 500 {object} map[string]string "Failed to get notification token results"
+// @Router /v1/notifications/{id}/tokens [get]
+func (h *PushHandler) GetNotificationTokens(c *gin.Context) {
+	results, err := h.pushService.GetNotificationTokens(c.Request.Context(), c.Param("id"), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		zap.L().Error("Failed to get notification token results", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification token results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// ListDeliveryEvents godoc
+// @Summary List recent delivery attempts
+// @Description Get the delivery attempts of every notification recorded after the attempt with ID after, oldest first, each with the status it left its notification in. Without after, get the last ones. Pass the response's after to get the attempts recorded since, e.g. to follow deliveries as they happen. Tokens are masked.
+// @Tags admin
+// @Produce json
+// @Param after query int false "Only attempts recorded after the attempt with this ID"
+// @Param limit query int false "Maximum attempts to return (default 50, max 500)"
+// @Success 200 {object} models.DeliveryEvents
+// @Failure 400 {object} map[string]string "Invalid after or limit"
+// @Failure 500 {object} map[string]string "Failed to list delivery attempts"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/delivery-events [get]
+func (h *PushHandler) ListDeliveryEvents(c *gin.Context) {
+	var after int64
+	if value := c.Query("after"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid after", "details": "after must be an attempt ID"})
+			return
+		}
+		after = parsed
+	}
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit", "details": "limit must be between 1 and 500"})
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := h.pushService.ListDeliveryEvents(c.Request.Context(), after, limit)
+	if err != nil {
+		zap.L().Error("Failed to list delivery attempts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list delivery attempts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
 // GetQueueStats godoc
 // @Summary Get queue statistics
 // @Description Get statistics for all push notification queues (main, retry, dead letter)
@@ -90,6 +430,7 @@ func (h *PushHandler) SendBulkPush(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{} "Queue statistics"
+// @Failure 403 {object} map[string]string "Not an internal client"
 // @Failure 500 {object} map[string]string "Failed to get queue statistics"
 // @Router /v1/queue/stats [get]
 func (h *PushHandler) GetQueueStats(c *gin.Context) {
@@ -116,8 +457,9 @@ func (h *PushHandler) GetQueueStats(c *gin.Context) {
 // @Produce json
 // @Param request body object true "Direct send request" example({"token":"fcm_token","title":"Test","body":"Test message"})
 // @Success 200 {object} map[string]string "FCM test message sent successfully"
-// @Failure 400 {object} map[string]string "Invalid request body"
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
 // @Failure 500 {object} map[string]string "FCM send failed"
+// @Failure 403 {object} map[string]string "Not an internal client"
 // @Router /v1/push/test-direct [post]
 func (h *PushHandler) TestDirectSend(c *gin.Context) {
 	var req struct {
@@ -129,11 +471,11 @@ func (h *PushHandler) TestDirectSend(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		zap.L().Warn("Invalid direct send request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		invalidBody(c, err)
 		return
 	}
 
-	zap.L().Info("🔧 Testing FCM direct send",
+	zap.L().Info("Testing FCM direct send",
 		zap.String("token", req.Token),
 		zap.String("title", req.Title),
 	)
@@ -150,7 +492,7 @@ func (h *PushHandler) TestDirectSend(c *gin.Context) {
 	}).SendDirect(c.Request.Context(), req.Token, notification)
 
 	if err != nil {
-		zap.L().Error("💥 FCM direct send failed",
+		zap.L().Error("FCM direct send failed",
 			zap.String("token", req.Token),
 			zap.Error(err),
 		)
@@ -161,7 +503,7 @@ func (h *PushHandler) TestDirectSend(c *gin.Context) {
 		return
 	}
 
-	zap.L().Info("✅ FCM direct send successful")
+	zap.L().Info("FCM direct send successful")
 	c.JSON(http.StatusOK, gin.H{
 		"message": "FCM test message sent successfully",
 	})
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/pkg/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// realtimeWriteTimeout bounds each write to a realtime connection
+const realtimeWriteTimeout = 10 * time.Second
+
+type RealtimeHandler struct {
+	hub            *realtime.Hub // nil if realtime delivery is disabled
+	upgrader       websocket.Upgrader
+	pingInterval   time.Duration
+	allowedOrigins []string
+}
+
+func NewRealtimeHandler(hub *realtime.Hub, cfg *config.RealtimeConfig) *RealtimeHandler {
+	pingInterval := cfg.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = 30 * time.Second // default
+	}
+
+	h := &RealtimeHandler{
+		hub:            hub,
+		pingInterval:   pingInterval,
+		allowedOrigins: cfg.AllowedOrigins,
+	}
+	h.upgrader = websocket.Upgrader{CheckOrigin: h.checkOrigin}
+	return h
+}
+
+// checkOrigin reports whether a browser on the request's origin may connect
+func (h *RealtimeHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return len(h.allowedOrigins) == 0 || origin == "" || slices.Contains(h.allowedOrigins, origin)
+}
+
+// Connect godoc
+// @Summary Receive notifications over a WebSocket
+// @Description Upgrade to a WebSocket that receives the user's notifications as they are processed, as JSON events of type notification whose data is the notification. Sends with suppress_push_if_connected skip the push while the user has a connection on any replica. The server pings the connection; messages from the client are ignored.
+// @Tags realtime
+// @Param user_id query string true "User ID"
+// @Success 101 {object} realtime.Event "Switching protocols; events follow on the socket"
+// @Failure 400 {object} map[string]string "User ID is required"
+// @Failure 503 {object} map[string]string "Realtime delivery is disabled"
+// @Router /v1/ws [get]
+func (h *RealtimeHandler) Connect(c *gin.Context) {
+	if h.hub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Realtime delivery is disabled"})
+		return
+	}
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		zap.L().Warn("Failed to upgrade realtime connection", zap.String("user_id", userID), zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	session, err := h.hub.Join(c.Request.Context(), userID)
+	if err != nil {
+		zap.L().Error("Failed to open realtime session", zap.String("user_id", userID), zap.Error(err))
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "session unavailable"), time.Now().Add(realtimeWriteTimeout))
+		return
+	}
+	defer h.hub.Leave(context.Background(), session)
+
+	closed := h.readUntilClosed(conn)
+
+	ping := time.NewTicker(h.pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-session.Done():
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(realtimeWriteTimeout))
+			return
+		case event := <-session.Events():
+			conn.SetWriteDeadline(time.Now().Add(realtimeWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				zap.L().Debug("Realtime connection write failed", zap.String("user_id", userID), zap.Error(err))
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(realtimeWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Stream godoc
+// @Summary Receive notifications as Server-Sent Events
+// @Description Stream the user's notifications as they are processed, as Server-Sent Events of type notification whose data is the same JSON event the WebSocket gets. For web dashboards that can't keep an FCM service worker. A stream counts as a connection for suppress_push_if_connected. Comments are sent as keepalives.
+// @Tags realtime
+// @Produce text/event-stream
+// @Param user_id query string true "User ID"
+// @Success 200 {object} realtime.Event "Event stream"
+// @Failure 400 {object} map[string]string "User ID is required"
+// @Failure 403 {object} map[string]string "Origin not allowed"
+// @Failure 503 {object} map[string]string "Realtime delivery is disabled"
+// @Router /v1/stream [get]
+func (h *RealtimeHandler) Stream(c *gin.Context) {
+	if h.hub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Realtime delivery is disabled"})
+		return
+	}
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+	if !h.checkOrigin(c.Request) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Origin not allowed"})
+		return
+	}
+
+	session, err := h.hub.Join(c.Request.Context(), userID)
+	if err != nil {
+		zap.L().Error("Failed to open realtime session", zap.String("user_id", userID), zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Realtime session unavailable"})
+		return
+	}
+	defer h.hub.Leave(context.Background(), session)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // keep proxies like nginx from buffering the stream
+	if origin := c.GetHeader("Origin"); origin != "" {
+		// EventSource is subject to CORS, unlike WebSockets
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Vary", "Origin")
+	}
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepalive := time.NewTicker(h.pingInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-session.Done():
+			return
+		case event := <-session.Events():
+			data, err := json.Marshal(event)
+			if err != nil {
+				zap.L().Error("Failed to encode realtime event", zap.String("event_id", event.ID), zap.Error(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// readUntilClosed discards the client's messages and answers its pings in
+// the background. The returned channel is closed when the connection is
+// closed or misses two pings in a row.
+func (h *RealtimeHandler) readUntilClosed(conn *websocket.Conn) <-chan struct{} {
+	closed := make(chan struct{})
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	})
+
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	return closed
+}
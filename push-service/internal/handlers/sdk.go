@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PlatformHeader lets the client SDK state its platform explicitly
+const PlatformHeader = "X-Platform"
+
+// SDKHandler serves the token lifecycle endpoints used by the client SDK
+type SDKHandler struct {
+	deviceService service.DeviceService
+}
+
+func NewSDKHandler(deviceService service.DeviceService) *SDKHandler {
+	return &SDKHandler{deviceService: deviceService}
+}
+
+// RegisterToken godoc
+// @Summary Register a token from the client SDK
+// @Description Register a device token. If platform is omitted it is detected from the X-Platform header or the User-Agent. An initial permission status may be reported along with the token.
+// @Tags sdk
+// @Accept json
+// @Produce json
+// @Param X-Platform header string false "Device platform (ios, android or web)"
+// @Param request body models.SDKRegisterRequest true "SDK token registration request"
+// @Success 201 {object} RegisterDeviceResponse
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body or platform could not be detected"
+// @Failure 500 {object} map[string]string "Failed to register device"
+// @Router /v1/sdk/tokens [post]
+func (h *SDKHandler) RegisterToken(c *gin.Context) {
+	var req models.SDKRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid SDK token registration", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	platform := req.Platform
+	if platform == "" {
+		platform = detectPlatform(c.GetHeader(PlatformHeader), c.GetHeader("User-Agent"))
+	}
+	if platform == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not detect platform, set platform or the X-Platform header"})
+		return
+	}
+
+	device, err := h.deviceService.RegisterDevice(c.Request.Context(), models.CreateDeviceRequest{
+		UserID:           req.UserID,
+		Token:            req.Token,
+		Platform:         platform,
+		PermissionStatus: req.PermissionStatus,
+		Timezone:         req.Timezone,
+		DeviceID:         req.DeviceID,
+		DeviceMetadata:   req.DeviceMetadata,
+	})
+	if err != nil {
+		zap.L().Error("Failed to register device", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Device registered successfully",
+		"device":  device,
+	})
+}
+
+// RefreshToken godoc
+// @Summary Refresh a rotated token
+// @Description Replace a device's token after FCM rotated it. The device keeps its ID, user and permission status.
+// @Tags sdk
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshTokenRequest true "Token refresh request"
+// @Success 200 {object} RegisterDeviceResponse
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 404 {object} map[string]string "Device not found"
+// @Failure 500 {object} map[string]string "Failed to refresh token"
+// @Router /v1/sdk/tokens/refresh [post]
+func (h *SDKHandler) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid token refresh request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	device, err := h.deviceService.RefreshToken(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		zap.L().Error("Failed to refresh token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Token refreshed successfully",
+		"device":  device,
+	})
+}
+
+// UpdatePermission godoc
+// @Summary Report notification permission
+// @Description Store the device's OS notification permission status (granted, denied or provisional)
+// @Tags sdk
+// @Accept json
+// @Produce json
+// @Param token path string true "Device token"
+// @Param request body models.UpdatePermissionRequest true "Permission status"
+// @Success 200 {object} map[string]string "Permission status updated successfully"
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 404 {object} map[string]string "Device not found"
+// @Failure 500 {object} map[string]string "Failed to update permission status"
+// @Router /v1/sdk/tokens/{token}/permission [put]
+func (h *SDKHandler) UpdatePermission(c *gin.Context) {
+	var req models.UpdatePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid permission status request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	if err := h.deviceService.UpdatePermission(c.Request.Context(), c.Param("token"), req.Status); err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		zap.L().Error("Failed to update permission status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update permission status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Permission status updated successfully"})
+}
+
+// GetDeviceConfig godoc
+// @Summary Get device config
+// @Description Get the configuration the client SDK applies on this device
+// @Tags sdk
+// @Accept json
+// @Produce json
+// @Param token path string true "Device token"
+// @Success 200 {object} models.DeviceConfig
+// @Failure 404 {object} map[string]string "Device not found"
+// @Failure 500 {object} map[string]string "Failed to get device config"
+// @Router /v1/sdk/tokens/{token}/config [get]
+func (h *SDKHandler) GetDeviceConfig(c *gin.Context) {
+	deviceConfig, err := h.deviceService.GetDeviceConfig(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		zap.L().Error("Failed to get device config", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get device config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deviceConfig)
+}
+
+// detectPlatform infers the device platform from the X-Platform header or,
+// failing that, the User-Agent. It returns "" if neither gives it away.
+func detectPlatform(platformHeader, userAgent string) string {
+	switch platform := strings.ToLower(strings.TrimSpace(platformHeader)); platform {
+	case "ios", "android", "web":
+		return platform
+	}
+
+	// Browsers (mobile ones included) identify as Mozilla; native HTTP
+	// stacks don't, e.g. "Dalvik/2.1.0 (Linux; Android 14)", "okhttp/4.12.0"
+	// or "MyApp/1.0 CFNetwork/1490 Darwin/23.2.0".
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.HasPrefix(ua, "mozilla/"):
+		return "web"
+	case strings.Contains(ua, "android"), strings.Contains(ua, "dalvik"), strings.Contains(ua, "okhttp"):
+		return "android"
+	case strings.Contains(ua, "cfnetwork"), strings.Contains(ua, "darwin"), strings.Contains(ua, "iphone"),
+		strings.Contains(ua, "ipad"), strings.Contains(ua, "ios"):
+		return "ios"
+	}
+	return ""
+}
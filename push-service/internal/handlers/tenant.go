@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TenantHandler struct {
+	tenantService service.TenantService
+}
+
+func NewTenantHandler(tenantService service.TenantService) *TenantHandler {
+	return &TenantHandler{tenantService: tenantService}
+}
+
+// GetTenant godoc
+// @Summary Get tenant soft launch settings
+// @Description Get whether a tenant is in soft launch, its daily send cap, test users and sends so far today. Tenants that were never updated are reported with the defaults.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.Tenant
+// @Failure 500 {object} map[string]string "Failed to get tenant"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/tenants/{id} [get]
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	tenant, err := h.tenantService.GetTenant(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		zap.L().Error("Failed to get tenant", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tenant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenant godoc
+// @Summary Update tenant soft launch settings
+// @Description Set a tenant's soft launch daily send cap and test users, or lift soft launch with soft_launch=false. While in soft launch, a tenant's sends go to its test users' devices only, bulk sends are dry runs and campaigns are rejected.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param request body models.UpdateTenantRequest true "Tenant settings"
+// @Success 200 {object} models.Tenant
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 500 {object} map[string]string "Failed to update tenant"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/tenants/{id} [put]
+func (h *TenantHandler) UpdateTenant(c *gin.Context) {
+	var req models.UpdateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid tenant update request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+
+	tenant, err := h.tenantService.UpdateTenant(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		zap.L().Error("Failed to update tenant", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tenant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
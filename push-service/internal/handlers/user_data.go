@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"push-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type UserDataHandler struct {
+	userDataService service.UserDataService
+}
+
+func NewUserDataHandler(userDataService service.UserDataService) *UserDataHandler {
+	return &UserDataHandler{userDataService: userDataService}
+}
+
+// DeleteUserData godoc
+// @Summary Delete a user's data
+// @Description Hard-delete everything stored about a user, e.g. for a GDPR erasure request: their devices, notification history and delivery attempts, inbox and digest items, mutes, dry run results and scheduled pushes. The user is also removed from campaigns and tenants' test users. Messages already queued for the user are dropped instead of delivered. Deleting the data of a user with none succeeds with zero counts.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UserDataDeletion
+// @Failure 500 {object} map[string]string "Failed to delete user data"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/users/{id}/data [delete]
+func (h *UserDataHandler) DeleteUserData(c *gin.Context) {
+	deletion, err := h.userDataService.DeleteUserData(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		zap.L().Error("Failed to delete user data", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deletion)
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// analyticsLabelPattern is the format FCM accepts for analytics labels
+var analyticsLabelPattern = regexp.MustCompile(`^[a-zA-Z0-9-_.~%]{1,50}$`)
+
+// RegisterValidators adds the request validation tags gin doesn't have, and
+// names fields in validation errors by their JSON names
+func RegisterValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v.RegisterValidation("analytics_label", func(fl validator.FieldLevel) bool {
+		return analyticsLabelPattern.MatchString(fl.Field().String())
+	})
+}
+
+// FieldError is a request field that failed validation
+// @Description A request field that failed validation, with the value it had and the constraint it broke
+type FieldError struct {
+	// Field is the field's path in the request body, e.g. notification.title
+	// or device_tokens[2]
+	Field string `json:"field" example:"notification.title"`
+	// Value is the rejected value; it is left out for missing fields
+	Value any `json:"value,omitempty" swaggertype:"string" example:""`
+	// Constraint is the validation that failed, e.g. required, max, oneof,
+	// or type when the value has the wrong JSON type
+	Constraint string `json:"constraint" example:"required"`
+	// Param is the constraint's parameter, e.g. 100 for max=100
+	Param   string `json:"param,omitempty" example:""`
+	Message string `json:"message" example:"notification.title is required"`
+}
+
+// ValidationErrorResponse is the response to a request body that couldn't
+// be decoded or failed validation
+// @Description Invalid request body, with each field that failed validation
+type ValidationErrorResponse struct {
+	Error   string       `json:"error" example:"Invalid request body"`
+	Details string       `json:"details" example:"notification.title is required"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// invalidBody answers 400 to a request body that couldn't be bound, naming
+// each field that failed and why rather than the Go types behind them
+func invalidBody(c *gin.Context, err error) {
+	fields, details := describeBindError(err)
+	c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+		Error:   "Invalid request body",
+		Details: details,
+		Fields:  fields,
+	})
+}
+
+// describeBindError explains a binding error per field, where it can
+func describeBindError(err error) ([]FieldError, string) {
+	var validationErrs validator.ValidationErrors
+	var sliceErrs binding.SliceValidationError
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			fields[i] = newFieldError(fieldErr)
+		}
+		return fields, joinMessages(fields)
+	case errors.As(err, &sliceErrs):
+		var fields []FieldError
+		for _, itemErr := range sliceErrs {
+			itemFields, _ := describeBindError(itemErr)
+			fields = append(fields, itemFields...)
+		}
+		return fields, joinMessages(fields)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return nil, "request body must be of type " + jsonType(typeErr.Type)
+		}
+		field := FieldError{
+			Field:      typeErr.Field,
+			Constraint: "type",
+			Param:      jsonType(typeErr.Type),
+			Message:    fmt.Sprintf("%s must be of type %s", typeErr.Field, jsonType(typeErr.Type)),
+		}
+		return []FieldError{field}, field.Message
+	case errors.As(err, &syntaxErr):
+		return nil, fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.Is(err, io.EOF):
+		return nil, "request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return nil, "request body is truncated"
+	default:
+		return nil, err.Error()
+	}
+}
+
+func newFieldError(fieldErr validator.FieldError) FieldError {
+	// The namespace starts with the request struct's name
+	field := fieldErr.Namespace()
+	if _, rest, ok := strings.Cut(field, "."); ok {
+		field = rest
+	}
+
+	fe := FieldError{
+		Field:      field,
+		Constraint: fieldErr.Tag(),
+		Param:      fieldErr.Param(),
+	}
+	if value := fieldErr.Value(); value != nil && !reflect.ValueOf(value).IsZero() {
+		fe.Value = value
+	}
+	switch fe.Constraint {
+	case "required_with", "required_without", "required_if", "required_unless", "excluded_with", "excluded_without":
+		// Their parameter names a Go field
+		fe.Param = snakeCase(fe.Param)
+	}
+	fe.Message = field + " " + constraintMessage(fe.Constraint, fe.Param, fieldErr.Kind())
+	return fe
+}
+
+// constraintMessage describes a validation tag's constraint
+func constraintMessage(tag, param string, kind reflect.Kind) string {
+	switch tag {
+	case "required":
+		return "is required"
+	case "required_with":
+		return "is required when " + param + " is set"
+	case "required_without":
+		return "is required when " + param + " is not set"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "min", "gte":
+		return "must be at least " + param + sizeUnit(kind)
+	case "max", "lte":
+		return "must be at most " + param + sizeUnit(kind)
+	case "len":
+		return "must be exactly " + param + sizeUnit(kind)
+	case "gt":
+		return "must be more than " + param + sizeUnit(kind)
+	case "lt":
+		return "must be less than " + param + sizeUnit(kind)
+	case "url":
+		return "must be a URL"
+	case "uuid":
+		return "must be a UUID"
+	case "email":
+		return "must be an email address"
+	case "timezone":
+		return "must be an IANA time zone, e.g. Europe/Paris"
+	case "bcp47_language_tag":
+		return "must be a BCP 47 language tag, e.g. en-US"
+	case "analytics_label":
+		return "must be 1 to 50 letters, digits or - _ . ~ %"
+	default:
+		return "failed the " + tag + " constraint"
+	}
+}
+
+// sizeUnit is what min, max and the like count for a kind of value
+func sizeUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
+
+// jsonType names the JSON type a Go type is decoded from
+func jsonType(t reflect.Type) string {
+	if t == nil {
+		return "any"
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "any"
+	}
+}
+
+// snakeCase turns a Go field name into its JSON name, e.g. CampaignID into
+// campaign_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A word starts at an upper case letter after a lower case one,
+			// or before one at the end of an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func joinMessages(fields []FieldError) string {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"push-service/internal/models"
+	"push-service/internal/service"
+	"push-service/pkg/tracing"
+	"push-service/pkg/webhook"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListWebhooksResponse represents the list webhooks response
+// @Description List webhooks response
+type ListWebhooksResponse struct {
+	Webhooks []models.Webhook `json:"webhooks"`
+	Count    int              `json:"count" example:"1"`
+}
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// CreateWebhook godoc
+// @Summary Register a delivery status webhook
+// @Description Have the tenant's notification events (queued, sent, failed, dead_lettered) POSTed to a URL. Requests are signed with the returned secret, which is not shown again.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant ID"
+// @Param request body models.CreateWebhookRequest true "Webhook request"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} ValidationErrorResponse "Invalid request body"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Failure 500 {object} map[string]string "Failed to register webhook"
+// @Router /v1/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Warn("Invalid webhook request", zap.Error(err))
+		invalidBody(c, err)
+		return
+	}
+	req.TenantID = c.GetHeader(tracing.TenantHeader)
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), req)
+	if err != nil {
+		zap.L().Error("Failed to register webhook", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks godoc
+// @Summary List delivery status webhooks
+// @Description Get the tenant's webhooks, without their secrets
+// @Tags webhooks
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant ID"
+// @Success 200 {object} ListWebhooksResponse
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Failure 500 {object} map[string]string "Failed to list webhooks"
+// @Router /v1/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context(), c.GetHeader(tracing.TenantHeader))
+	if err != nil {
+		zap.L().Error("Failed to list webhooks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListWebhooksResponse{Webhooks: webhooks, Count: len(webhooks)})
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a delivery status webhook
+// @Description Stop sending the tenant's notification events to a webhook
+// @Tags webhooks
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant ID"
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} map[string]string "Webhook deleted successfully"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Failure 500 {object} map[string]string "Failed to delete webhook"
+// @Router /v1/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), c.GetHeader(tracing.TenantHeader), c.Param("id")); err != nil {
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+		zap.L().Error("Failed to delete webhook", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// ListWebhookDeliveriesResponse represents the webhook deliveries response
+// @Description Webhook deliveries response
+type ListWebhookDeliveriesResponse struct {
+	Deliveries   []webhook.Delivery `json:"deliveries"`
+	Count        int                `json:"count" example:"1"`
+	OpenCircuits []webhook.Circuit  `json:"open_circuits"`
+}
+
+// ListDeliveries godoc
+// @Summary List recent webhook deliveries
+// @Description Get this instance's most recent webhook delivery attempts, newest first, and the webhooks whose circuit is open
+// @Tags admin
+// @Produce json
+// @Param webhook_id query string false "Only deliveries to this webhook"
+// @Param limit query int false "Maximum deliveries to return (default 50, max 500)"
+// @Success 200 {object} ListWebhookDeliveriesResponse
+// @Failure 400 {object} map[string]string "Invalid limit"
+// @Failure 403 {object} map[string]string "Not an internal client"
+// @Router /v1/admin/webhook-deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit", "details": "limit must be between 1 and 500"})
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, circuits := h.webhookService.ListDeliveries(c.Query("webhook_id"), limit)
+	c.JSON(http.StatusOK, ListWebhookDeliveriesResponse{
+		Deliveries:   deliveries,
+		Count:        len(deliveries),
+		OpenCircuits: circuits,
+	})
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// Alert rule metrics
+const (
+	// AlertMetricFailureRate is failed / attempted deliveries, between 0 and 1
+	AlertMetricFailureRate = "failure_rate"
+	// AlertMetricFailures is the number of failed deliveries
+	AlertMetricFailures = "failures"
+	// AlertMetricDeadLetters is how much the dead letter queue grew
+	AlertMetricDeadLetters = "dead_letters"
+	// AlertMetricDeadLetterDepth is how many messages the dead letter
+	// queue holds
+	AlertMetricDeadLetterDepth = "dead_letter_depth"
+	// AlertMetricRetryRate is deliveries put back for retry / attempted
+	// deliveries, between 0 and 1
+	AlertMetricRetryRate = "retry_rate"
+	// AlertMetricFCMErrorRate is deliveries failed on FCM's side, not
+	// because of their token or message, / attempted deliveries
+	AlertMetricFCMErrorRate = "fcm_error_rate"
+)
+
+const (
+	AlertRuleSourceBuiltin = "builtin"
+	AlertRuleSourceConfig  = "config"
+	AlertRuleSourceAPI     = "api"
+
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// AlertRule fires when Metric over the last Window, counting only
+// deliveries whose labels match Filter, compares to Threshold with Operator
+// (e.g. failure_rate > 0.2 for platform=ios over 10m).
+type AlertRule struct {
+	Name      string            `json:"name" db:"name" example:"ios_failure_rate"`
+	Metric    string            `json:"metric" db:"metric" example:"failure_rate"`
+	Filter    map[string]string `json:"filter,omitempty" db:"filter"`
+	Operator  string            `json:"operator" db:"operator" example:">"`
+	Threshold float64           `json:"threshold" db:"threshold" example:"0.2"`
+	Window    string            `json:"window" db:"window_duration" example:"10m"`
+	// MinEvents keeps rate rules (failure_rate, retry_rate and
+	// fcm_error_rate) quiet until the window has this many deliveries
+	MinEvents  int64   `json:"min_events,omitempty" db:"min_events" example:"100"`
+	WebhookURL *string `json:"webhook_url,omitempty" db:"webhook_url"`
+
+	Source    string      `json:"source" example:"api"`
+	State     *AlertState `json:"state,omitempty"`
+	CreatedAt *time.Time  `json:"created_at,omitempty" db:"created_at"`
+}
+
+// AlertState is the outcome of a rule's last evaluation
+type AlertState struct {
+	Firing      bool       `json:"firing" db:"firing"`
+	Value       float64    `json:"value" db:"value" example:"0.27"`
+	Since       *time.Time `json:"since,omitempty" db:"since"` // when the rule started firing
+	EvaluatedAt time.Time  `json:"evaluated_at" db:"evaluated_at"`
+}
+
+type CreateAlertRuleRequest struct {
+	Name       string            `json:"name" binding:"required,max=255" example:"ios_failure_rate"`
+	Metric     string            `json:"metric" binding:"required,oneof=failure_rate failures dead_letters dead_letter_depth retry_rate fcm_error_rate" example:"failure_rate"`
+	Filter     map[string]string `json:"filter,omitempty"` // platform, project and/or category
+	Operator   string            `json:"operator" binding:"required,oneof=> >= < <=" example:">"`
+	Threshold  float64           `json:"threshold" example:"0.2"`
+	Window     string            `json:"window" binding:"required" example:"10m"`
+	MinEvents  int64             `json:"min_events,omitempty" binding:"min=0" example:"100"`
+	WebhookURL *string           `json:"webhook_url,omitempty" binding:"omitempty,url"` // Defaults to the configured webhook
+}
+
+// AlertNotification is POSTed to the webhook when a rule starts firing and
+// when it resolves. Slack and email get it as text.
+type AlertNotification struct {
+	Rule      string            `json:"rule"`
+	Status    string            `json:"status" example:"firing"`
+	Metric    string            `json:"metric"`
+	Filter    map[string]string `json:"filter,omitempty"`
+	Operator  string            `json:"operator"`
+	Threshold float64           `json:"threshold"`
+	Window    string            `json:"window"`
+	Value     float64           `json:"value"`
+	Since     *time.Time        `json:"since,omitempty"`
+	At        time.Time         `json:"at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+// PlatformUnknown is the analytics platform of tokens without a registered
+// device, e.g. from the user resolver or a gateway push_token
+const PlatformUnknown = "unknown"
+
+// DeliveryCounts are the delivery outcomes of a day and platform. Sent counts
+// every token attempted, including retries; delivered and failed are FCM's
+// verdicts on them; opened is reported by the client SDK.
+type DeliveryCounts struct {
+	Sent      int64 `json:"sent"`
+	Delivered int64 `json:"delivered"`
+	Failed    int64 `json:"failed"`
+	Opened    int64 `json:"opened"`
+}
+
+// DeliveryRollup is one day's delivery counts on one platform
+type DeliveryRollup struct {
+	Day      string `json:"day" db:"day" example:"2026-10-15"`
+	Platform string `json:"platform" db:"platform" example:"android"`
+	DeliveryCounts
+}
+
+// AnalyticsSummary sums the delivery rollups of the days from From to To,
+// inclusive, in total and per platform
+type AnalyticsSummary struct {
+	From       string                    `json:"from" example:"2026-10-09"`
+	To         string                    `json:"to" example:"2026-10-15"`
+	Totals     DeliveryCounts            `json:"totals"`
+	ByPlatform map[string]DeliveryCounts `json:"by_platform"`
+	Days       []DeliveryRollup          `json:"days"`
+}
+
+type RecordOpenRequest struct {
+	NotificationID string `json:"notification_id" binding:"required"`
+	Platform       string `json:"platform,omitempty" binding:"omitempty,oneof=ios android web" example:"android"`
+
+	// The campaign_id and variant from the notification's data, if it was a
+	// variant of an A/B tested campaign
+	CampaignID string `json:"campaign_id,omitempty" binding:"required_with=Variant,omitempty,uuid"`
+	Variant    string `json:"variant,omitempty" binding:"required_with=CampaignID" example:"short_copy"`
+}
+
+// VariantRollup is the delivery counts of one variant of a campaign.
+// OpenRate is opened over delivered.
+type VariantRollup struct {
+	Variant string `json:"variant" example:"short_copy"`
+	DeliveryCounts
+	OpenRate float64 `json:"open_rate" example:"0.042"`
+}
+
+// CampaignVariantReport compares the variants of an A/B tested campaign
+type CampaignVariantReport struct {
+	CampaignID string          `json:"campaign_id"`
+	Variants   []VariantRollup `json:"variants"`
+}
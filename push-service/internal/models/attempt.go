@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Notification statuses derived from its delivery attempts
+const (
+	NotificationStatusQueued       = "queued"
+	NotificationStatusRetrying     = "retrying"
+	NotificationStatusDelivered    = "delivered"
+	NotificationStatusPartial      = "partially_delivered"
+	NotificationStatusFailed       = "failed"
+	NotificationStatusDeadLettered = "dead_lettered"
+)
+
+// DeliveryAttempt is one send of a notification to its device tokens
+type DeliveryAttempt struct {
+	NotificationID string `json:"-" db:"notification_id"`
+	UserID         string `json:"-" db:"user_id"`
+	RetryCount     int    `json:"retry_count" db:"retry_count"`
+	// Queue is the queue the message came from, e.g. a retry tier
+	Queue        string               `json:"queue" db:"queue" example:"push_retries_30s"`
+	Redelivered  bool                 `json:"redelivered" db:"redelivered"`
+	ProjectID    *string              `json:"project_id,omitempty" db:"project_id"`
+	Results      []AttemptTokenResult `json:"results" db:"results"`
+	SuccessCount int                  `json:"success_count" db:"success_count"`
+	FailureCount int                  `json:"failure_count" db:"failure_count"`
+	Error        *string              `json:"error,omitempty" db:"error"`
+	// NextQueue is where the message went after the attempt; empty once the
+	// notification was settled
+	NextQueue   *string   `json:"next_queue,omitempty" db:"next_queue" example:"push_retries_2m"`
+	AttemptedAt time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+// AttemptTokenResult is the outcome of an attempt for one device token
+type AttemptTokenResult struct {
+	// Token is masked; DeviceToken is the token itself, which is stored
+	// with the token's delivery result but not with the attempt
+	Token       string  `json:"token"`
+	DeviceToken string  `json:"-"`
+	Success     bool    `json:"success"`
+	MessageID   *string `json:"message_id,omitempty"`
+	ErrorKind   *string `json:"error_kind,omitempty" example:"unavailable"`
+	// ErrorCode is FCM's error code
+	ErrorCode *string `json:"error_code,omitempty" example:"UNAVAILABLE"`
+	Error     *string `json:"error,omitempty"`
+}
+
+// TokenDeliveryResult is where delivering a notification to one of its
+// device tokens stands, after its latest attempt. A token that was delivered
+// to stays delivered.
+// @Description The latest outcome of delivering a notification to one device token
+type TokenDeliveryResult struct {
+	Token     string    `json:"token" example:"dGVzdF90b...a2VuXzEyMw"` // masked
+	Success   bool      `json:"success"`
+	MessageID *string   `json:"message_id,omitempty"`
+	ErrorKind *string   `json:"error_kind,omitempty" example:"unregistered"`
+	ErrorCode *string   `json:"error_code,omitempty" example:"UNREGISTERED"`
+	Error     *string   `json:"error,omitempty"`
+	Attempts  int       `json:"attempts" example:"1"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotificationTokenResults lists a notification's results per device token
+// @Description A notification's latest delivery result for each of its device tokens
+type NotificationTokenResults struct {
+	NotificationID string                `json:"notification_id" example:"0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"`
+	Results        []TokenDeliveryResult `json:"results"`
+	Count          int                   `json:"count" example:"2"`
+}
+
+// NotificationDetail tells the story of a notification through its attempts,
+// oldest first
+type NotificationDetail struct {
+	ID       string            `json:"id"`
+	UserID   string            `json:"user_id"`
+	Status   string            `json:"status" example:"delivered"`
+	Attempts []DeliveryAttempt `json:"attempts"`
+}
+
+// DeliveryEvent is a delivery attempt listed with the notification it was
+// made for, as the admin API's feed of recent attempts shows it
+// @Description A delivery attempt of any notification, with its ID to continue the feed after it
+type DeliveryEvent struct {
+	ID             int64  `json:"id" example:"1042"`
+	NotificationID string `json:"notification_id" example:"0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"`
+	UserID         string `json:"user_id" example:"user123"`
+	// Status is the one the attempt left its notification in
+	Status string `json:"status" example:"delivered"`
+	DeliveryAttempt
+}
+
+// DeliveryEvents is a page of the feed of recent delivery attempts
+// @Description Delivery attempts recorded after a given one, oldest first
+type DeliveryEvents struct {
+	Events []DeliveryEvent `json:"events"`
+	Count  int             `json:"count" example:"1"`
+	// After is the ID to pass to get the attempts recorded since
+	After int64 `json:"after" example:"1042"`
+}
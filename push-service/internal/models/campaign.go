@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+const (
+	CampaignStatusRunning   = "running"
+	CampaignStatusSoaking   = "soaking" // Canary sent, waiting to continue
+	CampaignStatusCompleted = "completed"
+	CampaignStatusCancelled = "cancelled"
+)
+
+// CampaignVariant is one title and body of an A/B tested campaign. Weight is
+// the percentage of the audience that gets it.
+type CampaignVariant struct {
+	Name   string `json:"name" binding:"required,max=50" example:"short_copy"`
+	Title  string `json:"title" binding:"required"`
+	Body   string `json:"body" binding:"required"`
+	Weight int    `json:"weight" binding:"required,min=1,max=100" example:"50"`
+}
+
+// Campaign is a large send that is enqueued gradually, paced against the
+// FCM projects' daily quotas.
+type Campaign struct {
+	ID       string         `json:"id" db:"id"`
+	Name     string         `json:"name" db:"name"`
+	Title    string         `json:"title" db:"title"`
+	Body     string         `json:"body" db:"body"`
+	Image    *string        `json:"image,omitempty" db:"image"`
+	Link     *string        `json:"link,omitempty" db:"link"`
+	Data     map[string]any `json:"data,omitempty" db:"data"`
+	Sender   *string        `json:"sender,omitempty" db:"sender"`
+	Category *string        `json:"category,omitempty" db:"category"`
+
+	// AnalyticsLabel segments the campaign's deliveries in Firebase reporting
+	AnalyticsLabel *string `json:"analytics_label,omitempty" db:"analytics_label"`
+
+	// Variants, if set, replace Title and Body: each user gets one, picked
+	// by a hash of the campaign and user ID
+	Variants []CampaignVariant `json:"variants,omitempty" db:"variants"`
+
+	// Canary rollout: the first CanaryUsers of the shuffled audience are
+	// sent to, then the campaign soaks until ContinueAt, which is set
+	// CanarySoakSeconds after the canary or by an explicit continue
+	CanaryPercent     *int       `json:"canary_percent,omitempty" db:"canary_percent"`
+	CanaryUsers       int        `json:"canary_users,omitempty" db:"canary_users"`
+	CanarySoakSeconds *int       `json:"canary_soak_seconds,omitempty" db:"canary_soak_seconds"`
+	CanaryCompletedAt *time.Time `json:"canary_completed_at,omitempty" db:"canary_completed_at"`
+	ContinueAt        *time.Time `json:"continue_at,omitempty" db:"continue_at"`
+
+	// UserIDs holds the full audience on create and only the next batch
+	// when loaded by the scheduler.
+	UserIDs []string `json:"-" db:"user_ids"`
+
+	TotalUsers            int        `json:"total_users"`
+	ProcessedUsers        int        `json:"processed_users" db:"processed_users"`
+	EnqueuedUsers         int        `json:"enqueued_users" db:"enqueued_users"`
+	EnqueuedTokens        int64      `json:"enqueued_tokens" db:"enqueued_tokens"`
+	QuotaPolicy           string     `json:"quota_policy" db:"quota_policy"`
+	Status                string     `json:"status" db:"status"`
+	ProjectedCompletionAt *time.Time `json:"projected_completion_at,omitempty" db:"projected_completion_at"`
+	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt           *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+type CreateCampaignRequest struct {
+	Name        string         `json:"name" binding:"required" example:"spring_sale"`
+	Title       string         `json:"title" binding:"required_without=Variants"`
+	Body        string         `json:"body" binding:"required_without=Variants"`
+	Image       *string        `json:"image,omitempty"`
+	Link        *string        `json:"link,omitempty"`
+	Data        map[string]any `json:"data,omitempty"`
+	Sender      *string        `json:"sender,omitempty"`
+	Category    *string        `json:"category,omitempty"`
+	UserIDs     []string       `json:"user_ids" binding:"required,min=1"`
+	QuotaPolicy string         `json:"quota_policy,omitempty" binding:"omitempty,oneof=spread failover" example:"spread"` // Defaults to the configured policy
+	TenantID    string         `json:"-"`                                                                                 // From the X-Tenant-ID header
+
+	// Segments the campaign's deliveries in Firebase reporting
+	AnalyticsLabel *string `json:"analytics_label,omitempty" binding:"omitempty,analytics_label" example:"spring_sale_2026"`
+
+	// A/B test variants, whose weights add up to 100, instead of one title
+	// and body
+	Variants []CampaignVariant `json:"variants,omitempty" binding:"omitempty,min=2,max=10,dive"`
+
+	// Send to a small share of the audience first
+	Canary *CanaryRequest `json:"canary,omitempty"`
+}
+
+// CanaryRequest sends a campaign to Percent of its audience, picked at
+// random, first. The rest follows once Soak has passed or, without a soak,
+// when the campaign is continued through the API.
+type CanaryRequest struct {
+	Percent int    `json:"percent" binding:"required,min=1,max=50" example:"5"`
+	Soak    string `json:"soak,omitempty" example:"30m"`
+}
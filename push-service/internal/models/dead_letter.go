@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// ArchivedDeadLetter is a dead letter moved out of the dead letter queue. It
+// is staged until it is written to object storage, then ObjectURL and Line
+// say where it is.
+// @Description A dead letter moved to the archive: the object holding it and its line in the object, once written
+type ArchivedDeadLetter struct {
+	NotificationID *string    `json:"notification_id,omitempty" db:"notification_id" example:"0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"`
+	DeadLetteredAt *time.Time `json:"dead_lettered_at,omitempty" db:"dead_lettered_at"`
+	StagedAt       time.Time  `json:"staged_at" db:"staged_at"`
+	ObjectURL      *string    `json:"object_url,omitempty" db:"object_url" example:"s3://push-archive/dead-letters/2026/10/16/20261016T140000Z-5f0c9a.ndjson.gz"`
+	Line           *int       `json:"line,omitempty" db:"line" example:"42"` // 1-based
+	ArchivedAt     *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+}
+
+// ArchivedDeadLetters lists a notification's archived dead letters
+// @Description Where a notification's dead letters were archived, oldest first
+type ArchivedDeadLetters struct {
+	NotificationID string               `json:"notification_id" example:"0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"`
+	DeadLetters    []ArchivedDeadLetter `json:"dead_letters"`
+	Count          int                  `json:"count" example:"1"`
+}
+
+// StagedDeadLetter is a dead letter waiting to be written to object storage
+type StagedDeadLetter struct {
+	ID             int64      `db:"id"`
+	NotificationID *string    `db:"notification_id"`
+	Body           []byte     `db:"body"`
+	DeadLetteredAt *time.Time `db:"dead_lettered_at"`
+}
+
+// DeadLetter is a message waiting in the dead letter queue
+// @Description A dead-lettered push message, with its device tokens masked
+type DeadLetter struct {
+	ID             string     `json:"id"`
+	NotificationID string     `json:"notification_id,omitempty" example:"0b6f4c5e-7a55-4a43-9a3c-2f1d58c0b4de"`
+	UserID         string     `json:"user_id,omitempty" example:"user123"`
+	Title          string     `json:"title,omitempty" example:"New message"`
+	Priority       string     `json:"priority,omitempty" example:"high"`
+	CampaignID     string     `json:"campaign_id,omitempty"`
+	DeviceTokens   []string   `json:"device_tokens,omitempty" example:"dGVzdF90b...a2VuXzEyMw"` // masked
+	RetryCount     int        `json:"retry_count" example:"5"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeadLetteredAt *time.Time `json:"dead_lettered_at,omitempty"`
+	// Error is why the message couldn't be decoded; it can't be replayed
+	Error string `json:"error,omitempty"`
+}
+
+// DeadLetters lists the messages at the head of the dead letter queue
+// @Description Messages at the head of the dead letter queue, oldest first
+type DeadLetters struct {
+	DeadLetters []DeadLetter `json:"dead_letters"`
+	Count       int          `json:"count" example:"1"`
+}
+
+// DeadLetterReplay reports the dead letters moved back to the push queues
+// @Description Dead letters replayed onto the push queues
+type DeadLetterReplay struct {
+	// Replayed messages went back to their push queue with no retries counted
+	Replayed int `json:"replayed" example:"10"`
+	// Skipped messages couldn't be decoded and were left in the queue
+	Skipped int `json:"skipped" example:"0"`
+}
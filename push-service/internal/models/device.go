@@ -12,18 +12,135 @@ type Device struct {
 	IsActive  bool      `json:"is_active" db:"is_active"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// PermissionStatus is the OS notification permission reported by the
+	// client SDK; nil until reported.
+	PermissionStatus    *string    `json:"permission_status,omitempty" db:"permission_status"`
+	PermissionUpdatedAt *time.Time `json:"permission_updated_at,omitempty" db:"permission_updated_at"`
+
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty" db:"deactivated_at"`
+
+	// Timezone is the device's IANA timezone, e.g. Europe/Berlin; nil until
+	// reported. Sends at a local time use it.
+	Timezone *string `json:"timezone,omitempty" db:"timezone"`
+
+	// LastSeenAt is when the app last registered, refreshed the token or
+	// sent a heartbeat from the device
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty" db:"last_seen_at"`
+
+	// StableDeviceID identifies the physical device across app reinstalls
+	// and token changes; nil unless the client sends one
+	StableDeviceID *string `json:"device_id,omitempty" db:"stable_device_id"`
+
+	DeviceMetadata
+}
+
+// DeviceMetadata describes the app and device a token belongs to, as
+// reported by the client SDK. Fields are nil until reported, and a field
+// left out of a later registration keeps its value. Metadata holds
+// anything else the app keeps with the device; reported keys are merged
+// into it.
+type DeviceMetadata struct {
+	AppVersion  *string        `json:"app_version,omitempty" db:"app_version" binding:"omitempty,max=50" example:"4.2.0"`
+	OSVersion   *string        `json:"os_version,omitempty" db:"os_version" binding:"omitempty,max=50" example:"17.4.1"`
+	DeviceModel *string        `json:"device_model,omitempty" db:"device_model" binding:"omitempty,max=100" example:"iPhone15,2"`
+	Locale      *string        `json:"locale,omitempty" db:"locale" binding:"omitempty,max=35,bcp47_language_tag" example:"en-GB"`
+	Metadata    map[string]any `json:"metadata,omitempty" db:"metadata"`
 }
 
+// IsEmpty reports whether no metadata was reported
+func (m DeviceMetadata) IsEmpty() bool {
+	return m.AppVersion == nil && m.OSVersion == nil && m.DeviceModel == nil && m.Locale == nil && len(m.Metadata) == 0
+}
+
+// Notification permission statuses reported by the client SDK
+const (
+	PermissionGranted     = "granted"
+	PermissionDenied      = "denied"
+	PermissionProvisional = "provisional"
+)
+
 type CreateDeviceRequest struct {
 	UserID   string `json:"user_id" binding:"required"`
 	Token    string `json:"token" binding:"required"`
 	Platform string `json:"platform" binding:"required,oneof=ios android web"`
+
+	PermissionStatus *string `json:"permission_status,omitempty" binding:"omitempty,oneof=granted denied provisional"`
+	Timezone         *string `json:"timezone,omitempty" binding:"omitempty,timezone"`
+
+	// DeviceID is a stable ID of the device, e.g. from the keychain. A new
+	// token registered with it replaces the device's old registration.
+	DeviceID *string `json:"device_id,omitempty" binding:"omitempty,min=1,max=255"`
+
+	DeviceMetadata
 }
 
 type DeviceResponse struct {
-	ID       string `json:"id"`
-	UserID   string `json:"user_id"`
-	Token    string `json:"token"`
-	Platform string `json:"platform"`
-	IsActive bool   `json:"is_active"`
-}
\ No newline at end of file
+	ID               string  `json:"id"`
+	UserID           string  `json:"user_id"`
+	Token            string  `json:"token"`
+	Platform         string  `json:"platform"`
+	IsActive         bool    `json:"is_active"`
+	PermissionStatus *string `json:"permission_status,omitempty"`
+	Timezone         *string `json:"timezone,omitempty"`
+	Resurrected      bool    `json:"resurrected,omitempty"` // A deactivated registration was restored
+
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	DeviceID   *string    `json:"device_id,omitempty"` // The client's stable device ID
+	Replaced   bool       `json:"replaced,omitempty"`  // The device's registration under an older token was replaced
+
+	DeviceMetadata
+}
+
+// SDKRegisterRequest registers a token from the client SDK. Platform may be
+// left out and is then detected from the request headers.
+type SDKRegisterRequest struct {
+	UserID           string  `json:"user_id" binding:"required" example:"user123"`
+	Token            string  `json:"token" binding:"required" example:"fcm_token_here"`
+	Platform         string  `json:"platform,omitempty" binding:"omitempty,oneof=ios android web" example:"android"`
+	PermissionStatus *string `json:"permission_status,omitempty" binding:"omitempty,oneof=granted denied provisional" example:"granted"`
+	Timezone         *string `json:"timezone,omitempty" binding:"omitempty,timezone" example:"Europe/Berlin"`
+	DeviceID         *string `json:"device_id,omitempty" binding:"omitempty,min=1,max=255" example:"8F14E45F-CEEA-467A-9575-2F5C3C8F6A1B"`
+
+	DeviceMetadata
+}
+
+// RefreshTokenRequest swaps a rotated FCM token for its replacement
+type RefreshTokenRequest struct {
+	OldToken string `json:"old_token" binding:"required" example:"old_fcm_token"`
+	NewToken string `json:"new_token" binding:"required" example:"new_fcm_token"`
+}
+
+// UpdatePermissionRequest reports the device's notification permission
+type UpdatePermissionRequest struct {
+	Status string `json:"status" binding:"required,oneof=granted denied provisional" example:"provisional"`
+}
+
+// DeviceConfig is the per-device configuration fetched by the client SDK
+type DeviceConfig struct {
+	DeviceID                         string  `json:"device_id"`
+	Platform                         string  `json:"platform" example:"ios"`
+	PermissionStatus                 *string `json:"permission_status,omitempty" example:"granted"`
+	PushEnabled                      bool    `json:"push_enabled" example:"true"`
+	TokenRefreshIntervalSeconds      int64   `json:"token_refresh_interval_seconds" example:"86400"`
+	PermissionRecheckIntervalSeconds int64   `json:"permission_recheck_interval_seconds" example:"86400"`
+}
+
+// DeviceFilter selects devices, e.g. for an export. Empty fields match
+// every device.
+type DeviceFilter struct {
+	UserID        string
+	UserIDPrefix  string
+	Platform      string
+	Active        *bool
+	SeenSince     *time.Time // only devices last seen at or after it
+	CreatedBefore *time.Time // only devices registered before it
+}
+
+// DevicePage is a page of devices in ID order. NextCursor fetches the next
+// page and is empty on the last one.
+type DevicePage struct {
+	Devices    []Device `json:"devices"`
+	Count      int      `json:"count" example:"1"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Device import formats
+const (
+	DeviceImportFormatNDJSON = "ndjson"
+	DeviceImportFormatCSV    = "csv"
+)
+
+// Device import statuses
+const (
+	DeviceImportStatusRunning   = "running"
+	DeviceImportStatusCompleted = "completed"
+	DeviceImportStatusFailed    = "failed"
+)
+
+// DeviceImport is a bulk import of device tokens from an NDJSON or CSV
+// file. Its counts are updated after every batch while it runs.
+type DeviceImport struct {
+	ID          string              `json:"id" db:"id"`
+	Format      string              `json:"format" db:"format" example:"csv"`
+	Status      string              `json:"status" db:"status" example:"running"`
+	RowsRead    int64               `json:"rows_read" db:"rows_read" example:"250000"`
+	Inserted    int64               `json:"inserted" db:"inserted" example:"240000"`
+	Updated     int64               `json:"updated" db:"updated" example:"9990"`
+	Invalid     int64               `json:"invalid" db:"invalid" example:"10"`
+	Errors      []DeviceImportError `json:"errors" db:"errors"`
+	Error       *string             `json:"error,omitempty" db:"error"` // why a failed import stopped
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// DeviceImportError is a row an import rejected. Line is the row's line in
+// the file, counting a CSV header.
+type DeviceImportError struct {
+	Line  int64  `json:"line" example:"42"`
+	Error string `json:"error" example:"unknown platform \"blackberry\""`
+}
+
+// DeviceImportRow is one device of an import file
+type DeviceImportRow struct {
+	UserID   string `json:"user_id"`
+	Token    string `json:"token"`
+	Platform string `json:"platform"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DigestItem is a low-priority notification waiting to be delivered in its
+// user's next digest
+type DigestItem struct {
+	ID        string    `json:"id" db:"id"`
+	TenantID  string    `json:"tenant_id,omitempty" db:"tenant_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Title     string    `json:"title" db:"title"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	DryRunStatusPending   = "pending"
+	DryRunStatusCompleted = "completed"
+)
+
+// DryRun is a send validated by FCM (validate_only) instead of delivered.
+// It completes once the worker has processed every enqueued message.
+type DryRun struct {
+	ID                string         `json:"id" db:"id"`
+	Status            string         `json:"status" example:"completed"`
+	MessageCount      int            `json:"message_count" db:"message_count"`
+	CompletedMessages int            `json:"completed_messages" db:"completed_messages"`
+	Results           []DryRunResult `json:"results"`
+	CreatedAt         time.Time      `json:"created_at" db:"created_at"`
+}
+
+// DryRunResult is FCM's verdict on one device token
+type DryRunResult struct {
+	UserID    string  `json:"user_id" db:"user_id"`
+	Token     string  `json:"token" db:"token"`
+	Valid     bool    `json:"valid" db:"valid"`
+	ErrorKind *string `json:"error_kind,omitempty" db:"error_kind" example:"unregistered"`
+	Error     *string `json:"error,omitempty" db:"error"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Event types published on the events queue
+const (
+	EventDeviceResurrected = "device.resurrected"
+)
+
+// Event is a domain event for downstream consumers
+type Event struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Data       map[string]any `json:"data"`
+}
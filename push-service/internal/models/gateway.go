@@ -0,0 +1,36 @@
+package models
+
+// GatewayPushMessage is a notification the API Gateway publishes to
+// push.queue, as decoded from any schema version
+type GatewayPushMessage struct {
+	// SchemaVersion is the version of the gateway schema the message was
+	// published with; messages without one are version 1
+	SchemaVersion  int    `json:"schema_version"`
+	NotificationID string `json:"notification_id"`
+	UserID         string `json:"user_id"`
+	TenantID       string `json:"tenant_id,omitempty"`
+	// Priority selects the push queue, e.g. urgent or bulk, and samples the
+	// message's trace
+	Priority string `json:"priority,omitempty"`
+	// Type is the kind of notification, e.g. marketing. It sets the priority
+	// and TTL of messages without their own, and is the category of messages
+	// without one.
+	Type string `json:"type,omitempty"`
+	// PushToken is used when the user has no registered devices
+	PushToken string `json:"push_token,omitempty"`
+	// Sender and Category let users mute the notification
+	Sender   string           `json:"sender,omitempty"`
+	Category string           `json:"category,omitempty"`
+	Template *GatewayTemplate `json:"template,omitempty"`
+	Data     map[string]any   `json:"data,omitempty"`
+}
+
+// GatewayTemplate is the rendered template of a gateway message. HTMLBody
+// is preferred over Body, and the Variables are the names of the message's
+// data values substituted for {{name}} in the subject and body.
+type GatewayTemplate struct {
+	Subject   string   `json:"subject,omitempty"`
+	HTMLBody  string   `json:"html_body,omitempty"`
+	Body      string   `json:"body,omitempty"`
+	Variables []string `json:"variables,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// InboxItem is a notification in a user's inbox. It is unread until ReadAt
+// is set.
+type InboxItem struct {
+	ID             string         `json:"id" db:"id"`
+	UserID         string         `json:"user_id" db:"user_id"`
+	NotificationID string         `json:"notification_id" db:"notification_id"`
+	Title          string         `json:"title" db:"title"`
+	Body           string         `json:"body" db:"body"`
+	Image          *string        `json:"image,omitempty" db:"image"`
+	Link           *string        `json:"link,omitempty" db:"link"`
+	Data           map[string]any `json:"data,omitempty" db:"data"`
+	Category       *string        `json:"category,omitempty" db:"category"`
+	ReadAt         *time.Time     `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+}
+
+// InboxPage is a page of a user's inbox, newest first. NextCursor fetches
+// the next page and is empty on the last one.
+type InboxPage struct {
+	UserID      string      `json:"user_id" example:"user123"`
+	Items       []InboxItem `json:"items"`
+	UnreadCount int         `json:"unread_count" example:"3"`
+	NextCursor  string      `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	MuteScopeSender   = "sender"
+	MuteScopeCategory = "category"
+)
+
+// Drop reasons recorded when a notification is deliberately not delivered
+const (
+	DropReasonMutedSender   = "muted_sender"
+	DropReasonMutedCategory = "muted_category"
+)
+
+// UserMute silences one sender or notification category for a user without
+// opting them out of push entirely. A nil ExpiresAt mutes indefinitely.
+type UserMute struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"user_id" db:"user_id"`
+	Scope     string     `json:"scope" db:"scope"`
+	Value     string     `json:"value" db:"value"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateMuteRequest struct {
+	UserID    string     `json:"user_id" binding:"required" example:"user123"`
+	Scope     string     `json:"scope" binding:"required,oneof=sender category" example:"sender"`
+	Value     string     `json:"value" binding:"required" example:"brand_acme"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxMessage is a queue message waiting in the outbox to be published
+type OutboxMessage struct {
+	ID        int64           `json:"id" db:"id"`
+	Message   json.RawMessage `json:"message" db:"message"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
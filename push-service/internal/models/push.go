@@ -1,20 +1,82 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification priorities. Each has its own queue, and the worker drains
+// higher priorities first.
+const (
+	PriorityCritical = "critical"
+	PriorityHigh     = "high"
+	PriorityNormal   = "normal"
+	PriorityLow      = "low"
+)
+
+// DropReasonConnected is the drop reason of pushes skipped because the user
+// got the notification over a realtime connection
+const DropReasonConnected = "connected"
+
+// DropReasonExpired is the drop reason of notifications whose TTL passed
+// before they could be sent
+const DropReasonExpired = "expired"
+
+// DropReasonFrequencyCapped is the drop reason of notifications past their
+// category's frequency cap for the user
+const DropReasonFrequencyCapped = "frequency_capped"
+
+// NotificationAction is a button on a notification. The app learns which one
+// was tapped by its ID.
+type NotificationAction struct {
+	ID    string  `json:"id" binding:"required" example:"approve"`
+	Title string  `json:"title" binding:"required" example:"Approve"`
+	Icon  *string `json:"icon,omitempty"`
+}
+
+// IOSOptions are the APNs fields of a notification. Category selects the
+// app's registered notification category (its actions), unlike the muting
+// category of the notification itself.
+type IOSOptions struct {
+	Badge    *int   `json:"badge,omitempty" binding:"omitempty,min=0" example:"3"` // 0 clears the badge
+	Sound    string `json:"sound,omitempty" example:"default"`
+	Category string `json:"category,omitempty" example:"NEW_MESSAGE"`
+	ThreadID string `json:"thread_id,omitempty" example:"chat-42"` // Groups notifications
+}
+
+// PlatformOverrides are FCM v1 AndroidConfig, ApnsConfig and WebpushConfig
+// JSON objects merged over the configs built from a notification's fields,
+// for settings the API doesn't model
+type PlatformOverrides struct {
+	Android map[string]any `json:"android,omitempty"`
+	APNs    map[string]any `json:"apns,omitempty"`
+	Webpush map[string]any `json:"webpush,omitempty"`
+}
 
 type PushNotification struct {
-	ID           string         `json:"id" db:"id"`
-	DeviceID     *string        `json:"device_id,omitempty" db:"device_id"`
-	UserID       string         `json:"user_id" db:"user_id"`
-	Title        string         `json:"title" db:"title"`
-	Body         string         `json:"body" db:"body"`
-	Image        *string        `json:"image,omitempty" db:"image"`
-	Link         *string        `json:"link,omitempty" db:"link"`
-	Data         map[string]any `json:"data,omitempty" db:"data"`
-	Status       string         `json:"status" db:"status"`
-	ErrorMessage *string        `json:"error_message,omitempty" db:"error_message"`
-	SentAt       *time.Time     `json:"sent_at,omitempty" db:"sent_at"`
-	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	ID          string               `json:"id" db:"id"`
+	DeviceID    *string              `json:"device_id,omitempty" db:"device_id"`
+	UserID      string               `json:"user_id" db:"user_id"`
+	Title       string               `json:"title" db:"title"`
+	Body        string               `json:"body" db:"body"`
+	Image       *string              `json:"image,omitempty" db:"image"`
+	Link        *string              `json:"link,omitempty" db:"link"`
+	Data        map[string]any       `json:"data,omitempty" db:"data"`
+	Sender      *string              `json:"sender,omitempty" db:"sender"`
+	Category    *string              `json:"category,omitempty" db:"category"`
+	BundleID    *string              `json:"bundle_id,omitempty" db:"bundle_id"`
+	CollapseKey *string              `json:"collapse_key,omitempty" db:"collapse_key"`
+	ExpiresAt   *time.Time           `json:"expires_at,omitempty" db:"expires_at"`
+	ClickAction *string              `json:"click_action,omitempty" db:"click_action"`
+	Actions     []NotificationAction `json:"actions,omitempty" db:"actions"`
+	IOS         *IOSOptions          `json:"ios,omitempty" db:"ios"`
+
+	PlatformOverrides *PlatformOverrides `json:"platform_overrides,omitempty" db:"platform_overrides"`
+	AnalyticsLabel    *string            `json:"analytics_label,omitempty" db:"analytics_label"`
+	Status            string             `json:"status" db:"status"`
+	ErrorMessage      *string            `json:"error_message,omitempty" db:"error_message"`
+	SentAt            *time.Time         `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt         time.Time          `json:"created_at" db:"created_at"`
 }
 
 type SendPushRequest struct {
@@ -25,11 +87,116 @@ type SendPushRequest struct {
 	Link      *string        `json:"link,omitempty"`
 	Data      map[string]any `json:"data,omitempty"`
 	Platforms []string       `json:"platforms,omitempty"` // Filter by specific platforms
+	Sender    *string        `json:"sender,omitempty"`    // Sender ID users can mute
+	Category  *string        `json:"category,omitempty"`  // Category users can mute
+	DryRun    bool           `json:"dry_run,omitempty"`   // Validate with FCM without delivering
+	TenantID  string         `json:"-"`                   // From the X-Tenant-ID header
+
+	// Which priority queue the notification goes through
+	Priority string `json:"priority,omitempty" binding:"omitempty,oneof=critical high normal low" example:"high"` // Defaults to normal
+
+	// Notifications with the same collapse key replace each other on the
+	// device instead of stacking, e.g. live score updates
+	CollapseKey *string `json:"collapse_key,omitempty" binding:"omitempty,max=64" example:"match-1234"`
+
+	// How long the notification is worth delivering, e.g. "10m". It is
+	// dropped by the worker and by FCM once that has passed.
+	TTL string `json:"ttl,omitempty" example:"10m"`
+
+	// What tapping the notification or one of its buttons does. ClickAction
+	// is the Android intent action opened on tap; link stays the deep link.
+	ClickAction *string              `json:"click_action,omitempty" example:"OPEN_ORDER"`
+	Actions     []NotificationAction `json:"actions,omitempty" binding:"omitempty,max=3,dive"`
+
+	// Badge, sound and grouping on iOS
+	IOS *IOSOptions `json:"ios,omitempty"`
+
+	// Merged verbatim into the FCM platform configs, e.g. to set an APNs
+	// header that has no field here
+	PlatformOverrides *PlatformOverrides `json:"platform_overrides,omitempty"`
+
+	// Segments the notification's deliveries in Firebase reporting
+	AnalyticsLabel *string `json:"analytics_label,omitempty" binding:"omitempty,analytics_label" example:"otp"`
+
+	// Retry policy overrides, bounded by the server's caps
+	MaxRetries   *int   `json:"max_retries,omitempty" binding:"omitempty,min=0" example:"8"`
+	RetryBackoff string `json:"retry_backoff,omitempty" example:"30s"` // Every retry waits in the shortest retry tier at least this long
+
+	// Delivers the notification at the next HH:MM in each device's own
+	// timezone instead of right away. Ignored for dry runs.
+	DeliverAtLocalTime string `json:"deliver_at_local_time,omitempty" example:"09:00"`
+
+	// Skips the push for users with an open realtime connection, who get
+	// the notification over it instead
+	SuppressPushIfConnected bool `json:"suppress_push_if_connected,omitempty"`
+
+	// Override the category's policy: whether the notification is delivered
+	// during quiet hours, and whether it skips the frequency cap
+	BypassQuietHours   *bool `json:"bypass_quiet_hours,omitempty"`
+	BypassFrequencyCap bool  `json:"bypass_frequency_cap,omitempty"`
+}
+
+// BulkRecipient is a bulk send's user with their own template variables.
+// {{name}} placeholders in the title and body are replaced with the user's
+// value of name when their notification is processed.
+type BulkRecipient struct {
+	UserID string            `json:"user_id" binding:"required" example:"u1"`
+	Vars   map[string]string `json:"vars,omitempty"`
 }
 
 type BulkPushRequest struct {
-	UserIDs []string       `json:"user_ids" binding:"required"`
-	Title   string         `json:"title" binding:"required"`
-	Body    string         `json:"body" binding:"required"`
-	Data    map[string]any `json:"data,omitempty"`
+	UserIDs  []string        `json:"user_ids,omitempty" binding:"required_without=Users"`
+	Users    []BulkRecipient `json:"users,omitempty" binding:"omitempty,dive"` // Users with per-user template variables
+	Title    string          `json:"title" binding:"required"`
+	Body     string          `json:"body" binding:"required"`
+	Data     map[string]any  `json:"data,omitempty"`
+	Sender   *string         `json:"sender,omitempty"`
+	Category *string         `json:"category,omitempty"`
+	DryRun   bool            `json:"dry_run,omitempty"` // Validate with FCM without delivering
+	TenantID string          `json:"-"`                 // From the X-Tenant-ID header
+
+	// Which priority queue the notifications go through
+	Priority string `json:"priority,omitempty" binding:"omitempty,oneof=critical high normal low" example:"low"` // Defaults to normal
+
+	// Segments the notifications' deliveries in Firebase reporting
+	AnalyticsLabel *string `json:"analytics_label,omitempty" binding:"omitempty,analytics_label" example:"weekly_digest"`
+
+	// Override the category's policy, as for single sends
+	BypassQuietHours   *bool `json:"bypass_quiet_hours,omitempty"`
+	BypassFrequencyCap bool  `json:"bypass_frequency_cap,omitempty"`
+}
+
+// RawPushRequest sends a complete FCM v1 message, as in the "message" field
+// of FCM's messages:send, to the token, topic or condition it names
+type RawPushRequest struct {
+	Message  json.RawMessage `json:"message" binding:"required" swaggertype:"object"`
+	UserID   string          `json:"user_id,omitempty"` // Recorded with the delivery attempts
+	TenantID string          `json:"-"`                 // From the X-Tenant-ID header
+
+	// Which priority queue the message goes through
+	Priority string `json:"priority,omitempty" binding:"omitempty,oneof=critical high normal low" example:"high"` // Defaults to normal
+}
+
+// BundleItem is one notification of a bundle. Items without a title and body
+// are delivered as silent data messages.
+type BundleItem struct {
+	Title string         `json:"title,omitempty" binding:"required_with=Body"`
+	Body  string         `json:"body,omitempty" binding:"required_with=Title"`
+	Image *string        `json:"image,omitempty"`
+	Link  *string        `json:"link,omitempty"`
+	Data  map[string]any `json:"data,omitempty" binding:"required_without=Title"`
+}
+
+// SendBundleRequest sends related notifications (e.g. a badge update, a data
+// sync and an alert) to a user's devices as one unit.
+type SendBundleRequest struct {
+	UserID    string       `json:"user_id" binding:"required"`
+	Items     []BundleItem `json:"items" binding:"required,min=1,max=10,dive"`
+	Platforms []string     `json:"platforms,omitempty"`
+	Sender    *string      `json:"sender,omitempty"`
+	Category  *string      `json:"category,omitempty"`
+	TenantID  string       `json:"-"` // From the X-Tenant-ID header
+
+	// Which priority queue the bundle goes through
+	Priority string `json:"priority,omitempty" binding:"omitempty,oneof=critical high normal low" example:"high"` // Defaults to normal
 }
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ScheduledPush is a queue message held back until DeliverAt
+type ScheduledPush struct {
+	ID        string          `json:"id" db:"id"`
+	DeliverAt time.Time       `json:"deliver_at" db:"deliver_at"`
+	Message   json.RawMessage `json:"message" db:"message"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Drop reasons for gateway messages of tenants in soft launch
+const (
+	DropReasonSoftLaunch    = "soft_launch"     // no test devices to route to
+	DropReasonSoftLaunchCap = "soft_launch_cap" // daily send cap reached
+)
+
+// Tenant holds a tenant's soft launch settings. While soft launch is
+// enabled, new tenants start in soft launch: their sends are routed to their
+// test users' devices and capped per day, and broadcasts are dry runs, until
+// an admin lifts it.
+type Tenant struct {
+	ID         string `json:"id" db:"id" example:"acme"`
+	SoftLaunch bool   `json:"soft_launch" db:"soft_launch"`
+	// DailySendCap overrides the configured cap of sends per UTC day
+	DailySendCap       *int       `json:"daily_send_cap,omitempty" db:"daily_send_cap" example:"1000"`
+	TestUserIDs        []string   `json:"test_user_ids" db:"test_user_ids"`
+	SentToday          int64      `json:"sent_today"`
+	SoftLaunchLiftedAt *time.Time `json:"soft_launch_lifted_at,omitempty" db:"soft_launch_lifted_at"`
+	CreatedAt          *time.Time `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt          *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// UpdateTenantRequest changes a tenant's soft launch settings; omitted fields
+// are unchanged. Set soft_launch to false to lift it.
+type UpdateTenantRequest struct {
+	SoftLaunch   *bool    `json:"soft_launch,omitempty" example:"false"`
+	DailySendCap *int     `json:"daily_send_cap,omitempty" binding:"omitempty,min=1" example:"1000"`
+	TestUserIDs  []string `json:"test_user_ids,omitempty" binding:"omitempty,max=100"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// DropReasonErased is the drop reason of messages queued for a user before
+// their data was deleted
+const DropReasonErased = "erased"
+
+// UserDataDeletion reports what was deleted with a user's data
+// @Description Rows deleted per kind of data when a user's data was erased
+type UserDataDeletion struct {
+	UserID           string    `json:"user_id" example:"user123"`
+	Devices          int64     `json:"devices" example:"2"`
+	Notifications    int64     `json:"notifications" example:"40"`
+	DeliveryAttempts int64     `json:"delivery_attempts" example:"41"`
+	TokenResults     int64     `json:"token_results" example:"80"`
+	InboxItems       int64     `json:"inbox_items" example:"40"`
+	DigestItems      int64     `json:"digest_items" example:"0"`
+	Mutes            int64     `json:"mutes" example:"1"`
+	FrequencyCounts  int64     `json:"frequency_counts" example:"3"`
+	DryRunResults    int64     `json:"dry_run_results" example:"0"`
+	ScheduledPushes  int64     `json:"scheduled_pushes" example:"0"`
+	OutboxMessages   int64     `json:"outbox_messages" example:"40"`
+	DeadLetters      int64     `json:"dead_letters" example:"0"`
+	Campaigns        int64     `json:"campaigns" example:"1"` // campaigns the user was removed from
+	Tenants          int64     `json:"tenants" example:"0"`   // tenants the user was a test user of
+	ErasedAt         time.Time `json:"erased_at"`
+}
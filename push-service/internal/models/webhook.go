@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Delivery status events sent to webhooks
+const (
+	EventNotificationQueued       = "notification.queued"
+	EventNotificationSent         = "notification.sent"
+	EventNotificationFailed       = "notification.failed"
+	EventNotificationDeadLettered = "notification.dead_lettered"
+)
+
+// Device lifecycle events sent to webhooks, besides EventDeviceResurrected.
+// Devices belong to no tenant, so they go to the webhooks registered
+// without one.
+const (
+	EventDeviceRegistered     = "device.registered"
+	EventDeviceUnregistered   = "device.unregistered"
+	EventDeviceTokenRefreshed = "device.token_refreshed"
+)
+
+// Webhook is a URL a tenant registered for delivery status and device
+// lifecycle events. Each event is POSTed as an Event, signed with the
+// webhook's secret.
+type Webhook struct {
+	ID        string    `json:"id" db:"id"`
+	TenantID  string    `json:"tenant_id" db:"tenant_id"`
+	URL       string    `json:"url" db:"url" example:"https://api.example.com/push-events"`
+	Secret    string    `json:"secret,omitempty" db:"secret"` // Only returned when the webhook is created
+	Events    []string  `json:"events" db:"events" example:"notification.sent,notification.failed"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL      string   `json:"url" binding:"required,url" example:"https://api.example.com/push-events"`
+	Events   []string `json:"events" binding:"required,min=1,dive,oneof=notification.queued notification.sent notification.failed notification.dead_lettered device.registered device.unregistered device.token_refreshed device.resurrected" example:"notification.sent,notification.failed"`
+	TenantID string   `json:"-"` // From the X-Tenant-ID header
+}
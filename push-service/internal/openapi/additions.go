@@ -0,0 +1,79 @@
+package openapi
+
+import "strings"
+
+// errorSchema names the component of the error body most failures share
+const errorSchema = "ErrorResponse"
+
+var errorResponseSchema = map[string]any{
+	"type":        "object",
+	"description": "A failed request: what went wrong and, for some errors, why",
+	"required":    []any{"error"},
+	"properties": map[string]any{
+		"error": map[string]any{
+			"type":    "string",
+			"example": "Notification not found",
+		},
+		"details": map[string]any{
+			"description": "Why the request failed, usually a string",
+			"example":     "limit must be between 1 and 500",
+		},
+	},
+}
+
+// link points a response to an operation taking an ID the response holds
+type link struct {
+	operation string // method and path
+	field     string // response field holding the ID
+}
+
+// asyncOperation is an operation that only enqueues work. Its response
+// links to the operations tracking the work, by name.
+type asyncOperation map[string]link
+
+var (
+	trackNotification = link{"GET /v1/notifications/{id}", "notification_id"}
+	trackDryRun       = link{"GET /v1/push/dry-runs/{id}", "dry_run_id"}
+)
+
+var asyncOperations = map[string]asyncOperation{
+	"POST /v1/push/send": {
+		"GetNotification":       trackNotification,
+		"GetNotificationTokens": {"GET /v1/notifications/{id}/tokens", "notification_id"},
+		"GetDryRun":             trackDryRun,
+	},
+	"POST /v1/push/raw":         {"GetNotification": trackNotification},
+	"POST /v1/push/send-bulk":   {"GetDryRun": trackDryRun},
+	"POST /v1/push/send-bundle": {},
+	"POST /v1/campaigns":        {"GetCampaign": {"GET /v1/campaigns/{id}", "id"}},
+}
+
+// links returns the OpenAPI links of the operation's response
+func (a asyncOperation) links() map[string]any {
+	links := make(map[string]any, len(a))
+	for name, link := range a {
+		method, path, _ := strings.Cut(link.operation, " ")
+		links[name] = map[string]any{
+			"operationRef": "#/paths/" + strings.ReplaceAll(strings.ReplaceAll(path, "~", "~0"), "/", "~1") + "/" + strings.ToLower(method),
+			"parameters":   map[string]any{"id": "$response.body#/" + link.field},
+		}
+	}
+	return links
+}
+
+const asyncDescription = `## Asynchronous delivery
+Sends and campaigns only enqueue notifications; these operations are marked ` + "`x-async: true`" + `. A successful response means the notification waits on a push queue, not that it was delivered. Workers deliver it later, retry failed tokens with backoff through the retry queues and move it to the dead letter queue after its last retry.
+
+Follow a notification with its notification_id: the responses link to ` + "`GET /v1/notifications/{id}`" + `, whose status is final once it is delivered, partially_delivered, failed or dead_lettered; queued and retrying aren't. Alternatively, register a webhook for the notification.queued, notification.sent, notification.failed and notification.dead_lettered events. Dry runs are followed with ` + "`GET /v1/push/dry-runs/{id}`" + `, and a notification dropped before it was enqueued, e.g. because the user muted its sender, has a drop_reason instead of an ID.`
+
+const errorsDescription = `## Errors
+Failures answer with an ` + "`ErrorResponse`" + `, or with a ` + "`handlers.ValidationErrorResponse`" + ` naming each invalid field for request bodies that fail validation.
+
+- 400: the request is invalid
+- 403: the endpoint is internal-only and the client isn't internal, or the tenant's soft launch doesn't allow the send
+- 404: the resource doesn't exist, or the feature is off on this instance
+- 409: the request conflicts with the resource's state
+- 413: the notification and its data are larger than FCM accepts
+- 429: the tenant's soft launch daily send cap is reached
+- 500: the request failed on the server; it may be retried
+- 502, 503: a backend the request needs is unavailable; retry later`
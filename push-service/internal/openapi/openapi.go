@@ -0,0 +1,308 @@
+// Package openapi builds the API's OpenAPI 3.0 document for client
+// generators. swag only writes Swagger 2.0, so the document it generates
+// from the handlers' annotations is converted, and completed with what the
+// annotations can't say: the shape of error responses, and that sends only
+// enqueue notifications, with links to where their delivery is tracked.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"push-service/docs/swagger"
+)
+
+// Version is the OpenAPI version of the document
+const Version = "3.0.3"
+
+var (
+	once sync.Once
+	doc  []byte
+	err  error
+)
+
+// Document returns the OpenAPI 3.0 document as JSON. It is built on the
+// first call.
+func Document() ([]byte, error) {
+	once.Do(func() {
+		var spec map[string]any
+		spec, err = Convert([]byte(swagger.SwaggerInfo.ReadDoc()))
+		if err != nil {
+			return
+		}
+		doc, err = json.MarshalIndent(spec, "", "  ")
+	})
+	return doc, err
+}
+
+// Convert converts a Swagger 2.0 document, as swag generates it, to
+// OpenAPI 3.0 and completes it
+func Convert(swagger2 []byte) (map[string]any, error) {
+	var in map[string]any
+	if err := json.Unmarshal(swagger2, &in); err != nil {
+		return nil, fmt.Errorf("invalid Swagger document: %w", err)
+	}
+	if version, _ := in["swagger"].(string); version != "2.0" {
+		return nil, fmt.Errorf("unsupported Swagger version %q", version)
+	}
+
+	schemas := map[string]any{}
+	if definitions, ok := in["definitions"].(map[string]any); ok {
+		for name, schema := range definitions {
+			schemas[name] = convertSchema(schema)
+		}
+	}
+	schemas[errorSchema] = errorResponseSchema
+
+	paths := map[string]any{}
+	if in, ok := in["paths"].(map[string]any); ok {
+		for path, item := range in {
+			operations, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			out := map[string]any{}
+			for method, operation := range operations {
+				if operation, ok := operation.(map[string]any); ok {
+					out[method] = convertOperation(path, method, operation)
+				}
+			}
+			paths[path] = out
+		}
+	}
+
+	info, _ := in["info"].(map[string]any)
+	if info == nil {
+		info = map[string]any{}
+	}
+	description, _ := info["description"].(string)
+	info["description"] = strings.TrimSpace(description + "\n\n" + asyncDescription + "\n\n" + errorsDescription)
+
+	basePath, _ := in["basePath"].(string)
+	if basePath == "" {
+		basePath = "/"
+	}
+	return map[string]any{
+		"openapi": Version,
+		"info":    info,
+		// Relative, so clients call the host they got the document from
+		"servers":    []any{map[string]any{"url": basePath}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+		"tags":       tags(paths),
+	}, nil
+}
+
+// convertOperation moves an operation's body and parameters into OpenAPI
+// 3.0's requestBody and parameter schemas, and its responses' schemas into
+// content
+func convertOperation(path, method string, in map[string]any) map[string]any {
+	out := map[string]any{}
+	for key, value := range in {
+		switch key {
+		case "consumes", "produces", "parameters", "responses":
+		default:
+			out[key] = value
+		}
+	}
+
+	consumes := mediaTypes(in["consumes"])
+	produces := mediaTypes(in["produces"])
+
+	var parameters []any
+	params, _ := in["parameters"].([]any)
+	for _, param := range params {
+		param, ok := param.(map[string]any)
+		if !ok {
+			continue
+		}
+		if param["in"] == "body" {
+			body := map[string]any{
+				"content": content(consumes, convertSchema(param["schema"])),
+			}
+			if param["description"] != nil {
+				body["description"] = param["description"]
+			}
+			if required, _ := param["required"].(bool); required {
+				body["required"] = true
+			}
+			out["requestBody"] = body
+			continue
+		}
+		parameters = append(parameters, convertParameter(param))
+	}
+	if len(parameters) > 0 {
+		out["parameters"] = parameters
+	}
+
+	responses := map[string]any{}
+	if in, ok := in["responses"].(map[string]any); ok {
+		for status, response := range in {
+			response, ok := response.(map[string]any)
+			if !ok {
+				continue
+			}
+			responses[status] = convertResponse(status, response, produces)
+		}
+	}
+	out["responses"] = responses
+
+	if async, ok := asyncOperations[strings.ToUpper(method)+" "+path]; ok {
+		out["x-async"] = true
+		for _, status := range []string{"200", "201"} {
+			if response, ok := responses[status].(map[string]any); ok && len(async) > 0 {
+				response["links"] = async.links()
+				break
+			}
+		}
+	}
+	return out
+}
+
+// parameterSchemaKeys are the keys of a Swagger 2.0 parameter that belong in
+// its schema in OpenAPI 3.0
+var parameterSchemaKeys = []string{"type", "format", "items", "enum", "default", "minimum", "maximum", "minLength", "maxLength", "pattern"}
+
+func convertParameter(in map[string]any) map[string]any {
+	out := map[string]any{}
+	schema := map[string]any{}
+	for key, value := range in {
+		switch {
+		case key == "collectionFormat":
+			if value == "multi" {
+				out["explode"] = true
+			} else {
+				out["explode"] = false
+			}
+		case slices.Contains(parameterSchemaKeys, key):
+			schema[key] = value
+		default:
+			out[key] = value
+		}
+	}
+	if len(schema) > 0 {
+		out["schema"] = convertSchema(schema)
+	}
+	if out["in"] == "path" {
+		out["required"] = true
+	}
+	return out
+}
+
+func convertResponse(status string, in map[string]any, produces []string) map[string]any {
+	out := map[string]any{}
+	for key, value := range in {
+		if key != "schema" {
+			out[key] = value
+		}
+	}
+	if out["description"] == nil {
+		out["description"] = ""
+	}
+
+	schema, ok := in["schema"]
+	if !ok {
+		return out
+	}
+	if isError(status) && isStringMap(schema) {
+		// gin.H{"error": ..., "details": ...}, annotated as map[string]string
+		schema = map[string]any{"$ref": "#/components/schemas/" + errorSchema}
+	}
+	out["content"] = content(produces, convertSchema(schema))
+	return out
+}
+
+// convertSchema rewrites a Swagger 2.0 schema for OpenAPI 3.0: references
+// point to components, x-nullable becomes nullable and file becomes binary
+// strings
+func convertSchema(schema any) any {
+	switch schema := schema.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(schema))
+		for key, value := range schema {
+			switch key {
+			case "$ref":
+				ref, _ := value.(string)
+				out[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+			case "x-nullable":
+				out["nullable"] = value
+			default:
+				out[key] = convertSchema(value)
+			}
+		}
+		if out["type"] == "file" {
+			out["type"] = "string"
+			out["format"] = "binary"
+		}
+		return out
+	case []any:
+		out := make([]any, len(schema))
+		for i, value := range schema {
+			out[i] = convertSchema(value)
+		}
+		return out
+	default:
+		return schema
+	}
+}
+
+func content(mediaTypes []string, schema any) map[string]any {
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{"application/json"}
+	}
+	out := make(map[string]any, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		out[mediaType] = map[string]any{"schema": schema}
+	}
+	return out
+}
+
+func mediaTypes(value any) []string {
+	values, _ := value.([]any)
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if value, ok := value.(string); ok {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// tags lists the tags the operations use, sorted, so generators group the
+// operations the same way every time
+func tags(paths map[string]any) []any {
+	seen := map[string]bool{}
+	for _, item := range paths {
+		for _, operation := range item.(map[string]any) {
+			operationTags, _ := operation.(map[string]any)["tags"].([]any)
+			for _, tag := range operationTags {
+				if tag, ok := tag.(string); ok {
+					seen[tag] = true
+				}
+			}
+		}
+	}
+	names := slices.Sorted(maps.Keys(seen))
+	out := make([]any, len(names))
+	for i, name := range names {
+		out[i] = map[string]any{"name": name}
+	}
+	return out
+}
+
+func isError(status string) bool {
+	return len(status) == 3 && (status[0] == '4' || status[0] == '5')
+}
+
+func isStringMap(schema any) bool {
+	m, ok := schema.(map[string]any)
+	if !ok || m["type"] != "object" {
+		return false
+	}
+	additional, ok := m["additionalProperties"].(map[string]any)
+	return ok && additional["type"] == "string"
+}
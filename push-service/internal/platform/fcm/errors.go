@@ -0,0 +1,148 @@
+package fcm
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/v4/errorutils"
+	"firebase.google.com/go/v4/messaging"
+	"golang.org/x/oauth2"
+)
+
+// ErrorKind classifies FCM errors by how the caller should react to them
+type ErrorKind string
+
+const (
+	ErrorKindNone            ErrorKind = ""
+	ErrorKindAuth            ErrorKind = "auth"
+	ErrorKindUnregistered    ErrorKind = "unregistered"
+	ErrorKindInvalidArgument ErrorKind = "invalid_argument"
+	ErrorKindQuota           ErrorKind = "quota"
+	ErrorKindUnavailable     ErrorKind = "unavailable"
+	ErrorKindInternal        ErrorKind = "internal"
+	ErrorKindUnknown         ErrorKind = "unknown"
+)
+
+// ErrProviderAuth is returned when FCM rejects the service credentials
+// (expired or revoked service account, wrong project). Retrying individual
+// messages cannot succeed until the credentials are fixed.
+var ErrProviderAuth = errors.New("fcm provider authentication failed")
+
+// ErrProviderThrottled is returned when FCM rejects sends for exceeding a
+// quota (HTTP 429, QUOTA_EXCEEDED), and for sends attempted before the
+// Retry-After delay has passed. They would fail the same way until then.
+var ErrProviderThrottled = errors.New("fcm provider quota exceeded")
+
+// ClassifyError maps an error returned by the FCM SDK to an ErrorKind
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorKindNone
+	}
+
+	// SENDER_ID_MISMATCH is about one token, registered to another sender:
+	// only that token is dropped, the credentials are fine
+	var retrieveErr *oauth2.RetrieveError
+	switch {
+	case messaging.IsUnregistered(err),
+		messaging.IsSenderIDMismatch(err):
+		return ErrorKindUnregistered
+	case errors.Is(err, ErrProviderAuth),
+		errors.As(err, &retrieveErr),
+		messaging.IsThirdPartyAuthError(err),
+		errorutils.IsPermissionDenied(err):
+		return ErrorKindAuth
+	case messaging.IsInvalidArgument(err):
+		return ErrorKindInvalidArgument
+	case messaging.IsQuotaExceeded(err):
+		return ErrorKindQuota
+	case messaging.IsUnavailable(err):
+		return ErrorKindUnavailable
+	case messaging.IsInternal(err):
+		return ErrorKindInternal
+	}
+
+	// The token source and FCM's 401 responses surface as plain errors. A
+	// 403 may be a sender mismatch, so it isn't taken for a credential
+	// failure on its own.
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "sender_id_mismatch") || strings.Contains(msg, "senderid mismatch") {
+		return ErrorKindUnregistered
+	}
+	for _, marker := range []string{"oauth2", "status: 401", "unauthenticated", "permission_denied", "invalid_grant"} {
+		if strings.Contains(msg, marker) {
+			return ErrorKindAuth
+		}
+	}
+
+	return ErrorKindUnknown
+}
+
+// ErrorCode returns the FCM API error code behind err, e.g. UNREGISTERED,
+// or "" if err isn't an FCM error
+func ErrorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case messaging.IsUnregistered(err):
+		return "UNREGISTERED"
+	case messaging.IsSenderIDMismatch(err):
+		return "SENDER_ID_MISMATCH"
+	case messaging.IsThirdPartyAuthError(err):
+		return "THIRD_PARTY_AUTH_ERROR"
+	case messaging.IsQuotaExceeded(err):
+		return "QUOTA_EXCEEDED"
+	case messaging.IsInvalidArgument(err):
+		return "INVALID_ARGUMENT"
+	case messaging.IsUnavailable(err):
+		return "UNAVAILABLE"
+	case messaging.IsInternal(err):
+		return "INTERNAL"
+	case messaging.IsUnknown(err):
+		return "UNSPECIFIED_ERROR"
+	}
+	return ""
+}
+
+// IsInvalidToken reports whether err means the token itself can never be
+// delivered to: FCM reports it unregistered or registered to another sender
+// (SENDER_ID_MISMATCH), or rejects it as an invalid argument naming the
+// registration token. Other invalid arguments are about the message, not the
+// token. Wrapped errors are unwrapped.
+func IsInvalidToken(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch ClassifyError(err) {
+		case ErrorKindUnregistered:
+			return true
+		case ErrorKindInvalidArgument:
+			return strings.Contains(strings.ToLower(err.Error()), "registration token")
+		}
+	}
+	return false
+}
+
+// RetryAfter returns the delay FCM asked for in the Retry-After header of
+// the response behind err, given in seconds or as an HTTP date. It returns
+// false if there is no such hint. Wrapped errors are unwrapped.
+func RetryAfter(err error) (time.Duration, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		resp := errorutils.HTTPResponse(err)
+		if resp == nil {
+			continue
+		}
+		value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+		if value == "" {
+			return 0, false
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(time.Until(at), 0), true
+		}
+		return 0, false
+	}
+	return 0, false
+}
@@ -0,0 +1,82 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Sends and failures are counted over the last failureWindow, in
+// failureBuckets buckets
+const (
+	failureWindow  = time.Minute
+	failureBuckets = 6
+)
+
+// failureCounter keeps a rolling count of the messages sent to FCM and of
+// those FCM failed on its side (unavailable, internal and unknown errors).
+// Errors about a token or the message, quota and credential errors are not
+// failures of FCM, and have their own handling.
+type failureCounter struct {
+	mu      sync.Mutex
+	buckets [failureBuckets]failureBucket
+}
+
+type failureBucket struct {
+	slot   int64 // which bucketDuration since the epoch it counts
+	sent   int64
+	failed int64
+}
+
+const bucketDuration = failureWindow / failureBuckets
+
+// record counts sent messages, failed of which FCM failed on its side
+func (c *failureCounter) record(sent, failed int) {
+	if sent == 0 {
+		return
+	}
+	slot := time.Now().UnixNano() / int64(bucketDuration)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bucket := &c.buckets[slot%failureBuckets]
+	if bucket.slot != slot {
+		*bucket = failureBucket{slot: slot}
+	}
+	bucket.sent += int64(sent)
+	bucket.failed += int64(failed)
+}
+
+// rate returns the share of the messages sent in the last window that FCM
+// failed, and how many were sent
+func (c *failureCounter) rate() (float64, int64) {
+	oldest := time.Now().UnixNano()/int64(bucketDuration) - failureBuckets + 1
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var sent, failed int64
+	for _, bucket := range c.buckets {
+		if bucket.slot >= oldest {
+			sent += bucket.sent
+			failed += bucket.failed
+		}
+	}
+	if sent == 0 {
+		return 0, 0
+	}
+	return float64(failed) / float64(sent), sent
+}
+
+// providerFailure tells if err is FCM failing on its side. Sends we gave up
+// on are not.
+func providerFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch ClassifyError(err) {
+	case ErrorKindUnavailable, ErrorKindInternal, ErrorKindUnknown:
+		return true
+	}
+	return false
+}
@@ -2,36 +2,135 @@ package fcm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"push-service/internal/chaos"
 	"push-service/internal/config"
 	"push-service/internal/models"
+	"push-service/pkg/metrics"
+	"push-service/pkg/tracing"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	firebase "firebase.google.com/go"
-	"firebase.google.com/go/messaging"
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
 type FCMClient interface {
 	Send(ctx context.Context, deviceToken string, notification models.PushNotification) error
 	SendMultiple(ctx context.Context, deviceTokens []string, notification models.PushNotification) (int, int, error)
 	SendMulticast(ctx context.Context, deviceTokens []string, notification models.PushNotification) (*messaging.BatchResponse, error)
+	SendMulticastDryRun(ctx context.Context, deviceTokens []string, notification models.PushNotification) (*messaging.BatchResponse, error)
+	SendRaw(ctx context.Context, message *messaging.Message) (string, error)
 	ValidateToken(ctx context.Context, deviceToken string) error
+	Status() ProviderStatus
+	Reload(ctx context.Context) error
+	// SetMaxSendRate changes the messages per second this instance sends;
+	// 0 lifts the cap
+	SetMaxSendRate(rate float64)
+	// Sandbox returns the simulated FCM sends go to, or nil unless FCM_MODE
+	// is sandbox
+	Sandbox() *Sandbox
 }
 
+// Provider status values reported by Status
+const (
+	ProviderStatusOK         = "ok"
+	ProviderStatusAuthFailed = "provider_auth_failed"
+)
+
+// ProviderStatus describes whether FCM is currently accepting our credentials
+type ProviderStatus struct {
+	Status    string    `json:"status" example:"ok"`
+	LastError string    `json:"last_error,omitempty"`
+	Since     time.Time `json:"since"`
+
+	// ThrottledUntil is set while sends are paused after a quota error
+	ThrottledUntil *time.Time `json:"throttled_until,omitempty"`
+
+	// RecentSends is the number of messages sent in the last minute, and
+	// FailureRate the share of them FCM failed on its side
+	RecentSends int64   `json:"recent_sends" example:"1200"`
+	FailureRate float64 `json:"failure_rate" example:"0.02"`
+}
+
+// probeToken is used for validate-only probes; FCM rejects it as an invalid
+// argument when the credentials work and with an auth error when they don't.
+const probeToken = "push-service-credential-probe"
+
+// messagingScope is the OAuth scope FCM sends need
+const messagingScope = "https://www.googleapis.com/auth/firebase.messaging"
+
 type fcmClient struct {
-	client *messaging.Client
+	cfg      *config.FCMConfig
+	pacer    *pacer
+	failures failureCounter
+	sandbox  *Sandbox      // nil in live mode
+	faults   *chaos.Faults // nil unless fault injection is enabled
+
+	mu             sync.RWMutex
+	client         *messaging.Client
+	status         ProviderStatus
+	probing        bool
+	throttledUntil time.Time
 }
 
-func NewFCMClient(cfg *config.FCMConfig) (FCMClient, error) {
-	ctx := context.Background()
+func NewFCMClient(cfg *config.FCMConfig, faults *chaos.Faults) (FCMClient, error) {
+	var sandbox *Sandbox
+	if cfg.Mode == config.FCMModeSandbox {
+		sandbox = NewSandbox(&cfg.Sandbox)
+	}
 
-	// Get credentials from config
-	credentials, err := cfg.GetFCMCredentials()
+	client, err := newMessagingClient(context.Background(), cfg, sandbox, faults)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get FCM credentials: %w", err)
+		return nil, err
+	}
+
+	if sandbox != nil {
+		zap.L().Warn("FCM sandbox mode: sends go to a simulated FCM and reach no device",
+			zap.String("project_id", cfg.ProjectID),
+		)
+	} else {
+		zap.L().Info("FCM client initialized successfully",
+			zap.String("project_id", cfg.ProjectID),
+			zap.Bool("using_file", cfg.UseFile),
+		)
+	}
+	return &fcmClient{
+		cfg:     cfg,
+		pacer:   newPacer(cfg.MaxSendRate),
+		sandbox: sandbox,
+		faults:  faults,
+		client:  client,
+		status:  ProviderStatus{Status: ProviderStatusOK, Since: time.Now()},
+	}, nil
+}
+
+// newMessagingClient creates the Firebase SDK's client, sending to sandbox
+// instead of FCM if it isn't nil, through faults' transport if that isn't
+// nil either
+func newMessagingClient(ctx context.Context, cfg *config.FCMConfig, sandbox *Sandbox, faults *chaos.Faults) (*messaging.Client, error) {
+	if sandbox != nil {
+		projectID := cfg.ProjectID
+		if projectID == "" {
+			projectID = "sandbox" // default
+		}
+		app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: projectID},
+			option.WithHTTPClient(&http.Client{Transport: faults.Transport(sandbox)}),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Firebase app: %w", err)
+		}
+		return app.Messaging(ctx)
 	}
 
 	// Configure Firebase App
@@ -39,8 +138,33 @@ func NewFCMClient(cfg *config.FCMConfig) (FCMClient, error) {
 		ProjectID: cfg.ProjectID,
 	}
 
-	opt := option.WithCredentialsJSON(credentials)
-	app, err := firebase.NewApp(ctx, firebaseConfig, opt)
+	var opts []option.ClientOption
+	if cfg.Endpoint != "" {
+		// An emulator or fcmtest server; it needs no credentials
+		opts = append(opts, option.WithEndpoint(cfg.Endpoint))
+		if cfg.CredentialsJSON == "" {
+			opts = append(opts, option.WithoutAuthentication())
+		}
+	}
+	if cfg.Endpoint == "" || cfg.CredentialsJSON != "" {
+		// Get credentials from config
+		credentials, err := cfg.GetFCMCredentials()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get FCM credentials: %w", err)
+		}
+		opts = append(opts, option.WithCredentialsJSON(credentials))
+	}
+	if faults != nil {
+		// Faults are injected between the SDK and the authenticated client
+		// it would have built from the same options
+		hc, _, err := htransport.NewClient(ctx, append(opts, option.WithScopes(messagingScope))...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create FCM HTTP client: %w", err)
+		}
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: faults.Transport(hc.Transport)}))
+	}
+
+	app, err := firebase.NewApp(ctx, firebaseConfig, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Firebase app: %w", err)
 	}
@@ -50,26 +174,190 @@ func NewFCMClient(cfg *config.FCMConfig) (FCMClient, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create FCM client: %w", err)
 	}
+	return client, nil
+}
 
-	zap.L().Info("FCM client initialized successfully",
-		zap.String("project_id", cfg.ProjectID),
-		zap.Bool("using_file", cfg.UseFile),
-	)
-	return &fcmClient{client: client}, nil
+func (f *fcmClient) messaging() *messaging.Client {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.client
 }
 
-func (f *fcmClient) Send(ctx context.Context, deviceToken string, notification models.PushNotification) error {
-	// Convert map[string]any to map[string]string for FCM
-	data := convertDataToStringMap(notification.Data)
+// Status returns the provider's current credential and quota status
+func (f *fcmClient) Status() ProviderStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	status := f.status
+	if until := f.throttledUntil; until.After(time.Now()) {
+		status.ThrottledUntil = &until
+	}
+	status.FailureRate, status.RecentSends = f.failures.rate()
+	return status
+}
 
-	// Add link to data if provided
-	if notification.Link != nil && *notification.Link != "" {
-		if data == nil {
-			data = make(map[string]string)
+// throttled returns ErrProviderThrottled while sends are paused after a
+// quota error
+func (f *fcmClient) throttled() error {
+	f.mu.RLock()
+	until := f.throttledUntil
+	f.mu.RUnlock()
+	if until.After(time.Now()) {
+		return fmt.Errorf("%w: paused until %s", ErrProviderThrottled, until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// throttle pauses sends for the Retry-After delay of a quota error, or for
+// the configured backoff if FCM gave none, and wraps err in
+// ErrProviderThrottled
+func (f *fcmClient) throttle(err error) error {
+	delay, ok := RetryAfter(err)
+	if !ok {
+		delay = f.cfg.QuotaBackoff
+		if delay <= 0 {
+			delay = time.Minute // default
+		}
+	}
+
+	until := time.Now().Add(delay)
+	f.mu.Lock()
+	if until.After(f.throttledUntil) {
+		if !f.throttledUntil.After(time.Now()) {
+			zap.L().Warn("FCM quota exceeded; pausing sends",
+				zap.Duration("retry_after", delay),
+				zap.Bool("server_hint", ok),
+				zap.Error(err),
+			)
+		}
+		f.throttledUntil = until
+	}
+	f.mu.Unlock()
+
+	return fmt.Errorf("%w: %v", ErrProviderThrottled, err)
+}
+
+func (f *fcmClient) SetMaxSendRate(rate float64) {
+	f.pacer.SetRate(rate)
+}
+
+func (f *fcmClient) Sandbox() *Sandbox {
+	return f.sandbox
+}
+
+// Reload rebuilds the FCM client from the configured credentials (re-reading
+// the service account file) and verifies them with a validate-only probe.
+func (f *fcmClient) Reload(ctx context.Context) error {
+	client, err := newMessagingClient(ctx, f.cfg, f.sandbox, f.faults)
+	if err != nil {
+		return err
+	}
+
+	if err := probe(ctx, client); err != nil {
+		return fmt.Errorf("credential probe failed: %w", err)
+	}
+
+	f.mu.Lock()
+	f.client = client
+	recovered := f.status.Status != ProviderStatusOK
+	if recovered {
+		f.status = ProviderStatus{Status: ProviderStatusOK, Since: time.Now()}
+	}
+	f.mu.Unlock()
+
+	metrics.ProviderAuthFailed.Set(0)
+	zap.L().Info("FCM credentials reloaded", zap.Bool("recovered", recovered))
+	return nil
+}
+
+func probe(ctx context.Context, client *messaging.Client) error {
+	_, err := client.SendDryRun(ctx, &messaging.Message{
+		Token: probeToken,
+		Data:  map[string]string{"probe": "true"},
+	})
+	if ClassifyError(err) == ErrorKindAuth {
+		return err
+	}
+	return nil
+}
+
+// recordSend counts a single message's send towards the failure rate
+func (f *fcmClient) recordSend(err error) {
+	failed := 0
+	if providerFailure(err) {
+		failed = 1
+	}
+	f.failures.record(1, failed)
+}
+
+// observe records an FCM error and wraps credential failures in
+// ErrProviderAuth, and quota errors in ErrProviderThrottled, so callers can
+// stop retrying individual messages.
+func (f *fcmClient) observe(err error) error {
+	kind := ClassifyError(err)
+	if kind == ErrorKindNone {
+		return nil
+	}
+	metrics.FCMErrors.WithLabelValues(string(kind)).Inc()
+
+	if kind == ErrorKindQuota {
+		return f.throttle(err)
+	}
+	if kind != ErrorKindAuth {
+		return err
+	}
+
+	f.mu.Lock()
+	if f.status.Status != ProviderStatusAuthFailed {
+		f.status = ProviderStatus{
+			Status:    ProviderStatusAuthFailed,
+			LastError: err.Error(),
+			Since:     time.Now(),
+		}
+		metrics.ProviderAuthFailed.Set(1)
+		zap.L().Error("FCM rejected service credentials; pausing delivery until they are fixed", zap.Error(err))
+	}
+	startProbe := !f.probing
+	f.probing = true
+	f.mu.Unlock()
+
+	if startProbe {
+		go f.probeUntilRecovered()
+	}
+
+	if errors.Is(err, ErrProviderAuth) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrProviderAuth, err)
+}
+
+// probeUntilRecovered periodically reloads the credentials until FCM accepts
+// them again, so a fixed service account file is picked up without a restart.
+func (f *fcmClient) probeUntilRecovered() {
+	interval := f.cfg.AuthProbeInterval
+	if interval <= 0 {
+		interval = time.Minute // default
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := f.Reload(ctx)
+		cancel()
+
+		if err == nil {
+			f.mu.Lock()
+			f.probing = false
+			f.mu.Unlock()
+			return
 		}
-		data["link"] = *notification.Link
-		data["click_action"] = *notification.Link
+		zap.L().Warn("FCM credentials still rejected", zap.Error(err))
 	}
+}
+
+func (f *fcmClient) Send(ctx context.Context, deviceToken string, notification models.PushNotification) error {
+	data := messageData(notification)
 
 	msgNotification := &messaging.Notification{
 		Title: notification.Title,
@@ -82,41 +370,41 @@ func (f *fcmClient) Send(ctx context.Context, deviceToken string, notification m
 	}
 
 	message := &messaging.Message{
-		Token:        deviceToken,
-		Notification: msgNotification,
-		Data:         data,
+		Token: deviceToken,
+		Data:  data,
+	}
+
+	// Without a title or body it's a silent data message
+	if notification.Title != "" || notification.Body != "" {
+		message.Notification = msgNotification
 	}
 
 	// Add webpush config for web notifications
-	if notification.Image != nil || notification.Link != nil {
-		webpushConfig := &messaging.WebpushConfig{
-			Headers: map[string]string{
-				"Urgency": "high",
-			},
-		}
+	message.Webpush = webpushConfig(notification, message.Notification != nil)
+	message.Android = androidConfig(notification, message.Notification != nil)
+	message.APNS = apnsConfig(notification)
+	message.FCMOptions = fcmOptions(notification)
+	applyOverrides(notification, &message.Android, &message.APNS, &message.Webpush)
 
-		if notification.Image != nil || notification.Link != nil {
-			webpushNotification := &messaging.WebpushNotification{
-				Title: notification.Title,
-				Body:  notification.Body,
-			}
-			if notification.Image != nil && *notification.Image != "" {
-				webpushNotification.Icon = *notification.Image
-				webpushNotification.Image = *notification.Image
-			}
-			// Link is handled via data payload for web push
-			webpushConfig.Notification = webpushNotification
-		}
-		message.Webpush = webpushConfig
+	if err := f.throttled(); err != nil {
+		return err
+	}
+	if err := f.pacer.Wait(ctx, 1); err != nil {
+		return err
 	}
 
-	response, err := f.client.Send(ctx, message)
+	ctx, span := tracing.Tracer().Start(ctx, "fcm.send", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	response, err := f.messaging().Send(ctx, message)
+	f.recordSend(err)
 	if err != nil {
+		tracing.RecordError(span, err)
 		zap.L().Error("Failed to send FCM message",
 			zap.String("token", deviceToken),
 			zap.Error(err),
 		)
-		return err
+		return f.observe(err)
 	}
 
 	zap.L().Info("FCM message sent successfully",
@@ -126,97 +414,168 @@ func (f *fcmClient) Send(ctx context.Context, deviceToken string, notification m
 	return nil
 }
 
-func (f *fcmClient) SendMultiple(ctx context.Context, deviceTokens []string, notification models.PushNotification) (int, int, error) {
-	// Convert map[string]any to map[string]string for FCM
-	data := convertDataToStringMap(notification.Data)
-
-	// Add link to data if provided
-	if notification.Link != nil && *notification.Link != "" {
-		if data == nil {
-			data = make(map[string]string)
-		}
-		data["link"] = *notification.Link
-		data["click_action"] = *notification.Link
+// SendRaw sends a complete FCM message as is, to whichever token, topic or
+// condition it targets, and returns FCM's message ID
+func (f *fcmClient) SendRaw(ctx context.Context, message *messaging.Message) (string, error) {
+	if err := f.throttled(); err != nil {
+		return "", err
+	}
+	if err := f.pacer.Wait(ctx, 1); err != nil {
+		return "", err
 	}
 
-	msgNotification := &messaging.Notification{
-		Title: notification.Title,
-		Body:  notification.Body,
+	ctx, span := tracing.Tracer().Start(ctx, "fcm.send_raw", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	response, err := f.messaging().Send(ctx, message)
+	f.recordSend(err)
+	if err != nil {
+		tracing.RecordError(span, err)
+		zap.L().Error("Failed to send raw FCM message",
+			zap.String("token", message.Token),
+			zap.String("topic", message.Topic),
+			zap.Error(err),
+		)
+		return "", f.observe(err)
 	}
 
-	// Add image if provided
-	if notification.Image != nil && *notification.Image != "" {
-		msgNotification.ImageURL = *notification.Image
+	zap.L().Info("Raw FCM message sent successfully",
+		zap.String("message_id", response),
+		zap.String("topic", message.Topic),
+	)
+	return response, nil
+}
+
+// multicastBatchSize is the most tokens FCM accepts in one multicast request
+const multicastBatchSize = 500
+
+// SendMultiple sends the notification to every token through SendMulticast
+// and returns the success and failure counts. It only fails as a whole when
+// FCM rejects our credentials.
+func (f *fcmClient) SendMultiple(ctx context.Context, deviceTokens []string, notification models.PushNotification) (int, int, error) {
+	response, err := f.SendMulticast(ctx, deviceTokens, notification)
+	return response.SuccessCount, response.FailureCount, err
+}
+
+// SendMulticast sends the notification in batches of up to 500 tokens with
+// FCM's SendEachForMulticast. The response has one entry per token, in
+// order, with FCM's error for each failed token. A batch FCM refuses as a
+// whole counts as failed for each of its tokens. If FCM rejects our
+// credentials, the tokens sent so far are returned with ErrProviderAuth. On a
+// quota error, sending stops after the current batch and the tokens sent so
+// far are returned with ErrProviderThrottled; the quota-rejected tokens and
+// any not attempted still need sending once the pause is over.
+func (f *fcmClient) SendMulticast(ctx context.Context, deviceTokens []string, notification models.PushNotification) (*messaging.BatchResponse, error) {
+	return f.sendMulticast(ctx, deviceTokens, notification, false)
+}
+
+// SendMulticastDryRun is SendMulticast with FCM's validate_only flag: FCM
+// checks the message and every token but delivers nothing
+func (f *fcmClient) SendMulticastDryRun(ctx context.Context, deviceTokens []string, notification models.PushNotification) (*messaging.BatchResponse, error) {
+	return f.sendMulticast(ctx, deviceTokens, notification, true)
+}
+
+func (f *fcmClient) sendMulticast(ctx context.Context, deviceTokens []string, notification models.PushNotification, dryRun bool) (*messaging.BatchResponse, error) {
+	message := newMulticastMessage(notification)
+	send := f.messaging().SendEachForMulticast
+	if dryRun {
+		send = f.messaging().SendEachForMulticastDryRun
 	}
 
-	// For multiple devices, send individually for better error tracking
-	successCount := 0
-	failureCount := 0
+	ctx, span := tracing.Tracer().Start(ctx, "fcm.send_multicast",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.Int("fcm.device_count", len(deviceTokens)),
+			attribute.Bool("fcm.dry_run", dryRun),
+		),
+	)
+	defer span.End()
 
-	for _, token := range deviceTokens {
-		message := &messaging.Message{
-			Token:        token,
-			Notification: msgNotification,
-			Data:         data,
+	response := &messaging.BatchResponse{Responses: make([]*messaging.SendResponse, 0, len(deviceTokens))}
+	for start := 0; start < len(deviceTokens); start += multicastBatchSize {
+		if err := f.throttled(); err != nil {
+			tracing.RecordError(span, err)
+			return response, err
 		}
 
-		// Add webpush config for web notifications
-		if notification.Image != nil || notification.Link != nil {
-			webpushConfig := &messaging.WebpushConfig{
-				Headers: map[string]string{
-					"Urgency": "high",
-				},
-			}
-
-			if notification.Image != nil || notification.Link != nil {
-				webpushNotification := &messaging.WebpushNotification{
-					Title: notification.Title,
-					Body:  notification.Body,
-				}
-				if notification.Image != nil && *notification.Image != "" {
-					webpushNotification.Icon = *notification.Image
-					webpushNotification.Image = *notification.Image
+		tokens := deviceTokens[start:min(start+multicastBatchSize, len(deviceTokens))]
+		if err := f.pacer.Wait(ctx, len(tokens)); err != nil {
+			tracing.RecordError(span, err)
+			return response, err
+		}
+		message.Tokens = tokens
+
+		batch, err := send(ctx, message)
+		if err == nil {
+			failed := 0
+			for _, resp := range batch.Responses {
+				if providerFailure(resp.Error) {
+					failed++
 				}
-				// Link is handled via data payload for web push
-				webpushConfig.Notification = webpushNotification
 			}
-			message.Webpush = webpushConfig
+			f.failures.record(len(tokens), failed)
+		} else {
+			failed := 0
+			if providerFailure(err) {
+				failed = len(tokens)
+			}
+			f.failures.record(len(tokens), failed)
 		}
-
-		_, err := f.client.Send(ctx, message)
 		if err != nil {
-			zap.L().Error("Failed to send FCM message to device",
-				zap.String("token", token),
+			zap.L().Error("Failed to send multicast FCM batch",
+				zap.Int("device_count", len(tokens)),
 				zap.Error(err),
 			)
-			failureCount++
-			continue
+			if err := f.observe(err); errors.Is(err, ErrProviderAuth) || errors.Is(err, ErrProviderThrottled) {
+				tracing.RecordError(span, err)
+				return response, err
+			}
+			batch = &messaging.BatchResponse{FailureCount: len(tokens)}
+			for range tokens {
+				batch.Responses = append(batch.Responses, &messaging.SendResponse{Error: err})
+			}
+		}
+
+		// A refused batch was logged and observed above
+		var stopErr error
+		for i, resp := range batch.Responses {
+			if resp.Error == nil || err != nil {
+				continue
+			}
+			zap.L().Warn("Individual FCM send failed",
+				zap.String("token", tokens[i]),
+				zap.Error(resp.Error),
+			)
+			if err := f.observe(resp.Error); errors.Is(err, ErrProviderAuth) {
+				stopErr = err
+			} else if errors.Is(err, ErrProviderThrottled) && stopErr == nil {
+				stopErr = err
+			}
 		}
 
-		successCount++
+		response.SuccessCount += batch.SuccessCount
+		response.FailureCount += batch.FailureCount
+		response.Responses = append(response.Responses, batch.Responses...)
+		if stopErr != nil {
+			// Every remaining batch would fail the same way
+			tracing.RecordError(span, stopErr)
+			return response, stopErr
+		}
 	}
 
-	zap.L().Info("Batch FCM messages completed",
-		zap.Int("success_count", successCount),
-		zap.Int("failure_count", failureCount),
+	span.SetAttributes(attribute.Int("fcm.failure_count", response.FailureCount))
+	zap.L().Info("Multicast FCM messages completed",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("success_count", response.SuccessCount),
+		zap.Int("failure_count", response.FailureCount),
 		zap.Int("total", len(deviceTokens)),
 	)
-
-	return successCount, failureCount, nil
+	return response, nil
 }
 
-func (f *fcmClient) SendMulticast(ctx context.Context, deviceTokens []string, notification models.PushNotification) (*messaging.BatchResponse, error) {
-	// Convert map[string]any to map[string]string for FCM
-	data := convertDataToStringMap(notification.Data)
-
-	// Add link to data if provided
-	if notification.Link != nil && *notification.Link != "" {
-		if data == nil {
-			data = make(map[string]string)
-		}
-		data["link"] = *notification.Link
-		data["click_action"] = *notification.Link
-	}
+// newMulticastMessage builds the FCM message for notification, without tokens
+func newMulticastMessage(notification models.PushNotification) *messaging.MulticastMessage {
+	data := messageData(notification)
 
 	msgNotification := &messaging.Notification{
 		Title: notification.Title,
@@ -228,60 +587,188 @@ func (f *fcmClient) SendMulticast(ctx context.Context, deviceTokens []string, no
 		msgNotification.ImageURL = *notification.Image
 	}
 
-	// For web push, we need to configure it properly
-	webpushConfig := &messaging.WebpushConfig{
+	message := &messaging.MulticastMessage{
+		Notification: msgNotification,
+		Data:         data,
+	}
+
+	// Add webpush config for web notifications
+	message.Webpush = webpushConfig(notification, true)
+	message.Android = androidConfig(notification, true)
+	message.APNS = apnsConfig(notification)
+	message.FCMOptions = fcmOptions(notification)
+	applyOverrides(notification, &message.Android, &message.APNS, &message.Webpush)
+
+	return message
+}
+
+// webpushConfig returns the web push settings of a notification, or nil if
+// it has none. visible is false for silent data messages.
+func webpushConfig(notification models.PushNotification, visible bool) *messaging.WebpushConfig {
+	collapseKey := notification.CollapseKey != nil && *notification.CollapseKey != ""
+	withNotification := visible && (notification.Image != nil || notification.Link != nil || collapseKey || len(notification.Actions) > 0)
+	if !withNotification && notification.ExpiresAt == nil {
+		return nil
+	}
+
+	config := &messaging.WebpushConfig{
 		Headers: map[string]string{
 			"Urgency": "high",
 		},
 	}
+	if notification.ExpiresAt != nil {
+		config.Headers["TTL"] = strconv.Itoa(int(remainingTTL(*notification.ExpiresAt).Seconds()))
+	}
+	if !withNotification {
+		return config
+	}
 
 	webpushNotification := &messaging.WebpushNotification{
 		Title: notification.Title,
 		Body:  notification.Body,
 	}
-
 	if notification.Image != nil && *notification.Image != "" {
 		webpushNotification.Icon = *notification.Image
 		webpushNotification.Image = *notification.Image
 	}
-	// Link is handled via data payload for web push
+	if collapseKey {
+		// A notification with the same tag replaces the one shown
+		webpushNotification.Tag = *notification.CollapseKey
+	}
+	for _, action := range notification.Actions {
+		webpushAction := &messaging.WebpushNotificationAction{
+			Action: action.ID,
+			Title:  action.Title,
+		}
+		if action.Icon != nil {
+			webpushAction.Icon = *action.Icon
+		}
+		webpushNotification.Actions = append(webpushNotification.Actions, webpushAction)
+	}
+	config.Notification = webpushNotification
 
-	webpushConfig.Notification = webpushNotification
+	// FCM opens the link on click only for HTTPS URLs; other links (e.g.
+	// app deep links) are left to the service worker through the data
+	if notification.Link != nil && strings.HasPrefix(*notification.Link, "https://") {
+		config.FCMOptions = &messaging.WebpushFCMOptions{Link: *notification.Link}
+	}
+	return config
+}
 
-	message := &messaging.MulticastMessage{
-		Tokens:       deviceTokens,
-		Notification: msgNotification,
-		Data:         data,
-		Webpush:      webpushConfig,
+// androidConfig returns the Android settings of a notification, or nil if it
+// has none. visible is false for silent data messages.
+func androidConfig(notification models.PushNotification, visible bool) *messaging.AndroidConfig {
+	collapseKey := notification.CollapseKey != nil && *notification.CollapseKey != ""
+	clickAction := visible && notification.ClickAction != nil && *notification.ClickAction != ""
+	if !collapseKey && !clickAction && notification.ExpiresAt == nil {
+		return nil
 	}
 
-	response, err := f.client.SendMulticast(ctx, message)
-	if err != nil {
-		zap.L().Error("Failed to send multicast FCM message",
-			zap.Int("device_count", len(deviceTokens)),
-			zap.Error(err),
-		)
-		return nil, err
+	config := &messaging.AndroidConfig{}
+	if visible && (collapseKey || clickAction) {
+		config.Notification = &messaging.AndroidNotification{}
 	}
+	if collapseKey {
+		// FCM keeps only the latest message of a collapse key while the
+		// device is offline, and the tag replaces the notification shown
+		config.CollapseKey = *notification.CollapseKey
+		if visible {
+			config.Notification.Tag = *notification.CollapseKey
+		}
+	}
+	if clickAction {
+		// The activity with a matching intent filter opens on tap
+		config.Notification.ClickAction = *notification.ClickAction
+	}
+	if notification.ExpiresAt != nil {
+		ttl := remainingTTL(*notification.ExpiresAt)
+		config.TTL = &ttl
+	}
+	return config
+}
 
-	// Log detailed results
-	if response.FailureCount > 0 {
-		for i, resp := range response.Responses {
-			if resp.Error != nil {
-				zap.L().Warn("Individual FCM send failed",
-					zap.String("token", deviceTokens[i]),
-					zap.Error(resp.Error),
-				)
-			}
+// apnsConfig returns the APNs settings of a notification, or nil if it has
+// none
+func apnsConfig(notification models.PushNotification) *messaging.APNSConfig {
+	headers := make(map[string]string)
+	if notification.CollapseKey != nil && *notification.CollapseKey != "" {
+		headers["apns-collapse-id"] = *notification.CollapseKey
+	}
+	if notification.ExpiresAt != nil {
+		headers["apns-expiration"] = strconv.FormatInt(notification.ExpiresAt.Unix(), 10)
+	}
+	if len(headers) == 0 && notification.IOS == nil {
+		return nil
+	}
+
+	config := &messaging.APNSConfig{}
+	if len(headers) > 0 {
+		config.Headers = headers
+	}
+	if ios := notification.IOS; ios != nil {
+		config.Payload = &messaging.APNSPayload{
+			Aps: &messaging.Aps{
+				Badge:    ios.Badge,
+				Sound:    ios.Sound,
+				Category: ios.Category,
+				ThreadID: ios.ThreadID,
+			},
 		}
 	}
+	return config
+}
 
-	zap.L().Info("Multicast FCM messages completed",
-		zap.Int("success_count", response.SuccessCount),
-		zap.Int("failure_count", response.FailureCount),
-		zap.Int("total", len(deviceTokens)),
-	)
-	return response, nil
+// fcmOptions returns the FCM options of a notification, or nil if it has
+// none. The analytics label applies to every platform.
+func fcmOptions(notification models.PushNotification) *messaging.FCMOptions {
+	if notification.AnalyticsLabel == nil || *notification.AnalyticsLabel == "" {
+		return nil
+	}
+	return &messaging.FCMOptions{AnalyticsLabel: *notification.AnalyticsLabel}
+}
+
+// remainingTTL returns how long FCM may keep trying to deliver a notification
+// that expires at expiresAt. FCM drops a message with a zero TTL unless it
+// can deliver it right away.
+func remainingTTL(expiresAt time.Time) time.Duration {
+	ttl := time.Until(expiresAt).Truncate(time.Second)
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// messageData returns the data payload of notification: its data plus the
+// link, click action and action buttons, for apps to handle themselves
+func messageData(notification models.PushNotification) map[string]string {
+	// Convert map[string]any to map[string]string for FCM
+	data := convertDataToStringMap(notification.Data)
+	set := func(key, value string) {
+		if data == nil {
+			data = make(map[string]string)
+		}
+		data[key] = value
+	}
+
+	// Add link to data if provided
+	if notification.Link != nil && *notification.Link != "" {
+		set("link", *notification.Link)
+		set("click_action", *notification.Link)
+	}
+	if notification.ClickAction != nil && *notification.ClickAction != "" {
+		set("click_action", *notification.ClickAction)
+	}
+
+	// Android and iOS have no action buttons in FCM's notification payload,
+	// so apps render them from the data
+	if len(notification.Actions) > 0 {
+		actions, err := json.Marshal(notification.Actions)
+		if err == nil {
+			set("actions", string(actions))
+		}
+	}
+
+	return data
 }
 
 // convertDataToStringMap converts map[string]any to map[string]string
@@ -329,6 +816,10 @@ func (f *fcmClient) ValidateToken(ctx context.Context, deviceToken string) error
 
 	// Attempt to send - if it fails with certain errors, token is invalid
 	err := f.Send(validationCtx, deviceToken, testNotification)
+	if errors.Is(err, ErrProviderAuth) || errors.Is(err, ErrProviderThrottled) {
+		// Our credentials or quota are the problem, not the token
+		return nil
+	}
 	if err != nil {
 		// Check for specific invalid token errors
 		errStr := strings.ToLower(err.Error())
@@ -339,18 +830,10 @@ func (f *fcmClient) ValidateToken(ctx context.Context, deviceToken string) error
 		// For other errors (network, etc.), we consider the token potentially valid
 		// since the error might be transient
 		zap.L().Debug("Token validation encountered non-fatal error",
-			zap.String("token", maskToken(deviceToken)),
+			zap.String("token", deviceToken),
 			zap.Error(err),
 		)
 	}
 
 	return nil
 }
-
-// maskToken masks a token for logging (shows first 10 and last 10 chars)
-func maskToken(token string) string {
-	if len(token) <= 20 {
-		return "***"
-	}
-	return token[:10] + "..." + token[len(token)-10:]
-}
@@ -0,0 +1,371 @@
+// Package fcmtest runs a fake FCM v1 API on a local httptest server, so
+// tests can send through the Firebase SDK and the fcm client, worker
+// included, without network access or credentials.
+//
+//	srv := fcmtest.NewServer()
+//	defer srv.Close()
+//	srv.FailToken("stale-token", fcmtest.Unregistered)
+//	srv.FailNext(fcmtest.QuotaExceeded)
+//	client, err := fcm.NewFCMClient(srv.Config(), nil)
+//	...
+//	sends := srv.Sends()
+//
+// It serves messages:send, which the fcm client's multicast sends call once
+// per token through the SDK's SendEachForMulticast, and answers with FCM's
+// error format, so the SDK's error helpers and fcm.ClassifyError see the
+// same errors as from FCM. Multipart batch requests of the SDK's older
+// batch API are answered too.
+package fcmtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+)
+
+// ProjectID is the project the config of Server.Config sends to
+const ProjectID = "fcmtest"
+
+// Failure is an error the server answers a send with
+type Failure struct {
+	// Status is the HTTP status, and ErrStatus the error's canonical
+	// status, e.g. NOT_FOUND
+	Status    int
+	ErrStatus string
+	// ErrorCode is the FcmError code the SDK reads, e.g. UNREGISTERED, or
+	// "" for none
+	ErrorCode string
+	Message   string
+	// RetryAfter sets the Retry-After header if positive
+	RetryAfter time.Duration
+}
+
+// FCM's errors. The SDK itself retries 500 and 503 responses up to 4 times
+// with backoff, so a send failing with Internal or Unavailable every time
+// takes a few seconds to give up.
+var (
+	Unregistered = Failure{
+		Status:    http.StatusNotFound,
+		ErrStatus: "NOT_FOUND",
+		ErrorCode: "UNREGISTERED",
+		Message:   "Requested entity was not found.",
+	}
+	InvalidToken = Failure{
+		Status:    http.StatusBadRequest,
+		ErrStatus: "INVALID_ARGUMENT",
+		ErrorCode: "INVALID_ARGUMENT",
+		Message:   "The registration token is not a valid FCM registration token",
+	}
+	InvalidArgument = Failure{
+		Status:    http.StatusBadRequest,
+		ErrStatus: "INVALID_ARGUMENT",
+		ErrorCode: "INVALID_ARGUMENT",
+		Message:   "Request contains an invalid argument.",
+	}
+	SenderIDMismatch = Failure{
+		Status:    http.StatusForbidden,
+		ErrStatus: "PERMISSION_DENIED",
+		ErrorCode: "SENDER_ID_MISMATCH",
+		Message:   "SenderId mismatch",
+	}
+	ThirdPartyAuthError = Failure{
+		Status:    http.StatusUnauthorized,
+		ErrStatus: "UNAUTHENTICATED",
+		ErrorCode: "THIRD_PARTY_AUTH_ERROR",
+		Message:   "Auth error from APNS or Web Push Service",
+	}
+	QuotaExceeded = Failure{
+		Status:     http.StatusTooManyRequests,
+		ErrStatus:  "RESOURCE_EXHAUSTED",
+		ErrorCode:  "QUOTA_EXCEEDED",
+		Message:    "Quota exceeded for quota metric 'Send requests'.",
+		RetryAfter: time.Second,
+	}
+	Unavailable = Failure{
+		Status:    http.StatusServiceUnavailable,
+		ErrStatus: "UNAVAILABLE",
+		ErrorCode: "UNAVAILABLE",
+		Message:   "The service is currently unavailable.",
+	}
+	Internal = Failure{
+		Status:    http.StatusInternalServerError,
+		ErrStatus: "INTERNAL",
+		ErrorCode: "INTERNAL",
+		Message:   "Internal error encountered.",
+	}
+)
+
+// Send is a send the server received
+type Send struct {
+	ProjectID string
+	Message   Message
+	DryRun    bool
+	// Batch is set for sends that came in a batch request
+	Batch bool
+	// MessageID is what the server answered, or "" if it failed the send
+	// with ErrorCode
+	MessageID string
+	ErrorCode string
+}
+
+// Message is the part of an FCM v1 message the server reads. Raw has all of
+// it, as the SDK sent it.
+type Message struct {
+	Token        string            `json:"token"`
+	Topic        string            `json:"topic"`
+	Condition    string            `json:"condition"`
+	Data         map[string]string `json:"data"`
+	Notification *struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+		Image string `json:"image"`
+	} `json:"notification"`
+	Raw json.RawMessage `json:"-"`
+}
+
+// Server is a fake FCM v1 API. Every send succeeds unless a failure was set
+// up for it.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	sends    []Send
+	tokens   map[string]Failure // by token, until Reset
+	next     []Failure          // for the next sends, whatever their token
+	sequence int
+}
+
+// NewServer starts a server. Close it when done.
+func NewServer() *Server {
+	s := &Server{tokens: make(map[string]Failure)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Config returns an FCM config that sends to the server, with no
+// credentials
+func (s *Server) Config() *config.FCMConfig {
+	return &config.FCMConfig{
+		Mode:           config.FCMModeLive,
+		ProjectID:      ProjectID,
+		Endpoint:       s.URL + "/v1",
+		MaxPayloadSize: 4096,
+	}
+}
+
+// FailToken fails every send to token with f
+func (s *Server) FailToken(token string, f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = f
+}
+
+// FailNext fails the next sends with failures, one each, in order. They
+// take precedence over FailToken.
+func (s *Server) FailNext(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next = append(s.next, failures...)
+}
+
+// Sends returns the sends the server received, oldest first
+func (s *Server) Sends() []Send {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Send(nil), s.sends...)
+}
+
+// Reset forgets the sends and the failures set up
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sends = nil
+	s.tokens = make(map[string]Failure)
+	s.next = nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeResponse(w, errorResponse(Failure{
+			Status:    http.StatusMethodNotAllowed,
+			ErrStatus: "INVALID_ARGUMENT",
+			Message:   "Only POST is supported",
+		}))
+		return
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/mixed" {
+		s.serveBatch(w, r, params["boundary"])
+		return
+	}
+	writeResponse(w, s.send(r, false))
+}
+
+// response is an answer to a single send
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func writeResponse(w http.ResponseWriter, resp response) {
+	for key, values := range resp.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// send answers POST .../projects/{project}/messages:send
+func (s *Server) send(r *http.Request, batch bool) response {
+	path := r.URL.Path
+	start := strings.Index(path, "/projects/")
+	project, isSend := strings.CutSuffix(path[start+1:], "/messages:send")
+	if start < 0 || !isSend {
+		return errorResponse(Failure{
+			Status:    http.StatusNotFound,
+			ErrStatus: "NOT_FOUND",
+			Message:   "fcmtest only serves messages:send and batches of it",
+		})
+	}
+	project = strings.TrimPrefix(project, "projects/")
+
+	var req struct {
+		ValidateOnly bool            `json:"validate_only"`
+		Message      json.RawMessage `json:"message"`
+	}
+	var message Message
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	if err == nil {
+		err = json.Unmarshal(req.Message, &message)
+	}
+	if err != nil {
+		return errorResponse(Failure{
+			Status:    http.StatusBadRequest,
+			ErrStatus: "INVALID_ARGUMENT",
+			ErrorCode: "INVALID_ARGUMENT",
+			Message:   "Invalid JSON payload: " + err.Error(),
+		})
+	}
+	message.Raw = req.Message
+
+	send := Send{
+		ProjectID: project,
+		Message:   message,
+		DryRun:    req.ValidateOnly,
+		Batch:     batch,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failure, failed := s.tokens[message.Token]
+	if len(s.next) > 0 {
+		failure, failed = s.next[0], true
+		s.next = s.next[1:]
+	}
+	if failed {
+		send.ErrorCode = failure.ErrorCode
+		if send.ErrorCode == "" {
+			send.ErrorCode = failure.ErrStatus
+		}
+		s.sends = append(s.sends, send)
+		return errorResponse(failure)
+	}
+
+	s.sequence++
+	send.MessageID = fmt.Sprintf("projects/%s/messages/0:%d%%fcmtest", project, s.sequence)
+	s.sends = append(s.sends, send)
+	body, _ = json.Marshal(map[string]string{"name": send.MessageID})
+	return response{
+		status: http.StatusOK,
+		header: http.Header{"Content-Type": []string{"application/json; charset=UTF-8"}},
+		body:   body,
+	}
+}
+
+// errorResponse builds FCM's answer for f
+func errorResponse(f Failure) response {
+	details := []map[string]string{}
+	if f.ErrorCode != "" {
+		details = append(details, map[string]string{
+			"@type":     "type.googleapis.com/google.firebase.fcm.v1.FcmError",
+			"errorCode": f.ErrorCode,
+		})
+	}
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"code":    f.Status,
+			"message": f.Message,
+			"status":  f.ErrStatus,
+			"details": details,
+		},
+	})
+
+	header := http.Header{"Content-Type": []string{"application/json; charset=UTF-8"}}
+	if f.RetryAfter > 0 {
+		header.Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+	}
+	return response{status: f.Status, header: header, body: body}
+}
+
+// serveBatch answers a multipart/mixed batch of sends, each part an HTTP
+// request, with a part holding each one's HTTP response, in order
+func (s *Server) serveBatch(w http.ResponseWriter, r *http.Request, boundary string) {
+	var responses []response
+	reader := multipart.NewReader(r.Body, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		var req *http.Request
+		if err == nil {
+			req, err = http.ReadRequest(bufio.NewReader(part))
+		}
+		if err != nil {
+			writeResponse(w, errorResponse(Failure{
+				Status:    http.StatusBadRequest,
+				ErrStatus: "INVALID_ARGUMENT",
+				Message:   "Invalid batch request: " + err.Error(),
+			}))
+			return
+		}
+		responses = append(responses, s.send(req, true))
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, resp := range responses {
+		part, _ := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type": []string{"application/http"},
+			"Content-Id":   []string{fmt.Sprintf("response-%d", i+1)},
+		})
+		fmt.Fprintf(part, "HTTP/1.1 %d %s\r\n", resp.status, http.StatusText(resp.status))
+		resp.header.Write(part)
+		fmt.Fprintf(part, "Content-Length: %d\r\n\r\n", len(resp.body))
+		part.Write(resp.body)
+	}
+	writer.Close()
+
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,90 @@
+package fcmtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"push-service/internal/models"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/platform/fcm/fcmtest"
+)
+
+func newClient(t *testing.T, srv *fcmtest.Server) fcm.FCMClient {
+	t.Helper()
+	client, err := fcm.NewFCMClient(srv.Config(), nil)
+	if err != nil {
+		t.Fatalf("NewFCMClient: %v", err)
+	}
+	return client
+}
+
+func TestUnregisteredTokenIsRemoved(t *testing.T) {
+	srv := fcmtest.NewServer()
+	defer srv.Close()
+	srv.FailToken("stale-token", fcmtest.Unregistered)
+	client := newClient(t, srv)
+
+	response, err := client.SendMulticast(context.Background(), []string{"good-token", "stale-token"}, models.PushNotification{Title: "Hi", Body: "There"})
+	if err != nil {
+		t.Fatalf("SendMulticast: %v", err)
+	}
+	if response.SuccessCount != 1 || response.FailureCount != 1 {
+		t.Fatalf("got %d delivered and %d failed, want 1 and 1", response.SuccessCount, response.FailureCount)
+	}
+
+	good, stale := response.Responses[0], response.Responses[1]
+	if !good.Success || good.MessageID == "" {
+		t.Errorf("good-token: got success %v with message ID %q, want a delivery", good.Success, good.MessageID)
+	}
+	if kind := fcm.ClassifyError(stale.Error); kind != fcm.ErrorKindUnregistered {
+		t.Errorf("stale-token: got error kind %q, want %q", kind, fcm.ErrorKindUnregistered)
+	}
+	// The worker unregisters the tokens IsInvalidToken reports
+	if !fcm.IsInvalidToken(stale.Error) {
+		t.Errorf("stale-token: IsInvalidToken(%v) = false, want the token removed", stale.Error)
+	}
+	if status := client.Status(); status.Status != fcm.ProviderStatusOK {
+		t.Errorf("got provider status %q, want %q", status.Status, fcm.ProviderStatusOK)
+	}
+}
+
+func TestQuotaExceededHonorsRetryAfter(t *testing.T) {
+	srv := fcmtest.NewServer()
+	defer srv.Close()
+	srv.FailNext(fcmtest.QuotaExceeded)
+	client := newClient(t, srv)
+	ctx := context.Background()
+	tokens := []string{"token"}
+	notification := models.PushNotification{Title: "Hi", Body: "There"}
+
+	_, err := client.SendMulticast(ctx, tokens, notification)
+	if !errors.Is(err, fcm.ErrProviderThrottled) {
+		t.Fatalf("first send: got %v, want ErrProviderThrottled", err)
+	}
+	status := client.Status()
+	if status.ThrottledUntil == nil {
+		t.Fatal("got no throttled_until after a 429")
+	}
+	if wait := time.Until(*status.ThrottledUntil); wait <= 0 || wait > fcmtest.QuotaExceeded.RetryAfter {
+		t.Fatalf("got a pause of %s, want at most the Retry-After of %s", wait, fcmtest.QuotaExceeded.RetryAfter)
+	}
+
+	// Sends before Retry-After has passed are held back without calling FCM
+	if _, err := client.SendMulticast(ctx, tokens, notification); !errors.Is(err, fcm.ErrProviderThrottled) {
+		t.Fatalf("send during the pause: got %v, want ErrProviderThrottled", err)
+	}
+	if sends := srv.Sends(); len(sends) != 1 {
+		t.Fatalf("got %d sends to FCM during the pause, want only the throttled one", len(sends))
+	}
+
+	time.Sleep(time.Until(*status.ThrottledUntil) + 10*time.Millisecond)
+	response, err := client.SendMulticast(ctx, tokens, notification)
+	if err != nil {
+		t.Fatalf("retry after Retry-After: %v", err)
+	}
+	if response.SuccessCount != 1 {
+		t.Fatalf("retry after Retry-After: got %d delivered, want 1", response.SuccessCount)
+	}
+}
@@ -0,0 +1,90 @@
+package fcm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"push-service/internal/models"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// ValidateOverrides checks that overrides can be merged into FCM's platform
+// configs, so a send is rejected up front instead of failing in the worker
+func ValidateOverrides(overrides *models.PlatformOverrides) error {
+	if overrides == nil {
+		return nil
+	}
+	if _, err := mergeOverride[messaging.AndroidConfig](nil, overrides.Android); err != nil {
+		return fmt.Errorf("android: %w", err)
+	}
+	if _, err := mergeOverride[messaging.APNSConfig](nil, overrides.APNs); err != nil {
+		return fmt.Errorf("apns: %w", err)
+	}
+	if _, err := mergeOverride[messaging.WebpushConfig](nil, overrides.Webpush); err != nil {
+		return fmt.Errorf("webpush: %w", err)
+	}
+	return nil
+}
+
+// applyOverrides merges notification's platform overrides into the configs
+// built from its fields. Overrides were validated when the notification was
+// sent, so a config that fails to merge is kept as built.
+func applyOverrides(notification models.PushNotification, android **messaging.AndroidConfig, apns **messaging.APNSConfig, webpush **messaging.WebpushConfig) {
+	overrides := notification.PlatformOverrides
+	if overrides == nil {
+		return
+	}
+	if config, err := mergeOverride(*android, overrides.Android); err == nil {
+		*android = config
+	}
+	if config, err := mergeOverride(*apns, overrides.APNs); err == nil {
+		*apns = config
+	}
+	if config, err := mergeOverride(*webpush, overrides.Webpush); err == nil {
+		*webpush = config
+	}
+}
+
+// mergeOverride deep merges override, in FCM v1 JSON, into config: objects
+// are merged key by key and anything else replaces what config has. config
+// may be nil.
+func mergeOverride[T any](config *T, override map[string]any) (*T, error) {
+	if len(override) == 0 {
+		return config, nil
+	}
+
+	base := make(map[string]any)
+	if config != nil {
+		encoded, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(encoded, &base); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := json.Marshal(deepMerge(base, override))
+	if err != nil {
+		return nil, err
+	}
+	merged := new(T)
+	if err := json.Unmarshal(encoded, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+func deepMerge(base, override map[string]any) map[string]any {
+	for key, value := range override {
+		overrideObject, ok := value.(map[string]any)
+		baseObject, isObject := base[key].(map[string]any)
+		if ok && isObject {
+			base[key] = deepMerge(baseObject, overrideObject)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
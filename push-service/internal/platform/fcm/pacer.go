@@ -0,0 +1,75 @@
+package fcm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"push-service/pkg/metrics"
+)
+
+// pacer is a leaky bucket: messages leave at a steady rate, one every
+// interval, however fast sends arrive. A send waits until its messages'
+// turn; a multicast batch takes as many turns as it has tokens.
+type pacer struct {
+	mu       sync.Mutex
+	interval time.Duration // 0 doesn't pace
+	next     time.Time     // when the next message may leave
+}
+
+// newPacer returns a pacer letting out rate messages per second; it doesn't
+// pace if rate isn't positive
+func newPacer(rate float64) *pacer {
+	p := &pacer{}
+	p.SetRate(rate)
+	return p
+}
+
+// SetRate changes the messages let out per second; a rate that isn't
+// positive stops pacing. Turns already taken keep their time.
+func (p *pacer) SetRate(rate float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if rate <= 0 {
+		p.interval = 0
+		return
+	}
+	p.interval = time.Duration(float64(time.Second) / rate)
+}
+
+// Wait blocks until n messages may be sent, or until ctx is done. The turns
+// of a send given up on stay taken, which only slows later sends slightly.
+func (p *pacer) Wait(ctx context.Context, n int) error {
+	if p == nil || n <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	if p.interval == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if p.next.Before(now) {
+		// Idle time isn't saved up for a burst
+		p.next = now
+	}
+	at := p.next
+	p.next = p.next.Add(time.Duration(n) * p.interval)
+	p.mu.Unlock()
+
+	wait := at.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+	metrics.FCMPacingWait.Add(wait.Seconds())
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package fcm
+
+import (
+	"encoding/json"
+
+	"push-service/internal/models"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// MaxPayloadSize is the largest payload FCM accepts for a message, in bytes
+const MaxPayloadSize = 4096
+
+// PayloadSize returns the size of notification's payload as FCM counts it
+// against MaxPayloadSize: its notification and data, as they are sent
+func PayloadSize(notification models.PushNotification) int {
+	message := newMulticastMessage(notification)
+	payload, err := json.Marshal(struct {
+		Notification *messaging.Notification `json:"notification,omitempty"`
+		Data         map[string]string       `json:"data,omitempty"`
+	}{message.Notification, message.Data})
+	if err != nil {
+		return 0
+	}
+	return len(payload)
+}
+
+// DataSizes returns the size each of notification's data keys adds to its
+// payload
+func DataSizes(notification models.PushNotification) map[string]int {
+	data := convertDataToStringMap(notification.Data)
+	sizes := make(map[string]int, len(data))
+	for key, value := range data {
+		encoded, err := json.Marshal(map[string]string{key: value})
+		if err != nil {
+			continue
+		}
+		sizes[key] = len(encoded) - 1 // the braces, less the comma between keys
+	}
+	return sizes
+}
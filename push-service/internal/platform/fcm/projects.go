@@ -0,0 +1,84 @@
+package fcm
+
+import (
+	"fmt"
+	"push-service/internal/chaos"
+	"push-service/internal/config"
+)
+
+// Projects holds the primary FCM client and one client per failover
+// project, with each project's daily send quota.
+type Projects struct {
+	primaryID string
+	failover  []string
+	clients   map[string]FCMClient
+	quotas    map[string]int
+}
+
+// NewProjects wraps the primary client and connects the failover projects
+// configured in cfg, injecting faults into their sends too.
+func NewProjects(primary FCMClient, cfg *config.FCMConfig, faults *chaos.Faults) (*Projects, error) {
+	p := &Projects{
+		primaryID: cfg.ProjectID,
+		clients:   map[string]FCMClient{cfg.ProjectID: primary},
+		quotas:    map[string]int{cfg.ProjectID: cfg.DailyQuota},
+	}
+
+	for i := range cfg.FailoverProjects {
+		projectCfg := &cfg.FailoverProjects[i]
+		if cfg.Mode == config.FCMModeSandbox {
+			// Failover projects are simulated too
+			sandboxCfg := *projectCfg
+			sandboxCfg.Mode, sandboxCfg.Sandbox = cfg.Mode, cfg.Sandbox
+			projectCfg = &sandboxCfg
+		}
+		if _, ok := p.clients[projectCfg.ProjectID]; ok {
+			return nil, fmt.Errorf("duplicate FCM project %s", projectCfg.ProjectID)
+		}
+		client, err := NewFCMClient(projectCfg, faults)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize failover project %s: %w", projectCfg.ProjectID, err)
+		}
+		p.clients[projectCfg.ProjectID] = client
+		p.quotas[projectCfg.ProjectID] = projectCfg.DailyQuota
+		p.failover = append(p.failover, projectCfg.ProjectID)
+	}
+
+	return p, nil
+}
+
+// SetMaxSendRates applies the send rate caps in cfg to the primary project
+// and to the failover projects already connected
+func (p *Projects) SetMaxSendRates(cfg *config.FCMConfig) {
+	p.clients[p.primaryID].SetMaxSendRate(cfg.MaxSendRate)
+	for i := range cfg.FailoverProjects {
+		projectCfg := &cfg.FailoverProjects[i]
+		if client, ok := p.clients[projectCfg.ProjectID]; ok && projectCfg.ProjectID != p.primaryID {
+			client.SetMaxSendRate(projectCfg.MaxSendRate)
+		}
+	}
+}
+
+// Client returns the client for projectID, or the primary client if
+// projectID is empty or unknown.
+func (p *Projects) Client(projectID string) FCMClient {
+	if client, ok := p.clients[projectID]; ok {
+		return client
+	}
+	return p.clients[p.primaryID]
+}
+
+// PrimaryID returns the primary project's ID
+func (p *Projects) PrimaryID() string {
+	return p.primaryID
+}
+
+// Failover returns the failover project IDs in configured order
+func (p *Projects) Failover() []string {
+	return p.failover
+}
+
+// DailyQuota returns the project's messages-per-day limit; 0 is unlimited
+func (p *Projects) DailyQuota(projectID string) int {
+	return p.quotas[projectID]
+}
@@ -0,0 +1,221 @@
+package fcm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Sandbox stands in for FCM when FCM_MODE is sandbox. It answers the Firebase
+// SDK's FCM v1 send requests in process, so sends take the same path as in
+// live mode, pacing and error handling included, but reach no device. It
+// records the sends and fails the configured share of them with FCM's
+// errors.
+type Sandbox struct {
+	cfg config.SandboxConfig
+
+	mu    sync.Mutex
+	sends []SandboxSend // the most recent, oldest first
+	total int64
+}
+
+// SandboxSend is a send the sandbox received
+// @Description A send recorded by the FCM sandbox
+type SandboxSend struct {
+	ProjectID string `json:"project_id" example:"sandbox"`
+	// MessageID is what FCM would return; empty for failed sends
+	MessageID string `json:"message_id,omitempty" example:"projects/sandbox/messages/0:1700000000000000%abc"`
+	// Token is masked
+	Token     string            `json:"token,omitempty" example:"dGVzdFRva2...VuMTIzNDU2"`
+	Topic     string            `json:"topic,omitempty"`
+	Condition string            `json:"condition,omitempty"`
+	Title     string            `json:"title,omitempty" example:"Hello"`
+	Body      string            `json:"body,omitempty" example:"World"`
+	Data      map[string]string `json:"data,omitempty"`
+	DryRun    bool              `json:"dry_run" example:"false"`
+	// ErrorCode is the simulated FCM error, e.g. UNREGISTERED
+	ErrorCode string    `json:"error_code,omitempty" example:"UNREGISTERED"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// NewSandbox creates a sandbox that behaves as cfg says
+func NewSandbox(cfg *config.SandboxConfig) *Sandbox {
+	return &Sandbox{cfg: *cfg}
+}
+
+// Sends returns up to limit of the most recent sends, newest first, and how
+// many sends the sandbox received in total
+func (s *Sandbox) Sends(limit int) ([]SandboxSend, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit <= 0 || limit > len(s.sends) {
+		limit = len(s.sends)
+	}
+	sends := make([]SandboxSend, 0, limit)
+	for i := len(s.sends) - 1; i >= len(s.sends)-limit; i-- {
+		sends = append(sends, s.sends[i])
+	}
+	return sends, s.total
+}
+
+// Reset forgets the recorded sends
+func (s *Sandbox) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sends = nil
+	s.total = 0
+}
+
+func (s *Sandbox) record(send SandboxSend) {
+	maxRecorded := s.cfg.MaxRecorded
+	if maxRecorded <= 0 {
+		maxRecorded = 1000 // default
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	if len(s.sends) >= maxRecorded {
+		// Drop the oldest
+		s.sends = append(s.sends[:0], s.sends[len(s.sends)-maxRecorded+1:]...)
+	}
+	s.sends = append(s.sends, send)
+}
+
+// sandboxRequest is the part of an FCM v1 send request the sandbox reads
+type sandboxRequest struct {
+	ValidateOnly bool `json:"validate_only"`
+	Message      struct {
+		Token        string            `json:"token"`
+		Topic        string            `json:"topic"`
+		Condition    string            `json:"condition"`
+		Data         map[string]string `json:"data"`
+		Notification *struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		} `json:"notification"`
+	} `json:"message"`
+}
+
+// RoundTrip answers an FCM v1 request in process
+func (s *Sandbox) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, body := s.handle(req)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// handle answers POST /v1/projects/{project}/messages:send
+func (s *Sandbox) handle(req *http.Request) (int, []byte) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	project, ok := strings.CutPrefix(req.URL.Path, "/v1/projects/")
+	project, isSend := strings.CutSuffix(project, "/messages:send")
+	if !ok || !isSend || req.Method != http.MethodPost {
+		return sandboxError(http.StatusNotFound, "NOT_FOUND", "", "The sandbox only serves messages:send")
+	}
+
+	var sendReq sandboxRequest
+	if err := json.NewDecoder(req.Body).Decode(&sendReq); err != nil {
+		return sandboxError(http.StatusBadRequest, "INVALID_ARGUMENT", "INVALID_ARGUMENT", "Invalid JSON payload: "+err.Error())
+	}
+
+	if s.cfg.Latency > 0 {
+		select {
+		case <-time.After(s.cfg.Latency):
+		case <-req.Context().Done():
+			return sandboxError(http.StatusGatewayTimeout, "DEADLINE_EXCEEDED", "", "Request cancelled")
+		}
+	}
+
+	message := sendReq.Message
+	send := SandboxSend{
+		ProjectID: project,
+		Topic:     message.Topic,
+		Condition: message.Condition,
+		Data:      message.Data,
+		DryRun:    sendReq.ValidateOnly,
+		SentAt:    time.Now().UTC(),
+	}
+	if message.Token != "" {
+		send.Token = logger.MaskToken(message.Token)
+	}
+	if message.Notification != nil {
+		send.Title = message.Notification.Title
+		send.Body = message.Notification.Body
+	}
+
+	status, errStatus, errorCode, errMessage := s.roll()
+	if status != http.StatusOK {
+		send.ErrorCode = errorCode
+		if message.Token != probeToken {
+			s.record(send)
+		}
+		return sandboxError(status, errStatus, errorCode, errMessage)
+	}
+
+	send.MessageID = fmt.Sprintf("projects/%s/messages/%s", project, uuid.NewString())
+	if message.Token != probeToken {
+		s.record(send)
+	}
+	body, _ := json.Marshal(map[string]string{"name": send.MessageID})
+	return http.StatusOK, body
+}
+
+// roll picks the outcome of a send: the HTTP status and, for a failure, the
+// error's status, FCM error code and message
+func (s *Sandbox) roll() (int, string, string, string) {
+	r := rand.Float64()
+	switch {
+	case r < s.cfg.UnregisteredRate:
+		return http.StatusNotFound, "NOT_FOUND", "UNREGISTERED", "Requested entity was not found."
+	case r < s.cfg.UnregisteredRate+s.cfg.QuotaRate:
+		return http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "QUOTA_EXCEEDED", "Quota exceeded for quota metric 'Send requests'."
+	case r < s.cfg.UnregisteredRate+s.cfg.QuotaRate+s.cfg.FailureRate:
+		return http.StatusInternalServerError, "INTERNAL", "INTERNAL", "Internal error encountered."
+	}
+	return http.StatusOK, "", "", ""
+}
+
+// sandboxError builds an FCM v1 error response. errorCode goes in the
+// FcmError detail the SDK reads, if set.
+func sandboxError(status int, errStatus, errorCode, message string) (int, []byte) {
+	details := []map[string]string{}
+	if errorCode != "" {
+		details = append(details, map[string]string{
+			"@type":     "type.googleapis.com/google.firebase.fcm.v1.FcmError",
+			"errorCode": errorCode,
+		})
+	}
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"code":    status,
+			"message": message,
+			"status":  errStatus,
+			"details": details,
+		},
+	})
+	return status, body
+}
@@ -0,0 +1,175 @@
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"push-service/internal/config"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// GRPCMethod is the method called on gRPC resolvers. It takes the user ID as
+// a google.protobuf.StringValue and returns the tokens as a
+// google.protobuf.ListValue of strings, so resolvers need no generated code
+// from this service.
+const GRPCMethod = "/push.v1.UserResolver/ResolveTokens"
+
+// UserResolver looks up a user's push tokens in an external system, for users
+// with no devices registered here. A user it doesn't know resolves to no
+// tokens and no error.
+type UserResolver interface {
+	ResolveTokens(ctx context.Context, userID string) ([]string, error)
+	Close() error
+}
+
+// New returns the resolver for cfg.URL: http(s):// URLs are called over HTTP,
+// grpc:// and grpcs:// addresses over gRPC. It returns nil if no URL is
+// configured.
+func New(cfg *config.UserResolverConfig) (UserResolver, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user resolver url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return NewHTTPResolver(cfg), nil
+	case "grpc", "grpcs":
+		return NewGRPCResolver(u.Host, u.Scheme == "grpcs", cfg)
+	default:
+		return nil, fmt.Errorf("unsupported user resolver scheme %q", u.Scheme)
+	}
+}
+
+func timeout(cfg *config.UserResolverConfig) time.Duration {
+	if cfg.Timeout <= 0 {
+		return 2 * time.Second // default
+	}
+	return cfg.Timeout
+}
+
+// HTTPResolver calls GET <url>?user_id=<id>, which answers
+// {"tokens": ["..."]}, or 404 for an unknown user
+type HTTPResolver struct {
+	url       string
+	authToken string
+	client    *http.Client
+}
+
+func NewHTTPResolver(cfg *config.UserResolverConfig) *HTTPResolver {
+	return &HTTPResolver{
+		url:       cfg.URL,
+		authToken: cfg.AuthToken,
+		client:    &http.Client{Timeout: timeout(cfg)},
+	}
+}
+
+func (r *HTTPResolver) ResolveTokens(ctx context.Context, userID string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	query.Set("user_id", userID)
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("Accept", "application/json")
+	if r.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.authToken)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("user resolver request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("user resolver returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Tokens []string `json:"tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode user resolver response: %w", err)
+	}
+	return result.Tokens, nil
+}
+
+func (r *HTTPResolver) Close() error {
+	r.client.CloseIdleConnections()
+	return nil
+}
+
+// GRPCResolver calls GRPCMethod; NotFound means an unknown user
+type GRPCResolver struct {
+	conn      *grpc.ClientConn
+	authToken string
+	timeout   time.Duration
+}
+
+func NewGRPCResolver(target string, useTLS bool, cfg *config.UserResolverConfig) (*GRPCResolver, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user resolver client: %w", err)
+	}
+	return &GRPCResolver{
+		conn:      conn,
+		authToken: cfg.AuthToken,
+		timeout:   timeout(cfg),
+	}, nil
+}
+
+func (r *GRPCResolver) ResolveTokens(ctx context.Context, userID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if r.authToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+r.authToken)
+	}
+
+	var result structpb.ListValue
+	if err := r.conn.Invoke(ctx, GRPCMethod, wrapperspb.String(userID), &result); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("user resolver call failed: %w", err)
+	}
+
+	tokens := make([]string, 0, len(result.Values))
+	for _, value := range result.Values {
+		if token := value.GetStringValue(); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *GRPCResolver) Close() error {
+	return r.conn.Close()
+}
@@ -0,0 +1,205 @@
+package queue
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"push-service/pkg/metrics"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// ackState is where a delivery tracked by ackBatcher stands
+type ackState uint8
+
+const (
+	ackInFlight ackState = iota // not settled yet
+	ackPending                  // acked by the worker, not yet with the broker
+	ackSent                     // acked or nacked with the broker
+)
+
+type ackEntry struct {
+	tag   uint64
+	state ackState
+}
+
+// ackWindow tracks the deliveries of one channel, in delivery tag order,
+// from the oldest one not yet acked with the broker
+type ackWindow struct {
+	entries []ackEntry
+	pending int
+}
+
+// ackBatcher acknowledges RabbitMQ deliveries in batches. A multiple ack
+// acknowledges every delivery of its channel up to its tag, so it is only
+// sent for the oldest deliveries once all of them are settled; an ack behind
+// a delivery still being processed waits. Acks are sent once size of them
+// wait, and every interval, when those that still can't be covered by a
+// multiple ack are sent one by one. Nacks are always sent at once.
+//
+// Acks still waiting when a channel closes are lost, and the broker
+// redelivers their messages, as it does for messages being processed. Workers
+// already handle redeliveries, so batching keeps at-least-once delivery.
+type ackBatcher struct {
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	windows map[amqp.Acknowledger]*ackWindow
+	stop    chan struct{}
+	stopped sync.Once
+}
+
+func newAckBatcher(size int, interval time.Duration) *ackBatcher {
+	b := &ackBatcher{
+		size:     size,
+		interval: interval,
+		windows:  make(map[amqp.Acknowledger]*ackWindow),
+		stop:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// run sends the waiting acks every interval until close
+func (b *ackBatcher) run() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.flush()
+		}
+	}
+}
+
+// track records a delivery as it is received. Deliveries of a channel must
+// be tracked in the order they arrive.
+func (b *ackBatcher) track(msg amqp.Delivery) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	window, ok := b.windows[msg.Acknowledger]
+	if !ok {
+		window = &ackWindow{}
+		b.windows[msg.Acknowledger] = window
+	}
+	window.entries = append(window.entries, ackEntry{tag: msg.DeliveryTag})
+}
+
+// ack records that msg was acked by the worker, and sends the acks of its
+// channel once enough wait
+func (b *ackBatcher) ack(msg amqp.Delivery) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	window, entry := b.find(msg)
+	if entry == nil {
+		// Not tracked, e.g. its channel was dropped after an error
+		metrics.BrokerAcks.WithLabelValues("single").Inc()
+		return msg.Ack(false)
+	}
+	entry.state = ackPending
+	window.pending++
+	if window.pending < b.size {
+		return nil
+	}
+	return b.send(msg.Acknowledger, window, false)
+}
+
+// nacked records that msg was nacked with the broker
+func (b *ackBatcher) nacked(msg amqp.Delivery) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, entry := b.find(msg); entry != nil {
+		entry.state = ackSent
+	}
+}
+
+// flush sends every waiting ack, and forgets the channels with none left
+func (b *ackBatcher) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for acknowledger, window := range b.windows {
+		if err := b.send(acknowledger, window, true); err != nil {
+			zap.L().Warn("Failed to send batched acks, the broker will redeliver their messages", zap.Error(err))
+		}
+		if len(window.entries) == 0 {
+			delete(b.windows, acknowledger)
+		}
+	}
+}
+
+// close sends the waiting acks and stops the interval flushes
+func (b *ackBatcher) close() {
+	b.stopped.Do(func() { close(b.stop) })
+	b.flush()
+}
+
+// find returns msg's window and entry, or nils if it isn't tracked
+func (b *ackBatcher) find(msg amqp.Delivery) (*ackWindow, *ackEntry) {
+	window, ok := b.windows[msg.Acknowledger]
+	if !ok {
+		return nil, nil
+	}
+	i := sort.Search(len(window.entries), func(i int) bool {
+		return window.entries[i].tag >= msg.DeliveryTag
+	})
+	if i == len(window.entries) || window.entries[i].tag != msg.DeliveryTag {
+		return nil, nil
+	}
+	return window, &window.entries[i]
+}
+
+// send acks the settled deliveries at the start of window with one multiple
+// ack, and with all set, the acks waiting behind a delivery in flight one by
+// one. If the channel fails, its window is dropped: the broker redelivers
+// what it didn't ack.
+func (b *ackBatcher) send(acknowledger amqp.Acknowledger, window *ackWindow, all bool) error {
+	var last uint64
+	settled := 0
+	for settled < len(window.entries) && window.entries[settled].state != ackInFlight {
+		if window.entries[settled].state == ackPending {
+			last = window.entries[settled].tag
+			window.pending--
+		}
+		settled++
+	}
+	window.entries = window.entries[settled:]
+	if last > 0 {
+		metrics.BrokerAcks.WithLabelValues("multiple").Inc()
+		if err := acknowledger.Ack(last, true); err != nil {
+			b.drop(acknowledger)
+			return err
+		}
+	}
+	if !all {
+		return nil
+	}
+
+	for i := range window.entries {
+		entry := &window.entries[i]
+		if entry.state != ackPending {
+			continue
+		}
+		metrics.BrokerAcks.WithLabelValues("single").Inc()
+		if err := acknowledger.Ack(entry.tag, false); err != nil {
+			b.drop(acknowledger)
+			return err
+		}
+		entry.state = ackSent
+		window.pending--
+	}
+	return nil
+}
+
+// drop forgets a channel that failed
+func (b *ackBatcher) drop(acknowledger amqp.Acknowledger) {
+	if window, ok := b.windows[acknowledger]; ok {
+		window.entries = nil
+		window.pending = 0
+	}
+	delete(b.windows, acknowledger)
+}
@@ -0,0 +1,135 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// acknowledger records the acks a channel was sent
+type acknowledger struct {
+	acks []string
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	if multiple {
+		a.acks = append(a.acks, fmt.Sprintf("up to %d", tag))
+	} else {
+		a.acks = append(a.acks, fmt.Sprint(tag))
+	}
+	return nil
+}
+
+func (a *acknowledger) Nack(tag uint64, multiple, requeue bool) error { return nil }
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error { return nil }
+
+func TestAckBatcherAcksSettledPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		acked  []uint64
+		nacked []uint64
+		// sent before the flush, then by it
+		sent    []string
+		flushed []string
+	}{
+		{
+			name:    "below the batch size",
+			size:    10,
+			acked:   []uint64{1, 2},
+			flushed: []string{"up to 2"},
+		},
+		{
+			name:  "batch size reached",
+			size:  3,
+			acked: []uint64{1, 2, 3},
+			sent:  []string{"up to 3"},
+		},
+		{
+			name:    "behind a delivery in flight",
+			size:    2,
+			acked:   []uint64{1, 3, 4},
+			sent:    []string{"up to 1"},
+			flushed: []string{"3", "4"},
+		},
+		{
+			name:    "first delivery in flight",
+			size:    2,
+			acked:   []uint64{2, 3},
+			flushed: []string{"2", "3"},
+		},
+		{
+			name:    "over a nacked delivery",
+			size:    10,
+			acked:   []uint64{1, 3},
+			nacked:  []uint64{2},
+			flushed: []string{"up to 3"},
+		},
+		{
+			name:    "nacked deliveries alone",
+			size:    10,
+			nacked:  []uint64{1, 2},
+			flushed: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The interval flushes are kept out of the way, flush is called below
+			b := newAckBatcher(tt.size, time.Hour)
+			defer b.close()
+			channel := &acknowledger{}
+			deliveries := make(map[uint64]amqp.Delivery)
+			for tag := uint64(1); tag <= 5; tag++ {
+				deliveries[tag] = amqp.Delivery{Acknowledger: channel, DeliveryTag: tag}
+				b.track(deliveries[tag])
+			}
+
+			for _, tag := range tt.nacked {
+				b.nacked(deliveries[tag])
+			}
+			for _, tag := range tt.acked {
+				if err := b.ack(deliveries[tag]); err != nil {
+					t.Fatalf("ack(%d): %v", tag, err)
+				}
+			}
+			if !equalStrings(channel.acks, tt.sent) {
+				t.Fatalf("got acks %v before the flush, want %v", channel.acks, tt.sent)
+			}
+
+			channel.acks = nil
+			b.flush()
+			if !equalStrings(channel.acks, tt.flushed) {
+				t.Errorf("got acks %v on flush, want %v", channel.acks, tt.flushed)
+			}
+		})
+	}
+}
+
+func TestAckBatcherAcksUntrackedAtOnce(t *testing.T) {
+	b := newAckBatcher(10, time.Hour)
+	defer b.close()
+	channel := &acknowledger{}
+
+	if err := b.ack(amqp.Delivery{Acknowledger: channel, DeliveryTag: 7}); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if want := []string{"7"}; !equalStrings(channel.acks, want) {
+		t.Errorf("got acks %v, want %v", channel.acks, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Broker is the message broker behind PushQueue. Queues are addressed by
+// name; each implementation maps them onto its own primitives.
+type Broker interface {
+	// Declare creates the queue described by spec if it doesn't exist
+	Declare(ctx context.Context, spec QueueSpec) error
+	// Publish sends encoded bodies to a queue. On failure, bodies before the
+	// failing one may have been accepted.
+	Publish(ctx context.Context, queue string, bodies ...[]byte) error
+	// Consume delivers the queue's messages until ctx is cancelled, with at
+	// most prefetch unacknowledged at a time. tag names the consumer where
+	// the broker lists its consumers (RabbitMQ); the others ignore it. Use
+	// the package's Consume to get a tagged, tracked consumer.
+	Consume(ctx context.Context, queue, tag string, prefetch int) (<-chan Delivery, error)
+	Ack(d Delivery) error
+	// Nack rejects a delivery, putting it back on its queue if requeue is
+	// set and dead-lettering it otherwise.
+	Nack(d Delivery, requeue bool) error
+	// Stats returns the number of messages waiting in the queue
+	Stats(ctx context.Context, queue string) (int64, error)
+
+	Prefetch() int
+	SetPrefetch(prefetch int) error
+	Close() error
+}
+
+// Reconnector is implemented by brokers that reconnect on their own after
+// losing their connection
+type Reconnector interface {
+	// Reconnects returns how many times the connection was re-established
+	Reconnects() int
+}
+
+// ChannelKiller is implemented by brokers whose channels fault injection
+// can close, to check that they are reopened (RabbitMQ)
+type ChannelKiller interface {
+	// KillChannels closes the broker's channels as a channel error would
+	KillChannels()
+}
+
+// AckFlusher is implemented by brokers that can batch acks (RabbitMQ)
+type AckFlusher interface {
+	// FlushAcks sends the acks waiting to be batched
+	FlushAcks()
+}
+
+// QueueSpec describes a queue for Broker.Declare
+type QueueSpec struct {
+	Name string
+	// DeadLetter receives messages nacked without requeue
+	DeadLetter string
+	// Delay holds every message this long, then moves it to DelayTarget
+	// (used for the retry tiers). A delayed queue has no DeadLetter.
+	Delay       time.Duration
+	DelayTarget string
+	// MaxAge discards messages older than this
+	MaxAge time.Duration
+	// Exchange, of ExchangeType (direct or topic), and Bindings route
+	// messages to the queue on brokers with exchanges: it is bound to the
+	// exchange with each routing key or pattern. Unset, the queue keeps its
+	// usual exchange and routing key. Other brokers ignore them.
+	Exchange     string
+	ExchangeType string
+	Bindings     []string
+}
+
+// Delivery is a message received from a Broker. Settle it with Ack or Nack.
+type Delivery struct {
+	ID          string
+	Body        []byte
+	Redelivered bool
+	// PublishedAt is when the message was published, or dead-lettered, to
+	// the queue it came from, if the broker tells
+	PublishedAt time.Time
+	// RoutingKey is the key the message was published with, on brokers
+	// with exchanges
+	RoutingKey string
+
+	broker   Broker
+	handle   any       // broker-specific, e.g. the amqp.Delivery
+	consumer *consumer // the tracked consumer it came from, if any
+}
+
+// Ack acknowledges the delivery with the broker it came from
+func (d Delivery) Ack() error {
+	if d.consumer != nil {
+		d.consumer.settled()
+	}
+	return d.broker.Ack(d)
+}
+
+// Nack rejects the delivery with the broker it came from
+func (d Delivery) Nack(requeue bool) error {
+	if d.consumer != nil {
+		d.consumer.settled()
+	}
+	return d.broker.Nack(d, requeue)
+}
@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConsumerInfo describes a consumer this process is running
+// @Description A queue consumer of this process
+type ConsumerInfo struct {
+	// Tag identifies the consumer to the broker: host, purpose and a
+	// sequence number, e.g. push-worker-7d9f:push-high:3
+	Tag     string `json:"tag" example:"push-worker-7d9f:push-high:3"`
+	Host    string `json:"host" example:"push-worker-7d9f"`
+	Purpose string `json:"purpose" example:"push-high"`
+	Queue   string `json:"queue" example:"push.queue.high"`
+	// Prefetch is the prefetch count the consumer was started with
+	Prefetch  int       `json:"prefetch" example:"10"`
+	StartedAt time.Time `json:"started_at" example:"2024-01-01T00:00:00Z"`
+	// Delivered counts the messages handed to the consumer
+	Delivered int64 `json:"delivered" example:"1520"`
+	// Unacked counts those not yet acked or nacked
+	Unacked         int64      `json:"unacked" example:"4"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty" example:"2024-01-01T00:05:00Z"`
+}
+
+// consumer is a consumer in the registry
+type consumer struct {
+	tag       string
+	purpose   string
+	queue     string
+	prefetch  int
+	startedAt time.Time
+
+	delivered     atomic.Int64
+	unacked       atomic.Int64
+	lastDelivered atomic.Int64 // unix nanoseconds, 0 before the first
+}
+
+// settled records that one of the consumer's deliveries was acked or nacked
+func (c *consumer) settled() {
+	c.unacked.Add(-1)
+}
+
+var (
+	hostname = func() string {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			return "unknown"
+		}
+		return host
+	}()
+
+	consumersMu  sync.Mutex
+	consumerSeq  int
+	consumersSet = make(map[*consumer]struct{})
+)
+
+// Consume starts a consumer of queue on broker, tagged with this host and
+// purpose (e.g. "push-high"), and tracks it in ActiveConsumers until its
+// channel closes
+func Consume(ctx context.Context, broker Broker, queue, purpose string, prefetch int) (<-chan Delivery, error) {
+	consumersMu.Lock()
+	consumerSeq++
+	c := &consumer{
+		tag:       fmt.Sprintf("%s:%s:%d", hostname, purpose, consumerSeq),
+		purpose:   purpose,
+		queue:     queue,
+		prefetch:  prefetch,
+		startedAt: time.Now(),
+	}
+	consumersMu.Unlock()
+
+	msgs, err := broker.Consume(ctx, queue, c.tag, prefetch)
+	if err != nil {
+		return nil, err
+	}
+
+	consumersMu.Lock()
+	consumersSet[c] = struct{}{}
+	consumersMu.Unlock()
+
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+		defer func() {
+			consumersMu.Lock()
+			delete(consumersSet, c)
+			consumersMu.Unlock()
+		}()
+
+		for d := range msgs {
+			d.consumer = c
+			c.delivered.Add(1)
+			c.unacked.Add(1)
+			c.lastDelivered.Store(time.Now().UnixNano())
+			select {
+			case out <- d:
+			case <-ctx.Done():
+				// Nobody takes it anymore: back to the queue with the
+				// rest, as the broker's consumer does
+				d.Nack(true)
+				for d := range msgs {
+					d.Nack(true)
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// ActiveConsumers lists the consumers this process is running, ordered by
+// tag
+func ActiveConsumers() []ConsumerInfo {
+	consumersMu.Lock()
+	defer consumersMu.Unlock()
+
+	infos := make([]ConsumerInfo, 0, len(consumersSet))
+	for c := range consumersSet {
+		info := ConsumerInfo{
+			Tag:       c.tag,
+			Host:      hostname,
+			Purpose:   c.purpose,
+			Queue:     c.queue,
+			Prefetch:  c.prefetch,
+			StartedAt: c.startedAt,
+			Delivered: c.delivered.Load(),
+			Unacked:   c.unacked.Load(),
+		}
+		if last := c.lastDelivered.Load(); last != 0 {
+			at := time.Unix(0, last)
+			info.LastDeliveredAt = &at
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Tag < infos[j].Tag })
+	return infos
+}
+
+// Hostname is the host name consumer tags start with
+func Hostname() string {
+	return hostname
+}
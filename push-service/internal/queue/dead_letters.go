@@ -0,0 +1,136 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// deadLetterWait ends a peek or replay of the dead letter queue once no
+// message has arrived for this long, i.e. the queue is empty
+const deadLetterWait = time.Second
+
+// DeadLetter is a message taken from the dead letter queue. Message is nil,
+// and Err says why, if its body couldn't be decoded.
+type DeadLetter struct {
+	Delivery Delivery
+	Message  *PushMessage
+	Err      error
+}
+
+func deadLetterOf(delivery Delivery) DeadLetter {
+	message, err := DecodePushMessage(delivery.Body)
+	if err != nil {
+		return DeadLetter{Delivery: delivery, Err: err}
+	}
+	return DeadLetter{Delivery: delivery, Message: &message}
+}
+
+// PeekDeadLetters returns up to limit messages from the head of the dead
+// letter queue and puts them back. They are held until the peek ends, so no
+// more than the broker's prefetch count are returned.
+func (q *PushQueue) PeekDeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit = min(limit, q.broker.Prefetch())
+	msgs, err := Consume(ctx, q.broker, DeadLetterQueue, "dead-letter-peek", q.broker.Prefetch())
+	if err != nil {
+		return nil, err
+	}
+
+	var peeked []DeadLetter
+	defer func() {
+		for _, deadLetter := range peeked {
+			if err := deadLetter.Delivery.Nack(true); err != nil {
+				zap.L().Warn("Failed to put back dead letter", zap.String("delivery_id", deadLetter.Delivery.ID), zap.Error(err))
+			}
+		}
+	}()
+
+	idle := time.NewTimer(deadLetterWait)
+	defer idle.Stop()
+	for len(peeked) < limit {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-idle.C:
+			return peeked, nil
+		case delivery, ok := <-msgs:
+			if !ok {
+				return peeked, nil
+			}
+			peeked = append(peeked, deadLetterOf(delivery))
+			idle.Reset(deadLetterWait)
+		}
+	}
+	return peeked, nil
+}
+
+// ReplayDeadLetters moves up to limit messages from the head of the dead
+// letter queue back to the push queues of their priorities, with their
+// retries reset. Messages that can't be decoded are left in the queue and
+// counted as skipped.
+func (q *PushQueue) ReplayDeadLetters(ctx context.Context, limit int) (replayed, skipped int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	msgs, err := Consume(ctx, q.broker, DeadLetterQueue, "dead-letter-replay", q.broker.Prefetch())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Held until the replay ends, so they aren't received again
+	var undecodable []Delivery
+	defer func() {
+		for _, delivery := range undecodable {
+			if err := delivery.Nack(true); err != nil {
+				zap.L().Warn("Failed to put back dead letter", zap.String("delivery_id", delivery.ID), zap.Error(err))
+			}
+		}
+	}()
+
+	idle := time.NewTimer(deadLetterWait)
+	defer idle.Stop()
+	for replayed < limit {
+		select {
+		case <-ctx.Done():
+			return replayed, len(undecodable), ctx.Err()
+		case <-idle.C:
+			return replayed, len(undecodable), nil
+		case delivery, ok := <-msgs:
+			if !ok {
+				return replayed, len(undecodable), nil
+			}
+			idle.Reset(deadLetterWait)
+
+			deadLetter := deadLetterOf(delivery)
+			if deadLetter.Message == nil {
+				zap.L().Warn("Skipping dead letter that can't be decoded", zap.String("delivery_id", delivery.ID), zap.Error(deadLetter.Err))
+				undecodable = append(undecodable, delivery)
+				continue
+			}
+
+			message := *deadLetter.Message
+			message.RetryCount = 0
+			message.LastError = ""
+			if err := q.Requeue(ctx, message); err != nil {
+				if nackErr := delivery.Nack(true); nackErr != nil {
+					zap.L().Warn("Failed to put back dead letter", zap.String("delivery_id", delivery.ID), zap.Error(nackErr))
+				}
+				return replayed, len(undecodable), err
+			}
+			if err := delivery.Ack(); err != nil {
+				// Replayed twice if it is received again
+				zap.L().Warn("Failed to acknowledge replayed dead letter", zap.String("delivery_id", delivery.ID), zap.Error(err))
+			}
+			replayed++
+			zap.L().Info("Dead letter replayed",
+				zap.String("notification_id", message.Notification.ID),
+				zap.String("queue", PushQueueFor(message.Priority)),
+			)
+		}
+	}
+	return replayed, len(undecodable), nil
+}
@@ -0,0 +1,291 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/queue/queuepb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Content types of push message bodies
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// ContentType returns how a push message body is encoded. A JSON body is an
+// object, and a protobuf body can't start with '{' (it would be field 15 as
+// a group), so bodies describe themselves and consumers handle either,
+// whatever the broker carries besides the body.
+func ContentType(body []byte) string {
+	if len(body) > 0 && body[0] == '{' {
+		return ContentTypeJSON
+	}
+	return ContentTypeProtobuf
+}
+
+// EncodePushMessage encodes a push message as JSON or, with
+// config.QueueEncodingProtobuf, as a queuepb.PushMessage
+func EncodePushMessage(message PushMessage, encoding string) ([]byte, error) {
+	if encoding != config.QueueEncodingProtobuf {
+		return json.Marshal(message)
+	}
+
+	pb, err := pushMessageToProto(message)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(pb)
+}
+
+// DecodePushMessage decodes a push message body in either encoding
+func DecodePushMessage(body []byte) (PushMessage, error) {
+	var message PushMessage
+	if ContentType(body) == ContentTypeJSON {
+		err := json.Unmarshal(body, &message)
+		return message, err
+	}
+
+	var pb queuepb.PushMessage
+	if err := proto.Unmarshal(body, &pb); err != nil {
+		return message, err
+	}
+	return pushMessageFromProto(&pb), nil
+}
+
+func pushMessageToProto(message PushMessage) (*queuepb.PushMessage, error) {
+	notification, err := notificationToProto(message.Notification)
+	if err != nil {
+		return nil, err
+	}
+
+	pb := &queuepb.PushMessage{
+		Notification:        notification,
+		DeviceTokens:        message.DeviceTokens,
+		RetryCount:          int32(message.RetryCount),
+		Platforms:           message.Platforms,
+		CampaignId:          message.CampaignID,
+		Variant:             message.Variant,
+		TenantId:            message.TenantID,
+		SuppressIfConnected: message.SuppressIfConnected,
+		ProjectId:           message.ProjectID,
+		DryRunId:            message.DryRunID,
+		Vars:                message.Vars,
+		Raw:                 message.Raw,
+		TraceContext:        message.TraceContext,
+		Queue:               message.Queue,
+		LastError:           message.LastError,
+		Priority:            message.Priority,
+		BypassQuietHours:    message.BypassQuietHours,
+		BypassFrequencyCap:  message.BypassFrequencyCap,
+		PolicyApplied:       message.PolicyApplied,
+	}
+	if message.MaxRetries != nil {
+		maxRetries := int32(*message.MaxRetries)
+		pb.MaxRetries = &maxRetries
+	}
+	if message.RetryBackoff != 0 {
+		pb.RetryBackoff = durationpb.New(message.RetryBackoff)
+	}
+	for i, item := range message.Bundle {
+		encoded, err := notificationToProto(item)
+		if err != nil {
+			return nil, fmt.Errorf("bundle item %d: %w", i+1, err)
+		}
+		pb.Bundle = append(pb.Bundle, encoded)
+	}
+	return pb, nil
+}
+
+func pushMessageFromProto(pb *queuepb.PushMessage) PushMessage {
+	message := PushMessage{
+		Notification:        notificationFromProto(pb.GetNotification()),
+		DeviceTokens:        pb.DeviceTokens,
+		RetryCount:          int(pb.RetryCount),
+		Platforms:           pb.Platforms,
+		CampaignID:          pb.CampaignId,
+		Variant:             pb.Variant,
+		TenantID:            pb.TenantId,
+		SuppressIfConnected: pb.SuppressIfConnected,
+		ProjectID:           pb.ProjectId,
+		DryRunID:            pb.DryRunId,
+		Vars:                pb.Vars,
+		TraceContext:        pb.TraceContext,
+		Queue:               pb.Queue,
+		LastError:           pb.LastError,
+		Priority:            pb.Priority,
+		BypassQuietHours:    pb.BypassQuietHours,
+		BypassFrequencyCap:  pb.BypassFrequencyCap,
+		PolicyApplied:       pb.PolicyApplied,
+	}
+	if len(pb.Raw) > 0 {
+		message.Raw = json.RawMessage(pb.Raw)
+	}
+	if pb.MaxRetries != nil {
+		maxRetries := int(*pb.MaxRetries)
+		message.MaxRetries = &maxRetries
+	}
+	if pb.RetryBackoff != nil {
+		message.RetryBackoff = pb.RetryBackoff.AsDuration()
+	}
+	for _, item := range pb.Bundle {
+		message.Bundle = append(message.Bundle, notificationFromProto(item))
+	}
+	return message
+}
+
+func notificationToProto(notification models.PushNotification) (*queuepb.Notification, error) {
+	data, err := structToProto(notification.Data)
+	if err != nil {
+		return nil, fmt.Errorf("data: %w", err)
+	}
+
+	pb := &queuepb.Notification{
+		Id:             notification.ID,
+		DeviceId:       notification.DeviceID,
+		UserId:         notification.UserID,
+		Title:          notification.Title,
+		Body:           notification.Body,
+		Image:          notification.Image,
+		Link:           notification.Link,
+		Data:           data,
+		Sender:         notification.Sender,
+		Category:       notification.Category,
+		BundleId:       notification.BundleID,
+		CollapseKey:    notification.CollapseKey,
+		ExpiresAt:      timeToProto(notification.ExpiresAt),
+		ClickAction:    notification.ClickAction,
+		AnalyticsLabel: notification.AnalyticsLabel,
+		Status:         notification.Status,
+		ErrorMessage:   notification.ErrorMessage,
+		SentAt:         timeToProto(notification.SentAt),
+	}
+	if !notification.CreatedAt.IsZero() {
+		pb.CreatedAt = timestamppb.New(notification.CreatedAt)
+	}
+	for _, action := range notification.Actions {
+		pb.Actions = append(pb.Actions, &queuepb.NotificationAction{Id: action.ID, Title: action.Title, Icon: action.Icon})
+	}
+	if ios := notification.IOS; ios != nil {
+		pb.Ios = &queuepb.IOSOptions{Sound: ios.Sound, Category: ios.Category, ThreadId: ios.ThreadID}
+		if ios.Badge != nil {
+			badge := int32(*ios.Badge)
+			pb.Ios.Badge = &badge
+		}
+	}
+	if overrides := notification.PlatformOverrides; overrides != nil {
+		pb.PlatformOverrides = &queuepb.PlatformOverrides{}
+		if pb.PlatformOverrides.Android, err = structToProto(overrides.Android); err != nil {
+			return nil, fmt.Errorf("android overrides: %w", err)
+		}
+		if pb.PlatformOverrides.Apns, err = structToProto(overrides.APNs); err != nil {
+			return nil, fmt.Errorf("apns overrides: %w", err)
+		}
+		if pb.PlatformOverrides.Webpush, err = structToProto(overrides.Webpush); err != nil {
+			return nil, fmt.Errorf("webpush overrides: %w", err)
+		}
+	}
+	return pb, nil
+}
+
+func notificationFromProto(pb *queuepb.Notification) models.PushNotification {
+	if pb == nil {
+		return models.PushNotification{}
+	}
+
+	notification := models.PushNotification{
+		ID:             pb.Id,
+		DeviceID:       pb.DeviceId,
+		UserID:         pb.UserId,
+		Title:          pb.Title,
+		Body:           pb.Body,
+		Image:          pb.Image,
+		Link:           pb.Link,
+		Data:           structFromProto(pb.Data),
+		Sender:         pb.Sender,
+		Category:       pb.Category,
+		BundleID:       pb.BundleId,
+		CollapseKey:    pb.CollapseKey,
+		ExpiresAt:      timeFromProto(pb.ExpiresAt),
+		ClickAction:    pb.ClickAction,
+		AnalyticsLabel: pb.AnalyticsLabel,
+		Status:         pb.Status,
+		ErrorMessage:   pb.ErrorMessage,
+		SentAt:         timeFromProto(pb.SentAt),
+	}
+	if pb.CreatedAt != nil {
+		notification.CreatedAt = pb.CreatedAt.AsTime()
+	}
+	for _, action := range pb.Actions {
+		notification.Actions = append(notification.Actions, models.NotificationAction{ID: action.Id, Title: action.Title, Icon: action.Icon})
+	}
+	if ios := pb.Ios; ios != nil {
+		notification.IOS = &models.IOSOptions{Sound: ios.Sound, Category: ios.Category, ThreadID: ios.ThreadId}
+		if ios.Badge != nil {
+			badge := int(*ios.Badge)
+			notification.IOS.Badge = &badge
+		}
+	}
+	if overrides := pb.PlatformOverrides; overrides != nil {
+		notification.PlatformOverrides = &models.PlatformOverrides{
+			Android: structFromProto(overrides.Android),
+			APNs:    structFromProto(overrides.Apns),
+			Webpush: structFromProto(overrides.Webpush),
+		}
+	}
+	return notification
+}
+
+// structToProto converts a JSON object. Numbers come back as float64, as
+// they do from JSON.
+func structToProto(fields map[string]any) (*structpb.Struct, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	pb, err := structpb.NewStruct(fields)
+	if err == nil {
+		return pb, nil
+	}
+
+	// Values Struct doesn't take directly, e.g. a []string, are converted
+	// the way the JSON encoding would convert them
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(decoded)
+}
+
+func structFromProto(pb *structpb.Struct) map[string]any {
+	if pb == nil {
+		return nil
+	}
+	return pb.AsMap()
+}
+
+func timeToProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func timeFromProto(pb *timestamppb.Timestamp) *time.Time {
+	if pb == nil {
+		return nil
+	}
+	t := pb.AsTime()
+	return &t
+}
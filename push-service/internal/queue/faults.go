@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"context"
+
+	"push-service/internal/chaos"
+)
+
+// faultyBroker injects faults into the acks of the broker it wraps: it
+// drops some, leaving the message unacked until its channel closes or its
+// visibility timeout passes, and closes the broker's channels instead of
+// acking others
+type faultyBroker struct {
+	Broker
+	faults *chaos.Faults
+}
+
+// WithFaults wraps broker so its acks fail as faults decides. It returns
+// broker itself if faults is nil.
+func WithFaults(broker Broker, faults *chaos.Faults) Broker {
+	if faults == nil {
+		return broker
+	}
+	return &faultyBroker{Broker: broker, faults: faults}
+}
+
+// Consume hands out the wrapped broker's deliveries, settled through b
+func (b *faultyBroker) Consume(ctx context.Context, queue, tag string, prefetch int) (<-chan Delivery, error) {
+	msgs, err := b.Broker.Consume(ctx, queue, tag, prefetch)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+		for d := range msgs {
+			d.broker = b
+			select {
+			case out <- d:
+			case <-ctx.Done():
+				// Back to the queue with the rest, as the broker's
+				// consumer does
+				d.Nack(true)
+				for d := range msgs {
+					d.Nack(true)
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (b *faultyBroker) Ack(d Delivery) error {
+	killer, canKill := b.Broker.(ChannelKiller)
+	switch b.faults.AckFault(canKill) {
+	case chaos.FaultAckDrop:
+		return nil
+	case chaos.FaultChannelKill:
+		// The ack then fails on the closed channel
+		killer.KillChannels()
+	}
+	return b.Broker.Ack(d)
+}
+
+// Reconnects returns the wrapped broker's reconnects, if it counts them
+func (b *faultyBroker) Reconnects() int {
+	if reconnector, ok := b.Broker.(Reconnector); ok {
+		return reconnector.Reconnects()
+	}
+	return 0
+}
+
+// KillChannels closes broker's channels, as a channel error would, and
+// tells if it has channels to close
+func KillChannels(broker Broker) bool {
+	if faulty, ok := broker.(*faultyBroker); ok {
+		broker = faulty.Broker
+	}
+	killer, ok := broker.(ChannelKiller)
+	if ok {
+		killer.KillChannels()
+	}
+	return ok
+}
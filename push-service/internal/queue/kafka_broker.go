@@ -0,0 +1,500 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// KafkaBroker implements Broker on Kafka. Each queue is a topic consumed by
+// its own consumer group, so the push, retry and dead letter topics keep
+// independent offsets. Offsets are committed manually: Ack commits a
+// message once every earlier message of its partition has been settled, and
+// Nack republishes the message (to the same topic to requeue, to the dead
+// letter topic otherwise) before committing it. Delivery is at-least-once.
+//
+// Delayed queues are forwarded by the broker itself: a background consumer
+// holds each message until it is Delay old and then publishes it to the
+// DelayTarget topic.
+type KafkaBroker struct {
+	cfg    *config.KafkaConfig
+	client *kafka.Client
+	writer *kafka.Writer
+	dialer *kafka.Dialer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	specs     map[string]QueueSpec
+	consumers map[*kafkaConsumer]struct{}
+	prefetch  int
+}
+
+// kafkaConsumer is one consumer group reader plus the offsets it has handed
+// out and not yet committed, per partition in fetch order.
+type kafkaConsumer struct {
+	broker *KafkaBroker
+	reader *kafka.Reader
+	topic  string
+
+	mu       sync.Mutex
+	pending  map[int][]int64
+	settled  map[int]map[int64]bool
+	inflight int
+	wake     chan struct{}
+}
+
+type kafkaHandle struct {
+	consumer *kafkaConsumer
+	msg      kafka.Message
+}
+
+func NewKafkaBroker(cfg *config.KafkaConfig) (*KafkaBroker, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are required")
+	}
+
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 10 * time.Second // default
+	}
+	transport := &kafka.Transport{
+		ClientID:    cfg.ClientID,
+		DialTimeout: dialTimeout,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &KafkaBroker{
+		cfg: cfg,
+		client: &kafka.Client{
+			Addr:      kafka.TCP(cfg.Brokers...),
+			Timeout:   dialTimeout,
+			Transport: transport,
+		},
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
+			Transport:    transport,
+		},
+		dialer: &kafka.Dialer{
+			ClientID:  cfg.ClientID,
+			Timeout:   dialTimeout,
+			DualStack: true,
+		},
+		ctx:       ctx,
+		cancel:    cancel,
+		specs:     make(map[string]QueueSpec),
+		consumers: make(map[*kafkaConsumer]struct{}),
+	}
+
+	// Fail fast on unreachable brokers
+	if _, err := b.client.Metadata(ctx, &kafka.MetadataRequest{}); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to connect to kafka: %w", err)
+	}
+
+	zap.L().Info("Connected to Kafka", zap.Strings("brokers", cfg.Brokers))
+	return b, nil
+}
+
+// groupID names the consumer group of a topic
+func (b *KafkaBroker) groupID(topic string) string {
+	return b.cfg.GroupID + "." + topic
+}
+
+func (b *KafkaBroker) Declare(ctx context.Context, spec QueueSpec) error {
+	partitions := b.cfg.Partitions
+	if partitions <= 0 {
+		partitions = 6 // default
+	}
+	replication := b.cfg.ReplicationFactor
+	if replication <= 0 {
+		replication = 1 // default
+	}
+
+	topic := kafka.TopicConfig{
+		Topic:             spec.Name,
+		NumPartitions:     partitions,
+		ReplicationFactor: replication,
+	}
+	if spec.MaxAge > 0 {
+		topic.ConfigEntries = append(topic.ConfigEntries, kafka.ConfigEntry{
+			ConfigName:  "retention.ms",
+			ConfigValue: strconv.FormatInt(spec.MaxAge.Milliseconds(), 10),
+		})
+	}
+
+	res, err := b.client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: []kafka.TopicConfig{topic}})
+	if err != nil {
+		return fmt.Errorf("failed to create topic %s: %w", spec.Name, err)
+	}
+	if err := res.Errors[spec.Name]; err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+		return fmt.Errorf("failed to create topic %s: %w", spec.Name, err)
+	}
+
+	b.mu.Lock()
+	_, declared := b.specs[spec.Name]
+	b.specs[spec.Name] = spec
+	b.mu.Unlock()
+
+	if spec.Delay > 0 && !declared {
+		b.wg.Add(1)
+		go b.forwardDelayed(spec)
+	}
+	return nil
+}
+
+func (b *KafkaBroker) Publish(ctx context.Context, queue string, bodies ...[]byte) error {
+	msgs := make([]kafka.Message, len(bodies))
+	for i, body := range bodies {
+		msgs[i] = kafka.Message{Topic: queue, Value: body}
+	}
+	if err := b.writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", queue, err)
+	}
+	return nil
+}
+
+func (b *KafkaBroker) Consume(ctx context.Context, queue, tag string, prefetch int) (<-chan Delivery, error) {
+	b.mu.Lock()
+	if b.prefetch <= 0 {
+		b.prefetch = prefetch
+	}
+	b.mu.Unlock()
+
+	c := &kafkaConsumer{
+		broker: b,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: b.cfg.Brokers,
+			GroupID: b.groupID(queue),
+			Topic:   queue,
+			Dialer:  b.dialer,
+			MaxWait: 500 * time.Millisecond,
+		}),
+		topic:   queue,
+		pending: make(map[int][]int64),
+		settled: make(map[int]map[int64]bool),
+		wake:    make(chan struct{}, 1),
+	}
+
+	b.mu.Lock()
+	b.consumers[c] = struct{}{}
+	b.mu.Unlock()
+
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+		defer func() {
+			b.mu.Lock()
+			delete(b.consumers, c)
+			b.mu.Unlock()
+			c.reader.Close()
+		}()
+
+		for {
+			if !c.waitForCapacity(ctx) {
+				return
+			}
+			msg, err := c.reader.FetchMessage(ctx)
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, io.EOF) {
+					return
+				}
+				zap.L().Warn("Failed to fetch Kafka message", zap.String("topic", queue), zap.Error(err))
+				select {
+				case <-time.After(time.Second):
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			c.track(msg)
+			delivery := Delivery{
+				ID:          fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset),
+				Body:        msg.Value,
+				PublishedAt: msg.Time,
+				broker:      b,
+				handle:      &kafkaHandle{consumer: c, msg: msg},
+			}
+			select {
+			case out <- delivery:
+			case <-ctx.Done():
+				// Uncommitted; redelivered to the group after a restart
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Ack commits the delivery's offset once nothing before it is outstanding
+func (b *KafkaBroker) Ack(d Delivery) error {
+	h, ok := d.handle.(*kafkaHandle)
+	if !ok {
+		return fmt.Errorf("delivery %s is not from Kafka", d.ID)
+	}
+	return h.consumer.settle(h.msg)
+}
+
+// Nack republishes the delivery, to its own topic if requeue is set and to
+// the queue's dead letter topic otherwise, then commits it like Ack. Without
+// a dead letter topic the message is dropped.
+func (b *KafkaBroker) Nack(d Delivery, requeue bool) error {
+	h, ok := d.handle.(*kafkaHandle)
+	if !ok {
+		return fmt.Errorf("delivery %s is not from Kafka", d.ID)
+	}
+
+	target := h.msg.Topic
+	if !requeue {
+		b.mu.Lock()
+		target = b.specs[h.msg.Topic].DeadLetter
+		b.mu.Unlock()
+	}
+	if target != "" {
+		if err := b.writer.WriteMessages(b.ctx, kafka.Message{
+			Topic:   target,
+			Key:     h.msg.Key,
+			Value:   h.msg.Value,
+			Headers: h.msg.Headers,
+			// A requeued message keeps its age
+			Time: h.msg.Time,
+		}); err != nil {
+			// Leave the offset uncommitted so the message isn't lost
+			return fmt.Errorf("failed to republish %s to %s: %w", d.ID, target, err)
+		}
+	}
+	return h.consumer.settle(h.msg)
+}
+
+// Stats returns the consumer group's lag on the queue's topic
+func (b *KafkaBroker) Stats(ctx context.Context, queue string) (int64, error) {
+	meta, err := b.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{queue}})
+	if err != nil {
+		return 0, err
+	}
+	if len(meta.Topics) == 0 || meta.Topics[0].Error != nil {
+		return 0, fmt.Errorf("topic %s not found", queue)
+	}
+
+	var requests []kafka.OffsetRequest
+	var partitions []int
+	for _, p := range meta.Topics[0].Partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+		partitions = append(partitions, p.ID)
+	}
+
+	offsets, err := b.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{queue: requests},
+	})
+	if err != nil {
+		return 0, err
+	}
+	committed, err := b.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: b.groupID(queue),
+		Topics:  map[string][]int{queue: partitions},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	committedByPartition := make(map[int]int64)
+	for _, p := range committed.Topics[queue] {
+		committedByPartition[p.Partition] = p.CommittedOffset
+	}
+
+	var lag int64
+	for _, p := range offsets.Topics[queue] {
+		start := p.FirstOffset
+		if offset, ok := committedByPartition[p.Partition]; ok && offset > start {
+			start = offset
+		}
+		if p.LastOffset > start {
+			lag += p.LastOffset - start
+		}
+	}
+	return lag, nil
+}
+
+func (b *KafkaBroker) Prefetch() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.prefetch
+}
+
+// SetPrefetch changes the unacknowledged limit of every consumer
+func (b *KafkaBroker) SetPrefetch(prefetch int) error {
+	if prefetch <= 0 {
+		return fmt.Errorf("prefetch must be positive, got %d", prefetch)
+	}
+
+	b.mu.Lock()
+	b.prefetch = prefetch
+	consumers := make([]*kafkaConsumer, 0, len(b.consumers))
+	for c := range b.consumers {
+		consumers = append(consumers, c)
+	}
+	b.mu.Unlock()
+
+	for _, c := range consumers {
+		c.signal()
+	}
+	return nil
+}
+
+func (b *KafkaBroker) Close() error {
+	b.cancel()
+	b.wg.Wait()
+	return b.writer.Close()
+}
+
+// forwardDelayed moves each message of a delayed queue to its target once it
+// has waited spec.Delay. Messages of a partition are in publish order, so
+// waiting for the head one never holds back an older one.
+func (b *KafkaBroker) forwardDelayed(spec QueueSpec) {
+	defer b.wg.Done()
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: b.cfg.Brokers,
+		GroupID: b.groupID(spec.Name),
+		Topic:   spec.Name,
+		Dialer:  b.dialer,
+	})
+	defer reader.Close()
+
+	for {
+		msg, err := reader.FetchMessage(b.ctx)
+		if err != nil {
+			if b.ctx.Err() != nil {
+				return
+			}
+			zap.L().Warn("Failed to fetch delayed Kafka message", zap.String("topic", spec.Name), zap.Error(err))
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-b.ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-time.After(time.Until(msg.Time.Add(spec.Delay))):
+		case <-b.ctx.Done():
+			return
+		}
+
+		for {
+			err := b.writer.WriteMessages(b.ctx, kafka.Message{
+				Topic:   spec.DelayTarget,
+				Key:     msg.Key,
+				Value:   msg.Value,
+				Headers: msg.Headers,
+			})
+			if err == nil {
+				break
+			}
+			zap.L().Warn("Failed to forward delayed Kafka message",
+				zap.String("topic", spec.Name),
+				zap.String("target", spec.DelayTarget),
+				zap.Error(err),
+			)
+			select {
+			case <-time.After(time.Second):
+			case <-b.ctx.Done():
+				return
+			}
+		}
+
+		if err := reader.CommitMessages(b.ctx, msg); err != nil && b.ctx.Err() == nil {
+			zap.L().Warn("Failed to commit delayed Kafka message", zap.String("topic", spec.Name), zap.Error(err))
+		}
+	}
+}
+
+// waitForCapacity blocks while the consumer has prefetch messages unsettled
+func (c *kafkaConsumer) waitForCapacity(ctx context.Context) bool {
+	for {
+		c.mu.Lock()
+		inflight := c.inflight
+		c.mu.Unlock()
+
+		prefetch := c.broker.Prefetch()
+		if prefetch <= 0 || inflight < prefetch {
+			return true
+		}
+		select {
+		case <-c.wake:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (c *kafkaConsumer) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *kafkaConsumer) track(msg kafka.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[msg.Partition] = append(c.pending[msg.Partition], msg.Offset)
+	c.inflight++
+}
+
+// settle marks msg done and commits the longest settled prefix of its
+// partition, so a crash never skips a message that was still in flight.
+func (c *kafkaConsumer) settle(msg kafka.Message) error {
+	commit := c.markSettled(msg)
+	c.signal()
+
+	if commit < 0 {
+		return nil
+	}
+	return c.reader.CommitMessages(context.Background(), kafka.Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    commit,
+	})
+}
+
+// markSettled marks msg done and returns the last offset of the settled
+// prefix of its partition, now to be committed, or -1 if the prefix didn't
+// grow
+func (c *kafkaConsumer) markSettled(msg kafka.Message) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	settled := c.settled[msg.Partition]
+	if settled == nil {
+		settled = make(map[int64]bool)
+		c.settled[msg.Partition] = settled
+	}
+	settled[msg.Offset] = true
+	c.inflight--
+
+	pending := c.pending[msg.Partition]
+	commit := int64(-1)
+	for len(pending) > 0 && settled[pending[0]] {
+		commit = pending[0]
+		delete(settled, pending[0])
+		pending = pending[1:]
+	}
+	c.pending[msg.Partition] = pending
+	return commit
+}
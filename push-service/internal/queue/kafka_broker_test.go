@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestKafkaConsumerCommitsSettledPrefix(t *testing.T) {
+	type settle struct {
+		partition int
+		offset    int64
+		// the offset committed once it settles, -1 for none
+		commit int64
+	}
+	tests := []struct {
+		name    string
+		settles []settle
+	}{
+		{
+			name: "in order",
+			settles: []settle{
+				{0, 10, 10},
+				{0, 11, 11},
+				{0, 12, 12},
+			},
+		},
+		{
+			name: "out of order",
+			settles: []settle{
+				{0, 11, -1},
+				{0, 12, -1},
+				{0, 10, 12},
+			},
+		},
+		{
+			name: "gap in the middle",
+			settles: []settle{
+				{0, 10, 10},
+				{0, 12, -1},
+				{0, 11, 12},
+			},
+		},
+		{
+			name: "partitions apart",
+			settles: []settle{
+				{1, 20, 20},
+				{0, 11, -1},
+				{1, 21, 21},
+				{0, 10, 11},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &kafkaConsumer{
+				pending: map[int][]int64{0: {10, 11, 12}, 1: {20, 21}},
+				settled: make(map[int]map[int64]bool),
+			}
+			c.inflight = 5
+
+			for _, s := range tt.settles {
+				got := c.markSettled(kafka.Message{Partition: s.partition, Offset: s.offset})
+				if got != s.commit {
+					t.Errorf("settling %d/%d: got commit %d, want %d", s.partition, s.offset, got, s.commit)
+				}
+			}
+			if want := 5 - len(tt.settles); c.inflight != want {
+				t.Errorf("got %d in flight, want %d", c.inflight, want)
+			}
+		})
+	}
+}
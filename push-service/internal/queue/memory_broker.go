@@ -0,0 +1,288 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryBroker implements Broker in process, for local development and
+// tests without RabbitMQ. Messages are lost when the process exits, and only
+// consumers in the same process see them.
+type MemoryBroker struct {
+	mu       sync.Mutex
+	queues   map[string]*memoryQueue
+	timers   map[*time.Timer]struct{}
+	prefetch int
+	nextID   uint64
+	closed   bool
+}
+
+type memoryQueue struct {
+	spec     QueueSpec
+	messages []*memoryMessage
+	delayed  int // messages waiting for a delay to move elsewhere
+	// changed is closed and replaced whenever messages or capacity change
+	changed chan struct{}
+}
+
+type memoryMessage struct {
+	id          string
+	body        []byte
+	queue       string
+	publishedAt time.Time
+	redelivered bool
+}
+
+type memoryHandle struct {
+	msg      *memoryMessage
+	consumer *memoryConsumer
+}
+
+type memoryConsumer struct {
+	inflight int
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		queues: make(map[string]*memoryQueue),
+		timers: make(map[*time.Timer]struct{}),
+	}
+}
+
+// queue returns the named queue, creating it if it wasn't declared. Callers
+// hold b.mu.
+func (b *MemoryBroker) queue(name string) *memoryQueue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &memoryQueue{spec: QueueSpec{Name: name}, changed: make(chan struct{})}
+		b.queues[name] = q
+	}
+	return q
+}
+
+// notify wakes the consumers waiting on q. Callers hold b.mu.
+func (q *memoryQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func (b *MemoryBroker) Declare(ctx context.Context, spec QueueSpec) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.queue(spec.Name).spec = spec
+	return nil
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, queue string, bodies ...[]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return fmt.Errorf("broker is closed")
+	}
+
+	for _, body := range bodies {
+		b.nextID++
+		b.push(queue, &memoryMessage{
+			id:          strconv.FormatUint(b.nextID, 10),
+			body:        append([]byte(nil), body...),
+			publishedAt: time.Now(),
+		})
+	}
+	return nil
+}
+
+// push adds msg to the back of queue, or holds it for the queue's delay
+// first. Callers hold b.mu.
+func (b *MemoryBroker) push(queue string, msg *memoryMessage) {
+	q := b.queue(queue)
+	if q.spec.Delay <= 0 {
+		msg.queue = queue
+		q.messages = append(q.messages, msg)
+		q.notify()
+		return
+	}
+
+	q.delayed++
+	var timer *time.Timer
+	timer = time.AfterFunc(q.spec.Delay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.timers, timer)
+		q.delayed--
+		if b.closed {
+			return
+		}
+		msg.publishedAt = time.Now()
+		b.push(q.spec.DelayTarget, msg)
+	})
+	b.timers[timer] = struct{}{}
+}
+
+// pop takes the first message of q that hasn't exceeded the queue's MaxAge.
+// Callers hold b.mu.
+func (q *memoryQueue) pop() *memoryMessage {
+	for len(q.messages) > 0 {
+		msg := q.messages[0]
+		q.messages = q.messages[1:]
+		if q.spec.MaxAge > 0 && time.Since(msg.publishedAt) > q.spec.MaxAge {
+			continue
+		}
+		return msg
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Consume(ctx context.Context, queue, tag string, prefetch int) (<-chan Delivery, error) {
+	b.mu.Lock()
+	if b.prefetch <= 0 {
+		b.prefetch = prefetch
+	}
+	b.mu.Unlock()
+
+	consumer := &memoryConsumer{}
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+		for {
+			b.mu.Lock()
+			if b.closed {
+				b.mu.Unlock()
+				return
+			}
+			q := b.queue(queue)
+			var msg *memoryMessage
+			if b.prefetch <= 0 || consumer.inflight < b.prefetch {
+				msg = q.pop()
+			}
+			if msg == nil {
+				changed := q.changed
+				b.mu.Unlock()
+				select {
+				case <-changed:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+			consumer.inflight++
+			b.mu.Unlock()
+
+			delivery := Delivery{
+				ID:          msg.id,
+				Body:        msg.body,
+				Redelivered: msg.redelivered,
+				PublishedAt: msg.publishedAt,
+				broker:      b,
+				handle:      &memoryHandle{msg: msg, consumer: consumer},
+			}
+			select {
+			case out <- delivery:
+			case <-ctx.Done():
+				b.requeue(delivery)
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// settle releases a delivery's prefetch slot
+func (b *MemoryBroker) settle(d Delivery) (*memoryMessage, error) {
+	h, ok := d.handle.(*memoryHandle)
+	if !ok {
+		return nil, fmt.Errorf("delivery %s is not from the memory broker", d.ID)
+	}
+	h.consumer.inflight--
+	b.queue(h.msg.queue).notify()
+	return h.msg, nil
+}
+
+// requeue puts a delivery back at the front of its queue
+func (b *MemoryBroker) requeue(d Delivery) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg, err := b.settle(d)
+	if err != nil {
+		return err
+	}
+	msg.redelivered = true
+	q := b.queue(msg.queue)
+	q.messages = append([]*memoryMessage{msg}, q.messages...)
+	q.notify()
+	return nil
+}
+
+func (b *MemoryBroker) Ack(d Delivery) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err := b.settle(d)
+	return err
+}
+
+func (b *MemoryBroker) Nack(d Delivery, requeue bool) error {
+	if requeue {
+		return b.requeue(d)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg, err := b.settle(d)
+	if err != nil {
+		return err
+	}
+	if deadLetter := b.queue(msg.queue).spec.DeadLetter; deadLetter != "" {
+		msg.redelivered = false
+		msg.publishedAt = time.Now()
+		b.push(deadLetter, msg)
+	}
+	return nil
+}
+
+// Stats counts the queue's ready messages plus, for a delayed queue, the ones
+// still waiting out the delay
+func (b *MemoryBroker) Stats(ctx context.Context, queue string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q := b.queue(queue)
+	return int64(len(q.messages) + q.delayed), nil
+}
+
+func (b *MemoryBroker) Prefetch() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.prefetch
+}
+
+func (b *MemoryBroker) SetPrefetch(prefetch int) error {
+	if prefetch <= 0 {
+		return fmt.Errorf("prefetch must be positive, got %d", prefetch)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prefetch = prefetch
+	for _, q := range b.queues {
+		q.notify()
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for timer := range b.timers {
+		timer.Stop()
+		delete(b.timers, timer)
+	}
+	for _, q := range b.queues {
+		q.notify()
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// NATSBroker implements Broker on NATS JetStream. Each queue is a work-queue
+// stream with a single subject, consumed through one durable pull consumer
+// shared by every instance of the service.
+//
+// Nack with requeue naks the message for redelivery; the consumer's
+// MaxDeliver caps redeliveries, after which the message is moved to the
+// queue's dead-letter subject. Nack without requeue moves it there directly.
+// Delayed queues are forwarded by the broker itself, like KafkaBroker does.
+type NATSBroker struct {
+	cfg        *config.NATSConfig
+	conn       *nats.Conn
+	js         jetstream.JetStream
+	maxDeliver int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	specs     map[string]QueueSpec
+	consumers map[string]jetstream.ConsumerConfig // by stream name
+	prefetch  int
+	subs      []*nats.Subscription
+}
+
+// maxDeliveriesAdvisory is published by JetStream when a message exceeded
+// its consumer's MaxDeliver
+type maxDeliveriesAdvisory struct {
+	Stream    string `json:"stream"`
+	Consumer  string `json:"consumer"`
+	StreamSeq uint64 `json:"stream_seq"`
+}
+
+// NewNATSBroker connects to NATS. maxRetries bounds how often a message is
+// redelivered before it is dead-lettered.
+func NewNATSBroker(cfg *config.NATSConfig, maxRetries int) (*NATSBroker, error) {
+	conn, err := nats.Connect(cfg.URL,
+		nats.Name(cfg.Name),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			zap.L().Warn("Disconnected from NATS", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			zap.L().Info("Reconnected to NATS", zap.String("url", nc.ConnectedUrl()))
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	if maxRetries <= 0 {
+		maxRetries = 5 // default
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	zap.L().Info("Connected to NATS JetStream", zap.String("url", conn.ConnectedUrl()))
+
+	return &NATSBroker{
+		cfg:        cfg,
+		conn:       conn,
+		js:         js,
+		maxDeliver: maxRetries + 1, // the first delivery plus the retries
+		ctx:        ctx,
+		cancel:     cancel,
+		specs:      make(map[string]QueueSpec),
+		consumers:  make(map[string]jetstream.ConsumerConfig),
+	}, nil
+}
+
+// streamName maps a queue onto a valid stream name, e.g. push.queue to
+// PUSH_QUEUE
+func streamName(queue string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(queue))
+}
+
+// subject is the subject messages of a queue are published on
+func (b *NATSBroker) subject(queue string) string {
+	return b.cfg.SubjectPrefix + "." + queue
+}
+
+func (b *NATSBroker) Declare(ctx context.Context, spec QueueSpec) error {
+	replicas := b.cfg.Replicas
+	if replicas <= 0 {
+		replicas = 1 // default
+	}
+
+	_, err := b.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      streamName(spec.Name),
+		Subjects:  []string{b.subject(spec.Name)},
+		Retention: jetstream.WorkQueuePolicy,
+		Storage:   jetstream.FileStorage,
+		Replicas:  replicas,
+		MaxAge:    spec.MaxAge,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to declare stream for %s: %w", spec.Name, err)
+	}
+
+	b.mu.Lock()
+	_, declared := b.specs[spec.Name]
+	b.specs[spec.Name] = spec
+	b.mu.Unlock()
+
+	if spec.Delay > 0 && !declared {
+		b.wg.Add(1)
+		go b.forwardDelayed(spec)
+	}
+	return nil
+}
+
+func (b *NATSBroker) Publish(ctx context.Context, queue string, bodies ...[]byte) error {
+	for i, body := range bodies {
+		if _, err := b.js.Publish(ctx, b.subject(queue), body); err != nil {
+			return fmt.Errorf("publish failed at message %d of %d: %w", i+1, len(bodies), err)
+		}
+	}
+	return nil
+}
+
+// consumerConfig is the durable consumer of a queue's stream
+func (b *NATSBroker) consumerConfig(prefetch int) jetstream.ConsumerConfig {
+	ackWait := b.cfg.AckWait
+	if ackWait <= 0 {
+		ackWait = 30 * time.Second // default
+	}
+	return jetstream.ConsumerConfig{
+		Durable:       b.cfg.Durable,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       ackWait,
+		MaxDeliver:    b.maxDeliver,
+		MaxAckPending: prefetch,
+	}
+}
+
+func (b *NATSBroker) Consume(ctx context.Context, queue, tag string, prefetch int) (<-chan Delivery, error) {
+	b.mu.Lock()
+	if b.prefetch <= 0 {
+		b.prefetch = prefetch
+	}
+	prefetch = b.prefetch
+	b.mu.Unlock()
+
+	stream := streamName(queue)
+	consumerCfg := b.consumerConfig(prefetch)
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, stream, consumerCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer for %s: %w", queue, err)
+	}
+
+	// Dead-letter messages that ran out of deliveries. Every instance
+	// subscribes, in one queue group so only one of them handles each
+	// advisory and the message is dead-lettered once.
+	sub, err := b.conn.QueueSubscribe(
+		fmt.Sprintf("$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.%s.%s", stream, consumerCfg.Durable),
+		consumerCfg.Durable+"-dead-letter",
+		func(msg *nats.Msg) { b.deadLetterExhausted(queue, msg.Data) },
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to max deliveries advisories: %w", err)
+	}
+
+	iter, err := consumer.Messages(jetstream.PullMaxMessages(prefetch))
+	if err != nil {
+		sub.Unsubscribe()
+		return nil, fmt.Errorf("failed to consume %s: %w", queue, err)
+	}
+
+	b.mu.Lock()
+	b.consumers[stream] = consumerCfg
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+		defer iter.Stop()
+
+		for {
+			msg, err := iter.Next(jetstream.NextContext(ctx))
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+					return
+				}
+				zap.L().Warn("Failed to fetch NATS message", zap.String("queue", queue), zap.Error(err))
+				select {
+				case <-time.After(time.Second):
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			delivery := Delivery{
+				Body:   msg.Data(),
+				broker: b,
+				handle: msg,
+			}
+			if meta, err := msg.Metadata(); err == nil {
+				delivery.ID = fmt.Sprintf("%s/%d", meta.Stream, meta.Sequence.Stream)
+				delivery.Redelivered = meta.NumDelivered > 1
+				delivery.PublishedAt = meta.Timestamp
+			}
+			select {
+			case out <- delivery:
+			case <-ctx.Done():
+				// Unacked; redelivered after AckWait
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (b *NATSBroker) Ack(d Delivery) error {
+	msg, ok := d.handle.(jetstream.Msg)
+	if !ok {
+		return fmt.Errorf("delivery %s is not from NATS", d.ID)
+	}
+	return msg.Ack()
+}
+
+// Nack naks the delivery for redelivery if requeue is set. Otherwise it is
+// published to the queue's dead-letter subject (or dropped if there is none)
+// and acknowledged.
+func (b *NATSBroker) Nack(d Delivery, requeue bool) error {
+	msg, ok := d.handle.(jetstream.Msg)
+	if !ok {
+		return fmt.Errorf("delivery %s is not from NATS", d.ID)
+	}
+	if requeue {
+		return msg.Nak()
+	}
+
+	queue := strings.TrimPrefix(msg.Subject(), b.cfg.SubjectPrefix+".")
+	if err := b.deadLetter(queue, msg.Data(), msg.Headers()); err != nil {
+		// Leave it unacked so it is redelivered instead of lost
+		return err
+	}
+	return msg.Ack()
+}
+
+// deadLetter publishes data to queue's dead-letter subject, if it has one
+func (b *NATSBroker) deadLetter(queue string, data []byte, header nats.Header) error {
+	b.mu.Lock()
+	target := b.specs[queue].DeadLetter
+	b.mu.Unlock()
+	if target == "" {
+		return nil
+	}
+
+	if _, err := b.js.PublishMsg(b.ctx, &nats.Msg{
+		Subject: b.subject(target),
+		Header:  header,
+		Data:    data,
+	}); err != nil {
+		return fmt.Errorf("failed to dead-letter message from %s: %w", queue, err)
+	}
+	return nil
+}
+
+// deadLetterExhausted moves a message that hit MaxDeliver out of its stream
+func (b *NATSBroker) deadLetterExhausted(queue string, data []byte) {
+	var advisory maxDeliveriesAdvisory
+	if err := json.Unmarshal(data, &advisory); err != nil {
+		zap.L().Error("Failed to parse max deliveries advisory", zap.Error(err))
+		return
+	}
+
+	stream, err := b.js.Stream(b.ctx, advisory.Stream)
+	if err != nil {
+		zap.L().Error("Failed to look up stream", zap.String("stream", advisory.Stream), zap.Error(err))
+		return
+	}
+	msg, err := stream.GetMsg(b.ctx, advisory.StreamSeq)
+	if err != nil {
+		// Already acknowledged or removed by another instance
+		return
+	}
+	if err := b.deadLetter(queue, msg.Data, msg.Header); err != nil {
+		zap.L().Error("Failed to dead-letter exhausted message", zap.Error(err))
+		return
+	}
+	if err := stream.DeleteMsg(b.ctx, advisory.StreamSeq); err != nil {
+		zap.L().Warn("Failed to delete dead-lettered message",
+			zap.String("stream", advisory.Stream),
+			zap.Uint64("sequence", advisory.StreamSeq),
+			zap.Error(err),
+		)
+		return
+	}
+
+	zap.L().Warn("Message exceeded max deliveries, moved to dead letter queue",
+		zap.String("queue", queue),
+		zap.Int("max_deliver", b.maxDeliver),
+	)
+}
+
+// Stats returns the number of messages stored in the queue's stream, which
+// for a work queue are the ones not yet acknowledged
+func (b *NATSBroker) Stats(ctx context.Context, queue string) (int64, error) {
+	stream, err := b.js.Stream(ctx, streamName(queue))
+	if err != nil {
+		return 0, err
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(info.State.Msgs), nil
+}
+
+// Reconnects returns how many times the connection was re-established
+func (b *NATSBroker) Reconnects() int {
+	return int(b.conn.Stats().Reconnects)
+}
+
+func (b *NATSBroker) Prefetch() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.prefetch
+}
+
+// SetPrefetch updates the MaxAckPending of every consumer
+func (b *NATSBroker) SetPrefetch(prefetch int) error {
+	if prefetch <= 0 {
+		return fmt.Errorf("prefetch must be positive, got %d", prefetch)
+	}
+
+	b.mu.Lock()
+	b.prefetch = prefetch
+	consumers := make(map[string]jetstream.ConsumerConfig, len(b.consumers))
+	for stream, consumerCfg := range b.consumers {
+		consumerCfg.MaxAckPending = prefetch
+		b.consumers[stream] = consumerCfg
+		consumers[stream] = consumerCfg
+	}
+	b.mu.Unlock()
+
+	for stream, consumerCfg := range consumers {
+		if _, err := b.js.UpdateConsumer(b.ctx, stream, consumerCfg); err != nil {
+			return fmt.Errorf("failed to update consumer of %s: %w", stream, err)
+		}
+	}
+	return nil
+}
+
+func (b *NATSBroker) Close() error {
+	b.cancel()
+	b.wg.Wait()
+
+	b.mu.Lock()
+	for _, sub := range b.subs {
+		sub.Unsubscribe()
+	}
+	b.mu.Unlock()
+
+	return b.conn.Drain()
+}
+
+// forwardDelayed moves each message of a delayed queue to its target once it
+// has waited spec.Delay. The stream is consumed in order, so waiting for the
+// head message never holds back an older one.
+func (b *NATSBroker) forwardDelayed(spec QueueSpec) {
+	defer b.wg.Done()
+
+	consumerCfg := b.consumerConfig(1)
+	// The message stays unacknowledged while it waits
+	consumerCfg.AckWait = spec.Delay + time.Minute
+	consumerCfg.MaxDeliver = -1
+
+	var consumer jetstream.Consumer
+	for {
+		var err error
+		consumer, err = b.js.CreateOrUpdateConsumer(b.ctx, streamName(spec.Name), consumerCfg)
+		if err == nil {
+			break
+		}
+		zap.L().Warn("Failed to create delayed queue consumer", zap.String("queue", spec.Name), zap.Error(err))
+		select {
+		case <-time.After(time.Second):
+		case <-b.ctx.Done():
+			return
+		}
+	}
+
+	for {
+		msg, err := consumer.Next(jetstream.FetchMaxWait(5 * time.Second))
+		if err != nil {
+			if b.ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, nats.ErrTimeout) {
+				zap.L().Warn("Failed to fetch delayed NATS message", zap.String("queue", spec.Name), zap.Error(err))
+			}
+			continue
+		}
+
+		if meta, err := msg.Metadata(); err == nil {
+			select {
+			case <-time.After(time.Until(meta.Timestamp.Add(spec.Delay))):
+			case <-b.ctx.Done():
+				return
+			}
+		}
+
+		if _, err := b.js.PublishMsg(b.ctx, &nats.Msg{
+			Subject: b.subject(spec.DelayTarget),
+			Header:  msg.Headers(),
+			Data:    msg.Data(),
+		}); err != nil {
+			zap.L().Warn("Failed to forward delayed NATS message",
+				zap.String("queue", spec.Name),
+				zap.String("target", spec.DelayTarget),
+				zap.Error(err),
+			)
+			msg.Nak()
+			continue
+		}
+		if err := msg.Ack(); err != nil {
+			zap.L().Warn("Failed to ack delayed NATS message", zap.String("queue", spec.Name), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// notifyChannel is the LISTEN/NOTIFY channel announcing new messages; the
+// payload is the queue name
+const notifyChannel = "queue_messages"
+
+// PostgresBroker implements Broker on the queue_messages table, for small
+// deployments that don't run a message broker. Consumers claim messages with
+// FOR UPDATE SKIP LOCKED and are woken by NOTIFY when messages are published;
+// they also poll, which picks up delayed messages that became due and claims
+// that expired because their consumer died.
+type PostgresBroker struct {
+	pool *pgxpool.Pool
+	cfg  *config.PostgresQueueConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	specs     map[string]QueueSpec
+	listeners map[string][]chan struct{}
+	listening bool
+	prefetch  int
+}
+
+type postgresHandle struct {
+	id       int64
+	queue    string
+	consumer *postgresConsumer
+}
+
+type postgresConsumer struct {
+	mu       sync.Mutex
+	inflight int
+	wake     chan struct{}
+}
+
+func NewPostgresBroker(pool *pgxpool.Pool, cfg *config.PostgresQueueConfig) *PostgresBroker {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &PostgresBroker{
+		pool:      pool,
+		cfg:       cfg,
+		ctx:       ctx,
+		cancel:    cancel,
+		specs:     make(map[string]QueueSpec),
+		listeners: make(map[string][]chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go b.expireMessages()
+	return b
+}
+
+func (b *PostgresBroker) pollInterval() time.Duration {
+	if b.cfg.PollInterval <= 0 {
+		return time.Second // default
+	}
+	return b.cfg.PollInterval
+}
+
+func (b *PostgresBroker) visibilityTimeout() time.Duration {
+	if b.cfg.VisibilityTimeout <= 0 {
+		return 5 * time.Minute // default
+	}
+	return b.cfg.VisibilityTimeout
+}
+
+func (b *PostgresBroker) spec(queue string) QueueSpec {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.specs[queue]
+}
+
+// Declare records the queue's spec; all queues share the queue_messages table
+func (b *PostgresBroker) Declare(ctx context.Context, spec QueueSpec) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.specs[spec.Name] = spec
+	return nil
+}
+
+// Publish inserts the messages in one transaction. Messages for a delayed
+// queue are inserted into its target queue, becoming available after the
+// delay.
+func (b *PostgresBroker) Publish(ctx context.Context, queue string, bodies ...[]byte) error {
+	target, delay, delayedIn := queue, time.Duration(0), ""
+	if spec := b.spec(queue); spec.Delay > 0 {
+		target, delay, delayedIn = spec.DelayTarget, spec.Delay, queue
+	}
+
+	tx, err := b.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for i, body := range bodies {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO queue_messages (queue, body, delayed_in, available_at)
+			VALUES ($1, $2, NULLIF($3, ''), NOW() + make_interval(secs => $4::float8))
+		`, target, body, delayedIn, delay.Seconds()); err != nil {
+			return fmt.Errorf("publish failed at message %d of %d: %w", i+1, len(bodies), err)
+		}
+	}
+	if delay == 0 {
+		if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, target); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (b *PostgresBroker) Consume(ctx context.Context, queue, tag string, prefetch int) (<-chan Delivery, error) {
+	b.mu.Lock()
+	if b.prefetch <= 0 {
+		b.prefetch = prefetch
+	}
+	consumer := &postgresConsumer{wake: make(chan struct{}, 1)}
+	b.listeners[queue] = append(b.listeners[queue], consumer.wake)
+	if !b.listening {
+		b.listening = true
+		b.wg.Add(1)
+		go b.listen()
+	}
+	b.mu.Unlock()
+
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+		defer b.removeListener(queue, consumer.wake)
+
+		for {
+			deliveries, err := b.claim(ctx, queue, consumer)
+			if err != nil && ctx.Err() == nil {
+				zap.L().Warn("Failed to claim queue messages", zap.String("queue", queue), zap.Error(err))
+			}
+			for i, delivery := range deliveries {
+				select {
+				case out <- delivery:
+				case <-ctx.Done():
+					// Release the undelivered claims right away instead of
+					// waiting for the visibility timeout
+					for _, undelivered := range deliveries[i:] {
+						b.Nack(undelivered, true)
+					}
+					return
+				}
+			}
+			if len(deliveries) > 0 {
+				continue
+			}
+
+			select {
+			case <-consumer.wake:
+			case <-time.After(b.pollInterval()):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// claim locks up to the consumer's free prefetch slots of ready messages
+func (b *PostgresBroker) claim(ctx context.Context, queue string, consumer *postgresConsumer) ([]Delivery, error) {
+	consumer.mu.Lock()
+	free := b.Prefetch() - consumer.inflight
+	consumer.mu.Unlock()
+	if free <= 0 {
+		return nil, nil
+	}
+
+	maxAge := b.spec(queue).MaxAge
+	// A message whose lock had expired was claimed before by a consumer that
+	// never settled it, so it counts as redelivered
+	rows, err := b.pool.Query(ctx, `
+		WITH claimed AS (
+			SELECT id, locked_until IS NOT NULL AS expired
+			FROM queue_messages
+			WHERE queue = $1
+				AND available_at <= NOW()
+				AND (locked_until IS NULL OR locked_until < NOW())
+				AND ($4::float8 = 0 OR created_at > NOW() - make_interval(secs => $4::float8))
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE queue_messages m
+		SET locked_until = NOW() + make_interval(secs => $3::float8), delayed_in = NULL
+		FROM claimed
+		WHERE m.id = claimed.id
+		RETURNING m.id, m.body, m.redelivered OR claimed.expired, m.created_at
+	`, queue, free, b.visibilityTimeout().Seconds(), maxAge.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		var id int64
+		var body []byte
+		var redelivered bool
+		var createdAt time.Time
+		if err := rows.Scan(&id, &body, &redelivered, &createdAt); err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, Delivery{
+			ID:          strconv.FormatInt(id, 10),
+			Body:        body,
+			Redelivered: redelivered,
+			PublishedAt: createdAt,
+			broker:      b,
+			handle:      &postgresHandle{id: id, queue: queue, consumer: consumer},
+		})
+	}
+
+	consumer.mu.Lock()
+	consumer.inflight += len(deliveries)
+	consumer.mu.Unlock()
+	return deliveries, rows.Err()
+}
+
+// release frees the delivery's prefetch slot
+func (b *PostgresBroker) release(d Delivery) (*postgresHandle, error) {
+	h, ok := d.handle.(*postgresHandle)
+	if !ok {
+		return nil, fmt.Errorf("delivery %s is not from postgres", d.ID)
+	}
+	h.consumer.mu.Lock()
+	h.consumer.inflight--
+	h.consumer.mu.Unlock()
+	select {
+	case h.consumer.wake <- struct{}{}:
+	default:
+	}
+	return h, nil
+}
+
+func (b *PostgresBroker) Ack(d Delivery) error {
+	h, err := b.release(d)
+	if err != nil {
+		return err
+	}
+	_, err = b.pool.Exec(b.ctx, `DELETE FROM queue_messages WHERE id = $1`, h.id)
+	return err
+}
+
+// Nack unlocks the message for redelivery if requeue is set, and otherwise
+// moves it to the queue's dead letter queue or deletes it if there is none
+func (b *PostgresBroker) Nack(d Delivery, requeue bool) error {
+	h, err := b.release(d)
+	if err != nil {
+		return err
+	}
+
+	if requeue {
+		_, err = b.pool.Exec(b.ctx, `
+			UPDATE queue_messages SET locked_until = NULL, redelivered = true WHERE id = $1
+		`, h.id)
+		if err == nil {
+			_, err = b.pool.Exec(b.ctx, `SELECT pg_notify($1, $2)`, notifyChannel, h.queue)
+		}
+		return err
+	}
+
+	deadLetter := b.spec(h.queue).DeadLetter
+	if deadLetter == "" {
+		_, err = b.pool.Exec(b.ctx, `DELETE FROM queue_messages WHERE id = $1`, h.id)
+		return err
+	}
+	_, err = b.pool.Exec(b.ctx, `
+		UPDATE queue_messages
+		SET queue = $2, locked_until = NULL, redelivered = false, available_at = NOW(), created_at = NOW()
+		WHERE id = $1
+	`, h.id, deadLetter)
+	return err
+}
+
+// Stats counts a queue's waiting messages; for a delayed queue, the messages
+// still waiting out its delay
+func (b *PostgresBroker) Stats(ctx context.Context, queue string) (int64, error) {
+	var count int64
+	var err error
+	if spec := b.spec(queue); spec.Delay > 0 {
+		err = b.pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM queue_messages WHERE delayed_in = $1 AND available_at > NOW()
+		`, queue).Scan(&count)
+	} else {
+		err = b.pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM queue_messages
+			WHERE queue = $1 AND available_at <= NOW() AND (locked_until IS NULL OR locked_until < NOW())
+		`, queue).Scan(&count)
+	}
+	return count, err
+}
+
+func (b *PostgresBroker) Prefetch() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.prefetch
+}
+
+func (b *PostgresBroker) SetPrefetch(prefetch int) error {
+	if prefetch <= 0 {
+		return fmt.Errorf("prefetch must be positive, got %d", prefetch)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prefetch = prefetch
+	for _, wakes := range b.listeners {
+		for _, wake := range wakes {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// Close stops the listener and expiry loops; the pool belongs to the caller
+func (b *PostgresBroker) Close() error {
+	b.cancel()
+	b.wg.Wait()
+	return nil
+}
+
+func (b *PostgresBroker) removeListener(queue string, wake chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wakes := b.listeners[queue]
+	for i, w := range wakes {
+		if w == wake {
+			b.listeners[queue] = append(wakes[:i], wakes[i+1:]...)
+			break
+		}
+	}
+}
+
+// listen holds a connection LISTENing for new messages and wakes the
+// consumers of the notified queue. If the connection drops, consumers keep
+// polling until it is re-established.
+func (b *PostgresBroker) listen() {
+	defer b.wg.Done()
+
+	for b.ctx.Err() == nil {
+		if err := b.listenOnce(); err != nil && b.ctx.Err() == nil {
+			zap.L().Warn("Queue notification listener failed, retrying", zap.Error(err))
+			select {
+			case <-time.After(b.pollInterval()):
+			case <-b.ctx.Done():
+			}
+		}
+	}
+}
+
+func (b *PostgresBroker) listenOnce() error {
+	conn, err := b.pool.Acquire(b.ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(b.ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	// The connection goes back to the pool; stop listening on it
+	defer conn.Exec(context.Background(), "UNLISTEN "+notifyChannel)
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(b.ctx)
+		if err != nil {
+			return err
+		}
+
+		b.mu.Lock()
+		for _, wake := range b.listeners[notification.Payload] {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// expireMessages deletes messages older than their queue's MaxAge, e.g. in
+// the dead letter queue, which has no consumer to skip them
+func (b *PostgresBroker) expireMessages() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.ctx.Done():
+			return
+		}
+
+		b.mu.Lock()
+		specs := make([]QueueSpec, 0, len(b.specs))
+		for _, spec := range b.specs {
+			if spec.MaxAge > 0 {
+				specs = append(specs, spec)
+			}
+		}
+		b.mu.Unlock()
+
+		for _, spec := range specs {
+			if _, err := b.pool.Exec(b.ctx, `
+				DELETE FROM queue_messages
+				WHERE queue = $1 AND created_at < NOW() - make_interval(secs => $2::float8)
+			`, spec.Name, spec.MaxAge.Seconds()); err != nil && b.ctx.Err() == nil {
+				zap.L().Warn("Failed to expire queue messages", zap.String("queue", spec.Name), zap.Error(err))
+			}
+		}
+	}
+}
@@ -2,12 +2,16 @@ package queue
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"push-service/internal/config"
 	"push-service/internal/models"
-	"push-service/pkg/rabbitmq"
+	"push-service/pkg/tracing"
+	"strings"
+	"sync"
 	"time"
 
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -19,144 +23,493 @@ const (
 	DeadLetterExchange   = "push_dlx"
 	GatewayPushQueueName = "push.queue"
 	GatewayExchangeName  = "notifications.direct"
+	EventsQueueName      = "push_events"
+	MalformedQueueName   = "push_malformed"
 )
 
+// Priorities lists the message priorities, highest first. Each priority has
+// its own push queue and retry tiers, so the worker can drain the higher
+// ones first; normal keeps the original queue names.
+var Priorities = []string{models.PriorityCritical, models.PriorityHigh, models.PriorityNormal, models.PriorityLow}
+
 type PushQueue struct {
-	rabbitmqClient *rabbitmq.RabbitMQClient
-	cfg            *config.QueueConfig
+	broker      Broker
+	cfg         *config.QueueConfig
+	retryTiers  []time.Duration
+	retryQueues map[string][]string // by priority
+
+	// Retry limits, changed by SetRetryLimits
+	mu          sync.RWMutex
+	retryMax    int
+	retryMaxCap int
 }
 
-func NewPushQueue(rabbitmqClient *rabbitmq.RabbitMQClient, cfg *config.QueueConfig) (*PushQueue, error) {
+func NewPushQueue(broker Broker, cfg *config.QueueConfig, retention *config.RetentionConfig) (*PushQueue, error) {
 	ctx := context.Background()
 
-	// Set up dead letter exchange
-	if err := rabbitmqClient.EnsureExchange(ctx, DeadLetterExchange, "direct"); err != nil {
-		return nil, err
+	deadLetterAge := retention.DeadLetters
+	if deadLetterAge <= 0 {
+		deadLetterAge = 7 * 24 * time.Hour // default
 	}
 
-	// Set up main exchange
-	if err := rabbitmqClient.EnsureExchange(ctx, PushExchangeName, "direct"); err != nil {
+	// Set up dead letter queue
+	if err := broker.Declare(ctx, QueueSpec{
+		Name:   DeadLetterQueue,
+		MaxAge: deadLetterAge,
+	}); err != nil {
 		return nil, err
 	}
 
-	// Set up dead letter queue with arguments
-	dlqArgs := amqp.Table{
-		"x-message-ttl": int64(7 * 24 * time.Hour / time.Millisecond), // 7 days
-	}
-	if err := rabbitmqClient.EnsureQueue(ctx, DeadLetterQueue, dlqArgs); err != nil {
+	// Set up events queue for downstream consumers; unconsumed events expire
+	if err := broker.Declare(ctx, QueueSpec{
+		Name:   EventsQueueName,
+		MaxAge: 7 * 24 * time.Hour, // 7 days
+	}); err != nil {
 		return nil, err
 	}
-	if err := rabbitmqClient.BindQueue(ctx, DeadLetterQueue, DeadLetterExchange, "dead_letter"); err != nil {
+
+	// Set up the queue for messages that can never be processed, kept for
+	// inspection
+	if err := broker.Declare(ctx, QueueSpec{
+		Name:   MalformedQueueName,
+		MaxAge: deadLetterAge,
+	}); err != nil {
 		return nil, err
 	}
 
-	// Set up retry queue with DLX
-	retryArgs := amqp.Table{
-		"x-dead-letter-exchange":    PushExchangeName,
-		"x-dead-letter-routing-key": PushQueueName,
+	tiers := retryTiers(&cfg.Retry)
+	retryQueues := make(map[string][]string, len(Priorities))
+	for _, priority := range Priorities {
+		// Set up one retry queue per delay tier. Messages are held for the
+		// tier's delay and then moved back to their priority's queue.
+		pushQueue := PushQueueFor(priority)
+		retryQueues[priority] = make([]string, len(tiers))
+		for i, delay := range tiers {
+			retryQueues[priority][i] = retryQueueName(priority, delay)
+			if err := broker.Declare(ctx, QueueSpec{
+				Name:        retryQueues[priority][i],
+				Delay:       delay,
+				DelayTarget: pushQueue,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		// Set up the priority's push queue, dead-lettering rejected messages
+		if err := broker.Declare(ctx, QueueSpec{
+			Name:       pushQueue,
+			DeadLetter: DeadLetterQueue,
+		}); err != nil {
+			return nil, err
+		}
 	}
-	if err := rabbitmqClient.EnsureQueue(ctx, RetryQueueName, retryArgs); err != nil {
-		return nil, err
+
+	zap.L().Info("Push queue initialized",
+		zap.String("queue", PushQueueName),
+		zap.Strings("priorities", Priorities),
+		zap.Strings("retry_queues", retryQueues[models.PriorityNormal]),
+	)
+
+	q := &PushQueue{
+		broker:      broker,
+		cfg:         cfg,
+		retryTiers:  tiers,
+		retryQueues: retryQueues,
 	}
-	if err := rabbitmqClient.BindQueue(ctx, RetryQueueName, PushExchangeName, RetryQueueName); err != nil {
-		return nil, err
+	q.SetRetryLimits(cfg.Retry.MaxRetries, cfg.Retry.MaxRetriesCap)
+	return q, nil
+}
+
+// SetRetryLimits changes the max retries and the cap on per-message
+// overrides of it; 0 uses the default. The retry tiers can't change, as
+// their queues are declared up front.
+func (q *PushQueue) SetRetryLimits(maxRetries, maxRetriesCap int) {
+	if maxRetries == 0 {
+		maxRetries = 5 // default
 	}
+	if maxRetriesCap <= 0 {
+		maxRetriesCap = 10 // default
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retryMax = maxRetries
+	q.retryMaxCap = maxRetriesCap
+}
 
-	// Set up main push queue with DLX
-	pushArgs := amqp.Table{
-		"x-dead-letter-exchange":    DeadLetterExchange,
-		"x-dead-letter-routing-key": "dead_letter",
+// retryTiers returns the configured retry delays
+func retryTiers(cfg *config.RetryConfig) []time.Duration {
+	if len(cfg.Tiers) == 0 {
+		return []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute} // default
 	}
-	if err := rabbitmqClient.EnsureQueue(ctx, PushQueueName, pushArgs); err != nil {
-		return nil, err
+	return cfg.Tiers
+}
+
+// PushQueueFor returns the push queue of a priority, e.g.
+// push_notifications_critical. Normal and unknown priorities use
+// push_notifications.
+func PushQueueFor(priority string) string {
+	if priority == "" || priority == models.PriorityNormal || !isPriority(priority) {
+		return PushQueueName
 	}
-	if err := rabbitmqClient.BindQueue(ctx, PushQueueName, PushExchangeName, PushQueueName); err != nil {
-		return nil, err
+	return fmt.Sprintf("%s_%s", PushQueueName, priority)
+}
+
+// IsPushQueue reports whether name is the push queue of some priority
+func IsPushQueue(name string) bool {
+	for _, priority := range Priorities {
+		if name == PushQueueFor(priority) {
+			return true
+		}
 	}
+	return false
+}
 
-	zap.L().Info("Push queue initialized with RabbitMQ",
-		zap.String("exchange", PushExchangeName),
-		zap.String("queue", PushQueueName),
-	)
+func isPriority(priority string) bool {
+	for _, p := range Priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
 
-	return &PushQueue{
-		rabbitmqClient: rabbitmqClient,
-		cfg:            cfg,
-	}, nil
+// retryQueueName names a tier's queue after its priority and delay, e.g.
+// push_retries_2m for normal and push_retries_critical_2m
+func retryQueueName(priority string, delay time.Duration) string {
+	name := delay.String()
+	if strings.HasSuffix(name, "m0s") {
+		name = strings.TrimSuffix(name, "0s")
+	}
+	if strings.HasSuffix(name, "h0m") {
+		name = strings.TrimSuffix(name, "0m")
+	}
+	if PushQueueFor(priority) == PushQueueName {
+		return fmt.Sprintf("%s_%s", RetryQueueName, name)
+	}
+	return fmt.Sprintf("%s_%s_%s", RetryQueueName, priority, name)
 }
 
 type PushMessage struct {
 	Notification models.PushNotification `json:"notification"`
 	DeviceTokens []string                `json:"device_tokens"`
 	RetryCount   int                     `json:"retry_count"`
+
+	// Platforms maps device tokens to their platform where it is known, to
+	// label delivery outcomes
+	Platforms map[string]string `json:"platforms,omitempty"`
+
+	// CampaignID is set for campaign sends, whose quota is reserved up front
+	CampaignID string `json:"campaign_id,omitempty"`
+	// Variant is the campaign's A/B variant the message carries, if any
+	Variant string `json:"variant,omitempty"`
+	// TenantID is the sending tenant, whose webhooks get the message's
+	// delivery status
+	TenantID string `json:"tenant_id,omitempty"`
+	// SuppressIfConnected skips the push if the user has a realtime
+	// connection, which gets the notification anyway
+	SuppressIfConnected bool `json:"suppress_if_connected,omitempty"`
+	// ProjectID selects the FCM project to send through; empty is the primary
+	ProjectID string `json:"project_id,omitempty"`
+
+	// DryRunID, if set, has the worker validate the message with FCM instead
+	// of delivering it and record the per-token results under this dry run
+	DryRunID string `json:"dry_run_id,omitempty"`
+
+	// Vars are the recipient's template variables, rendered into the
+	// notification's {{name}} placeholders when the message is processed
+	Vars map[string]string `json:"vars,omitempty"`
+
+	// Bundle, if set, replaces Notification: each device gets every item in
+	// order, and visible items only after the silent ones before them
+	Bundle []models.PushNotification `json:"bundle,omitempty"`
+
+	// Raw, if set, is a complete FCM message that is sent as is instead of
+	// Notification, to its own token, topic or condition
+	Raw json.RawMessage `json:"raw,omitempty"`
+
+	// TraceContext carries the publisher's trace, so processing the message
+	// continues it and follows its sampling decision
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+
+	// Queue is the queue the message was last published to, e.g. the retry
+	// tier it waited in before coming back to the main queue
+	Queue string `json:"queue,omitempty"`
+	// MaxRetries and RetryBackoff override the configured retry policy for
+	// this message. A backoff makes every retry wait in the shortest tier at
+	// least that long, instead of escalating through the tiers.
+	MaxRetries   *int          `json:"max_retries,omitempty"`
+	RetryBackoff time.Duration `json:"retry_backoff,omitempty"`
+
+	// LastError is why the message was last retried outside the normal send
+	// path, e.g. the panic its processing recovered from
+	LastError string `json:"last_error,omitempty"`
+
+	// Priority selects the push queue and retry tiers of the message; empty
+	// is normal
+	Priority string `json:"priority,omitempty"`
+
+	// BypassQuietHours and BypassFrequencyCap override the policy of the
+	// notification's category. PolicyApplied is set once the category's
+	// frequency cap and quiet hours were applied, so retries, requeues and
+	// deliveries held back for quiet hours don't apply them again.
+	BypassQuietHours   *bool `json:"bypass_quiet_hours,omitempty"`
+	BypassFrequencyCap bool  `json:"bypass_frequency_cap,omitempty"`
+	PolicyApplied      bool  `json:"policy_applied,omitempty"`
 }
 
-func (q *PushQueue) EnqueuePush(ctx context.Context, notification models.PushNotification, deviceTokens []string) error {
-	message := PushMessage{
+// publish encodes messages and publishes them to queue
+func (q *PushQueue) publish(ctx context.Context, queue string, messages ...PushMessage) error {
+	traceContext := tracing.Inject(ctx)
+	bodies := make([][]byte, len(messages))
+	for i, message := range messages {
+		if traceContext != nil {
+			message.TraceContext = traceContext
+		}
+		if message.Notification.ID == "" && len(message.Bundle) == 0 {
+			// Every notification needs an ID to look up its delivery attempts
+			message.Notification.ID = uuid.NewString()
+		}
+		if message.Notification.CreatedAt.IsZero() {
+			// Workers drop messages created before their user's data was deleted
+			message.Notification.CreatedAt = time.Now().UTC()
+		}
+		message.Queue = queue
+		body, err := EncodePushMessage(message, q.cfg.Encoding)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message %d: %w", i+1, err)
+		}
+		bodies[i] = body
+	}
+	return q.broker.Publish(ctx, queue, bodies...)
+}
+
+// publishPush publishes messages to the push queues of their priorities,
+// keeping their order within each priority
+func (q *PushQueue) publishPush(ctx context.Context, messages ...PushMessage) error {
+	byQueue := make(map[string][]PushMessage)
+	for _, message := range messages {
+		name := PushQueueFor(message.Priority)
+		byQueue[name] = append(byQueue[name], message)
+	}
+	for _, priority := range Priorities {
+		name := PushQueueFor(priority)
+		if len(byQueue[name]) == 0 {
+			continue
+		}
+		if err := q.publish(ctx, name, byQueue[name]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnqueuePush publishes a notification for the given tokens. platforms maps
+// tokens to their device platform and may be nil.
+func (q *PushQueue) EnqueuePush(ctx context.Context, notification models.PushNotification, deviceTokens []string, platforms map[string]string) error {
+	return q.EnqueueMessage(ctx, PushMessage{
 		Notification: notification,
 		DeviceTokens: deviceTokens,
+		Platforms:    platforms,
 		RetryCount:   0,
-	}
+	})
+}
 
-	if err := q.rabbitmqClient.Enqueue(ctx, PushExchangeName, PushQueueName, message); err != nil {
+// EnqueueMessage publishes a push message built by the caller, e.g. with a
+// retry policy override
+func (q *PushQueue) EnqueueMessage(ctx context.Context, message PushMessage) error {
+	if err := q.publishPush(ctx, message); err != nil {
 		zap.L().Error("Failed to enqueue push message", zap.Error(err))
 		return err
 	}
 
 	zap.L().Info("Push message enqueued",
+		zap.Int("device_count", len(message.DeviceTokens)),
+		zap.String("title", message.Notification.Title),
+	)
+	return nil
+}
+
+// EnqueueBundle publishes a notification bundle as a single message on
+// priority's queue, so it is enqueued all-or-nothing.
+func (q *PushQueue) EnqueueBundle(ctx context.Context, bundle []models.PushNotification, deviceTokens []string, priority string) error {
+	message := PushMessage{
+		Notification: models.PushNotification{
+			UserID:   bundle[0].UserID,
+			BundleID: bundle[0].BundleID,
+			Status:   "queued",
+		},
+		DeviceTokens: deviceTokens,
+		Bundle:       bundle,
+		Priority:     priority,
+	}
+
+	if err := q.publishPush(ctx, message); err != nil {
+		zap.L().Error("Failed to enqueue push bundle", zap.Error(err))
+		return err
+	}
+
+	zap.L().Info("Push bundle enqueued",
 		zap.Int("device_count", len(deviceTokens)),
-		zap.String("title", notification.Title),
+		zap.Int("item_count", len(bundle)),
 	)
 	return nil
 }
 
-func (q *PushQueue) ConsumePush(ctx context.Context) (<-chan amqp.Delivery, error) {
+// Requeue puts a message back on its push queue without counting a retry
+func (q *PushQueue) Requeue(ctx context.Context, message PushMessage) error {
+	return q.publishPush(ctx, message)
+}
+
+// EnqueuePushBatch publishes several push messages in one call, amortizing
+// per-publish overhead for bulk sends.
+func (q *PushQueue) EnqueuePushBatch(ctx context.Context, messages []PushMessage) error {
+	if err := q.publishPush(ctx, messages...); err != nil {
+		zap.L().Error("Failed to enqueue push batch", zap.Error(err))
+		return err
+	}
+
+	zap.L().Info("Push message batch enqueued", zap.Int("message_count", len(messages)))
+	return nil
+}
+
+// ConsumePush consumes the push queue of every priority. The channels are
+// in Priorities order, highest first.
+func (q *PushQueue) ConsumePush(ctx context.Context) ([]<-chan Delivery, error) {
 	prefetchCount := q.cfg.Worker.PrefetchCount
 	if prefetchCount == 0 {
 		prefetchCount = 10 // default
 	}
-	return q.rabbitmqClient.Consume(ctx, PushQueueName, prefetchCount)
-}
 
-func (q *PushQueue) EnqueueRetry(ctx context.Context, message PushMessage) error {
-	message.RetryCount++
+	msgs := make([]<-chan Delivery, len(Priorities))
+	for i, priority := range Priorities {
+		ch, err := Consume(ctx, q.broker, PushQueueFor(priority), "push-"+priority, prefetchCount)
+		if err != nil {
+			return nil, err
+		}
+		msgs[i] = ch
+	}
+	return msgs, nil
+}
 
-	maxRetries := q.cfg.Retry.MaxRetries
-	if maxRetries == 0 {
-		maxRetries = 5 // default
+// EnqueueRetry publishes message to the retry tier of its next retry, or to
+// the dead letter queue past the max retries. Tokens results show were
+// delivered are stripped first, so a partly delivered message is only
+// retried for the rest; if none are left, nothing is published.
+func (q *PushQueue) EnqueueRetry(ctx context.Context, message PushMessage, results ...models.AttemptTokenResult) error {
+	if len(results) > 0 {
+		message.DeviceTokens = UndeliveredTokens(message.DeviceTokens, results)
+		if len(message.DeviceTokens) == 0 {
+			zap.L().Info("Every device token was delivered, nothing to retry",
+				zap.String("notification_id", message.Notification.ID),
+			)
+			return nil
+		}
 	}
 
-	if message.RetryCount > maxRetries {
+	retryQueue := q.RetryQueue(message)
+	message.RetryCount++
+
+	if retryQueue == DeadLetterQueue {
 		// Move to dead letter queue after max retries
 		zap.L().Warn("Message exceeded max retries, moving to dead letter queue",
 			zap.Int("retry_count", message.RetryCount),
-			zap.Int("max_retries", maxRetries),
+			zap.Int("max_retries", q.maxRetries(message)),
 		)
-		return q.rabbitmqClient.Enqueue(ctx, DeadLetterExchange, "dead_letter", message)
+		return q.publish(ctx, DeadLetterQueue, message)
 	}
 
-	// Calculate backoff delay
-	backoff := q.cfg.Retry.Backoff
-	if backoff == 0 {
-		backoff = 5 * time.Second // default
-	}
-	delay := time.Duration(message.RetryCount) * backoff
-
 	zap.L().Info("Enqueuing retry",
 		zap.Int("retry_count", message.RetryCount),
-		zap.Duration("delay", delay),
+		zap.String("retry_queue", retryQueue),
 	)
 
-	// Publish to retry queue with delay
-	return q.rabbitmqClient.EnqueueWithDelay(ctx, PushExchangeName, RetryQueueName, message, delay)
+	// Publish to the tier's retry queue; it moves back when the delay is up
+	return q.publish(ctx, retryQueue, message)
+}
+
+// UndeliveredTokens returns the tokens that results don't show were
+// delivered
+func UndeliveredTokens(tokens []string, results []models.AttemptTokenResult) []string {
+	delivered := make(map[string]bool, len(results))
+	for _, result := range results {
+		if result.Success && result.DeviceToken != "" {
+			delivered[result.DeviceToken] = true
+		}
+	}
+
+	remaining := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !delivered[token] {
+			remaining = append(remaining, token)
+		}
+	}
+	return remaining
+}
+
+// RetryQueue returns the queue EnqueueRetry publishes message to: the retry
+// tier of its next retry, or the dead letter queue past the max retries
+func (q *PushQueue) RetryQueue(message PushMessage) string {
+	retryCount := message.RetryCount + 1
+	if retryCount > q.maxRetries(message) {
+		return DeadLetterQueue
+	}
+
+	retryQueues := q.retryQueues[models.PriorityNormal]
+	if queues, ok := q.retryQueues[message.Priority]; ok {
+		retryQueues = queues
+	}
+
+	if message.RetryBackoff > 0 {
+		// The shortest tier that waits at least the backoff, else the longest
+		best, longest := -1, 0
+		for i, delay := range q.retryTiers {
+			if delay >= message.RetryBackoff && (best < 0 || delay < q.retryTiers[best]) {
+				best = i
+			}
+			if delay > q.retryTiers[longest] {
+				longest = i
+			}
+		}
+		if best < 0 {
+			best = longest
+		}
+		return retryQueues[best]
+	}
+
+	// Retry n waits in tier n; later retries reuse the last tier
+	tier := retryCount - 1
+	if tier >= len(retryQueues) {
+		tier = len(retryQueues) - 1
+	}
+	return retryQueues[tier]
+}
+
+// maxRetries returns message's max retries: its override if it has one,
+// capped at the configured cap, else the configured max
+func (q *PushQueue) maxRetries(message PushMessage) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if message.MaxRetries != nil {
+		if *message.MaxRetries > q.retryMaxCap {
+			return q.retryMaxCap
+		}
+		return *message.MaxRetries
+	}
+	return q.retryMax
 }
 
 func (q *PushQueue) GetQueueStats(ctx context.Context) (map[string]int64, error) {
 	stats := make(map[string]int64)
 
-	queues := []string{PushQueueName, RetryQueueName, DeadLetterQueue}
+	var queues []string
+	for _, priority := range Priorities {
+		queues = append(queues, PushQueueFor(priority))
+		queues = append(queues, q.retryQueues[priority]...)
+	}
+	queues = append(queues, DeadLetterQueue)
 	for _, queueName := range queues {
-		length, err := q.rabbitmqClient.QueueLength(ctx, queueName)
+		length, err := q.broker.Stats(ctx, queueName)
 		if err != nil {
 			zap.L().Warn("Failed to get queue length",
 				zap.String("queue", queueName),
@@ -172,25 +525,102 @@ func (q *PushQueue) GetQueueStats(ctx context.Context) (map[string]int64, error)
 	return stats, nil
 }
 
-// GetRabbitMQClient returns the underlying RabbitMQ client for ack/nack operations
-func (q *PushQueue) GetRabbitMQClient() *rabbitmq.RabbitMQClient {
-	return q.rabbitmqClient
+// Backlog returns the number of messages waiting to be processed now, in
+// the push queue of every priority and the gateway queue, and each queue's
+// depth. Messages waiting in a retry tier aren't due yet and don't count.
+func (q *PushQueue) Backlog(ctx context.Context) (int64, map[string]int64, error) {
+	queues := make([]string, 0, len(Priorities)+1)
+	for _, priority := range Priorities {
+		queues = append(queues, PushQueueFor(priority))
+	}
+	queues = append(queues, GatewayPushQueueName)
+
+	var backlog int64
+	depths := make(map[string]int64, len(queues))
+	for _, queueName := range queues {
+		length, err := q.broker.Stats(ctx, queueName)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get length of queue %s: %w", queueName, err)
+		}
+		depths[queueName] = length
+		backlog += length
+	}
+	return backlog, depths, nil
 }
 
-// ConsumeFromGateway consumes messages from the API Gateway's push.queue
-func (q *PushQueue) ConsumeFromGateway(ctx context.Context) (<-chan amqp.Delivery, error) {
-	// Ensure the gateway exchange exists
-	if err := q.rabbitmqClient.EnsureExchange(ctx, GatewayExchangeName, "direct"); err != nil {
-		return nil, err
+// PublishEvent publishes a domain event of eventType to the events queue
+func (q *PushQueue) PublishEvent(ctx context.Context, eventType string, data map[string]any) error {
+	body, err := json.Marshal(models.Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
 	}
+	return q.broker.Publish(ctx, EventsQueueName, body)
+}
 
-	// Ensure the gateway queue exists
-	if err := q.rabbitmqClient.EnsureQueue(ctx, GatewayPushQueueName, nil); err != nil {
-		return nil, err
+// MalformedMessage is a message that was rejected as malformed, as it is
+// published to the malformed queue
+type MalformedMessage struct {
+	// Source is the queue the message came from
+	Source string `json:"source"`
+	// Error is why the message was rejected, and Violations the schema
+	// violations if it didn't match its schema
+	Error         string    `json:"error"`
+	SchemaVersion int       `json:"schema_version,omitempty"`
+	Violations    []string  `json:"violations,omitempty"`
+	Body          string    `json:"body"`
+	ReceivedAt    time.Time `json:"received_at"`
+}
+
+// PublishMalformed publishes a message to the malformed queue with why it
+// was rejected
+func (q *PushQueue) PublishMalformed(ctx context.Context, message MalformedMessage) error {
+	if message.ReceivedAt.IsZero() {
+		message.ReceivedAt = time.Now().UTC()
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal malformed message: %w", err)
+	}
+	return q.broker.Publish(ctx, MalformedQueueName, body)
+}
+
+// Broker returns the underlying message broker
+func (q *PushQueue) Broker() Broker {
+	return q.broker
+}
+
+// GatewayQueueSpec returns the spec of the gateway's push.queue, bound with
+// the configured routing keys
+func GatewayQueueSpec(cfg *config.GatewayConfig) QueueSpec {
+	spec := QueueSpec{Name: GatewayPushQueueName}
+	if cfg.Exchange == "" {
+		return spec
+	}
+	spec.Exchange = cfg.Exchange
+	spec.ExchangeType = cfg.ExchangeType
+	for _, binding := range cfg.Bindings {
+		spec.Bindings = append(spec.Bindings, binding.RoutingKey)
 	}
+	return spec
+}
+
+// ConsumeFromGateway consumes messages from the API Gateway's push.queue
+func (q *PushQueue) ConsumeFromGateway(ctx context.Context) (<-chan Delivery, error) {
+	return q.ConsumeGatewayFrom(ctx, q.broker, "gateway")
+}
 
-	// Bind queue to exchange with routing key "push"
-	if err := q.rabbitmqClient.BindQueue(ctx, GatewayPushQueueName, GatewayExchangeName, "push"); err != nil {
+// ConsumeGatewayFrom consumes the API Gateway's push.queue on the given
+// broker, e.g. a regional gateway deployment's, with purpose naming the
+// consumer. Messages are still enqueued to this queue's (primary) broker for
+// delivery.
+func (q *PushQueue) ConsumeGatewayFrom(ctx context.Context, broker Broker, purpose string) (<-chan Delivery, error) {
+	// Ensure the gateway queue exists, with its bindings
+	if err := broker.Declare(ctx, GatewayQueueSpec(&q.cfg.Gateway)); err != nil {
 		return nil, err
 	}
 
@@ -200,9 +630,8 @@ func (q *PushQueue) ConsumeFromGateway(ctx context.Context) (<-chan amqp.Deliver
 	}
 
 	zap.L().Info("Gateway queue consumer initialized",
-		zap.String("exchange", GatewayExchangeName),
 		zap.String("queue", GatewayPushQueueName),
 	)
 
-	return q.rabbitmqClient.Consume(ctx, GatewayPushQueueName, prefetchCount)
+	return Consume(ctx, broker, GatewayPushQueueName, purpose, prefetchCount)
 }
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+)
+
+func TestUndeliveredTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokens  []string
+		results []models.AttemptTokenResult
+		want    []string
+	}{
+		{
+			name:   "no results",
+			tokens: []string{"a", "b"},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:    "delivered tokens dropped",
+			tokens:  []string{"a", "b", "c"},
+			results: []models.AttemptTokenResult{{DeviceToken: "a", Success: true}, {DeviceToken: "b"}, {DeviceToken: "c", Success: true}},
+			want:    []string{"b"},
+		},
+		{
+			name:    "every token delivered",
+			tokens:  []string{"a", "b"},
+			results: []models.AttemptTokenResult{{DeviceToken: "a", Success: true}, {DeviceToken: "b", Success: true}},
+			want:    []string{},
+		},
+		{
+			name:    "results for other tokens",
+			tokens:  []string{"a"},
+			results: []models.AttemptTokenResult{{DeviceToken: "z", Success: true}},
+			want:    []string{"a"},
+		},
+		{
+			name:    "result without a token",
+			tokens:  []string{"a", ""},
+			results: []models.AttemptTokenResult{{Success: true}},
+			want:    []string{"a", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UndeliveredTokens(tt.tokens, tt.results); !equalStrings(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnqueueRetryStripsDeliveredTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []models.AttemptTokenResult
+		// the tokens retried, nil for no retry
+		want []string
+	}{
+		{
+			name: "no results",
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name:    "partial delivery",
+			results: []models.AttemptTokenResult{{DeviceToken: "a", Success: true}, {DeviceToken: "b"}, {DeviceToken: "c"}},
+			want:    []string{"b", "c"},
+		},
+		{
+			name:    "every token delivered",
+			results: []models.AttemptTokenResult{{DeviceToken: "a", Success: true}, {DeviceToken: "b", Success: true}, {DeviceToken: "c", Success: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := NewMemoryBroker()
+			defer broker.Close()
+			cfg := &config.QueueConfig{Retry: config.RetryConfig{MaxRetries: 3, MaxRetriesCap: 3, Tiers: []time.Duration{10 * time.Millisecond}}}
+			q, err := NewPushQueue(broker, cfg, &config.RetentionConfig{})
+			if err != nil {
+				t.Fatalf("NewPushQueue: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			message := PushMessage{Notification: models.PushNotification{ID: "n-1"}, DeviceTokens: []string{"a", "b", "c"}}
+			if err := q.EnqueueRetry(ctx, message, tt.results...); err != nil {
+				t.Fatalf("EnqueueRetry: %v", err)
+			}
+
+			if tt.want == nil {
+				if n, err := broker.Stats(ctx, q.RetryQueue(message)); err != nil || n != 0 {
+					t.Errorf("retry queue holds %d messages (%v), want none", n, err)
+				}
+				return
+			}
+
+			// The retry moves back to the push queue once its tier's delay is up
+			deliveries, err := broker.Consume(ctx, PushQueueName, "test", 1)
+			if err != nil {
+				t.Fatalf("Consume: %v", err)
+			}
+			select {
+			case delivery := <-deliveries:
+				retried, err := DecodePushMessage(delivery.Body)
+				if err != nil {
+					t.Fatalf("DecodePushMessage: %v", err)
+				}
+				if !equalStrings(retried.DeviceTokens, tt.want) || retried.RetryCount != 1 {
+					t.Errorf("got retry of %v at count %d, want %v at 1", retried.DeviceTokens, retried.RetryCount, tt.want)
+				}
+			case <-ctx.Done():
+				t.Fatal("got no retry")
+			}
+		})
+	}
+}
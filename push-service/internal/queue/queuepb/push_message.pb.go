@@ -0,0 +1,816 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v5.28.3
+// source: internal/queue/queuepb/push_message.proto
+
+package queuepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PushMessage struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Notification        *Notification          `protobuf:"bytes,1,opt,name=notification,proto3" json:"notification,omitempty"`
+	DeviceTokens        []string               `protobuf:"bytes,2,rep,name=device_tokens,json=deviceTokens,proto3" json:"device_tokens,omitempty"`
+	RetryCount          int32                  `protobuf:"varint,3,opt,name=retry_count,json=retryCount,proto3" json:"retry_count,omitempty"`
+	Platforms           map[string]string      `protobuf:"bytes,4,rep,name=platforms,proto3" json:"platforms,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CampaignId          string                 `protobuf:"bytes,5,opt,name=campaign_id,json=campaignId,proto3" json:"campaign_id,omitempty"`
+	Variant             string                 `protobuf:"bytes,6,opt,name=variant,proto3" json:"variant,omitempty"`
+	TenantId            string                 `protobuf:"bytes,7,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	SuppressIfConnected bool                   `protobuf:"varint,8,opt,name=suppress_if_connected,json=suppressIfConnected,proto3" json:"suppress_if_connected,omitempty"`
+	ProjectId           string                 `protobuf:"bytes,9,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	DryRunId            string                 `protobuf:"bytes,10,opt,name=dry_run_id,json=dryRunId,proto3" json:"dry_run_id,omitempty"`
+	Vars                map[string]string      `protobuf:"bytes,11,rep,name=vars,proto3" json:"vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Bundle              []*Notification        `protobuf:"bytes,12,rep,name=bundle,proto3" json:"bundle,omitempty"`
+	Raw                 []byte                 `protobuf:"bytes,13,opt,name=raw,proto3" json:"raw,omitempty"`
+	TraceContext        map[string]string      `protobuf:"bytes,14,rep,name=trace_context,json=traceContext,proto3" json:"trace_context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Queue               string                 `protobuf:"bytes,15,opt,name=queue,proto3" json:"queue,omitempty"`
+	MaxRetries          *int32                 `protobuf:"varint,16,opt,name=max_retries,json=maxRetries,proto3,oneof" json:"max_retries,omitempty"`
+	RetryBackoff        *durationpb.Duration   `protobuf:"bytes,17,opt,name=retry_backoff,json=retryBackoff,proto3" json:"retry_backoff,omitempty"`
+	LastError           string                 `protobuf:"bytes,18,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	Priority            string                 `protobuf:"bytes,19,opt,name=priority,proto3" json:"priority,omitempty"`
+	BypassQuietHours    *bool                  `protobuf:"varint,20,opt,name=bypass_quiet_hours,json=bypassQuietHours,proto3,oneof" json:"bypass_quiet_hours,omitempty"`
+	BypassFrequencyCap  bool                   `protobuf:"varint,21,opt,name=bypass_frequency_cap,json=bypassFrequencyCap,proto3" json:"bypass_frequency_cap,omitempty"`
+	PolicyApplied       bool                   `protobuf:"varint,22,opt,name=policy_applied,json=policyApplied,proto3" json:"policy_applied,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *PushMessage) Reset() {
+	*x = PushMessage{}
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushMessage) ProtoMessage() {}
+
+func (x *PushMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushMessage.ProtoReflect.Descriptor instead.
+func (*PushMessage) Descriptor() ([]byte, []int) {
+	return file_internal_queue_queuepb_push_message_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PushMessage) GetNotification() *Notification {
+	if x != nil {
+		return x.Notification
+	}
+	return nil
+}
+
+func (x *PushMessage) GetDeviceTokens() []string {
+	if x != nil {
+		return x.DeviceTokens
+	}
+	return nil
+}
+
+func (x *PushMessage) GetRetryCount() int32 {
+	if x != nil {
+		return x.RetryCount
+	}
+	return 0
+}
+
+func (x *PushMessage) GetPlatforms() map[string]string {
+	if x != nil {
+		return x.Platforms
+	}
+	return nil
+}
+
+func (x *PushMessage) GetCampaignId() string {
+	if x != nil {
+		return x.CampaignId
+	}
+	return ""
+}
+
+func (x *PushMessage) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *PushMessage) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *PushMessage) GetSuppressIfConnected() bool {
+	if x != nil {
+		return x.SuppressIfConnected
+	}
+	return false
+}
+
+func (x *PushMessage) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *PushMessage) GetDryRunId() string {
+	if x != nil {
+		return x.DryRunId
+	}
+	return ""
+}
+
+func (x *PushMessage) GetVars() map[string]string {
+	if x != nil {
+		return x.Vars
+	}
+	return nil
+}
+
+func (x *PushMessage) GetBundle() []*Notification {
+	if x != nil {
+		return x.Bundle
+	}
+	return nil
+}
+
+func (x *PushMessage) GetRaw() []byte {
+	if x != nil {
+		return x.Raw
+	}
+	return nil
+}
+
+func (x *PushMessage) GetTraceContext() map[string]string {
+	if x != nil {
+		return x.TraceContext
+	}
+	return nil
+}
+
+func (x *PushMessage) GetQueue() string {
+	if x != nil {
+		return x.Queue
+	}
+	return ""
+}
+
+func (x *PushMessage) GetMaxRetries() int32 {
+	if x != nil && x.MaxRetries != nil {
+		return *x.MaxRetries
+	}
+	return 0
+}
+
+func (x *PushMessage) GetRetryBackoff() *durationpb.Duration {
+	if x != nil {
+		return x.RetryBackoff
+	}
+	return nil
+}
+
+func (x *PushMessage) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *PushMessage) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *PushMessage) GetBypassQuietHours() bool {
+	if x != nil && x.BypassQuietHours != nil {
+		return *x.BypassQuietHours
+	}
+	return false
+}
+
+func (x *PushMessage) GetBypassFrequencyCap() bool {
+	if x != nil {
+		return x.BypassFrequencyCap
+	}
+	return false
+}
+
+func (x *PushMessage) GetPolicyApplied() bool {
+	if x != nil {
+		return x.PolicyApplied
+	}
+	return false
+}
+
+type Notification struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	DeviceId          *string                `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3,oneof" json:"device_id,omitempty"`
+	UserId            string                 `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Title             string                 `protobuf:"bytes,4,opt,name=title,proto3" json:"title,omitempty"`
+	Body              string                 `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
+	Image             *string                `protobuf:"bytes,6,opt,name=image,proto3,oneof" json:"image,omitempty"`
+	Link              *string                `protobuf:"bytes,7,opt,name=link,proto3,oneof" json:"link,omitempty"`
+	Data              *structpb.Struct       `protobuf:"bytes,8,opt,name=data,proto3" json:"data,omitempty"`
+	Sender            *string                `protobuf:"bytes,9,opt,name=sender,proto3,oneof" json:"sender,omitempty"`
+	Category          *string                `protobuf:"bytes,10,opt,name=category,proto3,oneof" json:"category,omitempty"`
+	BundleId          *string                `protobuf:"bytes,11,opt,name=bundle_id,json=bundleId,proto3,oneof" json:"bundle_id,omitempty"`
+	CollapseKey       *string                `protobuf:"bytes,12,opt,name=collapse_key,json=collapseKey,proto3,oneof" json:"collapse_key,omitempty"`
+	ExpiresAt         *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	ClickAction       *string                `protobuf:"bytes,14,opt,name=click_action,json=clickAction,proto3,oneof" json:"click_action,omitempty"`
+	Actions           []*NotificationAction  `protobuf:"bytes,15,rep,name=actions,proto3" json:"actions,omitempty"`
+	Ios               *IOSOptions            `protobuf:"bytes,16,opt,name=ios,proto3" json:"ios,omitempty"`
+	PlatformOverrides *PlatformOverrides     `protobuf:"bytes,17,opt,name=platform_overrides,json=platformOverrides,proto3" json:"platform_overrides,omitempty"`
+	AnalyticsLabel    *string                `protobuf:"bytes,18,opt,name=analytics_label,json=analyticsLabel,proto3,oneof" json:"analytics_label,omitempty"`
+	Status            string                 `protobuf:"bytes,19,opt,name=status,proto3" json:"status,omitempty"`
+	ErrorMessage      *string                `protobuf:"bytes,20,opt,name=error_message,json=errorMessage,proto3,oneof" json:"error_message,omitempty"`
+	SentAt            *timestamppb.Timestamp `protobuf:"bytes,21,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	CreatedAt         *timestamppb.Timestamp `protobuf:"bytes,22,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Notification) Reset() {
+	*x = Notification{}
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Notification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Notification) ProtoMessage() {}
+
+func (x *Notification) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Notification.ProtoReflect.Descriptor instead.
+func (*Notification) Descriptor() ([]byte, []int) {
+	return file_internal_queue_queuepb_push_message_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Notification) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Notification) GetDeviceId() string {
+	if x != nil && x.DeviceId != nil {
+		return *x.DeviceId
+	}
+	return ""
+}
+
+func (x *Notification) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Notification) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Notification) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *Notification) GetImage() string {
+	if x != nil && x.Image != nil {
+		return *x.Image
+	}
+	return ""
+}
+
+func (x *Notification) GetLink() string {
+	if x != nil && x.Link != nil {
+		return *x.Link
+	}
+	return ""
+}
+
+func (x *Notification) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Notification) GetSender() string {
+	if x != nil && x.Sender != nil {
+		return *x.Sender
+	}
+	return ""
+}
+
+func (x *Notification) GetCategory() string {
+	if x != nil && x.Category != nil {
+		return *x.Category
+	}
+	return ""
+}
+
+func (x *Notification) GetBundleId() string {
+	if x != nil && x.BundleId != nil {
+		return *x.BundleId
+	}
+	return ""
+}
+
+func (x *Notification) GetCollapseKey() string {
+	if x != nil && x.CollapseKey != nil {
+		return *x.CollapseKey
+	}
+	return ""
+}
+
+func (x *Notification) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Notification) GetClickAction() string {
+	if x != nil && x.ClickAction != nil {
+		return *x.ClickAction
+	}
+	return ""
+}
+
+func (x *Notification) GetActions() []*NotificationAction {
+	if x != nil {
+		return x.Actions
+	}
+	return nil
+}
+
+func (x *Notification) GetIos() *IOSOptions {
+	if x != nil {
+		return x.Ios
+	}
+	return nil
+}
+
+func (x *Notification) GetPlatformOverrides() *PlatformOverrides {
+	if x != nil {
+		return x.PlatformOverrides
+	}
+	return nil
+}
+
+func (x *Notification) GetAnalyticsLabel() string {
+	if x != nil && x.AnalyticsLabel != nil {
+		return *x.AnalyticsLabel
+	}
+	return ""
+}
+
+func (x *Notification) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Notification) GetErrorMessage() string {
+	if x != nil && x.ErrorMessage != nil {
+		return *x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *Notification) GetSentAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SentAt
+	}
+	return nil
+}
+
+func (x *Notification) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type NotificationAction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Icon          *string                `protobuf:"bytes,3,opt,name=icon,proto3,oneof" json:"icon,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NotificationAction) Reset() {
+	*x = NotificationAction{}
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NotificationAction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NotificationAction) ProtoMessage() {}
+
+func (x *NotificationAction) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NotificationAction.ProtoReflect.Descriptor instead.
+func (*NotificationAction) Descriptor() ([]byte, []int) {
+	return file_internal_queue_queuepb_push_message_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *NotificationAction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *NotificationAction) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *NotificationAction) GetIcon() string {
+	if x != nil && x.Icon != nil {
+		return *x.Icon
+	}
+	return ""
+}
+
+type IOSOptions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Badge         *int32                 `protobuf:"varint,1,opt,name=badge,proto3,oneof" json:"badge,omitempty"`
+	Sound         string                 `protobuf:"bytes,2,opt,name=sound,proto3" json:"sound,omitempty"`
+	Category      string                 `protobuf:"bytes,3,opt,name=category,proto3" json:"category,omitempty"`
+	ThreadId      string                 `protobuf:"bytes,4,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IOSOptions) Reset() {
+	*x = IOSOptions{}
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IOSOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IOSOptions) ProtoMessage() {}
+
+func (x *IOSOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IOSOptions.ProtoReflect.Descriptor instead.
+func (*IOSOptions) Descriptor() ([]byte, []int) {
+	return file_internal_queue_queuepb_push_message_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IOSOptions) GetBadge() int32 {
+	if x != nil && x.Badge != nil {
+		return *x.Badge
+	}
+	return 0
+}
+
+func (x *IOSOptions) GetSound() string {
+	if x != nil {
+		return x.Sound
+	}
+	return ""
+}
+
+func (x *IOSOptions) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *IOSOptions) GetThreadId() string {
+	if x != nil {
+		return x.ThreadId
+	}
+	return ""
+}
+
+type PlatformOverrides struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Android       *structpb.Struct       `protobuf:"bytes,1,opt,name=android,proto3" json:"android,omitempty"`
+	Apns          *structpb.Struct       `protobuf:"bytes,2,opt,name=apns,proto3" json:"apns,omitempty"`
+	Webpush       *structpb.Struct       `protobuf:"bytes,3,opt,name=webpush,proto3" json:"webpush,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlatformOverrides) Reset() {
+	*x = PlatformOverrides{}
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlatformOverrides) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlatformOverrides) ProtoMessage() {}
+
+func (x *PlatformOverrides) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_queue_queuepb_push_message_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlatformOverrides.ProtoReflect.Descriptor instead.
+func (*PlatformOverrides) Descriptor() ([]byte, []int) {
+	return file_internal_queue_queuepb_push_message_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PlatformOverrides) GetAndroid() *structpb.Struct {
+	if x != nil {
+		return x.Android
+	}
+	return nil
+}
+
+func (x *PlatformOverrides) GetApns() *structpb.Struct {
+	if x != nil {
+		return x.Apns
+	}
+	return nil
+}
+
+func (x *PlatformOverrides) GetWebpush() *structpb.Struct {
+	if x != nil {
+		return x.Webpush
+	}
+	return nil
+}
+
+var File_internal_queue_queuepb_push_message_proto protoreflect.FileDescriptor
+
+const file_internal_queue_queuepb_push_message_proto_rawDesc = "" +
+	"\n" +
+	")internal/queue/queuepb/push_message.proto\x12\rpush.queue.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9c\t\n" +
+	"\vPushMessage\x12?\n" +
+	"\fnotification\x18\x01 \x01(\v2\x1b.push.queue.v1.NotificationR\fnotification\x12#\n" +
+	"\rdevice_tokens\x18\x02 \x03(\tR\fdeviceTokens\x12\x1f\n" +
+	"\vretry_count\x18\x03 \x01(\x05R\n" +
+	"retryCount\x12G\n" +
+	"\tplatforms\x18\x04 \x03(\v2).push.queue.v1.PushMessage.PlatformsEntryR\tplatforms\x12\x1f\n" +
+	"\vcampaign_id\x18\x05 \x01(\tR\n" +
+	"campaignId\x12\x18\n" +
+	"\avariant\x18\x06 \x01(\tR\avariant\x12\x1b\n" +
+	"\ttenant_id\x18\a \x01(\tR\btenantId\x122\n" +
+	"\x15suppress_if_connected\x18\b \x01(\bR\x13suppressIfConnected\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\t \x01(\tR\tprojectId\x12\x1c\n" +
+	"\n" +
+	"dry_run_id\x18\n" +
+	" \x01(\tR\bdryRunId\x128\n" +
+	"\x04vars\x18\v \x03(\v2$.push.queue.v1.PushMessage.VarsEntryR\x04vars\x123\n" +
+	"\x06bundle\x18\f \x03(\v2\x1b.push.queue.v1.NotificationR\x06bundle\x12\x10\n" +
+	"\x03raw\x18\r \x01(\fR\x03raw\x12Q\n" +
+	"\rtrace_context\x18\x0e \x03(\v2,.push.queue.v1.PushMessage.TraceContextEntryR\ftraceContext\x12\x14\n" +
+	"\x05queue\x18\x0f \x01(\tR\x05queue\x12$\n" +
+	"\vmax_retries\x18\x10 \x01(\x05H\x00R\n" +
+	"maxRetries\x88\x01\x01\x12>\n" +
+	"\rretry_backoff\x18\x11 \x01(\v2\x19.google.protobuf.DurationR\fretryBackoff\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x12 \x01(\tR\tlastError\x12\x1a\n" +
+	"\bpriority\x18\x13 \x01(\tR\bpriority\x121\n" +
+	"\x12bypass_quiet_hours\x18\x14 \x01(\bH\x01R\x10bypassQuietHours\x88\x01\x01\x120\n" +
+	"\x14bypass_frequency_cap\x18\x15 \x01(\bR\x12bypassFrequencyCap\x12%\n" +
+	"\x0epolicy_applied\x18\x16 \x01(\bR\rpolicyApplied\x1a<\n" +
+	"\x0ePlatformsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a7\n" +
+	"\tVarsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a?\n" +
+	"\x11TraceContextEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x0e\n" +
+	"\f_max_retriesB\x15\n" +
+	"\x13_bypass_quiet_hours\"\xf9\a\n" +
+	"\fNotification\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12 \n" +
+	"\tdevice_id\x18\x02 \x01(\tH\x00R\bdeviceId\x88\x01\x01\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05title\x18\x04 \x01(\tR\x05title\x12\x12\n" +
+	"\x04body\x18\x05 \x01(\tR\x04body\x12\x19\n" +
+	"\x05image\x18\x06 \x01(\tH\x01R\x05image\x88\x01\x01\x12\x17\n" +
+	"\x04link\x18\a \x01(\tH\x02R\x04link\x88\x01\x01\x12+\n" +
+	"\x04data\x18\b \x01(\v2\x17.google.protobuf.StructR\x04data\x12\x1b\n" +
+	"\x06sender\x18\t \x01(\tH\x03R\x06sender\x88\x01\x01\x12\x1f\n" +
+	"\bcategory\x18\n" +
+	" \x01(\tH\x04R\bcategory\x88\x01\x01\x12 \n" +
+	"\tbundle_id\x18\v \x01(\tH\x05R\bbundleId\x88\x01\x01\x12&\n" +
+	"\fcollapse_key\x18\f \x01(\tH\x06R\vcollapseKey\x88\x01\x01\x129\n" +
+	"\n" +
+	"expires_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12&\n" +
+	"\fclick_action\x18\x0e \x01(\tH\aR\vclickAction\x88\x01\x01\x12;\n" +
+	"\aactions\x18\x0f \x03(\v2!.push.queue.v1.NotificationActionR\aactions\x12+\n" +
+	"\x03ios\x18\x10 \x01(\v2\x19.push.queue.v1.IOSOptionsR\x03ios\x12O\n" +
+	"\x12platform_overrides\x18\x11 \x01(\v2 .push.queue.v1.PlatformOverridesR\x11platformOverrides\x12,\n" +
+	"\x0fanalytics_label\x18\x12 \x01(\tH\bR\x0eanalyticsLabel\x88\x01\x01\x12\x16\n" +
+	"\x06status\x18\x13 \x01(\tR\x06status\x12(\n" +
+	"\rerror_message\x18\x14 \x01(\tH\tR\ferrorMessage\x88\x01\x01\x123\n" +
+	"\asent_at\x18\x15 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\x129\n" +
+	"\n" +
+	"created_at\x18\x16 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAtB\f\n" +
+	"\n" +
+	"_device_idB\b\n" +
+	"\x06_imageB\a\n" +
+	"\x05_linkB\t\n" +
+	"\a_senderB\v\n" +
+	"\t_categoryB\f\n" +
+	"\n" +
+	"_bundle_idB\x0f\n" +
+	"\r_collapse_keyB\x0f\n" +
+	"\r_click_actionB\x12\n" +
+	"\x10_analytics_labelB\x10\n" +
+	"\x0e_error_message\"\\\n" +
+	"\x12NotificationAction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x17\n" +
+	"\x04icon\x18\x03 \x01(\tH\x00R\x04icon\x88\x01\x01B\a\n" +
+	"\x05_icon\"\x80\x01\n" +
+	"\n" +
+	"IOSOptions\x12\x19\n" +
+	"\x05badge\x18\x01 \x01(\x05H\x00R\x05badge\x88\x01\x01\x12\x14\n" +
+	"\x05sound\x18\x02 \x01(\tR\x05sound\x12\x1a\n" +
+	"\bcategory\x18\x03 \x01(\tR\bcategory\x12\x1b\n" +
+	"\tthread_id\x18\x04 \x01(\tR\bthreadIdB\b\n" +
+	"\x06_badge\"\xa6\x01\n" +
+	"\x11PlatformOverrides\x121\n" +
+	"\aandroid\x18\x01 \x01(\v2\x17.google.protobuf.StructR\aandroid\x12+\n" +
+	"\x04apns\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x04apns\x121\n" +
+	"\awebpush\x18\x03 \x01(\v2\x17.google.protobuf.StructR\awebpushB%Z#push-service/internal/queue/queuepbb\x06proto3"
+
+var (
+	file_internal_queue_queuepb_push_message_proto_rawDescOnce sync.Once
+	file_internal_queue_queuepb_push_message_proto_rawDescData []byte
+)
+
+func file_internal_queue_queuepb_push_message_proto_rawDescGZIP() []byte {
+	file_internal_queue_queuepb_push_message_proto_rawDescOnce.Do(func() {
+		file_internal_queue_queuepb_push_message_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_queue_queuepb_push_message_proto_rawDesc), len(file_internal_queue_queuepb_push_message_proto_rawDesc)))
+	})
+	return file_internal_queue_queuepb_push_message_proto_rawDescData
+}
+
+var file_internal_queue_queuepb_push_message_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_internal_queue_queuepb_push_message_proto_goTypes = []any{
+	(*PushMessage)(nil),           // 0: push.queue.v1.PushMessage
+	(*Notification)(nil),          // 1: push.queue.v1.Notification
+	(*NotificationAction)(nil),    // 2: push.queue.v1.NotificationAction
+	(*IOSOptions)(nil),            // 3: push.queue.v1.IOSOptions
+	(*PlatformOverrides)(nil),     // 4: push.queue.v1.PlatformOverrides
+	nil,                           // 5: push.queue.v1.PushMessage.PlatformsEntry
+	nil,                           // 6: push.queue.v1.PushMessage.VarsEntry
+	nil,                           // 7: push.queue.v1.PushMessage.TraceContextEntry
+	(*durationpb.Duration)(nil),   // 8: google.protobuf.Duration
+	(*structpb.Struct)(nil),       // 9: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+}
+var file_internal_queue_queuepb_push_message_proto_depIdxs = []int32{
+	1,  // 0: push.queue.v1.PushMessage.notification:type_name -> push.queue.v1.Notification
+	5,  // 1: push.queue.v1.PushMessage.platforms:type_name -> push.queue.v1.PushMessage.PlatformsEntry
+	6,  // 2: push.queue.v1.PushMessage.vars:type_name -> push.queue.v1.PushMessage.VarsEntry
+	1,  // 3: push.queue.v1.PushMessage.bundle:type_name -> push.queue.v1.Notification
+	7,  // 4: push.queue.v1.PushMessage.trace_context:type_name -> push.queue.v1.PushMessage.TraceContextEntry
+	8,  // 5: push.queue.v1.PushMessage.retry_backoff:type_name -> google.protobuf.Duration
+	9,  // 6: push.queue.v1.Notification.data:type_name -> google.protobuf.Struct
+	10, // 7: push.queue.v1.Notification.expires_at:type_name -> google.protobuf.Timestamp
+	2,  // 8: push.queue.v1.Notification.actions:type_name -> push.queue.v1.NotificationAction
+	3,  // 9: push.queue.v1.Notification.ios:type_name -> push.queue.v1.IOSOptions
+	4,  // 10: push.queue.v1.Notification.platform_overrides:type_name -> push.queue.v1.PlatformOverrides
+	10, // 11: push.queue.v1.Notification.sent_at:type_name -> google.protobuf.Timestamp
+	10, // 12: push.queue.v1.Notification.created_at:type_name -> google.protobuf.Timestamp
+	9,  // 13: push.queue.v1.PlatformOverrides.android:type_name -> google.protobuf.Struct
+	9,  // 14: push.queue.v1.PlatformOverrides.apns:type_name -> google.protobuf.Struct
+	9,  // 15: push.queue.v1.PlatformOverrides.webpush:type_name -> google.protobuf.Struct
+	16, // [16:16] is the sub-list for method output_type
+	16, // [16:16] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_internal_queue_queuepb_push_message_proto_init() }
+func file_internal_queue_queuepb_push_message_proto_init() {
+	if File_internal_queue_queuepb_push_message_proto != nil {
+		return
+	}
+	file_internal_queue_queuepb_push_message_proto_msgTypes[0].OneofWrappers = []any{}
+	file_internal_queue_queuepb_push_message_proto_msgTypes[1].OneofWrappers = []any{}
+	file_internal_queue_queuepb_push_message_proto_msgTypes[2].OneofWrappers = []any{}
+	file_internal_queue_queuepb_push_message_proto_msgTypes[3].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_queue_queuepb_push_message_proto_rawDesc), len(file_internal_queue_queuepb_push_message_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_internal_queue_queuepb_push_message_proto_goTypes,
+		DependencyIndexes: file_internal_queue_queuepb_push_message_proto_depIdxs,
+		MessageInfos:      file_internal_queue_queuepb_push_message_proto_msgTypes,
+	}.Build()
+	File_internal_queue_queuepb_push_message_proto = out.File
+	file_internal_queue_queuepb_push_message_proto_goTypes = nil
+	file_internal_queue_queuepb_push_message_proto_depIdxs = nil
+}
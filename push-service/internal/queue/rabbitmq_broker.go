@@ -0,0 +1,224 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"push-service/pkg/rabbitmq"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type rabbitRoute struct {
+	exchange   string
+	routingKey string
+}
+
+// rabbitRoutes keeps the exchanges and routing keys these queues have always
+// been bound with; any other queue is bound to PushExchangeName by its name.
+var rabbitRoutes = map[string]rabbitRoute{
+	DeadLetterQueue:      {exchange: DeadLetterExchange, routingKey: "dead_letter"},
+	GatewayPushQueueName: {exchange: GatewayExchangeName, routingKey: "push"},
+}
+
+func routeFor(queue string) rabbitRoute {
+	if route, ok := rabbitRoutes[queue]; ok {
+		return route
+	}
+	return rabbitRoute{exchange: PushExchangeName, routingKey: queue}
+}
+
+// RabbitMQBroker implements Broker on a RabbitMQ client. Each queue is bound
+// to a direct exchange, unless its spec names another exchange; delays use a
+// per-queue message TTL that dead-letters to the target queue.
+type RabbitMQBroker struct {
+	client *rabbitmq.RabbitMQClient
+
+	mu     sync.Mutex
+	routes map[string]rabbitRoute // of queues declared with their own exchange
+
+	acks *ackBatcher // nil unless acks are batched
+}
+
+func NewRabbitMQBroker(client *rabbitmq.RabbitMQClient) *RabbitMQBroker {
+	return &RabbitMQBroker{client: client, routes: make(map[string]rabbitRoute)}
+}
+
+// BatchAcks makes the broker acknowledge deliveries in batches of up to
+// size, sent at least every interval, instead of one by one. Call it before
+// consuming.
+func (b *RabbitMQBroker) BatchAcks(size int, interval time.Duration) {
+	if size <= 1 || interval <= 0 {
+		return
+	}
+	b.acks = newAckBatcher(size, interval)
+}
+
+// route returns the exchange and routing key messages are published to
+// queue with
+func (b *RabbitMQBroker) route(queue string) rabbitRoute {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if route, ok := b.routes[queue]; ok {
+		return route
+	}
+	return routeFor(queue)
+}
+
+func (b *RabbitMQBroker) Declare(ctx context.Context, spec QueueSpec) error {
+	route := routeFor(spec.Name)
+	kind := "direct"
+	bindings := []string{route.routingKey}
+	if spec.Exchange != "" {
+		route.exchange = spec.Exchange
+		if spec.ExchangeType != "" {
+			kind = spec.ExchangeType
+		}
+		if len(spec.Bindings) > 0 {
+			// Messages published to the queue itself get the first key
+			route.routingKey = spec.Bindings[0]
+			bindings = spec.Bindings
+		}
+	}
+	if err := b.client.EnsureExchange(ctx, route.exchange, kind); err != nil {
+		return err
+	}
+
+	args := amqp.Table{}
+	if spec.MaxAge > 0 {
+		args["x-message-ttl"] = spec.MaxAge.Milliseconds()
+	}
+	deadLetter := spec.DeadLetter
+	if spec.Delay > 0 {
+		args["x-message-ttl"] = spec.Delay.Milliseconds()
+		deadLetter = spec.DelayTarget
+	}
+	if deadLetter != "" {
+		target := routeFor(deadLetter)
+		args["x-dead-letter-exchange"] = target.exchange
+		args["x-dead-letter-routing-key"] = target.routingKey
+	}
+	if len(args) == 0 {
+		args = nil
+	}
+
+	if err := b.client.EnsureQueue(ctx, spec.Name, args); err != nil {
+		return err
+	}
+	for _, routingKey := range bindings {
+		if err := b.client.BindQueue(ctx, spec.Name, route.exchange, routingKey); err != nil {
+			return err
+		}
+	}
+	if spec.Exchange != "" {
+		b.mu.Lock()
+		b.routes[spec.Name] = route
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+func (b *RabbitMQBroker) Publish(ctx context.Context, queue string, bodies ...[]byte) error {
+	route := b.route(queue)
+	return b.client.Publish(ctx, route.exchange, route.routingKey, bodies)
+}
+
+func (b *RabbitMQBroker) Consume(ctx context.Context, queue, tag string, prefetch int) (<-chan Delivery, error) {
+	msgs, err := b.client.Consume(ctx, queue, tag, prefetch)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+		for msg := range msgs {
+			delivery := Delivery{
+				ID:          msg.MessageId,
+				Body:        msg.Body,
+				Redelivered: msg.Redelivered,
+				PublishedAt: msg.Timestamp,
+				RoutingKey:  msg.RoutingKey,
+				broker:      b,
+				handle:      msg,
+			}
+			if b.acks != nil {
+				b.acks.track(msg)
+			}
+			select {
+			case out <- delivery:
+			case <-ctx.Done():
+				// Back to the queue now rather than when the channel closes,
+				// with the rest the consumer was sent before it stopped
+				b.Nack(delivery, true)
+				for msg := range msgs {
+					msg.Nack(false, true)
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Ack and Nack settle the delivery on the channel it arrived on
+func (b *RabbitMQBroker) Ack(d Delivery) error {
+	msg, ok := d.handle.(amqp.Delivery)
+	if !ok {
+		return fmt.Errorf("delivery %s is not from RabbitMQ", d.ID)
+	}
+	if b.acks != nil {
+		return b.acks.ack(msg)
+	}
+	return msg.Ack(false)
+}
+
+func (b *RabbitMQBroker) Nack(d Delivery, requeue bool) error {
+	msg, ok := d.handle.(amqp.Delivery)
+	if !ok {
+		return fmt.Errorf("delivery %s is not from RabbitMQ", d.ID)
+	}
+	err := msg.Nack(false, requeue)
+	if b.acks != nil {
+		b.acks.nacked(msg)
+	}
+	return err
+}
+
+// FlushAcks sends the acks waiting to be batched
+func (b *RabbitMQBroker) FlushAcks() {
+	if b.acks != nil {
+		b.acks.flush()
+	}
+}
+
+func (b *RabbitMQBroker) Stats(ctx context.Context, queue string) (int64, error) {
+	return b.client.QueueLength(ctx, queue)
+}
+
+// Reconnects returns how many times the connection was re-established
+func (b *RabbitMQBroker) Reconnects() int {
+	return b.client.Reconnects()
+}
+
+// KillChannels closes the client's channels, which are then reopened
+func (b *RabbitMQBroker) KillChannels() {
+	b.client.KillChannels()
+}
+
+func (b *RabbitMQBroker) Prefetch() int {
+	return b.client.Prefetch()
+}
+
+func (b *RabbitMQBroker) SetPrefetch(prefetch int) error {
+	return b.client.SetPrefetch(prefetch)
+}
+
+func (b *RabbitMQBroker) Close() error {
+	if b.acks != nil {
+		b.acks.close()
+	}
+	return b.client.Close()
+}
@@ -0,0 +1,39 @@
+package queue
+
+import "strings"
+
+// MatchesBinding reports whether a message published with routingKey to an
+// exchange of kind reaches a queue bound with binding. Direct exchanges
+// route by equal keys. On topic exchanges the binding is a pattern of
+// dot-separated words, where * stands for one word and # for any number.
+func MatchesBinding(kind, binding, routingKey string) bool {
+	if kind != "topic" {
+		return binding == routingKey
+	}
+	return matchWords(strings.Split(binding, "."), strings.Split(routingKey, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			// Try every number of words, none first
+			for i := 0; i <= len(words); i++ {
+				if matchWords(pattern[1:], words[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+		default:
+			if len(words) == 0 || words[0] != pattern[0] {
+				return false
+			}
+		}
+		if len(words) == 0 {
+			return false
+		}
+		pattern, words = pattern[1:], words[1:]
+	}
+	return len(words) == 0
+}
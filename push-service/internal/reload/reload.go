@@ -0,0 +1,203 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"push-service/internal/config"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/worker"
+	"push-service/pkg/logger"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Result describes what a reload changed
+// @Description Settings changed by a configuration reload
+type Result struct {
+	// Applied lists the settings that changed and now apply
+	Applied []string `json:"applied" example:"log.level,queue.worker.prefetch_count"`
+	// RestartRequired is set when other settings changed too; they apply
+	// after a restart
+	RestartRequired bool `json:"restart_required" example:"false"`
+}
+
+// Reloader re-reads the configuration and applies the settings that can
+// change without restarting: the log level, the worker's prefetch count and
+// concurrency, the retry limits and the FCM send rate caps. Consumers and
+// the HTTP server keep running.
+type Reloader struct {
+	file     string
+	worker   *worker.Worker
+	projects *fcm.Projects
+
+	mu  sync.Mutex
+	cfg *config.Config // the configuration in effect
+}
+
+// New creates a reloader of the configuration loaded from file ("" for the
+// default search paths), where cfg is what was loaded at startup
+func New(file string, cfg *config.Config, w *worker.Worker, projects *fcm.Projects) *Reloader {
+	return &Reloader{file: file, worker: w, projects: projects, cfg: cfg}
+}
+
+// Watch reloads the configuration on every SIGHUP until ctx is cancelled
+func (r *Reloader) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			zap.L().Info("SIGHUP received, reloading configuration")
+			// Reload logs its own errors
+			r.Reload()
+		}
+	}
+}
+
+// Reload re-reads the configuration and applies the tunable settings that
+// changed. Nothing is applied if the new configuration is invalid.
+func (r *Reloader) Reload() (*Result, error) {
+	cfg, err := config.Load(r.file)
+	if err == nil {
+		_, err = zapcore.ParseLevel(cfg.Log.Level)
+	}
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues("failed").Inc()
+		zap.L().Error("Failed to reload configuration", zap.Error(err))
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.cfg
+	result := &Result{Applied: []string{}}
+
+	// The worker first, as it may refuse its settings
+	if r.worker != nil {
+		if err := r.updateWorker(cfg, current, result); err != nil {
+			metrics.ConfigReloads.WithLabelValues("failed").Inc()
+			zap.L().Error("Failed to apply reloaded worker settings", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	if cfg.Log.Level != current.Log.Level {
+		if err := logger.SetLevel(cfg.Log.Level); err != nil {
+			zap.L().Warn("Failed to change log level", zap.Error(err))
+		} else {
+			result.Applied = append(result.Applied, "log.level")
+		}
+	}
+
+	if !reflect.DeepEqual(sendRates(&cfg.FCM), sendRates(&current.FCM)) {
+		r.projects.SetMaxSendRates(&cfg.FCM)
+		result.Applied = append(result.Applied, "fcm.max_send_rate")
+	}
+
+	// Whatever else changed waits for a restart
+	next := *current
+	withTunables(&next, cfg)
+	result.RestartRequired = !reflect.DeepEqual(&next, cfg)
+	r.cfg = &next
+
+	metrics.ConfigReloads.WithLabelValues("applied").Inc()
+	zap.L().Info("Configuration reloaded",
+		zap.Strings("applied", result.Applied),
+		zap.Bool("restart_required", result.RestartRequired),
+	)
+	if result.RestartRequired {
+		zap.L().Warn("Reloaded configuration changes settings that only apply after a restart")
+	}
+	return result, nil
+}
+
+// updateWorker applies the worker's prefetch count, concurrency and retry
+// limits, where they changed
+func (r *Reloader) updateWorker(cfg, current *config.Config, result *Result) error {
+	prefetchCount := cfg.Queue.Worker.PrefetchCount
+	if prefetchCount == 0 {
+		prefetchCount = 10 // default
+	}
+	concurrency := cfg.Queue.Worker.Concurrency
+	if concurrency == 0 {
+		concurrency = 10 // default
+	}
+	if maxConcurrency := r.worker.Settings().MaxConcurrency; concurrency > maxConcurrency {
+		zap.L().Warn("Configured worker concurrency exceeds resource limit, capping",
+			zap.Int("concurrency", concurrency),
+			zap.Int("max_concurrency", maxConcurrency),
+		)
+		concurrency = maxConcurrency
+	}
+	var newPrefetch, newConcurrency *int
+	if cfg.Queue.Worker.PrefetchCount != current.Queue.Worker.PrefetchCount {
+		newPrefetch = &prefetchCount
+		result.Applied = append(result.Applied, "queue.worker.prefetch_count")
+	}
+	if cfg.Queue.Worker.Concurrency != current.Queue.Worker.Concurrency {
+		newConcurrency = &concurrency
+		result.Applied = append(result.Applied, "queue.worker.concurrency")
+	}
+	if newPrefetch != nil || newConcurrency != nil {
+		if _, err := r.worker.Update(newPrefetch, newConcurrency); err != nil {
+			return err
+		}
+	}
+
+	retry, currentRetry := cfg.Queue.Retry, current.Queue.Retry
+	if retry.MaxRetries != currentRetry.MaxRetries {
+		result.Applied = append(result.Applied, "queue.retry.max_retries")
+	}
+	if retry.MaxRetriesCap != currentRetry.MaxRetriesCap {
+		result.Applied = append(result.Applied, "queue.retry.max_retries_cap")
+	}
+	if retry.MaxRetries != currentRetry.MaxRetries || retry.MaxRetriesCap != currentRetry.MaxRetriesCap {
+		r.worker.SetRetryLimits(retry.MaxRetries, retry.MaxRetriesCap)
+	}
+	return nil
+}
+
+// sendRates returns the send rate cap of each FCM project
+func sendRates(cfg *config.FCMConfig) map[string]float64 {
+	rates := map[string]float64{cfg.ProjectID: cfg.MaxSendRate}
+	for _, project := range cfg.FailoverProjects {
+		rates[project.ProjectID] = project.MaxSendRate
+	}
+	return rates
+}
+
+// withTunables copies the settings Reload applies from src to dst
+func withTunables(dst, src *config.Config) {
+	dst.Log.Level = src.Log.Level
+	dst.Queue.Worker.PrefetchCount = src.Queue.Worker.PrefetchCount
+	dst.Queue.Worker.Concurrency = src.Queue.Worker.Concurrency
+	dst.Queue.Retry.MaxRetries = src.Queue.Retry.MaxRetries
+	dst.Queue.Retry.MaxRetriesCap = src.Queue.Retry.MaxRetriesCap
+
+	dst.FCM.MaxSendRate = src.FCM.MaxSendRate
+	if dst.FCM.FailoverProjects == nil {
+		return
+	}
+	// A copy, as dst may share its projects with the config it was copied
+	// from
+	rates := sendRates(&src.FCM)
+	failover := make([]config.FCMConfig, len(dst.FCM.FailoverProjects))
+	for i, project := range dst.FCM.FailoverProjects {
+		if rate, ok := rates[project.ProjectID]; ok {
+			project.MaxSendRate = rate
+		}
+		failover[i] = project
+	}
+	dst.FCM.FailoverProjects = failover
+}
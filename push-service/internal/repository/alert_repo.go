@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"push-service/internal/models"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// AlertCount is an amount to add to a labelled counter
+type AlertCount struct {
+	Metric string
+	Labels map[string]string
+	Value  int64
+}
+
+type AlertRepository interface {
+	AddCounts(ctx context.Context, bucket time.Time, counts []AlertCount) error
+	SetGauge(ctx context.Context, bucket time.Time, metric string, value int64) error
+	Sum(ctx context.Context, metrics []string, filter map[string]string, since time.Time) (map[string]int64, error)
+	FirstGauge(ctx context.Context, metric string, since time.Time) (int64, bool, error)
+	DeleteCountsBefore(ctx context.Context, before time.Time) (int64, error)
+
+	ListRules(ctx context.Context) ([]models.AlertRule, error)
+	CreateRule(ctx context.Context, rule *models.AlertRule) error
+	DeleteRule(ctx context.Context, name string) error
+
+	GetStates(ctx context.Context) (map[string]models.AlertState, error)
+	SaveState(ctx context.Context, ruleName string, state models.AlertState) error
+}
+
+type alertRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAlertRepository(db *pgxpool.Pool) AlertRepository {
+	return &alertRepo{db: db}
+}
+
+// AddCounts adds counts to their metric's bucket, in one transaction. Rows
+// are upserted in a fixed order so concurrent flushes from several instances
+// can't deadlock.
+func (r *alertRepo) AddCounts(ctx context.Context, bucket time.Time, counts []AlertCount) error {
+	type row struct {
+		metric string
+		labels []byte
+		value  int64
+	}
+	rows := make([]row, 0, len(counts))
+	for _, count := range counts {
+		labels := count.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		// Maps marshal with sorted keys, so equal label sets encode equally
+		encoded, err := json.Marshal(labels)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row{metric: count.Metric, labels: encoded, value: count.Value})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].metric != rows[j].metric {
+			return rows[i].metric < rows[j].metric
+		}
+		return string(rows[i].labels) < string(rows[j].labels)
+	})
+
+	batch := &pgx.Batch{}
+	for _, row := range rows {
+		batch.Queue(`
+			INSERT INTO alert_counts (bucket, metric, labels, value)
+			VALUES ($1, $2, $3::jsonb, $4)
+			ON CONFLICT (bucket, metric, labels) DO UPDATE SET value = alert_counts.value + EXCLUDED.value
+		`, bucket, row.metric, string(row.labels), row.value)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		zap.L().Error("Failed to add alert counts", zap.Error(err))
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetGauge stores the latest sample of an unlabelled gauge for bucket
+func (r *alertRepo) SetGauge(ctx context.Context, bucket time.Time, metric string, value int64) error {
+	query := `
+		INSERT INTO alert_counts (bucket, metric, value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (bucket, metric, labels) DO UPDATE SET value = EXCLUDED.value
+	`
+
+	if _, err := r.db.Exec(ctx, query, bucket, metric, value); err != nil {
+		zap.L().Error("Failed to store alert gauge", zap.String("metric", metric), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Sum totals each metric's counters since the given bucket whose labels
+// include every filter label. Metrics with no counters sum to 0.
+func (r *alertRepo) Sum(ctx context.Context, metrics []string, filter map[string]string, since time.Time) (map[string]int64, error) {
+	if filter == nil {
+		filter = map[string]string{}
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT metric, SUM(value)::bigint
+		FROM alert_counts
+		WHERE metric = ANY($1) AND bucket >= $2 AND labels @> $3
+		GROUP BY metric
+	`, metrics, since, filter)
+	if err != nil {
+		zap.L().Error("Failed to sum alert counts", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := make(map[string]int64, len(metrics))
+	for _, metric := range metrics {
+		sums[metric] = 0
+	}
+	for rows.Next() {
+		var metric string
+		var sum int64
+		if err := rows.Scan(&metric, &sum); err != nil {
+			return nil, err
+		}
+		sums[metric] = sum
+	}
+	return sums, rows.Err()
+}
+
+// FirstGauge returns a gauge's earliest sample since the given bucket, and
+// false if there is none
+func (r *alertRepo) FirstGauge(ctx context.Context, metric string, since time.Time) (int64, bool, error) {
+	query := `
+		SELECT value
+		FROM alert_counts
+		WHERE metric = $1 AND bucket >= $2
+		ORDER BY bucket
+		LIMIT 1
+	`
+
+	var value int64
+	if err := r.db.QueryRow(ctx, query, metric, since).Scan(&value); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, false, nil
+		}
+		zap.L().Error("Failed to get alert gauge", zap.String("metric", metric), zap.Error(err))
+		return 0, false, err
+	}
+	return value, true, nil
+}
+
+func (r *alertRepo) DeleteCountsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM alert_counts WHERE bucket < $1`, before)
+	if err != nil {
+		zap.L().Error("Failed to delete old alert counts", zap.Error(err))
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *alertRepo) ListRules(ctx context.Context) ([]models.AlertRule, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT name, metric, filter, operator, threshold, window_duration, min_events, webhook_url, created_at
+		FROM alert_rules
+		ORDER BY name
+	`)
+	if err != nil {
+		zap.L().Error("Failed to list alert rules", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.AlertRule
+	for rows.Next() {
+		var rule models.AlertRule
+		if err := rows.Scan(
+			&rule.Name,
+			&rule.Metric,
+			&rule.Filter,
+			&rule.Operator,
+			&rule.Threshold,
+			&rule.Window,
+			&rule.MinEvents,
+			&rule.WebhookURL,
+			&rule.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		rule.Source = models.AlertRuleSourceAPI
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CreateRule stores an API rule. It returns pgx.ErrNoRows if a rule with
+// the same name exists.
+func (r *alertRepo) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	query := `
+		INSERT INTO alert_rules (name, metric, filter, operator, threshold, window_duration, min_events, webhook_url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING created_at
+	`
+
+	filter := rule.Filter
+	if filter == nil {
+		filter = map[string]string{}
+	}
+	err := r.db.QueryRow(ctx, query,
+		rule.Name,
+		rule.Metric,
+		filter,
+		rule.Operator,
+		rule.Threshold,
+		rule.Window,
+		rule.MinEvents,
+		rule.WebhookURL,
+	).Scan(&rule.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return err
+		}
+		zap.L().Error("Failed to create alert rule", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteRule removes an API rule and its state. It returns pgx.ErrNoRows if
+// there is no such rule.
+func (r *alertRepo) DeleteRule(ctx context.Context, name string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM alert_rules WHERE name = $1`, name)
+	if err != nil {
+		zap.L().Error("Failed to delete alert rule", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM alert_states WHERE rule_name = $1`, name); err != nil {
+		zap.L().Error("Failed to delete alert state", zap.Error(err))
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *alertRepo) GetStates(ctx context.Context) (map[string]models.AlertState, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT rule_name, firing, value, since, evaluated_at
+		FROM alert_states
+	`)
+	if err != nil {
+		zap.L().Error("Failed to get alert states", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]models.AlertState)
+	for rows.Next() {
+		var name string
+		var state models.AlertState
+		if err := rows.Scan(&name, &state.Firing, &state.Value, &state.Since, &state.EvaluatedAt); err != nil {
+			return nil, err
+		}
+		states[name] = state
+	}
+	return states, rows.Err()
+}
+
+func (r *alertRepo) SaveState(ctx context.Context, ruleName string, state models.AlertState) error {
+	query := `
+		INSERT INTO alert_states (rule_name, firing, value, since, evaluated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (rule_name) DO UPDATE SET
+			firing = EXCLUDED.firing,
+			value = EXCLUDED.value,
+			since = EXCLUDED.since,
+			evaluated_at = EXCLUDED.evaluated_at
+	`
+
+	if _, err := r.db.Exec(ctx, query, ruleName, state.Firing, state.Value, state.Since, state.EvaluatedAt); err != nil {
+		zap.L().Error("Failed to save alert state", zap.String("rule", ruleName), zap.Error(err))
+		return err
+	}
+	return nil
+}
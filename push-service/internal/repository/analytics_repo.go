@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// AnalyticsRepository keeps the delivery rollups per UTC day and platform,
+// and per campaign variant
+type AnalyticsRepository interface {
+	Add(ctx context.Context, day time.Time, platform string, counts models.DeliveryCounts) error
+	List(ctx context.Context, from, to time.Time) ([]models.DeliveryRollup, error)
+	AddVariant(ctx context.Context, campaignID, variant string, counts models.DeliveryCounts) error
+	ListVariants(ctx context.Context, campaignID string) ([]models.VariantRollup, error)
+}
+
+type analyticsRepo struct {
+	db *pgxpool.Pool
+	// replica serves the stats reads
+	replica *pgxpool.Pool
+}
+
+// NewAnalyticsRepository creates the repository. replica may be the primary
+// itself.
+func NewAnalyticsRepository(db, replica *pgxpool.Pool) AnalyticsRepository {
+	return &analyticsRepo{db: db, replica: replica}
+}
+
+// Add adds counts to the day's rollup of platform
+func (r *analyticsRepo) Add(ctx context.Context, day time.Time, platform string, counts models.DeliveryCounts) error {
+	query := `
+		INSERT INTO delivery_rollups (day, platform, sent, delivered, failed, opened)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (day, platform) DO UPDATE
+		SET sent = delivery_rollups.sent + $3,
+			delivered = delivery_rollups.delivered + $4,
+			failed = delivery_rollups.failed + $5,
+			opened = delivery_rollups.opened + $6
+	`
+
+	_, err := r.db.Exec(ctx, query, day, platform, counts.Sent, counts.Delivered, counts.Failed, counts.Opened)
+	if err != nil {
+		zap.L().Error("Failed to update delivery rollup", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// List returns the rollups of the days from from to to, inclusive, by day
+// and platform
+func (r *analyticsRepo) List(ctx context.Context, from, to time.Time) ([]models.DeliveryRollup, error) {
+	query := `
+		SELECT to_char(day, 'YYYY-MM-DD'), platform, sent, delivered, failed, opened
+		FROM delivery_rollups
+		WHERE day BETWEEN $1 AND $2
+		ORDER BY day, platform
+	`
+
+	rows, err := r.replica.Query(ctx, query, from, to)
+	if err != nil {
+		zap.L().Error("Failed to list delivery rollups", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollups := []models.DeliveryRollup{}
+	for rows.Next() {
+		var rollup models.DeliveryRollup
+		if err := rows.Scan(&rollup.Day, &rollup.Platform, &rollup.Sent, &rollup.Delivered, &rollup.Failed, &rollup.Opened); err != nil {
+			zap.L().Error("Failed to scan delivery rollup", zap.Error(err))
+			return nil, err
+		}
+		rollups = append(rollups, rollup)
+	}
+
+	return rollups, rows.Err()
+}
+
+// AddVariant adds counts to the rollup of a campaign's variant
+func (r *analyticsRepo) AddVariant(ctx context.Context, campaignID, variant string, counts models.DeliveryCounts) error {
+	query := `
+		INSERT INTO campaign_variant_rollups (campaign_id, variant, sent, delivered, failed, opened)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (campaign_id, variant) DO UPDATE
+		SET sent = campaign_variant_rollups.sent + $3,
+			delivered = campaign_variant_rollups.delivered + $4,
+			failed = campaign_variant_rollups.failed + $5,
+			opened = campaign_variant_rollups.opened + $6
+	`
+
+	_, err := r.db.Exec(ctx, query, campaignID, variant, counts.Sent, counts.Delivered, counts.Failed, counts.Opened)
+	if err != nil {
+		zap.L().Error("Failed to update campaign variant rollup", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ListVariants returns the rollups of a campaign's variants, by name
+func (r *analyticsRepo) ListVariants(ctx context.Context, campaignID string) ([]models.VariantRollup, error) {
+	query := `
+		SELECT variant, sent, delivered, failed, opened
+		FROM campaign_variant_rollups
+		WHERE campaign_id = $1
+		ORDER BY variant
+	`
+
+	rows, err := r.replica.Query(ctx, query, campaignID)
+	if err != nil {
+		zap.L().Error("Failed to list campaign variant rollups", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollups := []models.VariantRollup{}
+	for rows.Next() {
+		var rollup models.VariantRollup
+		if err := rows.Scan(&rollup.Variant, &rollup.Sent, &rollup.Delivered, &rollup.Failed, &rollup.Opened); err != nil {
+			zap.L().Error("Failed to scan campaign variant rollup", zap.Error(err))
+			return nil, err
+		}
+		rollups = append(rollups, rollup)
+	}
+
+	return rollups, rows.Err()
+}
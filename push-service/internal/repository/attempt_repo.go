@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type DeliveryAttemptRepository interface {
+	Create(ctx context.Context, attempt *models.DeliveryAttempt) error
+	ListByNotification(ctx context.Context, notificationID string) ([]models.DeliveryAttempt, error)
+	// ListRecent returns the attempts recorded after the attempt with ID
+	// after, oldest first, up to limit. With after 0, it returns the last
+	// limit attempts.
+	ListRecent(ctx context.Context, after int64, limit int) ([]models.DeliveryEvent, error)
+	// SaveTokenResults stores an attempt's result for each device token, over
+	// the token's previous result unless that was a success
+	SaveTokenResults(ctx context.Context, notificationID, userID string, results []models.AttemptTokenResult) error
+	// ListTokenResults returns the notification's result for each device
+	// token, or only for token if it is set. Tokens are returned in full.
+	ListTokenResults(ctx context.Context, notificationID, token string) ([]models.TokenDeliveryResult, error)
+}
+
+type deliveryAttemptRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDeliveryAttemptRepository(db *pgxpool.Pool) DeliveryAttemptRepository {
+	return &deliveryAttemptRepo{db: db}
+}
+
+func (r *deliveryAttemptRepo) Create(ctx context.Context, attempt *models.DeliveryAttempt) error {
+	query := `
+		INSERT INTO delivery_attempts (notification_id, user_id, retry_count, queue, redelivered, project_id,
+			results, success_count, failure_count, error, next_queue)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING attempted_at
+	`
+
+	results := attempt.Results
+	if results == nil {
+		results = []models.AttemptTokenResult{}
+	}
+	err := r.db.QueryRow(ctx, query,
+		attempt.NotificationID,
+		attempt.UserID,
+		attempt.RetryCount,
+		attempt.Queue,
+		attempt.Redelivered,
+		attempt.ProjectID,
+		results,
+		attempt.SuccessCount,
+		attempt.FailureCount,
+		attempt.Error,
+		attempt.NextQueue,
+	).Scan(&attempt.AttemptedAt)
+	if err != nil {
+		zap.L().Error("Failed to record delivery attempt", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *deliveryAttemptRepo) ListByNotification(ctx context.Context, notificationID string) ([]models.DeliveryAttempt, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT notification_id, user_id, retry_count, queue, redelivered, project_id,
+			results, success_count, failure_count, error, next_queue, attempted_at
+		FROM delivery_attempts
+		WHERE notification_id = $1
+		ORDER BY attempted_at, id
+	`, notificationID)
+	if err != nil {
+		zap.L().Error("Failed to list delivery attempts", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []models.DeliveryAttempt
+	for rows.Next() {
+		var attempt models.DeliveryAttempt
+		if err := rows.Scan(
+			&attempt.NotificationID,
+			&attempt.UserID,
+			&attempt.RetryCount,
+			&attempt.Queue,
+			&attempt.Redelivered,
+			&attempt.ProjectID,
+			&attempt.Results,
+			&attempt.SuccessCount,
+			&attempt.FailureCount,
+			&attempt.Error,
+			&attempt.NextQueue,
+			&attempt.AttemptedAt,
+		); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+func (r *deliveryAttemptRepo) ListRecent(ctx context.Context, after int64, limit int) ([]models.DeliveryEvent, error) {
+	query := `
+		SELECT id, notification_id, user_id, retry_count, queue, redelivered, project_id,
+			results, success_count, failure_count, error, next_queue, attempted_at
+		FROM delivery_attempts
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+	if after <= 0 {
+		// The newest attempts, turned back oldest first
+		query = `
+			SELECT * FROM (
+				SELECT id, notification_id, user_id, retry_count, queue, redelivered, project_id,
+					results, success_count, failure_count, error, next_queue, attempted_at
+				FROM delivery_attempts
+				WHERE id > $1
+				ORDER BY id DESC
+				LIMIT $2
+			) AS recent
+			ORDER BY id
+		`
+		after = 0
+	}
+
+	rows, err := r.db.Query(ctx, query, after, limit)
+	if err != nil {
+		zap.L().Error("Failed to list recent delivery attempts", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.DeliveryEvent{}
+	for rows.Next() {
+		var event models.DeliveryEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.NotificationID,
+			&event.UserID,
+			&event.RetryCount,
+			&event.Queue,
+			&event.Redelivered,
+			&event.ProjectID,
+			&event.Results,
+			&event.SuccessCount,
+			&event.FailureCount,
+			&event.Error,
+			&event.NextQueue,
+			&event.AttemptedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *deliveryAttemptRepo) SaveTokenResults(ctx context.Context, notificationID, userID string, results []models.AttemptTokenResult) error {
+	// A token can't be upserted twice in one statement
+	seen := make(map[string]bool, len(results))
+	var tokens []string
+	var successes []bool
+	var messageIDs, errorKinds, errorCodes, errorMessages []*string
+	for _, result := range results {
+		if result.DeviceToken == "" || seen[result.DeviceToken] {
+			continue
+		}
+		seen[result.DeviceToken] = true
+		tokens = append(tokens, result.DeviceToken)
+		successes = append(successes, result.Success)
+		messageIDs = append(messageIDs, result.MessageID)
+		errorKinds = append(errorKinds, result.ErrorKind)
+		errorCodes = append(errorCodes, result.ErrorCode)
+		errorMessages = append(errorMessages, result.Error)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO delivery_token_results (notification_id, user_id, token, success, message_id, error_kind, error_code, error)
+		SELECT $1, $2, result.*
+		FROM unnest($3::text[], $4::boolean[], $5::text[], $6::text[], $7::text[], $8::text[]) AS result
+		ON CONFLICT (notification_id, token) DO UPDATE
+		SET success = EXCLUDED.success,
+			message_id = EXCLUDED.message_id,
+			error_kind = EXCLUDED.error_kind,
+			error_code = EXCLUDED.error_code,
+			error = EXCLUDED.error,
+			attempts = delivery_token_results.attempts + 1,
+			updated_at = NOW()
+		WHERE NOT delivery_token_results.success
+	`
+
+	_, err := r.db.Exec(ctx, query, notificationID, userID, tokens, successes, messageIDs, errorKinds, errorCodes, errorMessages)
+	if err != nil {
+		zap.L().Error("Failed to save token delivery results", zap.String("notification_id", notificationID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *deliveryAttemptRepo) ListTokenResults(ctx context.Context, notificationID, token string) ([]models.TokenDeliveryResult, error) {
+	query := `
+		SELECT token, success, message_id, error_kind, error_code, error, attempts, updated_at
+		FROM delivery_token_results
+		WHERE notification_id = $1 AND ($2 = '' OR token = $2)
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, notificationID, token)
+	if err != nil {
+		zap.L().Error("Failed to list token delivery results", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.TokenDeliveryResult{}
+	for rows.Next() {
+		var result models.TokenDeliveryResult
+		if err := rows.Scan(
+			&result.Token,
+			&result.Success,
+			&result.MessageID,
+			&result.ErrorKind,
+			&result.ErrorCode,
+			&result.Error,
+			&result.Attempts,
+			&result.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
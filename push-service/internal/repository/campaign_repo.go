@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type CampaignRepository interface {
+	Create(ctx context.Context, campaign *models.Campaign) error
+	GetByID(ctx context.Context, id string) (*models.Campaign, error)
+	ClaimRunning(ctx context.Context, lease time.Duration, batchSize int) ([]models.Campaign, error)
+	UpdateProgress(ctx context.Context, campaign *models.Campaign) error
+	Cancel(ctx context.Context, id string) error
+	Continue(ctx context.Context, id string) error
+}
+
+type campaignRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewCampaignRepository(db *pgxpool.Pool) CampaignRepository {
+	return &campaignRepo{db: db}
+}
+
+// campaignColumns lists every column except user_ids; total_users is derived
+const campaignColumns = `
+	id, name, title, body, image, link, data, sender, category, analytics_label,
+	variants, canary_percent, canary_users, canary_soak_seconds, canary_completed_at,
+	continue_at, cardinality(user_ids), processed_users, enqueued_users, enqueued_tokens,
+	quota_policy, status, projected_completion_at, created_at, updated_at, completed_at
+`
+
+func scanCampaign(row pgx.Row, campaign *models.Campaign, extra ...any) error {
+	dest := []any{
+		&campaign.ID,
+		&campaign.Name,
+		&campaign.Title,
+		&campaign.Body,
+		&campaign.Image,
+		&campaign.Link,
+		&campaign.Data,
+		&campaign.Sender,
+		&campaign.Category,
+		&campaign.AnalyticsLabel,
+		&campaign.Variants,
+		&campaign.CanaryPercent,
+		&campaign.CanaryUsers,
+		&campaign.CanarySoakSeconds,
+		&campaign.CanaryCompletedAt,
+		&campaign.ContinueAt,
+		&campaign.TotalUsers,
+		&campaign.ProcessedUsers,
+		&campaign.EnqueuedUsers,
+		&campaign.EnqueuedTokens,
+		&campaign.QuotaPolicy,
+		&campaign.Status,
+		&campaign.ProjectedCompletionAt,
+		&campaign.CreatedAt,
+		&campaign.UpdatedAt,
+		&campaign.CompletedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func (r *campaignRepo) Create(ctx context.Context, campaign *models.Campaign) error {
+	query := `
+		INSERT INTO campaigns (name, title, body, image, link, data, sender, category, analytics_label, variants, canary_percent, canary_users, canary_soak_seconds, user_ids, quota_policy, status, projected_completion_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		campaign.Name,
+		campaign.Title,
+		campaign.Body,
+		campaign.Image,
+		campaign.Link,
+		campaign.Data,
+		campaign.Sender,
+		campaign.Category,
+		campaign.AnalyticsLabel,
+		campaign.Variants,
+		campaign.CanaryPercent,
+		campaign.CanaryUsers,
+		campaign.CanarySoakSeconds,
+		campaign.UserIDs,
+		campaign.QuotaPolicy,
+		campaign.Status,
+		campaign.ProjectedCompletionAt,
+	).Scan(&campaign.ID, &campaign.CreatedAt, &campaign.UpdatedAt)
+
+	if err != nil {
+		zap.L().Error("Failed to create campaign", zap.Error(err))
+		return err
+	}
+
+	campaign.TotalUsers = len(campaign.UserIDs)
+	return nil
+}
+
+func (r *campaignRepo) GetByID(ctx context.Context, id string) (*models.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns WHERE id = $1`
+
+	var campaign models.Campaign
+	if err := scanCampaign(r.db.QueryRow(ctx, query, id), &campaign); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to get campaign", zap.Error(err))
+		return nil, err
+	}
+
+	return &campaign, nil
+}
+
+// ClaimRunning leases running campaigns, and soaking ones due to continue,
+// that no other worker holds and loads the next batchSize unprocessed user
+// IDs of each. A batch of the canary stops at the last canary user. The lease
+// is released by UpdateProgress or expires, so a crashed worker doesn't
+// strand a campaign.
+func (r *campaignRepo) ClaimRunning(ctx context.Context, lease time.Duration, batchSize int) ([]models.Campaign, error) {
+	query := `
+		UPDATE campaigns
+		SET locked_until = NOW() + make_interval(secs => $1)
+		WHERE id IN (
+			SELECT id FROM campaigns
+			WHERE (status = 'running' OR (status = 'soaking' AND continue_at <= NOW()))
+				AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY created_at
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + campaignColumns + `, user_ids[processed_users + 1 : CASE
+			WHEN processed_users < canary_users THEN LEAST(processed_users + $2, canary_users)
+			ELSE processed_users + $2
+		END]
+	`
+
+	rows, err := r.db.Query(ctx, query, lease.Seconds(), batchSize)
+	if err != nil {
+		zap.L().Error("Failed to claim running campaigns", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var campaigns []models.Campaign
+	for rows.Next() {
+		var campaign models.Campaign
+		if err := scanCampaign(rows, &campaign, &campaign.UserIDs); err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns, rows.Err()
+}
+
+// UpdateProgress saves the campaign's counters, status, canary progress and
+// projection and releases its lease. A cancelled campaign stays cancelled.
+func (r *campaignRepo) UpdateProgress(ctx context.Context, campaign *models.Campaign) error {
+	query := `
+		UPDATE campaigns
+		SET processed_users = $2,
+			enqueued_users = $3,
+			enqueued_tokens = $4,
+			status = CASE WHEN status = 'cancelled' THEN status ELSE $5 END,
+			projected_completion_at = $6,
+			completed_at = $7,
+			canary_completed_at = $8,
+			continue_at = $9,
+			locked_until = NULL,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(
+		ctx,
+		query,
+		campaign.ID,
+		campaign.ProcessedUsers,
+		campaign.EnqueuedUsers,
+		campaign.EnqueuedTokens,
+		campaign.Status,
+		campaign.ProjectedCompletionAt,
+		campaign.CompletedAt,
+		campaign.CanaryCompletedAt,
+		campaign.ContinueAt,
+	)
+	if err != nil {
+		zap.L().Error("Failed to update campaign progress", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *campaignRepo) Cancel(ctx context.Context, id string) error {
+	query := `
+		UPDATE campaigns
+		SET status = 'cancelled', projected_completion_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status IN ('running', 'soaking')
+	`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		zap.L().Error("Failed to cancel campaign", zap.Error(err))
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// Continue releases a soaking campaign to the rest of its audience on the
+// scheduler's next tick
+func (r *campaignRepo) Continue(ctx context.Context, id string) error {
+	query := `
+		UPDATE campaigns
+		SET continue_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'soaking'
+	`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		zap.L().Error("Failed to continue campaign", zap.Error(err))
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type DeadLetterArchiveRepository interface {
+	// Stage stores a dead letter taken off the queue until it is archived
+	Stage(ctx context.Context, notificationID, userID *string, body []byte, deadLetteredAt *time.Time) error
+	// ListStaged returns up to limit staged dead letters, oldest first
+	ListStaged(ctx context.Context, limit int) ([]models.StagedDeadLetter, error)
+	// MarkArchived records that the dead letters ids, in order, are the lines
+	// of the object at objectURL, and drops their staged bodies
+	MarkArchived(ctx context.Context, ids []int64, objectURL string) error
+	// ListByNotification returns a notification's dead letters, oldest first
+	ListByNotification(ctx context.Context, notificationID string) ([]models.ArchivedDeadLetter, error)
+}
+
+type deadLetterArchiveRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDeadLetterArchiveRepository(db *pgxpool.Pool) DeadLetterArchiveRepository {
+	return &deadLetterArchiveRepo{db: db}
+}
+
+func (r *deadLetterArchiveRepo) Stage(ctx context.Context, notificationID, userID *string, body []byte, deadLetteredAt *time.Time) error {
+	query := `
+		INSERT INTO dead_letter_archive (notification_id, user_id, body, dead_lettered_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.db.Exec(ctx, query, notificationID, userID, body, deadLetteredAt); err != nil {
+		zap.L().Error("Failed to stage dead letter", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *deadLetterArchiveRepo) ListStaged(ctx context.Context, limit int) ([]models.StagedDeadLetter, error) {
+	query := `
+		SELECT id, notification_id, body, dead_lettered_at
+		FROM dead_letter_archive
+		WHERE object_url IS NULL
+		ORDER BY id
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		zap.L().Error("Failed to list staged dead letters", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var staged []models.StagedDeadLetter
+	for rows.Next() {
+		var deadLetter models.StagedDeadLetter
+		if err := rows.Scan(&deadLetter.ID, &deadLetter.NotificationID, &deadLetter.Body, &deadLetter.DeadLetteredAt); err != nil {
+			return nil, err
+		}
+		staged = append(staged, deadLetter)
+	}
+	return staged, rows.Err()
+}
+
+func (r *deadLetterArchiveRepo) MarkArchived(ctx context.Context, ids []int64, objectURL string) error {
+	query := `
+		UPDATE dead_letter_archive a
+		SET object_url = $2, line = archived.line, body = NULL, archived_at = NOW()
+		FROM unnest($1::bigint[]) WITH ORDINALITY AS archived(id, line)
+		WHERE a.id = archived.id
+	`
+
+	if _, err := r.db.Exec(ctx, query, ids, objectURL); err != nil {
+		zap.L().Error("Failed to mark dead letters archived", zap.String("object_url", objectURL), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *deadLetterArchiveRepo) ListByNotification(ctx context.Context, notificationID string) ([]models.ArchivedDeadLetter, error) {
+	query := `
+		SELECT notification_id, dead_lettered_at, staged_at, object_url, line, archived_at
+		FROM dead_letter_archive
+		WHERE notification_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, notificationID)
+	if err != nil {
+		zap.L().Error("Failed to list archived dead letters", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadLetters := []models.ArchivedDeadLetter{}
+	for rows.Next() {
+		var deadLetter models.ArchivedDeadLetter
+		if err := rows.Scan(
+			&deadLetter.NotificationID,
+			&deadLetter.DeadLetteredAt,
+			&deadLetter.StagedAt,
+			&deadLetter.ObjectURL,
+			&deadLetter.Line,
+			&deadLetter.ArchivedAt,
+		); err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, rows.Err()
+}
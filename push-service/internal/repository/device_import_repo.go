@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type DeviceImportRepository interface {
+	Create(ctx context.Context, deviceImport *models.DeviceImport) error
+	Update(ctx context.Context, deviceImport *models.DeviceImport) error
+	GetByID(ctx context.Context, id string) (*models.DeviceImport, error)
+	List(ctx context.Context, limit int) ([]models.DeviceImport, error)
+}
+
+type deviceImportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDeviceImportRepository(db *pgxpool.Pool) DeviceImportRepository {
+	return &deviceImportRepo{db: db}
+}
+
+func (r *deviceImportRepo) Create(ctx context.Context, deviceImport *models.DeviceImport) error {
+	query := `
+		INSERT INTO device_imports (format, status)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, deviceImport.Format, deviceImport.Status).Scan(
+		&deviceImport.ID,
+		&deviceImport.CreatedAt,
+		&deviceImport.UpdatedAt,
+	)
+	if err != nil {
+		zap.L().Error("Failed to create device import", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Update stores an import's progress, and its outcome once it has one
+func (r *deviceImportRepo) Update(ctx context.Context, deviceImport *models.DeviceImport) error {
+	query := `
+		UPDATE device_imports
+		SET status = $2, rows_read = $3, inserted = $4, updated = $5, invalid = $6,
+			errors = $7, error = $8, completed_at = $9, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		deviceImport.ID,
+		deviceImport.Status,
+		deviceImport.RowsRead,
+		deviceImport.Inserted,
+		deviceImport.Updated,
+		deviceImport.Invalid,
+		deviceImport.Errors,
+		deviceImport.Error,
+		deviceImport.CompletedAt,
+	).Scan(&deviceImport.UpdatedAt)
+	if err != nil {
+		zap.L().Error("Failed to update device import", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *deviceImportRepo) GetByID(ctx context.Context, id string) (*models.DeviceImport, error) {
+	query := `
+		SELECT id, format, status, rows_read, inserted, updated, invalid, errors, error, created_at, updated_at, completed_at
+		FROM device_imports
+		WHERE id = $1
+	`
+
+	deviceImport, err := scanDeviceImport(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to get device import", zap.Error(err))
+		return nil, err
+	}
+
+	return deviceImport, nil
+}
+
+// List returns the most recent imports, newest first
+func (r *deviceImportRepo) List(ctx context.Context, limit int) ([]models.DeviceImport, error) {
+	query := `
+		SELECT id, format, status, rows_read, inserted, updated, invalid, errors, error, created_at, updated_at, completed_at
+		FROM device_imports
+		ORDER BY created_at DESC
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		zap.L().Error("Failed to list device imports", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports := []models.DeviceImport{}
+	for rows.Next() {
+		deviceImport, err := scanDeviceImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, *deviceImport)
+	}
+
+	return imports, rows.Err()
+}
+
+func scanDeviceImport(row pgx.Row) (*models.DeviceImport, error) {
+	var deviceImport models.DeviceImport
+	err := row.Scan(
+		&deviceImport.ID,
+		&deviceImport.Format,
+		&deviceImport.Status,
+		&deviceImport.RowsRead,
+		&deviceImport.Inserted,
+		&deviceImport.Updated,
+		&deviceImport.Invalid,
+		&deviceImport.Errors,
+		&deviceImport.Error,
+		&deviceImport.CreatedAt,
+		&deviceImport.UpdatedAt,
+		&deviceImport.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &deviceImport, nil
+}
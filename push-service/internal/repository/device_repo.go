@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"push-service/internal/models"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -12,24 +14,46 @@ import (
 type DeviceRepository interface {
 	Create(ctx context.Context, device *models.Device) error
 	GetByToken(ctx context.Context, token string) (*models.Device, error)
+	FindDeactivated(ctx context.Context, token string) (*models.Device, error)
+	Reactivate(ctx context.Context, id, userID, platform string) (*models.Device, error)
 	GetByUserID(ctx context.Context, userID string) ([]models.Device, error)
+	// GetByUserIDs returns the active devices of each of userIDs, newest
+	// first, in one query. Users without devices are left out.
+	GetByUserIDs(ctx context.Context, userIDs []string) (map[string][]models.Device, error)
 	UpdateStatus(ctx context.Context, token string, isActive bool) error
 	Delete(ctx context.Context, token string) error
+	ReplaceToken(ctx context.Context, oldToken, newToken string) (*models.Device, error)
+	UpdatePermission(ctx context.Context, token, status string) error
+	UpdateTimezone(ctx context.Context, token, timezone string) error
+	UpdateMetadata(ctx context.Context, token string, metadata models.DeviceMetadata) error
+	Touch(ctx context.Context, token string) (time.Time, error)
+	GetByStableID(ctx context.Context, stableDeviceID string) (*models.Device, error)
+	ClaimStableID(ctx context.Context, token, stableDeviceID string) (int64, error)
+	DeactivateStale(ctx context.Context, seenBefore time.Time, limit int) (int64, error)
+	PurgeDeactivated(ctx context.Context, deactivatedBefore time.Time, limit int) (int64, error)
+	UpsertBatch(ctx context.Context, rows []models.DeviceImportRow) (inserted, updated int64, err error)
+	ListAfter(ctx context.Context, filter models.DeviceFilter, after string, limit int) ([]models.Device, error)
 }
 
 type deviceRepo struct {
 	db *pgxpool.Pool
+	// replica serves the device lookups of sends and exports, which may lag
+	// slightly behind registrations
+	replica *pgxpool.Pool
 }
 
-func NewDeviceRepository(db *pgxpool.Pool) DeviceRepository {
-	return &deviceRepo{db: db}
+// NewDeviceRepository creates the repository. replica may be the primary
+// itself.
+func NewDeviceRepository(db, replica *pgxpool.Pool) DeviceRepository {
+	return &deviceRepo{db: db, replica: replica}
 }
 
 func (r *deviceRepo) Create(ctx context.Context, device *models.Device) error {
 	query := `
-		INSERT INTO devices (user_id, token, platform, is_active)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at
+		INSERT INTO devices (user_id, token, platform, is_active, permission_status, permission_updated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $5::text IS NULL THEN NULL ELSE NOW() END, $6, $7, $8, $9, $10, $11, NOW(), $12)
+		RETURNING id, created_at, updated_at, permission_updated_at, last_seen_at
 	`
 
 	err := r.db.QueryRow(
@@ -39,7 +63,15 @@ func (r *deviceRepo) Create(ctx context.Context, device *models.Device) error {
 		device.Token,
 		device.Platform,
 		device.IsActive,
-	).Scan(&device.ID, &device.CreatedAt, &device.UpdatedAt)
+		device.PermissionStatus,
+		device.Timezone,
+		device.AppVersion,
+		device.OSVersion,
+		device.DeviceModel,
+		device.Locale,
+		device.Metadata,
+		device.StableDeviceID,
+	).Scan(&device.ID, &device.CreatedAt, &device.UpdatedAt, &device.PermissionUpdatedAt, &device.LastSeenAt)
 
 	if err != nil {
 		zap.L().Error("Failed to create device", zap.Error(err))
@@ -51,7 +83,8 @@ func (r *deviceRepo) Create(ctx context.Context, device *models.Device) error {
 
 func (r *deviceRepo) GetByToken(ctx context.Context, token string) (*models.Device, error) {
 	query := `
-		SELECT id, user_id, token, platform, is_active, created_at, updated_at
+		SELECT id, user_id, token, platform, is_active, created_at, updated_at, permission_status, permission_updated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id
 		FROM devices
 		WHERE token = $1 AND is_active = true
 	`
@@ -65,6 +98,16 @@ func (r *deviceRepo) GetByToken(ctx context.Context, token string) (*models.Devi
 		&device.IsActive,
 		&device.CreatedAt,
 		&device.UpdatedAt,
+		&device.PermissionStatus,
+		&device.PermissionUpdatedAt,
+		&device.Timezone,
+		&device.AppVersion,
+		&device.OSVersion,
+		&device.DeviceModel,
+		&device.Locale,
+		&device.Metadata,
+		&device.LastSeenAt,
+		&device.StableDeviceID,
 	)
 
 	if err != nil {
@@ -78,15 +121,103 @@ func (r *deviceRepo) GetByToken(ctx context.Context, token string) (*models.Devi
 	return &device, nil
 }
 
+// FindDeactivated returns the most recently deactivated registration of token
+func (r *deviceRepo) FindDeactivated(ctx context.Context, token string) (*models.Device, error) {
+	query := `
+		SELECT id, user_id, token, platform, is_active, created_at, updated_at, permission_status, permission_updated_at, deactivated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id
+		FROM devices
+		WHERE token = $1 AND is_active = false
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+
+	var device models.Device
+	err := r.db.QueryRow(ctx, query, token).Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Token,
+		&device.Platform,
+		&device.IsActive,
+		&device.CreatedAt,
+		&device.UpdatedAt,
+		&device.PermissionStatus,
+		&device.PermissionUpdatedAt,
+		&device.DeactivatedAt,
+		&device.Timezone,
+		&device.AppVersion,
+		&device.OSVersion,
+		&device.DeviceModel,
+		&device.Locale,
+		&device.Metadata,
+		&device.LastSeenAt,
+		&device.StableDeviceID,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to find deactivated device", zap.Error(err))
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// Reactivate restores a deactivated device, keeping its ID, permission status
+// and history, for userID on platform.
+func (r *deviceRepo) Reactivate(ctx context.Context, id, userID, platform string) (*models.Device, error) {
+	query := `
+		UPDATE devices
+		SET is_active = true, deactivated_at = NULL, user_id = $2, platform = $3, last_seen_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, user_id, token, platform, is_active, created_at, updated_at, permission_status, permission_updated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id
+	`
+
+	var device models.Device
+	err := r.db.QueryRow(ctx, query, id, userID, platform).Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Token,
+		&device.Platform,
+		&device.IsActive,
+		&device.CreatedAt,
+		&device.UpdatedAt,
+		&device.PermissionStatus,
+		&device.PermissionUpdatedAt,
+		&device.Timezone,
+		&device.AppVersion,
+		&device.OSVersion,
+		&device.DeviceModel,
+		&device.Locale,
+		&device.Metadata,
+		&device.LastSeenAt,
+		&device.StableDeviceID,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to reactivate device", zap.Error(err))
+		return nil, err
+	}
+
+	return &device, nil
+}
+
 func (r *deviceRepo) GetByUserID(ctx context.Context, userID string) ([]models.Device, error) {
 	query := `
-		SELECT id, user_id, token, platform, is_active, created_at, updated_at
+		SELECT id, user_id, token, platform, is_active, created_at, updated_at, permission_status, permission_updated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id
 		FROM devices
 		WHERE user_id = $1 AND is_active = true
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := r.replica.Query(ctx, query, userID)
 	if err != nil {
 		zap.L().Error("Failed to get devices by user ID", zap.Error(err))
 		return nil, err
@@ -104,6 +235,16 @@ func (r *deviceRepo) GetByUserID(ctx context.Context, userID string) ([]models.D
 			&device.IsActive,
 			&device.CreatedAt,
 			&device.UpdatedAt,
+			&device.PermissionStatus,
+			&device.PermissionUpdatedAt,
+			&device.Timezone,
+			&device.AppVersion,
+			&device.OSVersion,
+			&device.DeviceModel,
+			&device.Locale,
+			&device.Metadata,
+			&device.LastSeenAt,
+			&device.StableDeviceID,
 		)
 		if err != nil {
 			return nil, err
@@ -114,10 +255,59 @@ func (r *deviceRepo) GetByUserID(ctx context.Context, userID string) ([]models.D
 	return devices, nil
 }
 
+func (r *deviceRepo) GetByUserIDs(ctx context.Context, userIDs []string) (map[string][]models.Device, error) {
+	query := `
+		SELECT id, user_id, token, platform, is_active, created_at, updated_at, permission_status, permission_updated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id
+		FROM devices
+		WHERE user_id = ANY($1) AND is_active = true
+		ORDER BY user_id, created_at DESC
+	`
+
+	rows, err := r.replica.Query(ctx, query, userIDs)
+	if err != nil {
+		zap.L().Error("Failed to get devices by user IDs", zap.Int("user_count", len(userIDs)), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make(map[string][]models.Device)
+	for rows.Next() {
+		var device models.Device
+		err := rows.Scan(
+			&device.ID,
+			&device.UserID,
+			&device.Token,
+			&device.Platform,
+			&device.IsActive,
+			&device.CreatedAt,
+			&device.UpdatedAt,
+			&device.PermissionStatus,
+			&device.PermissionUpdatedAt,
+			&device.Timezone,
+			&device.AppVersion,
+			&device.OSVersion,
+			&device.DeviceModel,
+			&device.Locale,
+			&device.Metadata,
+			&device.LastSeenAt,
+			&device.StableDeviceID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		devices[device.UserID] = append(devices[device.UserID], device)
+	}
+
+	return devices, rows.Err()
+}
+
 func (r *deviceRepo) UpdateStatus(ctx context.Context, token string, isActive bool) error {
 	query := `
 		UPDATE devices 
-		SET is_active = $1, updated_at = NOW()
+		SET is_active = $1,
+			deactivated_at = CASE WHEN $1 THEN NULL ELSE COALESCE(deactivated_at, NOW()) END,
+			updated_at = NOW()
 		WHERE token = $2
 	`
 
@@ -148,4 +338,386 @@ func (r *deviceRepo) Delete(ctx context.Context, token string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// ReplaceToken moves a device to its refreshed token, keeping its ID, user
+// and permission status. A separate registration of newToken is removed.
+func (r *deviceRepo) ReplaceToken(ctx context.Context, oldToken, newToken string) (*models.Device, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM devices WHERE token = $1 AND token <> $2`, newToken, oldToken); err != nil {
+		zap.L().Error("Failed to replace device token", zap.Error(err))
+		return nil, err
+	}
+
+	query := `
+		UPDATE devices
+		SET token = $2, is_active = true, last_seen_at = NOW(), updated_at = NOW()
+		WHERE token = $1
+		RETURNING id, user_id, token, platform, is_active, created_at, updated_at, permission_status, permission_updated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id
+	`
+
+	var device models.Device
+	err = tx.QueryRow(ctx, query, oldToken, newToken).Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Token,
+		&device.Platform,
+		&device.IsActive,
+		&device.CreatedAt,
+		&device.UpdatedAt,
+		&device.PermissionStatus,
+		&device.PermissionUpdatedAt,
+		&device.Timezone,
+		&device.AppVersion,
+		&device.OSVersion,
+		&device.DeviceModel,
+		&device.Locale,
+		&device.Metadata,
+		&device.LastSeenAt,
+		&device.StableDeviceID,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to replace device token", zap.Error(err))
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *deviceRepo) UpdatePermission(ctx context.Context, token, status string) error {
+	query := `
+		UPDATE devices
+		SET permission_status = $1, permission_updated_at = NOW(), updated_at = NOW()
+		WHERE token = $2 AND is_active = true
+	`
+
+	result, err := r.db.Exec(ctx, query, status, token)
+	if err != nil {
+		zap.L().Error("Failed to update device permission", zap.Error(err))
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *deviceRepo) UpdateTimezone(ctx context.Context, token, timezone string) error {
+	query := `
+		UPDATE devices
+		SET timezone = $1, updated_at = NOW()
+		WHERE token = $2 AND is_active = true
+	`
+
+	result, err := r.db.Exec(ctx, query, timezone, token)
+	if err != nil {
+		zap.L().Error("Failed to update device timezone", zap.Error(err))
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateMetadata stores the reported metadata; fields that are nil keep
+// their value, and metadata keys are merged into the stored ones
+func (r *deviceRepo) UpdateMetadata(ctx context.Context, token string, metadata models.DeviceMetadata) error {
+	query := `
+		UPDATE devices
+		SET app_version = COALESCE($2, app_version),
+			os_version = COALESCE($3, os_version),
+			device_model = COALESCE($4, device_model),
+			locale = COALESCE($5, locale),
+			metadata = CASE WHEN $6::jsonb IS NULL THEN metadata ELSE COALESCE(metadata, '{}'::jsonb) || $6::jsonb END,
+			updated_at = NOW()
+		WHERE token = $1 AND is_active = true
+	`
+
+	result, err := r.db.Exec(ctx, query,
+		token,
+		metadata.AppVersion,
+		metadata.OSVersion,
+		metadata.DeviceModel,
+		metadata.Locale,
+		metadata.Metadata,
+	)
+	if err != nil {
+		zap.L().Error("Failed to update device metadata", zap.Error(err))
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// Touch records that the app checked in from the device and returns when.
+// pgx.ErrNoRows is returned if no active device has the token.
+func (r *deviceRepo) Touch(ctx context.Context, token string) (time.Time, error) {
+	query := `
+		UPDATE devices
+		SET last_seen_at = NOW()
+		WHERE token = $1 AND is_active = true
+		RETURNING last_seen_at
+	`
+
+	var lastSeenAt time.Time
+	err := r.db.QueryRow(ctx, query, token).Scan(&lastSeenAt)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			zap.L().Error("Failed to touch device", zap.Error(err))
+		}
+		return time.Time{}, err
+	}
+
+	return lastSeenAt, nil
+}
+
+// GetByStableID returns the most recently updated registration, active or
+// not, of the physical device stableDeviceID
+func (r *deviceRepo) GetByStableID(ctx context.Context, stableDeviceID string) (*models.Device, error) {
+	query := `
+		SELECT id, user_id, token, platform, is_active, created_at, updated_at, permission_status, permission_updated_at, deactivated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id
+		FROM devices
+		WHERE stable_device_id = $1
+		ORDER BY is_active DESC, updated_at DESC
+		LIMIT 1
+	`
+
+	var device models.Device
+	err := r.db.QueryRow(ctx, query, stableDeviceID).Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Token,
+		&device.Platform,
+		&device.IsActive,
+		&device.CreatedAt,
+		&device.UpdatedAt,
+		&device.PermissionStatus,
+		&device.PermissionUpdatedAt,
+		&device.DeactivatedAt,
+		&device.Timezone,
+		&device.AppVersion,
+		&device.OSVersion,
+		&device.DeviceModel,
+		&device.Locale,
+		&device.Metadata,
+		&device.LastSeenAt,
+		&device.StableDeviceID,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to get device by stable ID", zap.Error(err))
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// ClaimStableID records that token belongs to the physical device
+// stableDeviceID, and deactivates the device's other active registrations.
+// It returns how many it deactivated.
+func (r *deviceRepo) ClaimStableID(ctx context.Context, token, stableDeviceID string) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE devices
+		SET is_active = false, deactivated_at = COALESCE(deactivated_at, NOW()), updated_at = NOW()
+		WHERE stable_device_id = $1 AND token <> $2 AND is_active = true
+	`, stableDeviceID, token)
+	if err != nil {
+		zap.L().Error("Failed to deactivate duplicate devices", zap.Error(err))
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE devices
+		SET stable_device_id = $1, updated_at = NOW()
+		WHERE token = $2 AND is_active = true
+	`, stableDeviceID, token); err != nil {
+		zap.L().Error("Failed to set device stable ID", zap.Error(err))
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeactivateStale deactivates up to limit active devices last seen before
+// seenBefore
+func (r *deviceRepo) DeactivateStale(ctx context.Context, seenBefore time.Time, limit int) (int64, error) {
+	query := `
+		UPDATE devices
+		SET is_active = false, deactivated_at = NOW(), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM devices
+			WHERE is_active = true AND last_seen_at < $1
+			LIMIT $2
+		)
+	`
+
+	result, err := r.db.Exec(ctx, query, seenBefore, limit)
+	if err != nil {
+		zap.L().Error("Failed to deactivate stale devices", zap.Error(err))
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// PurgeDeactivated deletes up to limit devices deactivated before
+// deactivatedBefore
+func (r *deviceRepo) PurgeDeactivated(ctx context.Context, deactivatedBefore time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM devices
+		WHERE id IN (
+			SELECT id FROM devices
+			WHERE is_active = false AND deactivated_at < $1
+			LIMIT $2
+		)
+	`
+
+	result, err := r.db.Exec(ctx, query, deactivatedBefore, limit)
+	if err != nil {
+		zap.L().Error("Failed to purge deactivated devices", zap.Error(err))
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// UpsertBatch registers imported devices. A token that is already registered,
+// even deactivated, is moved to the row's user and platform and reactivated;
+// if a token appears more than once, its last row wins. New devices count as
+// seen when imported.
+func (r *deviceRepo) UpsertBatch(ctx context.Context, rows []models.DeviceImportRow) (int64, int64, error) {
+	userIDs := make([]string, len(rows))
+	tokens := make([]string, len(rows))
+	platforms := make([]string, len(rows))
+	for i, row := range rows {
+		userIDs[i] = row.UserID
+		tokens[i] = row.Token
+		platforms[i] = row.Platform
+	}
+
+	query := `
+		WITH input AS (
+			SELECT DISTINCT ON (token) user_id, token, platform
+			FROM unnest($1::text[], $2::text[], $3::text[]) WITH ORDINALITY AS r(user_id, token, platform, n)
+			ORDER BY token, n DESC
+		),
+		updated AS (
+			UPDATE devices d
+			SET user_id = i.user_id, platform = i.platform, is_active = true, deactivated_at = NULL, updated_at = NOW()
+			FROM input i
+			WHERE d.token = i.token
+			RETURNING d.token
+		),
+		inserted AS (
+			INSERT INTO devices (user_id, token, platform, last_seen_at)
+			SELECT i.user_id, i.token, i.platform, NOW()
+			FROM input i
+			WHERE NOT EXISTS (SELECT 1 FROM updated u WHERE u.token = i.token)
+			RETURNING token
+		)
+		SELECT (SELECT COUNT(*) FROM inserted), (SELECT COUNT(DISTINCT token) FROM updated)
+	`
+
+	var inserted, updated int64
+	if err := r.db.QueryRow(ctx, query, userIDs, tokens, platforms).Scan(&inserted, &updated); err != nil {
+		zap.L().Error("Failed to upsert devices", zap.Error(err))
+		return 0, 0, err
+	}
+
+	return inserted, updated, nil
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListAfter returns up to limit of the devices matching filter, active or
+// not, in ID order after the device ID after if it is set
+func (r *deviceRepo) ListAfter(ctx context.Context, filter models.DeviceFilter, after string, limit int) ([]models.Device, error) {
+	query := `
+		SELECT id, user_id, token, platform, is_active, created_at, updated_at, permission_status, permission_updated_at, deactivated_at, timezone,
+			app_version, os_version, device_model, locale, metadata, last_seen_at, stable_device_id
+		FROM devices
+		WHERE ($1 = '' OR id > NULLIF($1, '')::uuid)
+			AND ($2 = '' OR user_id = $2)
+			AND ($3 = '' OR platform = $3)
+			AND ($4::boolean IS NULL OR is_active = $4)
+			AND ($5::timestamptz IS NULL OR last_seen_at >= $5)
+			AND ($6 = '' OR user_id LIKE $6 || '%')
+			AND ($7::timestamptz IS NULL OR created_at < $7)
+		ORDER BY id
+		LIMIT $8
+	`
+
+	rows, err := r.replica.Query(ctx, query, after, filter.UserID, filter.Platform, filter.Active, filter.SeenSince,
+		likeEscaper.Replace(filter.UserIDPrefix), filter.CreatedBefore, limit)
+	if err != nil {
+		zap.L().Error("Failed to list devices", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.Device{}
+	for rows.Next() {
+		var device models.Device
+		err := rows.Scan(
+			&device.ID,
+			&device.UserID,
+			&device.Token,
+			&device.Platform,
+			&device.IsActive,
+			&device.CreatedAt,
+			&device.UpdatedAt,
+			&device.PermissionStatus,
+			&device.PermissionUpdatedAt,
+			&device.DeactivatedAt,
+			&device.Timezone,
+			&device.AppVersion,
+			&device.OSVersion,
+			&device.DeviceModel,
+			&device.Locale,
+			&device.Metadata,
+			&device.LastSeenAt,
+			&device.StableDeviceID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type DigestRepository interface {
+	Add(ctx context.Context, item *models.DigestItem) error
+	ClaimDue(ctx context.Context, before time.Time, limit int) ([]models.DigestItem, error)
+}
+
+type digestRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDigestRepository(db *pgxpool.Pool) DigestRepository {
+	return &digestRepo{db: db}
+}
+
+// Add buffers an item. An item with CreatedAt set keeps it, so an item put
+// back after a failed delivery keeps its place.
+func (r *digestRepo) Add(ctx context.Context, item *models.DigestItem) error {
+	query := `
+		INSERT INTO digest_items (tenant_id, user_id, title, body, created_at)
+		VALUES ($1, $2, $3, $4, COALESCE($5, NOW()))
+		RETURNING id, created_at
+	`
+
+	var createdAt *time.Time
+	if !item.CreatedAt.IsZero() {
+		createdAt = &item.CreatedAt
+	}
+
+	err := r.db.QueryRow(ctx, query, item.TenantID, item.UserID, item.Title, item.Body, createdAt).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		zap.L().Error("Failed to add digest item", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDue removes and returns the items of up to limit users whose oldest
+// item was buffered before before, oldest first. A user's items are claimed
+// together, by one instance.
+func (r *digestRepo) ClaimDue(ctx context.Context, before time.Time, limit int) ([]models.DigestItem, error) {
+	query := `
+		DELETE FROM digest_items d
+		USING (
+			SELECT tenant_id, user_id
+			FROM digest_items
+			GROUP BY tenant_id, user_id
+			HAVING MIN(created_at) <= $1
+			ORDER BY MIN(created_at)
+			LIMIT $2
+		) due
+		WHERE d.tenant_id = due.tenant_id AND d.user_id = due.user_id
+		RETURNING d.id, d.tenant_id, d.user_id, d.title, d.body, d.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, before, limit)
+	if err != nil {
+		zap.L().Error("Failed to claim digest items", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.DigestItem
+	for rows.Next() {
+		var item models.DigestItem
+		if err := rows.Scan(&item.ID, &item.TenantID, &item.UserID, &item.Title, &item.Body, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
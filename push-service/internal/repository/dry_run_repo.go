@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type DryRunRepository interface {
+	Create(ctx context.Context, id string, messageCount int) error
+	AddResults(ctx context.Context, id string, results []models.DryRunResult) error
+	GetByID(ctx context.Context, id string) (*models.DryRun, error)
+}
+
+type dryRunRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDryRunRepository(db *pgxpool.Pool) DryRunRepository {
+	return &dryRunRepo{db: db}
+}
+
+// Create records how many messages the dry run enqueued. A worker may
+// already have added results, so an existing row is updated.
+func (r *dryRunRepo) Create(ctx context.Context, id string, messageCount int) error {
+	query := `
+		INSERT INTO push_dry_runs (id, message_count)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET message_count = EXCLUDED.message_count
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, messageCount); err != nil {
+		zap.L().Error("Failed to create dry run", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// AddResults stores the results of one dry run message and counts the
+// message as completed
+func (r *dryRunRepo) AddResults(ctx context.Context, id string, results []models.DryRunResult) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO push_dry_runs (id, completed_messages)
+		VALUES ($1, 1)
+		ON CONFLICT (id) DO UPDATE SET completed_messages = push_dry_runs.completed_messages + 1
+	`, id); err != nil {
+		zap.L().Error("Failed to update dry run", zap.Error(err))
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, result := range results {
+		batch.Queue(`
+			INSERT INTO push_dry_run_results (dry_run_id, user_id, token, valid, error_kind, error)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, id, result.UserID, result.Token, result.Valid, result.ErrorKind, result.Error)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		zap.L().Error("Failed to insert dry run results", zap.Error(err))
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *dryRunRepo) GetByID(ctx context.Context, id string) (*models.DryRun, error) {
+	query := `
+		SELECT id, COALESCE(message_count, 0), completed_messages, created_at,
+			message_count IS NOT NULL AND completed_messages >= message_count
+		FROM push_dry_runs
+		WHERE id = $1
+	`
+
+	var dryRun models.DryRun
+	var completed bool
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&dryRun.ID,
+		&dryRun.MessageCount,
+		&dryRun.CompletedMessages,
+		&dryRun.CreatedAt,
+		&completed,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to get dry run", zap.Error(err))
+		return nil, err
+	}
+
+	dryRun.Status = models.DryRunStatusPending
+	if completed {
+		dryRun.Status = models.DryRunStatusCompleted
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT user_id, token, valid, error_kind, error
+		FROM push_dry_run_results
+		WHERE dry_run_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		zap.L().Error("Failed to get dry run results", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	dryRun.Results = []models.DryRunResult{}
+	for rows.Next() {
+		var result models.DryRunResult
+		if err := rows.Scan(&result.UserID, &result.Token, &result.Valid, &result.ErrorKind, &result.Error); err != nil {
+			return nil, err
+		}
+		dryRun.Results = append(dryRun.Results, result)
+	}
+
+	return &dryRun, rows.Err()
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type FrequencyRepository interface {
+	// Reserve atomically counts a send to the user in category against the
+	// window starting at windowStart if it fits under limit, and reports
+	// whether it did
+	Reserve(ctx context.Context, userID, category string, windowStart, expiresAt time.Time, limit int) (bool, error)
+}
+
+type frequencyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewFrequencyRepository(db *pgxpool.Pool) FrequencyRepository {
+	return &frequencyRepo{db: db}
+}
+
+func (r *frequencyRepo) Reserve(ctx context.Context, userID, category string, windowStart, expiresAt time.Time, limit int) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+
+	query := `
+		INSERT INTO frequency_counts (user_id, category, window_start, expires_at, sent)
+		VALUES ($1, $2, $3, $4, 1)
+		ON CONFLICT (user_id, category, window_start) DO UPDATE
+		SET sent = frequency_counts.sent + 1
+		WHERE frequency_counts.sent < $5
+		RETURNING sent
+	`
+
+	var sent int
+	err := r.db.QueryRow(ctx, query, userID, category, windowStart, expiresAt, limit).Scan(&sent)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		zap.L().Error("Failed to reserve frequency cap", zap.Error(err))
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type InboxRepository interface {
+	Add(ctx context.Context, item *models.InboxItem) error
+	List(ctx context.Context, userID, cursor string, limit int, unreadOnly bool) ([]models.InboxItem, error)
+	CountUnread(ctx context.Context, userID string) (int, error)
+	MarkRead(ctx context.Context, userID, id string) error
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+}
+
+type inboxRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInboxRepository(db *pgxpool.Pool) InboxRepository {
+	return &inboxRepo{db: db}
+}
+
+// Add stores an item unless the user's inbox already has its notification
+func (r *inboxRepo) Add(ctx context.Context, item *models.InboxItem) error {
+	query := `
+		INSERT INTO inbox_items (user_id, notification_id, title, body, image, link, data, category)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, notification_id) DO NOTHING
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		item.UserID,
+		item.NotificationID,
+		item.Title,
+		item.Body,
+		item.Image,
+		item.Link,
+		item.Data,
+		item.Category,
+	)
+	if err != nil {
+		zap.L().Error("Failed to add inbox item", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// List returns up to limit of the user's items, newest first, after the
+// item cursor if it is set
+func (r *inboxRepo) List(ctx context.Context, userID, cursor string, limit int, unreadOnly bool) ([]models.InboxItem, error) {
+	query := `
+		SELECT id, user_id, notification_id, title, body, image, link, data, category, read_at, created_at
+		FROM inbox_items
+		WHERE user_id = $1
+			AND ($2 = '' OR (created_at, id) < (SELECT created_at, id FROM inbox_items WHERE id = NULLIF($2, '')::uuid))
+			AND (NOT $3 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, cursor, unreadOnly, limit)
+	if err != nil {
+		zap.L().Error("Failed to list inbox items", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.InboxItem{}
+	for rows.Next() {
+		var item models.InboxItem
+		err := rows.Scan(
+			&item.ID,
+			&item.UserID,
+			&item.NotificationID,
+			&item.Title,
+			&item.Body,
+			&item.Image,
+			&item.Link,
+			&item.Data,
+			&item.Category,
+			&item.ReadAt,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *inboxRepo) CountUnread(ctx context.Context, userID string) (int, error) {
+	query := `SELECT COUNT(*) FROM inbox_items WHERE user_id = $1 AND read_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		zap.L().Error("Failed to count unread inbox items", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MarkRead marks one of the user's items read. An item that was already
+// read keeps its read time.
+func (r *inboxRepo) MarkRead(ctx context.Context, userID, id string) error {
+	query := `
+		UPDATE inbox_items
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		zap.L().Error("Failed to mark inbox item read", zap.Error(err))
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// MarkAllRead marks the user's unread items read and returns how many
+// there were
+func (r *inboxRepo) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	query := `UPDATE inbox_items SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`
+
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		zap.L().Error("Failed to mark inbox items read", zap.Error(err))
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
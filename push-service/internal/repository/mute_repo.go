@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type MuteRepository interface {
+	Upsert(ctx context.Context, mute *models.UserMute) error
+	GetByUserID(ctx context.Context, userID string) ([]models.UserMute, error)
+	FindActive(ctx context.Context, userID string, sender, category *string) (*models.UserMute, error)
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type muteRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewMuteRepository(db *pgxpool.Pool) MuteRepository {
+	return &muteRepo{db: db}
+}
+
+// Upsert creates a mute, or refreshes the expiry of an existing one for the
+// same user, scope and value.
+func (r *muteRepo) Upsert(ctx context.Context, mute *models.UserMute) error {
+	query := `
+		INSERT INTO user_mutes (user_id, scope, value, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, scope, value) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		mute.UserID,
+		mute.Scope,
+		mute.Value,
+		mute.ExpiresAt,
+	).Scan(&mute.ID, &mute.CreatedAt)
+
+	if err != nil {
+		zap.L().Error("Failed to upsert user mute", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *muteRepo) GetByUserID(ctx context.Context, userID string) ([]models.UserMute, error) {
+	query := `
+		SELECT id, user_id, scope, value, expires_at, created_at
+		FROM user_mutes
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		zap.L().Error("Failed to get mutes by user ID", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mutes []models.UserMute
+	for rows.Next() {
+		var mute models.UserMute
+		err := rows.Scan(
+			&mute.ID,
+			&mute.UserID,
+			&mute.Scope,
+			&mute.Value,
+			&mute.ExpiresAt,
+			&mute.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		mutes = append(mutes, mute)
+	}
+
+	return mutes, nil
+}
+
+// FindActive returns an unexpired mute matching the sender or category, or
+// nil if the user hasn't muted either. Sender mutes win over category mutes.
+func (r *muteRepo) FindActive(ctx context.Context, userID string, sender, category *string) (*models.UserMute, error) {
+	if sender == nil && category == nil {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, user_id, scope, value, expires_at, created_at
+		FROM user_mutes
+		WHERE user_id = $1
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		  AND ((scope = 'sender' AND value = $2) OR (scope = 'category' AND value = $3))
+		ORDER BY scope DESC
+		LIMIT 1
+	`
+
+	var mute models.UserMute
+	err := r.db.QueryRow(ctx, query, userID, sender, category).Scan(
+		&mute.ID,
+		&mute.UserID,
+		&mute.Scope,
+		&mute.Value,
+		&mute.ExpiresAt,
+		&mute.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to find active mute", zap.Error(err))
+		return nil, err
+	}
+
+	return &mute, nil
+}
+
+func (r *muteRepo) Delete(ctx context.Context, userID, id string) error {
+	query := `DELETE FROM user_mutes WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		zap.L().Error("Failed to delete user mute", zap.Error(err))
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
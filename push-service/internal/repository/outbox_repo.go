@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"push-service/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type OutboxRepository interface {
+	// Add stores a notification's record and its queue message in one
+	// transaction, and returns the message's outbox ID
+	Add(ctx context.Context, notification models.PushNotification, message json.RawMessage) (int64, error)
+	// ListPending returns up to limit unpublished messages stored before
+	// before, oldest first
+	ListPending(ctx context.Context, before time.Time, limit int) ([]models.OutboxMessage, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+}
+
+type outboxRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) OutboxRepository {
+	return &outboxRepo{db: db}
+}
+
+func (r *outboxRepo) Add(ctx context.Context, notification models.PushNotification, message json.RawMessage) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO push_notifications (id, user_id, title, body, data, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = tx.Exec(ctx, query, notification.ID, notification.UserID, notification.Title, notification.Body,
+		notification.Data, notification.Status, notification.CreatedAt)
+	if err != nil {
+		zap.L().Error("Failed to create notification record", zap.String("notification_id", notification.ID), zap.Error(err))
+		return 0, err
+	}
+
+	var id int64
+	query = `
+		INSERT INTO push_outbox (message)
+		VALUES ($1)
+		RETURNING id
+	`
+	if err := tx.QueryRow(ctx, query, message).Scan(&id); err != nil {
+		zap.L().Error("Failed to add message to the outbox", zap.String("notification_id", notification.ID), zap.Error(err))
+		return 0, err
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+func (r *outboxRepo) ListPending(ctx context.Context, before time.Time, limit int) ([]models.OutboxMessage, error) {
+	query := `
+		SELECT id, message, created_at
+		FROM push_outbox
+		WHERE published_at IS NULL AND created_at < $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, before, limit)
+	if err != nil {
+		zap.L().Error("Failed to list pending outbox messages", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var message models.OutboxMessage
+		if err := rows.Scan(&message.ID, &message.Message, &message.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+func (r *outboxRepo) MarkPublished(ctx context.Context, ids []int64) error {
+	query := `
+		UPDATE push_outbox
+		SET published_at = NOW()
+		WHERE id = ANY($1) AND published_at IS NULL
+	`
+
+	if _, err := r.db.Exec(ctx, query, ids); err != nil {
+		zap.L().Error("Failed to mark outbox messages published", zap.Int("count", len(ids)), zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// QuotaRepository tracks messages sent per FCM project per UTC day
+type QuotaRepository interface {
+	Used(ctx context.Context, projectID string, day time.Time) (int64, error)
+	Add(ctx context.Context, projectID string, day time.Time, n int64) error
+	Reserve(ctx context.Context, projectID string, day time.Time, want, limit int64) (int64, error)
+}
+
+type quotaRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewQuotaRepository(db *pgxpool.Pool) QuotaRepository {
+	return &quotaRepo{db: db}
+}
+
+func (r *quotaRepo) Used(ctx context.Context, projectID string, day time.Time) (int64, error) {
+	query := `SELECT sent FROM fcm_quota_usage WHERE project_id = $1 AND day = $2`
+
+	var sent int64
+	err := r.db.QueryRow(ctx, query, projectID, day).Scan(&sent)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, nil
+		}
+		zap.L().Error("Failed to get quota usage", zap.Error(err))
+		return 0, err
+	}
+
+	return sent, nil
+}
+
+// Add records n sends (or gives n back if negative) against the day's usage
+func (r *quotaRepo) Add(ctx context.Context, projectID string, day time.Time, n int64) error {
+	query := `
+		INSERT INTO fcm_quota_usage (project_id, day, sent)
+		VALUES ($1, $2, GREATEST($3, 0))
+		ON CONFLICT (project_id, day) DO UPDATE
+		SET sent = GREATEST(fcm_quota_usage.sent + $3, 0)
+	`
+
+	if _, err := r.db.Exec(ctx, query, projectID, day, n); err != nil {
+		zap.L().Error("Failed to record quota usage", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Reserve atomically claims up to want sends from the day's remaining quota
+// and returns how many were granted. A limit of 0 means unlimited.
+func (r *quotaRepo) Reserve(ctx context.Context, projectID string, day time.Time, want, limit int64) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO fcm_quota_usage (project_id, day, sent)
+		VALUES ($1, $2, 0)
+		ON CONFLICT (project_id, day) DO NOTHING
+	`, projectID, day)
+	if err != nil {
+		zap.L().Error("Failed to reserve quota", zap.Error(err))
+		return 0, err
+	}
+
+	var sent int64
+	err = tx.QueryRow(ctx, `
+		SELECT sent FROM fcm_quota_usage WHERE project_id = $1 AND day = $2 FOR UPDATE
+	`, projectID, day).Scan(&sent)
+	if err != nil {
+		zap.L().Error("Failed to reserve quota", zap.Error(err))
+		return 0, err
+	}
+
+	granted := want
+	if limit > 0 && sent+granted > limit {
+		granted = limit - sent
+	}
+	if granted <= 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE fcm_quota_usage SET sent = sent + $3 WHERE project_id = $1 AND day = $2
+	`, projectID, day, granted)
+	if err != nil {
+		zap.L().Error("Failed to reserve quota", zap.Error(err))
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return granted, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// retentionTables maps the tables the retention purge deletes from to the
+// column their rows' age is read from
+var retentionTables = map[string]string{
+	"push_notifications":     "created_at",
+	"inbox_items":            "created_at",
+	"delivery_attempts":      "attempted_at",
+	"delivery_token_results": "updated_at",
+	"push_dry_runs":          "created_at",   // results are deleted with their dry run
+	"push_outbox":            "published_at", // unpublished messages are kept
+	"frequency_counts":       "expires_at",
+}
+
+type RetentionRepository interface {
+	// Purge deletes up to limit rows of table older than before
+	Purge(ctx context.Context, table string, before time.Time, limit int) (int64, error)
+}
+
+type retentionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewRetentionRepository(db *pgxpool.Pool) RetentionRepository {
+	return &retentionRepo{db: db}
+}
+
+func (r *retentionRepo) Purge(ctx context.Context, table string, before time.Time, limit int) (int64, error) {
+	column, ok := retentionTables[table]
+	if !ok {
+		return 0, fmt.Errorf("no retention for table %q", table)
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE id IN (
+			SELECT id FROM %[1]s
+			WHERE %[2]s < $1
+			LIMIT $2
+		)
+	`, table, column)
+
+	result, err := r.db.Exec(ctx, query, before, limit)
+	if err != nil {
+		zap.L().Error("Failed to purge expired rows", zap.String("table", table), zap.Error(err))
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type ScheduledPushRepository interface {
+	Create(ctx context.Context, push *models.ScheduledPush) error
+	ClaimDue(ctx context.Context, limit int) ([]models.ScheduledPush, error)
+}
+
+type scheduledPushRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewScheduledPushRepository(db *pgxpool.Pool) ScheduledPushRepository {
+	return &scheduledPushRepo{db: db}
+}
+
+func (r *scheduledPushRepo) Create(ctx context.Context, push *models.ScheduledPush) error {
+	query := `
+		INSERT INTO scheduled_pushes (deliver_at, message)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, push.DeliverAt, push.Message).Scan(&push.ID, &push.CreatedAt)
+	if err != nil {
+		zap.L().Error("Failed to create scheduled push", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDue removes and returns up to limit pushes whose time has come,
+// earliest first. Rows claimed by another instance are skipped, so each
+// push is claimed once.
+func (r *scheduledPushRepo) ClaimDue(ctx context.Context, limit int) ([]models.ScheduledPush, error) {
+	query := `
+		DELETE FROM scheduled_pushes
+		WHERE id IN (
+			SELECT id FROM scheduled_pushes
+			WHERE deliver_at <= NOW()
+			ORDER BY deliver_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, deliver_at, message, created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		zap.L().Error("Failed to claim scheduled pushes", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pushes []models.ScheduledPush
+	for rows.Next() {
+		var push models.ScheduledPush
+		if err := rows.Scan(&push.ID, &push.DeliverAt, &push.Message, &push.CreatedAt); err != nil {
+			return nil, err
+		}
+		pushes = append(pushes, push)
+	}
+
+	return pushes, rows.Err()
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type TenantRepository interface {
+	GetByID(ctx context.Context, id string) (*models.Tenant, error)
+	Update(ctx context.Context, id string, req models.UpdateTenantRequest) (*models.Tenant, error)
+	SentOn(ctx context.Context, id string, day time.Time) (int64, error)
+	ReserveSends(ctx context.Context, id string, day time.Time, n, limit int64) (bool, error)
+}
+
+type tenantRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTenantRepository(db *pgxpool.Pool) TenantRepository {
+	return &tenantRepo{db: db}
+}
+
+func (r *tenantRepo) GetByID(ctx context.Context, id string) (*models.Tenant, error) {
+	query := `
+		SELECT id, soft_launch, daily_send_cap, test_user_ids, soft_launch_lifted_at, created_at, updated_at
+		FROM tenants
+		WHERE id = $1
+	`
+
+	var tenant models.Tenant
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&tenant.ID,
+		&tenant.SoftLaunch,
+		&tenant.DailySendCap,
+		&tenant.TestUserIDs,
+		&tenant.SoftLaunchLiftedAt,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		zap.L().Error("Failed to get tenant", zap.Error(err))
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+// Update applies req to the tenant, creating it in soft launch if it has no
+// record yet. Lifting soft launch records when it was lifted.
+func (r *tenantRepo) Update(ctx context.Context, id string, req models.UpdateTenantRequest) (*models.Tenant, error) {
+	query := `
+		INSERT INTO tenants (id, soft_launch, daily_send_cap, test_user_ids, soft_launch_lifted_at)
+		VALUES ($1, COALESCE($2, TRUE), $3, COALESCE($4, '{}'::text[]),
+			CASE WHEN $2 = FALSE THEN NOW() END)
+		ON CONFLICT (id) DO UPDATE SET
+			soft_launch = COALESCE($2, tenants.soft_launch),
+			daily_send_cap = COALESCE($3, tenants.daily_send_cap),
+			test_user_ids = COALESCE($4, tenants.test_user_ids),
+			soft_launch_lifted_at = CASE
+				WHEN $2 = FALSE AND tenants.soft_launch THEN NOW()
+				WHEN $2 = TRUE THEN NULL
+				ELSE tenants.soft_launch_lifted_at
+			END,
+			updated_at = NOW()
+		RETURNING id, soft_launch, daily_send_cap, test_user_ids, soft_launch_lifted_at, created_at, updated_at
+	`
+
+	var tenant models.Tenant
+	err := r.db.QueryRow(ctx, query, id, req.SoftLaunch, req.DailySendCap, req.TestUserIDs).Scan(
+		&tenant.ID,
+		&tenant.SoftLaunch,
+		&tenant.DailySendCap,
+		&tenant.TestUserIDs,
+		&tenant.SoftLaunchLiftedAt,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	if err != nil {
+		zap.L().Error("Failed to update tenant", zap.Error(err))
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+func (r *tenantRepo) SentOn(ctx context.Context, id string, day time.Time) (int64, error) {
+	query := `SELECT sent FROM tenant_daily_sends WHERE tenant_id = $1 AND day = $2`
+
+	var sent int64
+	err := r.db.QueryRow(ctx, query, id, day).Scan(&sent)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, nil
+		}
+		zap.L().Error("Failed to get tenant sends", zap.Error(err))
+		return 0, err
+	}
+
+	return sent, nil
+}
+
+// ReserveSends atomically counts n sends against the tenant's day if they
+// fit under limit, and reports whether they did
+func (r *tenantRepo) ReserveSends(ctx context.Context, id string, day time.Time, n, limit int64) (bool, error) {
+	if n > limit {
+		return false, nil
+	}
+
+	query := `
+		INSERT INTO tenant_daily_sends (tenant_id, day, sent)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, day) DO UPDATE
+		SET sent = tenant_daily_sends.sent + EXCLUDED.sent
+		WHERE tenant_daily_sends.sent + EXCLUDED.sent <= $4
+		RETURNING sent
+	`
+
+	var sent int64
+	err := r.db.QueryRow(ctx, query, id, day, n, limit).Scan(&sent)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		zap.L().Error("Failed to reserve tenant sends", zap.Error(err))
+		return false, err
+	}
+
+	return true, nil
+}
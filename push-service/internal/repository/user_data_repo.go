@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type UserDataRepository interface {
+	// Erase deletes everything stored about userID in one transaction and
+	// records the erasure under userHash
+	Erase(ctx context.Context, userID, userHash string) (*models.UserDataDeletion, error)
+	// ListErasures returns when each user erased since was erased, by hash
+	ListErasures(ctx context.Context, since time.Time) (map[string]time.Time, error)
+	// PurgeErasures forgets the erasures older than before
+	PurgeErasures(ctx context.Context, before time.Time) (int64, error)
+}
+
+type userDataRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewUserDataRepository(db *pgxpool.Pool) UserDataRepository {
+	return &userDataRepo{db: db}
+}
+
+func (r *userDataRepo) Erase(ctx context.Context, userID, userHash string) (*models.UserDataDeletion, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	deletion := &models.UserDataDeletion{UserID: userID}
+	statements := []struct {
+		query string
+		count *int64
+	}{
+		// Notifications first, their device_id would be cleared otherwise
+		{`DELETE FROM push_notifications WHERE user_id = $1`, &deletion.Notifications},
+		{`DELETE FROM devices WHERE user_id = $1`, &deletion.Devices},
+		{`DELETE FROM delivery_attempts WHERE user_id = $1`, &deletion.DeliveryAttempts},
+		{`DELETE FROM delivery_token_results WHERE user_id = $1`, &deletion.TokenResults},
+		{`DELETE FROM inbox_items WHERE user_id = $1`, &deletion.InboxItems},
+		{`DELETE FROM digest_items WHERE user_id = $1`, &deletion.DigestItems},
+		{`DELETE FROM user_mutes WHERE user_id = $1`, &deletion.Mutes},
+		{`DELETE FROM frequency_counts WHERE user_id = $1`, &deletion.FrequencyCounts},
+		{`DELETE FROM push_dry_run_results WHERE user_id = $1`, &deletion.DryRunResults},
+		{`DELETE FROM scheduled_pushes WHERE message->'notification'->>'user_id' = $1`, &deletion.ScheduledPushes},
+		{`DELETE FROM push_outbox WHERE message->'notification'->>'user_id' = $1`, &deletion.OutboxMessages},
+		// Archived objects are left to the bucket's lifecycle rules
+		{`DELETE FROM dead_letter_archive WHERE user_id = $1`, &deletion.DeadLetters},
+		// Campaigns track their progress by position in user_ids, so the user
+		// is blanked rather than removed
+		{`UPDATE campaigns SET user_ids = array_replace(user_ids, $1, ''), updated_at = NOW() WHERE $1 = ANY(user_ids)`, &deletion.Campaigns},
+		{`UPDATE tenants SET test_user_ids = array_remove(test_user_ids, $1), updated_at = NOW() WHERE $1 = ANY(test_user_ids)`, &deletion.Tenants},
+	}
+	for _, statement := range statements {
+		result, err := tx.Exec(ctx, statement.query, userID)
+		if err != nil {
+			zap.L().Error("Failed to delete user data", zap.String("query", statement.query), zap.Error(err))
+			return nil, err
+		}
+		*statement.count = result.RowsAffected()
+	}
+
+	query := `
+		INSERT INTO user_erasures (user_hash)
+		VALUES ($1)
+		ON CONFLICT (user_hash) DO UPDATE SET erased_at = NOW()
+		RETURNING erased_at
+	`
+	if err := tx.QueryRow(ctx, query, userHash).Scan(&deletion.ErasedAt); err != nil {
+		zap.L().Error("Failed to record user erasure", zap.Error(err))
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		zap.L().Error("Failed to commit user data deletion", zap.Error(err))
+		return nil, err
+	}
+	return deletion, nil
+}
+
+func (r *userDataRepo) ListErasures(ctx context.Context, since time.Time) (map[string]time.Time, error) {
+	rows, err := r.db.Query(ctx, `SELECT user_hash, erased_at FROM user_erasures WHERE erased_at >= $1`, since)
+	if err != nil {
+		zap.L().Error("Failed to list user erasures", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	erasures := make(map[string]time.Time)
+	for rows.Next() {
+		var userHash string
+		var erasedAt time.Time
+		if err := rows.Scan(&userHash, &erasedAt); err != nil {
+			zap.L().Error("Failed to scan user erasure", zap.Error(err))
+			return nil, err
+		}
+		erasures[userHash] = erasedAt
+	}
+
+	return erasures, rows.Err()
+}
+
+func (r *userDataRepo) PurgeErasures(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM user_erasures WHERE erased_at < $1`, before)
+	if err != nil {
+		zap.L().Error("Failed to purge user erasures", zap.Error(err))
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
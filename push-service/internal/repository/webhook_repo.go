@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	ListByTenant(ctx context.Context, tenantID string) ([]models.Webhook, error)
+	Delete(ctx context.Context, tenantID, id string) error
+}
+
+type webhookRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) WebhookRepository {
+	return &webhookRepo{db: db}
+}
+
+func (r *webhookRepo) Create(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (tenant_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		webhook.TenantID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+
+	if err != nil {
+		zap.L().Error("Failed to create webhook", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ListByTenant returns the tenant's webhooks with their secrets, oldest first
+func (r *webhookRepo) ListByTenant(ctx context.Context, tenantID string) ([]models.Webhook, error) {
+	query := `
+		SELECT id, tenant_id, url, secret, events, created_at
+		FROM webhooks
+		WHERE tenant_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		zap.L().Error("Failed to list webhooks", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		err := rows.Scan(
+			&webhook.ID,
+			&webhook.TenantID,
+			&webhook.URL,
+			&webhook.Secret,
+			&webhook.Events,
+			&webhook.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (r *webhookRepo) Delete(ctx context.Context, tenantID, id string) error {
+	query := `DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2`
+
+	result, err := r.db.Exec(ctx, query, id, tenantID)
+	if err != nil {
+		zap.L().Error("Failed to delete webhook", zap.Error(err))
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
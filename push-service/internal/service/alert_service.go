@@ -0,0 +1,716 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/internal/repository"
+	"push-service/pkg/lock"
+	"push-service/pkg/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrAlertRuleNotFound is returned for unknown API rules
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	// ErrAlertRuleExists is returned when a rule with the name exists
+	ErrAlertRuleExists = errors.New("alert rule already exists")
+	// ErrInvalidAlertRule wraps the reason a rule was rejected
+	ErrInvalidAlertRule = errors.New("invalid alert rule")
+)
+
+// Counters stored for the alert rules
+const (
+	alertCounterDelivered  = "delivered"
+	alertCounterFailed     = "failed"
+	alertCounterFCMErrors  = "fcm_errors"
+	alertCounterRetried    = "retried"
+	alertGaugeDeadLetters  = "dead_letter_depth"
+	alertLockKey           = "alerting"
+	alertBucketGranularity = time.Minute
+)
+
+// alertFilterLabels are the delivery labels rules may filter on
+var alertFilterLabels = map[string]bool{"platform": true, "project": true, "category": true}
+
+// AlertService evaluates alert rules on delivery outcomes and the dead
+// letter queue. Workers count outcomes in memory and add them to per-minute
+// buckets in Postgres, so rules see every instance; one instance at a time,
+// under a lock, evaluates the rules and notifies their webhook, Slack and
+// email.
+type AlertService interface {
+	ListRules(ctx context.Context) ([]models.AlertRule, error)
+	CreateRule(ctx context.Context, req models.CreateAlertRuleRequest) (*models.AlertRule, error)
+	DeleteRule(ctx context.Context, name string) error
+	// RecordDeliveries counts delivery outcomes with the given labels
+	RecordDeliveries(labels map[string]string, outcomes AlertOutcomes)
+	// RunFlusher stores the delivery counts this instance recorded; every
+	// instance that sends runs it
+	RunFlusher(ctx context.Context)
+	// Run evaluates the rules; one instance at a time does
+	Run(ctx context.Context)
+}
+
+// AlertOutcomes are delivery outcomes counted for the alert rules
+type AlertOutcomes struct {
+	Delivered int
+	Failed    int
+	// FCMErrors are the failures on FCM's side (unavailable, internal,
+	// quota and unknown errors), not caused by the token or the message
+	FCMErrors int
+	// Retried are the deliveries put back on a retry queue
+	Retried int
+}
+
+type alertService struct {
+	alertRepo repository.AlertRepository
+	pushQueue *queue.PushQueue
+	locker    lock.Locker
+	client    *http.Client
+	cfg       *config.Config
+
+	mu      sync.Mutex
+	pending map[string]*pendingDeliveries
+}
+
+// pendingDeliveries are outcomes not yet added to the current bucket
+type pendingDeliveries struct {
+	labels    map[string]string
+	delivered int64
+	failed    int64
+	fcmErrors int64
+	retried   int64
+}
+
+func NewAlertService(alertRepo repository.AlertRepository, pushQueue *queue.PushQueue, locker lock.Locker, cfg *config.Config) AlertService {
+	timeout := cfg.Alerting.WebhookTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second // default
+	}
+
+	return &alertService{
+		alertRepo: alertRepo,
+		pushQueue: pushQueue,
+		locker:    locker,
+		client:    &http.Client{Timeout: timeout},
+		cfg:       cfg,
+		pending:   make(map[string]*pendingDeliveries),
+	}
+}
+
+// ListRules returns the built-in rules that are on, the config rules and the
+// API rules, with the outcome of their last evaluation
+func (s *alertService) ListRules(ctx context.Context) ([]models.AlertRule, error) {
+	rules, err := s.rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := s.alertRepo.GetStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if state, ok := states[rules[i].Name]; ok {
+			rules[i].State = &state
+		}
+	}
+	return rules, nil
+}
+
+func (s *alertService) CreateRule(ctx context.Context, req models.CreateAlertRuleRequest) (*models.AlertRule, error) {
+	rule := &models.AlertRule{
+		Name:       req.Name,
+		Metric:     req.Metric,
+		Filter:     req.Filter,
+		Operator:   req.Operator,
+		Threshold:  req.Threshold,
+		Window:     req.Window,
+		MinEvents:  req.MinEvents,
+		WebhookURL: req.WebhookURL,
+		Source:     models.AlertRuleSourceAPI,
+	}
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+	for _, configRule := range append(s.cfg.Alerting.Builtin.Rules(), s.cfg.Alerting.Rules...) {
+		if configRule.Name == rule.Name {
+			return nil, ErrAlertRuleExists
+		}
+	}
+
+	if err := s.alertRepo.CreateRule(ctx, rule); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAlertRuleExists
+		}
+		return nil, err
+	}
+
+	zap.L().Info("Alert rule created", zap.String("rule", rule.Name))
+	return rule, nil
+}
+
+func (s *alertService) validateRule(rule *models.AlertRule) error {
+	window, err := time.ParseDuration(rule.Window)
+	if err != nil {
+		return fmt.Errorf("%w: invalid window: %v", ErrInvalidAlertRule, err)
+	}
+	if window <= 0 || window > s.retention() {
+		return fmt.Errorf("%w: window must be positive and at most the %s retention", ErrInvalidAlertRule, s.retention())
+	}
+	if (rule.Metric == models.AlertMetricDeadLetters || rule.Metric == models.AlertMetricDeadLetterDepth) && len(rule.Filter) > 0 {
+		return fmt.Errorf("%w: %s rules take no filter", ErrInvalidAlertRule, rule.Metric)
+	}
+	for label := range rule.Filter {
+		if !alertFilterLabels[label] {
+			return fmt.Errorf("%w: unknown filter label %q", ErrInvalidAlertRule, label)
+		}
+	}
+	if rule.WebhookURL == nil && s.cfg.Alerting.WebhookURL == "" && s.cfg.Alerting.SlackWebhookURL == "" && !s.cfg.Alerting.Email.Enabled() {
+		return fmt.Errorf("%w: webhook_url is required when no default webhook, Slack or email is configured", ErrInvalidAlertRule)
+	}
+	return nil
+}
+
+// DeleteRule deletes an API rule; config rules can only be removed from the
+// config file
+func (s *alertService) DeleteRule(ctx context.Context, name string) error {
+	if err := s.alertRepo.DeleteRule(ctx, name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAlertRuleNotFound
+		}
+		return err
+	}
+
+	zap.L().Info("Alert rule deleted", zap.String("rule", name))
+	return nil
+}
+
+// rules returns the built-in rules that are on, then the config rules, then
+// the API rules
+func (s *alertService) rules(ctx context.Context) ([]models.AlertRule, error) {
+	apiRules, err := s.alertRepo.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	builtins := s.cfg.Alerting.Builtin.Rules()
+	rules := make([]models.AlertRule, 0, len(builtins)+len(s.cfg.Alerting.Rules)+len(apiRules))
+	for _, builtin := range builtins {
+		rules = append(rules, configRule(builtin, models.AlertRuleSourceBuiltin))
+	}
+	for _, rule := range s.cfg.Alerting.Rules {
+		rules = append(rules, configRule(rule, models.AlertRuleSourceConfig))
+	}
+	return append(rules, apiRules...), nil
+}
+
+// configRule converts a rule from the config
+func configRule(cfg config.AlertRuleConfig, source string) models.AlertRule {
+	rule := models.AlertRule{
+		Name:      cfg.Name,
+		Metric:    cfg.Metric,
+		Filter:    cfg.Filter,
+		Operator:  cfg.Operator,
+		Threshold: cfg.Threshold,
+		Window:    cfg.Window.String(),
+		MinEvents: cfg.MinEvents,
+		Source:    source,
+	}
+	if cfg.WebhookURL != "" {
+		rule.WebhookURL = &cfg.WebhookURL
+	}
+	return rule
+}
+
+func (s *alertService) RecordDeliveries(labels map[string]string, outcomes AlertOutcomes) {
+	if !s.cfg.Alerting.Enabled || outcomes == (AlertOutcomes{}) {
+		return
+	}
+
+	key := labelsKey(labels)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending, ok := s.pending[key]
+	if !ok {
+		pending = &pendingDeliveries{labels: labels}
+		s.pending[key] = pending
+	}
+	pending.delivered += int64(outcomes.Delivered)
+	pending.failed += int64(outcomes.Failed)
+	pending.fcmErrors += int64(outcomes.FCMErrors)
+	pending.retried += int64(outcomes.Retried)
+}
+
+// labelsKey encodes labels in key order
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(labels[key])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// RunFlusher stores recorded delivery counts every flush interval until ctx
+// is cancelled. It returns at once if alerting is disabled.
+func (s *alertService) RunFlusher(ctx context.Context) {
+	if !s.cfg.Alerting.Enabled {
+		return
+	}
+
+	flushInterval := s.cfg.Alerting.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = 10 * time.Second // default
+	}
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Don't lose the last counts on shutdown
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.flush(flushCtx)
+			cancel()
+			return
+		case <-flushTicker.C:
+			s.flush(ctx)
+		}
+	}
+}
+
+// Run evaluates the rules every evaluation interval until ctx is cancelled.
+// It returns at once if alerting is disabled.
+func (s *alertService) Run(ctx context.Context) {
+	if !s.cfg.Alerting.Enabled {
+		return
+	}
+
+	evaluationInterval := s.cfg.Alerting.EvaluationInterval
+	if evaluationInterval <= 0 {
+		evaluationInterval = time.Minute // default
+	}
+	evaluationTicker := time.NewTicker(evaluationInterval)
+	defer evaluationTicker.Stop()
+
+	zap.L().Info("Alert rules engine started",
+		zap.Duration("evaluation_interval", evaluationInterval),
+		zap.Int("config_rules", len(s.cfg.Alerting.Rules)),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-evaluationTicker.C:
+			err := lock.Run(ctx, s.locker, alertLockKey, 0, s.evaluate)
+			if err != nil && !errors.Is(err, lock.ErrNotAcquired) {
+				zap.L().Error("Failed to evaluate alert rules", zap.Error(err))
+			}
+		}
+	}
+}
+
+// flush adds the recorded delivery counts to the current bucket. Counts that
+// fail to store are kept for the next flush.
+func (s *alertService) flush(ctx context.Context) {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]*pendingDeliveries)
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	counts := make([]repository.AlertCount, 0, 2*len(pending))
+	for _, p := range pending {
+		for _, c := range []struct {
+			metric string
+			value  int64
+		}{
+			{alertCounterDelivered, p.delivered},
+			{alertCounterFailed, p.failed},
+			{alertCounterFCMErrors, p.fcmErrors},
+			{alertCounterRetried, p.retried},
+		} {
+			if c.value > 0 {
+				counts = append(counts, repository.AlertCount{Metric: c.metric, Labels: p.labels, Value: c.value})
+			}
+		}
+	}
+
+	if err := s.alertRepo.AddCounts(ctx, time.Now().UTC().Truncate(alertBucketGranularity), counts); err != nil {
+		zap.L().Warn("Failed to store delivery counts for alerting, keeping them for the next flush", zap.Error(err))
+		s.mu.Lock()
+		for key, p := range pending {
+			if current, ok := s.pending[key]; ok {
+				current.delivered += p.delivered
+				current.failed += p.failed
+				current.fcmErrors += p.fcmErrors
+				current.retried += p.retried
+			} else {
+				s.pending[key] = p
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// evaluate samples the dead letter queue, evaluates every rule and notifies
+// the rules that started firing or resolved
+func (s *alertService) evaluate(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	depth, err := s.pushQueue.Broker().Stats(ctx, queue.DeadLetterQueue)
+	if err != nil {
+		return fmt.Errorf("failed to get dead letter queue depth: %w", err)
+	}
+	if err := s.alertRepo.SetGauge(ctx, now.Truncate(alertBucketGranularity), alertGaugeDeadLetters, depth); err != nil {
+		return err
+	}
+
+	rules, err := s.rules(ctx)
+	if err != nil {
+		return err
+	}
+	states, err := s.alertRepo.GetStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		value, breached, ok, err := s.evaluateRule(ctx, rule, now, depth)
+		if err != nil {
+			zap.L().Error("Failed to evaluate alert rule", zap.String("rule", rule.Name), zap.Error(err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		previous := states[rule.Name]
+		state := models.AlertState{Firing: breached, Value: value, EvaluatedAt: now}
+		if breached {
+			state.Since = previous.Since
+			if !previous.Firing {
+				state.Since = &now
+			}
+		}
+
+		if state.Firing != previous.Firing {
+			status, since := models.AlertStatusResolved, previous.Since
+			if state.Firing {
+				status, since = models.AlertStatusFiring, state.Since
+			}
+			if err := s.notify(ctx, rule, status, value, since, now); err != nil {
+				// Leave the state as it was, so the next evaluation retries
+				zap.L().Error("Failed to send alert notification",
+					zap.String("rule", rule.Name),
+					zap.String("status", status),
+					zap.Error(err),
+				)
+				continue
+			}
+			zap.L().Warn("Alert rule "+status,
+				zap.String("rule", rule.Name),
+				zap.Float64("value", value),
+				zap.Float64("threshold", rule.Threshold),
+			)
+		}
+
+		// Failures are logged by the repository and retried next evaluation
+		s.alertRepo.SaveState(ctx, rule.Name, state)
+	}
+
+	if _, err := s.alertRepo.DeleteCountsBefore(ctx, now.Add(-s.retention())); err != nil {
+		return err
+	}
+	return nil
+}
+
+// evaluateRule returns the rule's current value and whether it breaches the
+// threshold. ok is false while there is no data to evaluate the rule on.
+func (s *alertService) evaluateRule(ctx context.Context, rule models.AlertRule, now time.Time, deadLetterDepth int64) (value float64, breached, ok bool, err error) {
+	window, err := time.ParseDuration(rule.Window)
+	if err != nil {
+		return 0, false, false, fmt.Errorf("invalid window %q: %w", rule.Window, err)
+	}
+	since := now.Add(-window).Truncate(alertBucketGranularity)
+
+	switch rule.Metric {
+	case models.AlertMetricFailureRate, models.AlertMetricRetryRate, models.AlertMetricFCMErrorRate:
+		counter := map[string]string{
+			models.AlertMetricFailureRate:  alertCounterFailed,
+			models.AlertMetricRetryRate:    alertCounterRetried,
+			models.AlertMetricFCMErrorRate: alertCounterFCMErrors,
+		}[rule.Metric]
+		counters := []string{alertCounterDelivered, alertCounterFailed}
+		if counter != alertCounterFailed {
+			counters = append(counters, counter)
+		}
+		sums, err := s.alertRepo.Sum(ctx, counters, rule.Filter, since)
+		if err != nil {
+			return 0, false, false, err
+		}
+		total := sums[alertCounterDelivered] + sums[alertCounterFailed]
+		if total == 0 {
+			return 0, false, true, nil
+		}
+		value = float64(sums[counter]) / float64(total)
+		return value, total >= rule.MinEvents && compare(value, rule.Operator, rule.Threshold), true, nil
+	case models.AlertMetricFailures:
+		sums, err := s.alertRepo.Sum(ctx, []string{alertCounterFailed}, rule.Filter, since)
+		if err != nil {
+			return 0, false, false, err
+		}
+		value = float64(sums[alertCounterFailed])
+		return value, compare(value, rule.Operator, rule.Threshold), true, nil
+	case models.AlertMetricDeadLetters:
+		first, found, err := s.alertRepo.FirstGauge(ctx, alertGaugeDeadLetters, since)
+		if err != nil || !found {
+			return 0, false, false, err
+		}
+		value = float64(deadLetterDepth - first)
+		return value, compare(value, rule.Operator, rule.Threshold), true, nil
+	case models.AlertMetricDeadLetterDepth:
+		value = float64(deadLetterDepth)
+		return value, compare(value, rule.Operator, rule.Threshold), true, nil
+	default:
+		return 0, false, false, fmt.Errorf("unknown metric %q", rule.Metric)
+	}
+}
+
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	default:
+		return false
+	}
+}
+
+// notify sends an AlertNotification to the rule's webhook, or the default
+// one, and to Slack and email if configured. It fails only if every channel
+// failed, so one channel being down doesn't hold back or repeat the others.
+func (s *alertService) notify(ctx context.Context, rule models.AlertRule, status string, value float64, since *time.Time, now time.Time) error {
+	notification := models.AlertNotification{
+		Rule:      rule.Name,
+		Status:    status,
+		Metric:    rule.Metric,
+		Filter:    rule.Filter,
+		Operator:  rule.Operator,
+		Threshold: rule.Threshold,
+		Window:    rule.Window,
+		Value:     value,
+		Since:     since,
+		At:        now,
+	}
+
+	url := s.cfg.Alerting.WebhookURL
+	if rule.WebhookURL != nil && *rule.WebhookURL != "" {
+		url = *rule.WebhookURL
+	}
+	channels := make(map[string]func() error)
+	if url != "" {
+		channels["webhook"] = func() error { return s.sendWebhook(ctx, url, notification) }
+	}
+	if s.cfg.Alerting.SlackWebhookURL != "" {
+		channels["slack"] = func() error { return s.sendSlack(ctx, notification) }
+	}
+	if s.cfg.Alerting.Email.Enabled() {
+		channels["email"] = func() error { return s.sendEmail(notification) }
+	}
+	if len(channels) == 0 {
+		return fmt.Errorf("no webhook, Slack or email configured")
+	}
+
+	var errs []error
+	for channel, send := range channels {
+		if err := send(); err != nil {
+			metrics.AlertNotifications.WithLabelValues(channel, "failed").Inc()
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+			continue
+		}
+		metrics.AlertNotifications.WithLabelValues(channel, "sent").Inc()
+	}
+	if len(errs) == len(channels) {
+		return errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		zap.L().Warn("Failed to send alert notification to some channels",
+			zap.String("rule", rule.Name),
+			zap.String("status", status),
+			zap.Error(errors.Join(errs...)),
+		)
+	}
+	return nil
+}
+
+// sendWebhook POSTs the notification as JSON to url
+func (s *alertService) sendWebhook(ctx context.Context, url string, notification models.AlertNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	return s.post(ctx, url, body)
+}
+
+// post POSTs a JSON body to url and fails on a non-2xx response
+func (s *alertService) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// alertText describes a notification in one line, e.g. "[FIRING]
+// ios_failure_rate: failure_rate is 0.27 (> 0.2 over 10m, platform=ios)"
+func alertText(n models.AlertNotification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s: %s is %s (%s %s over %s",
+		strings.ToUpper(n.Status), n.Rule, n.Metric,
+		strconv.FormatFloat(n.Value, 'g', 4, 64), n.Operator,
+		strconv.FormatFloat(n.Threshold, 'g', 4, 64), n.Window,
+	)
+	labels := make([]string, 0, len(n.Filter))
+	for label, value := range n.Filter {
+		labels = append(labels, label+"="+value)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		b.WriteString(", ")
+		b.WriteString(label)
+	}
+	b.WriteByte(')')
+	if n.Since != nil {
+		if n.Status == models.AlertStatusResolved {
+			fmt.Fprintf(&b, ", fired for %s", n.At.Sub(*n.Since).Round(time.Second))
+		} else {
+			fmt.Fprintf(&b, ", since %s", n.Since.Format(time.RFC3339))
+		}
+	}
+	return b.String()
+}
+
+// sendSlack posts the notification to the Slack incoming webhook
+func (s *alertService) sendSlack(ctx context.Context, n models.AlertNotification) error {
+	body, err := json.Marshal(map[string]string{"text": alertText(n)})
+	if err != nil {
+		return err
+	}
+	return s.post(ctx, s.cfg.Alerting.SlackWebhookURL, body)
+}
+
+// sendEmail mails the notification to the configured recipients, upgrading
+// to TLS when the server offers STARTTLS. The whole exchange is bounded by
+// the webhook timeout, so a stuck server doesn't hold up the evaluation.
+func (s *alertService) sendEmail(n models.AlertNotification) error {
+	cfg := s.cfg.Alerting.Email
+	text := alertText(n)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", text)
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.At.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(text + "\r\n")
+
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+	conn, err := net.DialTimeout("tcp", addr, s.client.Timeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.client.Timeout))
+	client, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: cfg.SMTPHost}); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (s *alertService) retention() time.Duration {
+	if s.cfg.Alerting.Retention <= 0 {
+		return 24 * time.Hour // default
+	}
+	return s.cfg.Alerting.Retention
+}
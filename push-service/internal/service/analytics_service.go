@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"push-service/internal/models"
+	"push-service/internal/repository"
+	"push-service/pkg/eventsink"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidDateRange is returned for analytics queries with unusable dates
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+const (
+	analyticsDateLayout = "2006-01-02"
+	// analyticsMaxDays is the longest range a summary covers
+	analyticsMaxDays = 366
+	// analyticsDefaultDays is the range of a summary without dates, ending today
+	analyticsDefaultDays = 7
+)
+
+type AnalyticsService interface {
+	GetSummary(ctx context.Context, from, to string) (*models.AnalyticsSummary, error)
+	GetCampaignVariants(ctx context.Context, campaignID string) (*models.CampaignVariantReport, error)
+	RecordOpen(ctx context.Context, req models.RecordOpenRequest) error
+}
+
+type analyticsService struct {
+	analyticsRepo repository.AnalyticsRepository
+	campaignRepo  repository.CampaignRepository
+	events        *eventsink.Sink // nil if no event sink is configured
+}
+
+func NewAnalyticsService(analyticsRepo repository.AnalyticsRepository, campaignRepo repository.CampaignRepository, events *eventsink.Sink) AnalyticsService {
+	return &analyticsService{
+		analyticsRepo: analyticsRepo,
+		campaignRepo:  campaignRepo,
+		events:        events,
+	}
+}
+
+// GetSummary sums the delivery rollups from from to to, inclusive. Both are
+// UTC dates (YYYY-MM-DD); to defaults to today and from to a week before it.
+func (s *analyticsService) GetSummary(ctx context.Context, from, to string) (*models.AnalyticsSummary, error) {
+	toDay := quotaDay(time.Now())
+	if to != "" {
+		day, err := time.Parse(analyticsDateLayout, to)
+		if err != nil {
+			return nil, fmt.Errorf("%w: to must be a date like 2026-10-15, got %q", ErrInvalidDateRange, to)
+		}
+		toDay = day
+	}
+	fromDay := toDay.AddDate(0, 0, -(analyticsDefaultDays - 1))
+	if from != "" {
+		day, err := time.Parse(analyticsDateLayout, from)
+		if err != nil {
+			return nil, fmt.Errorf("%w: from must be a date like 2026-10-09, got %q", ErrInvalidDateRange, from)
+		}
+		fromDay = day
+	}
+	if fromDay.After(toDay) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidDateRange)
+	}
+	if toDay.Sub(fromDay) >= analyticsMaxDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: at most %d days", ErrInvalidDateRange, analyticsMaxDays)
+	}
+
+	rollups, err := s.analyticsRepo.List(ctx, fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.AnalyticsSummary{
+		From:       fromDay.Format(analyticsDateLayout),
+		To:         toDay.Format(analyticsDateLayout),
+		ByPlatform: make(map[string]models.DeliveryCounts),
+		Days:       rollups,
+	}
+	for _, rollup := range rollups {
+		summary.Totals = addCounts(summary.Totals, rollup.DeliveryCounts)
+		summary.ByPlatform[rollup.Platform] = addCounts(summary.ByPlatform[rollup.Platform], rollup.DeliveryCounts)
+	}
+	return summary, nil
+}
+
+// GetCampaignVariants returns the delivery counts and open rate of each of
+// a campaign's variants, in the campaign's order. Campaigns without variants
+// have none.
+func (s *analyticsService) GetCampaignVariants(ctx context.Context, campaignID string) (*models.CampaignVariantReport, error) {
+	campaign, err := s.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+
+	rollups, err := s.analyticsRepo.ListVariants(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]models.DeliveryCounts, len(rollups))
+	for _, rollup := range rollups {
+		counts[rollup.Variant] = rollup.DeliveryCounts
+	}
+
+	report := &models.CampaignVariantReport{
+		CampaignID: campaignID,
+		Variants:   make([]models.VariantRollup, len(campaign.Variants)),
+	}
+	for i, variant := range campaign.Variants {
+		rollup := models.VariantRollup{Variant: variant.Name, DeliveryCounts: counts[variant.Name]}
+		if rollup.Delivered > 0 {
+			rollup.OpenRate = float64(rollup.Opened) / float64(rollup.Delivered)
+		}
+		report.Variants[i] = rollup
+	}
+	return report, nil
+}
+
+// RecordOpen counts a notification opened on the device towards today's
+// rollup of its platform and, for a campaign variant, the variant's rollup
+func (s *analyticsService) RecordOpen(ctx context.Context, req models.RecordOpenRequest) error {
+	platform := req.Platform
+	if platform == "" {
+		platform = models.PlatformUnknown
+	}
+	if err := s.analyticsRepo.Add(ctx, quotaDay(time.Now()), platform, models.DeliveryCounts{Opened: 1}); err != nil {
+		return err
+	}
+	if req.Variant != "" {
+		if err := s.analyticsRepo.AddVariant(ctx, req.CampaignID, req.Variant, models.DeliveryCounts{Opened: 1}); err != nil {
+			return err
+		}
+	}
+
+	s.events.Record(eventsink.Event{
+		ID:             uuid.NewString(),
+		Type:           eventsink.EventOpened,
+		OccurredAt:     time.Now().UTC(),
+		NotificationID: req.NotificationID,
+		Platform:       platform,
+		CampaignID:     req.CampaignID,
+		Variant:        req.Variant,
+	})
+
+	zap.L().Debug("Notification open recorded",
+		zap.String("notification_id", req.NotificationID),
+		zap.String("platform", platform),
+	)
+	return nil
+}
+
+func addCounts(a, b models.DeliveryCounts) models.DeliveryCounts {
+	return models.DeliveryCounts{
+		Sent:      a.Sent + b.Sent,
+		Delivered: a.Delivered + b.Delivered,
+		Failed:    a.Failed + b.Failed,
+		Opened:    a.Opened + b.Opened,
+	}
+}
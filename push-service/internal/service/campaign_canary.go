@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"push-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidCanary is returned for campaigns with an unusable canary
+	ErrInvalidCanary = errors.New("invalid canary")
+	// ErrCampaignNotSoaking is returned when continuing a campaign that isn't
+	// waiting after its canary
+	ErrCampaignNotSoaking = errors.New("campaign is not soaking")
+)
+
+// maxCanarySoak bounds how long a campaign waits after its canary
+const maxCanarySoak = 7 * 24 * time.Hour
+
+// planCanary sets up the campaign's canary stage: the audience is shuffled,
+// so the canary is a random sample, and the first canary users of it are
+// sent to first
+func planCanary(campaign *models.Campaign, canary *models.CanaryRequest) error {
+	if canary == nil {
+		return nil
+	}
+
+	if canary.Soak != "" {
+		soak, err := time.ParseDuration(canary.Soak)
+		if err != nil || soak <= 0 || soak > maxCanarySoak {
+			return fmt.Errorf("%w: soak must be a positive duration up to %s, got %q", ErrInvalidCanary, maxCanarySoak, canary.Soak)
+		}
+		seconds := int(soak.Seconds())
+		campaign.CanarySoakSeconds = &seconds
+	}
+
+	users := (len(campaign.UserIDs)*canary.Percent + 99) / 100
+	if users >= len(campaign.UserIDs) {
+		return fmt.Errorf("%w: %d%% of %d users leaves nobody for after the canary", ErrInvalidCanary, canary.Percent, len(campaign.UserIDs))
+	}
+
+	percent := canary.Percent
+	campaign.CanaryPercent = &percent
+	campaign.CanaryUsers = users
+
+	userIDs := append([]string{}, campaign.UserIDs...)
+	rand.Shuffle(len(userIDs), func(i, j int) { userIDs[i], userIDs[j] = userIDs[j], userIDs[i] })
+	campaign.UserIDs = userIDs
+	return nil
+}
+
+// soakAfterCanary puts a campaign that has just enqueued its last canary
+// user to soak, and reports whether it did
+func soakAfterCanary(campaign *models.Campaign) bool {
+	if campaign.CanaryUsers == 0 || campaign.CanaryCompletedAt != nil || campaign.ProcessedUsers < campaign.CanaryUsers {
+		return false
+	}
+
+	now := time.Now().UTC()
+	campaign.CanaryCompletedAt = &now
+	campaign.Status = models.CampaignStatusSoaking
+	if campaign.CanarySoakSeconds != nil {
+		continueAt := now.Add(time.Duration(*campaign.CanarySoakSeconds) * time.Second)
+		campaign.ContinueAt = &continueAt
+	}
+
+	zap.L().Info("Campaign canary enqueued, soaking",
+		zap.String("campaign_id", campaign.ID),
+		zap.Int("canary_users", campaign.CanaryUsers),
+		zap.Timep("continue_at", campaign.ContinueAt),
+	)
+	return true
+}
+
+// ContinueCampaign sends a soaking campaign to the rest of its audience
+// without waiting for the soak to pass
+func (s *campaignService) ContinueCampaign(ctx context.Context, id string) error {
+	if err := s.campaignRepo.Continue(ctx, id); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		campaign, err := s.campaignRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if campaign == nil {
+			return ErrCampaignNotFound
+		}
+		return ErrCampaignNotSoaking
+	}
+
+	zap.L().Info("Campaign continued after canary", zap.String("campaign_id", id))
+	return nil
+}
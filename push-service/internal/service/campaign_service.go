@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/queue"
+	"push-service/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// ErrCampaignNotFound is returned for unknown (or no longer running) campaigns
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+// campaignLease bounds how long one worker may hold a campaign per tick
+const campaignLease = 5 * time.Minute
+
+type CampaignService interface {
+	CreateCampaign(ctx context.Context, req models.CreateCampaignRequest) (*models.Campaign, error)
+	GetCampaign(ctx context.Context, id string) (*models.Campaign, error)
+	CancelCampaign(ctx context.Context, id string) error
+	ContinueCampaign(ctx context.Context, id string) error
+	Run(ctx context.Context)
+}
+
+type campaignService struct {
+	campaignRepo repository.CampaignRepository
+	quotaRepo    repository.QuotaRepository
+	tenantRepo   repository.TenantRepository
+	pushService  PushService
+	pushQueue    *queue.PushQueue
+	projects     *fcm.Projects
+	cfg          *config.Config
+}
+
+func NewCampaignService(campaignRepo repository.CampaignRepository, quotaRepo repository.QuotaRepository, tenantRepo repository.TenantRepository, pushService PushService, pushQueue *queue.PushQueue, projects *fcm.Projects, cfg *config.Config) CampaignService {
+	return &campaignService{
+		campaignRepo: campaignRepo,
+		quotaRepo:    quotaRepo,
+		tenantRepo:   tenantRepo,
+		pushService:  pushService,
+		pushQueue:    pushQueue,
+		projects:     projects,
+		cfg:          cfg,
+	}
+}
+
+func (s *campaignService) CreateCampaign(ctx context.Context, req models.CreateCampaignRequest) (*models.Campaign, error) {
+	tenant, err := softLaunchTenant(ctx, s.tenantRepo, s.cfg, req.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tenant != nil {
+		return nil, ErrSoftLaunchCampaign
+	}
+	if err := validateVariants(req.Variants); err != nil {
+		return nil, err
+	}
+	if len(req.Variants) > 0 {
+		// Shown as the campaign's title and body; users get their variant's
+		req.Title = req.Variants[0].Title
+		req.Body = req.Variants[0].Body
+	}
+
+	policy := req.QuotaPolicy
+	if policy == "" {
+		policy = s.cfg.Campaign.QuotaPolicy
+	}
+	if policy == "" {
+		policy = config.QuotaPolicySpread // default
+	}
+
+	campaign := &models.Campaign{
+		Name:           req.Name,
+		Title:          req.Title,
+		Body:           req.Body,
+		Image:          req.Image,
+		Link:           req.Link,
+		Data:           req.Data,
+		Sender:         req.Sender,
+		Category:       req.Category,
+		AnalyticsLabel: req.AnalyticsLabel,
+		Variants:       req.Variants,
+		UserIDs:        req.UserIDs,
+		TotalUsers:     len(req.UserIDs),
+		QuotaPolicy:    policy,
+		Status:         models.CampaignStatusRunning,
+	}
+	if err := planCanary(campaign, req.Canary); err != nil {
+		return nil, err
+	}
+	campaign.ProjectedCompletionAt = s.projectCompletion(ctx, campaign)
+
+	if err := s.campaignRepo.Create(ctx, campaign); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("Campaign launched",
+		zap.String("campaign_id", campaign.ID),
+		zap.String("name", campaign.Name),
+		zap.Int("total_users", campaign.TotalUsers),
+		zap.String("quota_policy", campaign.QuotaPolicy),
+		zap.Int("canary_users", campaign.CanaryUsers),
+		zap.Timep("projected_completion_at", campaign.ProjectedCompletionAt),
+	)
+
+	return campaign, nil
+}
+
+// GetCampaign returns the campaign with a projection refreshed against the
+// current quota usage.
+func (s *campaignService) GetCampaign(ctx context.Context, id string) (*models.Campaign, error) {
+	campaign, err := s.campaignRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+
+	if campaign.Status == models.CampaignStatusRunning || campaign.Status == models.CampaignStatusSoaking {
+		campaign.ProjectedCompletionAt = s.projectCompletion(ctx, campaign)
+	}
+	return campaign, nil
+}
+
+func (s *campaignService) CancelCampaign(ctx context.Context, id string) error {
+	if err := s.campaignRepo.Cancel(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCampaignNotFound
+		}
+		return err
+	}
+
+	zap.L().Info("Campaign cancelled", zap.String("campaign_id", id))
+	return nil
+}
+
+// Run advances running campaigns every tick until ctx is cancelled
+func (s *campaignService) Run(ctx context.Context) {
+	interval := s.tickInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	zap.L().Info("Campaign scheduler started", zap.Duration("tick_interval", interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *campaignService) tick(ctx context.Context) {
+	campaigns, err := s.campaignRepo.ClaimRunning(ctx, campaignLease, s.batchSize())
+	if err != nil {
+		zap.L().Error("Failed to load running campaigns", zap.Error(err))
+		return
+	}
+
+	for i := range campaigns {
+		s.advance(ctx, &campaigns[i])
+	}
+}
+
+// advance enqueues the campaign's next batch of users, as far as the
+// quota policy allows today, and saves its progress.
+func (s *campaignService) advance(ctx context.Context, campaign *models.Campaign) {
+	if campaign.Status == models.CampaignStatusSoaking {
+		// Claimed because its soak is over or it was continued
+		campaign.Status = models.CampaignStatusRunning
+		zap.L().Info("Campaign continuing after canary", zap.String("campaign_id", campaign.ID))
+	}
+
+	if len(campaign.UserIDs) > 0 {
+		notification := models.PushNotification{
+			Title:          campaign.Title,
+			Body:           campaign.Body,
+			Image:          campaign.Image,
+			Link:           campaign.Link,
+			Data:           campaign.Data,
+			Sender:         campaign.Sender,
+			Category:       campaign.Category,
+			AnalyticsLabel: campaign.AnalyticsLabel,
+			Status:         "queued",
+		}
+		messages, err := s.pushService.ResolveRecipients(ctx, campaign.UserIDs, notification)
+		if err != nil {
+			// Leave processed_users as it is, so the batch is retried next tick
+			zap.L().Error("Failed to resolve campaign recipients",
+				zap.String("campaign_id", campaign.ID),
+				zap.Error(err),
+			)
+		} else {
+			applyVariants(campaign, messages)
+			s.dispatch(ctx, campaign, messages)
+		}
+	}
+
+	if campaign.ProcessedUsers >= campaign.TotalUsers {
+		now := time.Now().UTC()
+		campaign.Status = models.CampaignStatusCompleted
+		campaign.CompletedAt = &now
+		campaign.ProjectedCompletionAt = &now
+		zap.L().Info("Campaign completed",
+			zap.String("campaign_id", campaign.ID),
+			zap.Int("enqueued_users", campaign.EnqueuedUsers),
+			zap.Int64("enqueued_tokens", campaign.EnqueuedTokens),
+		)
+	} else {
+		soakAfterCanary(campaign)
+		campaign.ProjectedCompletionAt = s.projectCompletion(ctx, campaign)
+	}
+
+	if err := s.campaignRepo.UpdateProgress(ctx, campaign); err != nil {
+		zap.L().Error("Failed to save campaign progress", zap.String("campaign_id", campaign.ID), zap.Error(err))
+	}
+}
+
+// campaignSend is a message assigned to a project, with its position in the batch
+type campaignSend struct {
+	index   int
+	message queue.PushMessage
+}
+
+// dispatch reserves quota for messages in order, project by project per the
+// campaign's policy, enqueues what fits and advances the campaign's counters
+// past every user that was handled. Users that didn't fit are retried on a
+// later tick, after the quota day rolls over under the spread policy.
+func (s *campaignService) dispatch(ctx context.Context, campaign *models.Campaign, messages []*queue.PushMessage) {
+	day := quotaDay(time.Now())
+
+	var sends []campaignSend
+	next := 0
+	for _, projectID := range s.projectOrder(campaign.QuotaPolicy) {
+		want := tokensFrom(messages[next:])
+		if want == 0 {
+			break
+		}
+
+		granted, err := s.quotaRepo.Reserve(ctx, projectID, day, want, int64(s.projects.DailyQuota(projectID)))
+		if err != nil {
+			zap.L().Error("Failed to reserve FCM quota", zap.String("project_id", projectID), zap.Error(err))
+			break
+		}
+
+		var used int64
+		for ; next < len(messages); next++ {
+			message := messages[next]
+			if message == nil {
+				continue
+			}
+			tokens := int64(len(message.DeviceTokens))
+			if used+tokens > granted {
+				break
+			}
+			used += tokens
+
+			send := campaignSend{index: next, message: *message}
+			send.message.CampaignID = campaign.ID
+			send.message.ProjectID = projectID
+			sends = append(sends, send)
+		}
+
+		if used < granted {
+			s.releaseQuota(ctx, projectID, day, granted-used)
+		}
+	}
+
+	// Only nil (skipped) entries left means the whole batch was handled
+	if tokensFrom(messages[next:]) == 0 {
+		next = len(messages)
+	}
+
+	batchSize := s.cfg.Queue.Bulk.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100 // default
+	}
+
+	for start := 0; start < len(sends); start += batchSize {
+		end := start + batchSize
+		if end > len(sends) {
+			end = len(sends)
+		}
+
+		batch := make([]queue.PushMessage, end-start)
+		for i, send := range sends[start:end] {
+			// Campaigns go out at low priority so they never hold up
+			// transactional notifications
+			batch[i] = send.message
+			batch[i].Priority = models.PriorityLow
+		}
+
+		if err := s.pushQueue.EnqueuePushBatch(ctx, batch); err != nil {
+			zap.L().Error("Failed to enqueue campaign batch",
+				zap.String("campaign_id", campaign.ID),
+				zap.Error(err),
+			)
+			// Hand back the quota of everything not enqueued and resume
+			// from the first user of the failed batch next tick.
+			for _, send := range sends[start:] {
+				s.releaseQuota(ctx, send.message.ProjectID, day, int64(len(send.message.DeviceTokens)))
+			}
+			next = sends[start].index
+			break
+		}
+
+		for _, send := range sends[start:end] {
+			campaign.EnqueuedUsers++
+			campaign.EnqueuedTokens += int64(len(send.message.DeviceTokens))
+		}
+	}
+
+	campaign.ProcessedUsers += next
+
+	if next < len(messages) {
+		zap.L().Info("Campaign paused for quota",
+			zap.String("campaign_id", campaign.ID),
+			zap.String("quota_policy", campaign.QuotaPolicy),
+			zap.Int("processed_users", campaign.ProcessedUsers),
+			zap.Int("total_users", campaign.TotalUsers),
+		)
+	}
+}
+
+func (s *campaignService) releaseQuota(ctx context.Context, projectID string, day time.Time, n int64) {
+	if err := s.quotaRepo.Add(ctx, projectID, day, -n); err != nil {
+		zap.L().Warn("Failed to release FCM quota", zap.String("project_id", projectID), zap.Error(err))
+	}
+}
+
+// projectOrder lists the projects a campaign may send through, in order
+func (s *campaignService) projectOrder(policy string) []string {
+	order := []string{s.projects.PrimaryID()}
+	if policy == config.QuotaPolicyFailover {
+		order = append(order, s.projects.Failover()...)
+	}
+	return order
+}
+
+// projectCompletion estimates when the campaign's remaining users will have
+// been enqueued, including the wait after a canary. There is no estimate
+// while the campaign waits for an explicit continue.
+func (s *campaignService) projectCompletion(ctx context.Context, campaign *models.Campaign) *time.Time {
+	var wait time.Duration
+	switch {
+	case campaign.Status == models.CampaignStatusSoaking:
+		if campaign.ContinueAt == nil {
+			return nil
+		}
+		wait = max(time.Until(*campaign.ContinueAt), 0)
+	case campaign.CanaryUsers > 0 && campaign.CanaryCompletedAt == nil:
+		if campaign.CanarySoakSeconds == nil {
+			return nil
+		}
+		wait = time.Duration(*campaign.CanarySoakSeconds) * time.Second
+	}
+
+	at := s.projectEnqueueing(ctx, campaign)
+	if wait > 0 && campaign.ProcessedUsers < campaign.TotalUsers {
+		shifted := at.Add(wait)
+		at = &shifted
+	}
+	return at
+}
+
+// projectEnqueueing estimates when the campaign's remaining users will have
+// been enqueued, given one batch per tick and the projects' remaining and
+// daily quotas. Tokens per user are extrapolated from what was sent so far.
+func (s *campaignService) projectEnqueueing(ctx context.Context, campaign *models.Campaign) *time.Time {
+	now := time.Now().UTC()
+	remainingUsers := campaign.TotalUsers - campaign.ProcessedUsers
+	if remainingUsers <= 0 {
+		return &now
+	}
+
+	tokensPerUser := 1.0
+	if campaign.EnqueuedUsers > 0 {
+		tokensPerUser = float64(campaign.EnqueuedTokens) / float64(campaign.EnqueuedUsers)
+	}
+	remainingTokens := int64(math.Ceil(float64(remainingUsers) * tokensPerUser))
+
+	day := quotaDay(now)
+	var availableToday, dailyCapacity int64
+	for _, projectID := range s.projectOrder(campaign.QuotaPolicy) {
+		limit := int64(s.projects.DailyQuota(projectID))
+		if limit == 0 {
+			// Unlimited: only pacing matters
+			at := now.Add(s.pacing(remainingUsers))
+			return &at
+		}
+		used, err := s.quotaRepo.Used(ctx, projectID, day)
+		if err != nil {
+			used = 0
+		}
+		if used < limit {
+			availableToday += limit - used
+		}
+		dailyCapacity += limit
+	}
+
+	if remainingTokens <= availableToday {
+		at := now.Add(s.pacing(remainingUsers))
+		return &at
+	}
+
+	// The overflow waits for the following quota days
+	overflow := remainingTokens - availableToday
+	days := (overflow + dailyCapacity - 1) / dailyCapacity
+	lastDayTokens := overflow - (days-1)*dailyCapacity
+	lastDayUsers := int(math.Ceil(float64(lastDayTokens) / tokensPerUser))
+
+	at := day.AddDate(0, 0, int(days)).Add(s.pacing(lastDayUsers))
+	return &at
+}
+
+// pacing is how long the scheduler takes to work through users at one batch per tick
+func (s *campaignService) pacing(users int) time.Duration {
+	batchSize := s.batchSize()
+	ticks := (users + batchSize - 1) / batchSize
+	return time.Duration(ticks) * s.tickInterval()
+}
+
+func (s *campaignService) tickInterval() time.Duration {
+	if s.cfg.Campaign.TickInterval <= 0 {
+		return 10 * time.Second // default
+	}
+	return s.cfg.Campaign.TickInterval
+}
+
+func (s *campaignService) batchSize() int {
+	if s.cfg.Campaign.BatchSize <= 0 {
+		return 500 // default
+	}
+	return s.cfg.Campaign.BatchSize
+}
+
+func tokensFrom(messages []*queue.PushMessage) int64 {
+	var tokens int64
+	for _, message := range messages {
+		if message != nil {
+			tokens += int64(len(message.DeviceTokens))
+		}
+	}
+	return tokens
+}
+
+// quotaDay returns the UTC day t's sends count against
+func quotaDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"push-service/internal/models"
+	"push-service/internal/queue"
+)
+
+// ErrInvalidVariants is returned for campaigns whose A/B variants can't be
+// split between users
+var ErrInvalidVariants = errors.New("invalid campaign variants")
+
+// validateVariants checks variant names are unique and weights add up to 100
+func validateVariants(variants []models.CampaignVariant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool, len(variants))
+	total := 0
+	for _, variant := range variants {
+		if names[variant.Name] {
+			return fmt.Errorf("%w: duplicate variant %q", ErrInvalidVariants, variant.Name)
+		}
+		names[variant.Name] = true
+		total += variant.Weight
+	}
+	if total != 100 {
+		return fmt.Errorf("%w: weights add up to %d, not 100", ErrInvalidVariants, total)
+	}
+	return nil
+}
+
+// assignVariant picks a user's variant. The user's bucket depends only on the
+// campaign and user ID, so a user gets the same variant on every batch and
+// retry.
+func assignVariant(campaignID, userID string, variants []models.CampaignVariant) *models.CampaignVariant {
+	h := fnv.New32a()
+	h.Write([]byte(campaignID + ":" + userID))
+	bucket := int(h.Sum32() % 100)
+
+	for i := range variants {
+		bucket -= variants[i].Weight
+		if bucket < 0 {
+			return &variants[i]
+		}
+	}
+	return &variants[len(variants)-1]
+}
+
+// applyVariants gives each message its user's variant of the campaign. The
+// campaign ID and variant go in the notification's data, so the client SDK
+// can report opens per variant.
+func applyVariants(campaign *models.Campaign, messages []*queue.PushMessage) {
+	if len(campaign.Variants) == 0 {
+		return
+	}
+
+	data := make(map[string]map[string]any, len(campaign.Variants))
+	for _, variant := range campaign.Variants {
+		variantData := make(map[string]any, len(campaign.Data)+2)
+		for key, value := range campaign.Data {
+			variantData[key] = value
+		}
+		variantData["campaign_id"] = campaign.ID
+		variantData["variant"] = variant.Name
+		data[variant.Name] = variantData
+	}
+
+	for _, message := range messages {
+		if message == nil {
+			continue
+		}
+		variant := assignVariant(campaign.ID, message.Notification.UserID, campaign.Variants)
+		message.Notification.Title = variant.Title
+		message.Notification.Body = variant.Body
+		message.Notification.Data = data[variant.Name]
+		message.Variant = variant.Name
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// categoryPolicy returns the configured policy of notification's category,
+// and whether it has one
+func (s *pushService) categoryPolicy(notification models.PushNotification) (config.CategoryConfig, bool) {
+	if s.cfg == nil || notification.Category == nil {
+		return config.CategoryConfig{}, false
+	}
+	policy, ok := s.cfg.Categories[*notification.Category]
+	return policy, ok
+}
+
+// applyCategoryDefaults fills in the retries and TTL message's request left
+// to its category's policy. The TTL counts from now, when the notification
+// is first picked up.
+func applyCategoryDefaults(message *queue.PushMessage, policy config.CategoryConfig, now time.Time) {
+	if message.MaxRetries == nil && policy.MaxRetries != nil {
+		maxRetries := *policy.MaxRetries
+		message.MaxRetries = &maxRetries
+	}
+	if message.Notification.ExpiresAt == nil && policy.TTL > 0 {
+		expiresAt := now.Add(policy.TTL)
+		message.Notification.ExpiresAt = &expiresAt
+	}
+}
+
+// applyCategoryPolicy applies the policy of message's category before its
+// first attempt: it is dropped if its user reached the category's frequency
+// cap, and the devices in quiet hours get it once they are over. It returns
+// the message with the devices to deliver to now, and whether the delivery
+// was settled instead because none are left.
+func (s *pushService) applyCategoryPolicy(ctx context.Context, delivery queue.Delivery, message queue.PushMessage, policy config.CategoryConfig, now time.Time) (queue.PushMessage, bool) {
+	message.PolicyApplied = true
+
+	if s.frequencyCapped(ctx, message, policy, now) {
+		s.dropFrequencyCapped(ctx, delivery, message)
+		return message, true
+	}
+
+	bypass := policy.BypassQuietHours
+	if message.BypassQuietHours != nil {
+		bypass = *message.BypassQuietHours
+	}
+	if !s.cfg.QuietHours.Enabled || bypass {
+		return message, false
+	}
+
+	message.DeviceTokens = s.deferQuietHours(ctx, message, now)
+	if len(message.DeviceTokens) > 0 {
+		return message, false
+	}
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+	}
+	return message, true
+}
+
+// frequencyCapped counts message against its user's frequency cap in its
+// category, and reports whether the cap was already reached. Errors fail
+// open so a database hiccup can't silently swallow notifications.
+func (s *pushService) frequencyCapped(ctx context.Context, message queue.PushMessage, policy config.CategoryConfig, now time.Time) bool {
+	if policy.FrequencyCap <= 0 || message.BypassFrequencyCap || message.Notification.UserID == "" || s.frequencyRepo == nil {
+		return false
+	}
+
+	windowStart := now.Truncate(policy.FrequencyWindow)
+	reserved, err := s.frequencyRepo.Reserve(ctx, message.Notification.UserID, *message.Notification.Category, windowStart, windowStart.Add(policy.FrequencyWindow), policy.FrequencyCap)
+	if err != nil {
+		zap.L().Warn("Failed to check frequency cap, delivering anyway",
+			zap.String("user_id", message.Notification.UserID),
+			zap.Error(err),
+		)
+		return false
+	}
+	return !reserved
+}
+
+// dropFrequencyCapped settles a message whose user already got as many
+// notifications of its category as the cap allows in the current window
+func (s *pushService) dropFrequencyCapped(ctx context.Context, delivery queue.Delivery, message queue.PushMessage) {
+	zap.L().Info("Notification dropped",
+		zap.String("notification_id", message.Notification.ID),
+		zap.String("user_id", message.Notification.UserID),
+		zap.String("category", *message.Notification.Category),
+		zap.String("reason", models.DropReasonFrequencyCapped),
+	)
+	metrics.NotificationsDropped.WithLabelValues(models.DropReasonFrequencyCapped).Inc()
+
+	s.recordAttempt(ctx, delivery, message, failedResults(message.DeviceTokens, "frequency cap reached"), errors.New("frequency cap reached"), "")
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+	}
+}
+
+// deferQuietHours holds message back for its devices whose timezone is in
+// quiet hours, storing it for each group of them until quiet hours end
+// there. It returns the tokens to deliver to now. If it can't, the message
+// is delivered to all of them now.
+func (s *pushService) deferQuietHours(ctx context.Context, message queue.PushMessage, now time.Time) []string {
+	startHour, startMinute, err := parseLocalTime(s.cfg.QuietHours.Start)
+	if err != nil {
+		return message.DeviceTokens
+	}
+	endHour, endMinute, err := parseLocalTime(s.cfg.QuietHours.End)
+	if err != nil {
+		return message.DeviceTokens
+	}
+	defaultLoc, err := time.LoadLocation(s.cfg.Scheduler.DefaultTimezone)
+	if err != nil {
+		return message.DeviceTokens
+	}
+
+	timezones := make(map[string]*string)
+	if message.Notification.UserID != "" {
+		devices, err := s.deviceRepo.GetByUserID(ctx, message.Notification.UserID)
+		if err != nil {
+			zap.L().Warn("Failed to look up device timezones, ignoring quiet hours",
+				zap.String("user_id", message.Notification.UserID),
+				zap.Error(err),
+			)
+			return message.DeviceTokens
+		}
+		for _, device := range devices {
+			timezones[device.Token] = device.Timezone
+		}
+	}
+
+	var immediate []string
+	groups := make(map[time.Time][]string)
+	var order []time.Time
+	for _, token := range message.DeviceTokens {
+		loc := defaultLoc
+		if timezone := timezones[token]; timezone != nil {
+			if deviceLoc, err := time.LoadLocation(*timezone); err == nil {
+				loc = deviceLoc
+			}
+		}
+		if !inQuietHours(now.In(loc), startHour, startMinute, endHour, endMinute) {
+			immediate = append(immediate, token)
+			continue
+		}
+		at := nextLocalTime(now, loc, endHour, endMinute)
+		if _, ok := groups[at]; !ok {
+			order = append(order, at)
+		}
+		groups[at] = append(groups[at], token)
+	}
+
+	for _, at := range order {
+		deferred := message
+		deferred.DeviceTokens = groups[at]
+		body, err := json.Marshal(deferred)
+		if err == nil {
+			err = s.scheduledRepo.Create(ctx, &models.ScheduledPush{DeliverAt: at, Message: body})
+		}
+		if err != nil {
+			zap.L().Warn("Failed to hold notification back for quiet hours, delivering now",
+				zap.String("notification_id", message.Notification.ID),
+				zap.Error(err),
+			)
+			immediate = append(immediate, groups[at]...)
+			continue
+		}
+		metrics.QuietHoursDeferred.Add(float64(len(groups[at])))
+		zap.L().Info("Notification held back for quiet hours",
+			zap.String("notification_id", message.Notification.ID),
+			zap.String("user_id", message.Notification.UserID),
+			zap.Int("device_count", len(groups[at])),
+			zap.Time("deliver_at", at),
+		)
+	}
+	return immediate
+}
+
+// inQuietHours reports whether the clock of local reads between start and
+// end, which wrap around midnight if end is earlier
+func inQuietHours(local time.Time, startHour, startMinute, endHour, endMinute int) bool {
+	minute := local.Hour()*60 + local.Minute()
+	start := startHour*60 + startMinute
+	end := endHour*60 + endMinute
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/internal/repository"
+	"push-service/pkg/lock"
+	"push-service/pkg/metrics"
+	"push-service/pkg/objectstore"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const deadLetterArchiveLockKey = "dead-letter-archive"
+
+// archiveIdleTimeout ends a drain of the dead letter queue once no message
+// has arrived for this long, i.e. the queue is empty
+const archiveIdleTimeout = 5 * time.Second
+
+// DeadLetterArchiver moves dead letters out of the dead letter queue before
+// they expire, into gzipped NDJSON objects in a bucket, and indexes them by
+// notification
+type DeadLetterArchiver interface {
+	Run(ctx context.Context)
+	// ListArchived returns where a notification's dead letters were archived
+	ListArchived(ctx context.Context, notificationID string) (*models.ArchivedDeadLetters, error)
+}
+
+type deadLetterArchiver struct {
+	archiveRepo repository.DeadLetterArchiveRepository
+	pushQueue   *queue.PushQueue
+	store       objectstore.Store
+	locker      lock.Locker
+	cfg         *config.ArchiveConfig
+}
+
+// NewDeadLetterArchiver creates the archiver. store may be nil if the
+// archiver is only used to list archived dead letters.
+func NewDeadLetterArchiver(archiveRepo repository.DeadLetterArchiveRepository, pushQueue *queue.PushQueue, store objectstore.Store, locker lock.Locker, cfg *config.Config) DeadLetterArchiver {
+	return &deadLetterArchiver{
+		archiveRepo: archiveRepo,
+		pushQueue:   pushQueue,
+		store:       store,
+		locker:      locker,
+		cfg:         &cfg.Archive,
+	}
+}
+
+// archivedLine is a dead letter's line in an archive object
+type archivedLine struct {
+	NotificationID *string         `json:"notification_id,omitempty"`
+	DeadLetteredAt *time.Time      `json:"dead_lettered_at,omitempty"`
+	Message        json.RawMessage `json:"message,omitempty"`
+	Body           []byte          `json:"body,omitempty"` // base64, if the message couldn't be decoded
+}
+
+// Run archives old dead letters every interval until ctx is cancelled. It
+// returns at once if archiving is disabled.
+func (a *deadLetterArchiver) Run(ctx context.Context) {
+	if !a.cfg.Enabled || a.store == nil {
+		return
+	}
+
+	interval := a.cfg.Interval
+	if interval <= 0 {
+		interval = time.Hour // default
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	zap.L().Info("Dead letter archiver started",
+		zap.Duration("interval", interval),
+		zap.String("bucket", a.store.URL("")),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := lock.Run(ctx, a.locker, deadLetterArchiveLockKey, 0, a.archive)
+			if err != nil && !errors.Is(err, lock.ErrNotAcquired) {
+				zap.L().Error("Failed to archive dead letters", zap.Error(err))
+			}
+		}
+	}
+}
+
+// archive stages the old dead letters, then writes every staged dead letter
+// to the bucket. Dead letters staged by a run that failed to write them are
+// written by the next.
+func (a *deadLetterArchiver) archive(ctx context.Context) error {
+	after := a.cfg.After
+	if after <= 0 {
+		after = 6 * 24 * time.Hour // default
+	}
+
+	staged, err := a.drain(ctx, time.Now().Add(-after))
+	if err != nil {
+		return err
+	}
+	if staged > 0 {
+		zap.L().Info("Dead letters staged for archiving", zap.Int("staged", staged))
+	}
+	return a.upload(ctx)
+}
+
+// drain moves the dead letters published before cutoff from the queue to
+// the staging table, oldest first. It stops at the first younger message,
+// which is put back, or once the queue is empty.
+func (a *deadLetterArchiver) drain(ctx context.Context, cutoff time.Time) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	broker := a.pushQueue.Broker()
+	msgs, err := queue.Consume(ctx, broker, queue.DeadLetterQueue, "dead-letter-archive", broker.Prefetch())
+	if err != nil {
+		return 0, err
+	}
+
+	staged := 0
+	idle := time.NewTimer(archiveIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return staged, ctx.Err()
+		case <-idle.C:
+			return staged, nil
+		case delivery, ok := <-msgs:
+			if !ok {
+				return staged, nil
+			}
+			// Messages whose broker doesn't tell when they were dead-lettered
+			// are archived at once
+			if !delivery.PublishedAt.IsZero() && delivery.PublishedAt.After(cutoff) {
+				if err := delivery.Nack(true); err != nil {
+					zap.L().Warn("Failed to put back dead letter", zap.String("delivery_id", delivery.ID), zap.Error(err))
+				}
+				return staged, nil
+			}
+
+			if err := a.stage(ctx, delivery); err != nil {
+				if nackErr := delivery.Nack(true); nackErr != nil {
+					zap.L().Warn("Failed to put back dead letter", zap.String("delivery_id", delivery.ID), zap.Error(nackErr))
+				}
+				return staged, err
+			}
+			if err := delivery.Ack(); err != nil {
+				// Staged twice if redelivered; both copies are archived
+				zap.L().Warn("Failed to acknowledge staged dead letter", zap.String("delivery_id", delivery.ID), zap.Error(err))
+			}
+			staged++
+			idle.Reset(archiveIdleTimeout)
+		}
+	}
+}
+
+// stage stores a dead letter, indexed by its notification and user if its
+// message can be decoded
+func (a *deadLetterArchiver) stage(ctx context.Context, delivery queue.Delivery) error {
+	var notificationID, userID *string
+	if message, err := queue.DecodePushMessage(delivery.Body); err == nil {
+		if message.Notification.ID != "" {
+			notificationID = &message.Notification.ID
+		}
+		if message.Notification.UserID != "" {
+			userID = &message.Notification.UserID
+		}
+	}
+
+	var deadLetteredAt *time.Time
+	if !delivery.PublishedAt.IsZero() {
+		publishedAt := delivery.PublishedAt.UTC()
+		deadLetteredAt = &publishedAt
+	}
+	return a.archiveRepo.Stage(ctx, notificationID, userID, delivery.Body, deadLetteredAt)
+}
+
+// upload writes the staged dead letters to the bucket, up to batch size per
+// object
+func (a *deadLetterArchiver) upload(ctx context.Context) error {
+	batchSize := a.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 10000 // default
+	}
+
+	for {
+		staged, err := a.archiveRepo.ListStaged(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(staged) == 0 {
+			return nil
+		}
+
+		body, err := encodeArchive(staged)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		key := a.cfg.Prefix + now.Format("2006/01/02/") + now.Format("20060102T150405Z") + "-" + uuid.NewString() + ".ndjson.gz"
+		if err := a.store.Put(ctx, key, body, "application/gzip"); err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(staged))
+		for i, deadLetter := range staged {
+			ids[i] = deadLetter.ID
+		}
+		objectURL := a.store.URL(key)
+		if err := a.archiveRepo.MarkArchived(ctx, ids, objectURL); err != nil {
+			// Written again to a new object on the next run
+			return err
+		}
+		metrics.DeadLettersArchived.Add(float64(len(staged)))
+		zap.L().Info("Dead letters archived", zap.String("object_url", objectURL), zap.Int("count", len(staged)))
+
+		if len(staged) < batchSize {
+			return nil
+		}
+	}
+}
+
+// encodeArchive gzips the dead letters as NDJSON, one line each in order.
+// Messages are written as JSON whatever their queue encoding.
+func encodeArchive(staged []models.StagedDeadLetter) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for _, deadLetter := range staged {
+		line := archivedLine{
+			NotificationID: deadLetter.NotificationID,
+			DeadLetteredAt: deadLetter.DeadLetteredAt,
+		}
+		if message, err := queue.DecodePushMessage(deadLetter.Body); err == nil {
+			if line.Message, err = json.Marshal(message); err != nil {
+				return nil, err
+			}
+		} else {
+			line.Body = deadLetter.Body
+		}
+		if err := encoder.Encode(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *deadLetterArchiver) ListArchived(ctx context.Context, notificationID string) (*models.ArchivedDeadLetters, error) {
+	deadLetters, err := a.archiveRepo.ListByNotification(ctx, notificationID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ArchivedDeadLetters{
+		NotificationID: notificationID,
+		DeadLetters:    deadLetters,
+		Count:          len(deadLetters),
+	}, nil
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"push-service/internal/models"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/queue"
+	"push-service/pkg/logger"
+
+	"firebase.google.com/go/v4/messaging"
+	"go.uber.org/zap"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// GetNotification returns a notification's delivery attempts, oldest first,
+// and the status they add up to
+func (s *pushService) GetNotification(ctx context.Context, id string) (*models.NotificationDetail, error) {
+	attempts, err := s.attemptRepo.ListByNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(attempts) == 0 {
+		return nil, ErrNotificationNotFound
+	}
+
+	return &models.NotificationDetail{
+		ID:       id,
+		UserID:   attempts[0].UserID,
+		Status:   notificationStatus(attempts[len(attempts)-1]),
+		Attempts: attempts,
+	}, nil
+}
+
+// GetNotificationTokens returns a notification's latest result for each of
+// its device tokens, or only for token if it is set, with the tokens masked
+func (s *pushService) GetNotificationTokens(ctx context.Context, id, token string) (*models.NotificationTokenResults, error) {
+	results, err := s.attemptRepo.ListTokenResults(ctx, id, token)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotificationNotFound
+	}
+
+	for i := range results {
+		results[i].Token = logger.MaskToken(results[i].Token)
+	}
+	return &models.NotificationTokenResults{NotificationID: id, Results: results, Count: len(results)}, nil
+}
+
+// ListDeliveryEvents returns the delivery attempts of every notification
+// recorded after the attempt with ID after, oldest first, or the last ones
+// if after is 0
+func (s *pushService) ListDeliveryEvents(ctx context.Context, after int64, limit int) (*models.DeliveryEvents, error) {
+	events, err := s.attemptRepo.ListRecent(ctx, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range events {
+		events[i].Status = notificationStatus(events[i].DeliveryAttempt)
+	}
+	if len(events) > 0 {
+		after = events[len(events)-1].ID
+	}
+	return &models.DeliveryEvents{Events: events, Count: len(events), After: after}, nil
+}
+
+// notificationStatus derives a notification's status from its last attempt
+func notificationStatus(last models.DeliveryAttempt) string {
+	switch {
+	case last.NextQueue == nil && last.SuccessCount > 0 && last.FailureCount > 0:
+		return models.NotificationStatusPartial
+	case last.NextQueue == nil && last.SuccessCount > 0:
+		return models.NotificationStatusDelivered
+	case last.NextQueue == nil:
+		return models.NotificationStatusFailed
+	case *last.NextQueue == queue.DeadLetterQueue:
+		return models.NotificationStatusDeadLettered
+	case queue.IsPushQueue(*last.NextQueue), *last.NextQueue == queue.GatewayPushQueueName:
+		return models.NotificationStatusQueued
+	default:
+		return models.NotificationStatusRetrying
+	}
+}
+
+// requeuedTo returns the queue a message nacked with requeue goes back to:
+// the gateway's for gateway messages processed directly, and otherwise the
+// push queue of its priority
+func requeuedTo(pushMessage queue.PushMessage) string {
+	if pushMessage.Queue == queue.GatewayPushQueueName {
+		return queue.GatewayPushQueueName
+	}
+	return queue.PushQueueFor(pushMessage.Priority)
+}
+
+// recordAttempt stores an attempt to send pushMessage to deviceTokens, and
+// nextQueue, where the message went afterwards ("" if it was settled).
+// Failing to record it doesn't fail the delivery.
+func (s *pushService) recordAttempt(ctx context.Context, delivery queue.Delivery, pushMessage queue.PushMessage, results []models.AttemptTokenResult, sendErr error, nextQueue string) {
+	attempt := &models.DeliveryAttempt{
+		NotificationID: pushMessage.Notification.ID,
+		UserID:         pushMessage.Notification.UserID,
+		RetryCount:     pushMessage.RetryCount,
+		Queue:          pushMessage.Queue,
+		Redelivered:    delivery.Redelivered,
+		Results:        results,
+	}
+	if attempt.Queue == "" {
+		attempt.Queue = queue.PushQueueFor(pushMessage.Priority)
+	}
+	if pushMessage.ProjectID != "" {
+		attempt.ProjectID = &pushMessage.ProjectID
+	}
+	for _, result := range results {
+		if result.Success {
+			attempt.SuccessCount++
+		} else {
+			attempt.FailureCount++
+		}
+	}
+	if sendErr != nil {
+		message := sendErr.Error()
+		attempt.Error = &message
+	}
+	if nextQueue != "" {
+		attempt.NextQueue = &nextQueue
+	}
+
+	if err := s.attemptRepo.Create(ctx, attempt); err != nil {
+		zap.L().Warn("Failed to record delivery attempt",
+			zap.String("notification_id", attempt.NotificationID),
+			zap.Error(err),
+		)
+	}
+	if err := s.attemptRepo.SaveTokenResults(ctx, attempt.NotificationID, attempt.UserID, results); err != nil {
+		zap.L().Warn("Failed to record token delivery results",
+			zap.String("notification_id", attempt.NotificationID),
+			zap.Error(err),
+		)
+	}
+	s.recordEvents(pushMessage, results)
+	s.notifyStatus(ctx, pushMessage, *attempt)
+}
+
+// undeliveredTokens returns the tokens of a retried or redelivered message
+// that no earlier attempt delivered to. If the results can't be read, every
+// token is sent again.
+func (s *pushService) undeliveredTokens(ctx context.Context, pushMessage queue.PushMessage) []string {
+	if pushMessage.Notification.ID == "" {
+		return pushMessage.DeviceTokens
+	}
+
+	stored, err := s.attemptRepo.ListTokenResults(ctx, pushMessage.Notification.ID, "")
+	if err != nil {
+		zap.L().Warn("Failed to load token delivery results, retrying every token",
+			zap.String("notification_id", pushMessage.Notification.ID),
+			zap.Error(err),
+		)
+		return pushMessage.DeviceTokens
+	}
+
+	results := make([]models.AttemptTokenResult, len(stored))
+	for i, result := range stored {
+		results[i] = models.AttemptTokenResult{DeviceToken: result.Token, Success: result.Success}
+	}
+	return queue.UndeliveredTokens(pushMessage.DeviceTokens, results)
+}
+
+// skipDelivered settles a retried or redelivered message whose tokens were
+// all delivered by earlier attempts
+func (s *pushService) skipDelivered(delivery queue.Delivery, pushMessage queue.PushMessage) error {
+	zap.L().Info("Every device token was already delivered, skipping message",
+		zap.String("notification_id", pushMessage.Notification.ID),
+		zap.Int("retry_count", pushMessage.RetryCount),
+	)
+
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// statusEvents maps notification statuses to the webhook events they emit.
+// Retries aren't reported; the notification is still in flight.
+var statusEvents = map[string]string{
+	models.NotificationStatusDelivered:    models.EventNotificationSent,
+	models.NotificationStatusPartial:      models.EventNotificationSent,
+	models.NotificationStatusFailed:       models.EventNotificationFailed,
+	models.NotificationStatusDeadLettered: models.EventNotificationDeadLettered,
+}
+
+// notifyStatus sends the tenant's webhooks the status an attempt left its
+// notification in, if it is one they are told about
+func (s *pushService) notifyStatus(ctx context.Context, pushMessage queue.PushMessage, attempt models.DeliveryAttempt) {
+	status := notificationStatus(attempt)
+	event, ok := statusEvents[status]
+	if !ok {
+		return
+	}
+
+	data := map[string]any{
+		"notification_id": attempt.NotificationID,
+		"user_id":         attempt.UserID,
+		"status":          status,
+		"success_count":   attempt.SuccessCount,
+		"failure_count":   attempt.FailureCount,
+		"retry_count":     attempt.RetryCount,
+	}
+	if attempt.Error != nil {
+		data["error"] = *attempt.Error
+	}
+	s.webhooks.Notify(ctx, pushMessage.TenantID, event, data)
+}
+
+// notifyQueued tells the tenant's webhooks a notification was enqueued
+func (s *pushService) notifyQueued(ctx context.Context, tenantID string, notification models.PushNotification) {
+	s.webhooks.Notify(ctx, tenantID, models.EventNotificationQueued, map[string]any{
+		"notification_id": notification.ID,
+		"user_id":         notification.UserID,
+		"status":          models.NotificationStatusQueued,
+	})
+}
+
+// attemptResults returns each token's outcome of a multicast send. Tokens
+// without a response failed with sendErr.
+func attemptResults(deviceTokens []string, response *messaging.BatchResponse, sendErr error) []models.AttemptTokenResult {
+	results := make([]models.AttemptTokenResult, len(deviceTokens))
+	for i, token := range deviceTokens {
+		results[i] = models.AttemptTokenResult{Token: logger.MaskToken(token), DeviceToken: token}
+		err := sendErr
+		if response != nil && i < len(response.Responses) {
+			resp := response.Responses[i]
+			if resp.Success {
+				results[i].Success = true
+				if resp.MessageID != "" {
+					messageID := resp.MessageID
+					results[i].MessageID = &messageID
+				}
+				continue
+			}
+			err = resp.Error
+		}
+		if err == nil {
+			continue
+		}
+		kind := string(fcm.ClassifyError(err))
+		message := err.Error()
+		results[i].ErrorKind = &kind
+		results[i].Error = &message
+		if code := fcm.ErrorCode(err); code != "" {
+			results[i].ErrorCode = &code
+		}
+	}
+	return results
+}
+
+// failedResults returns results failing every token with reason, for
+// attempts that never reached FCM
+func failedResults(deviceTokens []string, reason string) []models.AttemptTokenResult {
+	results := make([]models.AttemptTokenResult, len(deviceTokens))
+	for i, token := range deviceTokens {
+		message := reason
+		results[i] = models.AttemptTokenResult{Token: logger.MaskToken(token), DeviceToken: token, Error: &message}
+	}
+	return results
+}
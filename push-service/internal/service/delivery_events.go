@@ -0,0 +1,65 @@
+package service
+
+import (
+	"time"
+
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/pkg/eventsink"
+	"push-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// recordEvents streams an attempt's result for each token to the event
+// sink, as sent or failed events
+func (s *pushService) recordEvents(pushMessage queue.PushMessage, results []models.AttemptTokenResult) {
+	if s.events == nil || len(results) == 0 {
+		return
+	}
+
+	notification := pushMessage.Notification
+	category := ""
+	if notification.Category != nil {
+		category = *notification.Category
+	}
+	priority := pushMessage.Priority
+	if priority == "" {
+		priority = models.PriorityNormal
+	}
+
+	now := time.Now().UTC()
+	events := make([]eventsink.Event, len(results))
+	for i, result := range results {
+		platform := pushMessage.Platforms[result.DeviceToken]
+		if platform == "" {
+			platform = models.PlatformUnknown
+		}
+		event := eventsink.Event{
+			ID:             uuid.NewString(),
+			Type:           eventsink.EventSent,
+			OccurredAt:     now,
+			NotificationID: notification.ID,
+			UserHash:       logger.HashUserID(notification.UserID),
+			Platform:       platform,
+			TenantID:       pushMessage.TenantID,
+			ProjectID:      pushMessage.ProjectID,
+			CampaignID:     pushMessage.CampaignID,
+			Variant:        pushMessage.Variant,
+			Category:       category,
+			Priority:       priority,
+			RetryCount:     pushMessage.RetryCount,
+		}
+		if !result.Success {
+			event.Type = eventsink.EventFailed
+			if result.ErrorKind != nil {
+				event.ErrorKind = *result.ErrorKind
+			}
+			if result.ErrorCode != nil {
+				event.ErrorCode = *result.ErrorCode
+			}
+		}
+		events[i] = event
+	}
+	s.events.Record(events...)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/repository"
+	"push-service/pkg/lock"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+const deviceCleanupLockKey = "device-cleanup"
+
+// DeviceCleanupService keeps the device table from growing without bound:
+// it deactivates devices that stopped checking in and deletes devices that
+// have been deactivated for longer than the retention
+type DeviceCleanupService interface {
+	Run(ctx context.Context)
+}
+
+type deviceCleanupService struct {
+	deviceRepo repository.DeviceRepository
+	locker     lock.Locker
+	cfg        *config.DeviceCleanupConfig
+}
+
+func NewDeviceCleanupService(deviceRepo repository.DeviceRepository, locker lock.Locker, cfg *config.Config) DeviceCleanupService {
+	return &deviceCleanupService{
+		deviceRepo: deviceRepo,
+		locker:     locker,
+		cfg:        &cfg.Devices.Cleanup,
+	}
+}
+
+// Run cleans up every interval until ctx is cancelled. It returns at once if
+// the cleanup is disabled.
+func (s *deviceCleanupService) Run(ctx context.Context) {
+	if !s.cfg.Enabled {
+		return
+	}
+
+	interval := s.cfg.Interval
+	if interval <= 0 {
+		interval = time.Hour // default
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	zap.L().Info("Device cleanup started",
+		zap.Duration("interval", interval),
+		zap.Duration("stale_after", s.cfg.StaleAfter),
+		zap.Duration("retention", s.cfg.Retention),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := lock.Run(ctx, s.locker, deviceCleanupLockKey, 0, s.cleanup)
+			if err != nil && !errors.Is(err, lock.ErrNotAcquired) {
+				zap.L().Error("Failed to clean up devices", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *deviceCleanupService) cleanup(ctx context.Context) error {
+	batchSize := s.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000 // default
+	}
+	staleAfter := s.cfg.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = 90 * 24 * time.Hour // default
+	}
+	retention := s.cfg.Retention
+	if retention <= 0 {
+		retention = 30 * 24 * time.Hour // default
+	}
+
+	now := time.Now()
+	deactivated, err := inBatches(ctx, batchSize, func(ctx context.Context) (int64, error) {
+		return s.deviceRepo.DeactivateStale(ctx, now.Add(-staleAfter), batchSize)
+	})
+	metrics.DevicesCleanedUp.WithLabelValues("deactivated").Add(float64(deactivated))
+	if err != nil {
+		return err
+	}
+
+	purged, err := inBatches(ctx, batchSize, func(ctx context.Context) (int64, error) {
+		return s.deviceRepo.PurgeDeactivated(ctx, now.Add(-retention), batchSize)
+	})
+	metrics.DevicesCleanedUp.WithLabelValues("purged").Add(float64(purged))
+	if err != nil {
+		return err
+	}
+
+	if deactivated > 0 || purged > 0 {
+		zap.L().Info("Devices cleaned up",
+			zap.Int64("deactivated", deactivated),
+			zap.Int64("purged", purged),
+		)
+	}
+	return nil
+}
+
+// inBatches calls batch until it handles fewer than batchSize rows, and
+// returns how many rows it handled in all. Each batch is its own statement,
+// so locks are held briefly.
+func inBatches(ctx context.Context, batchSize int, batch func(ctx context.Context) (int64, error)) (int64, error) {
+	var total int64
+	for {
+		n, err := batch(ctx)
+		total += n
+		if err != nil || n < int64(batchSize) {
+			return total, err
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+
+	"push-service/internal/models"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// registerStableDevice registers a token of a device that sent its stable
+// ID. If the device is registered under another token, e.g. from before the
+// app was reinstalled, that registration moves to the new token, keeping its
+// ID and history. Any other registrations of the device are deactivated, so
+// it isn't sent notifications twice.
+func (s *deviceService) registerStableDevice(ctx context.Context, req models.CreateDeviceRequest) (*models.DeviceResponse, error) {
+	response, err := s.replaceRegistration(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		if response, err = s.registerToken(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	deactivated, err := s.deviceRepo.ClaimStableID(ctx, req.Token, *req.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	if deactivated > 0 {
+		metrics.DevicesDeduplicated.Add(float64(deactivated))
+		zap.L().Info("Duplicate device registrations deactivated",
+			zap.String("device_id", response.ID),
+			zap.Int64("deactivated", deactivated),
+		)
+	}
+
+	response.DeviceID = req.DeviceID
+	return response, nil
+}
+
+// replaceRegistration moves the device's registration under an older token
+// to req's token. It returns nil if there is nothing to replace: the device
+// isn't registered yet, is registered under this token, or the token is
+// registered as a device of its own.
+func (s *deviceService) replaceRegistration(ctx context.Context, req models.CreateDeviceRequest) (*models.DeviceResponse, error) {
+	previous, err := s.deviceRepo.GetByStableID(ctx, *req.DeviceID)
+	if err != nil || previous == nil || previous.Token == req.Token {
+		return nil, err
+	}
+	current, err := s.deviceRepo.GetByToken(ctx, req.Token)
+	if err != nil || current != nil {
+		return nil, err
+	}
+
+	if _, err := s.deviceRepo.ReplaceToken(ctx, previous.Token, req.Token); err != nil {
+		return nil, err
+	}
+	device, err := s.deviceRepo.Reactivate(ctx, previous.ID, req.UserID, req.Platform)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+	if req.PermissionStatus != nil {
+		if err := s.deviceRepo.UpdatePermission(ctx, req.Token, *req.PermissionStatus); err != nil {
+			return nil, err
+		}
+		device.PermissionStatus = req.PermissionStatus
+	}
+	if req.Timezone != nil {
+		if err := s.deviceRepo.UpdateTimezone(ctx, req.Token, *req.Timezone); err != nil {
+			return nil, err
+		}
+		device.Timezone = req.Timezone
+	}
+	if !req.DeviceMetadata.IsEmpty() {
+		if err := s.deviceRepo.UpdateMetadata(ctx, req.Token, req.DeviceMetadata); err != nil {
+			return nil, err
+		}
+		device.DeviceMetadata = mergeMetadata(device.DeviceMetadata, req.DeviceMetadata)
+	}
+	metrics.DevicesDeduplicated.Inc()
+
+	zap.L().Info("Device registration replaced by new token",
+		zap.String("device_id", device.ID),
+		zap.String("user_id", device.UserID),
+		zap.String("previous_user_id", previous.UserID),
+		zap.String("platform", device.Platform),
+	)
+	s.notifyDevice(ctx, models.EventDeviceTokenRefreshed, device)
+
+	response := toDeviceResponse(device)
+	response.Replaced = true
+	return response, nil
+}
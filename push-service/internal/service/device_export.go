@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"push-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// exportPageSize is how many devices an export reads at a time
+const exportPageSize = 1000
+
+func (s *deviceService) ListDevices(ctx context.Context, filter models.DeviceFilter, cursor string, limit int) (*models.DevicePage, error) {
+	if cursor != "" {
+		if _, err := uuid.Parse(cursor); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+	}
+
+	devices, err := s.deviceRepo.ListAfter(ctx, filter, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.DevicePage{Devices: devices, Count: len(devices)}
+	if len(devices) == limit {
+		page.NextCursor = devices[len(devices)-1].ID
+	}
+	return page, nil
+}
+
+func (s *deviceService) ExportDevices(ctx context.Context, filter models.DeviceFilter, cursor string, write func([]models.Device) error) error {
+	if cursor != "" {
+		if _, err := uuid.Parse(cursor); err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+	}
+
+	for {
+		devices, err := s.deviceRepo.ListAfter(ctx, filter, cursor, exportPageSize)
+		if err != nil {
+			return err
+		}
+		if len(devices) == 0 {
+			return nil
+		}
+		if err := write(devices); err != nil {
+			return err
+		}
+		if len(devices) < exportPageSize {
+			return nil
+		}
+		cursor = devices[len(devices)-1].ID
+	}
+}
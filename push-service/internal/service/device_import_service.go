@@ -0,0 +1,351 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/repository"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnknownImportFormat is returned for imports that are neither NDJSON
+	// nor CSV
+	ErrUnknownImportFormat = errors.New("unknown import format")
+	// ErrInvalidImportFile is returned when an import file can't be read as
+	// its format, e.g. a CSV file without the device columns
+	ErrInvalidImportFile = errors.New("invalid import file")
+	// ErrDeviceImportNotFound is returned for imports that don't exist
+	ErrDeviceImportNotFound = errors.New("device import not found")
+)
+
+// maxImportLine bounds an NDJSON line
+const maxImportLine = 1 << 20
+
+// DeviceImportService imports device tokens in bulk from NDJSON or CSV
+// files, e.g. when migrating from another push provider
+type DeviceImportService interface {
+	// Import streams rows of user_id, token and platform from r, and
+	// upserts the valid ones in batches. Invalid rows are counted and
+	// skipped. The import is returned, with its outcome, whenever it was
+	// started, even if it failed.
+	Import(ctx context.Context, format string, r io.Reader) (*models.DeviceImport, error)
+	GetImport(ctx context.Context, id string) (*models.DeviceImport, error)
+	ListImports(ctx context.Context, limit int) ([]models.DeviceImport, error)
+}
+
+type deviceImportService struct {
+	deviceRepo repository.DeviceRepository
+	importRepo repository.DeviceImportRepository
+	batchSize  int
+	maxErrors  int
+}
+
+func NewDeviceImportService(deviceRepo repository.DeviceRepository, importRepo repository.DeviceImportRepository, cfg *config.Config) DeviceImportService {
+	batchSize := cfg.Devices.Import.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000 // default
+	}
+	maxErrors := cfg.Devices.Import.MaxErrors
+	if maxErrors <= 0 {
+		maxErrors = 100 // default
+	}
+
+	return &deviceImportService{
+		deviceRepo: deviceRepo,
+		importRepo: importRepo,
+		batchSize:  batchSize,
+		maxErrors:  maxErrors,
+	}
+}
+
+// importRowError is a row of an import file that is skipped
+type importRowError struct {
+	line int64
+	err  error
+}
+
+func (e *importRowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+// importReader reads the rows of an import file. A row that can't be
+// imported is returned as an *importRowError; io.EOF ends the file.
+type importReader interface {
+	next() (models.DeviceImportRow, int64, error)
+}
+
+func (s *deviceImportService) Import(ctx context.Context, format string, r io.Reader) (*models.DeviceImport, error) {
+	var reader importReader
+	switch format {
+	case models.DeviceImportFormatNDJSON:
+		reader = newNDJSONImportReader(r)
+	case models.DeviceImportFormatCSV:
+		reader = newCSVImportReader(r)
+	default:
+		return nil, fmt.Errorf("%w %q, use ndjson or csv", ErrUnknownImportFormat, format)
+	}
+
+	deviceImport := &models.DeviceImport{
+		Format: format,
+		Status: models.DeviceImportStatusRunning,
+		Errors: []models.DeviceImportError{},
+	}
+	if err := s.importRepo.Create(ctx, deviceImport); err != nil {
+		return nil, err
+	}
+	zap.L().Info("Device import started", zap.String("import_id", deviceImport.ID), zap.String("format", format))
+
+	if err := s.run(ctx, deviceImport, reader); err != nil {
+		// The request may be gone, the import's outcome is still stored
+		s.finish(context.WithoutCancel(ctx), deviceImport, err)
+		return deviceImport, err
+	}
+
+	s.finish(ctx, deviceImport, nil)
+	return deviceImport, nil
+}
+
+// run imports every row of the file, storing progress after each batch
+func (s *deviceImportService) run(ctx context.Context, deviceImport *models.DeviceImport, reader importReader) error {
+	batch := make([]models.DeviceImportRow, 0, s.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		inserted, updated, err := s.deviceRepo.UpsertBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		deviceImport.Inserted += inserted
+		deviceImport.Updated += updated
+		batch = batch[:0]
+		return s.importRepo.Update(ctx, deviceImport)
+	}
+
+	for {
+		row, line, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *importRowError
+		if errors.As(err, &rowErr) {
+			deviceImport.RowsRead++
+			s.reject(deviceImport, rowErr.line, rowErr.err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		deviceImport.RowsRead++
+		if err := validateImportRow(&row); err != nil {
+			s.reject(deviceImport, line, err)
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == s.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// reject counts a row that is skipped, keeping the first maxErrors reasons
+func (s *deviceImportService) reject(deviceImport *models.DeviceImport, line int64, err error) {
+	deviceImport.Invalid++
+	if len(deviceImport.Errors) < s.maxErrors {
+		deviceImport.Errors = append(deviceImport.Errors, models.DeviceImportError{Line: line, Error: err.Error()})
+	}
+}
+
+// finish stores the import's outcome
+func (s *deviceImportService) finish(ctx context.Context, deviceImport *models.DeviceImport, importErr error) {
+	now := time.Now().UTC()
+	deviceImport.CompletedAt = &now
+	deviceImport.Status = models.DeviceImportStatusCompleted
+	if importErr != nil {
+		message := importErr.Error()
+		deviceImport.Status = models.DeviceImportStatusFailed
+		deviceImport.Error = &message
+	}
+
+	if err := s.importRepo.Update(ctx, deviceImport); err != nil {
+		zap.L().Error("Failed to store device import outcome", zap.String("import_id", deviceImport.ID), zap.Error(err))
+	}
+
+	zap.L().Info("Device import finished",
+		zap.String("import_id", deviceImport.ID),
+		zap.String("status", deviceImport.Status),
+		zap.Int64("rows_read", deviceImport.RowsRead),
+		zap.Int64("inserted", deviceImport.Inserted),
+		zap.Int64("updated", deviceImport.Updated),
+		zap.Int64("invalid", deviceImport.Invalid),
+		zap.Error(importErr),
+	)
+}
+
+func (s *deviceImportService) GetImport(ctx context.Context, id string) (*models.DeviceImport, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrDeviceImportNotFound
+	}
+
+	deviceImport, err := s.importRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deviceImport == nil {
+		return nil, ErrDeviceImportNotFound
+	}
+	return deviceImport, nil
+}
+
+func (s *deviceImportService) ListImports(ctx context.Context, limit int) ([]models.DeviceImport, error) {
+	return s.importRepo.List(ctx, limit)
+}
+
+// validateImportRow trims the row's fields and checks they make a device
+// registration
+func validateImportRow(row *models.DeviceImportRow) error {
+	row.UserID = strings.TrimSpace(row.UserID)
+	row.Token = strings.TrimSpace(row.Token)
+	row.Platform = strings.ToLower(strings.TrimSpace(row.Platform))
+
+	switch {
+	case row.UserID == "":
+		return errors.New("user_id is required")
+	case len(row.UserID) > 255:
+		return errors.New("user_id is longer than 255 characters")
+	case row.Token == "":
+		return errors.New("token is required")
+	}
+	switch row.Platform {
+	case "ios", "android", "web":
+	default:
+		return fmt.Errorf("unknown platform %q", row.Platform)
+	}
+	return nil
+}
+
+// ndjsonImportReader reads one JSON object per line; blank lines are skipped
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int64
+}
+
+func newNDJSONImportReader(r io.Reader) *ndjsonImportReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	return &ndjsonImportReader{scanner: scanner}
+}
+
+func (r *ndjsonImportReader) next() (models.DeviceImportRow, int64, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var row models.DeviceImportRow
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			return row, r.line, &importRowError{line: r.line, err: err}
+		}
+		return row, r.line, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return models.DeviceImportRow{}, r.line + 1, fmt.Errorf("%w: line %d is longer than %d bytes", ErrInvalidImportFile, r.line+1, maxImportLine)
+		}
+		return models.DeviceImportRow{}, r.line, err
+	}
+	return models.DeviceImportRow{}, r.line, io.EOF
+}
+
+// csvImportReader reads a CSV file whose header names its columns. The
+// user_id, token and platform columns may come in any order; others are
+// ignored.
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	width   int // columns a row needs
+}
+
+func newCSVImportReader(r io.Reader) *csvImportReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return &csvImportReader{reader: reader}
+}
+
+func (r *csvImportReader) next() (models.DeviceImportRow, int64, error) {
+	if r.columns == nil {
+		if err := r.readHeader(); err != nil {
+			return models.DeviceImportRow{}, 1, err
+		}
+	}
+
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return models.DeviceImportRow{}, int64(parseErr.StartLine), &importRowError{line: int64(parseErr.StartLine), err: parseErr.Err}
+		}
+		return models.DeviceImportRow{}, 0, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	if len(record) < r.width {
+		return models.DeviceImportRow{}, int64(line), &importRowError{line: int64(line), err: fmt.Errorf("expected at least %d columns, got %d", r.width, len(record))}
+	}
+
+	row := models.DeviceImportRow{
+		UserID:   record[r.columns["user_id"]],
+		Token:    record[r.columns["token"]],
+		Platform: record[r.columns["platform"]],
+	}
+	return row, int64(line), nil
+}
+
+func (r *csvImportReader) readHeader() error {
+	header, err := r.reader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%w: the CSV file is empty", ErrInvalidImportFile)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: reading the CSV header: %v", ErrInvalidImportFile, err)
+	}
+
+	columns := make(map[string]int, 3)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "user_id", "token", "platform":
+			columns[name] = i
+			r.width = max(r.width, i+1)
+		}
+	}
+	for _, name := range []string{"user_id", "token", "platform"} {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("%w: the CSV header has no %s column", ErrInvalidImportFile, name)
+		}
+	}
+	r.columns = columns
+	return nil
+}
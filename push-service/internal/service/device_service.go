@@ -2,31 +2,55 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"push-service/internal/config"
 	"push-service/internal/models"
 	"push-service/internal/platform/fcm"
+	"push-service/internal/queue"
 	"push-service/internal/repository"
+	"push-service/pkg/metrics"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
+// ErrDeviceNotFound is returned when no active device has the given token
+var ErrDeviceNotFound = errors.New("device not found")
+
 type DeviceService interface {
 	RegisterDevice(ctx context.Context, req models.CreateDeviceRequest) (*models.DeviceResponse, error)
 	UnregisterDevice(ctx context.Context, token string) error
 	GetUserDevices(ctx context.Context, userID string) ([]models.DeviceResponse, error)
+	RefreshToken(ctx context.Context, req models.RefreshTokenRequest) (*models.DeviceResponse, error)
+	UpdatePermission(ctx context.Context, token, status string) error
+	GetDeviceConfig(ctx context.Context, token string) (*models.DeviceConfig, error)
+	// Heartbeat records that the app is still running on the device and
+	// returns when
+	Heartbeat(ctx context.Context, token string) (time.Time, error)
+	// ExportDevices passes the devices matching filter to write a page at a
+	// time, in ID order, starting after the device ID cursor if it is set
+	ExportDevices(ctx context.Context, filter models.DeviceFilter, cursor string, write func([]models.Device) error) error
+	// ListDevices returns a page of up to limit devices matching filter, in
+	// ID order, after the device ID cursor if it is set
+	ListDevices(ctx context.Context, filter models.DeviceFilter, cursor string, limit int) (*models.DevicePage, error)
 }
 
 type deviceService struct {
 	deviceRepo repository.DeviceRepository
 	fcmClient  fcm.FCMClient
+	pushQueue  *queue.PushQueue
+	webhooks   WebhookService
 	cfg        *config.Config
 }
 
-func NewDeviceService(deviceRepo repository.DeviceRepository, fcmClient fcm.FCMClient, cfg *config.Config) DeviceService {
+func NewDeviceService(deviceRepo repository.DeviceRepository, fcmClient fcm.FCMClient, pushQueue *queue.PushQueue, webhooks WebhookService, cfg *config.Config) DeviceService {
 	return &deviceService{
 		deviceRepo: deviceRepo,
 		fcmClient:  fcmClient,
+		pushQueue:  pushQueue,
+		webhooks:   webhooks,
 		cfg:        cfg,
 	}
 }
@@ -38,7 +62,7 @@ func (s *deviceService) RegisterDevice(ctx context.Context, req models.CreateDev
 			zap.L().Warn("Token validation failed during device registration",
 				zap.String("user_id", req.UserID),
 				zap.String("platform", req.Platform),
-				zap.String("token", maskToken(req.Token)),
+				zap.String("token", req.Token),
 				zap.Error(err),
 			)
 			return nil, fmt.Errorf("token validation failed: %w", err)
@@ -49,6 +73,14 @@ func (s *deviceService) RegisterDevice(ctx context.Context, req models.CreateDev
 		)
 	}
 
+	if req.DeviceID != nil {
+		return s.registerStableDevice(ctx, req)
+	}
+	return s.registerToken(ctx, req)
+}
+
+// registerToken registers req's token, or updates its registration
+func (s *deviceService) registerToken(ctx context.Context, req models.CreateDeviceRequest) (*models.DeviceResponse, error) {
 	// Check if device already exists
 	existingDevice, err := s.deviceRepo.GetByToken(ctx, req.Token)
 	if err != nil {
@@ -60,21 +92,61 @@ func (s *deviceService) RegisterDevice(ctx context.Context, req models.CreateDev
 		if err := s.deviceRepo.UpdateStatus(ctx, req.Token, true); err != nil {
 			return nil, err
 		}
+		lastSeenAt, err := s.deviceRepo.Touch(ctx, req.Token)
+		if err != nil {
+			return nil, err
+		}
+		permissionStatus := existingDevice.PermissionStatus
+		if req.PermissionStatus != nil {
+			if err := s.deviceRepo.UpdatePermission(ctx, req.Token, *req.PermissionStatus); err != nil {
+				return nil, err
+			}
+			permissionStatus = req.PermissionStatus
+		}
+		timezone := existingDevice.Timezone
+		if req.Timezone != nil {
+			if err := s.deviceRepo.UpdateTimezone(ctx, req.Token, *req.Timezone); err != nil {
+				return nil, err
+			}
+			timezone = req.Timezone
+		}
+		if !req.DeviceMetadata.IsEmpty() {
+			if err := s.deviceRepo.UpdateMetadata(ctx, req.Token, req.DeviceMetadata); err != nil {
+				return nil, err
+			}
+		}
 		return &models.DeviceResponse{
-			ID:       existingDevice.ID,
-			UserID:   existingDevice.UserID,
-			Token:    existingDevice.Token,
-			Platform: existingDevice.Platform,
-			IsActive: true,
+			ID:               existingDevice.ID,
+			UserID:           existingDevice.UserID,
+			Token:            existingDevice.Token,
+			Platform:         existingDevice.Platform,
+			IsActive:         true,
+			PermissionStatus: permissionStatus,
+			Timezone:         timezone,
+			LastSeenAt:       &lastSeenAt,
+			DeviceMetadata:   mergeMetadata(existingDevice.DeviceMetadata, req.DeviceMetadata),
 		}, nil
 	}
 
+	// A token that was unregistered before (e.g. the app was reinstalled)
+	// gets its old device back, with the state hanging off it
+	deactivated, err := s.deviceRepo.FindDeactivated(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if deactivated != nil {
+		return s.resurrectDevice(ctx, deactivated, req)
+	}
+
 	// Create new device
 	device := &models.Device{
-		UserID:   req.UserID,
-		Token:    req.Token,
-		Platform: req.Platform,
-		IsActive: true,
+		UserID:           req.UserID,
+		Token:            req.Token,
+		Platform:         req.Platform,
+		IsActive:         true,
+		PermissionStatus: req.PermissionStatus,
+		Timezone:         req.Timezone,
+		DeviceMetadata:   req.DeviceMetadata,
 	}
 
 	if err := s.deviceRepo.Create(ctx, device); err != nil {
@@ -85,36 +157,153 @@ func (s *deviceService) RegisterDevice(ctx context.Context, req models.CreateDev
 		zap.String("user_id", req.UserID),
 		zap.String("platform", req.Platform),
 	)
+	s.notifyDevice(ctx, models.EventDeviceRegistered, device)
+
+	return toDeviceResponse(device), nil
+}
 
+// resurrectDevice reactivates a deactivated device for req and emits a
+// device.resurrected event. The device keeps its ID, so preferences and
+// history recorded against it carry over.
+func (s *deviceService) resurrectDevice(ctx context.Context, device *models.Device, req models.CreateDeviceRequest) (*models.DeviceResponse, error) {
+	restored, err := s.deviceRepo.Reactivate(ctx, device.ID, req.UserID, req.Platform)
+	if err != nil {
+		return nil, err
+	}
+	if restored == nil {
+		return nil, ErrDeviceNotFound
+	}
+	if req.PermissionStatus != nil {
+		if err := s.deviceRepo.UpdatePermission(ctx, req.Token, *req.PermissionStatus); err != nil {
+			return nil, err
+		}
+		restored.PermissionStatus = req.PermissionStatus
+	}
+	if req.Timezone != nil {
+		if err := s.deviceRepo.UpdateTimezone(ctx, req.Token, *req.Timezone); err != nil {
+			return nil, err
+		}
+		restored.Timezone = req.Timezone
+	}
+	if !req.DeviceMetadata.IsEmpty() {
+		if err := s.deviceRepo.UpdateMetadata(ctx, req.Token, req.DeviceMetadata); err != nil {
+			return nil, err
+		}
+		restored.DeviceMetadata = mergeMetadata(restored.DeviceMetadata, req.DeviceMetadata)
+	}
+	metrics.DevicesResurrected.Inc()
+
+	data := map[string]any{
+		"device_id":        restored.ID,
+		"user_id":          restored.UserID,
+		"platform":         restored.Platform,
+		"previous_user_id": device.UserID,
+	}
+	if device.DeactivatedAt != nil {
+		data["deactivated_at"] = device.DeactivatedAt
+	}
+	if s.webhooks != nil {
+		s.webhooks.Notify(ctx, "", models.EventDeviceResurrected, data)
+	}
+	if s.pushQueue != nil {
+		// The device is restored either way; a lost event is only logged
+		if err := s.pushQueue.PublishEvent(ctx, models.EventDeviceResurrected, data); err != nil {
+			zap.L().Error("Failed to publish device.resurrected event",
+				zap.String("device_id", restored.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	zap.L().Info("Deactivated device restored",
+		zap.String("device_id", restored.ID),
+		zap.String("user_id", restored.UserID),
+		zap.String("previous_user_id", device.UserID),
+		zap.String("platform", restored.Platform),
+	)
+
+	response := toDeviceResponse(restored)
+	response.Resurrected = true
+	return response, nil
+}
+
+// notifyDevice sends a device lifecycle event to the webhooks registered
+// without a tenant
+func (s *deviceService) notifyDevice(ctx context.Context, eventType string, device *models.Device) {
+	if s.webhooks == nil {
+		return
+	}
+	s.webhooks.Notify(ctx, "", eventType, map[string]any{
+		"device_id": device.ID,
+		"user_id":   device.UserID,
+		"platform":  device.Platform,
+	})
+}
+
+func toDeviceResponse(device *models.Device) *models.DeviceResponse {
 	return &models.DeviceResponse{
-		ID:       device.ID,
-		UserID:   device.UserID,
-		Token:    device.Token,
-		Platform: device.Platform,
-		IsActive: device.IsActive,
-	}, nil
+		ID:               device.ID,
+		UserID:           device.UserID,
+		Token:            device.Token,
+		Platform:         device.Platform,
+		IsActive:         device.IsActive,
+		PermissionStatus: device.PermissionStatus,
+		Timezone:         device.Timezone,
+		LastSeenAt:       device.LastSeenAt,
+		DeviceID:         device.StableDeviceID,
+		DeviceMetadata:   device.DeviceMetadata,
+	}
 }
 
-// maskToken masks a token for logging
-func maskToken(token string) string {
-	if len(token) <= 20 {
-		return "***"
+// mergeMetadata applies reported metadata to the stored metadata the way
+// the repository's UpdateMetadata does
+func mergeMetadata(stored, reported models.DeviceMetadata) models.DeviceMetadata {
+	merged := stored
+	if reported.AppVersion != nil {
+		merged.AppVersion = reported.AppVersion
+	}
+	if reported.OSVersion != nil {
+		merged.OSVersion = reported.OSVersion
+	}
+	if reported.DeviceModel != nil {
+		merged.DeviceModel = reported.DeviceModel
 	}
-	return token[:10] + "..." + token[len(token)-10:]
+	if reported.Locale != nil {
+		merged.Locale = reported.Locale
+	}
+	if len(reported.Metadata) > 0 {
+		merged.Metadata = make(map[string]any, len(stored.Metadata)+len(reported.Metadata))
+		for key, value := range stored.Metadata {
+			merged.Metadata[key] = value
+		}
+		for key, value := range reported.Metadata {
+			merged.Metadata[key] = value
+		}
+	}
+	return merged
 }
 
 func (s *deviceService) UnregisterDevice(ctx context.Context, token string) error {
+	// Looked up first for the device.unregistered event
+	device, err := s.deviceRepo.GetByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
 	// Soft delete by setting is_active to false
-	err := s.deviceRepo.UpdateStatus(ctx, token, false)
+	err = s.deviceRepo.UpdateStatus(ctx, token, false)
 	if err != nil {
-		zap.L().Error("Failed to unregister device", 
-			zap.String("token", token), 
+		zap.L().Error("Failed to unregister device",
+			zap.String("token", token),
 			zap.Error(err),
 		)
 		return err
 	}
 
 	zap.L().Info("Device unregistered successfully", zap.String("token", token))
+	if device != nil {
+		s.notifyDevice(ctx, models.EventDeviceUnregistered, device)
+	}
 	return nil
 }
 
@@ -125,15 +314,84 @@ func (s *deviceService) GetUserDevices(ctx context.Context, userID string) ([]mo
 	}
 
 	responses := make([]models.DeviceResponse, len(devices))
-	for i, device := range devices {
-		responses[i] = models.DeviceResponse{
-			ID:       device.ID,
-			UserID:   device.UserID,
-			Token:    device.Token,
-			Platform: device.Platform,
-			IsActive: device.IsActive,
-		}
+	for i := range devices {
+		responses[i] = *toDeviceResponse(&devices[i])
 	}
 
 	return responses, nil
-}
\ No newline at end of file
+}
+
+// RefreshToken moves a device to the token FCM rotated it to
+func (s *deviceService) RefreshToken(ctx context.Context, req models.RefreshTokenRequest) (*models.DeviceResponse, error) {
+	if s.cfg != nil && s.cfg.Queue.Validation.Enabled && s.fcmClient != nil {
+		if err := s.fcmClient.ValidateToken(ctx, req.NewToken); err != nil {
+			zap.L().Warn("Token validation failed during token refresh",
+				zap.String("token", req.NewToken),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("token validation failed: %w", err)
+		}
+	}
+
+	device, err := s.deviceRepo.ReplaceToken(ctx, req.OldToken, req.NewToken)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	zap.L().Info("Device token refreshed",
+		zap.String("user_id", device.UserID),
+		zap.String("device_id", device.ID),
+	)
+	s.notifyDevice(ctx, models.EventDeviceTokenRefreshed, device)
+
+	return toDeviceResponse(device), nil
+}
+
+func (s *deviceService) UpdatePermission(ctx context.Context, token, status string) error {
+	if err := s.deviceRepo.UpdatePermission(ctx, token, status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrDeviceNotFound
+		}
+		return err
+	}
+
+	zap.L().Info("Device permission status updated",
+		zap.String("token", token),
+		zap.String("permission_status", status),
+	)
+	return nil
+}
+
+// GetDeviceConfig returns the settings the client SDK applies on the device
+func (s *deviceService) GetDeviceConfig(ctx context.Context, token string) (*models.DeviceConfig, error) {
+	device, err := s.deviceRepo.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	return &models.DeviceConfig{
+		DeviceID:                         device.ID,
+		Platform:                         device.Platform,
+		PermissionStatus:                 device.PermissionStatus,
+		PushEnabled:                      device.PermissionStatus == nil || *device.PermissionStatus != models.PermissionDenied,
+		TokenRefreshIntervalSeconds:      int64(s.cfg.SDK.TokenRefreshInterval.Seconds()),
+		PermissionRecheckIntervalSeconds: int64(s.cfg.SDK.PermissionRecheckInterval.Seconds()),
+	}, nil
+}
+
+func (s *deviceService) Heartbeat(ctx context.Context, token string) (time.Time, error) {
+	lastSeenAt, err := s.deviceRepo.Touch(ctx, token)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, ErrDeviceNotFound
+		}
+		return time.Time{}, err
+	}
+	return lastSeenAt, nil
+}
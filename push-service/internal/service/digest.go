@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"push-service/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// digestTitles is how many of the most recent titles {{titles}} lists
+const digestTitles = 3
+
+// digestable reports whether a send waits for the user's next digest
+// instead of being delivered on its own
+func (s *pushService) digestable(req models.SendPushRequest) bool {
+	return s.cfg.Digest.Enabled && req.Priority == models.PriorityLow && !req.DryRun && req.DeliverAtLocalTime == ""
+}
+
+// addToDigest buffers a send for the user's next digest and returns the
+// buffered item's ID
+func (s *pushService) addToDigest(ctx context.Context, req models.SendPushRequest) (string, error) {
+	item := &models.DigestItem{
+		TenantID: req.TenantID,
+		UserID:   req.UserID,
+		Title:    req.Title,
+		Body:     req.Body,
+	}
+	if err := s.digestRepo.Add(ctx, item); err != nil {
+		return "", fmt.Errorf("failed to add notification to digest: %w", err)
+	}
+
+	zap.L().Info("Push notification added to digest",
+		zap.String("digest_item_id", item.ID),
+		zap.String("user_id", req.UserID),
+	)
+	return item.ID, nil
+}
+
+// flushDigests delivers the digests of users whose first buffered
+// notification has waited for the digest interval
+func (s *schedulerService) flushDigests(ctx context.Context, batchSize int) {
+	interval := s.cfg.Digest.Interval
+	if interval <= 0 {
+		interval = time.Hour // default
+	}
+
+	items, err := s.digestRepo.ClaimDue(ctx, time.Now().Add(-interval), batchSize)
+	if err != nil {
+		zap.L().Error("Failed to load due digests", zap.Error(err))
+		return
+	}
+
+	digests := make(map[string][]models.DigestItem)
+	var order []string
+	for _, item := range items {
+		key := item.TenantID + "/" + item.UserID
+		if _, ok := digests[key]; !ok {
+			order = append(order, key)
+		}
+		digests[key] = append(digests[key], item)
+	}
+	for _, key := range order {
+		s.deliverDigest(ctx, digests[key])
+	}
+}
+
+// deliverDigest enqueues one user's digest. A single notification is sent
+// as it is; more are rolled up into the configured title and body. Items
+// that can't be enqueued are put back for the next tick.
+func (s *schedulerService) deliverDigest(ctx context.Context, items []models.DigestItem) {
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	userID := items[0].UserID
+
+	notification := models.PushNotification{
+		ID:     uuid.NewString(),
+		Title:  items[0].Title,
+		Body:   items[0].Body,
+		Status: "queued",
+	}
+	var vars map[string]string
+	if len(items) > 1 {
+		notification.Title = s.cfg.Digest.Title
+		notification.Body = s.cfg.Digest.Body
+		vars = map[string]string{
+			"count":  strconv.Itoa(len(items)),
+			"titles": digestTitleList(items),
+		}
+	}
+
+	messages, err := s.pushService.ResolveRecipients(ctx, []string{userID}, notification)
+	if err != nil {
+		zap.L().Error("Failed to resolve digest recipient, keeping its notifications",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		s.keepDigest(ctx, items)
+		return
+	}
+	message := messages[0]
+	if message == nil {
+		zap.L().Debug("Digest dropped, user has no devices", zap.String("user_id", userID))
+		return
+	}
+	message.Vars = vars
+	message.TenantID = items[0].TenantID
+	message.Priority = models.PriorityLow
+
+	if err := s.pushQueue.EnqueueMessage(ctx, *message); err != nil {
+		zap.L().Error("Failed to enqueue digest, keeping its notifications",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		s.keepDigest(ctx, items)
+		return
+	}
+
+	zap.L().Info("Digest enqueued",
+		zap.String("notification_id", notification.ID),
+		zap.String("user_id", userID),
+		zap.Int("notification_count", len(items)),
+	)
+}
+
+// keepDigest puts a digest's items back for the next tick
+func (s *schedulerService) keepDigest(ctx context.Context, items []models.DigestItem) {
+	for i := range items {
+		if err := s.digestRepo.Add(context.WithoutCancel(ctx), &items[i]); err != nil {
+			zap.L().Error("Lost digest notification",
+				zap.String("user_id", items[i].UserID),
+				zap.String("title", items[i].Title),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// digestTitleList joins the titles of the newest of items, which are sorted
+// oldest first, newest first
+func digestTitleList(items []models.DigestItem) string {
+	titles := make([]string, 0, digestTitles)
+	for i := len(items) - 1; i >= 0 && len(titles) < digestTitles; i-- {
+		titles = append(titles, items[i].Title)
+	}
+	return strings.Join(titles, ", ")
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInboxItemNotFound is returned when marking an item the user's inbox
+	// doesn't have
+	ErrInboxItemNotFound = errors.New("inbox item not found")
+	// ErrInvalidCursor is returned for pages after an unusable cursor
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// InboxService keeps every notification a user is sent in their inbox, with
+// its read state, for the app's notification center
+type InboxService interface {
+	// Record adds a notification to its user's inbox. Failures are logged,
+	// they don't hold up delivery.
+	Record(ctx context.Context, notification models.PushNotification)
+	ListInbox(ctx context.Context, userID, cursor string, limit int, unreadOnly bool) (*models.InboxPage, error)
+	MarkRead(ctx context.Context, userID, id string) error
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+}
+
+type inboxService struct {
+	inboxRepo repository.InboxRepository
+	enabled   bool
+}
+
+func NewInboxService(inboxRepo repository.InboxRepository, cfg *config.Config) InboxService {
+	return &inboxService{
+		inboxRepo: inboxRepo,
+		enabled:   cfg.Inbox.Enabled,
+	}
+}
+
+func (s *inboxService) Record(ctx context.Context, notification models.PushNotification) {
+	// Silent data messages have nothing to show
+	if !s.enabled || notification.UserID == "" || (notification.Title == "" && notification.Body == "") {
+		return
+	}
+
+	err := s.inboxRepo.Add(ctx, &models.InboxItem{
+		UserID:         notification.UserID,
+		NotificationID: notification.ID,
+		Title:          notification.Title,
+		Body:           notification.Body,
+		Image:          notification.Image,
+		Link:           notification.Link,
+		Data:           notification.Data,
+		Category:       notification.Category,
+	})
+	if err != nil {
+		zap.L().Warn("Failed to add notification to inbox",
+			zap.String("notification_id", notification.ID),
+			zap.String("user_id", notification.UserID),
+			zap.Error(err),
+		)
+	}
+}
+
+// ListInbox returns a page of the user's inbox, newest first, with their
+// unread count
+func (s *inboxService) ListInbox(ctx context.Context, userID, cursor string, limit int, unreadOnly bool) (*models.InboxPage, error) {
+	if cursor != "" {
+		if _, err := uuid.Parse(cursor); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+	}
+
+	items, err := s.inboxRepo.List(ctx, userID, cursor, limit, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.inboxRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.InboxPage{
+		UserID:      userID,
+		Items:       items,
+		UnreadCount: unread,
+	}
+	if len(items) == limit {
+		page.NextCursor = items[len(items)-1].ID
+	}
+	return page, nil
+}
+
+func (s *inboxService) MarkRead(ctx context.Context, userID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInboxItemNotFound
+	}
+	if err := s.inboxRepo.MarkRead(ctx, userID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInboxItemNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *inboxService) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	return s.inboxRepo.MarkAllRead(ctx, userID)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"push-service/internal/models"
+	"push-service/internal/queue"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidLocalTime is returned for sends with an unusable
+// deliver_at_local_time
+var ErrInvalidLocalTime = errors.New("invalid local time")
+
+// parseLocalTime parses an HH:MM time of day
+func parseLocalTime(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: want HH:MM, got %q", ErrInvalidLocalTime, value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// nextLocalTime returns the first time after now at which the clock in loc
+// reads hour:minute
+func nextLocalTime(now time.Time, loc *time.Location, hour, minute int) time.Time {
+	local := now.In(loc)
+	at := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !at.After(now) {
+		at = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+	}
+	return at.UTC()
+}
+
+// scheduleAtLocalTime holds message back until hour:minute in each of its
+// devices' timezones. Devices are grouped by when that is, and each group
+// is stored as its own message, whose TTL starts at its delivery.
+func (s *pushService) scheduleAtLocalTime(ctx context.Context, message queue.PushMessage, devices []models.Device, hour, minute int, ttl string) error {
+	defaultLoc, err := time.LoadLocation(s.cfg.Scheduler.DefaultTimezone)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	groups := make(map[time.Time][]models.Device)
+	var order []time.Time
+	for _, device := range devices {
+		loc := defaultLoc
+		if device.Timezone != nil {
+			if deviceLoc, err := time.LoadLocation(*device.Timezone); err == nil {
+				loc = deviceLoc
+			} else {
+				zap.L().Warn("Unknown device timezone, using the default",
+					zap.String("device_id", device.ID),
+					zap.String("timezone", *device.Timezone),
+				)
+			}
+		}
+		at := nextLocalTime(now, loc, hour, minute)
+		if _, ok := groups[at]; !ok {
+			order = append(order, at)
+		}
+		groups[at] = append(groups[at], device)
+	}
+
+	for _, at := range order {
+		group := message
+		group.DeviceTokens = make([]string, len(groups[at]))
+		for i, device := range groups[at] {
+			group.DeviceTokens[i] = device.Token
+		}
+		group.Platforms = devicePlatforms(groups[at])
+		expiresAt, err := parseTTL(ttl, at)
+		if err != nil {
+			return err
+		}
+		group.Notification.ExpiresAt = expiresAt
+
+		body, err := json.Marshal(group)
+		if err != nil {
+			return err
+		}
+		if err := s.scheduledRepo.Create(ctx, &models.ScheduledPush{DeliverAt: at, Message: body}); err != nil {
+			return err
+		}
+	}
+
+	zap.L().Info("Push notification scheduled for local time",
+		zap.String("notification_id", message.Notification.ID),
+		zap.String("user_id", message.Notification.UserID),
+		zap.Int("device_count", len(devices)),
+		zap.Int("delivery_times", len(order)),
+	)
+	return nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"push-service/internal/models"
+	"push-service/internal/repository"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrMuteNotFound is returned when unmuting a mute the user doesn't have
+	ErrMuteNotFound = errors.New("mute not found")
+	// ErrMuteExpired is returned when a mute would already be expired
+	ErrMuteExpired = errors.New("expires_at must be in the future")
+)
+
+type MuteService interface {
+	Mute(ctx context.Context, req models.CreateMuteRequest) (*models.UserMute, error)
+	Unmute(ctx context.Context, userID, muteID string) error
+	GetUserMutes(ctx context.Context, userID string) ([]models.UserMute, error)
+}
+
+type muteService struct {
+	muteRepo repository.MuteRepository
+}
+
+func NewMuteService(muteRepo repository.MuteRepository) MuteService {
+	return &muteService{muteRepo: muteRepo}
+}
+
+func (s *muteService) Mute(ctx context.Context, req models.CreateMuteRequest) (*models.UserMute, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrMuteExpired
+	}
+
+	mute := &models.UserMute{
+		UserID:    req.UserID,
+		Scope:     req.Scope,
+		Value:     req.Value,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.muteRepo.Upsert(ctx, mute); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("User mute saved",
+		zap.String("user_id", mute.UserID),
+		zap.String("scope", mute.Scope),
+		zap.String("value", mute.Value),
+	)
+
+	return mute, nil
+}
+
+func (s *muteService) Unmute(ctx context.Context, userID, muteID string) error {
+	if err := s.muteRepo.Delete(ctx, userID, muteID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMuteNotFound
+		}
+		return err
+	}
+
+	zap.L().Info("User mute removed", zap.String("user_id", userID), zap.String("mute_id", muteID))
+	return nil
+}
+
+func (s *muteService) GetUserMutes(ctx context.Context, userID string) ([]models.UserMute, error) {
+	mutes, err := s.muteRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mutes == nil {
+		mutes = []models.UserMute{}
+	}
+	return mutes, nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/queue"
+	"push-service/internal/repository"
+	"push-service/pkg/lock"
+	"push-service/pkg/metrics"
+	"push-service/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+const outboxRelayLockKey = "outbox-relay"
+
+// OutboxRelay publishes the outbox messages their sends couldn't publish,
+// e.g. because the broker was down or the instance stopped in between
+type OutboxRelay interface {
+	Run(ctx context.Context)
+}
+
+type outboxRelay struct {
+	outboxRepo repository.OutboxRepository
+	pushQueue  *queue.PushQueue
+	locker     lock.Locker
+	cfg        *config.OutboxConfig
+}
+
+func NewOutboxRelay(outboxRepo repository.OutboxRepository, pushQueue *queue.PushQueue, locker lock.Locker, cfg *config.Config) OutboxRelay {
+	return &outboxRelay{
+		outboxRepo: outboxRepo,
+		pushQueue:  pushQueue,
+		locker:     locker,
+		cfg:        &cfg.Outbox,
+	}
+}
+
+// Run relays pending messages every interval until ctx is cancelled. It
+// returns at once if the outbox is disabled.
+func (r *outboxRelay) Run(ctx context.Context) {
+	if !r.cfg.Enabled {
+		return
+	}
+
+	interval := r.cfg.RelayInterval
+	if interval <= 0 {
+		interval = time.Second // default
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	zap.L().Info("Outbox relay started", zap.Duration("interval", interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := lock.Run(ctx, r.locker, outboxRelayLockKey, 0, r.relay)
+			if err != nil && !errors.Is(err, lock.ErrNotAcquired) {
+				zap.L().Error("Failed to relay outbox messages", zap.Error(err))
+			}
+		}
+	}
+}
+
+// relay publishes pending messages in order, stopping at the first that
+// can't be published so it is retried first on the next tick
+func (r *outboxRelay) relay(ctx context.Context) error {
+	batchSize := r.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100 // default
+	}
+	delay := r.cfg.RelayDelay
+	if delay <= 0 {
+		delay = 10 * time.Second // default
+	}
+
+	for {
+		// Recent messages are still being published by their sends
+		pending, err := r.outboxRepo.ListPending(ctx, time.Now().Add(-delay), batchSize)
+		if err != nil {
+			return err
+		}
+
+		published := make([]int64, 0, len(pending))
+		var publishErr error
+		for _, outboxMessage := range pending {
+			var message queue.PushMessage
+			if err := json.Unmarshal(outboxMessage.Message, &message); err != nil {
+				zap.L().Error("Dropping undecodable outbox message", zap.Int64("outbox_id", outboxMessage.ID), zap.Error(err))
+				published = append(published, outboxMessage.ID)
+				continue
+			}
+
+			if publishErr = r.pushQueue.EnqueueMessage(tracing.Extract(ctx, message.TraceContext), message); publishErr != nil {
+				break
+			}
+			published = append(published, outboxMessage.ID)
+			metrics.OutboxRelayed.Inc()
+			zap.L().Info("Outbox message relayed",
+				zap.Int64("outbox_id", outboxMessage.ID),
+				zap.String("notification_id", message.Notification.ID),
+				zap.Duration("age", time.Since(outboxMessage.CreatedAt)),
+			)
+		}
+
+		if len(published) > 0 {
+			if err := r.outboxRepo.MarkPublished(ctx, published); err != nil {
+				// They are published again on the next tick
+				return err
+			}
+		}
+		if publishErr != nil {
+			return publishErr
+		}
+		if len(pending) < batchSize {
+			return nil
+		}
+	}
+}
+
+// enqueue queues a send's message. With the outbox, the message is stored
+// with its notification's record first, then published; a message that
+// can't be published now is published by the outbox relay, so it is queued
+// once enqueue returns nil.
+func (s *pushService) enqueue(ctx context.Context, message queue.PushMessage) error {
+	if !s.cfg.Outbox.Enabled {
+		return s.pushQueue.EnqueueMessage(ctx, message)
+	}
+
+	if message.Notification.CreatedAt.IsZero() {
+		message.Notification.CreatedAt = time.Now().UTC()
+	}
+	// The relay publishes under the send's trace
+	message.TraceContext = tracing.Inject(ctx)
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	id, err := s.outboxRepo.Add(ctx, message.Notification, body)
+	if err != nil {
+		return err
+	}
+
+	if err := s.pushQueue.EnqueueMessage(ctx, message); err != nil {
+		zap.L().Warn("Failed to enqueue push notification, leaving it to the outbox relay",
+			zap.String("notification_id", message.Notification.ID),
+			zap.Int64("outbox_id", id),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	if err := s.outboxRepo.MarkPublished(context.WithoutCancel(ctx), []int64{id}); err != nil {
+		zap.L().Warn("Published outbox message left pending, the relay will publish it again",
+			zap.String("notification_id", message.Notification.ID),
+			zap.Int64("outbox_id", id),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// enqueueBatch queues a bulk send's messages like enqueue, publishing them in
+// one call, and returns how many were queued. With the outbox, messages are
+// queued once stored; if storing one fails, those stored before it are still
+// published and the error is returned.
+func (s *pushService) enqueueBatch(ctx context.Context, messages []queue.PushMessage) (int, error) {
+	if !s.cfg.Outbox.Enabled {
+		if err := s.pushQueue.EnqueuePushBatch(ctx, messages); err != nil {
+			return 0, err
+		}
+		return len(messages), nil
+	}
+
+	traceContext := tracing.Inject(ctx)
+	ids := make([]int64, 0, len(messages))
+	var addErr error
+	for i := range messages {
+		message := &messages[i]
+		if message.Notification.CreatedAt.IsZero() {
+			message.Notification.CreatedAt = time.Now().UTC()
+		}
+		message.TraceContext = traceContext
+
+		body, err := json.Marshal(message)
+		if err != nil {
+			addErr = err
+			break
+		}
+		id, err := s.outboxRepo.Add(ctx, message.Notification, body)
+		if err != nil {
+			addErr = err
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return 0, addErr
+	}
+
+	if err := s.pushQueue.EnqueuePushBatch(ctx, messages[:len(ids)]); err != nil {
+		zap.L().Warn("Failed to enqueue bulk push batch, leaving it to the outbox relay",
+			zap.Int("batch_size", len(ids)),
+			zap.Error(err),
+		)
+		return len(ids), addErr
+	}
+
+	if err := s.outboxRepo.MarkPublished(context.WithoutCancel(ctx), ids); err != nil {
+		zap.L().Warn("Published outbox messages left pending, the relay will publish them again",
+			zap.Int("batch_size", len(ids)),
+			zap.Error(err),
+		)
+	}
+	return len(ids), addErr
+}
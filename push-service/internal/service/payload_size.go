@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/platform/fcm"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// ErrPayloadTooLarge is returned for sends whose payload FCM would reject
+// as too large
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// fitPayload checks that a notification's payload fits in the configured
+// maximum and returns its data. Under the truncate policy, data keys are
+// dropped, largest first, until it fits; it is only rejected if it still
+// doesn't fit without them, e.g. because of a long body.
+func (s *pushService) fitPayload(notification models.PushNotification) (map[string]any, error) {
+	maxSize := s.cfg.FCM.MaxPayloadSize
+	if maxSize <= 0 {
+		maxSize = fcm.MaxPayloadSize // default
+	}
+
+	size := fcm.PayloadSize(notification)
+	if size <= maxSize {
+		return notification.Data, nil
+	}
+	if s.cfg.FCM.OversizePayload != config.OversizePayloadTruncate || len(notification.Data) == 0 {
+		return nil, fmt.Errorf("%w: %d bytes, the limit is %d", ErrPayloadTooLarge, size, maxSize)
+	}
+
+	sizes := fcm.DataSizes(notification)
+	keys := make([]string, 0, len(sizes))
+	for key := range sizes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return sizes[keys[i]] > sizes[keys[j]] })
+
+	data := make(map[string]any, len(notification.Data))
+	for key, value := range notification.Data {
+		data[key] = value
+	}
+	notification.Data = data
+
+	var dropped []string
+	for _, key := range keys {
+		delete(data, key)
+		dropped = append(dropped, key)
+		if size = fcm.PayloadSize(notification); size <= maxSize {
+			break
+		}
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes without its data, the limit is %d", ErrPayloadTooLarge, size, maxSize)
+	}
+
+	metrics.PayloadsTruncated.Inc()
+	zap.L().Warn("Payload too large, data truncated",
+		zap.String("user_id", notification.UserID),
+		zap.Strings("dropped_keys", dropped),
+		zap.Int("size", size),
+		zap.Int("max_size", maxSize),
+	)
+	return data, nil
+}
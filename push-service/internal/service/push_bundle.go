@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"push-service/internal/models"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/queue"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// SendBundle enqueues req's items as one unit under a shared bundle ID. Silent
+// items are ordered before visible ones, so a device that gets the alert has
+// already received the data it depends on.
+func (s *pushService) SendBundle(ctx context.Context, req models.SendBundleRequest) (string, error) {
+	if reason := s.dropReason(ctx, req.UserID, req.Sender, req.Category); reason != "" {
+		return "", &DroppedError{UserID: req.UserID, Reason: reason}
+	}
+
+	tenant, err := softLaunchTenant(ctx, s.tenantRepo, s.cfg, req.TenantID)
+	if err != nil {
+		return "", err
+	}
+
+	var devices []models.Device
+	if tenant != nil {
+		// Routed to the tenant's test devices, see sendSoftLaunch
+		devices, err = s.testDevices(ctx, tenant, nil)
+	} else {
+		devices, err = s.deviceRepo.GetByUserID(ctx, req.UserID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrSoftLaunchNoTestUsers) {
+			return "", err
+		}
+		return "", fmt.Errorf("database error: %w", err)
+	}
+
+	var deviceTokens []string
+	for _, device := range devices {
+		if len(req.Platforms) == 0 || containsString(req.Platforms, device.Platform) {
+			deviceTokens = append(deviceTokens, device.Token)
+		}
+	}
+	if len(deviceTokens) == 0 {
+		return "", fmt.Errorf("no devices found for user: %s", req.UserID)
+	}
+
+	if tenant != nil {
+		if err := s.reserveSoftLaunchSends(ctx, tenant, len(req.Items)*len(deviceTokens)); err != nil {
+			return "", err
+		}
+	}
+
+	bundleID := uuid.NewString()
+	items := make([]models.BundleItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		if item.Title == "" && item.Body == "" {
+			items = append(items, item)
+		}
+	}
+	for _, item := range req.Items {
+		if item.Title != "" || item.Body != "" {
+			items = append(items, item)
+		}
+	}
+
+	bundle := make([]models.PushNotification, len(items))
+	for i, item := range items {
+		// Clients use these to de-duplicate retried items and to wait for the rest
+		data := make(map[string]any, len(item.Data)+3)
+		for key, value := range item.Data {
+			data[key] = value
+		}
+		data["bundle_id"] = bundleID
+		data["bundle_index"] = strconv.Itoa(i)
+		data["bundle_size"] = strconv.Itoa(len(items))
+
+		bundle[i] = models.PushNotification{
+			UserID:   req.UserID,
+			Title:    item.Title,
+			Body:     item.Body,
+			Image:    item.Image,
+			Link:     item.Link,
+			Data:     data,
+			Sender:   req.Sender,
+			Category: req.Category,
+			BundleID: &bundleID,
+			Status:   "queued",
+		}
+	}
+
+	if err := s.pushQueue.EnqueueBundle(ctx, bundle, deviceTokens, req.Priority); err != nil {
+		return "", fmt.Errorf("failed to enqueue push bundle: %w", err)
+	}
+
+	zap.L().Info("Push bundle enqueued successfully",
+		zap.String("user_id", req.UserID),
+		zap.String("bundle_id", bundleID),
+		zap.Int("item_count", len(bundle)),
+		zap.Int("device_count", len(deviceTokens)),
+	)
+
+	return bundleID, nil
+}
+
+// processBundle delivers a bundle device by device, item by item. A device
+// whose item fails gets none of the remaining items and is retried with the
+// whole bundle.
+func (s *pushService) processBundle(ctx context.Context, delivery queue.Delivery, pushMessage queue.PushMessage) error {
+	client := s.projects.Client(pushMessage.ProjectID)
+	deviceTokens := pushMessage.DeviceTokens
+	bundleID := ""
+	if pushMessage.Notification.BundleID != nil {
+		bundleID = *pushMessage.Notification.BundleID
+	}
+
+	if s.cfg != nil && s.cfg.Queue.Validation.Enabled {
+		var unregistered []string
+		deviceTokens, unregistered = s.validateTokens(ctx, client, deviceTokens)
+		if len(deviceTokens) == 0 {
+			zap.L().Warn("No valid tokens found for bundle",
+				zap.String("bundle_id", bundleID),
+				zap.Int("original_count", len(pushMessage.DeviceTokens)),
+			)
+			// Tokens FCM rejected for good are never retried
+			retry := pushMessage
+			retry.DeviceTokens = withoutTokens(pushMessage.DeviceTokens, unregistered)
+			if len(retry.DeviceTokens) > 0 {
+				if err := s.pushQueue.EnqueueRetry(ctx, retry); err != nil {
+					zap.L().Error("Failed to enqueue to retry/dead letter", zap.Error(err))
+				}
+			}
+			if err := delivery.Ack(); err != nil {
+				zap.L().Error("Failed to ack message", zap.Error(err))
+			}
+			return fmt.Errorf("no valid tokens")
+		}
+	}
+
+	sent := 0
+	var failed []string
+	for i, token := range deviceTokens {
+		for _, item := range pushMessage.Bundle {
+			err := client.Send(ctx, token, item)
+			if errors.Is(err, fcm.ErrProviderThrottled) {
+				// Resending before FCM's Retry-After would fail again
+				waitForThrottle(ctx, client)
+			}
+			if errors.Is(err, fcm.ErrProviderAuth) || errors.Is(err, fcm.ErrProviderThrottled) {
+				// Put the devices not yet served back untouched; the worker
+				// pauses until the credentials or the quota recover.
+				s.recordUsage(ctx, pushMessage.ProjectID, sent)
+				remaining := pushMessage
+				remaining.DeviceTokens = append(failed, deviceTokens[i:]...)
+				if err := s.pushQueue.Requeue(ctx, remaining); err != nil {
+					zap.L().Error("Failed to requeue bundle", zap.Error(err))
+					if err := delivery.Nack(true); err != nil {
+						zap.L().Error("Failed to nack message", zap.Error(err))
+					}
+					return err
+				}
+				if err := delivery.Ack(); err != nil {
+					zap.L().Error("Failed to ack message", zap.Error(err))
+				}
+				return err
+			}
+			if fcm.IsInvalidToken(err) {
+				// The device gets none of the remaining items, now or later
+				s.unregisterTokens(ctx, []string{token})
+				break
+			}
+			if err != nil {
+				failed = append(failed, token)
+				break
+			}
+			sent++
+		}
+	}
+	s.recordUsage(ctx, pushMessage.ProjectID, sent)
+
+	if len(failed) > 0 {
+		zap.L().Warn("Bundle failed for some devices, enqueuing them for retry",
+			zap.String("bundle_id", bundleID),
+			zap.Int("failed_count", len(failed)),
+			zap.Int("device_count", len(deviceTokens)),
+		)
+		retry := pushMessage
+		retry.DeviceTokens = failed
+		if err := s.pushQueue.EnqueueRetry(ctx, retry); err != nil {
+			zap.L().Error("Failed to enqueue retry", zap.Error(err))
+		}
+	}
+
+	zap.L().Info("Push bundle processed",
+		zap.String("user_id", pushMessage.Notification.UserID),
+		zap.String("bundle_id", bundleID),
+		zap.Int("device_count", len(deviceTokens)),
+		zap.Int("failed_count", len(failed)),
+	)
+
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
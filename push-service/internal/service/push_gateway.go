@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/gateway"
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// rejectMalformed moves a gateway message that can never be processed to
+// the malformed queue, with why, and acks it
+func (s *pushService) rejectMalformed(ctx context.Context, delivery queue.Delivery, reason error) error {
+	malformed := queue.MalformedMessage{
+		Source: queue.GatewayPushQueueName,
+		Error:  reason.Error(),
+		Body:   string(delivery.Body),
+	}
+	var schemaErr *gateway.SchemaError
+	if errors.As(reason, &schemaErr) {
+		malformed.SchemaVersion = schemaErr.SchemaVersion
+		malformed.Violations = schemaErr.Violations
+	}
+
+	if err := s.pushQueue.PublishMalformed(ctx, malformed); err != nil {
+		zap.L().Error("Failed to publish malformed gateway message",
+			zap.String("message_id", delivery.ID),
+			zap.Error(err),
+		)
+		// Try again later rather than lose the message
+		if err := delivery.Nack(true); err != nil {
+			zap.L().Error("Failed to nack gateway message", zap.Error(err))
+		}
+		return err
+	}
+
+	metrics.MalformedMessages.WithLabelValues("gateway").Inc()
+	zap.L().Warn("Malformed gateway message moved to the malformed queue",
+		zap.String("message_id", delivery.ID),
+		zap.Strings("violations", malformed.Violations),
+		zap.Error(reason),
+	)
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack gateway message", zap.Error(err))
+	}
+	return fmt.Errorf("malformed gateway message: %w", reason)
+}
+
+// gatewayContent returns the title and body of a gateway message from its
+// template, with the template's variables substituted from the message's
+// data, or generic ones if it has no template
+func gatewayContent(message *models.GatewayPushMessage) (string, string) {
+	title := "Notification"
+	body := "You have a new notification"
+	template := message.Template
+	if template == nil {
+		return title, body
+	}
+
+	if template.Subject != "" {
+		title = template.Subject
+	}
+	// Template service returns 'html_body', not 'body'
+	if template.HTMLBody != "" {
+		body = template.HTMLBody
+	} else if template.Body != "" {
+		body = template.Body
+	}
+
+	for _, name := range template.Variables {
+		if value, ok := message.Data[name].(string); ok {
+			placeholder := "{{" + name + "}}"
+			body = strings.ReplaceAll(body, placeholder, value)
+			title = strings.ReplaceAll(title, placeholder, value)
+		}
+	}
+	return title, body
+}
+
+// gatewayBinding returns the binding of push.queue a gateway message was
+// routed by, the first whose routing key matches its own, or nil if none
+// does, e.g. on brokers without exchanges
+func (s *pushService) gatewayBinding(routingKey string) *config.GatewayBindingConfig {
+	if s.cfg == nil || routingKey == "" {
+		return nil
+	}
+	gatewayCfg := &s.cfg.Queue.Gateway
+	for i, binding := range gatewayCfg.Bindings {
+		if queue.MatchesBinding(gatewayCfg.ExchangeType, binding.RoutingKey, routingKey) {
+			return &gatewayCfg.Bindings[i]
+		}
+	}
+	return nil
+}
+
+// gatewayMode returns how the messages of binding are processed
+func (s *pushService) gatewayMode(binding *config.GatewayBindingConfig) string {
+	if binding != nil && binding.Mode != "" {
+		return binding.Mode
+	}
+	if s.cfg == nil || s.cfg.Queue.Gateway.Mode == "" {
+		return config.GatewayModeEnqueue
+	}
+	return s.cfg.Queue.Gateway.Mode
+}
+
+// gatewayPolicy returns how a gateway message routed by binding, which may
+// be nil, is delivered: its priority, or its binding's, or its type's, its
+// type's TTL as an expiry, and its category, or its type. Messages without
+// a type have their binding's.
+func (s *pushService) gatewayPolicy(message *models.GatewayPushMessage, binding *config.GatewayBindingConfig, now time.Time) (priority string, expiresAt *time.Time, category *string) {
+	var cfg config.GatewayConfig
+	if s.cfg != nil {
+		cfg = s.cfg.Queue.Gateway
+	}
+	messageType := message.Type
+	if messageType == "" && binding != nil {
+		messageType = binding.Type
+	}
+
+	priority = gateway.Priority(message.Priority)
+	if priority == "" && binding != nil {
+		priority = binding.Priority
+	}
+	if priority == "" {
+		priority = cfg.TypePriorities[messageType]
+	}
+	if ttl := cfg.TypeTTLs[messageType]; ttl > 0 {
+		expiry := now.Add(ttl)
+		expiresAt = &expiry
+	}
+	category = optionalString(message.Category)
+	if category == nil {
+		category = optionalString(messageType)
+	}
+	return priority, expiresAt, category
+}
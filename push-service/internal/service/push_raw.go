@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"push-service/internal/models"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/queue"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidRawMessage is returned for raw sends that aren't an FCM message
+// with exactly one target
+var ErrInvalidRawMessage = errors.New("invalid raw FCM message")
+
+// SendRaw enqueues a complete FCM message, which goes through the queue,
+// retries and delivery attempts like any notification, and returns the
+// notification ID its attempts are recorded under
+func (s *pushService) SendRaw(ctx context.Context, req models.RawPushRequest) (string, error) {
+	message, err := parseRawMessage(req.Message)
+	if err != nil {
+		return "", err
+	}
+
+	tenant, err := softLaunchTenant(ctx, s.tenantRepo, s.cfg, req.TenantID)
+	if err != nil {
+		return "", err
+	}
+	if tenant != nil {
+		return "", ErrSoftLaunchRaw
+	}
+
+	pushMessage := queue.PushMessage{
+		Notification: models.PushNotification{
+			ID:     uuid.NewString(),
+			UserID: req.UserID,
+			Status: "queued",
+		},
+		Raw:      req.Message,
+		TenantID: req.TenantID,
+		Priority: req.Priority,
+	}
+	if message.Token != "" {
+		// Lets the worker unregister the token if FCM rejects it
+		pushMessage.DeviceTokens = []string{message.Token}
+	}
+
+	if err := s.pushQueue.EnqueueMessage(ctx, pushMessage); err != nil {
+		return "", fmt.Errorf("failed to enqueue raw message: %w", err)
+	}
+
+	zap.L().Info("Raw FCM message enqueued",
+		zap.String("notification_id", pushMessage.Notification.ID),
+		zap.String("topic", message.Topic),
+	)
+	s.notifyQueued(ctx, req.TenantID, pushMessage.Notification)
+	return pushMessage.Notification.ID, nil
+}
+
+// parseRawMessage parses an FCM v1 message and checks it has one target
+func parseRawMessage(raw json.RawMessage) (*messaging.Message, error) {
+	var message messaging.Message
+	if err := json.Unmarshal(raw, &message); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRawMessage, err)
+	}
+
+	targets := 0
+	for _, target := range []string{message.Token, message.Topic, message.Condition} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return nil, fmt.Errorf("%w: exactly one of token, topic and condition is required", ErrInvalidRawMessage)
+	}
+	return &message, nil
+}
+
+// processRaw sends a raw FCM message. Like a notification, it is retried
+// through the retry tiers, requeued while FCM is paused and recorded as a
+// delivery attempt.
+func (s *pushService) processRaw(ctx context.Context, delivery queue.Delivery, pushMessage queue.PushMessage) error {
+	message, err := parseRawMessage(pushMessage.Raw)
+	if err != nil {
+		zap.L().Error("Failed to parse raw FCM message", zap.Error(err))
+		// Nack and don't requeue - message is malformed
+		if err := delivery.Nack(false); err != nil {
+			zap.L().Error("Failed to nack malformed message", zap.Error(err))
+		}
+		return err
+	}
+
+	client := s.projects.Client(pushMessage.ProjectID)
+	messageID, sendErr := client.SendRaw(ctx, message)
+	results := rawResults(message, messageID, sendErr)
+
+	switch {
+	case errors.Is(sendErr, fcm.ErrProviderThrottled), errors.Is(sendErr, fcm.ErrProviderAuth):
+		// Not a message problem: put it back untouched once FCM takes
+		// sends again, instead of burning retries
+		if errors.Is(sendErr, fcm.ErrProviderThrottled) {
+			waitForThrottle(ctx, client)
+		}
+		s.recordAttempt(ctx, delivery, pushMessage, results, sendErr, queue.PushQueueFor(pushMessage.Priority))
+		if err := s.pushQueue.Requeue(ctx, pushMessage); err != nil {
+			zap.L().Error("Failed to requeue raw message", zap.Error(err))
+			if err := delivery.Nack(true); err != nil {
+				zap.L().Error("Failed to nack message", zap.Error(err))
+			}
+			return err
+		}
+	case fcm.IsInvalidToken(sendErr):
+		// Retrying an unregistered token can't succeed
+		s.unregisterTokens(ctx, pushMessage.DeviceTokens)
+		s.recordAttempt(ctx, delivery, pushMessage, results, sendErr, "")
+	case sendErr != nil:
+		s.recordAttempt(ctx, delivery, pushMessage, results, sendErr, s.pushQueue.RetryQueue(pushMessage))
+		if err := s.pushQueue.EnqueueRetry(ctx, pushMessage); err != nil {
+			zap.L().Error("Failed to enqueue retry", zap.Error(err))
+		}
+	default:
+		s.recordUsage(ctx, pushMessage.ProjectID, 1)
+		s.recordAttempt(ctx, delivery, pushMessage, results, nil, "")
+	}
+
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+		return err
+	}
+	return sendErr
+}
+
+// rawResults returns the outcome of a raw send for its delivery attempt.
+// Topics and conditions are recorded as they are; tokens are masked.
+func rawResults(message *messaging.Message, messageID string, sendErr error) []models.AttemptTokenResult {
+	response := &messaging.BatchResponse{
+		Responses: []*messaging.SendResponse{{Success: sendErr == nil, MessageID: messageID, Error: sendErr}},
+	}
+	results := attemptResults([]string{message.Token}, response, sendErr)
+	switch {
+	case message.Topic != "":
+		results[0].Token = "topic:" + message.Topic
+	case message.Condition != "":
+		results[0].Token = "condition:" + message.Condition
+	}
+	return results
+}
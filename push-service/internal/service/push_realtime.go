@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/pkg/metrics"
+	"push-service/pkg/realtime"
+
+	"go.uber.org/zap"
+)
+
+// publishRealtime sends the notification to the user's realtime sessions on
+// every replica, and reports whether its push should be skipped because the
+// message asked for that and the user is connected
+func (s *pushService) publishRealtime(ctx context.Context, message queue.PushMessage, notification models.PushNotification) bool {
+	if s.realtime == nil || notification.UserID == "" {
+		return false
+	}
+
+	if err := s.realtime.Publish(ctx, notification.UserID, realtime.EventNotification, notification); err != nil {
+		// The push still goes out
+		zap.L().Warn("Failed to publish realtime notification",
+			zap.String("notification_id", notification.ID),
+			zap.String("user_id", notification.UserID),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	return message.SuppressIfConnected && s.realtime.Connected(ctx, notification.UserID)
+}
+
+// skipConnected settles a message whose push isn't needed, since the user
+// got it over a realtime connection
+func (s *pushService) skipConnected(delivery queue.Delivery, notification models.PushNotification) error {
+	zap.L().Info("Notification dropped",
+		zap.String("notification_id", notification.ID),
+		zap.String("user_id", notification.UserID),
+		zap.String("reason", models.DropReasonConnected),
+	)
+	metrics.NotificationsDropped.WithLabelValues(models.DropReasonConnected).Inc()
+
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+	}
+	return nil
+}
@@ -2,79 +2,261 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"push-service/internal/config"
+	"push-service/internal/gateway"
 	"push-service/internal/models"
 	"push-service/internal/platform/fcm"
+	"push-service/internal/platform/resolver"
 	"push-service/internal/queue"
 	"push-service/internal/repository"
+	"push-service/pkg/eventsink"
+	"push-service/pkg/metrics"
+	"push-service/pkg/realtime"
+	"push-service/pkg/tracing"
 
-	amqp "github.com/rabbitmq/amqp091-go"
+	"firebase.google.com/go/v4/messaging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 type PushService interface {
-	SendPush(ctx context.Context, req models.SendPushRequest) error
-	SendBulkPush(ctx context.Context, req models.BulkPushRequest) error
-	ProcessPushFromQueue(ctx context.Context, delivery amqp.Delivery) error
-	ProcessGatewayMessage(ctx context.Context, delivery amqp.Delivery) error
+	SendPush(ctx context.Context, req models.SendPushRequest) (string, error)
+	SendBulkPush(ctx context.Context, req models.BulkPushRequest) (string, error)
+	GetDryRun(ctx context.Context, id string) (*models.DryRun, error)
+	GetNotification(ctx context.Context, id string) (*models.NotificationDetail, error)
+	GetNotificationTokens(ctx context.Context, id, token string) (*models.NotificationTokenResults, error)
+	ListDeliveryEvents(ctx context.Context, after int64, limit int) (*models.DeliveryEvents, error)
+	SendBundle(ctx context.Context, req models.SendBundleRequest) (string, error)
+	SendRaw(ctx context.Context, req models.RawPushRequest) (string, error)
+	ResolveRecipients(ctx context.Context, userIDs []string, notification models.PushNotification) ([]*queue.PushMessage, error)
+	ProcessPushFromQueue(ctx context.Context, delivery queue.Delivery) error
+	ProcessGatewayMessage(ctx context.Context, delivery queue.Delivery) error
 	GetQueueStats(ctx context.Context) (map[string]int64, error)
 }
 
+var ErrDryRunNotFound = errors.New("dry run not found")
+
+// ErrInvalidRetryPolicy is returned for sends with an unusable retry override
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+// ErrInvalidPlatformOverrides is returned for sends whose platform overrides
+// don't fit FCM's platform configs
+var ErrInvalidPlatformOverrides = errors.New("invalid platform overrides")
+
+// DroppedError reports a notification that was deliberately not delivered,
+// e.g. because the user muted its sender. It is not a delivery failure.
+type DroppedError struct {
+	UserID string
+	Reason string
+}
+
+func (e *DroppedError) Error() string {
+	return fmt.Sprintf("notification for user %s dropped: %s", e.UserID, e.Reason)
+}
+
 type pushService struct {
-	deviceRepo repository.DeviceRepository
-	fcmClient  fcm.FCMClient
-	pushQueue  *queue.PushQueue
-	cfg        *config.Config
+	deviceRepo    repository.DeviceRepository
+	muteRepo      repository.MuteRepository
+	quotaRepo     repository.QuotaRepository
+	dryRunRepo    repository.DryRunRepository
+	tenantRepo    repository.TenantRepository
+	attemptRepo   repository.DeliveryAttemptRepository
+	analyticsRepo repository.AnalyticsRepository
+	scheduledRepo repository.ScheduledPushRepository
+	digestRepo    repository.DigestRepository
+	outboxRepo    repository.OutboxRepository
+	frequencyRepo repository.FrequencyRepository
+	fcmClient     fcm.FCMClient
+	projects      *fcm.Projects
+	userResolver  resolver.UserResolver // nil if not configured
+	alerts        AlertService
+	webhooks      WebhookService
+	inbox         InboxService
+	userData      UserDataService
+	realtime      *realtime.Hub   // nil if realtime delivery is disabled
+	events        *eventsink.Sink // nil if no event sink is configured
+	pushQueue     *queue.PushQueue
+	cfg           *config.Config
 }
 
-func NewPushService(deviceRepo repository.DeviceRepository, fcmClient fcm.FCMClient, pushQueue *queue.PushQueue, cfg *config.Config) PushService {
+// PushDeps are the repositories and collaborators the push service is built
+// on
+type PushDeps struct {
+	DeviceRepo    repository.DeviceRepository
+	MuteRepo      repository.MuteRepository
+	QuotaRepo     repository.QuotaRepository
+	DryRunRepo    repository.DryRunRepository
+	TenantRepo    repository.TenantRepository
+	AttemptRepo   repository.DeliveryAttemptRepository
+	AnalyticsRepo repository.AnalyticsRepository
+	ScheduledRepo repository.ScheduledPushRepository
+	DigestRepo    repository.DigestRepository
+	OutboxRepo    repository.OutboxRepository
+	FrequencyRepo repository.FrequencyRepository
+	Projects      *fcm.Projects
+	UserResolver  resolver.UserResolver // nil if not configured
+	Alerts        AlertService
+	Webhooks      WebhookService
+	Inbox         InboxService
+	UserData      UserDataService
+	Realtime      *realtime.Hub   // nil if realtime delivery is disabled
+	Events        *eventsink.Sink // nil if no event sink is configured
+	PushQueue     *queue.PushQueue
+}
+
+func NewPushService(deps PushDeps, cfg *config.Config) PushService {
 	return &pushService{
-		deviceRepo: deviceRepo,
-		fcmClient:  fcmClient,
-		pushQueue:  pushQueue,
-		cfg:        cfg,
+		deviceRepo:    deps.DeviceRepo,
+		muteRepo:      deps.MuteRepo,
+		quotaRepo:     deps.QuotaRepo,
+		dryRunRepo:    deps.DryRunRepo,
+		tenantRepo:    deps.TenantRepo,
+		attemptRepo:   deps.AttemptRepo,
+		analyticsRepo: deps.AnalyticsRepo,
+		scheduledRepo: deps.ScheduledRepo,
+		digestRepo:    deps.DigestRepo,
+		outboxRepo:    deps.OutboxRepo,
+		frequencyRepo: deps.FrequencyRepo,
+		fcmClient:     deps.Projects.Client(deps.Projects.PrimaryID()),
+		projects:      deps.Projects,
+		userResolver:  deps.UserResolver,
+		alerts:        deps.Alerts,
+		webhooks:      deps.Webhooks,
+		inbox:         deps.Inbox,
+		userData:      deps.UserData,
+		realtime:      deps.Realtime,
+		events:        deps.Events,
+		pushQueue:     deps.PushQueue,
+		cfg:           cfg,
 	}
 }
 
-func (s *pushService) SendPush(ctx context.Context, req models.SendPushRequest) error {
-	zap.L().Debug("=== SEND PUSH START ===",
-		zap.String("user_id", req.UserID),
-		zap.String("title", req.Title),
-		zap.String("body", req.Body),
-		zap.Any("data", req.Data),
-		zap.Strings("platforms", req.Platforms),
+// resolveUserTokens asks the external user resolver for the tokens of a user
+// with no registered devices. Errors are logged and yield no tokens, so the
+// caller can still fall back to the message's push_token.
+func (s *pushService) resolveUserTokens(ctx context.Context, userID string) []string {
+	if s.userResolver == nil {
+		return nil
+	}
+
+	tokens, err := s.userResolver.ResolveTokens(ctx, userID)
+	if err != nil {
+		metrics.UserResolutions.WithLabelValues("error").Inc()
+		zap.L().Warn("Failed to resolve user tokens externally",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return nil
+	}
+	if len(tokens) == 0 {
+		metrics.UserResolutions.WithLabelValues("not_found").Inc()
+		return nil
+	}
+	metrics.UserResolutions.WithLabelValues("resolved").Inc()
+	return tokens
+}
+
+// dropReason returns why a notification from sender/category must not reach
+// userID, or "" if it may be delivered. Lookup errors fail open so a database
+// hiccup can't silently swallow notifications.
+func (s *pushService) dropReason(ctx context.Context, userID string, sender, category *string) string {
+	mute, err := s.muteRepo.FindActive(ctx, userID, sender, category)
+	if err != nil {
+		zap.L().Warn("Failed to check user mutes, delivering anyway",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return ""
+	}
+	if mute == nil {
+		return ""
+	}
+
+	reason := models.DropReasonMutedCategory
+	if mute.Scope == models.MuteScopeSender {
+		reason = models.DropReasonMutedSender
+	}
+	metrics.NotificationsDropped.WithLabelValues(reason).Inc()
+	zap.L().Info("Notification dropped",
+		zap.String("user_id", userID),
+		zap.String("reason", reason),
+		zap.String("mute_id", mute.ID),
 	)
+	return reason
+}
+
+// SendPush enqueues a notification for the user's devices and returns its
+// ID, or for a dry run the dry run's ID.
+func (s *pushService) SendPush(ctx context.Context, req models.SendPushRequest) (string, error) {
+	retryBackoff, err := parseRetryBackoff(req.RetryBackoff)
+	if err != nil {
+		return "", err
+	}
+	expiresAt, err := parseTTL(req.TTL, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if err := fcm.ValidateOverrides(req.PlatformOverrides); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPlatformOverrides, err)
+	}
+	if req.Data, err = s.fitPayload(models.PushNotification{
+		UserID:      req.UserID,
+		Title:       req.Title,
+		Body:        req.Body,
+		Image:       req.Image,
+		Link:        req.Link,
+		Data:        req.Data,
+		ClickAction: req.ClickAction,
+		Actions:     req.Actions,
+	}); err != nil {
+		return "", err
+	}
+	var localHour, localMinute int
+	if req.DeliverAtLocalTime != "" {
+		if localHour, localMinute, err = parseLocalTime(req.DeliverAtLocalTime); err != nil {
+			return "", err
+		}
+	}
+
+	if reason := s.dropReason(ctx, req.UserID, req.Sender, req.Category); reason != "" {
+		return "", &DroppedError{UserID: req.UserID, Reason: reason}
+	}
+
+	tenant, err := softLaunchTenant(ctx, s.tenantRepo, s.cfg, req.TenantID)
+	if err != nil {
+		return "", err
+	}
+	if tenant != nil && !req.DryRun {
+		return s.sendSoftLaunch(ctx, tenant, req, retryBackoff, expiresAt)
+	}
 
 	// Get user's devices
 	devices, err := s.deviceRepo.GetByUserID(ctx, req.UserID)
 	if err != nil {
-		zap.L().Error("❌ FAILED to get user devices from database",
+		zap.L().Error("Failed to get user devices",
 			zap.String("user_id", req.UserID),
 			zap.Error(err),
 		)
-		return fmt.Errorf("database error: %w", err)
+		return "", fmt.Errorf("database error: %w", err)
 	}
 
-	zap.L().Debug("📱 Database query result",
-		zap.String("user_id", req.UserID),
-		zap.Int("device_count", len(devices)),
-		zap.Any("devices", devices), // Log all devices found
-	)
-
 	if len(devices) == 0 {
-		zap.L().Warn("⚠️ No devices found for user", zap.String("user_id", req.UserID))
-		return fmt.Errorf("no devices found for user: %s", req.UserID)
+		zap.L().Warn("No devices found for user", zap.String("user_id", req.UserID))
+		return "", fmt.Errorf("no devices found for user: %s", req.UserID)
 	}
 
 	// Filter by platform if specified
 	var targetDevices []models.Device
 	if len(req.Platforms) > 0 {
-		zap.L().Debug("🔍 Filtering devices by platforms", zap.Strings("platforms", req.Platforms))
 		for _, device := range devices {
 			for _, platform := range req.Platforms {
 				if device.Platform == platform {
@@ -87,63 +269,177 @@ func (s *pushService) SendPush(ctx context.Context, req models.SendPushRequest)
 		targetDevices = devices
 	}
 
-	zap.L().Debug("🎯 Devices after filtering",
-		zap.Int("original_count", len(devices)),
-		zap.Int("filtered_count", len(targetDevices)),
-	)
-
 	if len(targetDevices) == 0 {
-		zap.L().Error("❌ No devices match the specified platforms",
+		zap.L().Error("No devices match the specified platforms",
 			zap.String("user_id", req.UserID),
 			zap.Strings("requested_platforms", req.Platforms),
 			zap.Any("available_platforms", getPlatforms(devices)),
 		)
-		return fmt.Errorf("no devices match platforms: %v", req.Platforms)
+		return "", fmt.Errorf("no devices match platforms: %v", req.Platforms)
+	}
+
+	if s.digestable(req) {
+		return s.addToDigest(ctx, req)
 	}
 
 	// Extract device tokens
 	deviceTokens := make([]string, len(targetDevices))
 	for i, device := range targetDevices {
 		deviceTokens[i] = device.Token
-		zap.L().Debug("📲 Device token",
-			zap.String("platform", device.Platform),
-			zap.String("token", device.Token), // Log full token for debugging
-		)
 	}
 
 	// Create notification
 	notification := models.PushNotification{
-		UserID: req.UserID,
-		Title:  req.Title,
-		Body:   req.Body,
-		Image:  req.Image,
-		Link:   req.Link,
-		Data:   req.Data,
-		Status: "queued",
+		ID:                uuid.NewString(),
+		UserID:            req.UserID,
+		Title:             req.Title,
+		Body:              req.Body,
+		Image:             req.Image,
+		Link:              req.Link,
+		Data:              req.Data,
+		Sender:            req.Sender,
+		Category:          req.Category,
+		CollapseKey:       req.CollapseKey,
+		ExpiresAt:         expiresAt,
+		ClickAction:       req.ClickAction,
+		Actions:           req.Actions,
+		IOS:               req.IOS,
+		PlatformOverrides: req.PlatformOverrides,
+		AnalyticsLabel:    req.AnalyticsLabel,
+		Status:            "queued",
 	}
 
-	zap.L().Info("🚀 Enqueuing push notification to RabbitMQ",
-		zap.String("user_id", req.UserID),
-		zap.Int("device_count", len(deviceTokens)),
-		zap.String("title", req.Title),
-	)
+	if req.DryRun {
+		dryRunID, err := s.enqueueDryRun(ctx, []queue.PushMessage{{
+			Notification: notification,
+			DeviceTokens: deviceTokens,
+		}})
+		if err != nil {
+			return "", fmt.Errorf("failed to enqueue dry run: %w", err)
+		}
+		return dryRunID, nil
+	}
+
+	message := queue.PushMessage{
+		Notification: notification,
+		DeviceTokens: deviceTokens,
+		Platforms:    devicePlatforms(targetDevices),
+		TenantID:     req.TenantID,
+		Priority:     req.Priority,
+		MaxRetries:   req.MaxRetries,
+		RetryBackoff: retryBackoff,
+
+		SuppressIfConnected: req.SuppressPushIfConnected,
+		BypassQuietHours:    req.BypassQuietHours,
+		BypassFrequencyCap:  req.BypassFrequencyCap,
+	}
+
+	if req.DeliverAtLocalTime != "" {
+		if err := s.scheduleAtLocalTime(ctx, message, targetDevices, localHour, localMinute, req.TTL); err != nil {
+			return "", fmt.Errorf("failed to schedule push notification: %w", err)
+		}
+		s.notifyQueued(ctx, req.TenantID, notification)
+		return notification.ID, nil
+	}
 
-	// Enqueue to RabbitMQ instead of sending directly
-	if err := s.pushQueue.EnqueuePush(ctx, notification, deviceTokens); err != nil {
-		zap.L().Error("💥 Failed to enqueue push notification",
+	// Enqueue instead of sending directly
+	if err := s.enqueue(ctx, message); err != nil {
+		zap.L().Error("Failed to enqueue push notification",
 			zap.String("user_id", req.UserID),
 			zap.Int("device_count", len(deviceTokens)),
 			zap.Error(err),
 		)
-		return fmt.Errorf("failed to enqueue push notification: %w", err)
+		return "", fmt.Errorf("failed to enqueue push notification: %w", err)
 	}
 
-	zap.L().Info("✅ Push notification enqueued successfully",
+	zap.L().Info("Push notification enqueued",
+		zap.String("notification_id", notification.ID),
 		zap.String("user_id", req.UserID),
 		zap.Int("device_count", len(deviceTokens)),
 	)
+	s.notifyQueued(ctx, req.TenantID, notification)
 
-	return nil
+	return notification.ID, nil
+}
+
+// sendSoftLaunch enqueues a notification of a tenant in soft launch for its
+// test users' devices instead of the user's, within its daily send cap
+func (s *pushService) sendSoftLaunch(ctx context.Context, tenant *models.Tenant, req models.SendPushRequest, retryBackoff time.Duration, expiresAt *time.Time) (string, error) {
+	devices, err := s.testDevices(ctx, tenant, req.Platforms)
+	if err != nil {
+		return "", err
+	}
+	if err := s.reserveSoftLaunchSends(ctx, tenant, len(devices)); err != nil {
+		return "", err
+	}
+
+	deviceTokens := make([]string, len(devices))
+	for i, device := range devices {
+		deviceTokens[i] = device.Token
+	}
+
+	notification := models.PushNotification{
+		ID:                uuid.NewString(),
+		UserID:            req.UserID,
+		Title:             req.Title,
+		Body:              req.Body,
+		Image:             req.Image,
+		Link:              req.Link,
+		Data:              req.Data,
+		Sender:            req.Sender,
+		Category:          req.Category,
+		CollapseKey:       req.CollapseKey,
+		ExpiresAt:         expiresAt,
+		ClickAction:       req.ClickAction,
+		Actions:           req.Actions,
+		IOS:               req.IOS,
+		PlatformOverrides: req.PlatformOverrides,
+		AnalyticsLabel:    req.AnalyticsLabel,
+		Status:            "queued",
+	}
+	if err := s.enqueue(ctx, queue.PushMessage{
+		Notification: notification,
+		DeviceTokens: deviceTokens,
+		Platforms:    devicePlatforms(devices),
+		TenantID:     req.TenantID,
+		Priority:     req.Priority,
+		MaxRetries:   req.MaxRetries,
+		RetryBackoff: retryBackoff,
+
+		BypassQuietHours:   req.BypassQuietHours,
+		BypassFrequencyCap: req.BypassFrequencyCap,
+	}); err != nil {
+		return "", fmt.Errorf("failed to enqueue push notification: %w", err)
+	}
+	s.notifyQueued(ctx, req.TenantID, notification)
+
+	zap.L().Info("Soft launch push notification routed to test devices",
+		zap.String("tenant_id", tenant.ID),
+		zap.String("user_id", req.UserID),
+		zap.Int("device_count", len(deviceTokens)),
+	)
+	return notification.ID, nil
+}
+
+// parseRetryBackoff parses a send's retry_backoff override; empty means none
+func parseRetryBackoff(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	backoff, err := time.ParseDuration(value)
+	if err != nil || backoff <= 0 {
+		return 0, fmt.Errorf("%w: retry_backoff must be a positive duration, got %q", ErrInvalidRetryPolicy, value)
+	}
+	return backoff, nil
+}
+
+// devicePlatforms maps each device's token to its platform
+func devicePlatforms(devices []models.Device) map[string]string {
+	platforms := make(map[string]string, len(devices))
+	for _, device := range devices {
+		platforms[device.Token] = device.Platform
+	}
+	return platforms
 }
 
 // Helper function to get unique platforms from devices
@@ -160,80 +456,361 @@ func getPlatforms(devices []models.Device) []string {
 	return result
 }
 
-func (s *pushService) SendBulkPush(ctx context.Context, req models.BulkPushRequest) error {
+// SendBulkPush enqueues a notification for each user's devices. For a dry
+// run it returns the dry run's ID; broadcasts of tenants in soft launch are
+// always dry runs.
+func (s *pushService) SendBulkPush(ctx context.Context, req models.BulkPushRequest) (string, error) {
+	var err error
+	if req.Data, err = s.fitPayload(models.PushNotification{Title: req.Title, Body: req.Body, Data: req.Data}); err != nil {
+		return "", err
+	}
+
+	tenant, err := softLaunchTenant(ctx, s.tenantRepo, s.cfg, req.TenantID)
+	if err != nil {
+		return "", err
+	}
+	dryRun := req.DryRun || tenant != nil
+
 	// For bulk pushes, use the queue for better scalability
 	baseNotification := models.PushNotification{
-		Title:  req.Title,
-		Body:   req.Body,
-		Data:   req.Data,
-		Status: "queued",
+		Title:          req.Title,
+		Body:           req.Body,
+		Data:           req.Data,
+		Sender:         req.Sender,
+		Category:       req.Category,
+		AnalyticsLabel: req.AnalyticsLabel,
+		Status:         "queued",
 	}
 
+	batchSize := s.cfg.Queue.Bulk.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100 // default
+	}
+
+	userIDs := append([]string{}, req.UserIDs...)
+	vars := make([]map[string]string, len(userIDs), len(userIDs)+len(req.Users))
+	for _, user := range req.Users {
+		userIDs = append(userIDs, user.UserID)
+		vars = append(vars, user.Vars)
+	}
+
+	messages, err := s.ResolveRecipients(ctx, userIDs, baseNotification)
+	if err != nil {
+		return "", err
+	}
+
+	resolved := make([]queue.PushMessage, 0, len(messages))
+	for i, message := range messages {
+		if message != nil {
+			message.Notification.ID = uuid.NewString()
+			message.Vars = vars[i]
+			message.TenantID = req.TenantID
+			message.Priority = req.Priority
+			message.BypassQuietHours = req.BypassQuietHours
+			message.BypassFrequencyCap = req.BypassFrequencyCap
+			resolved = append(resolved, *message)
+		}
+	}
+
+	if dryRun {
+		dryRunID, err := s.enqueueDryRun(ctx, resolved)
+		if err != nil {
+			return "", fmt.Errorf("failed to enqueue dry run: %w", err)
+		}
+		return dryRunID, nil
+	}
+
+	// Enqueue in batches, stopping at the first that fails so the users
+	// before it are the ones queued
 	enqueuedCount := 0
-	for _, userID := range req.UserIDs {
-		devices, err := s.deviceRepo.GetByUserID(ctx, userID)
+	for start := 0; start < len(resolved); start += batchSize {
+		end := start + batchSize
+		if end > len(resolved) {
+			end = len(resolved)
+		}
+
+		batch := resolved[start:end]
+		queued, err := s.enqueueBatch(ctx, batch)
+		enqueuedCount += queued
 		if err != nil {
-			zap.L().Error("Failed to get devices for user",
-				zap.String("user_id", userID),
+			zap.L().Error("Failed to enqueue bulk push batch",
+				zap.Int("batch_size", len(batch)),
+				zap.Int("enqueued_users", enqueuedCount),
+				zap.Int("resolved_users", len(resolved)),
 				zap.Error(err),
 			)
-			continue
+			return "", fmt.Errorf("enqueued %d of %d users: %w", enqueuedCount, len(resolved), err)
 		}
+	}
 
-		if len(devices) == 0 {
-			zap.L().Debug("No devices found for user", zap.String("user_id", userID))
-			continue
-		}
+	zap.L().Info("Bulk push enqueuing completed",
+		zap.Int("enqueued_users", enqueuedCount),
+		zap.Int("resolved_users", len(resolved)),
+		zap.Int("total_users", len(userIDs)),
+	)
 
-		deviceTokens := make([]string, len(devices))
-		for i, device := range devices {
-			deviceTokens[i] = device.Token
+	return "", nil
+}
+
+// enqueueDryRun enqueues messages as one dry run and returns its ID. Dry runs
+// go through the queue and the worker like regular sends, but the worker only
+// validates them with FCM.
+func (s *pushService) enqueueDryRun(ctx context.Context, messages []queue.PushMessage) (string, error) {
+	dryRunID := uuid.NewString()
+	for i := range messages {
+		messages[i].DryRunID = dryRunID
+	}
+
+	if len(messages) > 0 {
+		if err := s.pushQueue.EnqueuePushBatch(ctx, messages); err != nil {
+			return "", err
 		}
+	}
+	if err := s.dryRunRepo.Create(ctx, dryRunID, len(messages)); err != nil {
+		return "", err
+	}
 
-		userNotification := baseNotification
-		userNotification.UserID = userID
+	zap.L().Info("Dry run enqueued",
+		zap.String("dry_run_id", dryRunID),
+		zap.Int("message_count", len(messages)),
+	)
+	return dryRunID, nil
+}
 
-		// Enqueue to RabbitMQ
-		if err := s.pushQueue.EnqueuePush(ctx, userNotification, deviceTokens); err != nil {
-			zap.L().Error("Failed to enqueue push for user",
-				zap.String("user_id", userID),
-				zap.Error(err),
-			)
+// GetDryRun returns a dry run with the per-token results recorded so far
+func (s *pushService) GetDryRun(ctx context.Context, id string) (*models.DryRun, error) {
+	dryRun, err := s.dryRunRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun == nil {
+		return nil, ErrDryRunNotFound
+	}
+	return dryRun, nil
+}
+
+// processDryRun validates a dry run message with FCM (validate_only) and
+// records the result for each token. Nothing is delivered, retried, counted
+// against the quota or unregistered.
+func (s *pushService) processDryRun(ctx context.Context, delivery queue.Delivery, pushMessage queue.PushMessage) error {
+	client := s.projects.Client(pushMessage.ProjectID)
+	notification := renderVars(pushMessage.Notification, pushMessage.Vars)
+	deviceTokens := pushMessage.DeviceTokens
+
+	response, err := client.SendMulticastDryRun(ctx, deviceTokens, notification)
+	if errors.Is(err, fcm.ErrProviderThrottled) {
+		// Validating again is harmless; just not before FCM allows it
+		waitForThrottle(ctx, client)
+	}
+	if errors.Is(err, fcm.ErrProviderAuth) || errors.Is(err, fcm.ErrProviderThrottled) {
+		// Same as a regular send: wait for the credentials to recover
+		if err := delivery.Nack(true); err != nil {
+			zap.L().Error("Failed to nack message", zap.Error(err))
+		}
+		return err
+	}
+
+	results := make([]models.DryRunResult, len(deviceTokens))
+	for i, token := range deviceTokens {
+		results[i] = models.DryRunResult{UserID: notification.UserID, Token: token}
+		var sendErr error
+		switch {
+		case err != nil:
+			sendErr = err
+		case response != nil && i < len(response.Responses):
+			sendErr = response.Responses[i].Error
+		}
+		if sendErr == nil {
+			results[i].Valid = true
 			continue
 		}
+		kind := string(fcm.ClassifyError(sendErr))
+		message := sendErr.Error()
+		results[i].ErrorKind = &kind
+		results[i].Error = &message
+	}
 
-		enqueuedCount++
-		zap.L().Info("Bulk push enqueued for user",
-			zap.String("user_id", userID),
-			zap.Int("device_count", len(deviceTokens)),
+	if err := s.dryRunRepo.AddResults(ctx, pushMessage.DryRunID, results); err != nil {
+		zap.L().Error("Failed to record dry run results",
+			zap.String("dry_run_id", pushMessage.DryRunID),
+			zap.Error(err),
 		)
+		if err := delivery.Nack(true); err != nil {
+			zap.L().Error("Failed to nack message", zap.Error(err))
+		}
+		return fmt.Errorf("failed to record dry run results: %w", err)
 	}
 
-	zap.L().Info("Bulk push enqueuing completed",
-		zap.Int("enqueued_users", enqueuedCount),
-		zap.Int("total_users", len(req.UserIDs)),
+	zap.L().Info("Dry run message validated",
+		zap.String("dry_run_id", pushMessage.DryRunID),
+		zap.String("user_id", notification.UserID),
+		zap.Int("device_count", len(deviceTokens)),
 	)
 
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ResolveRecipients builds one queue message per user. Devices are read
+// LookupSize users per query, and mutes checked with bounded concurrency.
+// The result is aligned with userIDs; users who muted the notification or
+// have no devices get a nil entry. It fails if a device lookup fails, so
+// callers don't take the users of that lookup for handled.
+func (s *pushService) ResolveRecipients(ctx context.Context, userIDs []string, notification models.PushNotification) ([]*queue.PushMessage, error) {
+	lookupSize := s.cfg.Queue.Bulk.LookupSize
+	if lookupSize <= 0 {
+		lookupSize = 1000 // default
+	}
+
+	messages := make([]*queue.PushMessage, len(userIDs))
+	for start := 0; start < len(userIDs); start += lookupSize {
+		end := min(start+lookupSize, len(userIDs))
+		if err := s.resolveChunk(ctx, userIDs[start:end], notification, messages[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	return messages, nil
+}
+
+// resolveChunk fills messages, aligned with userIDs, for one device lookup
+func (s *pushService) resolveChunk(ctx context.Context, userIDs []string, notification models.PushNotification, messages []*queue.PushMessage) error {
+	concurrency := s.cfg.Queue.Bulk.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10 // default
+	}
+
+	devicesByUser, err := s.deviceRepo.GetByUserIDs(ctx, userIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get devices for %d users: %w", len(userIDs), err)
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, userID := range userIDs {
+		if userID == "" {
+			// Blanked out when the user's data was deleted
+			continue
+		}
+		devices := devicesByUser[userID]
+		if len(devices) == 0 {
+			zap.L().Debug("No devices found for user", zap.String("user_id", userID))
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, userID string, devices []models.Device) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if reason := s.dropReason(ctx, userID, notification.Sender, notification.Category); reason != "" {
+				return
+			}
+
+			deviceTokens := make([]string, len(devices))
+			for i, device := range devices {
+				deviceTokens[i] = device.Token
+			}
+
+			userNotification := notification
+			userNotification.UserID = userID
+
+			messages[i] = &queue.PushMessage{
+				Notification: userNotification,
+				DeviceTokens: deviceTokens,
+				Platforms:    devicePlatforms(devices),
+			}
+		}(i, userID, devices)
+	}
+	wg.Wait()
 	return nil
 }
 
 // ProcessPushFromQueue processes a single message from the queue
 // This is called by the worker for each message consumed from RabbitMQ
-func (s *pushService) ProcessPushFromQueue(ctx context.Context, delivery amqp.Delivery) error {
-	var pushMessage queue.PushMessage
-	if err := json.Unmarshal(delivery.Body, &pushMessage); err != nil {
+func (s *pushService) ProcessPushFromQueue(ctx context.Context, delivery queue.Delivery) (err error) {
+	pushMessage, err := queue.DecodePushMessage(delivery.Body)
+	if err != nil {
 		zap.L().Error("Failed to unmarshal push message",
+			zap.String("content_type", queue.ContentType(delivery.Body)),
 			zap.Error(err),
 		)
 		// Nack and don't requeue - message is malformed
-		if err := s.pushQueue.GetRabbitMQClient().Nack(delivery.DeliveryTag, false, false); err != nil {
+		if err := delivery.Nack(false); err != nil {
 			zap.L().Error("Failed to nack malformed message", zap.Error(err))
 		}
 		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
-	notification := pushMessage.Notification
+	// Continue the publisher's trace
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, pushMessage.TraceContext), "push.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.message.id", delivery.ID),
+			attribute.Int("push.retry_count", pushMessage.RetryCount),
+			attribute.Int("push.device_count", len(pushMessage.DeviceTokens)),
+		),
+	)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	return s.processPush(ctx, delivery, pushMessage)
+}
+
+// processPush sends a push message and settles its delivery: acked once it
+// is delivered or dropped, or after its retry is enqueued
+func (s *pushService) processPush(ctx context.Context, delivery queue.Delivery, pushMessage queue.PushMessage) error {
+	if s.userData.Erased(ctx, pushMessage.Notification.UserID, pushMessage.Notification.CreatedAt) {
+		return s.dropErased(delivery, pushMessage)
+	}
+	if pushMessage.DryRunID != "" {
+		return s.processDryRun(ctx, delivery, pushMessage)
+	}
+	if len(pushMessage.Bundle) > 0 {
+		return s.processBundle(ctx, delivery, pushMessage)
+	}
+	if len(pushMessage.Raw) > 0 {
+		return s.processRaw(ctx, delivery, pushMessage)
+	}
+	now := time.Now()
+	policy, hasPolicy := s.categoryPolicy(pushMessage.Notification)
+	if hasPolicy {
+		applyCategoryDefaults(&pushMessage, policy, now)
+	}
+	if expired(pushMessage.Notification, now) {
+		return s.dropExpired(ctx, delivery, pushMessage)
+	}
+	if hasPolicy && !pushMessage.PolicyApplied {
+		var settled bool
+		if pushMessage, settled = s.applyCategoryPolicy(ctx, delivery, pushMessage, policy, now); settled {
+			return nil
+		}
+	}
+	if pushMessage.RetryCount > 0 || delivery.Redelivered {
+		// An earlier attempt may have reached some of the devices already
+		pushMessage.DeviceTokens = s.undeliveredTokens(ctx, pushMessage)
+		if len(pushMessage.DeviceTokens) == 0 {
+			return s.skipDelivered(delivery, pushMessage)
+		}
+	}
+
+	notification := renderVars(pushMessage.Notification, pushMessage.Vars)
 	deviceTokens := pushMessage.DeviceTokens
+	if pushMessage.RetryCount == 0 {
+		// Whether or not it is delivered, the notification is in the inbox
+		s.inbox.Record(ctx, notification)
+		if s.publishRealtime(ctx, pushMessage, notification) {
+			return s.skipConnected(delivery, notification)
+		}
+	}
+	client := s.projects.Client(pushMessage.ProjectID)
 
 	zap.L().Info("Processing push message from queue",
 		zap.String("user_id", notification.UserID),
@@ -242,39 +819,35 @@ func (s *pushService) ProcessPushFromQueue(ctx context.Context, delivery amqp.De
 		zap.Int("retry_count", pushMessage.RetryCount),
 	)
 
+	// Tokens FCM rejected for good, never retried
+	var unregistered []string
+
 	// Validate tokens if validation is enabled
-	validTokens := make([]string, 0, len(deviceTokens))
 	if s.cfg != nil && s.cfg.Queue.Validation.Enabled {
-		for _, token := range deviceTokens {
-			validationCtx, cancel := context.WithTimeout(ctx, s.cfg.Queue.Validation.Timeout)
-			err := s.fcmClient.ValidateToken(validationCtx, token)
-			cancel()
-
-			if err != nil {
-				maskedToken := "***"
-				if len(token) > 20 {
-					maskedToken = token[:10] + "..." + token[len(token)-10:]
-				}
-				zap.L().Warn("Token validation failed, skipping",
-					zap.String("token", maskedToken),
-					zap.Error(err),
-				)
-				continue
-			}
-			validTokens = append(validTokens, token)
-		}
+		var validTokens []string
+		validTokens, unregistered = s.validateTokens(ctx, client, deviceTokens)
 
 		if len(validTokens) == 0 {
-			zap.L().Warn("No valid tokens found, moving to dead letter queue",
-				zap.String("user_id", notification.UserID),
-				zap.Int("original_count", len(deviceTokens)),
-			)
-			// All tokens invalid - move to dead letter queue
-			if err := s.pushQueue.EnqueueRetry(ctx, pushMessage); err != nil {
-				zap.L().Error("Failed to enqueue to retry/dead letter", zap.Error(err))
+			// Tokens FCM rejected for good are never retried
+			retry := pushMessage
+			retry.DeviceTokens = withoutTokens(pushMessage.DeviceTokens, unregistered)
+			nextQueue := ""
+			if len(retry.DeviceTokens) == 0 {
+				zap.L().Warn("All device tokens are unregistered, dropping message",
+					zap.String("user_id", notification.UserID),
+					zap.Int("original_count", len(deviceTokens)),
+				)
+			} else {
+				zap.L().Warn("No valid tokens found, moving to dead letter queue",
+					zap.String("user_id", notification.UserID),
+					zap.Int("original_count", len(deviceTokens)),
+				)
+				nextQueue = s.pushQueue.RetryQueue(retry)
 			}
-			// Ack the message since we've handled it
-			if err := s.pushQueue.GetRabbitMQClient().Ack(delivery.DeliveryTag, false); err != nil {
+			s.recordAttempt(ctx, delivery, pushMessage, failedResults(deviceTokens, "token validation failed"), errors.New("no valid tokens"), nextQueue)
+			if len(retry.DeviceTokens) > 0 {
+				s.settleRetry(ctx, delivery, retry)
+			} else if err := delivery.Ack(); err != nil {
 				zap.L().Error("Failed to ack message", zap.Error(err))
 			}
 			return fmt.Errorf("no valid tokens")
@@ -291,41 +864,104 @@ func (s *pushService) ProcessPushFromQueue(ctx context.Context, delivery amqp.De
 	notification.Status = "sending"
 
 	// Send notifications via FCM
-	successCount, failureCount, err := s.fcmClient.SendMultiple(ctx, deviceTokens, notification)
+	response, err := client.SendMulticast(ctx, deviceTokens, notification)
+	var successCount, failureCount int
+	if response != nil {
+		successCount, failureCount = response.SuccessCount, response.FailureCount
+		var rejected []string
+		for i, resp := range response.Responses {
+			if resp.Error != nil && fcm.IsInvalidToken(resp.Error) {
+				rejected = append(rejected, deviceTokens[i])
+			}
+		}
+		s.unregisterTokens(ctx, rejected)
+		unregistered = append(unregistered, rejected...)
+	}
+	if pushMessage.CampaignID == "" {
+		s.recordUsage(ctx, pushMessage.ProjectID, successCount+failureCount)
+	}
+	if !errors.Is(err, fcm.ErrProviderAuth) && !errors.Is(err, fcm.ErrProviderThrottled) {
+		s.recordOutcomes(ctx, pushMessage, deviceTokens, response, err)
+	}
+	if errors.Is(err, fcm.ErrProviderThrottled) {
+		// Retrying now would fail the same way: wait out FCM's Retry-After,
+		// then put the tokens not yet delivered back without counting a retry
+		return s.requeueThrottled(ctx, delivery, pushMessage, client, deviceTokens, response, err)
+	}
+	if errors.Is(err, fcm.ErrProviderAuth) {
+		// Credentials problem, not a message problem: put it back untouched
+		// instead of burning retries and dead-lettering the backlog.
+		zap.L().Warn("FCM credentials rejected, requeueing message",
+			zap.String("user_id", notification.UserID),
+		)
+		s.recordAttempt(ctx, delivery, pushMessage, attemptResults(deviceTokens, response, err), err, requeuedTo(pushMessage))
+		if err := delivery.Nack(true); err != nil {
+			zap.L().Error("Failed to nack message", zap.Error(err))
+		}
+		return err
+	}
 	if err != nil {
 		zap.L().Error("Failed to send push notifications",
 			zap.String("user_id", notification.UserID),
 			zap.Int("device_count", len(deviceTokens)),
 			zap.Error(err),
 		)
-		// Enqueue for retry
-		if err := s.pushQueue.EnqueueRetry(ctx, pushMessage); err != nil {
-			zap.L().Error("Failed to enqueue retry", zap.Error(err))
-		}
-		// Nack and requeue via retry queue
-		if err := s.pushQueue.GetRabbitMQClient().Nack(delivery.DeliveryTag, false, false); err != nil {
-			zap.L().Error("Failed to nack message", zap.Error(err))
-		}
+		results := attemptResults(deviceTokens, response, err)
+		// Only the tokens not yet delivered, less those FCM rejected for good
+		retry := pushMessage
+		retry.DeviceTokens = withoutTokens(pushMessage.DeviceTokens, unregistered)
+		s.recordAttempt(ctx, delivery, pushMessage, results, err, s.pushQueue.RetryQueue(retry))
+		s.settleRetry(ctx, delivery, retry, results...)
 		return fmt.Errorf("fcm send failed: %w", err)
 	}
 
 	// Check if all sends failed
 	if failureCount == len(deviceTokens) {
+		// Tokens FCM rejected for good are never retried
+		retry := pushMessage
+		retry.DeviceTokens = withoutTokens(pushMessage.DeviceTokens, unregistered)
+		if len(retry.DeviceTokens) == 0 {
+			s.recordAttempt(ctx, delivery, pushMessage, attemptResults(deviceTokens, response, nil), nil, "")
+			zap.L().Warn("All device tokens are unregistered, dropping message",
+				zap.String("user_id", notification.UserID),
+				zap.Int("device_count", len(deviceTokens)),
+			)
+			if err := delivery.Ack(); err != nil {
+				zap.L().Error("Failed to ack message", zap.Error(err))
+			}
+			return fmt.Errorf("all device tokens are unregistered")
+		}
+
 		zap.L().Warn("All push notifications failed, enqueuing for retry",
 			zap.String("user_id", notification.UserID),
 			zap.Int("device_count", len(deviceTokens)),
 		)
-		// Enqueue for retry
-		if err := s.pushQueue.EnqueueRetry(ctx, pushMessage); err != nil {
-			zap.L().Error("Failed to enqueue retry", zap.Error(err))
-		}
-		// Nack - message will go to retry queue
-		if err := s.pushQueue.GetRabbitMQClient().Nack(delivery.DeliveryTag, false, false); err != nil {
-			zap.L().Error("Failed to nack message", zap.Error(err))
-		}
+		s.recordAttempt(ctx, delivery, pushMessage, attemptResults(deviceTokens, response, nil), nil, s.pushQueue.RetryQueue(retry))
+		s.settleRetry(ctx, delivery, retry)
 		return fmt.Errorf("all notifications failed")
 	}
 
+	results := attemptResults(deviceTokens, response, nil)
+	if failureCount > 0 {
+		// Partly delivered: retry the rest, less the tokens FCM rejected for
+		// good. EnqueueRetry strips the delivered ones.
+		retry := pushMessage
+		retry.DeviceTokens = withoutTokens(pushMessage.DeviceTokens, unregistered)
+		if len(queue.UndeliveredTokens(retry.DeviceTokens, results)) > 0 {
+			zap.L().Warn("Some push notifications failed, enqueuing the rest for retry",
+				zap.String("user_id", notification.UserID),
+				zap.Int("device_count", len(deviceTokens)),
+				zap.Int("success_count", successCount),
+				zap.Int("failure_count", failureCount),
+			)
+			s.recordAttempt(ctx, delivery, pushMessage, results, nil, s.pushQueue.RetryQueue(retry))
+			s.settleRetry(ctx, delivery, retry, results...)
+			return nil
+		}
+	}
+
+	s.recordAttempt(ctx, delivery, pushMessage, results, nil, "")
+
 	// Success - ack the message
 	zap.L().Info("Push notifications sent successfully",
 		zap.String("user_id", notification.UserID),
@@ -334,7 +970,7 @@ func (s *pushService) ProcessPushFromQueue(ctx context.Context, delivery amqp.De
 		zap.Int("failure_count", failureCount),
 	)
 
-	if err := s.pushQueue.GetRabbitMQClient().Ack(delivery.DeliveryTag, false); err != nil {
+	if err := delivery.Ack(); err != nil {
 		zap.L().Error("Failed to ack message", zap.Error(err))
 		return err
 	}
@@ -342,108 +978,404 @@ func (s *pushService) ProcessPushFromQueue(ctx context.Context, delivery amqp.De
 	return nil
 }
 
-// GetQueueStats returns statistics about the push queues
-func (s *pushService) GetQueueStats(ctx context.Context) (map[string]int64, error) {
-	return s.pushQueue.GetQueueStats(ctx)
+// requeueThrottled waits until client accepts sends again, then requeues
+// the message for the tokens FCM rejected for quota or that weren't
+// attempted, and acks the delivery. It returns sendErr.
+func (s *pushService) requeueThrottled(ctx context.Context, delivery queue.Delivery, pushMessage queue.PushMessage, client fcm.FCMClient, deviceTokens []string, response *messaging.BatchResponse, sendErr error) error {
+	var remaining []string
+	for i, token := range deviceTokens {
+		if response == nil || i >= len(response.Responses) || fcm.ClassifyError(response.Responses[i].Error) == fcm.ErrorKindQuota {
+			remaining = append(remaining, token)
+		}
+	}
+
+	zap.L().Warn("FCM quota exceeded, requeueing message once the pause is over",
+		zap.String("user_id", pushMessage.Notification.UserID),
+		zap.Int("device_count", len(deviceTokens)),
+		zap.Int("remaining_count", len(remaining)),
+	)
+	nextQueue := ""
+	if len(remaining) > 0 {
+		nextQueue = queue.PushQueueFor(pushMessage.Priority)
+	}
+	s.recordAttempt(ctx, delivery, pushMessage, attemptResults(deviceTokens, response, sendErr), sendErr, nextQueue)
+	waitForThrottle(ctx, client)
+
+	if len(remaining) > 0 {
+		retry := pushMessage
+		retry.DeviceTokens = remaining
+		if err := s.pushQueue.Requeue(ctx, retry); err != nil {
+			zap.L().Error("Failed to requeue throttled message", zap.Error(err))
+			if err := delivery.Nack(true); err != nil {
+				zap.L().Error("Failed to nack message", zap.Error(err))
+			}
+			return sendErr
+		}
+	}
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+	}
+	return sendErr
 }
 
-// ProcessGatewayMessage processes messages from the API Gateway's push.queue
-// API Gateway sends: {notification_id, user_id, push_token, name, template: {subject, body}, ...}
-func (s *pushService) ProcessGatewayMessage(ctx context.Context, delivery amqp.Delivery) error {
-	// Parse API Gateway message format
-	var gatewayMessage map[string]interface{}
-	if err := json.Unmarshal(delivery.Body, &gatewayMessage); err != nil {
-		zap.L().Error("Failed to unmarshal gateway message",
-			zap.Error(err),
-		)
-		// Nack and don't requeue - message is malformed
-		if err := s.pushQueue.GetRabbitMQClient().Nack(delivery.DeliveryTag, false, false); err != nil {
-			zap.L().Error("Failed to nack malformed gateway message", zap.Error(err))
+// waitForThrottle blocks while client is paused after a quota error, or
+// until ctx is done
+func waitForThrottle(ctx context.Context, client fcm.FCMClient) {
+	until := client.Status().ThrottledUntil
+	if until == nil {
+		return
+	}
+
+	timer := time.NewTimer(time.Until(*until))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// recordOutcomes counts each token's delivery outcome for the alert rules,
+// labelled with the device's platform, the FCM project and the category, and
+// in the day's delivery rollups per platform and, for campaign variants, in
+// the variant's rollup. A send that failed as a whole counts every token as
+// failed. Failures on FCM's side are also counted as FCM errors.
+func (s *pushService) recordOutcomes(ctx context.Context, pushMessage queue.PushMessage, deviceTokens []string, response *messaging.BatchResponse, sendErr error) {
+	outcomes := make(map[string]*AlertOutcomes)
+	for i, token := range deviceTokens {
+		platform := pushMessage.Platforms[token]
+		o, ok := outcomes[platform]
+		if !ok {
+			o = &AlertOutcomes{}
+			outcomes[platform] = o
+		}
+		var tokenErr error
+		if response != nil && i < len(response.Responses) {
+			if response.Responses[i].Success {
+				o.Delivered++
+				continue
+			}
+			tokenErr = response.Responses[i].Error
+		} else if sendErr != nil {
+			tokenErr = sendErr
+		} else {
+			continue
+		}
+		o.Failed++
+		if isFCMSideError(tokenErr) {
+			o.FCMErrors++
 		}
-		return fmt.Errorf("failed to unmarshal gateway message: %w", err)
 	}
 
-	// Extract data from gateway message
-	notificationID, ok := gatewayMessage["notification_id"].(string)
-	if !ok {
-		zap.L().Error("Missing or invalid notification_id in gateway message")
-		if err := s.pushQueue.GetRabbitMQClient().Nack(delivery.DeliveryTag, false, false); err != nil {
-			zap.L().Error("Failed to nack gateway message", zap.Error(err))
+	for platform, o := range outcomes {
+		s.alerts.RecordDeliveries(s.alertLabels(pushMessage, platform), *o)
+
+		if platform == "" {
+			platform = models.PlatformUnknown
+		}
+		counts := models.DeliveryCounts{
+			Sent:      int64(o.Delivered + o.Failed),
+			Delivered: int64(o.Delivered),
+			Failed:    int64(o.Failed),
+		}
+		if err := s.analyticsRepo.Add(ctx, quotaDay(time.Now()), platform, counts); err != nil {
+			zap.L().Warn("Failed to record delivery rollup", zap.String("platform", platform), zap.Error(err))
+		}
+		if pushMessage.Variant != "" {
+			if err := s.analyticsRepo.AddVariant(ctx, pushMessage.CampaignID, pushMessage.Variant, counts); err != nil {
+				zap.L().Warn("Failed to record campaign variant rollup",
+					zap.String("campaign_id", pushMessage.CampaignID),
+					zap.String("variant", pushMessage.Variant),
+					zap.Error(err),
+				)
+			}
 		}
-		return fmt.Errorf("missing notification_id")
 	}
+}
 
-	userID, ok := gatewayMessage["user_id"].(string)
-	if !ok {
-		zap.L().Error("Missing or invalid user_id in gateway message")
-		if err := s.pushQueue.GetRabbitMQClient().Nack(delivery.DeliveryTag, false, false); err != nil {
-			zap.L().Error("Failed to nack gateway message", zap.Error(err))
+// settleRetry enqueues retry, the tokens of a failed send still to deliver,
+// and acks the original delivery. The original is not nacked: the push
+// queues dead-letter nacked messages, which would put a copy with every
+// token, delivered ones included, in the dead letter queue. If the retry
+// can't be enqueued, the original is requeued as it is instead.
+func (s *pushService) settleRetry(ctx context.Context, delivery queue.Delivery, retry queue.PushMessage, results ...models.AttemptTokenResult) {
+	if err := s.pushQueue.EnqueueRetry(ctx, retry, results...); err != nil {
+		zap.L().Error("Failed to enqueue retry, requeueing message", zap.Error(err))
+		if err := delivery.Nack(true); err != nil {
+			zap.L().Error("Failed to nack message", zap.Error(err))
 		}
-		return fmt.Errorf("missing user_id")
+		return
+	}
+	if len(results) > 0 {
+		retry.DeviceTokens = queue.UndeliveredTokens(retry.DeviceTokens, results)
+	}
+	s.recordRetries(retry)
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+	}
+}
+
+// recordRetries counts the tokens put back on a retry queue for the alert
+// rules, unless retries ran out and they go to the dead letter queue
+func (s *pushService) recordRetries(retry queue.PushMessage) {
+	if s.pushQueue.RetryQueue(retry) == queue.DeadLetterQueue {
+		return
+	}
+	retried := make(map[string]int)
+	for _, token := range retry.DeviceTokens {
+		retried[retry.Platforms[token]]++
+	}
+	for platform, n := range retried {
+		s.alerts.RecordDeliveries(s.alertLabels(retry, platform), AlertOutcomes{Retried: n})
+	}
+}
+
+// alertLabels returns the labels deliveries to platform are counted with for
+// the alert rules
+func (s *pushService) alertLabels(pushMessage queue.PushMessage, platform string) map[string]string {
+	projectID := pushMessage.ProjectID
+	if projectID == "" {
+		projectID = s.projects.PrimaryID()
+	}
+	labels := map[string]string{"project": projectID}
+	if platform != "" {
+		labels["platform"] = platform
+	}
+	if category := pushMessage.Notification.Category; category != nil && *category != "" {
+		labels["category"] = *category
+	}
+	return labels
+}
+
+// isFCMSideError reports whether a send failed on FCM's side rather than
+// because of the token or the message
+func isFCMSideError(err error) bool {
+	switch fcm.ClassifyError(err) {
+	case fcm.ErrorKindUnavailable, fcm.ErrorKindInternal, fcm.ErrorKindQuota, fcm.ErrorKindUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordUsage counts sends against the project's daily quota so campaigns
+// are paced around transactional traffic. Campaign sends reserve their quota
+// when they are enqueued instead.
+func (s *pushService) recordUsage(ctx context.Context, projectID string, sent int) {
+	if sent == 0 {
+		return
+	}
+	if projectID == "" {
+		projectID = s.projects.PrimaryID()
 	}
+	if err := s.quotaRepo.Add(ctx, projectID, quotaDay(time.Now()), int64(sent)); err != nil {
+		zap.L().Warn("Failed to record FCM quota usage", zap.String("project_id", projectID), zap.Error(err))
+	}
+}
+
+// validateTokens validates tokens concurrently with at most
+// Queue.Validation.Concurrency requests in flight, preserving input order.
+// Tokens FCM rejects for good are unregistered and returned separately.
+func (s *pushService) validateTokens(ctx context.Context, client fcm.FCMClient, deviceTokens []string) (valid, unregistered []string) {
+	concurrency := s.cfg.Queue.Validation.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10 // default
+	}
+
+	errs := make([]error, len(deviceTokens))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
 
-	// Get template (may be nil)
-	var template map[string]interface{}
-	if templateVal, ok := gatewayMessage["template"]; ok {
-		if templateMap, ok := templateVal.(map[string]interface{}); ok {
-			template = templateMap
+	for i, token := range deviceTokens {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, token string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			validationCtx, cancel := context.WithTimeout(ctx, s.cfg.Queue.Validation.Timeout)
+			err := client.ValidateToken(validationCtx, token)
+			cancel()
+
+			if err != nil {
+				zap.L().Warn("Token validation failed, skipping",
+					zap.String("token", token),
+					zap.Error(err),
+				)
+			}
+			errs[i] = err
+		}(i, token)
+	}
+	wg.Wait()
+
+	valid = make([]string, 0, len(deviceTokens))
+	for i, token := range deviceTokens {
+		switch {
+		case errs[i] == nil:
+			valid = append(valid, token)
+		case fcm.IsInvalidToken(errs[i]):
+			unregistered = append(unregistered, token)
 		}
 	}
+	s.unregisterTokens(ctx, unregistered)
+	return valid, unregistered
+}
 
-	// Extract title and body from template
-	title := "Notification"
-	body := "You have a new notification"
-	if template != nil {
-		if subject, ok := template["subject"].(string); ok && subject != "" {
-			title = subject
+// unregisterTokens deactivates the devices of tokens FCM rejected for good,
+// so they are no longer sent to. Tokens that aren't registered here, e.g. a
+// gateway message's push_token, are skipped.
+func (s *pushService) unregisterTokens(ctx context.Context, tokens []string) {
+	for _, token := range tokens {
+		err := s.deviceRepo.UpdateStatus(ctx, token, false)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
 		}
-		// Template service returns 'html_body', not 'body'
-		if htmlBody, ok := template["html_body"].(string); ok && htmlBody != "" {
-			body = htmlBody
-		} else if bodyContent, ok := template["body"].(string); ok && bodyContent != "" {
-			body = bodyContent
+		if err != nil {
+			zap.L().Warn("Failed to unregister invalid device token",
+				zap.String("token", token),
+				zap.Error(err),
+			)
+			continue
 		}
+		metrics.DevicesUnregistered.Inc()
+		zap.L().Info("Unregistered device rejected by FCM", zap.String("token", token))
+	}
+}
 
-		// Handle template variable substitution
-		if variables, ok := template["variables"].([]interface{}); ok {
-			if dataVal, ok := gatewayMessage["data"].(map[string]interface{}); ok {
-				for _, varName := range variables {
-					if varNameStr, ok := varName.(string); ok {
-						if value, ok := dataVal[varNameStr].(string); ok {
-							placeholder := "{{" + varNameStr + "}}"
-							body = strings.ReplaceAll(body, placeholder, value)
-							title = strings.ReplaceAll(title, placeholder, value)
-						}
-					}
-				}
-			}
+// withoutTokens returns tokens minus those in remove
+func withoutTokens(tokens, remove []string) []string {
+	if len(remove) == 0 {
+		return tokens
+	}
+	removed := make(map[string]bool, len(remove))
+	for _, token := range remove {
+		removed[token] = true
+	}
+	kept := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !removed[token] {
+			kept = append(kept, token)
 		}
 	}
+	return kept
+}
+
+// GetQueueStats returns statistics about the push queues
+func (s *pushService) GetQueueStats(ctx context.Context) (map[string]int64, error) {
+	return s.pushQueue.GetQueueStats(ctx)
+}
 
-	// Get device tokens from database
-	devices, err := s.deviceRepo.GetByUserID(ctx, userID)
+// ProcessGatewayMessage processes messages from the API Gateway's push.queue
+// API Gateway sends: {notification_id, user_id, push_token, name, template: {subject, body}, ...}
+// The notification is enqueued to the internal push queue, or in the direct
+// gateway mode sent right away.
+func (s *pushService) ProcessGatewayMessage(ctx context.Context, delivery queue.Delivery) (err error) {
+	// Messages that don't match their gateway schema will never be processed
+	gatewayMessage, err := gateway.Decode(delivery.Body)
 	if err != nil {
-		zap.L().Warn("Failed to get devices from database, using push_token fallback",
-			zap.String("user_id", userID),
-			zap.Error(err),
-		)
+		return s.rejectMalformed(ctx, delivery, err)
+	}
+
+	// The gateway's priority and type, and the binding the message was
+	// routed by, map to the notification's priority, TTL and category
+	binding := s.gatewayBinding(delivery.RoutingKey)
+	priority, expiresAt, category := s.gatewayPolicy(gatewayMessage, binding, time.Now())
+
+	// Gateway messages start their trace here, sampled by tenant and priority
+	tenant := gatewayMessage.TenantID
+	ctx, span := tracing.Tracer().Start(ctx, "gateway.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(append(tracing.Attributes(tenant, priority),
+			attribute.String("messaging.message.id", delivery.ID),
+			attribute.Int("gateway.schema_version", gatewayMessage.SchemaVersion),
+			attribute.String("messaging.rabbitmq.destination.routing_key", delivery.RoutingKey),
+		)...),
+	)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	notificationID := gatewayMessage.NotificationID
+	userID := gatewayMessage.UserID
+	title, body := gatewayContent(gatewayMessage)
+
+	sender := optionalString(gatewayMessage.Sender)
+	if reason := s.dropReason(ctx, userID, sender, category); reason != "" {
+		// Dropping is a final outcome, not a failure
+		if err := delivery.Ack(); err != nil {
+			zap.L().Error("Failed to ack gateway message", zap.Error(err))
+		}
+		return nil
+	}
+
+	softLaunch, err := softLaunchTenant(ctx, s.tenantRepo, s.cfg, tenant)
+	if err != nil {
+		if err := delivery.Nack(true); err != nil {
+			zap.L().Error("Failed to nack gateway message", zap.Error(err))
+		}
+		return err
+	}
+
+	var devices []models.Device
+	if softLaunch != nil {
+		// Only the tenant's test devices get its notifications until soft
+		// launch is lifted
+		devices, err = s.testDevices(ctx, softLaunch, nil)
+		if err == nil {
+			err = s.reserveSoftLaunchSends(ctx, softLaunch, len(devices))
+		}
+		if reason := softLaunchDropReason(err); reason != "" {
+			zap.L().Info("Notification dropped",
+				zap.String("notification_id", notificationID),
+				zap.String("tenant_id", tenant),
+				zap.String("reason", reason),
+			)
+			if err := delivery.Ack(); err != nil {
+				zap.L().Error("Failed to ack gateway message", zap.Error(err))
+			}
+			return nil
+		}
+		if err != nil {
+			if err := delivery.Nack(true); err != nil {
+				zap.L().Error("Failed to nack gateway message", zap.Error(err))
+			}
+			return err
+		}
+	} else {
+		// Get device tokens from database
+		devices, err = s.deviceRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			zap.L().Warn("Failed to get devices from database, using push_token fallback",
+				zap.String("user_id", userID),
+				zap.Error(err),
+			)
+		}
 	}
 
 	var deviceTokens []string
+	var platforms map[string]string
 	if len(devices) > 0 {
 		// Use tokens from database
 		deviceTokens = make([]string, len(devices))
 		for i, device := range devices {
 			deviceTokens[i] = device.Token
 		}
+		platforms = devicePlatforms(devices)
 		zap.L().Info("Using device tokens from database",
 			zap.String("user_id", userID),
 			zap.Int("device_count", len(deviceTokens)),
 		)
+	} else if resolved := s.resolveUserTokens(ctx, userID); len(resolved) > 0 {
+		// Use tokens from the external user resolver
+		deviceTokens = resolved
+		zap.L().Info("Using device tokens from user resolver",
+			zap.String("user_id", userID),
+			zap.Int("device_count", len(deviceTokens)),
+		)
 	} else {
 		// Fallback to push_token from gateway message
-		if pushToken, ok := gatewayMessage["push_token"].(string); ok && pushToken != "" {
-			deviceTokens = []string{pushToken}
+		if gatewayMessage.PushToken != "" {
+			deviceTokens = []string{gatewayMessage.PushToken}
 			zap.L().Info("Using push_token from gateway message",
 				zap.String("user_id", userID),
 			)
@@ -453,28 +1385,23 @@ func (s *pushService) ProcessGatewayMessage(ctx context.Context, delivery amqp.D
 				zap.String("notification_id", notificationID),
 			)
 			// Ack the message since we can't process it
-			if err := s.pushQueue.GetRabbitMQClient().Ack(delivery.DeliveryTag, false); err != nil {
+			if err := delivery.Ack(); err != nil {
 				zap.L().Error("Failed to ack gateway message", zap.Error(err))
 			}
 			return fmt.Errorf("no device tokens available for user: %s", userID)
 		}
 	}
 
-	// Extract data if present
-	var data map[string]interface{}
-	if dataVal, ok := gatewayMessage["data"]; ok {
-		if dataMap, ok := dataVal.(map[string]interface{}); ok {
-			data = dataMap
-		}
-	}
-
 	// Create notification
 	notification := models.PushNotification{
 		ID:        notificationID,
 		UserID:    userID,
 		Title:     title,
 		Body:      body,
-		Data:      data,
+		Data:      gatewayMessage.Data,
+		Sender:    sender,
+		Category:  category,
+		ExpiresAt: expiresAt,
 		Status:    "queued",
 		CreatedAt: time.Now(),
 	}
@@ -484,24 +1411,40 @@ func (s *pushService) ProcessGatewayMessage(ctx context.Context, delivery amqp.D
 		zap.String("user_id", userID),
 		zap.Int("device_count", len(deviceTokens)),
 		zap.String("title", title),
+		zap.String("priority", priority),
 	)
 
+	message := queue.PushMessage{
+		Notification: notification,
+		DeviceTokens: deviceTokens,
+		Platforms:    platforms,
+		TenantID:     tenant,
+		Priority:     priority,
+	}
+	if s.gatewayMode(binding) == config.GatewayModeDirect {
+		// Send it now, settling the gateway delivery as if it were an
+		// internal one; only retries go through the internal queues
+		message.Queue = queue.GatewayPushQueueName
+		s.notifyQueued(ctx, tenant, notification)
+		return s.processPush(ctx, delivery, message)
+	}
+
 	// Enqueue to internal push queue for processing
-	if err := s.pushQueue.EnqueuePush(ctx, notification, deviceTokens); err != nil {
+	if err := s.pushQueue.EnqueueMessage(ctx, message); err != nil {
 		zap.L().Error("Failed to enqueue push from gateway",
 			zap.String("notification_id", notificationID),
 			zap.String("user_id", userID),
 			zap.Error(err),
 		)
 		// Nack and requeue
-		if err := s.pushQueue.GetRabbitMQClient().Nack(delivery.DeliveryTag, false, true); err != nil {
+		if err := delivery.Nack(true); err != nil {
 			zap.L().Error("Failed to nack gateway message", zap.Error(err))
 		}
 		return fmt.Errorf("failed to enqueue push: %w", err)
 	}
 
 	// Ack the gateway message
-	if err := s.pushQueue.GetRabbitMQClient().Ack(delivery.DeliveryTag, false); err != nil {
+	if err := delivery.Ack(); err != nil {
 		zap.L().Error("Failed to ack gateway message", zap.Error(err))
 		return err
 	}
@@ -510,12 +1453,37 @@ func (s *pushService) ProcessGatewayMessage(ctx context.Context, delivery amqp.D
 		zap.String("notification_id", notificationID),
 		zap.String("user_id", userID),
 	)
+	s.notifyQueued(ctx, tenant, notification)
+
+	return nil
+}
+
+// renderVars replaces the {{name}} placeholders in notification's title and
+// body with their value in vars. Placeholders without a value are left as is.
+func renderVars(notification models.PushNotification, vars map[string]string) models.PushNotification {
+	if len(vars) == 0 {
+		return notification
+	}
+	replacements := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		replacements = append(replacements, "{{"+name+"}}", value)
+	}
+	replacer := strings.NewReplacer(replacements...)
+	notification.Title = replacer.Replace(notification.Title)
+	notification.Body = replacer.Replace(notification.Body)
+	return notification
+}
 
+// optionalString returns a pointer to v if it is a non-empty string
+func optionalString(str string) *string {
+	if str != "" {
+		return &str
+	}
 	return nil
 }
 
 func (s *pushService) SendDirect(ctx context.Context, token string, notification models.PushNotification) error {
-	zap.L().Debug("🔧 Sending direct FCM message",
+	zap.L().Debug("Sending direct FCM message",
 		zap.String("token", token),
 		zap.String("title", notification.Title),
 		zap.String("body", notification.Body),
@@ -523,7 +1491,7 @@ func (s *pushService) SendDirect(ctx context.Context, token string, notification
 
 	err := s.fcmClient.Send(ctx, token, notification)
 	if err != nil {
-		zap.L().Error("💥 FCM direct send failed",
+		zap.L().Error("FCM direct send failed",
 			zap.String("token", token),
 			zap.String("error_type", fmt.Sprintf("%T", err)),
 			zap.Error(err),
@@ -531,6 +1499,6 @@ func (s *pushService) SendDirect(ctx context.Context, token string, notification
 		return err
 	}
 
-	zap.L().Info("✅ FCM direct send successful")
+	zap.L().Info("FCM direct send successful")
 	return nil
 }
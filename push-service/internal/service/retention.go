@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/repository"
+	"push-service/pkg/lock"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+const retentionLockKey = "retention"
+
+// RetentionService purges stored data once it outlives its retention, so
+// notification history and delivery logs don't grow forever
+type RetentionService interface {
+	Run(ctx context.Context)
+}
+
+type retentionService struct {
+	retentionRepo repository.RetentionRepository
+	locker        lock.Locker
+	cfg           *config.RetentionConfig
+}
+
+func NewRetentionService(retentionRepo repository.RetentionRepository, locker lock.Locker, cfg *config.Config) RetentionService {
+	return &retentionService{
+		retentionRepo: retentionRepo,
+		locker:        locker,
+		cfg:           &cfg.Retention,
+	}
+}
+
+// retentionPolicy is how long the rows of tables are kept
+type retentionPolicy struct {
+	tables    []string
+	retention time.Duration
+}
+
+// Run purges every interval until ctx is cancelled. It returns at once if the
+// purge is disabled.
+func (s *retentionService) Run(ctx context.Context) {
+	if !s.cfg.Enabled {
+		return
+	}
+
+	interval := s.cfg.Interval
+	if interval <= 0 {
+		interval = time.Hour // default
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	zap.L().Info("Retention purge started",
+		zap.Duration("interval", interval),
+		zap.Duration("notifications", s.cfg.Notifications),
+		zap.Duration("delivery_logs", s.cfg.DeliveryLogs),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := lock.Run(ctx, s.locker, retentionLockKey, 0, s.purge)
+			if err != nil && !errors.Is(err, lock.ErrNotAcquired) {
+				zap.L().Error("Failed to purge expired data", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *retentionService) purge(ctx context.Context) error {
+	batchSize := s.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000 // default
+	}
+	notifications := s.cfg.Notifications
+	if notifications <= 0 {
+		notifications = 90 * 24 * time.Hour // default
+	}
+	deliveryLogs := s.cfg.DeliveryLogs
+	if deliveryLogs <= 0 {
+		deliveryLogs = 30 * 24 * time.Hour // default
+	}
+	outbox := s.cfg.Outbox
+	if outbox <= 0 {
+		outbox = 24 * time.Hour // default
+	}
+
+	policies := []retentionPolicy{
+		{tables: []string{"push_notifications", "inbox_items"}, retention: notifications},
+		{tables: []string{"delivery_attempts", "delivery_token_results", "push_dry_runs"}, retention: deliveryLogs},
+		{tables: []string{"push_outbox"}, retention: outbox},
+		{tables: []string{"frequency_counts"}, retention: 0}, // kept until their window ends
+	}
+
+	now := time.Now()
+	for _, policy := range policies {
+		for _, table := range policy.tables {
+			purged, err := inBatches(ctx, batchSize, func(ctx context.Context) (int64, error) {
+				return s.retentionRepo.Purge(ctx, table, now.Add(-policy.retention), batchSize)
+			})
+			metrics.RetentionPurged.WithLabelValues(table).Add(float64(purged))
+			if purged > 0 {
+				zap.L().Info("Expired rows purged",
+					zap.String("table", table),
+					zap.Int64("purged", purged),
+					zap.Duration("retention", policy.retention),
+				)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	metrics.RetentionLastSuccess.SetToCurrentTime()
+	return nil
+}
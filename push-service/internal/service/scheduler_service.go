@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// SchedulerService enqueues deferred deliveries and digests once they are
+// due
+type SchedulerService interface {
+	Run(ctx context.Context)
+}
+
+type schedulerService struct {
+	scheduledRepo repository.ScheduledPushRepository
+	digestRepo    repository.DigestRepository
+	pushService   PushService
+	pushQueue     *queue.PushQueue
+	cfg           *config.Config
+}
+
+func NewSchedulerService(scheduledRepo repository.ScheduledPushRepository, digestRepo repository.DigestRepository, pushService PushService, pushQueue *queue.PushQueue, cfg *config.Config) SchedulerService {
+	return &schedulerService{
+		scheduledRepo: scheduledRepo,
+		digestRepo:    digestRepo,
+		pushService:   pushService,
+		pushQueue:     pushQueue,
+		cfg:           cfg,
+	}
+}
+
+// Run enqueues due deliveries and digests every tick until ctx is cancelled
+func (s *schedulerService) Run(ctx context.Context) {
+	interval := s.cfg.Scheduler.TickInterval
+	if interval <= 0 {
+		interval = 10 * time.Second // default
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	zap.L().Info("Delivery scheduler started", zap.Duration("tick_interval", interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *schedulerService) tick(ctx context.Context) {
+	batchSize := s.cfg.Scheduler.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500 // default
+	}
+
+	pushes, err := s.scheduledRepo.ClaimDue(ctx, batchSize)
+	if err != nil {
+		zap.L().Error("Failed to load due scheduled pushes", zap.Error(err))
+		return
+	}
+
+	for i := range pushes {
+		s.enqueue(ctx, &pushes[i])
+	}
+
+	s.flushDigests(ctx, batchSize)
+}
+
+// enqueue publishes a claimed push. Claiming removed it, so a push that
+// can't be published is put back to be tried on the next tick.
+func (s *schedulerService) enqueue(ctx context.Context, push *models.ScheduledPush) {
+	var message queue.PushMessage
+	if err := json.Unmarshal(push.Message, &message); err != nil {
+		zap.L().Error("Dropping undecodable scheduled push",
+			zap.String("scheduled_push_id", push.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if err := s.pushQueue.EnqueueMessage(ctx, message); err != nil {
+		zap.L().Error("Failed to enqueue scheduled push, rescheduling",
+			zap.String("scheduled_push_id", push.ID),
+			zap.String("notification_id", message.Notification.ID),
+			zap.Error(err),
+		)
+		if err := s.scheduledRepo.Create(context.WithoutCancel(ctx), push); err != nil {
+			zap.L().Error("Lost scheduled push",
+				zap.String("notification_id", message.Notification.ID),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+
+	zap.L().Info("Scheduled push enqueued",
+		zap.String("notification_id", message.Notification.ID),
+		zap.Int("device_count", len(message.DeviceTokens)),
+		zap.Time("deliver_at", push.DeliverAt),
+	)
+}
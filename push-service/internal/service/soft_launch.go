@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/repository"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrSoftLaunchCap is returned once a tenant in soft launch has used its
+	// daily send cap
+	ErrSoftLaunchCap = errors.New("soft launch daily send cap reached")
+	// ErrSoftLaunchNoTestUsers is returned for sends of a tenant in soft
+	// launch that has no test users (or none with devices) to route them to
+	ErrSoftLaunchNoTestUsers = errors.New("tenant is in soft launch and has no test devices")
+	// ErrSoftLaunchCampaign is returned for campaigns of a tenant in soft launch
+	ErrSoftLaunchCampaign = errors.New("campaigns are not allowed while the tenant is in soft launch")
+	// ErrSoftLaunchRaw is returned for raw sends of a tenant in soft launch,
+	// whose targets can't be redirected to its test users
+	ErrSoftLaunchRaw = errors.New("raw sends are not allowed while the tenant is in soft launch")
+)
+
+type TenantService interface {
+	GetTenant(ctx context.Context, id string) (*models.Tenant, error)
+	UpdateTenant(ctx context.Context, id string, req models.UpdateTenantRequest) (*models.Tenant, error)
+}
+
+type tenantService struct {
+	tenantRepo repository.TenantRepository
+	cfg        *config.Config
+}
+
+func NewTenantService(tenantRepo repository.TenantRepository, cfg *config.Config) TenantService {
+	return &tenantService{tenantRepo: tenantRepo, cfg: cfg}
+}
+
+// GetTenant returns the tenant's soft launch settings. A tenant without a
+// record is reported with the defaults it gets.
+func (s *tenantService) GetTenant(ctx context.Context, id string) (*models.Tenant, error) {
+	tenant, err := s.tenantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tenant == nil {
+		tenant = &models.Tenant{ID: id, SoftLaunch: s.cfg.SoftLaunch.Enabled, TestUserIDs: []string{}}
+	}
+	return s.withSentToday(ctx, tenant)
+}
+
+func (s *tenantService) UpdateTenant(ctx context.Context, id string, req models.UpdateTenantRequest) (*models.Tenant, error) {
+	tenant, err := s.tenantRepo.Update(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SoftLaunch != nil && !*req.SoftLaunch {
+		zap.L().Info("Soft launch lifted", zap.String("tenant_id", id))
+	}
+	return s.withSentToday(ctx, tenant)
+}
+
+func (s *tenantService) withSentToday(ctx context.Context, tenant *models.Tenant) (*models.Tenant, error) {
+	sent, err := s.tenantRepo.SentOn(ctx, tenant.ID, quotaDay(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	tenant.SentToday = sent
+	return tenant, nil
+}
+
+// softLaunchTenant returns tenantID's settings if it is in soft launch, or
+// nil. Tenants without a record are in soft launch while it is enabled.
+func softLaunchTenant(ctx context.Context, tenantRepo repository.TenantRepository, cfg *config.Config, tenantID string) (*models.Tenant, error) {
+	if !cfg.SoftLaunch.Enabled || tenantID == "" {
+		return nil, nil
+	}
+
+	tenant, err := tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	if tenant == nil {
+		return &models.Tenant{ID: tenantID, SoftLaunch: true}, nil
+	}
+	if !tenant.SoftLaunch {
+		return nil, nil
+	}
+	return tenant, nil
+}
+
+// testDevices returns the devices of a soft launched tenant's test users on
+// the given platforms (any if empty), which its sends are routed to
+func (s *pushService) testDevices(ctx context.Context, tenant *models.Tenant, platforms []string) ([]models.Device, error) {
+	devicesByUser, err := s.deviceRepo.GetByUserIDs(ctx, tenant.TestUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	var devices []models.Device
+	for _, userID := range tenant.TestUserIDs {
+		for _, device := range devicesByUser[userID] {
+			if len(platforms) == 0 || containsString(platforms, device.Platform) {
+				devices = append(devices, device)
+			}
+		}
+	}
+	if len(devices) == 0 {
+		return nil, ErrSoftLaunchNoTestUsers
+	}
+	return devices, nil
+}
+
+// reserveSoftLaunchSends counts n sends against the tenant's daily cap, or
+// returns ErrSoftLaunchCap if they don't fit
+func (s *pushService) reserveSoftLaunchSends(ctx context.Context, tenant *models.Tenant, n int) error {
+	limit := s.cfg.SoftLaunch.DailySendCap
+	if tenant.DailySendCap != nil {
+		limit = *tenant.DailySendCap
+	}
+	if limit <= 0 {
+		limit = 1000 // default
+	}
+
+	ok, err := s.tenantRepo.ReserveSends(ctx, tenant.ID, quotaDay(time.Now()), int64(n), int64(limit))
+	if err != nil {
+		return fmt.Errorf("failed to reserve soft launch sends: %w", err)
+	}
+	if !ok {
+		zap.L().Warn("Soft launch daily send cap reached",
+			zap.String("tenant_id", tenant.ID),
+			zap.Int("cap", limit),
+		)
+		return ErrSoftLaunchCap
+	}
+	return nil
+}
+
+// softLaunchDropReason maps a soft launch error to the reason a gateway
+// message is dropped for, or "" if it isn't a soft launch rejection
+func softLaunchDropReason(err error) string {
+	var reason string
+	switch {
+	case errors.Is(err, ErrSoftLaunchNoTestUsers):
+		reason = models.DropReasonSoftLaunch
+	case errors.Is(err, ErrSoftLaunchCap):
+		reason = models.DropReasonSoftLaunchCap
+	default:
+		return ""
+	}
+	metrics.NotificationsDropped.WithLabelValues(reason).Inc()
+	return reason
+}
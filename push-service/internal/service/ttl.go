@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidTTL is returned for sends with an unusable ttl
+var ErrInvalidTTL = errors.New("invalid ttl")
+
+// maxTTL is the longest TTL FCM accepts
+const maxTTL = 28 * 24 * time.Hour
+
+// parseTTL parses a send's ttl into when its notification expires; empty
+// means it never does
+func parseTTL(value string, now time.Time) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 || ttl > maxTTL {
+		return nil, fmt.Errorf("%w: ttl must be a positive duration of at most 28 days, got %q", ErrInvalidTTL, value)
+	}
+	expiresAt := now.Add(ttl)
+	return &expiresAt, nil
+}
+
+// expired reports whether notification's TTL has passed
+func expired(notification models.PushNotification, now time.Time) bool {
+	return notification.ExpiresAt != nil && !now.Before(*notification.ExpiresAt)
+}
+
+// dropExpired settles a message whose notification expired while it sat in
+// the queue, e.g. behind a backlog or in a retry tier, instead of sending it
+// late
+func (s *pushService) dropExpired(ctx context.Context, delivery queue.Delivery, pushMessage queue.PushMessage) error {
+	zap.L().Info("Notification dropped",
+		zap.String("notification_id", pushMessage.Notification.ID),
+		zap.String("user_id", pushMessage.Notification.UserID),
+		zap.String("reason", models.DropReasonExpired),
+		zap.Time("expires_at", *pushMessage.Notification.ExpiresAt),
+	)
+	metrics.NotificationsDropped.WithLabelValues(models.DropReasonExpired).Inc()
+
+	s.recordAttempt(ctx, delivery, pushMessage, failedResults(pushMessage.DeviceTokens, "notification expired"), errors.New("notification expired"), "")
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+	}
+	return nil
+}
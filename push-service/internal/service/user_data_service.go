@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/queue"
+	"push-service/internal/repository"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// UserDataService deletes a user's data on request, e.g. under the GDPR
+// right to erasure
+type UserDataService interface {
+	// DeleteUserData hard-deletes everything stored about the user and
+	// reports what was deleted. Messages already queued for the user are
+	// dropped by the workers.
+	DeleteUserData(ctx context.Context, userID string) (*models.UserDataDeletion, error)
+	// Erased reports whether the user's data was deleted after a
+	// notification created at createdAt, which must then not be delivered
+	Erased(ctx context.Context, userID string, createdAt time.Time) bool
+}
+
+type userDataService struct {
+	userDataRepo repository.UserDataRepository
+	window       time.Duration
+	refresh      time.Duration
+
+	mu       sync.Mutex
+	erasures map[string]time.Time // by user hash
+	loadedAt time.Time
+}
+
+func NewUserDataService(userDataRepo repository.UserDataRepository, cfg *config.Config) UserDataService {
+	window := cfg.Privacy.ErasureWindow
+	if window <= 0 {
+		window = 30 * 24 * time.Hour // default
+	}
+	refresh := cfg.Privacy.ErasureRefresh
+	if refresh <= 0 {
+		refresh = 30 * time.Second // default
+	}
+
+	return &userDataService{
+		userDataRepo: userDataRepo,
+		window:       window,
+		refresh:      refresh,
+		erasures:     make(map[string]time.Time),
+	}
+}
+
+// userHash is how an erased user is remembered, so their ID isn't kept
+func userHash(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *userDataService) DeleteUserData(ctx context.Context, userID string) (*models.UserDataDeletion, error) {
+	hash := userHash(userID)
+	deletion, err := s.userDataRepo.Erase(ctx, userID, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.erasures[hash] = deletion.ErasedAt
+	s.mu.Unlock()
+
+	// Erasures past the window have nothing left queued to drop
+	if _, err := s.userDataRepo.PurgeErasures(ctx, time.Now().Add(-s.window)); err != nil {
+		zap.L().Warn("Failed to purge old user erasures", zap.Error(err))
+	}
+
+	zap.L().Info("User data deleted",
+		zap.String("user_hash", hash),
+		zap.Int64("devices", deletion.Devices),
+		zap.Int64("notifications", deletion.Notifications),
+		zap.Int64("delivery_attempts", deletion.DeliveryAttempts),
+		zap.Int64("token_results", deletion.TokenResults),
+		zap.Int64("inbox_items", deletion.InboxItems),
+		zap.Int64("digest_items", deletion.DigestItems),
+		zap.Int64("mutes", deletion.Mutes),
+		zap.Int64("dry_run_results", deletion.DryRunResults),
+		zap.Int64("scheduled_pushes", deletion.ScheduledPushes),
+		zap.Int64("outbox_messages", deletion.OutboxMessages),
+		zap.Int64("dead_letters", deletion.DeadLetters),
+		zap.Int64("campaigns", deletion.Campaigns),
+		zap.Int64("tenants", deletion.Tenants),
+	)
+
+	return deletion, nil
+}
+
+func (s *userDataService) Erased(ctx context.Context, userID string, createdAt time.Time) bool {
+	if userID == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Erasures made through other replicas show up within a refresh
+	if time.Since(s.loadedAt) >= s.refresh {
+		erasures, err := s.userDataRepo.ListErasures(ctx, time.Now().Add(-s.window))
+		if err != nil {
+			zap.L().Warn("Failed to load user erasures, using the last ones loaded", zap.Error(err))
+		} else {
+			s.erasures = erasures
+		}
+		s.loadedAt = time.Now()
+	}
+
+	erasedAt, ok := s.erasures[userHash(userID)]
+	return ok && createdAt.Before(erasedAt)
+}
+
+// dropErased settles a message queued for a user before their data was
+// deleted. Nothing about it is recorded, that would store their data again.
+func (s *pushService) dropErased(delivery queue.Delivery, pushMessage queue.PushMessage) error {
+	zap.L().Info("Notification dropped",
+		zap.String("notification_id", pushMessage.Notification.ID),
+		zap.String("reason", models.DropReasonErased),
+	)
+	metrics.NotificationsDropped.WithLabelValues(models.DropReasonErased).Inc()
+
+	if err := delivery.Ack(); err != nil {
+		zap.L().Error("Failed to ack message", zap.Error(err))
+	}
+	return nil
+}
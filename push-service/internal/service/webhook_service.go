@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/repository"
+	"push-service/pkg/webhook"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// ErrWebhookNotFound is returned when deleting a webhook the tenant doesn't have
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookService manages tenants' webhooks and has the dispatcher deliver
+// them the events they subscribed to, signed with the webhook's secret
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, req models.CreateWebhookRequest) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context, tenantID string) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, tenantID, id string) error
+	// Notify sends an event to the tenant's webhooks in the background
+	Notify(ctx context.Context, tenantID, eventType string, data map[string]any)
+	// ListDeliveries returns this instance's most recent webhook deliveries,
+	// newest first, and the webhooks whose circuit is open
+	ListDeliveries(webhookID string, limit int) ([]webhook.Delivery, []webhook.Circuit)
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	dispatcher  *webhook.Dispatcher
+	cacheTTL    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedWebhooks
+}
+
+// cachedWebhooks are a tenant's webhooks as loaded at loadedAt
+type cachedWebhooks struct {
+	webhooks []models.Webhook
+	loadedAt time.Time
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository, dispatcher *webhook.Dispatcher, cfg *config.Config) WebhookService {
+	cacheTTL := cfg.Webhooks.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = 30 * time.Second // default
+	}
+
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		dispatcher:  dispatcher,
+		cacheTTL:    cacheTTL,
+		cache:       make(map[string]cachedWebhooks),
+	}
+}
+
+// CreateWebhook registers a webhook with a new secret, which is only
+// returned here
+func (s *webhookService) CreateWebhook(ctx context.Context, req models.CreateWebhookRequest) (*models.Webhook, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &models.Webhook{
+		TenantID: req.TenantID,
+		URL:      req.URL,
+		Secret:   secret,
+		Events:   req.Events,
+	}
+	if err := s.webhookRepo.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+	s.invalidate(req.TenantID)
+
+	zap.L().Info("Webhook registered",
+		zap.String("webhook_id", hook.ID),
+		zap.String("tenant_id", hook.TenantID),
+		zap.Strings("events", hook.Events),
+	)
+	return hook, nil
+}
+
+// ListWebhooks returns the tenant's webhooks without their secrets
+func (s *webhookService) ListWebhooks(ctx context.Context, tenantID string) ([]models.Webhook, error) {
+	webhooks, err := s.webhookRepo.ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, tenantID, id string) error {
+	if err := s.webhookRepo.Delete(ctx, tenantID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+	s.invalidate(tenantID)
+
+	zap.L().Info("Webhook deleted",
+		zap.String("webhook_id", id),
+		zap.String("tenant_id", tenantID),
+	)
+	return nil
+}
+
+func (s *webhookService) Notify(ctx context.Context, tenantID, eventType string, data map[string]any) {
+	webhooks, err := s.webhooks(ctx, tenantID)
+	if err != nil {
+		zap.L().Warn("Failed to load webhooks",
+			zap.String("tenant_id", tenantID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	event := models.Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		zap.L().Error("Failed to encode webhook event", zap.String("event", eventType), zap.Error(err))
+		return
+	}
+
+	message := webhook.Message{ID: event.ID, Type: event.Type, Body: body}
+	for _, hook := range webhooks {
+		if slices.Contains(hook.Events, eventType) {
+			s.dispatcher.Dispatch(webhook.Endpoint{ID: hook.ID, URL: hook.URL, Secret: hook.Secret}, message)
+		}
+	}
+}
+
+func (s *webhookService) ListDeliveries(webhookID string, limit int) ([]webhook.Delivery, []webhook.Circuit) {
+	return s.dispatcher.Deliveries(webhookID, limit), s.dispatcher.OpenCircuits()
+}
+
+// webhooks returns the tenant's webhooks, from the cache while it is fresh
+func (s *webhookService) webhooks(ctx context.Context, tenantID string) ([]models.Webhook, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < s.cacheTTL {
+		return cached.webhooks, nil
+	}
+
+	webhooks, err := s.webhookRepo.ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = cachedWebhooks{webhooks: webhooks, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return webhooks, nil
+}
+
+func (s *webhookService) invalidate(tenantID string) {
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
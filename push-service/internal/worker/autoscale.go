@@ -0,0 +1,164 @@
+package worker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// utilizationSampleInterval is how often the pool's busy handlers are
+// sampled for its utilization
+const utilizationSampleInterval = time.Second
+
+// AutoscaleStatus describes the worker's load, for autoscalers
+// @Description Queue backlog and worker load, for autoscalers such as KEDA
+type AutoscaleStatus struct {
+	// Backlog is the number of messages waiting in the push queues of every
+	// priority and the gateway queue, not counting those delivered to a
+	// worker
+	Backlog int64            `json:"backlog" example:"1200"`
+	Queues  map[string]int64 `json:"queues"`
+	// Utilization is the average share of the pool busy over the last
+	// interval
+	Utilization float64 `json:"utilization" example:"0.85"`
+	Concurrency int     `json:"concurrency" example:"10"`
+	Active      int     `json:"active" example:"8"`
+	// DesiredConcurrency is what the controller sizes the pool to: the
+	// handlers that were busy plus one per target backlog of messages
+	// waiting, within bounds
+	DesiredConcurrency int `json:"desired_concurrency" example:"32"`
+	// Controller is whether the worker resizes its pool on its own
+	Controller bool      `json:"controller" example:"false"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// autoscale is the worker's last reading of its load
+type autoscale struct {
+	mu     sync.Mutex
+	status AutoscaleStatus
+}
+
+// runAutoscale reads the queue backlog and the pool's utilization every
+// interval until ctx is cancelled and exports them. With the controller
+// enabled, it then resizes the pool to the backlog.
+func (w *Worker) runAutoscale(ctx context.Context) {
+	interval := w.autoscaleCfg.Interval
+	if interval <= 0 {
+		interval = 15 * time.Second // default
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	sampler := time.NewTicker(utilizationSampleInterval)
+	defer sampler.Stop()
+
+	var busy float64
+	var samples int
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sampler.C:
+			busy += float64(w.pool.Active()) / float64(w.pool.Size())
+			samples++
+		case <-ticker.C:
+			utilization := 0.0
+			if samples > 0 {
+				utilization = busy / float64(samples)
+			}
+			busy, samples = 0, 0
+			w.readLoad(ctx, utilization)
+		}
+	}
+}
+
+// readLoad exports the backlog and utilization, and with the controller
+// enabled, resizes the pool
+func (w *Worker) readLoad(ctx context.Context, utilization float64) {
+	backlog, queues, err := w.pushQueue.Backlog(ctx)
+	if err != nil {
+		zap.L().Warn("Failed to read the queue backlog", zap.Error(err))
+		return
+	}
+	concurrency := w.pool.Size()
+	for queue, depth := range queues {
+		metrics.QueueDepth.WithLabelValues(queue).Set(float64(depth))
+	}
+	metrics.QueueBacklog.Set(float64(backlog))
+	metrics.WorkerUtilization.Set(utilization)
+	metrics.WorkerConcurrency.Set(float64(concurrency))
+
+	desired := w.desiredConcurrency(backlog, utilization*float64(concurrency))
+	w.autoscale.mu.Lock()
+	w.autoscale.status = AutoscaleStatus{
+		Backlog:            backlog,
+		Queues:             queues,
+		Utilization:        utilization,
+		Concurrency:        concurrency,
+		Active:             w.pool.Active(),
+		DesiredConcurrency: desired,
+		Controller:         w.autoscaleCfg.Enabled,
+		UpdatedAt:          time.Now(),
+	}
+	w.autoscale.mu.Unlock()
+
+	if !w.autoscaleCfg.Enabled || desired == w.fullConcurrency() {
+		return
+	}
+	if _, err := w.Update(nil, &desired); err != nil {
+		zap.L().Warn("Failed to resize the worker pool", zap.Error(err))
+		return
+	}
+	zap.L().Info("Worker pool resized to the queue backlog",
+		zap.Int64("backlog", backlog),
+		zap.Float64("utilization", utilization),
+		zap.Int("concurrency", desired),
+	)
+}
+
+// desiredConcurrency returns the pool size for backlog messages waiting
+// while busy handlers were busy on average: enough to keep those, plus one
+// per target backlog, within the bounds. It shrinks by half the difference
+// at a time, so a backlog drained between two readings doesn't empty the
+// pool at once.
+func (w *Worker) desiredConcurrency(backlog int64, busy float64) int {
+	cfg := &w.autoscaleCfg
+	target := cfg.TargetBacklog
+	if target <= 0 {
+		target = 20 // default
+	}
+	minConcurrency := max(cfg.MinConcurrency, 1)
+	maxConcurrency := w.maxConcurrency
+	if cfg.MaxConcurrency > 0 && cfg.MaxConcurrency < maxConcurrency {
+		maxConcurrency = cfg.MaxConcurrency
+	}
+
+	desired := int(math.Ceil(busy)) + int((backlog+int64(target)-1)/int64(target))
+	if current := w.fullConcurrency(); desired < current {
+		desired = current - (current-desired+1)/2
+	}
+	return min(max(desired, minConcurrency), maxConcurrency)
+}
+
+// fullConcurrency returns the pool size the worker runs at when it isn't
+// slowed down by backpressure
+func (w *Worker) fullConcurrency() int {
+	w.backpressure.mu.Lock()
+	defer w.backpressure.mu.Unlock()
+	if w.backpressure.factor < 1 {
+		return w.backpressure.concurrency
+	}
+	return w.pool.Size()
+}
+
+// Autoscale returns the worker's last reading of its load, or false before
+// the first one
+func (w *Worker) Autoscale() (AutoscaleStatus, bool) {
+	w.autoscale.mu.Lock()
+	defer w.autoscale.mu.Unlock()
+	return w.autoscale.status, !w.autoscale.status.UpdatedAt.IsZero()
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"push-service/internal/platform/fcm"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// backpressure is how fast the worker consumes while FCM fails many sends.
+// The worker runs at factor of its prefetch and concurrency, and waits delay
+// between deliveries.
+type backpressure struct {
+	mu     sync.Mutex
+	factor float64 // 1 at full speed
+	// The settings at full speed, while slowed down
+	prefetch    int
+	concurrency int
+
+	delay atomic.Int64 // time.Duration
+}
+
+// runBackpressure adjusts the worker's speed to FCM's failure rate every
+// check interval until ctx is cancelled. It returns at once if backpressure
+// is disabled.
+func (w *Worker) runBackpressure(ctx context.Context) {
+	if !w.backpressureCfg.Enabled {
+		return
+	}
+
+	interval := w.backpressureCfg.CheckInterval
+	if interval <= 0 {
+		interval = 10 * time.Second // default
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.adjustSpeed(w.fcmClient.Status())
+		}
+	}
+}
+
+// adjustSpeed halves the worker's speed while FCM fails more than the
+// threshold of last minute's sends, and otherwise speeds it up a step at a
+// time until it is back to full speed. Sends retried into the dead letter
+// queue during an FCM incident are what it saves.
+func (w *Worker) adjustSpeed(status fcm.ProviderStatus) {
+	// Consumption is paused altogether meanwhile
+	if providerPaused(status) {
+		return
+	}
+
+	cfg := &w.backpressureCfg
+	minFactor := cfg.MinFactor
+	if minFactor <= 0 {
+		minFactor = 0.1 // default
+	}
+	step := cfg.RecoveryStep
+	if step <= 0 {
+		step = 0.1 // default
+	}
+
+	w.backpressure.mu.Lock()
+	defer w.backpressure.mu.Unlock()
+
+	factor := w.backpressure.factor
+	breached := status.RecentSends >= cfg.MinSends && status.FailureRate >= cfg.Threshold
+	switch {
+	case breached:
+		if factor == 1 {
+			w.backpressure.prefetch = w.pushQueue.Broker().Prefetch()
+			w.backpressure.concurrency = w.pool.Size()
+		}
+		factor = max(factor/2, minFactor)
+	case factor < 1:
+		factor = min(factor+step, 1)
+	}
+	if factor == w.backpressure.factor {
+		return
+	}
+
+	w.backpressure.factor = factor
+	w.applySpeedLocked()
+
+	fields := []zap.Field{
+		zap.Float64("speed_factor", factor),
+		zap.Float64("failure_rate", status.FailureRate),
+		zap.Int64("recent_sends", status.RecentSends),
+	}
+	switch {
+	case breached:
+		zap.L().Warn("FCM is failing many sends, slowing down consumption", fields...)
+	case factor == 1:
+		zap.L().Info("FCM recovered, consuming at full speed again", fields...)
+	default:
+		zap.L().Info("FCM failure rate is back under the threshold, speeding up consumption", fields...)
+	}
+}
+
+// applySpeedLocked scales the full-speed settings by the current factor.
+// Callers must hold w.backpressure.mu.
+func (w *Worker) applySpeedLocked() {
+	factor := w.backpressure.factor
+	prefetch := max(1, int(math.Ceil(float64(w.backpressure.prefetch)*factor)))
+	concurrency := max(1, int(math.Ceil(float64(w.backpressure.concurrency)*factor)))
+
+	if err := w.setPrefetch(prefetch); err != nil {
+		zap.L().Warn("Failed to change prefetch count for backpressure", zap.Error(err))
+	}
+	w.pool.Resize(concurrency)
+
+	maxDelay := w.backpressureCfg.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = time.Second // default
+	}
+	w.backpressure.delay.Store(int64(float64(maxDelay) * (1 - factor)))
+	metrics.WorkerSpeedFactor.Set(factor)
+}
+
+// waitBackpressure waits between deliveries while the worker is slowed down
+func (w *Worker) waitBackpressure(ctx context.Context) error {
+	delay := time.Duration(w.backpressure.delay.Load())
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/queue"
+	"push-service/pkg/rabbitmq"
+
+	"go.uber.org/zap"
+)
+
+// consumeRemoteGateway connects to a regional broker and consumes its gateway
+// queue until ctx is cancelled. The initial connection is retried with
+// backoff so an unreachable region doesn't hold up the others; once connected
+// the client reconnects on its own. Handlers run with processCtx.
+func (w *Worker) consumeRemoteGateway(ctx, processCtx context.Context, cfg config.RabbitMQConfig) {
+	backoff := cfg.ReconnectInitialBackoff
+	if backoff <= 0 {
+		backoff = time.Second // default
+	}
+	maxBackoff := cfg.ReconnectMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second // default
+	}
+
+	for {
+		client, err := rabbitmq.NewRabbitMQClient(&cfg)
+		if err == nil {
+			broker := queue.NewRabbitMQBroker(client)
+			broker.BatchAcks(w.ackBatchSize, w.ackInterval)
+			msgs, consumeErr := w.pushQueue.ConsumeGatewayFrom(ctx, broker, "gateway-"+cfg.Name)
+			if consumeErr == nil {
+				if !w.addRemote(broker) {
+					// Connected as the worker stopped
+					broker.Close()
+					return
+				}
+				zap.L().Info("Consuming gateway queue from regional broker",
+					zap.String("broker", cfg.Name),
+				)
+				w.run(ctx, processCtx, msgs, "gateway:"+cfg.Name, w.pushService.ProcessGatewayMessage)
+				return
+			}
+			// Also stops the ack batcher
+			broker.Close()
+			err = consumeErr
+		}
+
+		zap.L().Warn("Failed to connect to regional gateway broker",
+			zap.String("broker", cfg.Name),
+			zap.Duration("retry_in", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// addRemote keeps a regional broker for Stop to close. It returns false once
+// Stop has taken the brokers, and the caller closes it.
+func (w *Worker) addRemote(broker queue.Broker) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.remotesClosed {
+		return false
+	}
+	w.remotes = append(w.remotes, broker)
+	return true
+}
+
+// closeRemotes returns the regional brokers for Stop to close, and turns
+// away those that connect later
+func (w *Worker) closeRemotes() []queue.Broker {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.remotesClosed = true
+	remotes := w.remotes
+	w.remotes = nil
+	return remotes
+}
+
+func (w *Worker) remoteBrokers() []queue.Broker {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]queue.Broker(nil), w.remotes...)
+}
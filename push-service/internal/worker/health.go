@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"push-service/internal/queue"
+
+	"go.uber.org/zap"
+)
+
+// Worker health statuses
+const (
+	HealthOK      = "ok"
+	HealthPaused  = "paused"  // waiting for FCM to accept sends again
+	HealthStalled = "stalled" // work is pending but nothing finished lately
+	HealthStopped = "stopped"
+)
+
+// Health describes whether the worker is making progress
+// @Description Queue worker status
+type Health struct {
+	// Status is ok, paused (while FCM rejects our credentials or throttles
+	// us), stalled (messages are in flight or waiting, but none finished
+	// within the stall timeout) or stopped
+	Status string `json:"status" example:"ok"`
+	// LastProcessedAt is when a handler last finished a message
+	LastProcessedAt *time.Time `json:"last_processed_at,omitempty" example:"2025-01-01T00:00:00Z"`
+	InFlight        int        `json:"in_flight" example:"3"`
+	Concurrency     int        `json:"concurrency" example:"10"`
+	// Waiting counts the messages in the push and gateway queues
+	Waiting int64 `json:"waiting" example:"120"`
+	// Consumers counts the queue consumers of this process
+	Consumers int `json:"consumers" example:"5"`
+	// Reconnects counts how often the broker connections were
+	// re-established, where the broker tells
+	Reconnects int `json:"reconnects" example:"0"`
+}
+
+// Health reports the worker's progress. It asks the broker how many
+// messages are waiting.
+func (w *Worker) Health(ctx context.Context) Health {
+	health := Health{
+		Status:      HealthOK,
+		InFlight:    w.pool.Active(),
+		Concurrency: w.pool.Size(),
+		Waiting:     w.waiting(ctx),
+		Consumers:   len(queue.ActiveConsumers()),
+	}
+
+	// Since the start, until the first message is done
+	progress := w.startedAt
+	if last := w.lastProcessed.Load(); last != 0 {
+		at := time.Unix(0, last).UTC()
+		health.LastProcessedAt = &at
+		progress = at
+	}
+
+	for _, broker := range append([]queue.Broker{w.pushQueue.Broker()}, w.remoteBrokers()...) {
+		if reconnector, ok := broker.(queue.Reconnector); ok {
+			health.Reconnects += reconnector.Reconnects()
+		}
+	}
+
+	switch {
+	case w.stopped.Load():
+		health.Status = HealthStopped
+	case providerPaused(w.fcmClient.Status()):
+		health.Status = HealthPaused
+	case (health.InFlight > 0 || health.Waiting > 0) && time.Since(progress) > w.stallTimeout:
+		health.Status = HealthStalled
+	}
+	return health
+}
+
+// waiting counts the messages waiting in the queues the worker consumes on
+// its primary broker
+func (w *Worker) waiting(ctx context.Context) int64 {
+	broker := w.pushQueue.Broker()
+	queues := []string{queue.GatewayPushQueueName}
+	for _, priority := range queue.Priorities {
+		queues = append(queues, queue.PushQueueFor(priority))
+	}
+
+	var waiting int64
+	for _, name := range queues {
+		length, err := broker.Stats(ctx, name)
+		if err != nil {
+			zap.L().Warn("Failed to get queue length", zap.String("queue", name), zap.Error(err))
+			continue
+		}
+		waiting += length
+	}
+	return waiting
+}
@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"push-service/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// Jobs are the time-based background jobs: campaign pacing, alert rules,
+// deferred deliveries and digests, device cleanup, the retention purge, the
+// outbox relay and the dead letter archiver. The worker runs them unless
+// they run in the scheduler process (cmd/scheduler).
+type Jobs struct {
+	campaigns     service.CampaignService
+	alerts        service.AlertService
+	scheduler     service.SchedulerService
+	deviceCleanup service.DeviceCleanupService
+	retention     service.RetentionService
+	outbox        service.OutboxRelay
+	archiver      service.DeadLetterArchiver
+
+	running sync.WaitGroup
+}
+
+func NewJobs(campaigns service.CampaignService, alerts service.AlertService, scheduler service.SchedulerService, deviceCleanup service.DeviceCleanupService, retention service.RetentionService, outbox service.OutboxRelay, archiver service.DeadLetterArchiver) *Jobs {
+	return &Jobs{
+		campaigns:     campaigns,
+		alerts:        alerts,
+		scheduler:     scheduler,
+		deviceCleanup: deviceCleanup,
+		retention:     retention,
+		outbox:        outbox,
+		archiver:      archiver,
+	}
+}
+
+// Start runs each job in its own goroutine until ctx is cancelled
+func (j *Jobs) Start(ctx context.Context) {
+	// Pace running campaigns against the FCM quotas
+	j.run(ctx, j.campaigns.Run)
+
+	// Evaluate the alert rules
+	j.run(ctx, j.alerts.Run)
+
+	// Enqueue deferred deliveries and digests once they are due
+	j.run(ctx, j.scheduler.Run)
+
+	// Deactivate unseen devices and delete long-deactivated ones
+	j.run(ctx, j.deviceCleanup.Run)
+
+	// Purge notifications and delivery logs past their retention
+	j.run(ctx, j.retention.Run)
+
+	// Publish the sends' outbox messages they couldn't publish themselves
+	j.run(ctx, j.outbox.Run)
+
+	// Move old dead letters to the archive bucket before they expire
+	j.run(ctx, j.archiver.Run)
+
+	zap.L().Info("Background jobs started")
+}
+
+func (j *Jobs) run(ctx context.Context, job func(context.Context)) {
+	j.running.Add(1)
+	go func() {
+		defer j.running.Done()
+		job(ctx)
+	}()
+}
+
+// Wait blocks until every job has returned after ctx was cancelled, so their
+// locks are released before the connections close
+func (j *Jobs) Wait() {
+	j.running.Wait()
+}
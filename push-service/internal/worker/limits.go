@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"errors"
+	"math"
+	"runtime"
+	"runtime/debug"
+
+	"push-service/internal/config"
+
+	"github.com/KimMachineGun/automemlimit/memlimit"
+	"go.uber.org/automaxprocs/maxprocs"
+	"go.uber.org/zap"
+)
+
+// ErrConcurrencyLimit is returned when a requested pool size exceeds what the
+// available CPU and memory can sustain.
+var ErrConcurrencyLimit = errors.New("concurrency exceeds resource limit")
+
+// ErrInvalidSettings is returned for a prefetch count or concurrency that
+// isn't positive
+var ErrInvalidSettings = errors.New("invalid worker settings")
+
+// ConfigureRuntime aligns GOMAXPROCS and GOMEMLIMIT with the container's
+// cgroup CPU quota and memory limit. Without this, Go sizes itself to the
+// host's cores and memory and gets throttled under Kubernetes limits.
+func ConfigureRuntime(cfg *config.WorkerConfig) {
+	if _, err := maxprocs.Set(maxprocs.Logger(zap.S().Debugf)); err != nil {
+		zap.L().Warn("Failed to set GOMAXPROCS from CPU quota", zap.Error(err))
+	}
+
+	ratio := cfg.MemoryLimitRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 0.9 // default
+	}
+	limit, err := memlimit.SetGoMemLimitWithOpts(
+		memlimit.WithRatio(ratio),
+		memlimit.WithProvider(memlimit.ApplyFallback(memlimit.FromCgroup, memlimit.FromSystem)),
+	)
+	if err != nil {
+		zap.L().Warn("Failed to set GOMEMLIMIT from memory limit", zap.Error(err))
+	}
+
+	zap.L().Info("Runtime limits configured",
+		zap.Int("gomaxprocs", runtime.GOMAXPROCS(0)),
+		zap.Int64("gomemlimit_bytes", limit),
+	)
+}
+
+// MaxConcurrency returns the largest pool size the process can sustain:
+// a per-CPU multiple of GOMAXPROCS, further capped by how many handlers fit
+// in the Go memory limit.
+func MaxConcurrency(cfg *config.WorkerConfig) int {
+	perCPU := cfg.MaxConcurrencyPerCPU
+	if perCPU <= 0 {
+		perCPU = 8 // default
+	}
+	max := runtime.GOMAXPROCS(0) * perCPU
+
+	perHandlerMB := cfg.MemoryPerHandlerMB
+	if perHandlerMB <= 0 {
+		perHandlerMB = 16 // default
+	}
+	if memLimit := debug.SetMemoryLimit(-1); memLimit > 0 && memLimit != math.MaxInt64 {
+		if byMemory := int(memLimit / (int64(perHandlerMB) << 20)); byMemory < max {
+			max = byMemory
+		}
+	}
+
+	if max < 1 {
+		max = 1
+	}
+	return max
+}
@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool bounds the number of goroutines processing queue messages. Unlike a
+// fixed semaphore, its size can be changed while messages are in flight:
+// shrinking lets running handlers finish and only delays new ones.
+type Pool struct {
+	mu      sync.Mutex
+	size    int
+	active  int
+	changed chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewPool creates a pool that runs at most size handlers at once
+func NewPool(size int) *Pool {
+	if size <= 0 {
+		size = 1
+	}
+	return &Pool{
+		size:    size,
+		changed: make(chan struct{}),
+	}
+}
+
+// Go blocks until a slot is free, then runs fn in its own goroutine.
+// It returns ctx.Err() without running fn if ctx is cancelled first.
+func (p *Pool) Go(ctx context.Context, fn func()) error {
+	if err := p.acquire(ctx); err != nil {
+		return err
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.release()
+		fn()
+	}()
+	return nil
+}
+
+// Wait blocks until all handlers started with Go have returned
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Pool) acquire(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		if p.active < p.size {
+			p.active++
+			p.mu.Unlock()
+			return nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *Pool) release() {
+	p.mu.Lock()
+	p.active--
+	p.notifyLocked()
+	p.mu.Unlock()
+}
+
+// notifyLocked wakes every goroutine waiting in acquire. Callers must hold p.mu.
+func (p *Pool) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// Resize changes the maximum number of concurrent handlers
+func (p *Pool) Resize(size int) {
+	if size <= 0 {
+		size = 1
+	}
+	p.mu.Lock()
+	p.size = size
+	p.notifyLocked()
+	p.mu.Unlock()
+}
+
+// Size returns the maximum number of concurrent handlers
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// Active returns the number of handlers currently running
+func (p *Pool) Active() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.active
+}
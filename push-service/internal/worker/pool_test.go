@@ -0,0 +1,61 @@
+package worker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"push-service/internal/worker"
+)
+
+func TestPoolResizeWakesWaitingHandlers(t *testing.T) {
+	pool := worker.NewPool(1)
+	release := make(chan struct{})
+	block := func() { <-release }
+	ctx := context.Background()
+
+	if err := pool.Go(ctx, block); err != nil {
+		t.Fatalf("Go: %v", err)
+	}
+	// The pool is full, the next handler waits
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Go(short, block); err != context.DeadlineExceeded {
+		t.Fatalf("got %v starting a second handler, want it to wait", err)
+	}
+
+	started := make(chan error, 1)
+	go func() { started <- pool.Go(ctx, block) }()
+	pool.Resize(2)
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatalf("Go: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("got the waiting handler still blocked after growing the pool")
+	}
+	if size, active := pool.Size(), pool.Active(); size != 2 || active != 2 {
+		t.Errorf("got size %d with %d active, want 2 and 2", size, active)
+	}
+
+	// Shrinking keeps the handlers running, new ones wait for them
+	pool.Resize(1)
+	close(release)
+	pool.Wait()
+	if active := pool.Active(); active != 0 {
+		t.Errorf("got %d active after Wait, want 0", active)
+	}
+	if err := pool.Go(ctx, func() {}); err != nil {
+		t.Errorf("Go after shrinking: %v", err)
+	}
+	pool.Wait()
+}
+
+func TestPoolResizeFloorsAtOne(t *testing.T) {
+	pool := worker.NewPool(4)
+	pool.Resize(0)
+	if size := pool.Size(); size != 1 {
+		t.Errorf("got size %d, want 1", size)
+	}
+}
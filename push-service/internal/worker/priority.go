@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"reflect"
+
+	"push-service/internal/queue"
+)
+
+// prioritize merges msgs, given highest priority first, into one channel.
+// Whenever the worker is ready for a delivery it gets one from the highest
+// priority channel that has one ready, so lower priorities are only served
+// while the higher ones are empty. The result is closed once every input is.
+func prioritize(ctx context.Context, msgs []<-chan queue.Delivery) <-chan queue.Delivery {
+	out := make(chan queue.Delivery)
+
+	go func() {
+		defer close(out)
+
+		open := append([]<-chan queue.Delivery{}, msgs...)
+		remaining := len(open)
+		for remaining > 0 {
+			delivery, ok, i := next(open)
+			if !ok {
+				// Closed; a nil channel is never selected again
+				open[i] = nil
+				remaining--
+				continue
+			}
+
+			select {
+			case out <- delivery:
+			case <-ctx.Done():
+				requeue(delivery)
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// next receives from the first channel in open with a delivery ready, or
+// else waits for any of them. It returns the index of the channel received
+// from; ok is false if that channel is closed.
+func next(open []<-chan queue.Delivery) (queue.Delivery, bool, int) {
+	for i, ch := range open {
+		if ch == nil {
+			continue
+		}
+		select {
+		case delivery, ok := <-ch:
+			return delivery, ok, i
+		default:
+		}
+	}
+
+	cases := make([]reflect.SelectCase, len(open))
+	for i, ch := range open {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
+	}
+	i, value, ok := reflect.Select(cases)
+	if !ok {
+		return queue.Delivery{}, false, i
+	}
+	return value.Interface().(queue.Delivery), true, i
+}
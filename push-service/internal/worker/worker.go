@@ -0,0 +1,448 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/queue"
+	"push-service/internal/service"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Settings describes the worker's current concurrency settings
+type Settings struct {
+	PrefetchCount  int `json:"prefetch_count" example:"10"`
+	Concurrency    int `json:"concurrency" example:"4"`
+	MaxConcurrency int `json:"max_concurrency" example:"32"`
+	Active         int `json:"active" example:"2"`
+	// SpeedFactor is below 1 while the worker is slowed down because FCM
+	// fails many sends: prefetch and concurrency are that share of what
+	// they were set to
+	SpeedFactor float64 `json:"speed_factor" example:"1"`
+}
+
+// Worker consumes the internal and gateway push queues, plus the gateway
+// queue on any regional brokers, and dispatches each delivery to a bounded
+// pool of goroutines. It also runs the time-based background jobs, unless
+// they run in the scheduler process.
+type Worker struct {
+	pushService    service.PushService
+	alerts         service.AlertService
+	jobs           *Jobs
+	pushQueue      *queue.PushQueue
+	fcmClient      fcm.FCMClient
+	pool           *Pool
+	maxConcurrency int
+	recoverPanics  bool
+	gatewayBrokers []config.RabbitMQConfig
+	ackBatchSize   int // ack batching of the regional gateway brokers
+	ackInterval    time.Duration
+	stallTimeout   time.Duration
+
+	// Progress, for Health
+	startedAt     time.Time
+	lastProcessed atomic.Int64 // unix nanoseconds, 0 before the first
+	stopped       atomic.Bool
+
+	backpressureCfg config.BackpressureConfig
+	backpressure    backpressure
+
+	autoscaleCfg config.AutoscaleConfig
+	autoscale    autoscale
+
+	// Set by Start: stopConsuming cancels the consumers, stopProcessing the
+	// handlers of messages already received
+	stopConsuming  context.CancelFunc
+	stopProcessing context.CancelFunc
+	consuming      sync.WaitGroup // the dispatch loops
+	flushed        chan struct{}  // closed once the alert counts are flushed
+
+	mu            sync.Mutex
+	remotes       []queue.Broker // regional gateway brokers, closed by Stop
+	remotesClosed bool
+}
+
+// New creates the worker. jobs is nil if the background jobs run elsewhere.
+func New(pushService service.PushService, alerts service.AlertService, jobs *Jobs, pushQueue *queue.PushQueue, fcmClient fcm.FCMClient, cfg *config.QueueConfig) *Worker {
+	concurrency := cfg.Worker.Concurrency
+	if concurrency == 0 {
+		concurrency = 10 // default
+	}
+
+	stallTimeout := cfg.Worker.StallTimeout
+	if stallTimeout <= 0 {
+		stallTimeout = 5 * time.Minute // default
+	}
+
+	maxConcurrency := MaxConcurrency(&cfg.Worker)
+	if concurrency > maxConcurrency {
+		zap.L().Warn("Configured worker concurrency exceeds resource limit, capping",
+			zap.Int("concurrency", concurrency),
+			zap.Int("max_concurrency", maxConcurrency),
+		)
+		concurrency = maxConcurrency
+	}
+
+	worker := &Worker{
+		pushService:    pushService,
+		alerts:         alerts,
+		jobs:           jobs,
+		pushQueue:      pushQueue,
+		fcmClient:      fcmClient,
+		pool:           NewPool(concurrency),
+		maxConcurrency: maxConcurrency,
+		recoverPanics:  cfg.Worker.RecoverPanics,
+		gatewayBrokers: cfg.Gateway.Brokers,
+		ackBatchSize:   cfg.Worker.AckBatchSize,
+		ackInterval:    cfg.Worker.AckInterval,
+		stallTimeout:   stallTimeout,
+		startedAt:      time.Now(),
+
+		backpressureCfg: cfg.Worker.Backpressure,
+		autoscaleCfg:    cfg.Worker.Autoscale,
+	}
+	worker.backpressure.factor = 1
+	metrics.WorkerSpeedFactor.Set(1)
+	metrics.WorkerConcurrency.Set(float64(concurrency))
+	return worker
+}
+
+// Start begins consuming both queues. Consumption stops when ctx is
+// cancelled or on Stop, but messages being processed are only waited for by
+// Stop.
+func (w *Worker) Start(ctx context.Context) error {
+	ctx, w.stopConsuming = context.WithCancel(ctx)
+	// Handlers outlive the consumers, until Stop gives up on them
+	processCtx, stopProcessing := context.WithCancel(context.WithoutCancel(ctx))
+	w.stopProcessing = stopProcessing
+
+	// Start consuming messages from internal queues, highest priority first
+	msgs, err := w.pushQueue.ConsumePush(ctx)
+	if err != nil {
+		w.stopConsuming()
+		return fmt.Errorf("failed to start consuming messages from internal queue: %w", err)
+	}
+	w.consume(func() {
+		w.run(ctx, processCtx, prioritize(ctx, msgs), "internal", w.pushService.ProcessPushFromQueue)
+	})
+
+	// Start consuming messages from API Gateway queue
+	gatewayMsgs, err := w.pushQueue.ConsumeFromGateway(ctx)
+	if err != nil {
+		w.stopConsuming()
+		return fmt.Errorf("failed to start consuming messages from gateway queue: %w", err)
+	}
+	w.consume(func() {
+		w.run(ctx, processCtx, gatewayMsgs, "gateway", w.pushService.ProcessGatewayMessage)
+	})
+
+	// One consumer per regional gateway broker
+	for _, broker := range w.gatewayBrokers {
+		w.consume(func() { w.consumeRemoteGateway(ctx, processCtx, broker) })
+	}
+
+	// Slow down while FCM fails many sends
+	go w.runBackpressure(ctx)
+
+	// Export the backlog for autoscalers, and size the pool to it if enabled
+	go w.runAutoscale(ctx)
+
+	// Store the delivery counts of the alert rules, until the last handler
+	// has counted its sends
+	w.flushed = make(chan struct{})
+	go func() {
+		defer close(w.flushed)
+		w.alerts.RunFlusher(processCtx)
+	}()
+
+	if w.jobs != nil {
+		w.jobs.Start(ctx)
+	}
+
+	zap.L().Info("Push workers started (internal and gateway queues)",
+		zap.Int("prefetch_count", w.pushQueue.Broker().Prefetch()),
+		zap.Int("concurrency", w.pool.Size()),
+		zap.Int("regional_gateway_brokers", len(w.gatewayBrokers)),
+		zap.Bool("background_jobs", w.jobs != nil),
+	)
+	return nil
+}
+
+// Stop stops consuming and waits until the messages being processed are
+// done, or ctx is. Handlers still running then are cancelled, and their
+// messages go back to the queue once the broker connection closes. The
+// regional gateway brokers are closed here, after their pending acks are
+// flushed; the primary broker is closed by its owner. The alert counts are
+// flushed last. It returns ctx.Err() if handlers had to be cancelled.
+func (w *Worker) Stop(ctx context.Context) error {
+	w.stopped.Store(true)
+	w.stopConsuming()
+	zap.L().Info("Push worker stopped consuming, draining in-flight messages",
+		zap.Int("in_flight", w.pool.Active()),
+	)
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		// No handler starts once the dispatch loops have returned
+		w.consuming.Wait()
+		w.pool.Wait()
+		if w.jobs != nil {
+			w.jobs.Wait()
+		}
+	}()
+
+	var err error
+	select {
+	case <-drained:
+		zap.L().Info("Push worker drained")
+	case <-ctx.Done():
+		err = ctx.Err()
+		zap.L().Warn("Push worker drain deadline passed, cancelling in-flight messages",
+			zap.Int("in_flight", w.pool.Active()),
+		)
+	}
+
+	w.stopProcessing()
+	<-w.flushed
+	// The acks of the messages just drained may still be batched
+	remotes := w.closeRemotes()
+	for _, broker := range append([]queue.Broker{w.pushQueue.Broker()}, remotes...) {
+		if flusher, ok := broker.(queue.AckFlusher); ok {
+			flusher.FlushAcks()
+		}
+	}
+	for _, remote := range remotes {
+		if err := remote.Close(); err != nil {
+			zap.L().Warn("Failed to close regional gateway broker", zap.Error(err))
+		}
+	}
+	return err
+}
+
+// consume runs a dispatch loop in its own goroutine, for Stop to wait for
+func (w *Worker) consume(loop func()) {
+	w.consuming.Add(1)
+	go func() {
+		defer w.consuming.Done()
+		loop()
+	}()
+}
+
+// run dispatches msgs to the pool until ctx is cancelled or msgs closes.
+// Handlers run with processCtx, so they finish after consumption stops.
+func (w *Worker) run(ctx, processCtx context.Context, msgs <-chan queue.Delivery, source string, process func(context.Context, queue.Delivery) error) {
+	for {
+		var delivery queue.Delivery
+		select {
+		case <-ctx.Done():
+			return
+		case d, ok := <-msgs:
+			if !ok {
+				return
+			}
+			delivery = d
+		}
+
+		if err := w.waitForProvider(ctx); err != nil {
+			requeue(delivery)
+			return
+		}
+		if err := w.waitBackpressure(ctx); err != nil {
+			requeue(delivery)
+			return
+		}
+		err := w.pool.Go(ctx, func() {
+			if w.recoverPanics {
+				defer w.recoverMessage(processCtx, source, delivery)
+			}
+			defer w.lastProcessed.Store(time.Now().UnixNano())
+			if err := process(processCtx, delivery); err != nil {
+				zap.L().Error("Failed to process push message",
+					zap.String("source", source),
+					zap.Error(err),
+					zap.String("message_id", delivery.ID),
+				)
+			}
+		})
+		if err != nil {
+			// Stopped while waiting for a free slot
+			requeue(delivery)
+			return
+		}
+	}
+}
+
+// requeue puts a delivery that won't be processed back on its queue
+func requeue(delivery queue.Delivery) {
+	if err := delivery.Nack(true); err != nil {
+		zap.L().Warn("Failed to requeue message", zap.String("message_id", delivery.ID), zap.Error(err))
+	}
+}
+
+// recoverMessage recovers from a panic while processing delivery, so one
+// poison message can't take the consumer down with it. Internal messages go
+// to the retry queue with the panic as their last error, and reach the dead
+// letter queue like any other failure if they keep panicking. Gateway
+// messages and messages that can't be decoded are rejected without requeue.
+func (w *Worker) recoverMessage(ctx context.Context, source string, delivery queue.Delivery) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	metrics.MessagePanics.WithLabelValues(source).Inc()
+	reason := fmt.Sprintf("panic: %v", r)
+	zap.L().Error("Recovered from panic while processing message",
+		zap.String("source", source),
+		zap.String("message_id", delivery.ID),
+		zap.String("reason", reason),
+		zap.ByteString("stack", debug.Stack()),
+	)
+
+	if pushMessage, err := queue.DecodePushMessage(delivery.Body); source == "internal" && err == nil {
+		pushMessage.LastError = reason
+		err := w.pushQueue.EnqueueRetry(ctx, pushMessage)
+		if err == nil {
+			if err := delivery.Ack(); err != nil {
+				zap.L().Error("Failed to ack message", zap.Error(err))
+			}
+			return
+		}
+		zap.L().Error("Failed to enqueue retry", zap.Error(err))
+	}
+
+	// The delivery may already be settled if the panic came after that
+	if err := delivery.Nack(false); err != nil {
+		zap.L().Warn("Failed to nack message", zap.Error(err))
+	}
+}
+
+// waitForProvider pauses dispatch while FCM is rejecting our credentials or
+// our sends are paused after a quota error. Deliveries stay unacked on the
+// broker instead of being retried into the dead letter queue; dispatch
+// resumes once the credentials are reloaded and FCM's Retry-After has passed.
+func (w *Worker) waitForProvider(ctx context.Context) error {
+	if !providerPaused(w.fcmClient.Status()) {
+		return nil
+	}
+
+	zap.L().Warn("Pausing queue consumption until FCM accepts sends again",
+		zap.Any("provider_status", w.fcmClient.Status()),
+	)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for providerPaused(w.fcmClient.Status()) {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	zap.L().Info("FCM accepts sends again, resuming queue consumption")
+	return nil
+}
+
+func providerPaused(status fcm.ProviderStatus) bool {
+	return status.Status == fcm.ProviderStatusAuthFailed || status.ThrottledUntil != nil
+}
+
+// Settings returns the current prefetch and concurrency settings
+func (w *Worker) Settings() Settings {
+	w.backpressure.mu.Lock()
+	factor := w.backpressure.factor
+	w.backpressure.mu.Unlock()
+
+	return Settings{
+		PrefetchCount:  w.pushQueue.Broker().Prefetch(),
+		Concurrency:    w.pool.Size(),
+		MaxConcurrency: w.maxConcurrency,
+		Active:         w.pool.Active(),
+		SpeedFactor:    factor,
+	}
+}
+
+// Update applies new prefetch and/or concurrency settings without restarting
+// consumption. Nil values are left unchanged. While the worker is slowed
+// down, they are the settings it returns to once FCM recovers. Both values
+// are validated before either is applied, so a rejected update changes
+// nothing.
+func (w *Worker) Update(prefetchCount, concurrency *int) (Settings, error) {
+	if prefetchCount != nil && *prefetchCount <= 0 {
+		return w.Settings(), fmt.Errorf("%w: prefetch count must be positive, got %d", ErrInvalidSettings, *prefetchCount)
+	}
+	if concurrency != nil {
+		if *concurrency <= 0 {
+			return w.Settings(), fmt.Errorf("%w: concurrency must be positive, got %d", ErrInvalidSettings, *concurrency)
+		}
+		if *concurrency > w.maxConcurrency {
+			return w.Settings(), fmt.Errorf("%w: requested %d, max %d", ErrConcurrencyLimit, *concurrency, w.maxConcurrency)
+		}
+	}
+
+	w.backpressure.mu.Lock()
+	if w.backpressure.factor < 1 {
+		if prefetchCount != nil {
+			w.backpressure.prefetch = *prefetchCount
+		}
+		if concurrency != nil {
+			w.backpressure.concurrency = *concurrency
+		}
+		w.applySpeedLocked()
+		w.backpressure.mu.Unlock()
+		zap.L().Info("Worker settings updated, scaled down by backpressure")
+		return w.Settings(), nil
+	}
+	w.backpressure.mu.Unlock()
+
+	if prefetchCount != nil {
+		if err := w.setPrefetch(*prefetchCount); err != nil {
+			return w.Settings(), err
+		}
+	}
+
+	if concurrency != nil {
+		w.pool.Resize(*concurrency)
+		zap.L().Info("Worker concurrency updated", zap.Int("concurrency", *concurrency))
+	}
+
+	return w.Settings(), nil
+}
+
+// SetRetryLimits changes the max retries of failed sends, see
+// queue.PushQueue.SetRetryLimits
+func (w *Worker) SetRetryLimits(maxRetries, maxRetriesCap int) {
+	w.pushQueue.SetRetryLimits(maxRetries, maxRetriesCap)
+	zap.L().Info("Retry limits updated",
+		zap.Int("max_retries", maxRetries),
+		zap.Int("max_retries_cap", maxRetriesCap),
+	)
+}
+
+// setPrefetch changes the prefetch count on the broker and the regional
+// gateway brokers. If one of them fails, those already changed are set back.
+func (w *Worker) setPrefetch(prefetchCount int) error {
+	previous := w.pushQueue.Broker().Prefetch()
+	brokers := append([]queue.Broker{w.pushQueue.Broker()}, w.remoteBrokers()...)
+	for i, broker := range brokers {
+		if err := broker.SetPrefetch(prefetchCount); err != nil {
+			for _, changed := range brokers[:i] {
+				if err := changed.SetPrefetch(previous); err != nil {
+					zap.L().Warn("Failed to restore prefetch count", zap.Error(err))
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
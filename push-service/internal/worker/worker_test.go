@@ -0,0 +1,309 @@
+package worker_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/internal/models"
+	"push-service/internal/platform/fcm"
+	"push-service/internal/platform/fcm/fcmtest"
+	"push-service/internal/queue"
+	"push-service/internal/repository"
+	"push-service/internal/service"
+	"push-service/internal/worker"
+)
+
+// attempts stands in for the delivery attempt table: it keeps each
+// notification's attempts and latest result per token
+type attempts struct {
+	repository.DeliveryAttemptRepository
+
+	mu       sync.Mutex
+	attempts []models.DeliveryAttempt
+	results  map[string]map[string]bool
+}
+
+func (a *attempts) Create(ctx context.Context, attempt *models.DeliveryAttempt) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.attempts = append(a.attempts, *attempt)
+	return nil
+}
+
+func (a *attempts) SaveTokenResults(ctx context.Context, notificationID, userID string, results []models.AttemptTokenResult) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.results == nil {
+		a.results = make(map[string]map[string]bool)
+	}
+	if a.results[notificationID] == nil {
+		a.results[notificationID] = make(map[string]bool)
+	}
+	for _, result := range results {
+		a.results[notificationID][result.DeviceToken] = result.Success
+	}
+	return nil
+}
+
+func (a *attempts) ListTokenResults(ctx context.Context, notificationID, token string) ([]models.TokenDeliveryResult, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var results []models.TokenDeliveryResult
+	for token, success := range a.results[notificationID] {
+		results = append(results, models.TokenDeliveryResult{Token: token, Success: success})
+	}
+	return results, nil
+}
+
+func (a *attempts) list() []models.DeliveryAttempt {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]models.DeliveryAttempt(nil), a.attempts...)
+}
+
+// devices records the tokens the worker deactivates
+type devices struct {
+	repository.DeviceRepository
+
+	mu       sync.Mutex
+	inactive []string
+}
+
+func (d *devices) UpdateStatus(ctx context.Context, token string, isActive bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !isActive {
+		d.inactive = append(d.inactive, token)
+	}
+	return nil
+}
+
+func (d *devices) deactivated() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.inactive...)
+}
+
+type quotas struct{ repository.QuotaRepository }
+
+func (quotas) Add(ctx context.Context, projectID string, day time.Time, n int64) error { return nil }
+
+type analytics struct{ repository.AnalyticsRepository }
+
+func (analytics) Add(ctx context.Context, day time.Time, platform string, counts models.DeliveryCounts) error {
+	return nil
+}
+
+type webhooks struct{ service.WebhookService }
+
+func (webhooks) Notify(ctx context.Context, tenantID, eventType string, data map[string]any) {}
+
+type inbox struct{ service.InboxService }
+
+func (inbox) Record(ctx context.Context, notification models.PushNotification) {}
+
+type userData struct{ service.UserDataService }
+
+func (userData) Erased(ctx context.Context, userID string, createdAt time.Time) bool { return false }
+
+// harness runs a worker on the memory broker, sending to a fake FCM
+type harness struct {
+	broker   *queue.MemoryBroker
+	queue    *queue.PushQueue
+	worker   *worker.Worker
+	attempts *attempts
+	devices  *devices
+}
+
+func newHarness(t *testing.T, srv *fcmtest.Server, maxRetries int) *harness {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.FCM = *srv.Config()
+	cfg.Queue.Retry = config.RetryConfig{MaxRetries: maxRetries, MaxRetriesCap: maxRetries, Tiers: []time.Duration{10 * time.Millisecond}}
+	cfg.Queue.Worker.Concurrency = 1
+
+	client, err := fcm.NewFCMClient(&cfg.FCM, nil)
+	if err != nil {
+		t.Fatalf("NewFCMClient: %v", err)
+	}
+	projects, err := fcm.NewProjects(client, &cfg.FCM, nil)
+	if err != nil {
+		t.Fatalf("NewProjects: %v", err)
+	}
+
+	h := &harness{broker: queue.NewMemoryBroker(), attempts: &attempts{}, devices: &devices{}}
+	h.queue, err = queue.NewPushQueue(h.broker, &cfg.Queue, &cfg.Retention)
+	if err != nil {
+		t.Fatalf("NewPushQueue: %v", err)
+	}
+	alerts := service.NewAlertService(nil, h.queue, nil, cfg)
+	push := service.NewPushService(service.PushDeps{
+		DeviceRepo:    h.devices,
+		QuotaRepo:     quotas{},
+		AttemptRepo:   h.attempts,
+		AnalyticsRepo: analytics{},
+		Projects:      projects,
+		Alerts:        alerts,
+		Webhooks:      webhooks{},
+		Inbox:         inbox{},
+		UserData:      userData{},
+		PushQueue:     h.queue,
+	}, cfg)
+	h.worker = worker.New(push, alerts, nil, h.queue, client, &cfg.Queue)
+
+	if err := h.worker.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		h.worker.Stop(ctx)
+		h.broker.Close()
+	})
+	return h
+}
+
+func (h *harness) send(t *testing.T, id string, tokens ...string) {
+	t.Helper()
+	err := h.queue.EnqueueMessage(context.Background(), queue.PushMessage{
+		Notification: models.PushNotification{ID: id, UserID: "user-1", Title: "Hi", Body: "There", CreatedAt: time.Now()},
+		DeviceTokens: tokens,
+	})
+	if err != nil {
+		t.Fatalf("EnqueueMessage: %v", err)
+	}
+}
+
+// waitForAttempts waits until n delivery attempts were recorded
+func (h *harness) waitForAttempts(t *testing.T, n int) []models.DeliveryAttempt {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if attempts := h.attempts.list(); len(attempts) >= n {
+			return attempts
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("got %d delivery attempts, want %d", len(h.attempts.list()), n)
+	return nil
+}
+
+// depth returns how many messages queue holds, delayed ones included
+func (h *harness) depth(t *testing.T, name string) int64 {
+	t.Helper()
+	n, err := h.broker.Stats(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Stats(%s): %v", name, err)
+	}
+	return n
+}
+
+// sentTokens returns the tokens FCM was asked to deliver to, in the order
+// it got them
+func sentTokens(srv *fcmtest.Server) []string {
+	var tokens []string
+	for _, send := range srv.Sends() {
+		tokens = append(tokens, send.Message.Token)
+	}
+	return tokens
+}
+
+func TestWorkerAcksDeliveredMessage(t *testing.T) {
+	srv := fcmtest.NewServer()
+	defer srv.Close()
+	h := newHarness(t, srv, 3)
+
+	h.send(t, "n-delivered", "token-a", "token-b")
+	attempts := h.waitForAttempts(t, 1)
+
+	attempt := attempts[0]
+	if attempt.SuccessCount != 2 || attempt.FailureCount != 0 || attempt.NextQueue != nil {
+		t.Fatalf("got %d delivered, %d failed and next queue %v, want 2 delivered and the message settled", attempt.SuccessCount, attempt.FailureCount, attempt.NextQueue)
+	}
+	// Give a redelivery or a retry the time to show up
+	time.Sleep(100 * time.Millisecond)
+	if n := len(h.attempts.list()); n != 1 {
+		t.Fatalf("got %d attempts, want the message acked after the first", n)
+	}
+	if n := h.depth(t, queue.PushQueueName); n != 0 {
+		t.Errorf("push queue holds %d messages, want 0", n)
+	}
+	if n := h.depth(t, queue.DeadLetterQueue); n != 0 {
+		t.Errorf("dead letter queue holds %d messages, want 0", n)
+	}
+}
+
+func TestWorkerRetriesFailedTokensThenDeadLetters(t *testing.T) {
+	srv := fcmtest.NewServer()
+	defer srv.Close()
+	srv.FailToken("stale-token", fcmtest.Unregistered)
+	srv.FailToken("bad-token", fcmtest.InvalidArgument)
+	h := newHarness(t, srv, 1)
+
+	h.send(t, "n-partial", "good-token", "stale-token", "bad-token")
+	attempts := h.waitForAttempts(t, 2)
+
+	first, retry := attempts[0], attempts[1]
+	if first.SuccessCount != 1 || first.FailureCount != 2 {
+		t.Fatalf("first attempt: got %d delivered and %d failed, want 1 and 2", first.SuccessCount, first.FailureCount)
+	}
+	if first.NextQueue == nil || *first.NextQueue == queue.DeadLetterQueue {
+		t.Fatalf("first attempt: got next queue %v, want a retry queue", first.NextQueue)
+	}
+	// The retry skips the delivered token and the unregistered one
+	if retry.RetryCount != 1 || len(retry.Results) != 1 || retry.Results[0].DeviceToken != "bad-token" {
+		t.Fatalf("retry: got retry count %d with results %+v, want bad-token alone on the first retry", retry.RetryCount, retry.Results)
+	}
+	if retry.NextQueue == nil || *retry.NextQueue != queue.DeadLetterQueue {
+		t.Fatalf("retry: got next queue %v, want the dead letter queue past the max retries", retry.NextQueue)
+	}
+
+	// The first attempt's batch reaches FCM in any order
+	sent := sentTokens(srv)
+	if len(sent) == 4 {
+		sort.Strings(sent[:3])
+	}
+	if want := []string{"bad-token", "good-token", "stale-token", "bad-token"}; !equal(sent, want) {
+		t.Errorf("got sends to %v, want %v", sent, want)
+	}
+	if got := h.devices.deactivated(); !equal(got, []string{"stale-token"}) {
+		t.Errorf("got deactivated tokens %v, want stale-token", got)
+	}
+
+	// The dead letter carries only the token still undelivered
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	deadLetters, err := h.broker.Consume(ctx, queue.DeadLetterQueue, "test", 1)
+	if err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	var deadLetter queue.Delivery
+	select {
+	case deadLetter = <-deadLetters:
+	case <-ctx.Done():
+		t.Fatal("got no dead letter")
+	}
+	message, err := queue.DecodePushMessage(deadLetter.Body)
+	if err != nil {
+		t.Fatalf("DecodePushMessage: %v", err)
+	}
+	if message.Notification.ID != "n-partial" || !equal(message.DeviceTokens, []string{"bad-token"}) || message.RetryCount != 2 {
+		t.Errorf("got dead letter %s for %v after %d retries, want n-partial for bad-token after 2", message.Notification.ID, message.DeviceTokens, message.RetryCount)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
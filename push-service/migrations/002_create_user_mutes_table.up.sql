@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_mutes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id VARCHAR(255) NOT NULL,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('sender', 'category')),
+    value VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, scope, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_mutes_user_id ON user_mutes(user_id);
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    body TEXT NOT NULL,
+    image TEXT,
+    link TEXT,
+    data JSONB,
+    sender VARCHAR(255),
+    category VARCHAR(255),
+    user_ids TEXT[] NOT NULL,
+    processed_users INTEGER NOT NULL DEFAULT 0,
+    enqueued_users INTEGER NOT NULL DEFAULT 0,
+    enqueued_tokens BIGINT NOT NULL DEFAULT 0,
+    quota_policy VARCHAR(20) NOT NULL CHECK (quota_policy IN ('spread', 'failover')),
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'cancelled')),
+    projected_completion_at TIMESTAMP WITH TIME ZONE,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_status ON campaigns(status);
+
+-- Messages sent per FCM project per UTC day, used to pace campaigns
+CREATE TABLE IF NOT EXISTS fcm_quota_usage (
+    project_id VARCHAR(255) NOT NULL,
+    day DATE NOT NULL,
+    sent BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (project_id, day)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE devices
+    ADD COLUMN IF NOT EXISTS permission_status VARCHAR(20) CHECK (permission_status IN ('granted', 'denied', 'provisional')),
+    ADD COLUMN IF NOT EXISTS permission_updated_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,3 @@
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE devices SET deactivated_at = updated_at WHERE is_active = false AND deactivated_at IS NULL;
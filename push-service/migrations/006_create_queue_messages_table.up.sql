@@ -0,0 +1,15 @@
+-- Job table for the postgres queue backend (QUEUE_BACKEND=postgres)
+CREATE TABLE IF NOT EXISTS queue_messages (
+    id BIGSERIAL PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    body BYTEA NOT NULL,
+    -- Retry queue the message is waiting in until available_at
+    delayed_in VARCHAR(255),
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    redelivered BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_queue_messages_ready ON queue_messages(queue, available_at, id);
+CREATE INDEX IF NOT EXISTS idx_queue_messages_delayed ON queue_messages(delayed_in) WHERE delayed_in IS NOT NULL;
@@ -0,0 +1,22 @@
+-- Dry runs validate sends with FCM without delivering them. Workers may
+-- record results before the API has stored message_count, so both sides
+-- upsert the dry run row.
+CREATE TABLE IF NOT EXISTS push_dry_runs (
+    id UUID PRIMARY KEY,
+    message_count INTEGER,
+    completed_messages INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS push_dry_run_results (
+    id BIGSERIAL PRIMARY KEY,
+    dry_run_id UUID NOT NULL REFERENCES push_dry_runs(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    token TEXT NOT NULL,
+    valid BOOLEAN NOT NULL,
+    error_kind VARCHAR(50),
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_dry_run_results_dry_run_id ON push_dry_run_results(dry_run_id);
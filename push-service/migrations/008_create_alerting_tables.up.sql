@@ -0,0 +1,37 @@
+-- Delivery outcomes per minute, labelled with their platform, project and
+-- category. Workers on every instance add to the same buckets, so alert
+-- rules see the whole deployment. Gauges (the dead letter queue depth) keep
+-- the last sample of the minute instead.
+CREATE TABLE IF NOT EXISTS alert_counts (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    metric VARCHAR(50) NOT NULL,
+    labels JSONB NOT NULL DEFAULT '{}',
+    value BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, metric, labels)
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_counts_metric_bucket ON alert_counts(metric, bucket);
+
+-- Alert rules added through the admin API; rules from the config file are
+-- not stored
+CREATE TABLE IF NOT EXISTS alert_rules (
+    name VARCHAR(255) PRIMARY KEY,
+    metric VARCHAR(50) NOT NULL CHECK (metric IN ('failure_rate', 'failures', 'dead_letters')),
+    filter JSONB NOT NULL DEFAULT '{}',
+    operator VARCHAR(2) NOT NULL CHECK (operator IN ('>', '>=', '<', '<=')),
+    threshold DOUBLE PRECISION NOT NULL,
+    window_duration VARCHAR(50) NOT NULL,
+    min_events BIGINT NOT NULL DEFAULT 0,
+    webhook_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Last evaluation of every rule, config rules included, so a new evaluating
+-- instance doesn't fire alerts that are already firing
+CREATE TABLE IF NOT EXISTS alert_states (
+    rule_name VARCHAR(255) PRIMARY KEY,
+    firing BOOLEAN NOT NULL DEFAULT FALSE,
+    value DOUBLE PRECISION NOT NULL DEFAULT 0,
+    since TIMESTAMP WITH TIME ZONE,
+    evaluated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,19 @@
+-- Tenants are created on their first admin update. While soft launch is
+-- enabled, a tenant without a row is in soft launch with the configured cap.
+CREATE TABLE IF NOT EXISTS tenants (
+    id VARCHAR(255) PRIMARY KEY,
+    soft_launch BOOLEAN NOT NULL DEFAULT TRUE,
+    daily_send_cap INTEGER CHECK (daily_send_cap > 0), -- NULL uses the configured cap
+    test_user_ids TEXT[] NOT NULL DEFAULT '{}',
+    soft_launch_lifted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Sends of tenants in soft launch per UTC day, counted against their cap
+CREATE TABLE IF NOT EXISTS tenant_daily_sends (
+    tenant_id VARCHAR(255) NOT NULL,
+    day DATE NOT NULL,
+    sent BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, day)
+);
@@ -0,0 +1,19 @@
+-- One row per send attempt of a notification, so its detail can show every
+-- attempt, the queue it came from and where it went next
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    notification_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    queue VARCHAR(255) NOT NULL,
+    redelivered BOOLEAN NOT NULL DEFAULT FALSE,
+    project_id VARCHAR(255),
+    results JSONB NOT NULL DEFAULT '[]', -- per-token results
+    success_count INTEGER NOT NULL DEFAULT 0,
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    next_queue VARCHAR(255), -- NULL if the notification was settled
+    attempted_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_attempts_notification_id ON delivery_attempts(notification_id, attempted_at);
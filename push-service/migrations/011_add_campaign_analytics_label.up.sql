@@ -0,0 +1 @@
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS analytics_label VARCHAR(50);
@@ -0,0 +1,11 @@
+-- Delivery counts per UTC day and device platform, updated by the worker as
+-- it sends and by the SDK as notifications are opened
+CREATE TABLE IF NOT EXISTS delivery_rollups (
+    day DATE NOT NULL,
+    platform VARCHAR(20) NOT NULL, -- 'unknown' for tokens without a device
+    sent BIGINT NOT NULL DEFAULT 0,
+    delivered BIGINT NOT NULL DEFAULT 0,
+    failed BIGINT NOT NULL DEFAULT 0,
+    opened BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, platform)
+);
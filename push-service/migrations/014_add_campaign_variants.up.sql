@@ -0,0 +1,15 @@
+-- A/B test variants of a campaign: [{name, title, body, weight}], NULL for
+-- campaigns without variants
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS variants JSONB;
+
+-- Delivery counts per campaign variant, updated by the worker as it sends
+-- and by the SDK as notifications are opened
+CREATE TABLE IF NOT EXISTS campaign_variant_rollups (
+    campaign_id UUID NOT NULL,
+    variant VARCHAR(50) NOT NULL,
+    sent BIGINT NOT NULL DEFAULT 0,
+    delivered BIGINT NOT NULL DEFAULT 0,
+    failed BIGINT NOT NULL DEFAULT 0,
+    opened BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (campaign_id, variant)
+);
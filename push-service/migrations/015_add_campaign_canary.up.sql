@@ -0,0 +1,12 @@
+-- Canary rollout of campaigns: the first canary_users of the (shuffled)
+-- audience are sent to, then the campaign soaks until continue_at, set after
+-- canary_soak_seconds or by an explicit continue
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS canary_percent SMALLINT;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS canary_users INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS canary_soak_seconds INTEGER;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS canary_completed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS continue_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS campaigns_status_check;
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+    CHECK (status IN ('running', 'soaking', 'completed', 'cancelled'));
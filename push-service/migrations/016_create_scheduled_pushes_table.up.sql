@@ -0,0 +1,13 @@
+-- Devices report their IANA timezone, e.g. Europe/Berlin, so sends can be
+-- delivered at a local time
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+
+-- Queue messages held back until deliver_at, then enqueued by the scheduler
+CREATE TABLE IF NOT EXISTS scheduled_pushes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deliver_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    message JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_pushes_deliver_at ON scheduled_pushes(deliver_at);
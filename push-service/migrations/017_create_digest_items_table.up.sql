@@ -0,0 +1,12 @@
+-- Low-priority notifications buffered per user until they are delivered
+-- together as one digest. tenant_id is '' for sends without a tenant.
+CREATE TABLE IF NOT EXISTS digest_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+    user_id VARCHAR(255) NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_digest_items_user ON digest_items(tenant_id, user_id, created_at);
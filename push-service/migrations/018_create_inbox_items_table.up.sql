@@ -0,0 +1,19 @@
+-- Every notification a user was sent, for the app's notification center.
+-- A notification is stored once per user however many messages carried it.
+CREATE TABLE IF NOT EXISTS inbox_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id VARCHAR(255) NOT NULL,
+    notification_id VARCHAR(255) NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+    image TEXT,
+    link TEXT,
+    data JSONB,
+    category VARCHAR(255),
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, notification_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbox_items_user_created ON inbox_items(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_inbox_items_user_unread ON inbox_items(user_id) WHERE read_at IS NULL;
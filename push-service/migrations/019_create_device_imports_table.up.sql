@@ -0,0 +1,18 @@
+-- Bulk device imports. Counts are updated after every batch, so a running
+-- import's progress can be followed; errors holds the first rejected rows.
+CREATE TABLE IF NOT EXISTS device_imports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    format VARCHAR(10) NOT NULL CHECK (format IN ('ndjson', 'csv')),
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    rows_read BIGINT NOT NULL DEFAULT 0,
+    inserted BIGINT NOT NULL DEFAULT 0,
+    updated BIGINT NOT NULL DEFAULT 0,
+    invalid BIGINT NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_imports_created_at ON device_imports(created_at DESC);
@@ -0,0 +1,11 @@
+-- What the client SDK reports about the app and device it runs on. The
+-- columns sends are targeted by are indexed; anything else the app wants to
+-- keep with the device goes in metadata.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS app_version VARCHAR(50);
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS os_version VARCHAR(50);
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS device_model VARCHAR(100);
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS locale VARCHAR(35);
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_devices_platform_app_version ON devices(platform, app_version) WHERE is_active = true;
+CREATE INDEX IF NOT EXISTS idx_devices_locale ON devices(locale) WHERE is_active = true;
@@ -0,0 +1,7 @@
+-- When the app last checked in from the device: on registration, token
+-- refresh and heartbeats. Registrations that stop checking in are zombies.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE devices SET last_seen_at = updated_at WHERE last_seen_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_devices_last_seen_at ON devices(last_seen_at) WHERE is_active = true;
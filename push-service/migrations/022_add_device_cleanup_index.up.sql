@@ -0,0 +1,2 @@
+-- Deactivated devices are deleted by age once past retention
+CREATE INDEX IF NOT EXISTS idx_devices_deactivated_at ON devices(deactivated_at) WHERE is_active = false;
@@ -0,0 +1,6 @@
+-- A stable ID of the physical device (or app installation) from the client
+-- SDK. A new token registered with the same ID replaces the old
+-- registration, so the device isn't sent every notification twice.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS stable_device_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_devices_stable_device_id ON devices(stable_device_id) WHERE stable_device_id IS NOT NULL;
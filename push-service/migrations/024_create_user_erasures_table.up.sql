@@ -0,0 +1,12 @@
+-- Users whose data was deleted, by the SHA-256 hash of their ID, so workers
+-- can drop messages queued for them before the erasure without keeping the
+-- ID itself
+CREATE TABLE IF NOT EXISTS user_erasures (
+    user_hash CHAR(64) PRIMARY KEY,
+    erased_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_erasures_erased_at ON user_erasures(erased_at);
+
+-- Deleting a user's data looks their attempts up by user
+CREATE INDEX IF NOT EXISTS idx_delivery_attempts_user_id ON delivery_attempts(user_id);
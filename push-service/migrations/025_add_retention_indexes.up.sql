@@ -0,0 +1,4 @@
+-- The retention purge deletes rows by age
+CREATE INDEX IF NOT EXISTS idx_inbox_items_created_at ON inbox_items(created_at);
+CREATE INDEX IF NOT EXISTS idx_delivery_attempts_attempted_at ON delivery_attempts(attempted_at);
+CREATE INDEX IF NOT EXISTS idx_push_dry_runs_created_at ON push_dry_runs(created_at);
@@ -0,0 +1,3 @@
+-- The admin device listing filters by user ID prefix, which the plain
+-- user_id index can't serve outside the C collation
+CREATE INDEX IF NOT EXISTS idx_devices_user_id_prefix ON devices(user_id varchar_pattern_ops);
@@ -0,0 +1,15 @@
+-- Queue messages of sends, stored in the same transaction as their
+-- notification record and published from there, so a send is never recorded
+-- without being queued or queued without being recorded
+CREATE TABLE IF NOT EXISTS push_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    message JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_outbox_pending ON push_outbox(id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_push_outbox_published_at ON push_outbox(published_at) WHERE published_at IS NOT NULL;
+
+-- Titles aren't limited by the API
+ALTER TABLE push_notifications ALTER COLUMN title TYPE TEXT;
@@ -0,0 +1,20 @@
+-- The latest delivery result of each device token of a notification, so
+-- retries can skip the tokens already delivered to and support can see why
+-- a device didn't get a notification
+CREATE TABLE IF NOT EXISTS delivery_token_results (
+    id BIGSERIAL PRIMARY KEY,
+    notification_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    token TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    message_id TEXT,
+    error_kind VARCHAR(50),
+    error_code VARCHAR(50),
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (notification_id, token)
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_token_results_user_id ON delivery_token_results(user_id);
+CREATE INDEX IF NOT EXISTS idx_delivery_token_results_updated_at ON delivery_token_results(updated_at);
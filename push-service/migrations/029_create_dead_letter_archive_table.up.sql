@@ -0,0 +1,19 @@
+-- Dead letters moved out of the dead letter queue before they expire. Each is
+-- staged with its body until it is written to object storage, then keeps
+-- only where it was archived: the object and its line in it, to find a
+-- failed notification's messages in the archive.
+CREATE TABLE IF NOT EXISTS dead_letter_archive (
+    id BIGSERIAL PRIMARY KEY,
+    notification_id VARCHAR(255),
+    user_id VARCHAR(255),
+    body BYTEA,
+    dead_lettered_at TIMESTAMP WITH TIME ZONE,
+    staged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    object_url TEXT,
+    line INTEGER,
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letter_archive_notification_id ON dead_letter_archive(notification_id);
+CREATE INDEX IF NOT EXISTS idx_dead_letter_archive_user_id ON dead_letter_archive(user_id);
+CREATE INDEX IF NOT EXISTS idx_dead_letter_archive_pending ON dead_letter_archive(id) WHERE object_url IS NULL;
@@ -0,0 +1,13 @@
+-- Sends of capped categories per user and window, counted against the
+-- category's frequency cap. Rows are purged once their window has ended.
+CREATE TABLE IF NOT EXISTS frequency_counts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    window_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (user_id, category, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_frequency_counts_expires_at ON frequency_counts(expires_at);
@@ -0,0 +1,10 @@
+// Package migrations embeds the database schema migrations, so the service
+// can apply them itself
+package migrations
+
+import "embed"
+
+// FS holds the migrations, named <version>_<name>.up.sql
+//
+//go:embed *.sql
+var FS embed.FS
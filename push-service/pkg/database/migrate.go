@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+
+	"push-service/internal/config"
+	"push-service/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// ErrDirtySchema is returned when a migration failed partway, which needs
+// fixing by hand before migrations can run again
+var ErrDirtySchema = errors.New("database schema is dirty")
+
+// Migrate applies the embedded migrations the database hasn't had yet. It
+// holds a Postgres advisory lock meanwhile, so replicas starting together
+// apply each migration once. The schema_migrations table is the one the
+// migrate CLI uses.
+func Migrate(cfg *config.DatabaseConfig) error {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("unable to read migrations: %w", err)
+	}
+
+	dbURL, err := url.Parse(cfg.GetDatabaseURL())
+	if err != nil {
+		return fmt.Errorf("unable to parse database URL: %w", err)
+	}
+	// The migrate driver for pgx is registered under its own scheme
+	dbURL.Scheme = "pgx5"
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, dbURL.String())
+	if err != nil {
+		return fmt.Errorf("unable to start migrations: %w", err)
+	}
+	defer m.Close()
+
+	from, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("unable to read schema version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d: repair the failed migration and force the version with the migrate CLI", ErrDirtySchema, from)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("unable to apply migrations: %w", err)
+	}
+
+	to, _, err := m.Version()
+	if err != nil {
+		return fmt.Errorf("unable to read schema version: %w", err)
+	}
+	if to != from {
+		zap.L().Info("Database migrated", zap.Uint("from_version", from), zap.Uint("to_version", to))
+	} else {
+		zap.L().Info("Database schema is up to date", zap.Uint("version", to))
+	}
+	return nil
+}
+
+// LatestMigration is the version of the newest embedded migration, the one
+// the code expects the schema to be at
+func LatestMigration() uint {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return 0
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	for err == nil {
+		var next uint
+		if next, err = src.Next(version); err == nil {
+			version = next
+		}
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return 0
+	}
+	return version
+}
+
+// SchemaVersion reads the database's migration version, and whether the
+// last migration failed partway. A database never migrated is at version 0.
+func SchemaVersion(ctx context.Context, pool *pgxpool.Pool) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"push-service/internal/config"
+	"push-service/pkg/metrics"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
@@ -13,6 +14,8 @@ import (
 
 type DB struct {
 	Pool *pgxpool.Pool
+	// Replica is the read replica's pool, nil without one
+	Replica *pgxpool.Pool
 }
 
 func NewPostgresDB(cfg *config.DatabaseConfig) (*DB, error) {
@@ -20,8 +23,29 @@ func NewPostgresDB(cfg *config.DatabaseConfig) (*DB, error) {
 	defer cancel()
 
 	// Use the GetDatabaseURL method
-	dbURL := cfg.GetDatabaseURL()
+	pool, err := newPool(ctx, cfg, cfg.GetDatabaseURL())
+	if err != nil {
+		return nil, err
+	}
+	metrics.RegisterDBPool("primary", pool)
+	zap.L().Info("Connected to PostgreSQL database")
+
+	db := &DB{Pool: pool}
+	if cfg.ReplicaURL != "" {
+		replica, err := newPool(ctx, cfg, cfg.ReplicaURL)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("read replica: %w", err)
+		}
+		db.Replica = replica
+		metrics.RegisterDBPool("replica", replica)
+		zap.L().Info("Connected to PostgreSQL read replica")
+	}
+
+	return db, nil
+}
 
+func newPool(ctx context.Context, cfg *config.DatabaseConfig, dbURL string) (*pgxpool.Pool, error) {
 	poolConfig, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database URL: %w", err)
@@ -30,6 +54,12 @@ func NewPostgresDB(cfg *config.DatabaseConfig) (*DB, error) {
 	poolConfig.MaxConns = int32(cfg.MaxOpenConns)
 	poolConfig.MinConns = int32(cfg.MaxIdleConns)
 	poolConfig.MaxConnLifetime = cfg.ConnMaxLifetime
+	if cfg.ConnMaxIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
@@ -38,15 +68,27 @@ func NewPostgresDB(cfg *config.DatabaseConfig) (*DB, error) {
 
 	// Test connection
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("unable to ping database: %w", err)
 	}
 
-	zap.L().Info("Connected to PostgreSQL database")
-	return &DB{Pool: pool}, nil
+	return pool, nil
+}
+
+// ReadPool is the pool for read-only queries that tolerate replication lag:
+// the replica if there is one, the primary otherwise
+func (db *DB) ReadPool() *pgxpool.Pool {
+	if db.Replica != nil {
+		return db.Replica
+	}
+	return db.Pool
 }
 
 func (db *DB) Close() {
 	if db.Pool != nil {
 		db.Pool.Close()
 	}
+	if db.Replica != nil {
+		db.Replica.Close()
+	}
 }
@@ -0,0 +1,87 @@
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"push-service/internal/config"
+
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+)
+
+// bigQueryWriter streams events into a BigQuery table. Each row's insert ID
+// is the event ID, so BigQuery de-duplicates rows of a retried batch.
+type bigQueryWriter struct {
+	tabledata *bigquery.TabledataService
+	projectID string
+	dataset   string
+	table     string
+}
+
+func newBigQueryWriter(cfg *config.BigQuerySinkConfig) (*bigQueryWriter, error) {
+	var opts []option.ClientOption
+	if cfg.CredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read BigQuery credentials: %w", err)
+		}
+		opts = append(opts, option.WithCredentialsJSON(credentials))
+	}
+
+	service, err := bigquery.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+
+	table := cfg.Table
+	if table == "" {
+		table = "push_delivery_events" // default
+	}
+	return &bigQueryWriter{
+		tabledata: bigquery.NewTabledataService(service),
+		projectID: cfg.ProjectID,
+		dataset:   cfg.Dataset,
+		table:     table,
+	}, nil
+}
+
+func (w *bigQueryWriter) Write(ctx context.Context, events []Event) error {
+	rows := make([]*bigquery.TableDataInsertAllRequestRows, len(events))
+	for i, event := range events {
+		row, err := bigQueryRow(event)
+		if err != nil {
+			return err
+		}
+		rows[i] = &bigquery.TableDataInsertAllRequestRows{InsertId: event.ID, Json: row}
+	}
+
+	resp, err := w.tabledata.InsertAll(w.projectID, w.dataset, w.table, &bigquery.TableDataInsertAllRequest{Rows: rows}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	if len(resp.InsertErrors) > 0 {
+		// Rows are inserted all or none, so the whole batch is retried
+		first := resp.InsertErrors[0]
+		if len(first.Errors) > 0 {
+			return fmt.Errorf("bigquery rejected %d rows, row %d: %s", len(resp.InsertErrors), first.Index, first.Errors[0].Message)
+		}
+		return fmt.Errorf("bigquery rejected %d rows", len(resp.InsertErrors))
+	}
+	return nil
+}
+
+// bigQueryRow maps an event to its row, by its JSON field names
+func bigQueryRow(event Event) (map[string]bigquery.JsonValue, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var row map[string]bigquery.JsonValue
+	if err := json.Unmarshal(data, &row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
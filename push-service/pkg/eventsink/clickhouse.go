@@ -0,0 +1,74 @@
+package eventsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"push-service/internal/config"
+)
+
+// clickHouseWriter inserts events through ClickHouse's HTTP interface, one
+// JSON object per row
+type clickHouseWriter struct {
+	client   *http.Client
+	endpoint string
+	username string
+	password string
+}
+
+func newClickHouseWriter(cfg *config.ClickHouseSinkConfig) *clickHouseWriter {
+	table := cfg.Table
+	if table == "" {
+		table = "push_delivery_events" // default
+	}
+
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table))
+	// occurred_at is sent as RFC 3339
+	query.Set("date_time_input_format", "best_effort")
+
+	return &clickHouseWriter{
+		client:   &http.Client{},
+		endpoint: strings.TrimRight(cfg.URL, "/") + "/?" + query.Encode(),
+		username: cfg.Username,
+		password: cfg.Password,
+	}
+}
+
+func (w *clickHouseWriter) Write(ctx context.Context, events []Event) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if w.username != "" {
+		req.Header.Set("X-ClickHouse-User", w.username)
+		req.Header.Set("X-ClickHouse-Key", w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("clickhouse returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package eventsink streams delivery events to an analytics warehouse,
+// ClickHouse or BigQuery, so they can be analyzed without scraping logs.
+// Events are buffered in memory and written in batches in the background;
+// recording one never blocks a send. Events that can't be written are
+// dropped and counted.
+package eventsink
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Backends
+const (
+	BackendClickHouse = "clickhouse"
+	BackendBigQuery   = "bigquery"
+)
+
+// Event types
+const (
+	EventSent   = "sent"
+	EventFailed = "failed"
+	EventOpened = "opened"
+)
+
+// Event is one delivery event: a token's send result or an open reported
+// by the device. Users are identified by the hash of their ID, since the
+// warehouse isn't covered by user data deletion; tokens aren't included.
+type Event struct {
+	ID             string    `json:"event_id"`
+	Type           string    `json:"event_type"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	NotificationID string    `json:"notification_id"`
+	UserHash       string    `json:"user_hash"`
+	Platform       string    `json:"platform"`
+	TenantID       string    `json:"tenant_id"`
+	ProjectID      string    `json:"project_id"`
+	CampaignID     string    `json:"campaign_id"`
+	Variant        string    `json:"variant"`
+	Category       string    `json:"category"`
+	Priority       string    `json:"priority"`
+	RetryCount     int       `json:"retry_count"`
+	ErrorKind      string    `json:"error_kind"`
+	ErrorCode      string    `json:"error_code"`
+}
+
+// Writer writes a batch of events to a warehouse
+type Writer interface {
+	Write(ctx context.Context, events []Event) error
+}
+
+// Sink batches events to its writer. A nil *Sink records nothing.
+type Sink struct {
+	writer        Writer
+	backend       string
+	batchSize     int
+	flushInterval time.Duration
+	maxAttempts   int
+	timeout       time.Duration
+
+	events    chan Event
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates the sink of cfg.Backend and starts writing in the background,
+// or returns nil if no backend is set
+func New(cfg *config.EventSinkConfig) (*Sink, error) {
+	var writer Writer
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case BackendClickHouse:
+		writer = newClickHouseWriter(&cfg.ClickHouse)
+	case BackendBigQuery:
+		var err error
+		if writer, err = newBigQueryWriter(&cfg.BigQuery); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown event sink backend %q", cfg.Backend)
+	}
+
+	return NewSink(writer, cfg.Backend, cfg), nil
+}
+
+// NewSink creates a sink writing to writer, labelled backend in metrics, and
+// starts writing in the background
+func NewSink(writer Writer, backend string, cfg *config.EventSinkConfig) *Sink {
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 10000 // default
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500 // default
+	}
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second // default
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3 // default
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second // default
+	}
+
+	s := &Sink{
+		writer:        writer,
+		backend:       backend,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		maxAttempts:   maxAttempts,
+		timeout:       timeout,
+		events:        make(chan Event, bufferSize),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Record queues events to be written. Events that don't fit in the buffer
+// are dropped.
+func (s *Sink) Record(events ...Event) {
+	if s == nil {
+		return
+	}
+
+	for _, event := range events {
+		select {
+		case s.events <- event:
+		default:
+			metrics.EventSinkEvents.WithLabelValues(s.backend, "dropped").Inc()
+		}
+	}
+}
+
+// Close writes the events still buffered and stops the sink. Events
+// recorded afterwards are dropped.
+func (s *Sink) Close() {
+	if s == nil {
+		return
+	}
+
+	s.closeOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, s.batchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			s.write(batch)
+			batch = make([]Event, 0, s.batchSize)
+		}
+	}
+
+	for {
+		select {
+		case event := <-s.events:
+			batch = append(batch, event)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-s.stop:
+			// Drain what was recorded before the close
+			for {
+				select {
+				case event := <-s.events:
+					batch = append(batch, event)
+					if len(batch) >= s.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// write writes a batch, retrying with backoff up to maxAttempts times
+func (s *Sink) write(batch []Event) {
+	backoff := time.Second
+	var err error
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		err = s.writer.Write(ctx, batch)
+		cancel()
+		if err == nil {
+			metrics.EventSinkEvents.WithLabelValues(s.backend, "written").Add(float64(len(batch)))
+			return
+		}
+		if attempt < s.maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	metrics.EventSinkEvents.WithLabelValues(s.backend, "failed").Add(float64(len(batch)))
+	zap.L().Error("Failed to write delivery events, dropping them",
+		zap.String("backend", s.backend),
+		zap.Int("event_count", len(batch)),
+		zap.Int("attempts", s.maxAttempts),
+		zap.Error(err),
+	)
+}
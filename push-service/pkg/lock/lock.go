@@ -0,0 +1,118 @@
+// Package lock provides distributed locks for background jobs that must run
+// on one instance at a time, e.g. schedulers and cleanup jobs. Locks are
+// backed by Redis or by Postgres advisory locks.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"push-service/internal/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Lock backends
+const (
+	BackendPostgres = "postgres"
+	BackendRedis    = "redis"
+)
+
+var (
+	// ErrNotAcquired is returned when another holder has the lock
+	ErrNotAcquired = errors.New("lock is held by another holder")
+	// ErrLost is returned when a held lock expired or its session ended
+	ErrLost = errors.New("lock was lost")
+)
+
+// Locker hands out named locks. Acquire doesn't wait: it returns
+// ErrNotAcquired if the lock is held elsewhere.
+type Locker interface {
+	Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a held lock. Locks with a TTL expire unless refreshed, so a crashed
+// holder can't keep them forever.
+type Lock interface {
+	Key() string
+	// Refresh extends the lock by ttl, or returns ErrLost if it is no longer held
+	Refresh(ctx context.Context, ttl time.Duration) error
+	Release(ctx context.Context) error
+}
+
+// New returns the locker for cfg.Backend. The Redis client is only needed for
+// the redis backend.
+func New(cfg *config.LockConfig, pool *pgxpool.Pool, redisClient redis.UniversalClient) (Locker, error) {
+	switch cfg.Backend {
+	case "", BackendPostgres:
+		return NewPostgresLocker(pool, cfg.KeyPrefix), nil
+	case BackendRedis:
+		if redisClient == nil {
+			return nil, fmt.Errorf("redis lock backend requires a redis client")
+		}
+		return NewRedisLocker(redisClient, cfg.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown lock backend %q", cfg.Backend)
+	}
+}
+
+// Run calls fn while holding the lock key, and returns ErrNotAcquired without
+// calling it if the lock is held elsewhere. The lock is refreshed every ttl/3;
+// if it is lost, fn's context is cancelled and Run returns ErrLost.
+func Run(ctx context.Context, locker Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	if ttl <= 0 {
+		ttl = 30 * time.Second // default
+	}
+
+	l, err := locker.Acquire(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Release even if ctx is already cancelled
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := l.Release(releaseCtx); err != nil && !errors.Is(err, ErrLost) {
+			zap.L().Warn("Failed to release lock", zap.String("key", key), zap.Error(err))
+		}
+	}()
+
+	fnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lost := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := l.Refresh(fnCtx, ttl); err != nil {
+					if fnCtx.Err() != nil {
+						return
+					}
+					zap.L().Warn("Failed to refresh lock, stopping its holder", zap.String("key", key), zap.Error(err))
+					close(lost)
+					cancel()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = fn(fnCtx)
+	select {
+	case <-lost:
+		return ErrLost
+	default:
+		return err
+	}
+}
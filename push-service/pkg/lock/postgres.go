@@ -0,0 +1,71 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresLocker holds locks as session-level advisory locks, keyed by a hash
+// of the lock name. Each held lock keeps one pool connection; the lock ends
+// with the connection's session, so it needs no TTL and ttl is ignored.
+type PostgresLocker struct {
+	pool   *pgxpool.Pool
+	prefix string
+}
+
+func NewPostgresLocker(pool *pgxpool.Pool, prefix string) *PostgresLocker {
+	return &PostgresLocker{pool: pool, prefix: prefix}
+}
+
+type postgresLock struct {
+	conn *pgxpool.Conn
+	key  string
+}
+
+func (l *PostgresLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key = l.prefix + key
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtextextended($1, 0))`, key).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	if !acquired {
+		conn.Release()
+		return nil, ErrNotAcquired
+	}
+	return &postgresLock{conn: conn, key: key}, nil
+}
+
+func (l *postgresLock) Key() string {
+	return l.key
+}
+
+// Refresh checks that the session holding the lock is still alive
+func (l *postgresLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if err := l.conn.Ping(ctx); err != nil {
+		return ErrLost
+	}
+	return nil
+}
+
+func (l *postgresLock) Release(ctx context.Context) error {
+	defer l.conn.Release()
+
+	var released bool
+	if err := l.conn.QueryRow(ctx, `SELECT pg_advisory_unlock(hashtextextended($1, 0))`, l.key).Scan(&released); err != nil {
+		// Don't return a connection in an unknown state to the pool
+		l.conn.Conn().Close(context.Background())
+		return err
+	}
+	if !released {
+		return ErrLost
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// The scripts only touch the key while it still holds our token, so a lock
+// that expired and was taken by someone else is left alone
+var (
+	releaseScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0
+	`)
+	refreshScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+)
+
+// RedisLocker holds locks as keys set with NX and a TTL, whose value is a
+// random token identifying the holder
+type RedisLocker struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisLocker(client redis.UniversalClient, prefix string) *RedisLocker {
+	return &RedisLocker{client: client, prefix: prefix}
+}
+
+type redisLock struct {
+	client redis.UniversalClient
+	key    string
+	token  string
+}
+
+func (l *RedisLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	lock := &redisLock{
+		client: l.client,
+		key:    l.prefix + key,
+		token:  uuid.NewString(),
+	}
+
+	ok, err := l.client.SetNX(ctx, lock.key, lock.token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+	return lock, nil
+}
+
+func (l *redisLock) Key() string {
+	return l.key
+}
+
+func (l *redisLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	n, err := refreshScript.Run(ctx, l.client, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLost
+	}
+	return nil
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	n, err := releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLost
+	}
+	return nil
+}
@@ -1,11 +1,20 @@
 package logger
 
 import (
+	"errors"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func New(level, format string) (*zap.Logger, error) {
+// New builds a logger. With redact, push tokens, user IDs, notification
+// content and client IPs are masked in every entry, see NewRedactingCore.
+func New(level, format string, redact bool) (*zap.Logger, error) {
+	logger, _, err := build(level, format, redact)
+	return logger, err
+}
+
+func build(level, format string, redact bool) (*zap.Logger, zap.AtomicLevel, error) {
 	var config zap.Config
 
 	if format == "json" {
@@ -17,7 +26,7 @@ func New(level, format string) (*zap.Logger, error) {
 	// Set log level
 	logLevel := zap.InfoLevel
 	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
-		return nil, err
+		return nil, config.Level, err
 	}
 	config.Level = zap.NewAtomicLevelAt(logLevel)
 
@@ -25,22 +34,39 @@ func New(level, format string) (*zap.Logger, error) {
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
-	return config.Build()
+	var opts []zap.Option
+	if redact {
+		opts = append(opts, zap.WrapCore(NewRedactingCore))
+	}
+	logger, err := config.Build(opts...)
+	return logger, config.Level, err
 }
 
 // Global logger instance for easy access
-var globalLogger *zap.Logger
+var (
+	globalLogger *zap.Logger
+	globalLevel  zap.AtomicLevel
+)
 
-func InitGlobal(level, format string) error {
-	logger, err := New(level, format)
+func InitGlobal(level, format string, redact bool) error {
+	logger, atomicLevel, err := build(level, format, redact)
 	if err != nil {
 		return err
 	}
 	globalLogger = logger
+	globalLevel = atomicLevel
 	zap.ReplaceGlobals(logger)
 	return nil
 }
 
+// SetLevel changes the global logger's level at runtime
+func SetLevel(level string) error {
+	if globalLogger == nil {
+		return errors.New("global logger not initialized")
+	}
+	return globalLevel.UnmarshalText([]byte(level))
+}
+
 func L() *zap.Logger {
 	if globalLogger == nil {
 		// Fallback to a basic logger if not initialized
@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces values that are dropped from logs altogether
+const redacted = "[REDACTED]"
+
+// Log fields are redacted by key, so a value is masked the same way wherever
+// it is logged
+var (
+	// Push tokens are masked to their ends, enough to tell them apart
+	tokenKeys = map[string]bool{
+		"token": true, "tokens": true, "device_token": true, "device_tokens": true,
+		"push_token": true, "new_token": true, "old_token": true,
+	}
+	// User IDs are replaced by a hash, see HashUserID
+	userKeys = map[string]bool{
+		"user_id": true, "user_ids": true, "previous_user_id": true,
+	}
+	// Notification content and whole device records are dropped
+	contentKeys = map[string]bool{
+		"title": true, "body": true, "data": true, "vars": true, "devices": true,
+	}
+	// Client IPs are truncated to their network
+	ipKeys = map[string]bool{
+		"client_ip": true,
+	}
+)
+
+// MaskToken masks a push token to its first and last 10 characters. Masking
+// a masked token leaves it unchanged.
+func MaskToken(token string) string {
+	if len(token) <= 20 {
+		return "***"
+	}
+	return token[:10] + "..." + token[len(token)-10:]
+}
+
+// HashUserID replaces a user ID with the start of its SHA-256 hash, which
+// still correlates a user's log lines
+func HashUserID(userID string) string {
+	if userID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userID))
+	return "sha256:" + hex.EncodeToString(sum[:])[:16]
+}
+
+// maskIP keeps the /24 of an IPv4 address or the /48 of an IPv6 address
+func maskIP(value string) string {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return redacted
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// redactingCore masks the fields of every entry before the wrapped core
+// encodes them
+type redactingCore struct {
+	zapcore.Core
+}
+
+// NewRedactingCore wraps core so that push tokens, user IDs, notification
+// content and client IPs never reach the log output in full
+func NewRedactingCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, field := range fields {
+		masked, ok := redactField(field)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			// Copied on the first change, the caller's slice is left alone
+			out = make([]zapcore.Field, len(fields))
+			copy(out, fields)
+		}
+		out[i] = masked
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// redactField returns the redacted field, or false if field's key isn't
+// redacted
+func redactField(field zapcore.Field) (zapcore.Field, bool) {
+	var mask func(string) string
+	switch {
+	case tokenKeys[field.Key]:
+		mask = MaskToken
+	case userKeys[field.Key]:
+		mask = HashUserID
+	case ipKeys[field.Key]:
+		mask = maskIP
+	case contentKeys[field.Key]:
+		return zap.String(field.Key, redacted), true
+	default:
+		return field, false
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		return zap.String(field.Key, mask(field.String)), true
+	case zapcore.ArrayMarshalerType:
+		// e.g. zap.Strings: the elements are read back and masked one by one
+		enc := zapcore.NewMapObjectEncoder()
+		if marshaler, ok := field.Interface.(zapcore.ArrayMarshaler); ok && enc.AddArray(field.Key, marshaler) == nil {
+			if values, ok := enc.Fields[field.Key].([]any); ok {
+				masked := make([]string, 0, len(values))
+				for _, value := range values {
+					s, ok := value.(string)
+					if !ok {
+						return zap.String(field.Key, redacted), true
+					}
+					masked = append(masked, mask(s))
+				}
+				return zap.Strings(field.Key, masked), true
+			}
+		}
+	}
+	return zap.String(field.Key, redacted), true
+}
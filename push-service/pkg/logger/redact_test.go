@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMaskToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"", "***"},
+		{"short-token", "***"},
+		{"exactly-twenty-chars", "***"},
+		{"abcdefghij-middle-klmnopqrst", "abcdefghij...klmnopqrst"},
+		// Masking a masked token leaves it unchanged
+		{"abcdefghij...klmnopqrst", "abcdefghij...klmnopqrst"},
+	}
+
+	for _, tt := range tests {
+		if got := MaskToken(tt.token); got != tt.want {
+			t.Errorf("MaskToken(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestHashUserID(t *testing.T) {
+	if got := HashUserID(""); got != "" {
+		t.Errorf("HashUserID(\"\") = %q, want \"\"", got)
+	}
+
+	hashed := HashUserID("user-1")
+	if !strings.HasPrefix(hashed, "sha256:") || len(hashed) != len("sha256:")+16 {
+		t.Errorf("HashUserID(user-1) = %q, want sha256: and 16 hex characters", hashed)
+	}
+	if again := HashUserID("user-1"); again != hashed {
+		t.Errorf("HashUserID(user-1) = %q then %q, want the same hash", hashed, again)
+	}
+	if other := HashUserID("user-2"); other == hashed {
+		t.Errorf("HashUserID gave user-1 and user-2 the same hash %q", hashed)
+	}
+}
+
+func TestMaskIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.42", "203.0.113.0"},
+		{"::ffff:203.0.113.42", "203.0.113.0"},
+		{"2001:db8:abcd:12::1", "2001:db8:abcd::"},
+		{"not-an-ip", redacted},
+		{"", redacted},
+	}
+
+	for _, tt := range tests {
+		if got := maskIP(tt.ip); got != tt.want {
+			t.Errorf("maskIP(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestRedactField(t *testing.T) {
+	token := "abcdefghij-middle-klmnopqrst"
+	tests := []struct {
+		name  string
+		field zapcore.Field
+		// nil when the field isn't redacted
+		want any
+	}{
+		{"token", zap.String("device_token", token), "abcdefghij...klmnopqrst"},
+		{"tokens", zap.Strings("tokens", []string{token, "short"}), []any{"abcdefghij...klmnopqrst", "***"}},
+		{"user ID", zap.String("user_id", "user-1"), HashUserID("user-1")},
+		{"client IP", zap.String("client_ip", "203.0.113.42"), "203.0.113.0"},
+		{"content", zap.String("title", "Your order shipped"), redacted},
+		{"content not a string", zap.Any("data", map[string]string{"order": "42"}), redacted},
+		{"token not a string", zap.Int("token", 42), redacted},
+		{"other key", zap.String("notification_id", "n-1"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masked, ok := redactField(tt.field)
+			if tt.want == nil {
+				if ok {
+					t.Fatalf("got field redacted to %+v, want it left alone", masked)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("got field left alone, want it redacted")
+			}
+
+			enc := zapcore.NewMapObjectEncoder()
+			masked.AddTo(enc)
+			got := enc.Fields[tt.field.Key]
+			if want, ok := tt.want.([]any); ok {
+				values, _ := got.([]any)
+				if len(values) != len(want) {
+					t.Fatalf("got %v, want %v", got, want)
+				}
+				for i := range want {
+					if values[i] != want[i] {
+						t.Errorf("got %v, want %v", got, want)
+					}
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactFieldsLeavesCallerSliceAlone(t *testing.T) {
+	fields := []zapcore.Field{zap.String("notification_id", "n-1"), zap.String("user_id", "user-1")}
+	redacted := redactFields(fields)
+
+	if fields[1].String != "user-1" {
+		t.Errorf("got caller's user_id changed to %q, want it left alone", fields[1].String)
+	}
+	if redacted[0].String != "n-1" || redacted[1].String != HashUserID("user-1") {
+		t.Errorf("got fields %q and %q, want n-1 and the hashed user ID", redacted[0].String, redacted[1].String)
+	}
+}
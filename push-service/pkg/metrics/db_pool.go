@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dbPoolAcquiredConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquired_conns"),
+		"Connections currently in use, by pool.",
+		[]string{"pool"}, nil,
+	)
+	dbPoolIdleConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "idle_conns"),
+		"Idle connections, by pool.",
+		[]string{"pool"}, nil,
+	)
+	dbPoolTotalConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "total_conns"),
+		"Open connections, including ones being established, by pool.",
+		[]string{"pool"}, nil,
+	)
+	dbPoolMaxConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "max_conns"),
+		"Most connections the pool opens, by pool.",
+		[]string{"pool"}, nil,
+	)
+	dbPoolAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquires_total"),
+		"Connections acquired from the pool, by pool.",
+		[]string{"pool"}, nil,
+	)
+	dbPoolEmptyAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "empty_acquires_total"),
+		"Acquires that had to wait for a connection because none was idle, by pool.",
+		[]string{"pool"}, nil,
+	)
+	dbPoolCanceledAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "canceled_acquires_total"),
+		"Acquires given up before a connection was free, by pool.",
+		[]string{"pool"}, nil,
+	)
+	dbPoolAcquireWait = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquire_wait_seconds_total"),
+		"Time spent waiting for a connection by acquires that found none idle, by pool.",
+		[]string{"pool"}, nil,
+	)
+)
+
+// dbPoolCollector reads the statistics of every registered pool whenever
+// metrics are scraped. There is one for all pools, as collectors of the
+// same metrics can't be registered twice.
+type dbPoolCollector struct {
+	mu    sync.Mutex
+	pools map[string]*pgxpool.Pool
+}
+
+var dbPools = &dbPoolCollector{pools: make(map[string]*pgxpool.Pool)}
+
+func init() {
+	prometheus.MustRegister(dbPools)
+}
+
+// RegisterDBPool exports the connection statistics of pool, labelled name.
+// Registering a name again replaces its pool.
+func RegisterDBPool(name string, pool *pgxpool.Pool) {
+	dbPools.mu.Lock()
+	defer dbPools.mu.Unlock()
+	dbPools.pools[name] = pool
+}
+
+func (c *dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbPoolAcquiredConns
+	ch <- dbPoolIdleConns
+	ch <- dbPoolTotalConns
+	ch <- dbPoolMaxConns
+	ch <- dbPoolAcquires
+	ch <- dbPoolEmptyAcquires
+	ch <- dbPoolCanceledAcquires
+	ch <- dbPoolAcquireWait
+}
+
+func (c *dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, pool := range c.pools {
+		collectDBPool(ch, name, pool)
+	}
+}
+
+func collectDBPool(ch chan<- prometheus.Metric, name string, pool *pgxpool.Pool) {
+	stat := pool.Stat()
+	ch <- prometheus.MustNewConstMetric(dbPoolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()), name)
+	ch <- prometheus.MustNewConstMetric(dbPoolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()), name)
+	ch <- prometheus.MustNewConstMetric(dbPoolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()), name)
+	ch <- prometheus.MustNewConstMetric(dbPoolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()), name)
+	ch <- prometheus.MustNewConstMetric(dbPoolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()), name)
+	ch <- prometheus.MustNewConstMetric(dbPoolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()), name)
+	ch <- prometheus.MustNewConstMetric(dbPoolCanceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()), name)
+	ch <- prometheus.MustNewConstMetric(dbPoolAcquireWait, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds(), name)
+}
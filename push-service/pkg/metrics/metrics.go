@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "push_service"
+
+var (
+	// ProviderAuthFailed is 1 while FCM rejects our credentials
+	ProviderAuthFailed = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "provider_auth_failed",
+		Help:      "1 while the FCM provider is rejecting the service credentials, 0 otherwise.",
+	})
+
+	// FCMErrors counts FCM send errors by classified kind
+	FCMErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fcm_errors_total",
+		Help:      "FCM send errors by kind.",
+	}, []string{"kind"})
+
+	// NotificationsDropped counts notifications deliberately not delivered
+	NotificationsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_dropped_total",
+		Help:      "Notifications dropped before delivery, by reason.",
+	}, []string{"reason"})
+
+	// QuietHoursDeferred counts deliveries held back until quiet hours end
+	QuietHoursDeferred = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quiet_hours_deferred_total",
+		Help:      "Device deliveries held back until quiet hours end in the device's timezone.",
+	})
+
+	// DevicesResurrected counts deactivated tokens that registered again
+	DevicesResurrected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "devices_resurrected_total",
+		Help:      "Deactivated device tokens restored on re-registration.",
+	})
+
+	// DevicesUnregistered counts devices deactivated because FCM rejected
+	// their token
+	DevicesUnregistered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "devices_unregistered_total",
+		Help:      "Devices deactivated automatically after FCM reported their token unregistered or invalid.",
+	})
+
+	// DevicesDeduplicated counts registrations of a physical device that were
+	// replaced or deactivated because it registered another token
+	DevicesDeduplicated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "devices_deduplicated_total",
+		Help:      "Device registrations replaced or deactivated because the same physical device registered a new token.",
+	})
+
+	// DevicesCleanedUp counts devices the cleanup job deactivated or deleted
+	DevicesCleanedUp = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "devices_cleaned_up_total",
+		Help:      "Devices deactivated after going unseen (deactivated) or deleted after retention (purged) by the cleanup job.",
+	}, []string{"action"})
+
+	// RetentionPurged counts rows deleted by the retention purge
+	RetentionPurged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_purged_total",
+		Help:      "Rows deleted by the retention purge after outliving their retention, by table.",
+	}, []string{"table"})
+
+	// RetentionLastSuccess is when the retention purge last completed
+	RetentionLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "retention_last_success_timestamp_seconds",
+		Help:      "Unix time the retention purge last completed without errors.",
+	})
+
+	// MessagePanics counts queue messages whose processing panicked
+	MessagePanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_panics_total",
+		Help:      "Queue messages whose processing panicked and was recovered, by source queue.",
+	}, []string{"source"})
+
+	// BrokerAcks counts the acks sent to RabbitMQ when acks are batched
+	BrokerAcks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "broker_acks_total",
+		Help:      "Acks sent to RabbitMQ with batched acks, by mode (multiple, single).",
+	}, []string{"mode"})
+
+	// UserResolutions counts external user resolver lookups by result
+	UserResolutions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_resolutions_total",
+		Help:      "External user resolver lookups for gateway messages, by result (resolved, not_found, error).",
+	}, []string{"result"})
+
+	// WebhookDeliveries counts webhook delivery attempts by outcome
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts, by outcome (delivered, retrying, failed, circuit_open).",
+	}, []string{"outcome"})
+
+	// PayloadsTruncated counts sends whose data was cut to fit FCM's payload
+	// limit
+	PayloadsTruncated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payloads_truncated_total",
+		Help:      "Sends whose data keys were dropped to fit the maximum payload size.",
+	})
+
+	// MalformedMessages counts queue messages rejected as malformed
+	MalformedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "malformed_messages_total",
+		Help:      "Queue messages rejected as malformed and moved to the malformed queue, by source queue.",
+	}, []string{"source"})
+
+	// OutboxRelayed counts outbox messages published by the relay rather
+	// than by their send
+	OutboxRelayed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_relayed_total",
+		Help:      "Outbox messages published by the outbox relay because their send couldn't publish them.",
+	})
+
+	// FCMPacingWait adds up the time sends waited for their turn under the
+	// FCM send rate cap
+	FCMPacingWait = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fcm_pacing_wait_seconds_total",
+		Help:      "Time FCM sends waited for their turn under the send rate cap.",
+	})
+
+	// DeadLettersArchived counts dead letters written to the archive bucket
+	DeadLettersArchived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dead_letters_archived_total",
+		Help:      "Dead letters moved from the dead letter queue to the archive bucket.",
+	})
+
+	// EventSinkEvents counts delivery events streamed to the analytics
+	// warehouse, or lost on the way
+	EventSinkEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_sink_events_total",
+		Help:      "Delivery events handed to the event sink, by backend and outcome (written, dropped when the buffer was full, failed after every write attempt).",
+	}, []string{"backend", "outcome"})
+
+	// WorkerSpeedFactor is below 1 while the worker is slowed down because
+	// FCM fails many sends
+	WorkerSpeedFactor = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_speed_factor",
+		Help:      "Share of its prefetch and concurrency the worker runs at; below 1 while slowed down by FCM failures.",
+	})
+
+	// QueueDepth is the number of messages waiting in each queue, as a
+	// worker last read it
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Messages waiting in the queue, excluding those delivered to consumers and not yet acked.",
+	}, []string{"queue"})
+
+	// QueueBacklog is the number of messages waiting to be processed now:
+	// those in the push queues and the gateway queue, not the retry tiers
+	QueueBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_backlog",
+		Help:      "Messages waiting in the push queues of every priority and the gateway queue.",
+	})
+
+	// WorkerConcurrency is the size of the worker's handler pool
+	WorkerConcurrency = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_concurrency",
+		Help:      "Messages the worker processes concurrently at most.",
+	})
+
+	// WorkerUtilization is the share of the pool that was busy
+	WorkerUtilization = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_utilization",
+		Help:      "Average share of the worker's handlers busy over the last autoscale interval.",
+	})
+
+	// RealtimeSessions is the number of open realtime sessions on this
+	// replica
+	RealtimeSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "realtime_sessions",
+		Help:      "Open realtime (WebSocket) sessions on this replica.",
+	})
+
+	// ConfigReloads counts configuration reloads by result
+	ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Configuration reloads, by result (applied, or failed when the new configuration was invalid).",
+	}, []string{"result"})
+
+	// ChaosFaults counts the faults injected on purpose, by kind
+	ChaosFaults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chaos_faults_total",
+		Help:      "Faults injected on purpose with CHAOS_ENABLED, by kind (fcm_delay, fcm_failure, ack_drop, channel_kill).",
+	}, []string{"kind"})
+
+	// AlertNotifications counts alert notifications by channel and outcome
+	AlertNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alert_notifications_total",
+		Help:      "Alert notifications sent, by channel (webhook, slack, email) and outcome (sent, failed).",
+	}, []string{"channel", "outcome"})
+)
+
+// Handler serves all registered metrics in the Prometheus exposition format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
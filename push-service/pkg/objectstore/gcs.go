@@ -0,0 +1,47 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"push-service/internal/config"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+type gcsStore struct {
+	objects *storage.ObjectsService
+	bucket  string
+}
+
+func newGCSStore(ctx context.Context, bucket string, cfg *config.GCSArchiveConfig) (*gcsStore, error) {
+	var opts []option.ClientOption
+	if cfg.CredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GCS credentials: %w", err)
+		}
+		opts = append(opts, option.WithCredentialsJSON(credentials))
+	}
+
+	service, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return &gcsStore{objects: storage.NewObjectsService(service), bucket: bucket}, nil
+}
+
+func (s *gcsStore) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	object := &storage.Object{Name: key, ContentType: contentType}
+	if _, err := s.objects.Insert(s.bucket, object).Media(bytes.NewReader(body)).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.URL(key), err)
+	}
+	return nil
+}
+
+func (s *gcsStore) URL(key string) string {
+	return "gs://" + s.bucket + "/" + key
+}
@@ -0,0 +1,36 @@
+// Package objectstore writes objects to a bucket in Amazon S3, an
+// S3-compatible store, or Google Cloud Storage
+package objectstore
+
+import (
+	"context"
+	"fmt"
+
+	"push-service/internal/config"
+)
+
+// Backends
+const (
+	BackendS3  = "s3"
+	BackendGCS = "gcs"
+)
+
+// Store writes objects to one bucket
+type Store interface {
+	// Put writes body as the object key, replacing any object by that key
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	// URL names the object key, e.g. s3://bucket/key
+	URL(key string) string
+}
+
+// New creates the store of the archive's backend, for its bucket
+func New(ctx context.Context, cfg *config.ArchiveConfig) (Store, error) {
+	switch cfg.Backend {
+	case BackendS3:
+		return newS3Store(ctx, cfg.Bucket, &cfg.S3)
+	case BackendGCS:
+		return newGCSStore(ctx, cfg.Bucket, &cfg.GCS)
+	default:
+		return nil, fmt.Errorf("unknown object store backend %q", cfg.Backend)
+	}
+}
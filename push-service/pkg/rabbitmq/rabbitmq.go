@@ -2,23 +2,97 @@ package rabbitmq
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"push-service/internal/config"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrUnroutable is returned when a published message matched no queue
+	ErrUnroutable = errors.New("message was not routed to any queue")
+	// ErrNacked is returned when the broker refuses to take a published message
+	ErrNacked = errors.New("message was rejected by the broker")
+)
+
+// RabbitMQClient owns one connection with a dedicated publisher channel and
+// one channel per consumer. amqp091-go channels are not safe for concurrent
+// use, so HTTP-handler publishes never share a channel with worker acks.
 type RabbitMQClient struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	url     string
 	cfg     *config.RabbitMQConfig
+	amqpCfg amqp.Config
+
+	mu         sync.Mutex
+	conn       *amqp.Connection
+	pub        *publisher
+	prefetch   int
+	consumers  []*consumer
+	seq        int
+	reconnects int
+	closed     bool
+	done       chan struct{}
+
+	// pubMu serializes publishes on the publisher channel
+	pubMu sync.Mutex
+
+	// Declared topology, replayed after a reconnect
+	exchanges []exchangeDecl
+	queues    []queueDecl
+	bindings  []bindingDecl
+}
+
+// consumer tracks a registered queue consumer so it can be re-registered
+// (after a QoS change or a reconnect) without the caller's delivery channel
+// changing.
+type consumer struct {
+	queue   string
+	tag     string
+	channel *amqp.Channel
+	stopped bool
+	out     chan amqp.Delivery
+	swap    chan (<-chan amqp.Delivery)
+}
+
+// publisher is the channel used for all publishes. With publisher confirms
+// enabled it is in confirm mode and returns carries unroutable messages.
+type publisher struct {
+	channel *amqp.Channel
+	returns <-chan amqp.Return
+}
+
+type exchangeDecl struct {
+	name string
+	kind string
+}
+
+type queueDecl struct {
+	name string
+	args amqp.Table
+}
+
+type bindingDecl struct {
+	queue      string
+	exchange   string
+	routingKey string
 }
 
 func NewRabbitMQClient(cfg *config.RabbitMQConfig) (*RabbitMQClient, error) {
-	url := fmt.Sprintf("amqp://%s:%s@%s:%s/%s",
+	scheme := "amqp"
+	if cfg.TLS {
+		scheme = "amqps"
+	}
+	url := fmt.Sprintf("%s://%s:%s@%s:%s/%s",
+		scheme,
 		cfg.Username,
 		cfg.Password,
 		cfg.Host,
@@ -26,23 +100,25 @@ func NewRabbitMQClient(cfg *config.RabbitMQConfig) (*RabbitMQClient, error) {
 		cfg.VHost,
 	)
 
-	conn, err := amqp.Dial(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
-	}
-
-	channel, err := conn.Channel()
+	amqpCfg, err := newAMQPConfig(cfg)
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %w", err)
+		return nil, err
 	}
 
 	client := &RabbitMQClient{
-		conn:    conn,
-		channel: channel,
+		url:     url,
 		cfg:     cfg,
+		amqpCfg: amqpCfg,
+		done:    make(chan struct{}),
 	}
 
+	conn, pub, err := client.dial()
+	if err != nil {
+		return nil, err
+	}
+	client.conn = conn
+	client.pub = pub
+
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -52,23 +128,311 @@ func NewRabbitMQClient(cfg *config.RabbitMQConfig) (*RabbitMQClient, error) {
 		return nil, fmt.Errorf("failed to ping RabbitMQ: %w", err)
 	}
 
+	go client.watch(conn)
+	go client.watchPublisher(conn, pub)
+
 	zap.L().Info("Connected to RabbitMQ",
 		zap.String("host", cfg.Host),
 		zap.String("port", cfg.Port),
 		zap.String("vhost", cfg.VHost),
+		zap.Bool("tls", cfg.TLS),
 	)
 
 	return client, nil
 }
 
+// newAMQPConfig builds the connection settings: heartbeat, dial timeout,
+// connection name and, for amqps, the TLS client config.
+func newAMQPConfig(cfg *config.RabbitMQConfig) (amqp.Config, error) {
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 30 * time.Second // default
+	}
+
+	properties := amqp.NewConnectionProperties()
+	if cfg.ConnectionName != "" {
+		properties.SetClientConnectionName(cfg.ConnectionName)
+	}
+
+	amqpCfg := amqp.Config{
+		Heartbeat:  cfg.Heartbeat,
+		Locale:     "en_US",
+		Properties: properties,
+		Dial:       amqp.DefaultDial(dialTimeout),
+	}
+
+	if cfg.TLS {
+		tlsCfg, err := newTLSConfig(cfg)
+		if err != nil {
+			return amqp.Config{}, err
+		}
+		amqpCfg.TLSClientConfig = tlsCfg
+	}
+
+	return amqpCfg, nil
+}
+
+func newTLSConfig(cfg *config.RabbitMQConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.TLSServerName,
+	}
+	if tlsCfg.ServerName == "" {
+		tlsCfg.ServerName = cfg.Host
+	}
+
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RabbitMQ CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in RabbitMQ CA file %s", cfg.CACertFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load RabbitMQ client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+func (r *RabbitMQClient) dial() (*amqp.Connection, *publisher, error) {
+	conn, err := amqp.DialConfig(r.url, r.amqpCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	pub, err := r.openPublisher(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, pub, nil
+}
+
+func (r *RabbitMQClient) openPublisher(conn *amqp.Connection) (*publisher, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	pub := &publisher{channel: channel}
+	if r.cfg.PublisherConfirms {
+		if err := channel.Confirm(false); err != nil {
+			channel.Close()
+			return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+		}
+		pub.returns = channel.NotifyReturn(make(chan amqp.Return, 16))
+	}
+	return pub, nil
+}
+
+// watch blocks until the connection closes, then reconnects unless the
+// client itself was closed.
+func (r *RabbitMQClient) watch(conn *amqp.Connection) {
+	var reason *amqp.Error
+	select {
+	case reason = <-conn.NotifyClose(make(chan *amqp.Error, 1)):
+	case <-r.done:
+		return
+	}
+
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+
+	zap.L().Warn("RabbitMQ connection lost, reconnecting", zap.Any("reason", reason))
+	r.reconnect()
+}
+
+// watchPublisher reopens the publisher channel if the broker closes it while
+// the connection stays up (e.g. publishing to a missing exchange).
+func (r *RabbitMQClient) watchPublisher(conn *amqp.Connection, pub *publisher) {
+	select {
+	case <-pub.channel.NotifyClose(make(chan *amqp.Error, 1)):
+	case <-r.done:
+		return
+	}
+	if conn.IsClosed() {
+		return // watch handles the full reconnect
+	}
+
+	next, err := r.openPublisher(conn)
+	if err != nil {
+		zap.L().Error("Failed to reopen publisher channel", zap.Error(err))
+		conn.Close()
+		return
+	}
+
+	r.mu.Lock()
+	if r.closed || r.conn != conn {
+		r.mu.Unlock()
+		next.channel.Close()
+		return
+	}
+	r.pub = next
+	r.mu.Unlock()
+
+	zap.L().Warn("RabbitMQ publisher channel closed, reopened")
+	go r.watchPublisher(conn, next)
+}
+
+// watchConsumer reopens a consumer's channel and re-registers it if the
+// broker closes the channel while the connection stays up.
+func (r *RabbitMQClient) watchConsumer(conn *amqp.Connection, c *consumer, channel *amqp.Channel) {
+	select {
+	case <-channel.NotifyClose(make(chan *amqp.Error, 1)):
+	case <-r.done:
+		return
+	}
+	if conn.IsClosed() {
+		return // watch handles the full reconnect
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || c.stopped || r.conn != conn || c.channel != channel {
+		return
+	}
+
+	msgs, next, err := r.openConsumer(conn, c)
+	if err != nil {
+		zap.L().Error("Failed to reopen consumer channel", zap.String("consumer", c.tag), zap.Error(err))
+		conn.Close()
+		return
+	}
+	c.channel = next
+	c.replace(msgs)
+
+	zap.L().Warn("RabbitMQ consumer channel closed, reopened", zap.String("consumer", c.tag))
+	go r.watchConsumer(conn, c, next)
+}
+
+// reconnect redials with exponential backoff until it succeeds or the
+// client is closed.
+func (r *RabbitMQClient) reconnect() {
+	backoff := r.cfg.ReconnectInitialBackoff
+	if backoff <= 0 {
+		backoff = time.Second // default
+	}
+	maxBackoff := r.cfg.ReconnectMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second // default
+	}
+
+	for attempt := 1; ; attempt++ {
+		conn, pub, err := r.dial()
+		if err == nil {
+			if err = r.restore(conn, pub); err != nil {
+				conn.Close()
+			}
+		}
+		if err == nil {
+			zap.L().Info("Reconnected to RabbitMQ", zap.Int("attempt", attempt))
+			go r.watch(conn)
+			go r.watchPublisher(conn, pub)
+			return
+		}
+
+		zap.L().Warn("RabbitMQ reconnect failed",
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-r.done:
+			return
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// restore re-applies topology and consumers on a fresh connection and makes
+// it the current one.
+func (r *RabbitMQClient) restore(conn *amqp.Connection, pub *publisher) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return fmt.Errorf("client closed")
+	}
+
+	channel := pub.channel
+	for _, e := range r.exchanges {
+		if err := declareExchange(channel, e.name, e.kind); err != nil {
+			return fmt.Errorf("failed to redeclare exchange %s: %w", e.name, err)
+		}
+	}
+	for _, q := range r.queues {
+		if err := declareQueue(channel, q.name, q.args); err != nil {
+			return fmt.Errorf("failed to redeclare queue %s: %w", q.name, err)
+		}
+	}
+	for _, b := range r.bindings {
+		if err := bindQueue(channel, b.queue, b.exchange, b.routingKey); err != nil {
+			return fmt.Errorf("failed to rebind queue %s: %w", b.queue, err)
+		}
+	}
+
+	replacements := make([]<-chan amqp.Delivery, len(r.consumers))
+	channels := make([]*amqp.Channel, len(r.consumers))
+	for i, c := range r.consumers {
+		msgs, consumerChannel, err := r.openConsumer(conn, c)
+		if err != nil {
+			return err
+		}
+		replacements[i] = msgs
+		channels[i] = consumerChannel
+	}
+
+	r.conn = conn
+	r.pub = pub
+	r.reconnects++
+
+	for i, c := range r.consumers {
+		c.channel = channels[i]
+		c.replace(replacements[i])
+		go r.watchConsumer(conn, c, channels[i])
+	}
+
+	return nil
+}
+
 func (r *RabbitMQClient) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+
 	var errs []error
-	if r.channel != nil {
-		if err := r.channel.Close(); err != nil {
+	if r.pub != nil && !r.pub.channel.IsClosed() {
+		if err := r.pub.channel.Close(); err != nil {
 			errs = append(errs, err)
 		}
 	}
-	if r.conn != nil {
+	if r.conn != nil && !r.conn.IsClosed() {
 		if err := r.conn.Close(); err != nil {
 			errs = append(errs, err)
 		}
@@ -80,6 +444,9 @@ func (r *RabbitMQClient) Close() error {
 }
 
 func (r *RabbitMQClient) Ping(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	// Check if connection is still alive
 	if r.conn.IsClosed() {
 		return fmt.Errorf("connection is closed")
@@ -87,9 +454,95 @@ func (r *RabbitMQClient) Ping(ctx context.Context) error {
 	return nil
 }
 
+// Reconnects returns how many times the client has re-established its connection
+func (r *RabbitMQClient) Reconnects() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reconnects
+}
+
+// KillChannels closes the publisher channel and every consumer's channel,
+// as the broker would on a channel error, for fault injection. They are
+// reopened and the consumers' unacked messages are redelivered.
+func (r *RabbitMQClient) KillChannels() {
+	r.mu.Lock()
+	var channels []*amqp.Channel
+	if r.pub != nil {
+		channels = append(channels, r.pub.channel)
+	}
+	for _, c := range r.consumers {
+		if !c.stopped && c.channel != nil {
+			channels = append(channels, c.channel)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, channel := range channels {
+		channel.Close()
+	}
+	zap.L().Warn("RabbitMQ channels closed by fault injection", zap.Int("channels", len(channels)))
+}
+
+func (r *RabbitMQClient) currentPublisher() *publisher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pub
+}
+
 // EnsureExchange declares an exchange if it doesn't exist
 func (r *RabbitMQClient) EnsureExchange(ctx context.Context, name, kind string) error {
-	return r.channel.ExchangeDeclare(
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := declareExchange(r.pub.channel, name, kind); err != nil {
+		return err
+	}
+	for _, e := range r.exchanges {
+		if e.name == name {
+			return nil
+		}
+	}
+	r.exchanges = append(r.exchanges, exchangeDecl{name: name, kind: kind})
+	return nil
+}
+
+// EnsureQueue declares a queue if it doesn't exist
+func (r *RabbitMQClient) EnsureQueue(ctx context.Context, name string, args amqp.Table) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := declareQueue(r.pub.channel, name, args); err != nil {
+		return err
+	}
+	for _, q := range r.queues {
+		if q.name == name {
+			return nil
+		}
+	}
+	r.queues = append(r.queues, queueDecl{name: name, args: args})
+	return nil
+}
+
+// BindQueue binds a queue to an exchange
+func (r *RabbitMQClient) BindQueue(ctx context.Context, queueName, exchangeName, routingKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := bindQueue(r.pub.channel, queueName, exchangeName, routingKey); err != nil {
+		return err
+	}
+	binding := bindingDecl{queue: queueName, exchange: exchangeName, routingKey: routingKey}
+	for _, b := range r.bindings {
+		if b == binding {
+			return nil
+		}
+	}
+	r.bindings = append(r.bindings, binding)
+	return nil
+}
+
+func declareExchange(channel *amqp.Channel, name, kind string) error {
+	return channel.ExchangeDeclare(
 		name,  // name
 		kind,  // kind (direct, topic, fanout, headers)
 		true,  // durable
@@ -100,9 +553,8 @@ func (r *RabbitMQClient) EnsureExchange(ctx context.Context, name, kind string)
 	)
 }
 
-// EnsureQueue declares a queue if it doesn't exist
-func (r *RabbitMQClient) EnsureQueue(ctx context.Context, name string, args amqp.Table) error {
-	_, err := r.channel.QueueDeclare(
+func declareQueue(channel *amqp.Channel, name string, args amqp.Table) error {
+	_, err := channel.QueueDeclare(
 		name,  // name
 		true,  // durable
 		false, // delete when unused
@@ -113,9 +565,8 @@ func (r *RabbitMQClient) EnsureQueue(ctx context.Context, name string, args amqp
 	return err
 }
 
-// BindQueue binds a queue to an exchange
-func (r *RabbitMQClient) BindQueue(ctx context.Context, queueName, exchangeName, routingKey string) error {
-	return r.channel.QueueBind(
+func bindQueue(channel *amqp.Channel, queueName, exchangeName, routingKey string) error {
+	return channel.QueueBind(
 		queueName,    // queue name
 		routingKey,   // routing key
 		exchangeName, // exchange
@@ -131,97 +582,358 @@ func (r *RabbitMQClient) Enqueue(ctx context.Context, exchange, routingKey strin
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	err = r.channel.PublishWithContext(
-		ctx,
-		exchange,   // exchange
-		routingKey, // routing key
-		false,      // mandatory
-		false,      // immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         jsonMessage,
-			DeliveryMode: amqp.Persistent, // Make message persistent
-			Timestamp:    time.Now(),
-		},
-	)
-
-	if err != nil {
+	msg := amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         jsonMessage,
+		DeliveryMode: amqp.Persistent, // Make message persistent
+		Timestamp:    time.Now(),
+	}
+	if _, err := r.publish(ctx, exchange, routingKey, []amqp.Publishing{msg}); err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
 	return nil
 }
 
-// EnqueueWithDelay publishes a message with a delay (using TTL)
-func (r *RabbitMQClient) EnqueueWithDelay(ctx context.Context, exchange, routingKey string, message interface{}, delay time.Duration) error {
-	jsonMessage, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+// EnqueueBatch publishes several messages to an exchange back to back and,
+// with publisher confirms enabled, waits for all of them to be confirmed.
+// On failure, messages before the failing one have been accepted.
+func (r *RabbitMQClient) EnqueueBatch(ctx context.Context, exchange, routingKey string, messages []interface{}) error {
+	bodies := make([][]byte, len(messages))
+	for i, message := range messages {
+		jsonMessage, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message %d: %w", i+1, err)
+		}
+		bodies[i] = jsonMessage
 	}
+	return r.Publish(ctx, exchange, routingKey, bodies)
+}
 
-	delayMs := int64(delay.Milliseconds())
-
-	err = r.channel.PublishWithContext(
-		ctx,
-		exchange,   // exchange
-		routingKey, // routing key
-		false,      // mandatory
-		false,      // immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         jsonMessage,
+// Publish is EnqueueBatch for bodies that are already encoded. Bodies that
+// aren't JSON objects are protobuf, and are labelled as such.
+func (r *RabbitMQClient) Publish(ctx context.Context, exchange, routingKey string, bodies [][]byte) error {
+	msgs := make([]amqp.Publishing, len(bodies))
+	for i, body := range bodies {
+		contentType := "application/json"
+		if len(body) > 0 && body[0] != '{' {
+			contentType = "application/x-protobuf"
+		}
+		msgs[i] = amqp.Publishing{
+			ContentType:  contentType,
+			Body:         body,
 			DeliveryMode: amqp.Persistent,
 			Timestamp:    time.Now(),
-			Headers: amqp.Table{
-				"x-delay": delayMs,
-			},
-		},
-	)
+		}
+	}
+
+	if i, err := r.publish(ctx, exchange, routingKey, msgs); err != nil {
+		return fmt.Errorf("batch publish failed at message %d of %d: %w", i+1, len(bodies), err)
+	}
+	return nil
+}
+
+// publish sends msgs on the publisher channel. With publisher confirms
+// enabled the messages are mandatory and publish waits until the broker has
+// confirmed each one, failing on a NACK, a return or the confirm timeout. On
+// failure it reports the index of the first message that was not accepted.
+func (r *RabbitMQClient) publish(ctx context.Context, exchange, routingKey string, msgs []amqp.Publishing) (int, error) {
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+
+	pub := r.currentPublisher()
+	confirming := pub.returns != nil
+
+	confirms := make([]*amqp.DeferredConfirmation, len(msgs))
+	for i := range msgs {
+		if msgs[i].MessageId == "" {
+			msgs[i].MessageId = uuid.NewString()
+		}
+		confirm, err := pub.channel.PublishWithDeferredConfirmWithContext(
+			ctx,
+			exchange,   // exchange
+			routingKey, // routing key
+			confirming, // mandatory
+			false,      // immediate
+			msgs[i],
+		)
+		if err != nil {
+			return i, err
+		}
+		confirms[i] = confirm
+	}
+
+	if !confirming {
+		return 0, nil
+	}
+	return r.awaitConfirms(ctx, pub, msgs, confirms)
+}
+
+func (r *RabbitMQClient) awaitConfirms(ctx context.Context, pub *publisher, msgs []amqp.Publishing, confirms []*amqp.DeferredConfirmation) (int, error) {
+	timeout := r.cfg.ConfirmTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second // default
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	returned := make(map[string]amqp.Return)
+	for i, confirm := range confirms {
+	wait:
+		for {
+			select {
+			case <-confirm.Done():
+				break wait
+			case ret := <-pub.returns:
+				returned[ret.MessageId] = ret
+			case <-ctx.Done():
+				return i, fmt.Errorf("waiting for publisher confirm: %w", ctx.Err())
+			}
+		}
+		if !confirm.Acked() {
+			return i, ErrNacked
+		}
+	}
 
+	// The broker sends basic.return before the ack for the same message, so
+	// every return for this batch has been handed over by now.
+	for drained := false; !drained; {
+		select {
+		case ret := <-pub.returns:
+			returned[ret.MessageId] = ret
+		default:
+			drained = true
+		}
+	}
+
+	for i, msg := range msgs {
+		if ret, ok := returned[msg.MessageId]; ok {
+			return i, fmt.Errorf("%w: %d %s", ErrUnroutable, ret.ReplyCode, ret.ReplyText)
+		}
+	}
+	return 0, nil
+}
+
+// Consume starts consuming messages from a queue on its own channel. The
+// returned channel stays the same for the lifetime of the consumer, even
+// across SetPrefetch and reconnects; it is closed when ctx is cancelled or
+// the client is closed. Acknowledge deliveries with delivery.Ack/Nack so they
+// reach the channel they arrived on. The consumer is registered as tag, which
+// must be unique on the connection; if empty, one is generated from the queue
+// name.
+func (r *RabbitMQClient) Consume(ctx context.Context, queueName, tag string, prefetchCount int) (<-chan amqp.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prefetch = prefetchCount
+
+	if tag == "" {
+		r.seq++
+		tag = fmt.Sprintf("%s-%d", queueName, r.seq)
+	}
+	c := &consumer{
+		queue: queueName,
+		tag:   tag,
+		out:   make(chan amqp.Delivery),
+		swap:  make(chan (<-chan amqp.Delivery), 1),
+	}
+
+	msgs, channel, err := r.openConsumer(r.conn, c)
 	if err != nil {
-		return fmt.Errorf("failed to publish delayed message: %w", err)
+		return nil, err
 	}
+	c.channel = channel
+	r.consumers = append(r.consumers, c)
 
-	return nil
+	go r.forward(ctx, c, msgs)
+	go r.watchConsumer(r.conn, c, channel)
+
+	return c.out, nil
 }
 
-// Consume starts consuming messages from a queue
-func (r *RabbitMQClient) Consume(ctx context.Context, queueName string, prefetchCount int) (<-chan amqp.Delivery, error) {
+// openConsumer opens a dedicated channel for c, applies QoS and registers the
+// broker-side consumer. Callers must hold r.mu.
+func (r *RabbitMQClient) openConsumer(conn *amqp.Connection, c *consumer) (<-chan amqp.Delivery, *amqp.Channel, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open consumer channel: %w", err)
+	}
+
 	// Set QoS to control how many messages are delivered at once
-	if err := r.channel.Qos(
-		prefetchCount, // prefetch count
-		0,             // prefetch size
-		false,         // global
+	if err := channel.Qos(
+		r.prefetch, // prefetch count
+		0,          // prefetch size
+		false,      // global
 	); err != nil {
-		return nil, fmt.Errorf("failed to set QoS: %w", err)
+		channel.Close()
+		return nil, nil, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	msgs, err := r.channel.Consume(
-		queueName, // queue
-		"",        // consumer
-		false,     // auto-ack (we'll manually ack)
-		false,     // exclusive
-		false,     // no-local
-		false,     // no-wait
-		nil,       // args
-	)
+	msgs, err := register(channel, c)
+	if err != nil {
+		channel.Close()
+		return nil, nil, err
+	}
+	return msgs, channel, nil
+}
 
+func register(channel *amqp.Channel, c *consumer) (<-chan amqp.Delivery, error) {
+	msgs, err := channel.Consume(
+		c.queue, // queue
+		c.tag,   // consumer
+		false,   // auto-ack (we'll manually ack)
+		false,   // exclusive
+		false,   // no-local
+		false,   // no-wait
+		nil,     // args
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to register consumer: %w", err)
 	}
-
 	return msgs, nil
 }
 
+// replace hands a new delivery channel to the consumer's forwarder, dropping
+// any replacement it hasn't picked up yet since only the newest is live.
+// Callers must hold the client's mutex.
+func (c *consumer) replace(msgs <-chan amqp.Delivery) {
+	select {
+	case <-c.swap:
+	default:
+	}
+	c.swap <- msgs
+}
+
+// forward copies deliveries to the consumer's stable output channel, switching
+// to a replacement delivery channel whenever the consumer is re-registered.
+func (r *RabbitMQClient) forward(ctx context.Context, c *consumer, msgs <-chan amqp.Delivery) {
+	defer close(c.out)
+	defer r.removeConsumer(c)
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.cancelConsumer(c)
+			for d := range msgs {
+				d.Nack(false, true)
+			}
+			return
+		case <-r.done:
+			return
+		case d, ok := <-msgs:
+			if !ok {
+				// Cancelled for re-registration or connection lost;
+				// wait for the replacement.
+				select {
+				case next := <-c.swap:
+					msgs = next
+				case <-ctx.Done():
+					return
+				case <-r.done:
+					return
+				}
+				continue
+			}
+			select {
+			case c.out <- d:
+			case <-ctx.Done():
+				// Nobody takes it anymore: back to the queue with the rest
+				// sent before the cancel, instead of when the channel closes
+				d.Nack(false, true)
+				r.cancelConsumer(c)
+				for d := range msgs {
+					d.Nack(false, true)
+				}
+				return
+			}
+		}
+	}
+}
+
+func (r *RabbitMQClient) cancelConsumer(c *consumer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := c.channel.Cancel(c.tag, false); err != nil {
+		zap.L().Debug("Failed to cancel consumer", zap.String("consumer", c.tag), zap.Error(err))
+	}
+}
+
+// removeConsumer forgets c. Its channel stays open until the client closes so
+// in-flight deliveries can still be acked.
+func (r *RabbitMQClient) removeConsumer(c *consumer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.stopped = true
+	for i, existing := range r.consumers {
+		if existing == c {
+			r.consumers = append(r.consumers[:i], r.consumers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Prefetch returns the QoS prefetch count currently applied to consumers
+func (r *RabbitMQClient) Prefetch() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.prefetch
+}
+
+// SetPrefetch changes the QoS prefetch count at runtime. RabbitMQ only applies
+// per-consumer QoS to consumers registered after the change, so every active
+// consumer is cancelled and re-registered. Unacked deliveries stay valid.
+func (r *RabbitMQClient) SetPrefetch(prefetchCount int) error {
+	if prefetchCount <= 0 {
+		return fmt.Errorf("prefetch count must be positive")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prefetch = prefetchCount
+
+	for _, c := range r.consumers {
+		if err := c.channel.Qos(prefetchCount, 0, false); err != nil {
+			return fmt.Errorf("failed to set QoS for consumer %s: %w", c.tag, err)
+		}
+		if err := c.channel.Cancel(c.tag, false); err != nil {
+			return fmt.Errorf("failed to cancel consumer %s: %w", c.tag, err)
+		}
+		msgs, err := register(c.channel, c)
+		if err != nil {
+			return err
+		}
+		c.replace(msgs)
+	}
+
+	zap.L().Info("RabbitMQ prefetch updated",
+		zap.Int("prefetch_count", prefetchCount),
+		zap.Int("consumers", len(r.consumers)),
+	)
+	return nil
+}
+
 // QueueLength returns the number of messages in a queue
 func (r *RabbitMQClient) QueueLength(ctx context.Context, queueName string) (int64, error) {
+	r.mu.Lock()
+	conn := r.conn
+	r.mu.Unlock()
+
+	// A failed passive declare closes the channel it ran on, so inspect on a
+	// short-lived channel rather than the shared one.
+	channel, err := conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer channel.Close()
+
 	// Use QueueDeclare with Passive: true as QueueInspect is deprecated.
-	queue, err := r.channel.QueueDeclare(
+	queue, err := channel.QueueDeclarePassive(
 		queueName, // queue name
 		false,     // durable (unknown, as we're just inspecting)
 		false,     // autoDelete
 		false,     // exclusive
-		true,      // passive: true, only check if the queue exists and inspect it
+		false,     // no-wait
 		nil,       // args
 	)
 	if err != nil {
@@ -229,13 +941,3 @@ func (r *RabbitMQClient) QueueLength(ctx context.Context, queueName string) (int
 	}
 	return int64(queue.Messages), nil
 }
-
-// Ack acknowledges a message
-func (r *RabbitMQClient) Ack(tag uint64, multiple bool) error {
-	return r.channel.Ack(tag, multiple)
-}
-
-// Nack negatively acknowledges a message (reject and requeue)
-func (r *RabbitMQClient) Nack(tag uint64, multiple bool, requeue bool) error {
-	return r.channel.Nack(tag, multiple, requeue)
-}
@@ -0,0 +1,84 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryBus delivers events within the process, for single-replica setups
+type memoryBus struct {
+	mu       sync.RWMutex
+	handlers map[int]func(Event)
+	next     int
+}
+
+func NewMemoryBus() Bus {
+	return &memoryBus{handlers: make(map[int]func(Event))}
+}
+
+func (b *memoryBus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handle := range b.handlers {
+		handle(event)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context, handle func(Event)) error {
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.handlers[id] = handle
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.handlers, id)
+	b.mu.Unlock()
+	return ctx.Err()
+}
+
+// memoryPresence tracks the sessions of this process only
+type memoryPresence struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]time.Time // user ID -> session ID -> expiry
+}
+
+func NewMemoryPresence() Presence {
+	return &memoryPresence{sessions: make(map[string]map[string]time.Time)}
+}
+
+func (p *memoryPresence) Add(ctx context.Context, userID, sessionID string, ttl time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sessions[userID] == nil {
+		p.sessions[userID] = make(map[string]time.Time)
+	}
+	p.sessions[userID][sessionID] = time.Now().Add(ttl)
+	return nil
+}
+
+func (p *memoryPresence) Remove(ctx context.Context, userID, sessionID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions[userID], sessionID)
+	if len(p.sessions[userID]) == 0 {
+		delete(p.sessions, userID)
+	}
+	return nil
+}
+
+func (p *memoryPresence) Connected(ctx context.Context, userID string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, expiry := range p.sessions[userID] {
+		if expiry.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,301 @@
+// Package realtime delivers events to users' open app sessions, e.g.
+// WebSocket connections. Events go through a bus, Redis pub/sub or
+// in-process, so they reach the sessions on every replica, and sessions are
+// tracked as presence, so senders can tell whether a user is connected.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/pkg/metrics"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Bus backends
+const (
+	BusMemory = "memory"
+	BusRedis  = "redis"
+)
+
+// EventNotification is the type of events carrying a notification
+const EventNotification = "notification"
+
+// Event is sent to all of a user's sessions
+type Event struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type" example:"notification"`
+	UserID string          `json:"user_id" example:"user123"`
+	Data   json.RawMessage `json:"data" swaggertype:"object"`
+	At     time.Time       `json:"at"`
+}
+
+// Bus carries events to the hubs of every replica
+type Bus interface {
+	Publish(ctx context.Context, event Event) error
+	// Subscribe calls handle with every event published on any replica
+	// until ctx is cancelled
+	Subscribe(ctx context.Context, handle func(Event)) error
+}
+
+// Presence records which users have a session on any replica. Sessions
+// expire unless added again within their TTL, so a crashed replica's
+// sessions don't count for long.
+type Presence interface {
+	Add(ctx context.Context, userID, sessionID string, ttl time.Duration) error
+	Remove(ctx context.Context, userID, sessionID string) error
+	Connected(ctx context.Context, userID string) (bool, error)
+}
+
+// Session is one open connection of a user. Its events stop, and Done is
+// closed, when it leaves the hub or the hub is closed.
+type Session struct {
+	ID     string
+	UserID string
+
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Events returns the events sent to the session's user
+func (s *Session) Events() <-chan Event {
+	return s.events
+}
+
+// Done is closed once the session no longer gets events
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Session) end() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Hub keeps this replica's sessions and delivers them the events published
+// on the bus
+type Hub struct {
+	bus         Bus
+	presence    Presence
+	presenceTTL time.Duration
+	bufferSize  int
+
+	mu       sync.Mutex
+	sessions map[string]map[*Session]struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a hub on the bus selected by cfg.Bus. The Redis client is only
+// needed for the redis bus.
+func New(cfg *config.RealtimeConfig, redisClient redis.UniversalClient) (*Hub, error) {
+	var bus Bus
+	var presence Presence
+	switch cfg.Bus {
+	case "", BusMemory:
+		bus = NewMemoryBus()
+		presence = NewMemoryPresence()
+	case BusRedis:
+		if redisClient == nil {
+			return nil, fmt.Errorf("redis realtime bus requires a redis client")
+		}
+		bus = NewRedisBus(redisClient, cfg.Channel)
+		presence = NewRedisPresence(redisClient, cfg.KeyPrefix)
+	default:
+		return nil, fmt.Errorf("unknown realtime bus %q", cfg.Bus)
+	}
+	return NewHub(bus, presence, cfg), nil
+}
+
+// NewHub returns a hub on bus and starts delivering its events
+func NewHub(bus Bus, presence Presence, cfg *config.RealtimeConfig) *Hub {
+	presenceTTL := cfg.PresenceTTL
+	if presenceTTL <= 0 {
+		presenceTTL = time.Minute // default
+	}
+	bufferSize := cfg.SessionBuffer
+	if bufferSize <= 0 {
+		bufferSize = 16 // default
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Hub{
+		bus:         bus,
+		presence:    presence,
+		presenceTTL: presenceTTL,
+		bufferSize:  bufferSize,
+		sessions:    make(map[string]map[*Session]struct{}),
+		cancel:      cancel,
+	}
+
+	h.wg.Add(2)
+	go h.subscribe(ctx)
+	go h.refreshPresence(ctx)
+	return h
+}
+
+// Join opens a session for the user
+func (h *Hub) Join(ctx context.Context, userID string) (*Session, error) {
+	session := &Session{
+		ID:     uuid.NewString(),
+		UserID: userID,
+		events: make(chan Event, h.bufferSize),
+		done:   make(chan struct{}),
+	}
+	if err := h.presence.Add(ctx, userID, session.ID, h.presenceTTL); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	if h.sessions[userID] == nil {
+		h.sessions[userID] = make(map[*Session]struct{})
+	}
+	h.sessions[userID][session] = struct{}{}
+	h.mu.Unlock()
+	metrics.RealtimeSessions.Inc()
+	return session, nil
+}
+
+// Leave closes a session
+func (h *Hub) Leave(ctx context.Context, session *Session) {
+	h.mu.Lock()
+	if _, ok := h.sessions[session.UserID][session]; ok {
+		delete(h.sessions[session.UserID], session)
+		metrics.RealtimeSessions.Dec()
+	}
+	if len(h.sessions[session.UserID]) == 0 {
+		delete(h.sessions, session.UserID)
+	}
+	h.mu.Unlock()
+	session.end()
+
+	if err := h.presence.Remove(ctx, session.UserID, session.ID); err != nil {
+		zap.L().Warn("Failed to remove realtime session",
+			zap.String("user_id", session.UserID),
+			zap.Error(err),
+		)
+	}
+}
+
+// Publish sends an event with data to the user's sessions on every replica
+func (h *Hub) Publish(ctx context.Context, userID, eventType string, data any) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return h.bus.Publish(ctx, Event{
+		ID:     uuid.NewString(),
+		Type:   eventType,
+		UserID: userID,
+		Data:   body,
+		At:     time.Now().UTC(),
+	})
+}
+
+// Connected reports whether the user has a session on any replica. If
+// presence can't be checked, the user counts as not connected.
+func (h *Hub) Connected(ctx context.Context, userID string) bool {
+	connected, err := h.presence.Connected(ctx, userID)
+	if err != nil {
+		zap.L().Warn("Failed to check realtime presence", zap.String("user_id", userID), zap.Error(err))
+		return false
+	}
+	return connected
+}
+
+// Close stops delivering events and ends every session of this replica
+func (h *Hub) Close() {
+	h.cancel()
+	h.wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for userID, sessions := range h.sessions {
+		for session := range sessions {
+			session.end()
+			h.presence.Remove(ctx, userID, session.ID)
+			metrics.RealtimeSessions.Dec()
+		}
+	}
+	h.sessions = make(map[string]map[*Session]struct{})
+}
+
+// subscribe delivers the bus's events until ctx is cancelled, subscribing
+// again if the subscription breaks
+func (h *Hub) subscribe(ctx context.Context) {
+	defer h.wg.Done()
+	for {
+		err := h.bus.Subscribe(ctx, h.deliver)
+		if ctx.Err() != nil {
+			return
+		}
+		zap.L().Error("Realtime bus subscription ended, resubscribing", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// deliver hands an event to the user's sessions on this replica. A session
+// that isn't keeping up misses the event rather than holding up the others.
+func (h *Hub) deliver(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for session := range h.sessions[event.UserID] {
+		select {
+		case session.events <- event:
+		default:
+			zap.L().Warn("Realtime session is too slow, event dropped",
+				zap.String("user_id", event.UserID),
+				zap.String("session_id", session.ID),
+				zap.String("event_id", event.ID),
+			)
+		}
+	}
+}
+
+// refreshPresence keeps this replica's sessions from expiring
+func (h *Hub) refreshPresence(ctx context.Context) {
+	defer h.wg.Done()
+	ticker := time.NewTicker(h.presenceTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		h.mu.Lock()
+		sessions := make([]*Session, 0, len(h.sessions))
+		for _, userSessions := range h.sessions {
+			for session := range userSessions {
+				sessions = append(sessions, session)
+			}
+		}
+		h.mu.Unlock()
+
+		for _, session := range sessions {
+			if err := h.presence.Add(ctx, session.UserID, session.ID, h.presenceTTL); err != nil {
+				zap.L().Warn("Failed to refresh realtime session",
+					zap.String("user_id", session.UserID),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
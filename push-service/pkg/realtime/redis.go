@@ -0,0 +1,100 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// redisBus fans events out to every replica over Redis pub/sub. Events
+// published while a replica is disconnected are lost to it.
+type redisBus struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+func NewRedisBus(client redis.UniversalClient, channel string) Bus {
+	if channel == "" {
+		channel = "push:realtime" // default
+	}
+	return &redisBus{client: client, channel: channel}
+}
+
+func (b *redisBus) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, body).Err()
+}
+
+func (b *redisBus) Subscribe(ctx context.Context, handle func(Event)) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer pubsub.Close()
+
+	// Wait for the subscription, so a broken connection is reported
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message, ok := <-messages:
+			if !ok {
+				return redis.ErrClosed
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				zap.L().Warn("Dropping malformed realtime event", zap.Error(err))
+				continue
+			}
+			handle(event)
+		}
+	}
+}
+
+// redisPresence keeps each user's sessions in a sorted set scored by when
+// they expire
+type redisPresence struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+func NewRedisPresence(client redis.UniversalClient, keyPrefix string) Presence {
+	if keyPrefix == "" {
+		keyPrefix = "push:presence:" // default
+	}
+	return &redisPresence{client: client, keyPrefix: keyPrefix}
+}
+
+func (p *redisPresence) Add(ctx context.Context, userID, sessionID string, ttl time.Duration) error {
+	key := p.keyPrefix + userID
+	now := time.Now()
+
+	pipe := p.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: sessionID})
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (p *redisPresence) Remove(ctx context.Context, userID, sessionID string) error {
+	return p.client.ZRem(ctx, p.keyPrefix+userID, sessionID).Err()
+}
+
+func (p *redisPresence) Connected(ctx context.Context, userID string) (bool, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	count, err := p.client.ZCount(ctx, p.keyPrefix+userID, "("+now, "+inf").Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
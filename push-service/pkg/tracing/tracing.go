@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"push-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes the sampler decides on
+const (
+	AttrTenant   = attribute.Key("tenant.id")
+	AttrPriority = attribute.Key("notification.priority")
+)
+
+// Request headers carrying the sampling attributes of HTTP requests
+const (
+	TenantHeader   = "X-Tenant-ID"
+	PriorityHeader = "X-Priority"
+)
+
+const tracerName = "push-service"
+
+var propagator = propagation.TraceContext{}
+
+// Init installs the global tracer provider. Spans are exported over OTLP/HTTP
+// and sampled per tenant and priority; see Sampler. If tracing is disabled
+// the no-op provider stays in place. The returned function flushes and stops
+// the exporter.
+func Init(ctx context.Context, cfg *config.TracingConfig) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(NewSampler(cfg))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", cfg.ServiceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the service's tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Sampler samples root spans at the rate configured for their tenant, else
+// for their priority, else at the default rate. Child spans, including those
+// of queued messages, follow their root through ParentBased, so a trace is
+// kept or dropped as a whole.
+type Sampler struct {
+	defaultRate float64
+	tenants     map[string]float64
+	priorities  map[string]float64
+
+	mu       sync.Mutex
+	samplers map[float64]sdktrace.Sampler
+}
+
+func NewSampler(cfg *config.TracingConfig) *Sampler {
+	return &Sampler{
+		defaultRate: cfg.SampleRate,
+		tenants:     cfg.TenantSampleRates,
+		priorities:  cfg.PrioritySampleRates,
+		samplers:    make(map[float64]sdktrace.Sampler),
+	}
+}
+
+// Rate returns the sampling rate for a tenant and priority, either of which
+// may be empty
+func (s *Sampler) Rate(tenant, priority string) float64 {
+	if rate, ok := s.tenants[tenant]; ok && tenant != "" {
+		return rate
+	}
+	if rate, ok := s.priorities[priority]; ok && priority != "" {
+		return rate
+	}
+	return s.defaultRate
+}
+
+func (s *Sampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var tenant, priority string
+	for _, attr := range p.Attributes {
+		switch attr.Key {
+		case AttrTenant:
+			tenant = attr.Value.AsString()
+		case AttrPriority:
+			priority = attr.Value.AsString()
+		}
+	}
+	return s.ratioSampler(s.Rate(tenant, priority)).ShouldSample(p)
+}
+
+// ratioSampler returns the trace ID ratio sampler for rate. The decision only
+// depends on the trace ID, so it is the same wherever it is made.
+func (s *Sampler) ratioSampler(rate float64) sdktrace.Sampler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sampler, ok := s.samplers[rate]
+	if !ok {
+		sampler = sdktrace.TraceIDRatioBased(rate)
+		s.samplers[rate] = sampler
+	}
+	return sampler
+}
+
+func (s *Sampler) Description() string {
+	return "TenantPrioritySampler{default=" + strconv.FormatFloat(s.defaultRate, 'g', -1, 64) + "}"
+}
+
+// Attributes returns the sampling attributes, skipping empty values
+func Attributes(tenant, priority string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if tenant != "" {
+		attrs = append(attrs, AttrTenant.String(tenant))
+	}
+	if priority != "" {
+		attrs = append(attrs, AttrPriority.String(priority))
+	}
+	return attrs
+}
+
+// Inject returns the trace context of ctx for embedding in a queue message
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns ctx with the trace context embedded in a queue message
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// RecordError marks span as failed with err, if any
+func RecordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// Middleware starts a server span per request. The tenant and priority are
+// taken from the X-Tenant-ID and X-Priority headers for sampling.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		attrs := append(Attributes(c.GetHeader(TenantHeader), c.GetHeader(PriorityHeader)),
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+		)
+		ctx, span := Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+	}
+}
@@ -0,0 +1,361 @@
+// Package webhook delivers signed JSON payloads to HTTP endpoints. Failed
+// deliveries are retried with exponential backoff, endpoints that keep
+// failing are skipped for a while by a per-endpoint circuit breaker, and
+// recent deliveries are kept in memory for inspection.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"push-service/internal/config"
+	"push-service/pkg/metrics"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Delivery outcomes
+const (
+	OutcomeDelivered   = "delivered"
+	OutcomeRetrying    = "retrying"
+	OutcomeFailed      = "failed"
+	OutcomeCircuitOpen = "circuit_open"
+)
+
+// Endpoint is where a message is delivered. Secret signs the requests.
+type Endpoint struct {
+	ID     string
+	URL    string
+	Secret string
+}
+
+// Message is a payload to deliver. ID stays the same across retries, so
+// receivers can de-duplicate.
+type Message struct {
+	ID   string
+	Type string
+	Body []byte
+}
+
+// Delivery is one attempt to deliver a message
+type Delivery struct {
+	ID         string    `json:"id"`
+	EndpointID string    `json:"webhook_id"`
+	URL        string    `json:"url"`
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type" example:"notification.sent"`
+	Attempt    int       `json:"attempt" example:"1"`
+	Outcome    string    `json:"outcome" example:"delivered"` // delivered, retrying, failed or circuit_open
+	StatusCode int       `json:"status_code,omitempty" example:"200"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms" example:"84"`
+	At         time.Time `json:"at"`
+}
+
+// Circuit is an endpoint whose circuit is open: deliveries to it are
+// skipped until OpenUntil, then one is let through to probe it
+type Circuit struct {
+	EndpointID string    `json:"webhook_id"`
+	Failures   int       `json:"failures" example:"5"`
+	OpenUntil  time.Time `json:"open_until"`
+}
+
+// breaker counts an endpoint's consecutive failures
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// Dispatcher delivers messages in the background. One dispatcher should be
+// shared by everything in the process that sends webhooks, so circuits and
+// the delivery log cover all of them.
+type Dispatcher struct {
+	client           *http.Client
+	maxAttempts      int
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	failureThreshold int
+	openDuration     time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+	log      []Delivery // ring buffer of the most recent deliveries
+	next     int
+	full     bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewDispatcher(cfg *config.WebhooksConfig) *Dispatcher {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second // default
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5 // default
+	}
+	initialBackoff := cfg.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = time.Second // default
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Minute // default
+	}
+	failureThreshold := cfg.FailureThreshold
+	if failureThreshold <= 0 {
+		failureThreshold = 5 // default
+	}
+	openDuration := cfg.OpenDuration
+	if openDuration <= 0 {
+		openDuration = time.Minute // default
+	}
+	logSize := cfg.DeliveryLogSize
+	if logSize <= 0 {
+		logSize = 1000 // default
+	}
+
+	return &Dispatcher{
+		client:           &http.Client{Timeout: timeout},
+		maxAttempts:      maxAttempts,
+		initialBackoff:   initialBackoff,
+		maxBackoff:       maxBackoff,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		breakers:         make(map[string]*breaker),
+		log:              make([]Delivery, logSize),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Dispatch delivers message to endpoint in the background, retrying until
+// it is delivered, fails permanently or runs out of attempts
+func (d *Dispatcher) Dispatch(endpoint Endpoint, message Message) {
+	go d.run(endpoint, message)
+}
+
+// Close abandons pending retries. Deliveries in flight still finish.
+func (d *Dispatcher) Close() {
+	d.stopOnce.Do(func() { close(d.stop) })
+}
+
+func (d *Dispatcher) run(endpoint Endpoint, message Message) {
+	backoff := d.initialBackoff
+	for attempt := 1; ; attempt++ {
+		delivery := Delivery{
+			ID:         uuid.NewString(),
+			EndpointID: endpoint.ID,
+			URL:        endpoint.URL,
+			EventID:    message.ID,
+			EventType:  message.Type,
+			Attempt:    attempt,
+			At:         time.Now().UTC(),
+		}
+
+		if !d.allow(endpoint.ID) {
+			delivery.Outcome = OutcomeCircuitOpen
+			d.record(delivery)
+			zap.L().Warn("Webhook circuit open, delivery skipped",
+				zap.String("webhook_id", endpoint.ID),
+				zap.String("event_id", message.ID),
+			)
+			return
+		}
+
+		statusCode, err := d.send(endpoint, message, attempt)
+		delivery.StatusCode = statusCode
+		delivery.DurationMs = time.Since(delivery.At).Milliseconds()
+		d.recordResult(endpoint.ID, err == nil)
+
+		switch {
+		case err == nil:
+			delivery.Outcome = OutcomeDelivered
+			d.record(delivery)
+			return
+		case !retryable(statusCode) || attempt >= d.maxAttempts:
+			delivery.Outcome = OutcomeFailed
+			delivery.Error = err.Error()
+			d.record(delivery)
+			zap.L().Warn("Webhook delivery failed",
+				zap.String("webhook_id", endpoint.ID),
+				zap.String("event_id", message.ID),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			return
+		default:
+			delivery.Outcome = OutcomeRetrying
+			delivery.Error = err.Error()
+			d.record(delivery)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-d.stop:
+			return
+		}
+		backoff = min(backoff*2, d.maxBackoff)
+	}
+}
+
+// send POSTs message to endpoint. The signature covers a fresh timestamp,
+// so each attempt is signed again.
+func (d *Dispatcher) send(endpoint Endpoint, message Message, attempt int) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, endpoint.URL, bytes.NewReader(message.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", endpoint.ID)
+	req.Header.Set("X-Webhook-Event", message.Type)
+	req.Header.Set("X-Webhook-Event-ID", message.ID)
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(attempt))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(endpoint.Secret, timestamp, message.Body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed delivery may succeed later. Network
+// errors (no status), timeouts, rate limits and server errors are retried;
+// other client errors won't change.
+func retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500
+}
+
+// Sign returns the X-Webhook-Signature of body sent at timestamp: sha256=
+// and the hex HMAC-SHA256 with secret of the timestamp, "." and the body.
+// Signing the timestamp lets receivers reject replayed requests.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// allow reports whether a delivery to the endpoint may be attempted. Once
+// an open circuit's time is up, one delivery at a time probes the endpoint.
+func (d *Dispatcher) allow(endpointID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b, ok := d.breakers[endpointID]
+	if !ok || b.failures < d.failureThreshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// recordResult closes the endpoint's circuit on success, and opens it once
+// failures reach the threshold
+func (d *Dispatcher) recordResult(endpointID string, success bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if success {
+		delete(d.breakers, endpointID)
+		return
+	}
+
+	b, ok := d.breakers[endpointID]
+	if !ok {
+		b = &breaker{}
+		d.breakers[endpointID] = b
+	}
+	b.failures++
+	b.probing = false
+	if b.failures >= d.failureThreshold {
+		b.openUntil = time.Now().Add(d.openDuration)
+		if b.failures == d.failureThreshold {
+			zap.L().Warn("Webhook circuit opened",
+				zap.String("webhook_id", endpointID),
+				zap.Duration("open_for", d.openDuration),
+			)
+		}
+	}
+}
+
+// record adds a delivery to the log
+func (d *Dispatcher) record(delivery Delivery) {
+	metrics.WebhookDeliveries.WithLabelValues(delivery.Outcome).Inc()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.log[d.next] = delivery
+	d.next = (d.next + 1) % len(d.log)
+	if d.next == 0 {
+		d.full = true
+	}
+}
+
+// Deliveries returns up to limit of the most recent deliveries, newest
+// first, of one endpoint or, if endpointID is empty, of all of them
+func (d *Dispatcher) Deliveries(endpointID string, limit int) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	count := d.next
+	if d.full {
+		count = len(d.log)
+	}
+
+	deliveries := []Delivery{}
+	for i := 1; i <= count && len(deliveries) < limit; i++ {
+		delivery := d.log[(d.next-i+len(d.log))%len(d.log)]
+		if endpointID == "" || delivery.EndpointID == endpointID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
+
+// OpenCircuits returns the endpoints whose circuit is open, by ID
+func (d *Dispatcher) OpenCircuits() []Circuit {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	circuits := []Circuit{}
+	for endpointID, b := range d.breakers {
+		if b.failures >= d.failureThreshold {
+			circuits = append(circuits, Circuit{EndpointID: endpointID, Failures: b.failures, OpenUntil: b.openUntil})
+		}
+	}
+	sort.Slice(circuits, func(i, j int) bool { return circuits[i].EndpointID < circuits[j].EndpointID })
+	return circuits
+}